@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// writeTools modify files in the workspace, change the language server's state or
+// run commands, and are disabled in read-only mode
+var writeTools = map[string]bool{
+	"edit_file":               true,
+	"commit_staged":           true,
+	"replace_symbol_body":     true,
+	"insert_near_symbol":      true,
+	"apply_patch":             true,
+	"rename_symbol":           true,
+	"rename_file":             true,
+	"create_file":             true,
+	"delete_file":             true,
+	"apply_code_action":       true,
+	"fix_diagnostics":         true,
+	"extract":                 true,
+	"inline":                  true,
+	"undo_last_edit":          true,
+	"undo_transaction":        true,
+	"format_document":         true,
+	"execute_codelens":        true,
+	"run_test":                true,
+	"add_workspace_folder":    true,
+	"remove_workspace_folder": true,
+	"restart_language_server": true,
+	"reload_configuration":    true,
+	"vulncheck":               true,
+}
+
+// toolAccess decides which tools are registered
+type toolAccess struct {
+	// ReadOnly disables every tool that can change the workspace
+	ReadOnly bool `json:"readOnly"`
+	// Enable lists the only tools to register, all of them when empty
+	Enable []string `json:"enable"`
+	// Disable lists tools that are never registered
+	Disable []string `json:"disable"`
+	// HideUnsupported skips tools the language server lacks the capability for,
+	// which otherwise explain that they are not supported when called
+	HideUnsupported bool `json:"hideUnsupported"`
+	// AllowExternalFolders lets add_workspace_folder attach directories outside
+	// the configured workspace folders, which tools can then read and edit
+	AllowExternalFolders bool `json:"allowExternalFolders"`
+}
+
+// allowed reports whether a tool may be registered
+func (a toolAccess) allowed(name string) bool {
+	if a.ReadOnly && writeTools[name] {
+		return false
+	}
+	if len(a.Enable) > 0 && !slices.Contains(a.Enable, name) {
+		return false
+	}
+	return !slices.Contains(a.Disable, name)
+}
+
+// checkNames returns an error naming any configured tool that does not exist
+func (a toolAccess) checkNames(registered map[string]bool) error {
+	var unknown []string
+	for _, name := range append(append([]string{}, a.Enable...), a.Disable...) {
+		if !registered[name] && !slices.Contains(unknown, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown tools: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// addTool registers a tool unless it has been disabled
+func (s *mcpServer) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	s.toolNames[tool.Name] = true
+	if !s.config.tools.allowed(tool.Name) {
+		coreLogger.Info("Tool %s is disabled", tool.Name)
+		return
+	}
+	if s.config.tools.HideUnsupported && !s.toolSupported(tool.Name) {
+		coreLogger.Info("Tool %s is not supported by the language server", tool.Name)
+		return
+	}
+	handler = s.withCapabilityCheck(tool.Name, handler)
+	handler = s.withRouting(handler)
+	tool, handler = withIndexBase(tool, handler)
+	// Continuations keep the format of the result they continue, and are already
+	// within the budget
+	if tool.Name != continueOutputTool {
+		tool, handler = withFormat(tool, handler)
+		tool, handler = s.responses.withBudget(tool, handler)
+	}
+	if s.metrics != nil {
+		handler = s.metrics.withToolMetrics(tool.Name, handler)
+	}
+	s.mcpServer.AddTool(tool, handler)
+}
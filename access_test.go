@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readOnlyTools are the tools deliberately left enabled in read-only mode. A new
+// tool must be added here or to writeTools.
+var readOnlyTools = []string{
+	"batch_diagnostics",
+	"call_hierarchy",
+	"changed_diagnostics",
+	"completion",
+	"continue_output",
+	"declaration",
+	"definition",
+	"diagnostics",
+	"diagnostics_delta",
+	"discard_staged",
+	"document_highlight",
+	"edit_history",
+	"find_and_read",
+	"find_implementations",
+	"folding_ranges",
+	"grep",
+	"hover",
+	"list_code_actions",
+	"list_tests",
+	"lsp_capabilities",
+	"moniker",
+	"open_files",
+	"project_overview",
+	"read_file",
+	"read_symbol",
+	"references",
+	"resolve_symbol",
+	"semantic_tokens",
+	"server_status",
+	"type_definition",
+	"type_hierarchy",
+	"wait_for_diagnostics",
+	"workspace_symbols",
+}
+
+func TestReadOnlyTools(t *testing.T) {
+	s, err := newServer(&config{})
+	require.NoError(t, err)
+	s.mcpServer = server.NewMCPServer("test", "0")
+	require.NoError(t, s.registerTools())
+
+	readOnly := toolAccess{ReadOnly: true}
+	for name := range s.toolNames {
+		if readOnly.allowed(name) {
+			assert.Contains(t, readOnlyTools, name, "tool %s is enabled in read-only mode; add it to writeTools or readOnlyTools", name)
+		}
+	}
+	for _, name := range readOnlyTools {
+		assert.True(t, s.toolNames[name], "read-only tool %s is not registered", name)
+		assert.False(t, writeTools[name], "tool %s is both a write tool and a read-only tool", name)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// defaultMaxResponseBytes is the response budget when none is configured, about
+	// 15k tokens
+	defaultMaxResponseBytes = 60000
+	// maxPendingResponses bounds how many truncated responses are kept for
+	// continue_output. The oldest is dropped first.
+	maxPendingResponses = 32
+
+	continueOutputTool = "continue_output"
+)
+
+// responseBudget truncates tool results that are larger than the budget, and keeps
+// the rest of them for continue_output to return in further chunks
+type responseBudget struct {
+	// maxBytes is the largest result returned at once, 0 for no limit
+	maxBytes int
+
+	mu      sync.Mutex
+	pending map[string]*pendingResponse
+	// order holds the tokens of pending responses, oldest first
+	order []string
+	next  int
+}
+
+// pendingResponse is the part of a truncated result that was not returned yet
+type pendingResponse struct {
+	text string
+	// sent and total count the bytes of the whole result
+	sent  int
+	total int
+	json  bool
+}
+
+// newResponseBudget creates a budget from the configured size: 0 for the default,
+// negative for no limit
+func newResponseBudget(maxBytes int) *responseBudget {
+	switch {
+	case maxBytes == 0:
+		maxBytes = defaultMaxResponseBytes
+	case maxBytes < 0:
+		maxBytes = 0
+	}
+	return &responseBudget{
+		maxBytes: maxBytes,
+		pending:  make(map[string]*pendingResponse),
+	}
+}
+
+// withBudget wraps a tool's handler to truncate results larger than the budget
+func (b *responseBudget) withBudget(tool mcp.Tool, handler server.ToolHandlerFunc) (mcp.Tool, server.ToolHandlerFunc) {
+	if b.maxBytes == 0 {
+		return tool, handler
+	}
+	return tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := handler(ctx, request)
+		if err != nil || result == nil || result.IsError {
+			return result, err
+		}
+
+		text := ""
+		for _, content := range result.Content {
+			textContent, ok := content.(mcp.TextContent)
+			if !ok {
+				// Only text can be split into chunks
+				return result, nil
+			}
+			text += textContent.Text
+		}
+		if len(text) <= b.maxBytes {
+			return result, nil
+		}
+
+		return b.nextChunk(b.store(tool.Name, &pendingResponse{text: text, total: len(text), json: wantsJSON(request)}))
+	}
+}
+
+// store keeps a truncated response and returns its continuation token
+func (b *responseBudget) store(toolName string, response *pendingResponse) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.next++
+	token := fmt.Sprintf("%s-%d", toolName, b.next)
+	b.pending[token] = response
+	b.order = append(b.order, token)
+	for len(b.order) > maxPendingResponses {
+		delete(b.pending, b.order[0])
+		b.order = b.order[1:]
+	}
+	return token
+}
+
+// nextChunk returns the next chunk of a pending response, dropping the response
+// once it has all been returned
+func (b *responseBudget) nextChunk(token string) (*mcp.CallToolResult, error) {
+	b.mu.Lock()
+	response, ok := b.pending[token]
+	if !ok {
+		b.mu.Unlock()
+		return mcp.NewToolResultError(fmt.Sprintf("unknown or expired continuation token: %s. Call the tool again to get its output.", token)), nil
+	}
+	cut := splitPoint(response.text, b.maxBytes)
+	chunk := response.text[:cut]
+	response.text = response.text[cut:]
+	start := response.sent
+	response.sent += cut
+	done := response.text == ""
+	if done {
+		delete(b.pending, token)
+	}
+	b.mu.Unlock()
+
+	continuation := ""
+	if !done {
+		continuation = token
+	}
+	if response.json {
+		return jsonResult(textResult{Text: chunk, Continuation: continuation})
+	}
+	if done {
+		return mcp.NewToolResultText(fmt.Sprintf("%s\n\n[End of output: bytes %d-%d of %d.]",
+			strings.TrimRight(chunk, "\n"), start+1, response.sent, response.total)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("%s\n\n[Output truncated: bytes %d-%d of %d. Call %s with token %q for the rest.]",
+		strings.TrimRight(chunk, "\n"), start+1, response.sent, response.total, continueOutputTool, token)), nil
+}
+
+// splitPoint returns where to cut text so the first part fits in maxBytes. It
+// prefers the start of a section, such as the "---" before each file or symbol
+// in references and definitions, then a blank line, then the end of a line, as
+// long as that keeps at least half of the budget.
+func splitPoint(text string, maxBytes int) int {
+	if len(text) <= maxBytes {
+		return len(text)
+	}
+	window := text[:maxBytes]
+	if i := strings.LastIndex(window, "\n---\n"); i >= maxBytes/2 {
+		return i + 1
+	}
+	for _, separator := range []string{"\n\n", "\n"} {
+		if i := strings.LastIndex(window, separator); i >= maxBytes/2 {
+			return i + len(separator)
+		}
+	}
+	// Never split a character
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		_, cut = utf8.DecodeRuneInString(text)
+	}
+	return cut
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolRequirement is the LSP method a tool cannot work without, and the tools to
+// suggest in its place when the server does not support it
+type toolRequirement struct {
+	method       string
+	alternatives []string
+}
+
+// toolRequirements lists the tools that depend on an optional capability of the
+// language server
+var toolRequirements = map[string]toolRequirement{
+	"definition":           {"textDocument/definition", []string{"workspace_symbols", "find_and_read"}},
+	"declaration":          {"textDocument/declaration", []string{"definition"}},
+	"type_definition":      {"textDocument/typeDefinition", []string{"hover", "definition"}},
+	"find_implementations": {"textDocument/implementation", []string{"references", "type_hierarchy"}},
+	"references":           {"textDocument/references", []string{"document_highlight", "workspace_symbols"}},
+	"document_highlight":   {"textDocument/documentHighlight", []string{"references"}},
+	"hover":                {"textDocument/hover", []string{"read_symbol", "definition"}},
+	"workspace_symbols":    {"workspace/symbol", []string{"project_overview"}},
+	"find_and_read":        {"workspace/symbol", []string{"read_symbol", "project_overview"}},
+	"read_symbol":          {"textDocument/documentSymbol", []string{"definition"}},
+	"resolve_symbol":       {"workspace/symbol", []string{"workspace_symbols", "read_symbol"}},
+	"replace_symbol_body":  {"textDocument/documentSymbol", []string{"edit_file"}},
+	"insert_near_symbol":   {"textDocument/documentSymbol", []string{"edit_file"}},
+	"rename_symbol":        {"textDocument/rename", []string{"references", "edit_file"}},
+	"completion":           {"textDocument/completion", []string{"hover", "workspace_symbols"}},
+	"call_hierarchy":       {"textDocument/prepareCallHierarchy", []string{"references"}},
+	"type_hierarchy":       {"textDocument/prepareTypeHierarchy", []string{"find_implementations"}},
+	"list_code_actions":    {"textDocument/codeAction", []string{"diagnostics", "edit_file"}},
+	"apply_code_action":    {"textDocument/codeAction", []string{"edit_file"}},
+	"fix_diagnostics":      {"textDocument/codeAction", []string{"diagnostics", "edit_file"}},
+	"extract":              {"textDocument/codeAction", []string{"edit_file"}},
+	"inline":               {"textDocument/codeAction", []string{"references", "edit_file"}},
+	"format_document":      {"textDocument/formatting", nil},
+	"folding_ranges":       {"textDocument/foldingRange", []string{"read_symbol"}},
+	"semantic_tokens":      {"textDocument/semanticTokens/full", []string{"hover"}},
+	"get_codelens":         {"textDocument/codeLens", nil},
+	"execute_codelens":     {"textDocument/codeLens", nil},
+	"moniker":              {"textDocument/moniker", []string{"hover"}},
+}
+
+// toolSupported reports whether a language server supports what a tool needs.
+// With additional servers, any of them supporting it is enough to register the
+// tool. Tools are assumed to work before the server is started.
+func (s *mcpServer) toolSupported(name string) bool {
+	if clientSupports(s.lspClient, name) {
+		return true
+	}
+	for _, r := range s.routes {
+		if clientSupports(r.client, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientSupports reports whether one language server supports what a tool needs
+func clientSupports(client *lsp.Client, name string) bool {
+	requirement, ok := toolRequirements[name]
+	if !ok || client == nil {
+		return true
+	}
+	return client.SupportsMethod(requirement.method)
+}
+
+// withCapabilityCheck wraps the handler of a tool that depends on an optional
+// capability to explain that the server does not support it, naming tools to use
+// instead, rather than passing on the server's method not found error. The check
+// is made on each call, since servers may register capabilities after starting.
+func (s *mcpServer) withCapabilityCheck(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	requirement, ok := toolRequirements[name]
+	if !ok {
+		return handler
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Calls that are not about a file may be answered by any server
+		client, routed := routedClient(ctx)
+		if !routed {
+			client = s.lspClient
+			if s.toolSupported(name) {
+				return handler(ctx, request)
+			}
+		} else if clientSupports(client, name) {
+			return handler(ctx, request)
+		}
+
+		if client == s.fallback {
+			return mcp.NewToolResultError(fmt.Sprintf("%s needs a language server, and no language server handles this file since it matches the fallback globs. Use read_file, grep and edit_file instead.", name)), nil
+		}
+
+		message := fmt.Sprintf("%s is not supported by %s: the language server does not implement %s.",
+			name, client.ServerName(), requirement.method)
+		var alternatives []string
+		for _, alternative := range requirement.alternatives {
+			if s.config.tools.allowed(alternative) && clientSupports(client, alternative) {
+				alternatives = append(alternatives, alternative)
+			}
+		}
+		if len(alternatives) > 0 {
+			message += fmt.Sprintf(" Try %s instead.", strings.Join(alternatives, " or "))
+		}
+		return mcp.NewToolResultError(message), nil
+	}
+}
+
+// toolSupport returns the state of the registered tools that depend on an
+// optional capability of the language server, sorted by name
+func (s *mcpServer) toolSupport() []tools.ToolSupport {
+	var names []string
+	for name := range toolRequirements {
+		if s.toolNames[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	support := []tools.ToolSupport{}
+	for _, name := range names {
+		state := "active"
+		switch {
+		case !s.config.tools.allowed(name):
+			state = "disabled"
+		case !s.toolSupported(name) && s.config.tools.HideUnsupported:
+			state = "hidden"
+		case !s.toolSupported(name):
+			state = "unsupported"
+		}
+		support = append(support, tools.ToolSupport{
+			Name:   name,
+			Method: toolRequirements[name].method,
+			State:  state,
+		})
+	}
+	return support
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// version is the version of the binary, set at build time with
+// -ldflags "-X main.version=..."
+var version = "v0.0.2"
+
+// command is a subcommand of the binary. Commands that start the language server
+// take the flags of serve.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "Serve MCP over stdio or SSE (the default command)", runServe},
+	{"check", "Validate the configuration, start and initialize the language server, then exit", runCheck},
+	{"doctor", "Diagnose common setup problems and print the server's capabilities", runDoctor},
+	{"query", "Run one tool and print its result, without MCP", runQueryCommand},
+	{"index", "Export LSIF or SCIP code intelligence from the language server", runIndexCommand},
+	{"version", "Print the version", runVersion},
+}
+
+// runCommand runs the command named by the first argument. Arguments that start
+// with a flag run serve, so existing invocations keep working.
+func runCommand(args []string) error {
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printCommands()
+		return nil
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			flag.CommandLine.Init("mcp-language-server "+name, flag.ExitOnError)
+			return cmd.run(args)
+		}
+	}
+	return fmt.Errorf("unknown command %q, run mcp-language-server help for the commands", name)
+}
+
+// printCommands describes the commands for the help command
+func printCommands() {
+	fmt.Println("Usage: mcp-language-server [command] [flags] [-- LSP arguments]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Printf("  %-8s %s\n", "help", "Describe the commands")
+	fmt.Println()
+	fmt.Println("Run mcp-language-server <command> -h for the flags of a command.")
+}
+
+func runVersion(args []string) error {
+	fmt.Println(versionString())
+	return nil
+}
+
+// versionString describes the build: the version, or the module version when
+// installed with go install, and the Go version and platform
+func versionString() string {
+	v := version
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		v = info.Main.Version
+	}
+	return fmt.Sprintf("mcp-language-server %s (%s %s/%s)", v, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// runCheck validates the configuration and that the language server starts and
+// initializes, for scripts and CI. It fails with the first problem found.
+func runCheck(args []string) error {
+	config, err := parseConfig(args)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %v", err)
+	}
+	fmt.Printf("Configuration: ok (%s)\n", describeConfig(config))
+
+	server, err := newServer(config)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer cleanup(server, done)
+
+	start := time.Now()
+	client, err := server.startClient()
+	if err != nil {
+		return err
+	}
+	name, serverVersion := client.ServerInfo()
+	fmt.Printf("Language server: ok (%s initialized in %v)\n", strings.TrimSpace(name+" "+serverVersion), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// describeConfig summarizes how the language server is reached
+func describeConfig(config *config) string {
+	var server string
+	switch {
+	case config.indexOnly():
+		server = "code index " + config.codeIndex
+	case config.lspAddress != "":
+		server = "server at " + config.lspAddress
+	case config.remote.Host != "":
+		server = config.lspCommand + " on " + config.remote.Host
+	case config.container.Image != "":
+		server = config.lspCommand + " in " + config.container.Image
+	default:
+		server = config.lspCommand
+	}
+	for _, route := range config.routes {
+		server += fmt.Sprintf(" + %s for %s", route.Command, strings.Join(route.Globs, ", "))
+	}
+	roots := "1 workspace"
+	if len(config.workspaceDirs) != 1 {
+		roots = fmt.Sprintf("%d workspaces", len(config.workspaceDirs))
+	}
+	if config.configFile != "" {
+		return fmt.Sprintf("%s, %s, config file %s", server, roots, config.configFile)
+	}
+	return fmt.Sprintf("%s, %s", server, roots)
+}
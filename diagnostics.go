@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// workspaceDiagnosticsURI is the resource with the diagnostics of every file
+	workspaceDiagnosticsURI = "diagnostics://workspace"
+
+	// fileDiagnosticsPrefix starts the resource URI of a single file's diagnostics
+	fileDiagnosticsPrefix = "diagnostics://file"
+
+	// diagnosticsUpdateDelay batches bursts of diagnostics into one update
+	diagnosticsUpdateDelay = 500 * time.Millisecond
+)
+
+// diagnosticsFeed announces changes to the diagnostics resources with
+// notifications/resources/updated
+type diagnosticsFeed struct {
+	mcpServer *server.MCPServer
+
+	changed map[protocol.DocumentUri]bool
+	timer   *time.Timer
+	mu      sync.Mutex
+}
+
+// registerDiagnosticsResources exposes diagnostics as resources that are updated
+// whenever the language server publishes new diagnostics
+func (s *mcpServer) registerDiagnosticsResources() {
+	s.mcpServer.AddResource(
+		mcp.NewResource(workspaceDiagnosticsURI, "Workspace diagnostics",
+			mcp.WithResourceDescription("Diagnostics of every file in the workspace, updated as the language server reports them"),
+			mcp.WithMIMEType("application/json"),
+		),
+		s.readDiagnosticsResource,
+	)
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(fileDiagnosticsPrefix+"{+path}", "File diagnostics",
+			mcp.WithTemplateDescription("Diagnostics of a single file, by absolute path"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		s.readDiagnosticsResource,
+	)
+
+	feed := &diagnosticsFeed{
+		mcpServer: s.mcpServer,
+		changed:   make(map[protocol.DocumentUri]bool),
+	}
+	s.lspClient.SetDiagnosticsHandler(feed.update)
+}
+
+// readDiagnosticsResource reads the workspace or a file diagnostics resource
+func (s *mcpServer) readDiagnosticsResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := request.Params.URI
+
+	var report any
+	switch {
+	case uri == workspaceDiagnosticsURI:
+		report = tools.CollectDiagnostics(s.lspClient)
+	case strings.HasPrefix(uri, fileDiagnosticsPrefix+"/"):
+		// The resource path is the path of the file URI, so it is encoded the same way
+		path := strings.TrimPrefix(uri, fileDiagnosticsPrefix)
+		report = tools.CollectDiagnostics(s.lspClient, fileuri.Normalize("file://"+path))[0]
+	default:
+		return nil, fmt.Errorf("unknown diagnostics resource: %s", uri)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode diagnostics: %v", err)
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      uri,
+		MIMEType: "application/json",
+		Text:     string(data),
+	}}, nil
+}
+
+// update records a file whose diagnostics changed, and schedules the notifications
+func (f *diagnosticsFeed) update(uri protocol.DocumentUri) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.changed[uri] = true
+	if f.timer == nil {
+		f.timer = time.AfterFunc(diagnosticsUpdateDelay, f.flush)
+	}
+}
+
+// flush notifies clients of the workspace resource and each changed file's resource
+func (f *diagnosticsFeed) flush() {
+	f.mu.Lock()
+	changed := f.changed
+	f.changed = make(map[protocol.DocumentUri]bool)
+	f.timer = nil
+	f.mu.Unlock()
+
+	f.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{
+		"uri": workspaceDiagnosticsURI,
+	})
+	for uri := range changed {
+		f.mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{
+			"uri": fileDiagnosticsPrefix + strings.TrimPrefix(string(uri), "file://"),
+		})
+	}
+}
+
+// diagnosticFilter reads the severity, sources and codes arguments of the
+// diagnostics tool
+func diagnosticFilter(arguments map[string]any) (tools.DiagnosticFilter, error) {
+	var filter tools.DiagnosticFilter
+	if severity, ok := arguments["severity"].(string); ok && severity != "" {
+		minSeverity, err := tools.ParseSeverity(severity)
+		if err != nil {
+			return filter, err
+		}
+		filter.MinSeverity = minSeverity
+	}
+	for _, name := range []string{"sources", "codes"} {
+		values, ok := arguments[name].([]any)
+		if !ok {
+			continue
+		}
+		for _, value := range values {
+			// Codes may be numbers or strings
+			text := strings.TrimSpace(fmt.Sprint(value))
+			if text == "" {
+				continue
+			}
+			if name == "sources" {
+				filter.Sources = append(filter.Sources, text)
+			} else {
+				filter.Codes = append(filter.Codes, text)
+			}
+		}
+	}
+	return filter, nil
+}
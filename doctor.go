@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// doctorReport prints the checks of the doctor command and counts the failures
+type doctorReport struct {
+	failures int
+}
+
+func (r *doctorReport) ok(format string, args ...any) {
+	fmt.Printf("[ok]   "+format+"\n", args...)
+}
+
+func (r *doctorReport) warn(format string, args ...any) {
+	fmt.Printf("[warn] "+format+"\n", args...)
+}
+
+func (r *doctorReport) fail(format string, args ...any) {
+	r.failures++
+	fmt.Printf("[fail] "+format+"\n", args...)
+}
+
+// runDoctor diagnoses common setup problems: a language server that cannot be
+// found or fails to start, unreadable workspaces, file watching that misses
+// changes and a server that is slow to become ready. Unlike check, it carries on
+// after problems, and prints the capabilities of the server when it starts.
+func runDoctor(args []string) error {
+	report := &doctorReport{}
+	fmt.Println(versionString())
+	fmt.Println()
+
+	config, err := parseConfig(args)
+	if err != nil {
+		report.fail("Configuration: %v", err)
+		if strings.HasPrefix(err.Error(), "LSP command not found") {
+			fmt.Println("       Install the language server, or give the full path to its binary.")
+			fmt.Println("       MCP clients may start servers with a different PATH than your shell.")
+		}
+		return fmt.Errorf("%d problem(s) found", report.failures)
+	}
+	report.ok("Configuration (%s)", describeConfig(config))
+	for _, warning := range config.configWarnings {
+		report.warn("%s", warning)
+	}
+
+	switch {
+	case config.indexOnly(), config.lspAddress != "":
+	case config.remote.Host != "":
+		report.ok("Language server %s is looked up on %s when it starts", config.lspCommand, config.remote.Host)
+	case config.container.Image != "":
+		report.ok("Language server %s is looked up in %s when it starts", config.lspCommand, config.container.Image)
+	default:
+		path, _ := exec.LookPath(config.lspCommand)
+		report.ok("Language server binary: %s", path)
+	}
+
+	for _, dir := range config.workspaceDirs {
+		if _, err := os.ReadDir(dir); err != nil {
+			report.fail("Workspace %s is not readable: %v", dir, err)
+			continue
+		}
+		report.ok("Workspace %s", dir)
+		if watcher.OnNetworkFilesystem(dir) {
+			if config.watch.Backend == watcher.BackendNative {
+				report.warn("%s is on a network file system: native watching misses changes made on other machines, use --watcher poll", dir)
+			} else {
+				report.ok("%s is on a network file system, so changes are found by polling", dir)
+			}
+		}
+	}
+	if report.failures > 0 {
+		return fmt.Errorf("%d problem(s) found", report.failures)
+	}
+
+	server, err := newServer(config)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer cleanup(server, done)
+
+	start := time.Now()
+	client, err := server.startClient()
+	if err != nil {
+		report.fail("Language server: %v", err)
+		return fmt.Errorf("%d problem(s) found", report.failures)
+	}
+	name, serverVersion := client.ServerInfo()
+	report.ok("Language server %s initialized in %v", strings.TrimSpace(name+" "+serverVersion), time.Since(start).Round(time.Millisecond))
+
+	// The wait gives up at the probe's timeout without an error
+	start = time.Now()
+	if err := client.WaitForServerReady(server.ctx); err != nil {
+		report.fail("Language server did not become ready: %v", err)
+	} else if elapsed := time.Since(start); !config.indexOnly() && elapsed >= config.readiness.Timeout() {
+		report.warn("Language server was not ready after %v: tools may answer before it finishes indexing, raise the readiness timeoutMs", elapsed.Round(time.Second))
+	} else {
+		report.ok("Language server ready in %v", elapsed.Round(time.Millisecond))
+	}
+
+	if err := server.registerMCP(); err != nil {
+		report.fail("Tools: %v", err)
+	} else {
+		fmt.Println()
+		fmt.Print(tools.Capabilities(tools.CollectCapabilities(client, server.toolSupport())))
+	}
+
+	if report.failures > 0 {
+		return fmt.Errorf("%d problem(s) found", report.failures)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Every tool takes a format argument. With "json", the tools that find locations and
+// symbols return them as JSON objects, and the other tools return their text in a
+// JSON object with a single text field, so clients can parse the result of any
+// tool. Errors are returned as text either way.
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// jsonTools have handlers that return structured results for format "json"
+var jsonTools = map[string]bool{
+	"definition":           true,
+	"declaration":          true,
+	"type_definition":      true,
+	"references":           true,
+	"find_implementations": true,
+	"workspace_symbols":    true,
+	"resolve_symbol":       true,
+	"grep":                 true,
+	"document_highlight":   true,
+	"diagnostics":          true,
+	"server_status":        true,
+}
+
+// textResult is the JSON object other tools return their text in. It also holds
+// the chunks of truncated JSON results, with the token that continues them.
+type textResult struct {
+	Text         string `json:"text"`
+	Continuation string `json:"continuation,omitempty"`
+}
+
+// withFormat adds the format argument to a tool, and wraps its handler to put the
+// text it returns in a JSON object when the tool has no structured results
+func withFormat(tool mcp.Tool, handler server.ToolHandlerFunc) (mcp.Tool, server.ToolHandlerFunc) {
+	description := "Return the result as text (\"text\") or as JSON (\"json\")."
+	if jsonTools[tool.Name] {
+		description += " JSON results list locations and symbols with one-indexed lines and columns."
+	} else {
+		description += " JSON results hold the text in a text field."
+	}
+	mcp.WithString("format",
+		mcp.Description(description),
+		mcp.Enum(formatText, formatJSON),
+		mcp.DefaultString(formatText),
+	)(&tool)
+
+	return tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		switch request.Params.Arguments["format"] {
+		case nil, "", formatText:
+			return handler(ctx, request)
+		case formatJSON:
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("format must be %q or %q", formatText, formatJSON)), nil
+		}
+
+		result, err := handler(ctx, request)
+		if err != nil || result == nil || result.IsError || jsonTools[tool.Name] {
+			return result, err
+		}
+		text := ""
+		for _, content := range result.Content {
+			if textContent, ok := content.(mcp.TextContent); ok {
+				text += textContent.Text
+			}
+		}
+		return jsonResult(textResult{Text: text})
+	}
+}
+
+// wantsJSON reports whether a tool was called with format "json"
+func wantsJSON(request mcp.CallToolRequest) bool {
+	return request.Params.Arguments["format"] == formatJSON
+}
+
+// jsonResult returns a value as the JSON result of a tool
+func jsonResult(value any) (*mcp.CallToolResult, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode result: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// handleHealth serves the state of the language server as JSON, with status 503
+// while it is not running or not initialized, for load balancers and operators
+func (s *mcpServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if s.lspClient == nil {
+		http.Error(w, "language server not started", http.StatusServiceUnavailable)
+		return
+	}
+
+	report := tools.CollectServerStatus(s.lspClient)
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		coreLogger.Error("Failed to write health report: %v", err)
+	}
+}
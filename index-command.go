@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/codeindex"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// runIndexCommand runs the index subcommand, which starts the language server as
+// serving would, exports the code intelligence of the primary workspace to an
+// LSIF or SCIP file and exits. It takes the flags of the server and its own.
+func runIndexCommand(args []string) error {
+	output := flag.String("output", "index.scip", "File to write the index to")
+	format := flag.String("format", "", "Index format: scip or lsif (defaults to lsif for .lsif and .json outputs and scip otherwise)")
+	include := flag.String("include", "", "Glob, relative to the workspace, limiting the files indexed")
+
+	config, err := parseConfig(args)
+	if err != nil {
+		return err
+	}
+	if config.indexOnly() {
+		return fmt.Errorf("the index command requires an LSP command or address")
+	}
+	// The export must come from the server, not from an earlier index
+	config.codeIndex = ""
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*output)) {
+		case ".lsif", ".json":
+			*format = codeindex.FormatLSIF
+		default:
+			*format = codeindex.FormatSCIP
+		}
+	}
+	var write func(io.Writer, *codeindex.Export) error
+	switch *format {
+	case codeindex.FormatLSIF:
+		write = codeindex.WriteLSIF
+	case codeindex.FormatSCIP:
+		write = codeindex.WriteSCIP
+	default:
+		return fmt.Errorf("unsupported format: %s (expected scip or lsif)", *format)
+	}
+	outputPath, err := filepath.Abs(*output)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for output: %v", err)
+	}
+
+	server, err := newServer(config)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer cleanup(server, done)
+	if err := server.initializeLSP(); err != nil {
+		return err
+	}
+	client := server.lspClient
+	if !client.ServerReady() {
+		if err := client.WaitForServerReady(server.ctx); err != nil {
+			return err
+		}
+	}
+
+	export, err := tools.ExportIndex(server.ctx, client, tools.ExportOptions{
+		Include: *include,
+		Progress: func(done, total int, path string) {
+			coreLogger.Info("Indexing %d/%d: %s", done+1, total, path)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export index: %v", err)
+	}
+
+	// Written next to the output and renamed, so a failed export leaves no partial index
+	file, err := os.CreateTemp(filepath.Dir(outputPath), filepath.Base(outputPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create index: %v", err)
+	}
+	defer os.Remove(file.Name())
+	if err := write(file, export); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+	if err := os.Rename(file.Name(), outputPath); err != nil {
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+
+	var occurrences int
+	for _, doc := range export.Documents {
+		occurrences += len(doc.Occurrences)
+	}
+	coreLogger.Info("Wrote %s index of %d occurrences in %d files to %s", *format, occurrences, len(export.Documents), outputPath)
+	return nil
+}
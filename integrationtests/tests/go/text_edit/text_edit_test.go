@@ -172,7 +172,7 @@ func AnotherFunction() {
 			}
 
 			// Call the ApplyTextEdits tool with the non-URL file path
-			result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, tc.edits)
+			result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, tc.edits, false)
 			if err != nil {
 				t.Fatalf("Failed to apply text edits: %v", err)
 			}
@@ -320,7 +320,7 @@ func NewFunction() {
 			}
 
 			// Call the ApplyTextEdits tool
-			result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, tc.edits)
+			result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, tc.edits, false)
 			if err != nil {
 				t.Fatalf("Failed to apply text edits: %v", err)
 			}
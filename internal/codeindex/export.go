@@ -0,0 +1,325 @@
+package codeindex
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// symbolScheme starts the IDs of exported symbols, followed by placeholders for
+// the package manager, name and version, which a language server does not report
+const symbolScheme = "mcp-language-server . . . "
+
+// Export is code intelligence gathered from a language server, to write as an
+// LSIF or SCIP index. Positions count UTF-16 code units.
+type Export struct {
+	// Root is the directory document paths are relative to
+	Root string
+	// ToolName and ToolVersion describe what produced the index
+	ToolName    string
+	ToolVersion string
+
+	Documents []*ExportDocument
+	// Symbols holds the hover text of symbols, keyed by ID
+	Symbols map[string]string
+}
+
+// ExportDocument is a file and the symbol occurrences in it
+type ExportDocument struct {
+	// Path is relative to the root, with forward slashes
+	Path        string
+	Language    string
+	Occurrences []ExportOccurrence
+}
+
+// ExportOccurrence is a range of a document that defines or refers to a symbol
+type ExportOccurrence struct {
+	Range      protocol.Range
+	Symbol     string
+	Definition bool
+}
+
+// SymbolID returns the ID of a symbol of a file, from the descriptors of its
+// enclosing symbols and its own, in the syntax of SCIP symbols
+func SymbolID(relPath string, descriptors ...string) string {
+	var id strings.Builder
+	id.WriteString(symbolScheme)
+	for _, segment := range strings.Split(relPath, "/") {
+		id.WriteString(escapeName(segment) + "/")
+	}
+	for _, descriptor := range descriptors {
+		id.WriteString(descriptor)
+	}
+	return id.String()
+}
+
+// Descriptor returns the descriptor of a symbol in an ID, which tells types,
+// methods, namespaces and terms apart. A method's disambiguator tells overloads
+// apart.
+func Descriptor(name string, kind protocol.SymbolKind, disambiguator string) string {
+	name = escapeName(name)
+	switch kind {
+	case protocol.Namespace, protocol.Module, protocol.Package, protocol.File:
+		return name + "/"
+	case protocol.Class, protocol.Struct, protocol.Interface, protocol.Enum:
+		return name + "#"
+	case protocol.TypeParameter:
+		return "[" + name + "]"
+	case protocol.Method, protocol.Function, protocol.Constructor, protocol.Operator:
+		return name + "(" + disambiguator + ")."
+	}
+	if disambiguator != "" {
+		return name + "(" + disambiguator + ")."
+	}
+	return name + "."
+}
+
+// escapeName quotes a name with backticks unless it is a simple identifier
+func escapeName(name string) string {
+	simple := name != ""
+	for _, r := range name {
+		if !(r == '_' || r == '+' || r == '-' || r == '$' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			simple = false
+			break
+		}
+	}
+	if simple {
+		return name
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// sortedSymbols returns the IDs of the symbols that occur in the export, sorted
+func (e *Export) sortedSymbols() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, doc := range e.Documents {
+		for _, occ := range doc.Occurrences {
+			if !seen[occ.Symbol] {
+				seen[occ.Symbol] = true
+				ids = append(ids, occ.Symbol)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// WriteLSIF writes an export as an LSIF dump of JSON lines
+func WriteLSIF(w io.Writer, e *Export) error {
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+	var nextID int
+	emit := func(element map[string]any) (int, error) {
+		nextID++
+		element["id"] = nextID
+		return nextID, enc.Encode(element)
+	}
+	vertex := func(label string, fields map[string]any) (int, error) {
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		fields["type"] = "vertex"
+		fields["label"] = label
+		return emit(fields)
+	}
+	edge := func(label string, outV int, fields map[string]any) error {
+		fields["type"] = "edge"
+		fields["label"] = label
+		fields["outV"] = outV
+		_, err := emit(fields)
+		return err
+	}
+
+	if _, err := vertex("metaData", map[string]any{
+		"version":          "0.6.0",
+		"projectRoot":      string(fileuri.FromPath(e.Root)),
+		"positionEncoding": string(protocol.UTF16),
+		"toolInfo":         map[string]any{"name": e.ToolName, "version": e.ToolVersion},
+	}); err != nil {
+		return err
+	}
+
+	// Result sets first, so ranges can point to them
+	resultSets := make(map[string]int)
+	for _, id := range e.sortedSymbols() {
+		resultSet, err := vertex("resultSet", nil)
+		if err != nil {
+			return err
+		}
+		resultSets[id] = resultSet
+	}
+
+	// Ranges of each symbol's definitions and references, by document ID
+	type ranges map[int][]int
+	definitions := make(map[string]ranges)
+	references := make(map[string]ranges)
+	for _, doc := range e.Documents {
+		document, err := vertex("document", map[string]any{
+			"uri":        string(fileuri.FromPath(filepath.Join(e.Root, filepath.FromSlash(doc.Path)))),
+			"languageId": doc.Language,
+		})
+		if err != nil {
+			return err
+		}
+		var contained []int
+		for _, occ := range doc.Occurrences {
+			rng, err := vertex("range", map[string]any{"start": occ.Range.Start, "end": occ.Range.End})
+			if err != nil {
+				return err
+			}
+			if err := edge("next", rng, map[string]any{"inV": resultSets[occ.Symbol]}); err != nil {
+				return err
+			}
+			contained = append(contained, rng)
+
+			results := references
+			if occ.Definition {
+				results = definitions
+			}
+			if results[occ.Symbol] == nil {
+				results[occ.Symbol] = make(ranges)
+			}
+			results[occ.Symbol][document] = append(results[occ.Symbol][document], rng)
+		}
+		if len(contained) > 0 {
+			if err := edge("contains", document, map[string]any{"inVs": contained}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// items adds the ranges of a result, one edge per document in order
+	items := func(result int, byDocument ranges, property string) error {
+		documents := make([]int, 0, len(byDocument))
+		for document := range byDocument {
+			documents = append(documents, document)
+		}
+		sort.Ints(documents)
+		for _, document := range documents {
+			fields := map[string]any{"inVs": byDocument[document], "document": document}
+			if property != "" {
+				fields["property"] = property
+			}
+			if err := edge("item", result, fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, id := range e.sortedSymbols() {
+		resultSet := resultSets[id]
+		if hover := e.Symbols[id]; hover != "" {
+			result, err := vertex("hoverResult", map[string]any{
+				"result": map[string]any{"contents": protocol.MarkupContent{Kind: protocol.Markdown, Value: hover}},
+			})
+			if err != nil {
+				return err
+			}
+			if err := edge("textDocument/hover", resultSet, map[string]any{"inV": result}); err != nil {
+				return err
+			}
+		}
+		if len(definitions[id]) > 0 {
+			result, err := vertex("definitionResult", nil)
+			if err != nil {
+				return err
+			}
+			if err := edge("textDocument/definition", resultSet, map[string]any{"inV": result}); err != nil {
+				return err
+			}
+			if err := items(result, definitions[id], ""); err != nil {
+				return err
+			}
+		}
+		result, err := vertex("referenceResult", nil)
+		if err != nil {
+			return err
+		}
+		if err := edge("textDocument/references", resultSet, map[string]any{"inV": result}); err != nil {
+			return err
+		}
+		if err := items(result, definitions[id], "definitions"); err != nil {
+			return err
+		}
+		if err := items(result, references[id], "references"); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// WriteSCIP writes an export as a SCIP index
+func WriteSCIP(w io.Writer, e *Export) error {
+	// Hovers are documented with the symbol in the document defining it
+	definedIn := make(map[string]*ExportDocument)
+	for _, doc := range e.Documents {
+		for _, occ := range doc.Occurrences {
+			if occ.Definition && definedIn[occ.Symbol] == nil {
+				definedIn[occ.Symbol] = doc
+			}
+		}
+	}
+
+	metadata := concat(
+		pbMessage(scipMetadataToolInfo, concat(
+			pbString(scipToolInfoName, e.ToolName),
+			pbString(scipToolInfoVersion, e.ToolVersion),
+		)),
+		pbString(scipMetadataProjectRoot, string(fileuri.FromPath(e.Root))),
+		pbVarint(scipMetadataTextEncoding, scipTextUTF8),
+	)
+	if _, err := w.Write(pbMessage(scipIndexMetadata, metadata)); err != nil {
+		return err
+	}
+
+	for _, doc := range e.Documents {
+		document := concat(
+			pbString(scipDocumentRelativePath, doc.Path),
+			pbString(scipDocumentLanguage, doc.Language),
+			pbVarint(scipDocumentPositionEncoding, scipUTF16),
+		)
+		for _, occ := range doc.Occurrences {
+			r := occ.Range
+			var numbers []uint64
+			if r.Start.Line == r.End.Line {
+				numbers = []uint64{uint64(r.Start.Line), uint64(r.Start.Character), uint64(r.End.Character)}
+			} else {
+				numbers = []uint64{uint64(r.Start.Line), uint64(r.Start.Character), uint64(r.End.Line), uint64(r.End.Character)}
+			}
+			occurrence := concat(pbPacked(scipOccurrenceRange, numbers...), pbString(scipOccurrenceSymbol, occ.Symbol))
+			if occ.Definition {
+				occurrence = append(occurrence, pbVarint(scipOccurrenceSymbolRoles, scipDefinitionRole)...)
+			}
+			document = append(document, pbMessage(scipDocumentOccurrences, occurrence)...)
+		}
+
+		var defined []string
+		for id, in := range definedIn {
+			if in == doc {
+				defined = append(defined, id)
+			}
+		}
+		sort.Strings(defined)
+		for _, id := range defined {
+			information := pbString(scipSymbolSymbol, id)
+			if hover := e.Symbols[id]; hover != "" {
+				information = append(information, pbString(scipSymbolDocumentation, hover)...)
+			}
+			document = append(document, pbMessage(scipDocumentSymbols, information)...)
+		}
+
+		if _, err := w.Write(pbMessage(scipIndexDocuments, document)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", doc.Path, err)
+		}
+	}
+	return nil
+}
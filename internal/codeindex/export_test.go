@@ -0,0 +1,58 @@
+package codeindex
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolID(t *testing.T) {
+	assert.Equal(t, "mcp-language-server . . . internal/lsp/`client.go`/Client#OpenFile().",
+		SymbolID("internal/lsp/client.go", Descriptor("Client", protocol.Struct, ""), Descriptor("OpenFile", protocol.Method, "")))
+	assert.Equal(t, "mcp-language-server . . . `main.go`/hello(+1).", SymbolID("main.go", Descriptor("hello", protocol.Function, "+1")))
+	assert.Equal(t, "mcp-language-server . . . `main.go`/`a b`.", SymbolID("main.go", Descriptor("a b", protocol.Variable, "")))
+}
+
+func TestWriteExport(t *testing.T) {
+	hello := SymbolID("main.go", Descriptor("hello", protocol.Function, ""))
+	newExport := func(root string) *Export {
+		return &Export{
+			Root:     root,
+			ToolName: "mcp-language-server",
+			Documents: []*ExportDocument{{
+				Path:     "main.go",
+				Language: "go",
+				Occurrences: []ExportOccurrence{
+					{Range: protocol.Range{Start: protocol.Position{Line: 2, Character: 5}, End: protocol.Position{Line: 2, Character: 10}}, Symbol: hello, Definition: true},
+					{Range: protocol.Range{Start: protocol.Position{Line: 4, Character: 23}, End: protocol.Position{Line: 4, Character: 28}}, Symbol: hello},
+				},
+			}},
+			Symbols: map[string]string{hello: "```go\nfunc hello()\n```\n\nhello says hello"},
+		}
+	}
+
+	for _, format := range []string{FormatLSIF, FormatSCIP} {
+		t.Run(format, func(t *testing.T) {
+			root, indexPath := writeWorkspace(t, "index."+format, nil)
+			var buf bytes.Buffer
+			if format == FormatLSIF {
+				require.NoError(t, WriteLSIF(&buf, newExport(root)))
+			} else {
+				require.NoError(t, WriteSCIP(&buf, newExport(root)))
+			}
+			require.NoError(t, os.WriteFile(indexPath, buf.Bytes(), 0o644))
+
+			// What is written loads back with the same answers
+			index, err := Load(indexPath, t.TempDir())
+			require.NoError(t, err)
+			assert.Equal(t, format, index.Format)
+			assert.Equal(t, "mcp-language-server", index.Tool)
+			testQueries(t, index, filepath.Join(root, "main.go"))
+		})
+	}
+}
@@ -0,0 +1,337 @@
+// Package codeindex loads precomputed LSIF and SCIP code intelligence indexes, such
+// as those produced in CI for large repositories, and answers definition,
+// references and hover queries from them.
+package codeindex
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// Index formats
+const (
+	FormatLSIF = "lsif"
+	FormatSCIP = "scip"
+)
+
+// Index is the code intelligence of a project at the time the index was produced.
+// Positions are kept in the encoding of the index and converted to the server's
+// encoding when queried.
+type Index struct {
+	// Format is lsif or scip, and Tool the indexer that produced it, if known
+	Format string
+	Tool   string
+	// Root is the directory the indexed paths are relative to
+	Root string
+	// Produced is when the index file was written. Files modified since are not
+	// answered from the index.
+	Produced time.Time
+
+	documents map[string]*document
+	symbols   map[string]*symbol
+}
+
+// document is an indexed file and the symbol occurrences in it, sorted by start
+type document struct {
+	encoding    protocol.PositionEncodingKind
+	occurrences []occurrence
+}
+
+// occurrence is a range of a document that refers to a symbol
+type occurrence struct {
+	rng        protocol.Range
+	symbol     string
+	definition bool
+}
+
+// symbol is what the index knows about one symbol
+type symbol struct {
+	hover       string
+	definitions []location
+	references  []location
+}
+
+// location is a range in an indexed file
+type location struct {
+	path string
+	rng  protocol.Range
+}
+
+func newIndex(format, root string) *Index {
+	return &Index{
+		Format:    format,
+		Root:      root,
+		documents: make(map[string]*document),
+		symbols:   make(map[string]*symbol),
+	}
+}
+
+// Load reads an LSIF or SCIP index, telling them apart by their content. Paths in
+// the index are resolved against its project root, or against root when the
+// index does not record one.
+func Load(path, root string) (*Index, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 1024*1024)
+	start, err := reader.Peek(64)
+	if err != nil && len(start) == 0 {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	var index *Index
+	// LSIF is JSON, as lines of vertices and edges or as one array
+	if trimmed := bytes.TrimLeft(start, " \t\r\n"); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		index, err = loadLSIF(reader, root)
+	} else {
+		index, err = loadSCIP(reader, root)
+	}
+	if err != nil {
+		return nil, err
+	}
+	index.Produced = info.ModTime()
+	index.finish()
+	return index, nil
+}
+
+// finish sorts the occurrences of each document so they can be searched
+func (x *Index) finish() {
+	for _, doc := range x.documents {
+		sort.SliceStable(doc.occurrences, func(i, j int) bool {
+			return positionLess(doc.occurrences[i].rng.Start, doc.occurrences[j].rng.Start)
+		})
+	}
+}
+
+// Documents returns how many files the index has
+func (x *Index) Documents() int {
+	return len(x.documents)
+}
+
+// Has reports whether a file is in the index
+func (x *Index) Has(path string) bool {
+	_, ok := x.documents[path]
+	return ok
+}
+
+// Changed reports whether a file was modified or removed since the index was
+// produced, so that its positions can no longer be trusted
+func (x *Index) Changed(path string) bool {
+	info, err := os.Stat(path)
+	return err != nil || info.ModTime().After(x.Produced)
+}
+
+// Definition returns the definitions of the symbol at a position, with positions in
+// the given encoding, the server's. It reports false when the index has no symbol
+// there.
+func (x *Index) Definition(path string, pos protocol.Position, encoding protocol.PositionEncodingKind) ([]protocol.Location, bool) {
+	lines := newLineCache()
+	sym, ok := x.symbolAt(lines, path, pos, encoding)
+	if !ok || len(sym.definitions) == 0 {
+		return nil, false
+	}
+	return x.locations(lines, sym.definitions, encoding), true
+}
+
+// References returns the references to the symbol at a position, with its
+// definitions when includeDeclaration is set. It reports false when the index has
+// no symbol there. Positions are in the given encoding, as for Definition.
+func (x *Index) References(path string, pos protocol.Position, includeDeclaration bool, encoding protocol.PositionEncodingKind) ([]protocol.Location, bool) {
+	lines := newLineCache()
+	sym, ok := x.symbolAt(lines, path, pos, encoding)
+	if !ok {
+		return nil, false
+	}
+	var locs []location
+	if includeDeclaration {
+		locs = append(locs, sym.definitions...)
+	}
+	locs = append(locs, sym.references...)
+	return x.locations(lines, locs, encoding), true
+}
+
+// Hover returns the documentation of the symbol at a position as markdown, with
+// the range of the occurrence. It reports false when the index has none.
+func (x *Index) Hover(path string, pos protocol.Position, encoding protocol.PositionEncodingKind) (*protocol.Hover, bool) {
+	lines := newLineCache()
+	occ, ok := x.occurrenceAt(lines, path, pos, encoding)
+	if !ok {
+		return nil, false
+	}
+	sym := x.symbols[occ.symbol]
+	if sym == nil || sym.hover == "" {
+		return nil, false
+	}
+	rng := x.locations(lines, []location{{path, occ.rng}}, encoding)[0].Range
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: sym.hover},
+		Range:    rng,
+	}, true
+}
+
+// Paths returns the files of locations, to check that none changed
+func Paths(locations []protocol.Location) []string {
+	seen := make(map[protocol.DocumentUri]bool)
+	var paths []string
+	for _, loc := range locations {
+		if !seen[loc.URI] {
+			seen[loc.URI] = true
+			paths = append(paths, loc.URI.Path())
+		}
+	}
+	return paths
+}
+
+// symbolAt returns the symbol occurring at a position
+func (x *Index) symbolAt(lines *lineCache, path string, pos protocol.Position, encoding protocol.PositionEncodingKind) (*symbol, bool) {
+	occ, ok := x.occurrenceAt(lines, path, pos, encoding)
+	if !ok {
+		return nil, false
+	}
+	sym, ok := x.symbols[occ.symbol]
+	return sym, ok
+}
+
+// occurrenceAt returns the innermost occurrence whose range contains a position
+// given in the server's encoding
+func (x *Index) occurrenceAt(lines *lineCache, path string, pos protocol.Position, encoding protocol.PositionEncodingKind) (occurrence, bool) {
+	doc, ok := x.documents[path]
+	if !ok {
+		return occurrence{}, false
+	}
+	// The position is converted to the index's encoding to compare it
+	pos.Character = utilities.ConvertCharacter(lines.line(path, pos.Line), pos.Character, encoding, doc.encoding)
+
+	var best occurrence
+	found := false
+	for _, occ := range doc.occurrences {
+		if occ.rng.Start.Line > pos.Line {
+			break
+		}
+		if !containsPosition(occ.rng, pos) {
+			continue
+		}
+		if !found || positionLess(best.rng.Start, occ.rng.Start) {
+			best = occ
+			found = true
+		}
+	}
+	return best, found
+}
+
+// locations converts indexed locations to protocol locations in the server's
+// encoding, sorted by file and position without duplicates
+func (x *Index) locations(lines *lineCache, locs []location, encoding protocol.PositionEncodingKind) []protocol.Location {
+	seen := make(map[location]bool)
+	result := []protocol.Location{}
+	for _, loc := range locs {
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+
+		from := protocol.UTF16
+		if doc, ok := x.documents[loc.path]; ok {
+			from = doc.encoding
+		}
+		rng := loc.rng
+		rng.Start.Character = utilities.ConvertCharacter(lines.line(loc.path, rng.Start.Line), rng.Start.Character, from, encoding)
+		rng.End.Character = utilities.ConvertCharacter(lines.line(loc.path, rng.End.Line), rng.End.Character, from, encoding)
+		result = append(result, protocol.Location{URI: fileuri.FromPath(loc.path), Range: rng})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].URI != result[j].URI {
+			return result[i].URI < result[j].URI
+		}
+		return positionLess(result[i].Range.Start, result[j].Range.Start)
+	})
+	return result
+}
+
+// symbolFor returns the symbol with an ID, adding it when it is new
+func (x *Index) symbolFor(id string) *symbol {
+	sym, ok := x.symbols[id]
+	if !ok {
+		sym = &symbol{}
+		x.symbols[id] = sym
+	}
+	return sym
+}
+
+// documentFor returns the document of a path, adding it when it is new
+func (x *Index) documentFor(path string, encoding protocol.PositionEncodingKind) *document {
+	doc, ok := x.documents[path]
+	if !ok {
+		doc = &document{encoding: encoding}
+		x.documents[path] = doc
+	}
+	return doc
+}
+
+// resolvePath returns the absolute path of a file of the index, given as a file
+// URI or relative to the root
+func (x *Index) resolvePath(name string) string {
+	if strings.HasPrefix(name, "file://") {
+		if uri, err := protocol.ParseDocumentUri(name); err == nil {
+			return uri.Path()
+		}
+	}
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(x.Root, filepath.FromSlash(name))
+}
+
+// lineCache reads the lines of files once per query, to convert positions
+type lineCache struct {
+	files map[string][]string
+}
+
+func newLineCache() *lineCache {
+	return &lineCache{files: make(map[string][]string)}
+}
+
+// line returns a line of a file, or "" when it cannot be read
+func (c *lineCache) line(path string, line uint32) string {
+	lines, ok := c.files[path]
+	if !ok {
+		if content, err := os.ReadFile(path); err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		c.files[path] = lines
+	}
+	if int(line) >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line], "\r")
+}
+
+func positionLess(a, b protocol.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
+
+// containsPosition reports whether a range contains a position, including its end
+// so that a position just after a name still finds it
+func containsPosition(r protocol.Range, p protocol.Position) bool {
+	return !positionLess(p, r.Start) && !positionLess(r.End, p)
+}
@@ -0,0 +1,158 @@
+package codeindex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSource = `package main
+
+func hello() {}
+
+func main() { _ = "é"; hello() }
+`
+
+const testLSIF = `{"id":1,"type":"vertex","label":"metaData","version":"0.4.3","projectRoot":"file://ROOT","positionEncoding":"utf-16","toolInfo":{"name":"lsif-go"}}
+{"id":2,"type":"vertex","label":"document","uri":"file://ROOT/main.go","languageId":"go"}
+{"id":3,"type":"vertex","label":"range","start":{"line":2,"character":5},"end":{"line":2,"character":10}}
+{"id":4,"type":"vertex","label":"range","start":{"line":4,"character":23},"end":{"line":4,"character":28}}
+{"id":5,"type":"vertex","label":"resultSet"}
+{"id":6,"type":"edge","label":"next","outV":3,"inV":5}
+{"id":7,"type":"edge","label":"next","outV":4,"inV":5}
+{"id":8,"type":"vertex","label":"definitionResult"}
+{"id":9,"type":"edge","label":"textDocument/definition","outV":5,"inV":8}
+{"id":10,"type":"edge","label":"item","outV":8,"inVs":[3],"document":2}
+{"id":11,"type":"vertex","label":"referenceResult"}
+{"id":12,"type":"edge","label":"textDocument/references","outV":5,"inV":11}
+{"id":13,"type":"edge","label":"item","outV":11,"inVs":[3],"document":2,"property":"definitions"}
+{"id":14,"type":"edge","label":"item","outV":11,"inVs":[4],"document":2,"property":"references"}
+{"id":15,"type":"vertex","label":"hoverResult","result":{"contents":[{"language":"go","value":"func hello()"},"hello says hello"]}}
+{"id":16,"type":"edge","label":"textDocument/hover","outV":5,"inV":15}
+{"id":17,"type":"edge","label":"contains","outV":2,"inVs":[3,4]}
+`
+
+// writeWorkspace writes the test source, older than the index written after it
+func writeWorkspace(t *testing.T, name string, index []byte) (string, string) {
+	root := t.TempDir()
+	source := filepath.Join(root, "main.go")
+	require.NoError(t, os.WriteFile(source, []byte(testSource), 0o644))
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(source, past, past))
+	indexPath := filepath.Join(root, name)
+	require.NoError(t, os.WriteFile(indexPath, index, 0o644))
+	return root, indexPath
+}
+
+func TestLoadLSIF(t *testing.T) {
+	root, indexPath := writeWorkspace(t, "dump.lsif", nil)
+	require.NoError(t, os.WriteFile(indexPath, []byte(strings.ReplaceAll(testLSIF, "ROOT", filepath.ToSlash(root))), 0o644))
+
+	index, err := Load(indexPath, root)
+	require.NoError(t, err)
+	assert.Equal(t, FormatLSIF, index.Format)
+	assert.Equal(t, "lsif-go", index.Tool)
+	assert.Equal(t, 1, index.Documents())
+	testQueries(t, index, filepath.Join(root, "main.go"))
+}
+
+func TestLoadLSIFArray(t *testing.T) {
+	root, indexPath := writeWorkspace(t, "dump.json", nil)
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(testLSIF, "ROOT", filepath.ToSlash(root))), "\n")
+	require.NoError(t, os.WriteFile(indexPath, []byte("[\n"+strings.Join(lines, ",\n")+"\n]\n"), 0o644))
+
+	index, err := Load(indexPath, root)
+	require.NoError(t, err)
+	testQueries(t, index, filepath.Join(root, "main.go"))
+}
+
+func TestLoadSCIP(t *testing.T) {
+	helloSymbol := "scip-go gomod example v1 `example`/hello()."
+	document := concat(
+		pbString(scipDocumentRelativePath, "main.go"),
+		pbMessage(scipDocumentOccurrences, concat(
+			pbPacked(scipOccurrenceRange, 2, 5, 10),
+			pbString(scipOccurrenceSymbol, helloSymbol),
+			pbVarint(scipOccurrenceSymbolRoles, scipDefinitionRole),
+		)),
+		// Positions of this document count UTF-8 bytes, so é takes two
+		pbMessage(scipDocumentOccurrences, concat(
+			pbPacked(scipOccurrenceRange, 4, 24, 29),
+			pbString(scipOccurrenceSymbol, helloSymbol),
+		)),
+		pbMessage(scipDocumentOccurrences, concat(
+			pbPacked(scipOccurrenceRange, 4, 14, 4, 15),
+			pbString(scipOccurrenceSymbol, "local 0"),
+		)),
+		pbMessage(scipDocumentSymbols, concat(
+			pbString(scipSymbolSymbol, helloSymbol),
+			pbString(scipSymbolDocumentation, "hello says hello"),
+			pbMessage(scipSymbolSignatureDocumentation, concat(
+				pbString(scipSignatureLanguage, "Go"),
+				pbString(scipSignatureText, "func hello()"),
+			)),
+		)),
+		pbVarint(scipDocumentPositionEncoding, scipUTF8),
+	)
+
+	root, indexPath := writeWorkspace(t, "index.scip", nil)
+	data := concat(
+		pbMessage(scipIndexMetadata, concat(
+			pbMessage(scipMetadataToolInfo, pbString(scipToolInfoName, "scip-go")),
+			pbString(scipMetadataProjectRoot, "file://"+filepath.ToSlash(root)),
+		)),
+		pbMessage(scipIndexDocuments, document),
+	)
+	require.NoError(t, os.WriteFile(indexPath, data, 0o644))
+
+	index, err := Load(indexPath, t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, FormatSCIP, index.Format)
+	assert.Equal(t, "scip-go", index.Tool)
+	assert.Equal(t, root, index.Root, "the project root of the index is used")
+	testQueries(t, index, filepath.Join(root, "main.go"))
+}
+
+// testQueries checks the answers for the test source, with positions in UTF-16
+func testQueries(t *testing.T, index *Index, path string) {
+	t.Helper()
+	require.True(t, index.Has(path))
+	assert.False(t, index.Changed(path))
+
+	definition := protocol.Range{Start: protocol.Position{Line: 2, Character: 5}, End: protocol.Position{Line: 2, Character: 10}}
+	reference := protocol.Range{Start: protocol.Position{Line: 4, Character: 23}, End: protocol.Position{Line: 4, Character: 28}}
+
+	// From the call, after the multi-byte character
+	locations, ok := index.Definition(path, protocol.Position{Line: 4, Character: 25}, protocol.UTF16)
+	require.True(t, ok)
+	require.Len(t, locations, 1)
+	assert.Equal(t, definition, locations[0].Range)
+	assert.Equal(t, path, locations[0].URI.Path())
+
+	locations, ok = index.References(path, protocol.Position{Line: 2, Character: 6}, false, protocol.UTF16)
+	require.True(t, ok)
+	require.Len(t, locations, 1)
+	assert.Equal(t, reference, locations[0].Range)
+
+	locations, ok = index.References(path, protocol.Position{Line: 2, Character: 6}, true, protocol.UTF16)
+	require.True(t, ok)
+	assert.Len(t, locations, 2)
+
+	hover, ok := index.Hover(path, protocol.Position{Line: 4, Character: 23}, protocol.UTF16)
+	require.True(t, ok)
+	assert.Equal(t, "```go\nfunc hello()\n```\n\nhello says hello", hover.Contents.Value)
+	assert.Equal(t, reference, hover.Range)
+
+	_, ok = index.Definition(path, protocol.Position{Line: 0, Character: 2}, protocol.UTF16)
+	assert.False(t, ok, "no symbol on the package clause")
+
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, future, future))
+	assert.True(t, index.Changed(path))
+}
@@ -0,0 +1,337 @@
+package codeindex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// lsifElement is a vertex or an edge of an LSIF graph, with the fields of the
+// labels that are used
+type lsifElement struct {
+	ID    json.RawMessage `json:"id"`
+	Type  string          `json:"type"`
+	Label string          `json:"label"`
+
+	// metaData and document vertices
+	ProjectRoot      string `json:"projectRoot"`
+	PositionEncoding string `json:"positionEncoding"`
+	ToolInfo         *struct {
+		Name string `json:"name"`
+	} `json:"toolInfo"`
+	URI string `json:"uri"`
+
+	// range vertices
+	Start *protocol.Position `json:"start"`
+	End   *protocol.Position `json:"end"`
+
+	// hoverResult vertices
+	Result *struct {
+		Contents json.RawMessage `json:"contents"`
+	} `json:"result"`
+
+	// Edges
+	OutV     json.RawMessage   `json:"outV"`
+	InV      json.RawMessage   `json:"inV"`
+	InVs     []json.RawMessage `json:"inVs"`
+	Document json.RawMessage   `json:"document"`
+	Shard    json.RawMessage   `json:"shard"`
+	Property string            `json:"property"`
+}
+
+// lsifItem is an item edge, listing the ranges of a definition or reference result
+type lsifItem struct {
+	ranges   []string
+	document string
+	property string
+}
+
+// lsifGraph is the part of an LSIF graph that queries need
+type lsifGraph struct {
+	root     string
+	encoding protocol.PositionEncodingKind
+	tool     string
+
+	documents map[string]string
+	ranges    map[string]protocol.Range
+	contains  map[string][]string
+	next      map[string]string
+
+	definitionEdges map[string]string
+	referenceEdges  map[string]string
+	hoverEdges      map[string]string
+	hovers          map[string]string
+	items           map[string][]lsifItem
+}
+
+// loadLSIF reads an LSIF dump, given as JSON lines or as a JSON array
+func loadLSIF(r *bufio.Reader, root string) (*Index, error) {
+	g := &lsifGraph{
+		root:            root,
+		encoding:        protocol.UTF16,
+		documents:       make(map[string]string),
+		ranges:          make(map[string]protocol.Range),
+		contains:        make(map[string][]string),
+		next:            make(map[string]string),
+		definitionEdges: make(map[string]string),
+		referenceEdges:  make(map[string]string),
+		hoverEdges:      make(map[string]string),
+		hovers:          make(map[string]string),
+		items:           make(map[string][]lsifItem),
+	}
+
+	dec := json.NewDecoder(r)
+	start, _ := r.Peek(64)
+	array := bytes.HasPrefix(bytes.TrimLeft(start, " \t\r\n"), []byte("["))
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("invalid LSIF: %w", err)
+		}
+	}
+	for !array || dec.More() {
+		var element lsifElement
+		err := dec.Decode(&element)
+		if err == io.EOF && !array {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid LSIF: %w", err)
+		}
+		g.add(&element)
+	}
+	return g.index(), nil
+}
+
+// add records a vertex or an edge
+func (g *lsifGraph) add(e *lsifElement) {
+	id := lsifID(e.ID)
+	switch e.Label {
+	case "metaData":
+		if e.ProjectRoot != "" {
+			g.root = e.ProjectRoot
+		}
+		if e.PositionEncoding != "" {
+			g.encoding = protocol.PositionEncodingKind(e.PositionEncoding)
+		}
+		if e.ToolInfo != nil {
+			g.tool = e.ToolInfo.Name
+		}
+	case "document":
+		g.documents[id] = e.URI
+	case "range":
+		if e.Start != nil && e.End != nil {
+			g.ranges[id] = protocol.Range{Start: *e.Start, End: *e.End}
+		}
+	case "hoverResult":
+		if e.Result != nil {
+			g.hovers[id] = hoverText(e.Result.Contents)
+		}
+	case "contains":
+		for _, in := range e.InVs {
+			g.contains[lsifID(e.OutV)] = append(g.contains[lsifID(e.OutV)], lsifID(in))
+		}
+	case "next":
+		g.next[lsifID(e.OutV)] = lsifID(e.InV)
+	case "textDocument/definition":
+		g.definitionEdges[lsifID(e.OutV)] = lsifID(e.InV)
+	case "textDocument/references":
+		g.referenceEdges[lsifID(e.OutV)] = lsifID(e.InV)
+	case "textDocument/hover":
+		g.hoverEdges[lsifID(e.OutV)] = lsifID(e.InV)
+	case "item":
+		item := lsifItem{document: lsifID(e.Document), property: e.Property}
+		if item.document == "" {
+			item.document = lsifID(e.Shard)
+		}
+		for _, in := range e.InVs {
+			item.ranges = append(item.ranges, lsifID(in))
+		}
+		g.items[lsifID(e.OutV)] = append(g.items[lsifID(e.OutV)], item)
+	}
+}
+
+// index builds the index from the graph. Ranges that lead to the same result set
+// through next edges are occurrences of the same symbol.
+func (g *lsifGraph) index() *Index {
+	x := newIndex(FormatLSIF, "")
+	x.Tool = g.tool
+	x.Root = g.root
+	if strings.HasPrefix(g.root, "file://") {
+		if uri, err := protocol.ParseDocumentUri(g.root); err == nil {
+			x.Root = uri.Path()
+		}
+	}
+
+	symbols := make(map[string]*symbol)
+	for docID, uri := range g.documents {
+		doc := x.documentFor(x.resolvePath(uri), g.encoding)
+		for _, rangeID := range g.contains[docID] {
+			rng, ok := g.ranges[rangeID]
+			if !ok {
+				continue
+			}
+			chain := g.chain(rangeID)
+			key := "lsif:" + chain[len(chain)-1]
+			sym, ok := symbols[key]
+			if !ok {
+				sym = g.symbol(x, chain)
+				symbols[key] = sym
+				x.symbols[key] = sym
+			}
+			doc.occurrences = append(doc.occurrences, occurrence{
+				rng:        rng,
+				symbol:     key,
+				definition: hasLocation(sym.definitions, location{x.resolvePath(uri), rng}),
+			})
+		}
+	}
+	return x
+}
+
+// chain returns a vertex followed by the result sets its next edges lead to
+func (g *lsifGraph) chain(id string) []string {
+	chain := []string{id}
+	seen := map[string]bool{id: true}
+	for {
+		next, ok := g.next[id]
+		if !ok || seen[next] {
+			return chain
+		}
+		seen[next] = true
+		chain = append(chain, next)
+		id = next
+	}
+}
+
+// symbol collects the results attached to the first vertex of a chain that has
+// each kind of result
+func (g *lsifGraph) symbol(x *Index, chain []string) *symbol {
+	sym := &symbol{}
+	first := func(edges map[string]string) (string, bool) {
+		for _, id := range chain {
+			if result, ok := edges[id]; ok {
+				return result, true
+			}
+		}
+		return "", false
+	}
+
+	if result, ok := first(g.definitionEdges); ok {
+		sym.definitions = g.itemLocations(x, result, "")
+	}
+	if result, ok := first(g.referenceEdges); ok {
+		seen := make(map[string]bool)
+		var collect func(result string)
+		collect = func(result string) {
+			if seen[result] {
+				return
+			}
+			seen[result] = true
+			for _, item := range g.items[result] {
+				switch item.property {
+				case "referenceResults":
+					for _, other := range item.ranges {
+						collect(other)
+					}
+				case "definitions":
+					for _, loc := range g.locations(x, item) {
+						if !hasLocation(sym.definitions, loc) {
+							sym.definitions = append(sym.definitions, loc)
+						}
+					}
+				default:
+					sym.references = append(sym.references, g.locations(x, item)...)
+				}
+			}
+		}
+		collect(result)
+	}
+	if result, ok := first(g.hoverEdges); ok {
+		sym.hover = g.hovers[result]
+	}
+	return sym
+}
+
+// itemLocations returns the locations of the items of a result
+func (g *lsifGraph) itemLocations(x *Index, result string, property string) []location {
+	var locs []location
+	for _, item := range g.items[result] {
+		if property == "" || item.property == property {
+			locs = append(locs, g.locations(x, item)...)
+		}
+	}
+	return locs
+}
+
+// locations returns the ranges of an item as locations in its document
+func (g *lsifGraph) locations(x *Index, item lsifItem) []location {
+	uri, ok := g.documents[item.document]
+	if !ok {
+		return nil
+	}
+	path := x.resolvePath(uri)
+	var locs []location
+	for _, id := range item.ranges {
+		if rng, ok := g.ranges[id]; ok {
+			locs = append(locs, location{path, rng})
+		}
+	}
+	return locs
+}
+
+// lsifID returns the ID of an element, which may be a number or a string, as a
+// string
+func lsifID(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// hoverText converts the contents of a hover result, a marked string, markup
+// content or a list of marked strings, to markdown
+func hoverText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		var parts []string
+		for _, item := range list {
+			if part := hoverText(item); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+
+	var content struct {
+		Kind     string `json:"kind"`
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return ""
+	}
+	if content.Language != "" {
+		return "```" + content.Language + "\n" + content.Value + "\n```"
+	}
+	return content.Value
+}
+
+func hasLocation(locs []location, loc location) bool {
+	for _, l := range locs {
+		if l == loc {
+			return true
+		}
+	}
+	return false
+}
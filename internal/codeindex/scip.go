@@ -0,0 +1,354 @@
+package codeindex
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SCIP indexes are protocol buffers. Only the fields queries need are decoded, by
+// their numbers in scip.proto, and everything else is skipped.
+const (
+	scipIndexMetadata        = 1
+	scipIndexDocuments       = 2
+	scipIndexExternalSymbols = 3
+
+	scipMetadataToolInfo     = 2
+	scipMetadataProjectRoot  = 3
+	scipMetadataTextEncoding = 4
+	scipToolInfoName         = 1
+	scipToolInfoVersion      = 2
+
+	scipDocumentRelativePath     = 1
+	scipDocumentOccurrences      = 2
+	scipDocumentSymbols          = 3
+	scipDocumentLanguage         = 4
+	scipDocumentPositionEncoding = 6
+
+	scipOccurrenceRange       = 1
+	scipOccurrenceSymbol      = 2
+	scipOccurrenceSymbolRoles = 3
+
+	scipSymbolSymbol                 = 1
+	scipSymbolDocumentation          = 3
+	scipSymbolSignatureDocumentation = 7
+	scipSignatureText                = 5
+	scipSignatureLanguage            = 4
+
+	// scipDefinitionRole is the bit of symbol roles marking a definition
+	scipDefinitionRole = 0x1
+)
+
+// SCIP position encodings, and the text encoding of metadata
+const (
+	scipUTF8  = 1
+	scipUTF16 = 2
+	scipUTF32 = 3
+
+	scipTextUTF8 = 1
+)
+
+var errTruncated = errors.New("truncated message")
+
+// loadSCIP reads a SCIP index
+func loadSCIP(r io.Reader, root string) (*Index, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	x := newIndex(FormatSCIP, root)
+	var documents, external [][]byte
+	err = scipFields(data, func(field int, value []byte, _ uint64) error {
+		switch field {
+		case scipIndexMetadata:
+			return x.scipMetadata(value)
+		case scipIndexDocuments:
+			documents = append(documents, value)
+		case scipIndexExternalSymbols:
+			external = append(external, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCIP: %w", err)
+	}
+
+	// Documents are read after the metadata, which has the root they are relative to
+	for _, value := range documents {
+		if err := x.scipDocument(value); err != nil {
+			return nil, fmt.Errorf("invalid SCIP: %w", err)
+		}
+	}
+	for _, value := range external {
+		if err := x.scipSymbol(value, ""); err != nil {
+			return nil, fmt.Errorf("invalid SCIP: %w", err)
+		}
+	}
+	return x, nil
+}
+
+func (x *Index) scipMetadata(data []byte) error {
+	return scipFields(data, func(field int, value []byte, _ uint64) error {
+		switch field {
+		case scipMetadataToolInfo:
+			return scipFields(value, func(field int, value []byte, _ uint64) error {
+				if field == scipToolInfoName {
+					x.Tool = string(value)
+				}
+				return nil
+			})
+		case scipMetadataProjectRoot:
+			if root := x.resolvePath(string(value)); root != "" {
+				x.Root = root
+			}
+		}
+		return nil
+	})
+}
+
+func (x *Index) scipDocument(data []byte) error {
+	var path string
+	var occurrences, symbols [][]byte
+	encoding := protocol.UTF16
+	err := scipFields(data, func(field int, value []byte, number uint64) error {
+		switch field {
+		case scipDocumentRelativePath:
+			path = string(value)
+		case scipDocumentOccurrences:
+			occurrences = append(occurrences, value)
+		case scipDocumentSymbols:
+			symbols = append(symbols, value)
+		case scipDocumentPositionEncoding:
+			switch number {
+			case scipUTF8:
+				encoding = protocol.UTF8
+			case scipUTF32:
+				encoding = protocol.UTF32
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("document without a path")
+	}
+
+	path = x.resolvePath(path)
+	doc := x.documentFor(path, encoding)
+	for _, value := range occurrences {
+		occ, err := scipOccurrence(value, path)
+		if err != nil {
+			return err
+		}
+		if occ.symbol == "" {
+			continue
+		}
+		doc.occurrences = append(doc.occurrences, occ)
+
+		loc := location{path, occ.rng}
+		sym := x.symbolFor(occ.symbol)
+		if occ.definition {
+			sym.definitions = append(sym.definitions, loc)
+		} else {
+			sym.references = append(sym.references, loc)
+		}
+	}
+	for _, value := range symbols {
+		if err := x.scipSymbol(value, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scipOccurrence(data []byte, path string) (occurrence, error) {
+	var occ occurrence
+	var numbers []int32
+	err := scipFields(data, func(field int, value []byte, number uint64) error {
+		switch field {
+		case scipOccurrenceRange:
+			// Packed, or one element per field in older writers
+			if value == nil {
+				numbers = append(numbers, int32(number))
+				return nil
+			}
+			for len(value) > 0 {
+				n, size := binary.Uvarint(value)
+				if size <= 0 {
+					return errTruncated
+				}
+				numbers = append(numbers, int32(n))
+				value = value[size:]
+			}
+		case scipOccurrenceSymbol:
+			occ.symbol = scipSymbolID(string(value), path)
+		case scipOccurrenceSymbolRoles:
+			occ.definition = number&scipDefinitionRole != 0
+		}
+		return nil
+	})
+	if err != nil {
+		return occ, err
+	}
+
+	// Ranges are [startLine, startCharacter, endLine, endCharacter], or three
+	// elements when they start and end on the same line
+	switch len(numbers) {
+	case 3:
+		occ.rng = scipRange(numbers[0], numbers[1], numbers[0], numbers[2])
+	case 4:
+		occ.rng = scipRange(numbers[0], numbers[1], numbers[2], numbers[3])
+	default:
+		return occ, fmt.Errorf("occurrence with a range of %d elements", len(numbers))
+	}
+	return occ, nil
+}
+
+// scipSymbol reads the documentation of a symbol for hovers
+func (x *Index) scipSymbol(data []byte, path string) error {
+	var id, signature, language string
+	var documentation []string
+	err := scipFields(data, func(field int, value []byte, _ uint64) error {
+		switch field {
+		case scipSymbolSymbol:
+			id = scipSymbolID(string(value), path)
+		case scipSymbolDocumentation:
+			documentation = append(documentation, string(value))
+		case scipSymbolSignatureDocumentation:
+			return scipFields(value, func(field int, value []byte, _ uint64) error {
+				switch field {
+				case scipSignatureText:
+					signature = string(value)
+				case scipSignatureLanguage:
+					language = string(value)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil || id == "" {
+		return err
+	}
+
+	var parts []string
+	if signature != "" {
+		parts = append(parts, "```"+strings.ToLower(language)+"\n"+signature+"\n```")
+	}
+	parts = append(parts, documentation...)
+	if len(parts) > 0 {
+		x.symbolFor(id).hover = strings.Join(parts, "\n\n")
+	}
+	return nil
+}
+
+// scipSymbolID returns the key of a symbol. Local symbols are only unique within
+// their document.
+func scipSymbolID(symbol, path string) string {
+	if strings.HasPrefix(symbol, "local ") {
+		return path + "#" + symbol
+	}
+	return symbol
+}
+
+func scipRange(startLine, startCharacter, endLine, endCharacter int32) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(startLine), Character: uint32(startCharacter)},
+		End:   protocol.Position{Line: uint32(endLine), Character: uint32(endCharacter)},
+	}
+}
+
+// scipFields calls fn with each field of a protocol buffer message: the bytes of
+// length-delimited fields, or the number of varint and fixed-size fields
+func scipFields(data []byte, fn func(field int, value []byte, number uint64) error) error {
+	for len(data) > 0 {
+		key, size := binary.Uvarint(data)
+		if size <= 0 {
+			return errTruncated
+		}
+		data = data[size:]
+		field := int(key >> 3)
+
+		var value []byte
+		var number uint64
+		switch key & 7 {
+		case 0: // varint
+			number, size = binary.Uvarint(data)
+			if size <= 0 {
+				return errTruncated
+			}
+			data = data[size:]
+		case 1: // 64-bit
+			if len(data) < 8 {
+				return errTruncated
+			}
+			number = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case 2: // length-delimited
+			length, size := binary.Uvarint(data)
+			if size <= 0 || uint64(len(data)-size) < length {
+				return errTruncated
+			}
+			value = data[size : size+int(length)]
+			data = data[size+int(length):]
+		case 5: // 32-bit
+			if len(data) < 4 {
+				return errTruncated
+			}
+			number = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", key&7)
+		}
+
+		if err := fn(field, value, number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func concat(parts ...[]byte) []byte {
+	var data []byte
+	for _, part := range parts {
+		data = append(data, part...)
+	}
+	return data
+}
+
+// pbVarint encodes a varint field
+func pbVarint(field int, value uint64) []byte {
+	data := binary.AppendUvarint(nil, uint64(field)<<3)
+	return binary.AppendUvarint(data, value)
+}
+
+// pbMessage encodes a length-delimited field
+func pbMessage(field int, value []byte) []byte {
+	data := binary.AppendUvarint(nil, uint64(field)<<3|2)
+	data = binary.AppendUvarint(data, uint64(len(value)))
+	return append(data, value...)
+}
+
+// pbString encodes a string field, omitting empty strings as protocol buffers do
+func pbString(field int, value string) []byte {
+	if value == "" {
+		return nil
+	}
+	return pbMessage(field, []byte(value))
+}
+
+// pbPacked encodes a packed repeated varint field
+func pbPacked(field int, values ...uint64) []byte {
+	var packed []byte
+	for _, value := range values {
+		packed = binary.AppendUvarint(packed, value)
+	}
+	return pbMessage(field, packed)
+}
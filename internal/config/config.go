@@ -0,0 +1,520 @@
+// Package config resolves the settings of the server from the command line, from
+// MCP_LS_* environment variables and from a JSON config file.
+//
+// A setting given on the command line takes precedence over its environment
+// variable, which takes precedence over the config file, which takes precedence
+// over the default. Errors name the flag, variable or config file key they came
+// from.
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix starts the environment variable of every setting
+const EnvPrefix = "MCP_LS_"
+
+// Kind is the type of a setting's value
+type Kind int
+
+const (
+	// String is a string
+	String Kind = iota
+	// Path is a path, which is relative to the config file's directory when
+	// given there
+	Path
+	// Bool is true or false
+	Bool
+	// Int is an integer
+	Int
+	// List is a list of strings. Its flag may be repeated and takes comma
+	// separated values, as does its environment variable.
+	List
+	// Repeated is a list of strings whose flag is repeated with one value each.
+	// Its environment variable separates them with commas.
+	Repeated
+	// PathList is a list of paths. Its flag is repeated with one path each, and
+	// its environment variable separates paths like PATH does.
+	PathList
+	// Args is the list of arguments after -- on the command line, separated by
+	// spaces in its environment variable
+	Args
+)
+
+// Source is where the value of a setting came from
+type Source int
+
+const (
+	Default Source = iota
+	File
+	Env
+	Flag
+)
+
+func (s Source) String() string {
+	switch s {
+	case File:
+		return "config file"
+	case Env:
+		return "environment"
+	case Flag:
+		return "command line"
+	default:
+		return "default"
+	}
+}
+
+// Setting is one setting, and the flag, environment variable and config file key
+// it can be given by
+type Setting struct {
+	// Key is the config file key, with dots between the keys of nested objects
+	// as in watcher.exclude. Settings without one are not read from the file.
+	Key string
+	// Flag is the flag name. Settings without one, other than Args, are not read
+	// from the command line.
+	Flag string
+	Kind Kind
+	// Source is where the value came from once the set is parsed
+	Source Source
+
+	target  any
+	choices []string
+	minimum *int
+}
+
+// Env returns the environment variable of the setting, MCP_LS_ followed by its
+// flag, or its key when it has no flag, in upper snake case
+func (s *Setting) Env() string {
+	name := s.Flag
+	if name == "" {
+		name = s.Key
+	}
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	for i, r := range name {
+		switch {
+		case r == '-' || r == '.':
+			b.WriteByte('_')
+		case unicode.IsUpper(r):
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// OneOf limits a string setting to the given values. The empty string, meaning
+// the default, is always accepted.
+func (s *Setting) OneOf(values ...string) *Setting {
+	s.choices = values
+	return s
+}
+
+// NonNegative rejects negative values of an integer setting
+func (s *Setting) NonNegative() *Setting {
+	zero := 0
+	s.minimum = &zero
+	return s
+}
+
+// origin names where the value of the setting was given, for errors
+func (s *Setting) origin() string {
+	switch s.Source {
+	case Flag:
+		if s.Kind == Args {
+			return "LSP arguments"
+		}
+		return "--" + s.Flag
+	case Env:
+		return s.Env()
+	case File:
+		return "config file key " + s.Key
+	default:
+		if s.Key != "" {
+			return s.Key
+		}
+		return "--" + s.Flag
+	}
+}
+
+// validate checks the value against the setting's limits
+func (s *Setting) validate() error {
+	switch target := s.target.(type) {
+	case *string:
+		if len(s.choices) > 0 && *target != "" && !slices.Contains(s.choices, *target) {
+			return fmt.Errorf("%s: unsupported value %q (expected %s)", s.origin(), *target, strings.Join(s.choices, ", "))
+		}
+	case *int:
+		if s.minimum != nil && *target < *s.minimum {
+			return fmt.Errorf("%s: must not be less than %d", s.origin(), *s.minimum)
+		}
+	}
+	return nil
+}
+
+// Set is the settings of a command, registered with their flags on a flag set
+type Set struct {
+	flags    *flag.FlagSet
+	settings []*Setting
+	// configFile is the setting holding the path of the config file
+	configFile *Setting
+	// entries describes the config file entries that are not settings
+	entries  *Schema
+	warnings []string
+	sections []string
+}
+
+// NewSet returns a set registering the flags of its settings on flags
+func NewSet(flags *flag.FlagSet) *Set {
+	return &Set{flags: flags}
+}
+
+func (s *Set) add(setting *Setting) *Setting {
+	s.settings = append(s.settings, setting)
+	return setting
+}
+
+// StringVar defines a string setting
+func (s *Set) StringVar(p *string, key, name, value, usage string) *Setting {
+	if name != "" {
+		s.flags.StringVar(p, name, value, usage)
+	} else {
+		*p = value
+	}
+	return s.add(&Setting{Key: key, Flag: name, Kind: String, target: p})
+}
+
+// PathVar defines a path setting
+func (s *Set) PathVar(p *string, key, name, value, usage string) *Setting {
+	setting := s.StringVar(p, key, name, value, usage)
+	setting.Kind = Path
+	return setting
+}
+
+// BoolVar defines a boolean setting
+func (s *Set) BoolVar(p *bool, key, name string, value bool, usage string) *Setting {
+	s.flags.BoolVar(p, name, value, usage)
+	return s.add(&Setting{Key: key, Flag: name, Kind: Bool, target: p})
+}
+
+// IntVar defines an integer setting
+func (s *Set) IntVar(p *int, key, name string, value int, usage string) *Setting {
+	s.flags.IntVar(p, name, value, usage)
+	return s.add(&Setting{Key: key, Flag: name, Kind: Int, target: p})
+}
+
+// ListVar defines a list setting with a comma separated, repeatable flag
+func (s *Set) ListVar(p *[]string, key, name, usage string) *Setting {
+	s.flags.Var((*commaList)(p), name, usage)
+	return s.add(&Setting{Key: key, Flag: name, Kind: List, target: p})
+}
+
+// RepeatedVar defines a list setting with a repeated flag
+func (s *Set) RepeatedVar(p *[]string, key, name, usage string) *Setting {
+	s.flags.Var((*repeatedList)(p), name, usage)
+	return s.add(&Setting{Key: key, Flag: name, Kind: Repeated, target: p})
+}
+
+// PathListVar defines a list of paths with a repeated flag
+func (s *Set) PathListVar(p *[]string, key, name, usage string) *Setting {
+	s.flags.Var((*repeatedList)(p), name, usage)
+	return s.add(&Setting{Key: key, Flag: name, Kind: PathList, target: p})
+}
+
+// ArgsVar defines the setting of the arguments after -- on the command line
+func (s *Set) ArgsVar(p *[]string, key string) *Setting {
+	return s.add(&Setting{Key: key, Kind: Args, target: p})
+}
+
+// ConfigFileVar defines the flag and environment variable naming the config file
+// the other settings are read from
+func (s *Set) ConfigFileVar(p *string, name, usage string) *Setting {
+	s.configFile = s.StringVar(p, "", name, "", usage)
+	return s.configFile
+}
+
+// FileSchema describes the entries of the config file that are not settings, such
+// as JSON objects decoded by the caller. Keys neither it nor the settings describe
+// are reported as unknown, unless it has additional properties.
+func (s *Set) FileSchema(schema *Schema) {
+	s.entries = schema
+}
+
+// Warnings returns the problems found in the config file that are not errors,
+// such as unknown keys
+func (s *Set) Warnings() []string {
+	return s.warnings
+}
+
+// Sections returns the top-level keys of the config file that are neither
+// settings nor entries of the file schema, and are left to its additional
+// properties
+func (s *Set) Sections() []string {
+	return s.sections
+}
+
+// schema returns the schema of the config file
+func (s *Set) schema() *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, setting := range s.settings {
+		if setting.Key == "" {
+			continue
+		}
+		parts := strings.Split(setting.Key, ".")
+		object := schema
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := object.Properties[part]
+			if !ok {
+				child = &Schema{Type: "object", Properties: make(map[string]*Schema)}
+				object.Properties[part] = child
+			}
+			object = child
+		}
+		object.Properties[parts[len(parts)-1]] = setting.schema()
+	}
+	if s.entries != nil {
+		schema.merge(s.entries)
+	}
+	return schema
+}
+
+// Lookup returns the setting with a config file key or, for settings without a
+// key, a flag name
+func (s *Set) Lookup(name string) *Setting {
+	for _, setting := range s.settings {
+		if setting.Key == name || (setting.Key == "" && setting.Flag == name) {
+			return setting
+		}
+	}
+	return nil
+}
+
+// Parse parses the command line arguments, then fills the settings they leave
+// unset from the environment, and those still unset from the config file.
+// lookupEnv is usually os.LookupEnv.
+func (s *Set) Parse(args []string, lookupEnv func(string) (string, bool)) error {
+	if err := s.flags.Parse(args); err != nil {
+		return err
+	}
+	given := make(map[string]bool)
+	s.flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	for _, setting := range s.settings {
+		if setting.Kind == Args {
+			if rest := s.flags.Args(); len(rest) > 0 {
+				*setting.target.(*[]string) = rest
+				setting.Source = Flag
+			}
+		} else if setting.Flag != "" && given[setting.Flag] {
+			setting.Source = Flag
+		}
+		if setting.Source != Default {
+			continue
+		}
+		value, ok := lookupEnv(setting.Env())
+		if !ok {
+			continue
+		}
+		if err := setting.setEnv(value); err != nil {
+			return fmt.Errorf("%s: %v", setting.Env(), err)
+		}
+		setting.Source = Env
+	}
+
+	if s.configFile != nil && *s.configFile.target.(*string) != "" {
+		if err := s.loadFile(*s.configFile.target.(*string)); err != nil {
+			return err
+		}
+	}
+
+	for _, setting := range s.settings {
+		if err := setting.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFile fills the settings that are still unset from the config file
+func (s *Set) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	var file map[string]any
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	schema := s.schema()
+	warnings, err := schema.Validate(file)
+	if err != nil {
+		return err
+	}
+	s.warnings = warnings
+	if schema.AdditionalProperties != nil {
+		for key := range file {
+			if schema.Properties[key] == nil {
+				s.sections = append(s.sections, key)
+			}
+		}
+		sort.Strings(s.sections)
+	}
+
+	dir := filepath.Dir(path)
+	for _, setting := range s.settings {
+		if setting.Key == "" || setting.Source != Default {
+			continue
+		}
+		value, ok, err := lookupKey(file, setting.Key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := setting.setJSON(value, dir); err != nil {
+			return fmt.Errorf("config file key %s: %v", setting.Key, err)
+		}
+		setting.Source = File
+	}
+	return nil
+}
+
+// lookupKey finds a dotted key in nested JSON objects
+func lookupKey(file map[string]any, key string) (any, bool, error) {
+	parts := strings.Split(key, ".")
+	object := file
+	for i, part := range parts[:len(parts)-1] {
+		value, ok := object[part]
+		if !ok {
+			return nil, false, nil
+		}
+		if object, ok = value.(map[string]any); !ok {
+			return nil, false, fmt.Errorf("config file key %s: must be a JSON object", strings.Join(parts[:i+1], "."))
+		}
+	}
+	value, ok := object[parts[len(parts)-1]]
+	return value, ok, nil
+}
+
+// setEnv sets the value from an environment variable
+func (s *Setting) setEnv(value string) error {
+	switch target := s.target.(type) {
+	case *string:
+		*target = value
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*target = parsed
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*target = parsed
+	case *[]string:
+		var list []string
+		switch s.Kind {
+		case PathList:
+			list = filepath.SplitList(value)
+		case Args:
+			list = strings.Fields(value)
+		default:
+			(*commaList)(&list).Set(value)
+		}
+		*target = list
+	}
+	return nil
+}
+
+// setJSON sets the value from the config file, whose directory relative paths are
+// resolved against
+func (s *Setting) setJSON(value any, dir string) error {
+	resolve := func(path string) string {
+		if (s.Kind == Path || s.Kind == PathList) && path != "" && !filepath.IsAbs(path) {
+			return filepath.Join(dir, path)
+		}
+		return path
+	}
+
+	switch target := s.target.(type) {
+	case *string:
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		*target = resolve(str)
+	case *bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("must be true or false")
+		}
+		*target = b
+	case *int:
+		n, ok := value.(float64)
+		if !ok || n != float64(int(n)) {
+			return fmt.Errorf("must be an integer")
+		}
+		*target = int(n)
+	case *[]string:
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("must be a JSON array of strings")
+		}
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			str, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("must be a JSON array of strings")
+			}
+			list = append(list, resolve(str))
+		}
+		*target = list
+	}
+	return nil
+}
+
+// commaList is a flag holding a comma separated list, which may also be repeated
+type commaList []string
+
+func (l *commaList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *commaList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// repeatedList is a flag that may be repeated, collecting every value
+type repeatedList []string
+
+func (l *repeatedList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *repeatedList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSettings struct {
+	lsp        string
+	lspArgs    []string
+	configFile string
+	readOnly   bool
+	maxDirs    int
+	exclude    []string
+	workspaces []string
+	codeIndex  string
+	transport  string
+}
+
+func newTestSet(t *testing.T, file string) (*Set, *testSettings) {
+	t.Helper()
+	settings := &testSettings{}
+	set := NewSet(flag.NewFlagSet("test", flag.ContinueOnError))
+	set.StringVar(&settings.lsp, "lsp", "lsp", "", "")
+	set.ArgsVar(&settings.lspArgs, "lspArgs")
+	set.ConfigFileVar(&settings.configFile, "config", "")
+	set.BoolVar(&settings.readOnly, "tools.readOnly", "read-only", false, "")
+	set.IntVar(&settings.maxDirs, "watcher.maxDirs", "max-watched-dirs", 0, "").NonNegative()
+	set.ListVar(&settings.exclude, "watcher.exclude", "watch-exclude", "")
+	set.PathListVar(&settings.workspaces, "workspaces", "workspace", "")
+	set.PathVar(&settings.codeIndex, "codeIndex", "code-index", "", "")
+	set.StringVar(&settings.transport, "transport", "transport", "stdio", "").OneOf("stdio", "sse")
+
+	if file != "" {
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(file), 0o644))
+		settings.configFile = path
+	}
+	return set, settings
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestEnv(t *testing.T) {
+	set, _ := newTestSet(t, "")
+	assert.Equal(t, "MCP_LS_LSP", set.Lookup("lsp").Env())
+	assert.Equal(t, "MCP_LS_LSP_ARGS", set.Lookup("lspArgs").Env())
+	assert.Equal(t, "MCP_LS_MAX_WATCHED_DIRS", set.Lookup("watcher.maxDirs").Env())
+	assert.Equal(t, "MCP_LS_CONFIG", set.Lookup("config").Env())
+}
+
+func TestPrecedence(t *testing.T) {
+	set, settings := newTestSet(t, `{
+		"lsp": "pyright-langserver",
+		"lspArgs": ["--stdio"],
+		"tools": {"readOnly": true},
+		"watcher": {"maxDirs": 10, "exclude": ["vendor/**"]},
+		"transport": "sse"
+	}`)
+	vars := map[string]string{
+		"MCP_LS_LSP":              "gopls",
+		"MCP_LS_WATCH_EXCLUDE":    "node_modules/**, dist/**",
+		"MCP_LS_CONFIG":           "ignored.json",
+		"MCP_LS_MAX_WATCHED_DIRS": "20",
+	}
+	require.NoError(t, set.Parse([]string{"--config", settings.configFile, "--max-watched-dirs", "30"}, env(vars)))
+
+	assert.Equal(t, "gopls", settings.lsp)
+	assert.Equal(t, Env, set.Lookup("lsp").Source)
+	assert.Equal(t, []string{"--stdio"}, settings.lspArgs)
+	assert.True(t, settings.readOnly)
+	assert.Equal(t, File, set.Lookup("tools.readOnly").Source)
+	assert.Equal(t, 30, settings.maxDirs)
+	assert.Equal(t, Flag, set.Lookup("watcher.maxDirs").Source)
+	assert.Equal(t, []string{"node_modules/**", "dist/**"}, settings.exclude)
+	assert.Equal(t, "sse", settings.transport)
+}
+
+func TestArgs(t *testing.T) {
+	set, settings := newTestSet(t, `{"lspArgs": ["--from-file"]}`)
+	require.NoError(t, set.Parse([]string{"--config", settings.configFile, "--", "--stdio"}, env(nil)))
+	assert.Equal(t, []string{"--stdio"}, settings.lspArgs)
+
+	set, settings = newTestSet(t, "")
+	require.NoError(t, set.Parse(nil, env(map[string]string{"MCP_LS_LSP_ARGS": "--stdio  --verbose"})))
+	assert.Equal(t, []string{"--stdio", "--verbose"}, settings.lspArgs)
+}
+
+func TestFilePaths(t *testing.T) {
+	set, settings := newTestSet(t, `{"workspaces": ["service", "/abs/lib"], "codeIndex": "index.scip"}`)
+	require.NoError(t, set.Parse([]string{"--config", settings.configFile}, env(nil)))
+
+	dir := filepath.Dir(settings.configFile)
+	assert.Equal(t, []string{filepath.Join(dir, "service"), "/abs/lib"}, settings.workspaces)
+	assert.Equal(t, filepath.Join(dir, "index.scip"), settings.codeIndex)
+}
+
+func TestErrorsNameTheKey(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "file type",
+			file: `{"watcher": {"maxDirs": "ten"}}`,
+			want: "config file key watcher.maxDirs: must be an integer",
+		},
+		{
+			name: "file object",
+			file: `{"watcher": ["vendor"]}`,
+			want: "config file key watcher: must be a JSON object",
+		},
+		{
+			name: "file list",
+			file: `{"workspaces": "service"}`,
+			want: "config file key workspaces: must be a JSON array",
+		},
+		{
+			name: "file limit",
+			file: `{"watcher": {"maxDirs": -1}}`,
+			want: "config file key watcher.maxDirs: must not be less than 0",
+		},
+		{
+			name: "env type",
+			env:  map[string]string{"MCP_LS_READ_ONLY": "maybe"},
+			want: `MCP_LS_READ_ONLY: invalid boolean "maybe"`,
+		},
+		{
+			name: "env choice",
+			env:  map[string]string{"MCP_LS_TRANSPORT": "http"},
+			want: `MCP_LS_TRANSPORT: unsupported value "http" (expected stdio, sse)`,
+		},
+		{
+			name: "flag choice",
+			args: []string{"--transport", "http"},
+			want: `--transport: unsupported value "http"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, settings := newTestSet(t, tt.file)
+			args := tt.args
+			if settings.configFile != "" {
+				args = append([]string{"--config", settings.configFile}, args...)
+			}
+			err := set.Parse(args, env(tt.env))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
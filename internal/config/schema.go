@@ -0,0 +1,273 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Schema describes the JSON value expected at a key of the config file, after
+// JSON Schema. Type is object, array, string, integer, number or boolean, or
+// empty for any value.
+type Schema struct {
+	Type       string
+	Properties map[string]*Schema
+	// AdditionalProperties is the schema of the keys of an object that are not
+	// in Properties. Without one, such keys are reported as unknown.
+	AdditionalProperties *Schema
+	Items                *Schema
+	// Enum lists the values a string may have besides the empty string
+	Enum []string
+	// Description explains the value in errors about it
+	Description string
+}
+
+// SchemaOf returns the schema of the JSON encoding of a Go value, with the
+// properties of structs named by their json tags
+func SchemaOf(v any) *Schema {
+	return schemaOf(reflect.TypeOf(v))
+}
+
+func schemaOf(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			schema.Properties[name] = schemaOf(field.Type)
+		}
+		return schema
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{}
+	}
+}
+
+// schema returns the schema of the setting's value in the config file
+func (s *Setting) schema() *Schema {
+	switch s.Kind {
+	case Bool:
+		return &Schema{Type: "boolean"}
+	case Int:
+		return &Schema{Type: "integer"}
+	case List, Repeated, PathList, Args:
+		return &Schema{Type: "array", Items: &Schema{Type: "string"}}
+	default:
+		return &Schema{Type: "string", Enum: s.choices}
+	}
+}
+
+// merge adds the properties of other to an object schema, keeping those it has
+func (s *Schema) merge(other *Schema) {
+	if s.Properties == nil {
+		s.Properties = make(map[string]*Schema)
+	}
+	for name, property := range other.Properties {
+		existing, ok := s.Properties[name]
+		switch {
+		case !ok:
+			s.Properties[name] = property
+		case existing.Type == "object" && property.Type == "object":
+			existing.merge(property)
+		}
+	}
+	if other.AdditionalProperties != nil {
+		s.AdditionalProperties = other.AdditionalProperties
+	}
+}
+
+// Validate checks a decoded JSON value against the schema. It fails with the key
+// of the first value of the wrong type, and returns warnings for unknown keys and
+// for keys that look like misspellings of known ones.
+func (s *Schema) Validate(value any) ([]string, error) {
+	var warnings []string
+	err := s.validate(value, "", &warnings)
+	return warnings, err
+}
+
+func (s *Schema) validate(value any, path string, warnings *[]string) error {
+	keyError := func(path, message string) error {
+		if s.Description != "" {
+			message += " (" + s.Description + ")"
+		}
+		return keyError(path, message)
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return keyError(path, "must be a JSON object")
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			if property, ok := s.Properties[key]; ok {
+				if err := property.validate(object[key], child, warnings); err != nil {
+					return err
+				}
+				continue
+			}
+			// Keys of additional properties, such as server names, are only taken
+			// for misspellings when they are very close
+			maxDistance := 2
+			if s.AdditionalProperties != nil {
+				maxDistance = 1
+			}
+			suggestion := s.suggest(key, maxDistance)
+			if suggestion != "" && path != "" {
+				suggestion = path + "." + suggestion
+			}
+			if s.AdditionalProperties == nil {
+				warning := fmt.Sprintf("config file key %s is unknown and ignored", child)
+				if suggestion != "" {
+					warning += fmt.Sprintf(", did you mean %s?", suggestion)
+				}
+				*warnings = append(*warnings, warning)
+				continue
+			}
+			if err := s.AdditionalProperties.validate(object[key], child, warnings); err != nil {
+				if suggestion != "" {
+					return fmt.Errorf("%v, did you mean %s?", err, suggestion)
+				}
+				return err
+			}
+			if suggestion != "" {
+				*warnings = append(*warnings, fmt.Sprintf("config file key %s: did you mean %s?", child, suggestion))
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return keyError(path, "must be a JSON array")
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), warnings); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return keyError(path, "must be a string")
+		}
+		if len(s.Enum) > 0 && str != "" && !slices.Contains(s.Enum, str) {
+			return keyError(path, fmt.Sprintf("unsupported value %q (expected %s)", str, strings.Join(s.Enum, ", ")))
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int(n)) {
+			return keyError(path, "must be an integer")
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return keyError(path, "must be a number")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return keyError(path, "must be true or false")
+		}
+	}
+	return nil
+}
+
+// suggest returns the property a key is most likely a misspelling of, at most
+// maxDistance edits away or differing in case, or the empty string when none is
+func (s *Schema) suggest(key string, maxDistance int) string {
+	best, bestDistance := "", maxDistance+1
+	for name := range s.Properties {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+		if d := editDistance(strings.ToLower(name), strings.ToLower(key)); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	if bestDistance > maxDistance || len(key) <= 2*bestDistance {
+		return ""
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func keyError(path, message string) error {
+	if path == "" {
+		return fmt.Errorf("config file: %s", message)
+	}
+	return fmt.Errorf("config file key %s: %s", path, message)
+}
+
+// DecodeFile checks the entries of a config file that v describes against its
+// schema, ignoring the other keys, then decodes them into v. It returns the
+// whole file, for the entries v does not describe.
+func DecodeFile(data []byte, v any) (map[string]any, error) {
+	var file map[string]any
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON config: %v", err)
+	}
+	schema := SchemaOf(v)
+	schema.AdditionalProperties = &Schema{}
+	if _, err := schema.Validate(file); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testProbe struct {
+	Strategy  string `json:"strategy,omitempty"`
+	TimeoutMs int    `json:"timeoutMs,omitempty"`
+}
+
+type testEntries struct {
+	LanguageIDs map[string]string    `json:"languageIds"`
+	Readiness   map[string]testProbe `json:"readiness"`
+	Settings    map[string]any       `json:"settings"`
+	Globs       []string             `json:"globs"`
+	internal    bool
+}
+
+func decode(t *testing.T, text string) any {
+	t.Helper()
+	var value any
+	require.NoError(t, json.Unmarshal([]byte(text), &value))
+	return value
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema := SchemaOf(testEntries{})
+	assert.Equal(t, "object", schema.Type)
+	assert.Len(t, schema.Properties, 4)
+	assert.Equal(t, "string", schema.Properties["languageIds"].AdditionalProperties.Type)
+	assert.Equal(t, "integer", schema.Properties["readiness"].AdditionalProperties.Properties["timeoutMs"].Type)
+	assert.Equal(t, "", schema.Properties["settings"].AdditionalProperties.Type)
+	assert.Equal(t, "string", schema.Properties["globs"].Items.Type)
+}
+
+func TestValidate(t *testing.T) {
+	schema := SchemaOf(testEntries{})
+
+	tests := []struct {
+		name     string
+		file     string
+		want     string
+		warnings []string
+	}{
+		{
+			name: "valid",
+			file: `{"languageIds": {".tpl": "html"}, "settings": {"gopls": {"staticcheck": true}}}`,
+		},
+		{
+			name: "nested type",
+			file: `{"readiness": {"gopls": {"timeoutMs": "30s"}}}`,
+			want: "config file key readiness.gopls.timeoutMs: must be an integer",
+		},
+		{
+			name: "array item",
+			file: `{"globs": ["*.go", 3]}`,
+			want: "config file key globs[1]: must be a string",
+		},
+		{
+			name:     "unknown key",
+			file:     `{"readiness": {"gopls": {"timeout": 10}}, "extra": 1}`,
+			warnings: []string{"config file key extra is unknown and ignored", "config file key readiness.gopls.timeout is unknown and ignored, did you mean readiness.gopls.timeoutMs?"},
+		},
+		{
+			name:     "misspelled key",
+			file:     `{"languageIDs": {".tpl": "html"}}`,
+			warnings: []string{"config file key languageIDs is unknown and ignored, did you mean languageIds?"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := schema.Validate(decode(t, tt.file))
+			if tt.want != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.want, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.warnings, warnings)
+		})
+	}
+}
+
+func TestValidateAdditionalProperties(t *testing.T) {
+	schema := SchemaOf(testEntries{})
+	schema.AdditionalProperties = &Schema{Type: "object", AdditionalProperties: &Schema{}, Description: "server options"}
+
+	warnings, err := schema.Validate(decode(t, `{"gopls": {"analyses": {"unusedparams": true}}, "setings": {}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config file key setings: did you mean settings?"}, warnings)
+
+	_, err = schema.Validate(decode(t, `{"gopls": true}`))
+	require.Error(t, err)
+	assert.Equal(t, "config file key gopls: must be a JSON object (server options)", err.Error())
+
+	_, err = schema.Validate(decode(t, `{"glob": ["*.go"]}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did you mean globs?")
+}
+
+func TestSetFileSchema(t *testing.T) {
+	set, settings := newTestSet(t, `{
+		"lsp": "gopls",
+		"watcher": {"maxDirs": 5, "exlude": ["vendor"]},
+		"languageIds": {".tpl": "html"},
+		"gopls": {"staticcheck": true},
+		"pyright": {}
+	}`)
+	schema := SchemaOf(testEntries{})
+	schema.AdditionalProperties = &Schema{Type: "object", AdditionalProperties: &Schema{}}
+	set.FileSchema(schema)
+
+	require.NoError(t, set.Parse([]string{"--config", settings.configFile}, env(nil)))
+	assert.Equal(t, 5, settings.maxDirs)
+	assert.Equal(t, []string{"config file key watcher.exlude is unknown and ignored, did you mean watcher.exclude?"}, set.Warnings())
+	assert.Equal(t, []string{"gopls", "pyright"}, set.Sections())
+}
+
+func TestDecodeFile(t *testing.T) {
+	var entries testEntries
+	file, err := DecodeFile([]byte(`{"languageIds": {".tpl": "html"}, "gopls": {}}`), &entries)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{".tpl": "html"}, entries.LanguageIDs)
+	assert.Contains(t, file, "gopls")
+
+	_, err = DecodeFile([]byte(`{"languageIds": [".tpl"]}`), &entries)
+	require.Error(t, err)
+	assert.Equal(t, "config file key languageIds: must be a JSON object", err.Error())
+
+}
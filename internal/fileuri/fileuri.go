@@ -0,0 +1,65 @@
+// Package fileuri converts between file paths and the file URIs used by the
+// language server protocol.
+//
+// Paths are percent-encoded, so spaces, '#', '%' and non-ASCII characters
+// survive the round trip, and Windows paths get a forward-slash URI with an
+// upper case drive letter, as in file:///C:/project/main.go.
+package fileuri
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+const prefix = "file://"
+
+// FromPath returns the URI of a file path. Relative paths are taken relative
+// to the working directory.
+func FromPath(path string) protocol.DocumentUri {
+	return protocol.URIFromPath(path)
+}
+
+// ParsePath returns the file path of a URI, or an error when it is not a valid
+// file URI
+func ParsePath[U ~string](uri U) (string, error) {
+	parsed, err := protocol.ParseDocumentUri(string(uri))
+	if err != nil {
+		return "", err
+	}
+	return parsed.Path(), nil
+}
+
+// ToPath returns the file path of a URI. URIs that do not parse, such as ones a
+// server built without escaping, fall back to the text after file://, so paths
+// can always be shown.
+func ToPath[U ~string](uri U) string {
+	path, err := ParsePath(uri)
+	if err != nil {
+		return filepath.FromSlash(strings.TrimPrefix(string(uri), prefix))
+	}
+	return path
+}
+
+// Normalize returns the canonical form of a file URI, so that URIs encoded
+// differently, for example with an escaped or lower case drive letter, compare
+// equal. Invalid URIs are returned as they are.
+func Normalize[U ~string](uri U) protocol.DocumentUri {
+	parsed, err := protocol.ParseDocumentUri(string(uri))
+	if err != nil {
+		return protocol.DocumentUri(uri)
+	}
+	return parsed
+}
+
+// IsFile reports whether a URI uses the file scheme
+func IsFile[U ~string](uri U) bool {
+	return strings.HasPrefix(string(uri), prefix)
+}
+
+// InDir reports whether the file a URI refers to is dir or inside it
+func InDir[U ~string](uri U, dir string) bool {
+	rel, err := filepath.Rel(dir, ToPath(uri))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
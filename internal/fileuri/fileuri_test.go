@@ -0,0 +1,70 @@
+package fileuri
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		uri  protocol.DocumentUri
+	}{
+		{"plain", "/project/main.go", "file:///project/main.go"},
+		{"spaces", "/my project/main file.go", "file:///my%20project/main%20file.go"},
+		{"reserved characters", "/project/#1/100%/a?b.go", "file:///project/%231/100%25/a%3Fb.go"},
+		{"non-ASCII", "/projekt/größe.go", "file:///projekt/gr%C3%B6%C3%9Fe.go"},
+		{"drive letter", "c:/project/main.go", "file:///C:/project/main.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.uri, FromPath(tt.path))
+
+			path, err := ParsePath(tt.uri)
+			require.NoError(t, err)
+			if tt.name == "drive letter" {
+				assert.Equal(t, "C:/project/main.go", path)
+			} else {
+				assert.Equal(t, tt.path, path)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	// Encodings other clients and servers use for the same file
+	for _, uri := range []string{
+		"file:///C:/project/main.go",
+		"file:///c:/project/main.go",
+		"file:///C%3A/project/main.go",
+		"file:///c%3a/project/main.go",
+	} {
+		path, err := ParsePath(uri)
+		require.NoError(t, err, uri)
+		assert.Equal(t, "C:/project/main.go", path, uri)
+		assert.Equal(t, protocol.DocumentUri("file:///C:/project/main.go"), Normalize(uri), uri)
+	}
+
+	_, err := ParsePath("untitled:Untitled-1")
+	assert.Error(t, err)
+}
+
+func TestToPath(t *testing.T) {
+	assert.Equal(t, "/my project/a.go", ToPath("file:///my%20project/a.go"))
+	// Unescaped URIs from servers that build them by concatenation still give a path
+	assert.Equal(t, "/my project/a.go", ToPath("file:///my project/a.go"))
+	assert.Equal(t, "/100%/a.go", ToPath("file:///100%/a.go"))
+	assert.Equal(t, "", ToPath(""))
+}
+
+func TestInDir(t *testing.T) {
+	assert.True(t, InDir("file:///project/main.go", "/project"))
+	assert.True(t, InDir("file:///my%20project/a/b.go", "/my project"))
+	assert.True(t, InDir("file:///project", "/project"))
+	assert.False(t, InDir("file:///project2/main.go", "/project"))
+	assert.False(t, InDir("file:///other/main.go", "/project"))
+}
@@ -1,12 +1,14 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
 	"sync"
+	"time"
 )
 
 // LogLevel represents the severity of a log message
@@ -43,6 +45,23 @@ func (l LogLevel) String() string {
 	}
 }
 
+// ParseLevel returns the level with the given name, such as "debug" or "WARN"
+func ParseLevel(name string) (LogLevel, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "DEBUG":
+		return LevelDebug, nil
+	case "INFO":
+		return LevelInfo, nil
+	case "WARN", "WARNING":
+		return LevelWarn, nil
+	case "ERROR":
+		return LevelError, nil
+	case "FATAL":
+		return LevelFatal, nil
+	}
+	return 0, fmt.Errorf("unknown log level: %s (expected debug, info, warn, error or fatal)", name)
+}
+
 // Component represents a specific part of the application for which logs can be filtered
 type Component string
 
@@ -61,6 +80,66 @@ const (
 	Tools Component = "tools"
 )
 
+// Components lists every component
+var Components = []Component{Core, LSP, LSPWire, LSPProcess, Watcher, Tools}
+
+// ParseComponentLevels parses comma separated component:level pairs, such as
+// "lsp:debug,watcher:warn"
+func ParseComponentLevels(spec string) (map[Component]LogLevel, error) {
+	levels := make(map[Component]LogLevel)
+	for _, part := range strings.Split(spec, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, levelName, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid component level %q (expected component:level)", part)
+		}
+		component := Component(strings.TrimSpace(name))
+		known := false
+		for _, c := range Components {
+			known = known || c == component
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown log component: %s", component)
+		}
+		level, err := ParseLevel(levelName)
+		if err != nil {
+			return nil, err
+		}
+		levels[component] = level
+	}
+	return levels, nil
+}
+
+// Format is how log messages are written
+type Format string
+
+const (
+	// FormatText writes a line of text with the time, level and component
+	FormatText Format = "text"
+	// FormatJSON writes a JSON object per line, for log collectors
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown log format: %s (expected text or json)", name)
+}
+
+const (
+	// DefaultFileMaxBytes is the size log files are rotated at, keeping
+	// DefaultFileBackups old files
+	DefaultFileMaxBytes = 50 << 20
+	DefaultFileBackups  = 3
+)
+
 // DefaultMinLevel is the default minimum log level
 var DefaultMinLevel = LevelInfo
 
@@ -73,6 +152,12 @@ var Writer io.Writer = os.Stderr
 // TestOutput can be set during tests to capture log output
 var TestOutput io.Writer
 
+// OutputFormat is the format log messages are written in
+var OutputFormat = FormatText
+
+// logFile is the file logs are written to in addition to stderr, if any
+var logFile io.Closer
+
 // logMu protects concurrent modifications to logging config
 var logMu sync.Mutex
 
@@ -87,18 +172,9 @@ func init() {
 	ComponentLevels[LSPWire] = DefaultMinLevel
 
 	// Parse log level from environment variable
-	if level := os.Getenv("LOG_LEVEL"); level != "" {
-		switch strings.ToUpper(level) {
-		case "DEBUG":
-			DefaultMinLevel = LevelDebug
-		case "INFO":
-			DefaultMinLevel = LevelInfo
-		case "WARN":
-			DefaultMinLevel = LevelWarn
-		case "ERROR":
-			DefaultMinLevel = LevelError
-		case "FATAL":
-			DefaultMinLevel = LevelFatal
+	if name := os.Getenv("LOG_LEVEL"); name != "" {
+		if level, err := ParseLevel(name); err == nil {
+			DefaultMinLevel = level
 		}
 
 		// Set all components to this level by default
@@ -107,41 +183,27 @@ func init() {
 		}
 	}
 
-	// Allow overriding levels for specific components
+	// Allow overriding levels for specific components. Invalid entries are skipped.
 	if compLevels := os.Getenv("LOG_COMPONENT_LEVELS"); compLevels != "" {
-		for _, part := range strings.SplitN(compLevels, ",", -1) {
-			compAndLevel := strings.Split(part, ":")
-			if len(compAndLevel) != 2 {
-				continue
-			}
-
-			comp := Component(strings.TrimSpace(compAndLevel[0]))
-			levelStr := strings.ToUpper(strings.TrimSpace(compAndLevel[1]))
-
-			var level LogLevel
-			switch levelStr {
-			case "DEBUG":
-				level = LevelDebug
-			case "INFO":
-				level = LevelInfo
-			case "WARN":
-				level = LevelWarn
-			case "ERROR":
-				level = LevelError
-			case "FATAL":
-				level = LevelFatal
-			default:
-				continue
+		for _, part := range strings.Split(compLevels, ",") {
+			if levels, err := ParseComponentLevels(part); err == nil {
+				for comp, level := range levels {
+					ComponentLevels[comp] = level
+				}
 			}
+		}
+	}
 
-			ComponentLevels[comp] = level
+	if name := os.Getenv("LOG_FORMAT"); name != "" {
+		if format, err := ParseFormat(name); err == nil {
+			OutputFormat = format
 		}
 	}
 
 	// Use custom log file if specified
-	if logFile := os.Getenv("LOG_FILE"); logFile != "" {
-		file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err == nil {
+	if path := os.Getenv("LOG_FILE"); path != "" {
+		if file, err := OpenRotatingFile(path, DefaultFileMaxBytes, DefaultFileBackups); err == nil {
+			logFile = file
 			Writer = io.MultiWriter(os.Stderr, file)
 		}
 	}
@@ -194,7 +256,12 @@ func (l *ComponentLogger) log(level LogLevel, format string, v ...any) {
 	message := fmt.Sprintf(format, v...)
 	logMessage := fmt.Sprintf("[%s][%s] %s", level, l.component, message)
 
-	if err := log.Output(3, logMessage); err != nil {
+	logMu.Lock()
+	outputFormat := OutputFormat
+	logMu.Unlock()
+	if outputFormat == FormatJSON {
+		writeJSON(level, l.component, message)
+	} else if err := log.Output(3, logMessage); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to output log: %v\n", err)
 	}
 
@@ -206,6 +273,35 @@ func (l *ComponentLogger) log(level LogLevel, format string, v ...any) {
 	}
 }
 
+// jsonEntry is a log message written in the JSON format
+type jsonEntry struct {
+	Time      string    `json:"time"`
+	Level     string    `json:"level"`
+	Component Component `json:"component"`
+	Message   string    `json:"message"`
+}
+
+// writeJSON writes a log message as a line of JSON
+func writeJSON(level LogLevel, component Component, message string) {
+	data, err := json.Marshal(jsonEntry{
+		Time:      time.Now().Format(time.RFC3339Nano),
+		Level:     strings.ToLower(level.String()),
+		Component: component,
+		Message:   message,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode log: %v\n", err)
+		return
+	}
+
+	logMu.Lock()
+	w := Writer
+	logMu.Unlock()
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to output log: %v\n", err)
+	}
+}
+
 // Debug logs a debug message
 func (l *ComponentLogger) Debug(format string, v ...any) {
 	l.log(LevelDebug, format, v...)
@@ -259,19 +355,45 @@ func SetWriter(w io.Writer) {
 	log.SetOutput(Writer)
 }
 
+// SetFormat sets the format log messages are written in
+func SetFormat(format Format) {
+	logMu.Lock()
+	defer logMu.Unlock()
+	OutputFormat = format
+}
+
 // SetupFileLogging configures logging to a file in addition to stderr
 func SetupFileLogging(filePath string) error {
 	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
 	}
+	setLogFile(file)
+	return nil
+}
 
+// SetupRotatingFileLogging configures logging to a file in addition to stderr. The
+// file is rotated once it is larger than maxBytes, keeping backups old files.
+func SetupRotatingFileLogging(filePath string, maxBytes int64, backups int) error {
+	file, err := OpenRotatingFile(filePath, maxBytes, backups)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	setLogFile(file)
+	return nil
+}
+
+// setLogFile writes logs to file in addition to stderr, closing the previous log file
+func setLogFile(file io.WriteCloser) {
 	logMu.Lock()
 	defer logMu.Unlock()
 
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = file
 	Writer = io.MultiWriter(os.Stderr, file)
 	log.SetOutput(Writer)
-	return nil
 }
 
 // SetupTestLogging configures logging for tests
@@ -2,6 +2,7 @@ package logging
 
 import (
 	"bytes"
+	"encoding/json"
 	"maps"
 	"strings"
 	"testing"
@@ -106,3 +107,45 @@ func TestLogger(t *testing.T) {
 		})
 	}
 }
+
+func TestParseComponentLevels(t *testing.T) {
+	levels, err := ParseComponentLevels("lsp:debug, watcher:WARN,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(levels) != 2 || levels[LSP] != LevelDebug || levels[Watcher] != LevelWarn {
+		t.Errorf("Unexpected levels: %v", levels)
+	}
+
+	for _, spec := range []string{"lsp", "nope:debug", "lsp:loud"} {
+		if _, err := ParseComponentLevels(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	originalWriter := Writer
+	originalLevels := make(map[Component]LogLevel)
+	maps.Copy(originalLevels, ComponentLevels)
+
+	var buf bytes.Buffer
+	SetWriter(&buf)
+	SetFormat(FormatJSON)
+	defer func() {
+		SetWriter(originalWriter)
+		SetFormat(FormatText)
+		maps.Copy(ComponentLevels, originalLevels)
+	}()
+
+	SetLevel(Tools, LevelInfo)
+	NewLogger(Tools).Warn("disk %s", "full")
+
+	var entry map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Log is not JSON: %v: %s", err, buf.String())
+	}
+	if entry["level"] != "warn" || entry["component"] != "tools" || entry["message"] != "disk full" || entry["time"] == "" {
+		t.Errorf("Unexpected log entry: %v", entry)
+	}
+}
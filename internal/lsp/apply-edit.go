@@ -0,0 +1,67 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// ApplyWorkspaceEdit applies an edit the server computed to the files on disk. It
+// refuses when a document the edit changes is no longer the one the server
+// computed it against: its version moved on, it changed on disk without the
+// server being told yet, or it has staged edits that are not on disk.
+func (c *Client) ApplyWorkspaceEdit(edit protocol.WorkspaceEdit) error {
+	if err := c.checkEditConflicts(edit); err != nil {
+		return err
+	}
+	return utilities.ApplyWorkspaceEdit(edit, c.PositionEncoding())
+}
+
+// checkEditConflicts reports the first document an edit cannot safely be applied to
+func (c *Client) checkEditConflicts(edit protocol.WorkspaceEdit) error {
+	for uri := range edit.Changes {
+		if err := c.checkDocumentConflict(uri, 0); err != nil {
+			return err
+		}
+	}
+	for _, change := range edit.DocumentChanges {
+		if change.TextDocumentEdit == nil {
+			continue
+		}
+		document := change.TextDocumentEdit.TextDocument
+		if err := c.checkDocumentConflict(document.URI, document.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDocumentConflict checks that an open document is still at the version an
+// edit was computed for, and that the server's content matches the file on disk.
+// A version of 0 means the edit was computed against the file on disk.
+func (c *Client) checkDocumentConflict(uri protocol.DocumentUri, version int32) error {
+	path := fileuri.ToPath(uri)
+	doc, ok := c.Document(path)
+	if !ok {
+		return nil
+	}
+
+	if version != 0 && version != doc.Version {
+		return fmt.Errorf("version conflict: the edit is for version %d of %s, which is now at version %d", version, path, doc.Version)
+	}
+	if doc.Staged {
+		return fmt.Errorf("%s has staged edits, commit or discard them before applying the edit", path)
+	}
+	disk, err := os.ReadFile(path)
+	if err != nil {
+		// Deleted files are reported by applying the edit
+		return nil
+	}
+	if string(disk) != doc.Content {
+		return fmt.Errorf("%s changed on disk since the edit was computed, request the edit again", path)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyWorkspaceEditConflicts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0644))
+	uri := protocol.DocumentUri("file://" + path)
+
+	c := newClient()
+	c.openFiles[string(uri)] = &OpenFileInfo{URI: uri, Version: 2, content: "package main\n"}
+
+	versioned := func(version int32) protocol.WorkspaceEdit {
+		return protocol.WorkspaceEdit{DocumentChanges: []protocol.DocumentChange{{
+			TextDocumentEdit: &protocol.TextDocumentEdit{
+				TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+					Version:                version,
+					TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+				},
+				Edits: []protocol.Or_TextDocumentEdit_edits_Elem{{Value: protocol.TextEdit{
+					Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 8}, End: protocol.Position{Line: 0, Character: 12}},
+					NewText: "util",
+				}}},
+			},
+		}}}
+	}
+
+	err := c.ApplyWorkspaceEdit(versioned(1))
+	assert.ErrorContains(t, err, "version conflict")
+
+	// Changed on disk without the server being told
+	require.NoError(t, os.WriteFile(path, []byte("package other\n"), 0644))
+	err = c.ApplyWorkspaceEdit(versioned(2))
+	assert.ErrorContains(t, err, "changed on disk")
+
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0644))
+	require.NoError(t, c.ApplyWorkspaceEdit(versioned(2)))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package util\n", string(content))
+}
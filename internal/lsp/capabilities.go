@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// OptionalMethods lists the request methods that depend on a capability the
+// server may not have, in the order capability reports show them
+var OptionalMethods = []string{
+	"textDocument/hover",
+	"textDocument/definition",
+	"textDocument/declaration",
+	"textDocument/typeDefinition",
+	"textDocument/implementation",
+	"textDocument/references",
+	"textDocument/documentHighlight",
+	"textDocument/documentSymbol",
+	"workspace/symbol",
+	"textDocument/completion",
+	"textDocument/codeAction",
+	"textDocument/codeLens",
+	"textDocument/formatting",
+	"textDocument/rename",
+	"textDocument/foldingRange",
+	"textDocument/prepareCallHierarchy",
+	"textDocument/prepareTypeHierarchy",
+	"textDocument/semanticTokens/full",
+	"textDocument/moniker",
+	"workspace/executeCommand",
+}
+
+// ServerInfo returns the name and version the server reported in its initialize
+// response, which are empty when it did not report them
+func (c *Client) ServerInfo() (name, version string) {
+	if c.serverInfo == nil {
+		return "", ""
+	}
+	return c.serverInfo.Name, c.serverInfo.Version
+}
+
+// PositionEncoding returns how the server counts the character of a position. It
+// is UTF-16, the protocol's default, until the server is initialized.
+func (c *Client) PositionEncoding() protocol.PositionEncodingKind {
+	return utilities.NormalizePositionEncoding(c.positionEncoding)
+}
+
+// SupportsMethod reports whether the server handles a request method, either
+// because it advertised the capability in its initialize response or because it
+// registered it dynamically. Methods without a capability are assumed to be
+// supported.
+func (c *Client) SupportsMethod(method string) bool {
+	if c.registeredDynamically(method) {
+		return true
+	}
+
+	caps := c.serverCapabilities
+	switch method {
+	case "textDocument/hover":
+		return caps.HoverProvider != nil && providerEnabled(caps.HoverProvider.Value)
+	case "textDocument/definition":
+		return caps.DefinitionProvider != nil && providerEnabled(caps.DefinitionProvider.Value)
+	case "textDocument/declaration":
+		return caps.DeclarationProvider != nil && providerEnabled(caps.DeclarationProvider.Value)
+	case "textDocument/typeDefinition":
+		return caps.TypeDefinitionProvider != nil && providerEnabled(caps.TypeDefinitionProvider.Value)
+	case "textDocument/implementation":
+		return caps.ImplementationProvider != nil && providerEnabled(caps.ImplementationProvider.Value)
+	case "textDocument/references":
+		return caps.ReferencesProvider != nil && providerEnabled(caps.ReferencesProvider.Value)
+	case "textDocument/documentHighlight":
+		return caps.DocumentHighlightProvider != nil && providerEnabled(caps.DocumentHighlightProvider.Value)
+	case "textDocument/documentSymbol":
+		return caps.DocumentSymbolProvider != nil && providerEnabled(caps.DocumentSymbolProvider.Value)
+	case "workspace/symbol":
+		return caps.WorkspaceSymbolProvider != nil && providerEnabled(caps.WorkspaceSymbolProvider.Value)
+	case "textDocument/completion":
+		return caps.CompletionProvider != nil
+	case "textDocument/codeAction":
+		return providerEnabled(caps.CodeActionProvider)
+	case "textDocument/codeLens":
+		return caps.CodeLensProvider != nil
+	case "textDocument/formatting":
+		return caps.DocumentFormattingProvider != nil && providerEnabled(caps.DocumentFormattingProvider.Value)
+	case "textDocument/rename":
+		return providerEnabled(caps.RenameProvider)
+	case "textDocument/foldingRange":
+		return caps.FoldingRangeProvider != nil && providerEnabled(caps.FoldingRangeProvider.Value)
+	case "textDocument/prepareCallHierarchy":
+		return caps.CallHierarchyProvider != nil && providerEnabled(caps.CallHierarchyProvider.Value)
+	case "textDocument/prepareTypeHierarchy":
+		return caps.TypeHierarchyProvider != nil && providerEnabled(caps.TypeHierarchyProvider.Value)
+	case "textDocument/semanticTokens/full":
+		return caps.SemanticTokensProvider != nil
+	case "textDocument/moniker":
+		return caps.MonikerProvider != nil && providerEnabled(caps.MonikerProvider.Value)
+	case "workspace/executeCommand":
+		return caps.ExecuteCommandProvider != nil
+	}
+	return true
+}
+
+// providerEnabled reports whether a capability that is either a boolean or an
+// options object is enabled
+func providerEnabled(value any) bool {
+	if value == nil {
+		return false
+	}
+	if enabled, ok := value.(bool); ok {
+		return enabled
+	}
+	return true
+}
+
+// recordRegistration remembers a capability the server registered after
+// initializing, keyed by the registration id
+func (c *Client) recordRegistration(reg protocol.Registration) {
+	c.registrationsMu.Lock()
+	defer c.registrationsMu.Unlock()
+	c.registrations[reg.ID] = reg.Method
+}
+
+// forgetRegistration removes a capability the server unregistered
+func (c *Client) forgetRegistration(id string) {
+	c.registrationsMu.Lock()
+	defer c.registrationsMu.Unlock()
+	delete(c.registrations, id)
+}
+
+// registeredDynamically reports whether the server registered a method after
+// initializing
+func (c *Client) registeredDynamically(method string) bool {
+	c.registrationsMu.RLock()
+	defer c.registrationsMu.RUnlock()
+	for _, registered := range c.registrations {
+		if registered == method {
+			return true
+		}
+	}
+	return false
+}
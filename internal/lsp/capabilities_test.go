@@ -0,0 +1,65 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportsMethod(t *testing.T) {
+	c := newClient()
+	c.serverCapabilities = protocol.ServerCapabilities{
+		HoverProvider:      &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+		DefinitionProvider: &protocol.Or_ServerCapabilities_definitionProvider{Value: false},
+		ReferencesProvider: &protocol.Or_ServerCapabilities_referencesProvider{Value: protocol.ReferenceOptions{}},
+		RenameProvider:     false,
+	}
+
+	assert.True(t, c.SupportsMethod("textDocument/hover"))
+	assert.False(t, c.SupportsMethod("textDocument/definition"))
+	assert.True(t, c.SupportsMethod("textDocument/references"))
+	assert.False(t, c.SupportsMethod("textDocument/rename"))
+	assert.False(t, c.SupportsMethod("textDocument/prepareCallHierarchy"))
+	// Methods without a capability are assumed to work
+	assert.True(t, c.SupportsMethod("textDocument/didOpen"))
+
+	// Registered after initializing
+	c.recordRegistration(protocol.Registration{ID: "1", Method: "textDocument/prepareCallHierarchy"})
+	assert.True(t, c.SupportsMethod("textDocument/prepareCallHierarchy"))
+	c.forgetRegistration("1")
+	assert.False(t, c.SupportsMethod("textDocument/prepareCallHierarchy"))
+}
+
+func TestPositionEncodingPerClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("s := \"😀x\"\n"), 0o644))
+
+	// Each client counts positions the way its own server chose
+	utf8Client, utf16Client := newClient(), newClient()
+	utf8Client.positionEncoding = protocol.UTF8
+	assert.Equal(t, protocol.UTF8, utf8Client.PositionEncoding())
+	assert.Equal(t, protocol.UTF16, utf16Client.PositionEncoding(), "UTF-16 until the server is initialized")
+
+	// The x is 10 bytes and 8 UTF-16 units into the line
+	offset, err := utf8Client.OffsetAt(path, protocol.Position{Character: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 10, offset)
+	offset, err = utf16Client.OffsetAt(path, protocol.Position{Character: 8})
+	require.NoError(t, err)
+	assert.Equal(t, 10, offset)
+}
+
+func TestSupportsCompletionResolve(t *testing.T) {
+	c := newClient()
+	assert.False(t, c.SupportsCompletionResolve())
+
+	c.serverCapabilities.CompletionProvider = &protocol.CompletionOptions{}
+	assert.False(t, c.SupportsCompletionResolve())
+
+	c.serverCapabilities.CompletionProvider.ResolveProvider = true
+	assert.True(t, c.SupportsCompletionResolve())
+}
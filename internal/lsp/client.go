@@ -3,25 +3,102 @@ package lsp
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/codeindex"
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
 type Client struct {
 	Cmd    *exec.Cmd
+	conn   net.Conn
 	stdin  io.WriteCloser
-	stdout *bufio.Reader
 	stderr io.ReadCloser
 
+	// Guards Cmd, conn and stdin, which are replaced when the server restarts
+	transportMu sync.RWMutex
+
+	// How the server was started, kept so that it can be restarted
+	command     string
+	args        []string
+	address     string
+	serverName  string
+	initOptions map[string]any
+	languageIDs map[string]protocol.LanguageKind
+	saveActions SaveActions
+
+	// Settings returned for workspace/configuration requests, keyed by section
+	settings   map[string]any
+	settingsMu sync.RWMutex
+	restartMu  sync.Mutex
+
+	// Closed when the current server process exits, after exitErr is set
+	exited  chan struct{}
+	exitErr error
+
+	// When the current server was started or connected to, guarded by transportMu,
+	// and whether it has been initialized
+	startedAt   time.Time
+	initialized atomic.Bool
+
+	// Automatic restarts after crashes
+	closing          atomic.Bool
+	supervising      atomic.Bool
+	crashes          atomic.Int32
+	restartHandler   RestartHandler
+	restartHandlerMu sync.Mutex
+
+	// Totals since the client started, reported by Stats
+	totalCrashes  atomic.Int64
+	totalRestarts atomic.Int64
+	// Changes of open files not sent because the content was the same
+	skippedChanges atomic.Int64
+
+	// Told how long each request took when set
+	requestObserver atomic.Pointer[RequestObserver]
+
+	// Receives window/showMessage and window/logMessage notifications
+	messageHandler   MessageHandler
+	messageHandlerMu sync.Mutex
+
+	// Receive the file watchers the server registers and unregisters
+	fileWatchHandler   FileWatchHandler
+	fileUnwatchHandler FileUnwatchHandler
+	fileWatchHandlerMu sync.Mutex
+
+	// Receives work done progress reported by the server, guarded by progressMu
+	progressHandler ProgressHandler
+
+	// Titles of finished work done progress tasks, guarded by progressMu, and
+	// whether any diagnostics were published, used by readiness probes
+	endedProgress       map[string]bool
+	diagnosticsReceived atomic.Bool
+	readiness           ReadinessProbe
+	preload             PreloadPolicy
+	// Set once WaitForServerReady first returns
+	serverReady atomic.Bool
+
+	// Symbols kept across restarts, nil when there is no index
+	symbolIndex *SymbolIndex
+
+	// Prebuilt LSIF or SCIP index answering position queries, nil when there is
+	// none, and whether there is no server at all besides it
+	codeIndex *codeindex.Index
+	offline   bool
+
 	// Request ID counter
 	nextID atomic.Int32
 
@@ -41,48 +118,173 @@ type Client struct {
 	diagnostics   map[protocol.DocumentUri][]protocol.Diagnostic
 	diagnosticsMu sync.RWMutex
 
+	// Result ids of pulled diagnostic reports, guarded by diagnosticsMu
+	diagnosticResultIDs map[protocol.DocumentUri]string
+
+	// When each file's diagnostics were last published or pulled, guarded by
+	// diagnosticsMu
+	diagnosticTimes map[protocol.DocumentUri]time.Time
+
+	// Past diagnostics of each file, when each file's content was last sent to
+	// the server, and named checkpoints, all guarded by diagnosticsMu. They
+	// outlive restarts so that changes can be compared across them.
+	diagnosticHistory map[protocol.DocumentUri][]DiagnosticsSnapshot
+	lastEdits         map[protocol.DocumentUri]time.Time
+	checkpoints       map[string]time.Time
+
+	// Called when a file's diagnostics change, guarded by diagnosticsMu
+	diagnosticsHandler DiagnosticsHandler
+
+	// Work done progress in flight, keyed by token with the task's title, and
+	// the time the server last reported progress or diagnostics, used to
+	// detect when it becomes idle
+	activeProgress map[string]string
+	lastActivity   time.Time
+	progressMu     sync.Mutex
+
+	// Final messages of finished work done progress, keyed by token, kept until
+	// WaitForProgress collects them. Guarded by progressMu.
+	endedTokens map[string]string
+
 	// Files are currently opened by the LSP
 	openFiles   map[string]*OpenFileInfo
 	openFilesMu sync.RWMutex
+	// Most files kept open, 0 for no limit
+	maxOpenFiles int
+
+	// Serializes didChange notifications, so incremental changes reach the
+	// server in the order of the versions they were computed for
+	changeMu sync.Mutex
+
+	// Capabilities reported by the server in its initialize response
+	serverCapabilities protocol.ServerCapabilities
+	// Name and version the server reported in its initialize response, if any
+	serverInfo *protocol.ServerInfo
+	// How the server counts the character of a position, set when it is initialized
+	positionEncoding protocol.PositionEncodingKind
+
+	// Methods of capabilities the server registered after initializing, keyed by
+	// registration id
+	registrations   map[string]string
+	registrationsMu sync.RWMutex
+
+	// Workspace roots, the first of which is the primary workspace
+	workspaceRoots []string
+	workspaceMu    sync.RWMutex
+
+	// Traces the messages exchanged with the server when set
+	tracer atomic.Pointer[Tracer]
+
+	// Rewrites paths for a server that runs on another host, nil otherwise
+	paths *pathMapping
 }
 
 func NewClient(command string, args ...string) (*Client, error) {
-	cmd := exec.Command(command, args...)
+	client := newClient()
+	client.command = command
+	client.args = args
+
+	if err := client.start(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClientFromAddress connects to a language server that is already running and
+// listening on a socket, such as one managed by an IDE. The address is either
+// host:port, tcp:host:port, unix:/path/to/socket or an absolute socket path.
+func NewClientFromAddress(address string) (*Client, error) {
+	client := newClient()
+	client.address = address
+
+	if err := client.start(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func newClient() *Client {
+	return &Client{
+		handlers:              make(map[string]chan *Message),
+		notificationHandlers:  make(map[string]NotificationHandler),
+		serverRequestHandlers: make(map[string]ServerRequestHandler),
+		diagnostics:           make(map[protocol.DocumentUri][]protocol.Diagnostic),
+		diagnosticResultIDs:   make(map[protocol.DocumentUri]string),
+		diagnosticTimes:       make(map[protocol.DocumentUri]time.Time),
+		diagnosticHistory:     make(map[protocol.DocumentUri][]DiagnosticsSnapshot),
+		lastEdits:             make(map[protocol.DocumentUri]time.Time),
+		checkpoints:           make(map[string]time.Time),
+		activeProgress:        make(map[string]string),
+		endedProgress:         make(map[string]bool),
+		endedTokens:           make(map[string]string),
+		openFiles:             make(map[string]*OpenFileInfo),
+		registrations:         make(map[string]string),
+	}
+}
+
+// start launches the language server process, or connects to the server's address,
+// and begins reading messages from it
+func (c *Client) start() error {
+	// An index-only client has nothing to start
+	if c.offline {
+		return nil
+	}
+
+	if c.address != "" {
+		network, addr := parseAddress(c.address)
+		conn, err := net.DialTimeout(network, addr, 10*time.Second)
+		if err != nil {
+			return fmt.Errorf("failed to connect to LSP server at %s: %w", c.address, err)
+		}
+		lspLogger.Info("Connected to LSP server at %s://%s", network, addr)
+
+		c.transportMu.Lock()
+		c.conn = conn
+		c.stdin = conn
+		c.startedAt = time.Now()
+		c.transportMu.Unlock()
+
+		// Start message handling loop
+		go c.handleMessages(bufio.NewReader(conn))
+		return nil
+	}
+
+	cmd := exec.Command(c.command, c.args...)
 	// Copy env
 	cmd.Env = os.Environ()
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
-
-	client := &Client{
-		Cmd:                   cmd,
-		stdin:                 stdin,
-		stdout:                bufio.NewReader(stdout),
-		stderr:                stderr,
-		handlers:              make(map[string]chan *Message),
-		notificationHandlers:  make(map[string]NotificationHandler),
-		serverRequestHandlers: make(map[string]ServerRequestHandler),
-		diagnostics:           make(map[protocol.DocumentUri][]protocol.Diagnostic),
-		openFiles:             make(map[string]*OpenFileInfo),
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
 	// Start the LSP server process
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start LSP server: %w", err)
+		return fmt.Errorf("failed to start LSP server: %w", err)
 	}
 
+	exited := make(chan struct{})
+	c.transportMu.Lock()
+	c.Cmd = cmd
+	c.stdin = stdin
+	c.stderr = stderr
+	c.exited = exited
+	c.startedAt = time.Now()
+	c.transportMu.Unlock()
+
+	// Restart the server if it exits unexpectedly
+	go c.monitorProcess(cmd, exited, time.Now())
+
 	// Handle stderr in a separate goroutine with proper logging
 	go func() {
 		scanner := bufio.NewScanner(stderr)
@@ -96,9 +298,38 @@ func NewClient(command string, args ...string) (*Client, error) {
 	}()
 
 	// Start message handling loop
-	go client.handleMessages()
+	go c.handleMessages(bufio.NewReader(stdout))
 
-	return client, nil
+	return nil
+}
+
+// parseAddress splits an LSP server address into a network and address for net.Dial
+func parseAddress(address string) (string, string) {
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		return "unix", strings.TrimPrefix(address, "unix:")
+	case strings.HasPrefix(address, "tcp:"):
+		return "tcp", strings.TrimPrefix(address, "tcp:")
+	case filepath.IsAbs(address):
+		return "unix", address
+	default:
+		return "tcp", address
+	}
+}
+
+// ManagesProcess reports whether the client started the language server itself, as
+// opposed to connecting to one that is managed externally
+func (c *Client) ManagesProcess() bool {
+	return c.Cmd != nil
+}
+
+// commandPath returns the lower case path of the language server executable, or an
+// empty string when connected over a socket
+func (c *Client) commandPath() string {
+	if c.Cmd == nil {
+		return ""
+	}
+	return strings.ToLower(c.Cmd.Path)
 }
 
 func (c *Client) RegisterNotificationHandler(method string, handler NotificationHandler) {
@@ -113,35 +344,27 @@ func (c *Client) RegisterServerRequestHandler(method string, handler ServerReque
 	c.serverRequestHandlers[method] = handler
 }
 
-func getInitializationOptions(customConfig map[string]any) map[string]any {
-	// If custom config is provided, use it
-	if customConfig != nil && len(customConfig) > 0 {
-		return customConfig
-	}
-
-	// Otherwise, use default configuration (primarily for gopls)
-	return map[string]any{
-		"codelenses": map[string]bool{
-			"generate":           true,
-			"regenerate_cgo":     true,
-			"test":               true,
-			"tidy":               true,
-			"upgrade_dependency": true,
-			"vendor":             true,
-			"vulncheck":          false,
-		},
+// InitializeLSPClient initializes the server with one or more workspace roots. The
+// first root is used as the root URI for servers without workspace folder support.
+func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDirs []string, customConfig map[string]any) (*protocol.InitializeResult, error) {
+	if len(workspaceDirs) == 0 {
+		return nil, fmt.Errorf("at least one workspace directory is required")
+	}
+	workspaceDir := workspaceDirs[0]
+	c.initOptions = customConfig
+
+	c.workspaceMu.Lock()
+	c.workspaceRoots = append([]string(nil), workspaceDirs...)
+	c.workspaceMu.Unlock()
+
+	workspaceFolders := make([]protocol.WorkspaceFolder, 0, len(workspaceDirs))
+	for _, dir := range workspaceDirs {
+		workspaceFolders = append(workspaceFolders, workspaceFolder(dir))
 	}
-}
 
-func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string, customConfig map[string]any) (*protocol.InitializeResult, error) {
 	initParams := &protocol.InitializeParams{
 		WorkspaceFoldersInitializeParams: protocol.WorkspaceFoldersInitializeParams{
-			WorkspaceFolders: []protocol.WorkspaceFolder{
-				{
-					URI:  protocol.URI("file://" + workspaceDir),
-					Name: workspaceDir,
-				},
-			},
+			WorkspaceFolders: workspaceFolders,
 		},
 
 		XInitializeParams: protocol.XInitializeParams{
@@ -151,10 +374,11 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string, c
 				Version: "0.1.0",
 			},
 			RootPath: workspaceDir,
-			RootURI:  protocol.DocumentUri("file://" + workspaceDir),
+			RootURI:  fileuri.FromPath(workspaceDir),
 			Capabilities: protocol.ClientCapabilities{
 				Workspace: protocol.WorkspaceClientCapabilities{
-					Configuration: true,
+					Configuration:    true,
+					WorkspaceFolders: true,
 					DidChangeConfiguration: protocol.DidChangeConfigurationClientCapabilities{
 						DynamicRegistration: true,
 					},
@@ -162,14 +386,33 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string, c
 						DynamicRegistration:    true,
 						RelativePatternSupport: true,
 					},
+					Diagnostics: &protocol.DiagnosticWorkspaceClientCapabilities{
+						RefreshSupport: true,
+					},
+					FileOperations: &protocol.FileOperationClientCapabilities{
+						WillCreate: true,
+						DidCreate:  true,
+						WillRename: true,
+						DidRename:  true,
+						WillDelete: true,
+						DidDelete:  true,
+					},
 				},
 				TextDocument: protocol.TextDocumentClientCapabilities{
 					Synchronization: &protocol.TextDocumentSyncClientCapabilities{
 						DynamicRegistration: true,
+						WillSave:            c.saveActions.WillSaveWaitUntil,
+						WillSaveWaitUntil:   c.saveActions.WillSaveWaitUntil,
 						DidSave:             true,
 					},
 					Completion: protocol.CompletionClientCapabilities{
-						CompletionItem: protocol.ClientCompletionItemOptions{},
+						CompletionItem: protocol.ClientCompletionItemOptions{
+							DocumentationFormat: []protocol.MarkupKind{protocol.Markdown, protocol.PlainText},
+							DeprecatedSupport:   true,
+							ResolveSupport: &protocol.ClientCompletionItemResolveOptions{
+								Properties: []string{"detail", "documentation"},
+							},
+						},
 					},
 					CodeLens: &protocol.CodeLensClientCapabilities{
 						DynamicRegistration: true,
@@ -178,26 +421,93 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string, c
 					CodeAction: protocol.CodeActionClientCapabilities{
 						CodeActionLiteralSupport: protocol.ClientCodeActionLiteralOptions{
 							CodeActionKind: protocol.ClientCodeActionKindOptions{
-								ValueSet: []protocol.CodeActionKind{},
+								ValueSet: []protocol.CodeActionKind{
+									protocol.QuickFix,
+									protocol.Refactor,
+									protocol.RefactorExtract,
+									protocol.RefactorInline,
+									protocol.RefactorRewrite,
+									protocol.Source,
+									protocol.SourceOrganizeImports,
+									protocol.SourceFixAll,
+								},
 							},
 						},
+						IsPreferredSupport: true,
+						DisabledSupport:    true,
+						DataSupport:        true,
+						ResolveSupport: &protocol.ClientCodeActionResolveOptions{
+							Properties: []string{"edit"},
+						},
+					},
+					Implementation: &protocol.ImplementationClientCapabilities{
+						LinkSupport: true,
+					},
+					TypeDefinition: &protocol.TypeDefinitionClientCapabilities{
+						LinkSupport: true,
+					},
+					Declaration: &protocol.DeclarationClientCapabilities{
+						LinkSupport: true,
+					},
+					DocumentHighlight: &protocol.DocumentHighlightClientCapabilities{},
+					Moniker:           &protocol.MonikerClientCapabilities{},
+					CallHierarchy:     &protocol.CallHierarchyClientCapabilities{},
+					TypeHierarchy:     &protocol.TypeHierarchyClientCapabilities{},
+					Formatting:        &protocol.DocumentFormattingClientCapabilities{},
+					RangeFormatting:   &protocol.DocumentRangeFormattingClientCapabilities{},
+					FoldingRange: &protocol.FoldingRangeClientCapabilities{
+						LineFoldingOnly: true,
+						FoldingRangeKind: &protocol.ClientFoldingRangeKindOptions{
+							ValueSet: []protocol.FoldingRangeKind{protocol.Comment, protocol.Imports, protocol.Region},
+						},
+						FoldingRange: &protocol.ClientFoldingRangeOptions{
+							CollapsedText: true,
+						},
 					},
 					PublishDiagnostics: protocol.PublishDiagnosticsClientCapabilities{
 						VersionSupport: true,
+						DiagnosticsCapabilities: protocol.DiagnosticsCapabilities{
+							RelatedInformation:     true,
+							CodeDescriptionSupport: true,
+						},
+					},
+					Diagnostic: &protocol.DiagnosticClientCapabilities{
+						RelatedDocumentSupport: true,
+						DiagnosticsCapabilities: protocol.DiagnosticsCapabilities{
+							RelatedInformation:     true,
+							CodeDescriptionSupport: true,
+						},
 					},
 					SemanticTokens: protocol.SemanticTokensClientCapabilities{
 						Requests: protocol.ClientSemanticTokensRequestOptions{
-							Range: &protocol.Or_ClientSemanticTokensRequestOptions_range{},
-							Full:  &protocol.Or_ClientSemanticTokensRequestOptions_full{},
+							Range: &protocol.Or_ClientSemanticTokensRequestOptions_range{Value: true},
+							Full:  &protocol.Or_ClientSemanticTokensRequestOptions_full{Value: true},
+						},
+						TokenTypes: []string{
+							"namespace", "type", "class", "enum", "interface", "struct",
+							"typeParameter", "parameter", "variable", "property", "enumMember",
+							"event", "function", "method", "macro", "keyword", "modifier",
+							"comment", "string", "number", "regexp", "operator", "decorator",
+						},
+						TokenModifiers: []string{
+							"declaration", "definition", "readonly", "static", "deprecated",
+							"abstract", "async", "modification", "documentation", "defaultLibrary",
 						},
-						TokenTypes:     []string{},
-						TokenModifiers: []string{},
-						Formats:        []protocol.TokenFormat{},
+						Formats: []protocol.TokenFormat{protocol.Relative},
+					},
+				},
+				Window: protocol.WindowClientCapabilities{
+					WorkDoneProgress: true,
+				},
+				General: &protocol.GeneralClientCapabilities{
+					// UTF-8 saves converting positions in the common case, UTF-16
+					// is what servers that do not choose use
+					PositionEncodings: []protocol.PositionEncodingKind{
+						protocol.UTF8, protocol.UTF32, protocol.UTF16,
 					},
 				},
-				Window: protocol.WindowClientCapabilities{},
 			},
-			InitializationOptions: getInitializationOptions(customConfig),
+			InitializationOptions: c.initializationOptions(customConfig),
 		},
 	}
 
@@ -205,18 +515,42 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string, c
 	if err := c.Call(ctx, "initialize", initParams, &result); err != nil {
 		return nil, fmt.Errorf("initialize failed: %w", err)
 	}
+	c.serverCapabilities = result.Capabilities
+	c.serverInfo = result.ServerInfo
+
+	c.positionEncoding = protocol.UTF16
+	if result.Capabilities.PositionEncoding != nil {
+		c.positionEncoding = utilities.NormalizePositionEncoding(*result.Capabilities.PositionEncoding)
+	}
+	lspLogger.Debug("Position encoding: %s", c.positionEncoding)
 
 	if err := c.Notify(ctx, "initialized", struct{}{}); err != nil {
 		return nil, fmt.Errorf("initialized notification failed: %w", err)
 	}
 
 	// Register handlers
-	c.RegisterServerRequestHandler("workspace/applyEdit", HandleApplyEdit)
-	c.RegisterServerRequestHandler("workspace/configuration", HandleWorkspaceConfiguration)
-	c.RegisterServerRequestHandler("client/registerCapability", HandleRegisterCapability)
-	c.RegisterNotificationHandler("window/showMessage", HandleServerMessage)
+	c.RegisterServerRequestHandler("workspace/applyEdit",
+		func(params json.RawMessage) (any, error) { return HandleApplyEdit(c, params) })
+	c.RegisterServerRequestHandler("workspace/configuration",
+		func(params json.RawMessage) (any, error) { return HandleWorkspaceConfiguration(c, params) })
+	c.RegisterServerRequestHandler("client/registerCapability",
+		func(params json.RawMessage) (any, error) { return HandleRegisterCapability(c, params) })
+	c.RegisterServerRequestHandler("client/unregisterCapability",
+		func(params json.RawMessage) (any, error) { return HandleUnregisterCapability(c, params) })
+	c.RegisterServerRequestHandler("workspace/workspaceFolders",
+		func(params json.RawMessage) (any, error) { return HandleWorkspaceFolders(c) })
+	c.RegisterServerRequestHandler("workspace/diagnostic/refresh",
+		func(params json.RawMessage) (any, error) { return HandleDiagnosticRefresh(c) })
+	c.RegisterServerRequestHandler("window/workDoneProgress/create",
+		func(params json.RawMessage) (any, error) { return HandleWorkDoneProgressCreate(c, params) })
+	c.RegisterNotificationHandler("window/showMessage",
+		func(params json.RawMessage) { HandleServerMessage(c, params, true) })
+	c.RegisterNotificationHandler("window/logMessage",
+		func(params json.RawMessage) { HandleServerMessage(c, params, false) })
 	c.RegisterNotificationHandler("textDocument/publishDiagnostics",
 		func(params json.RawMessage) { HandleDiagnostics(c, params) })
+	c.RegisterNotificationHandler("$/progress",
+		func(params json.RawMessage) { HandleProgress(c, params) })
 
 	// Notify the LSP server
 	err := c.Initialized(ctx, protocol.InitializedParams{})
@@ -225,19 +559,20 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string, c
 	}
 
 	// LSP sepecific Initialization
-	path := strings.ToLower(c.Cmd.Path)
-	switch {
-	case strings.Contains(path, "typescript-language-server"):
-		err := initializeTypescriptLanguageServer(ctx, c, workspaceDir)
-		if err != nil {
+	for _, dir := range workspaceDirs {
+		if err := c.afterInitialize(ctx, dir); err != nil {
 			return nil, err
 		}
 	}
 
+	c.initialized.Store(true)
 	return &result, nil
 }
 
 func (c *Client) Close() error {
+	// The server exiting from here on is expected
+	c.closing.Store(true)
+
 	// Try to close all open files first
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -245,14 +580,28 @@ func (c *Client) Close() error {
 	// Attempt to close files but continue shutdown regardless
 	c.CloseAllFiles(ctx)
 
+	c.transportMu.RLock()
+	cmd, conn, stdin, exited := c.Cmd, c.conn, c.stdin, c.exited
+	c.transportMu.RUnlock()
+
+	// Externally managed servers keep running, only the connection is closed
+	if conn != nil {
+		return conn.Close()
+	}
+
+	// Nothing to stop if a restart failed to start the server
+	if cmd == nil {
+		return nil
+	}
+
 	// Force kill the LSP process if it doesn't exit within timeout
 	forcedKill := make(chan struct{})
 	go func() {
 		select {
 		case <-time.After(2 * time.Second):
 			lspLogger.Warn("LSP process did not exit within timeout, forcing kill")
-			if c.Cmd.Process != nil {
-				if err := c.Cmd.Process.Kill(); err != nil {
+			if cmd.Process != nil {
+				if err := cmd.Process.Kill(); err != nil {
 					lspLogger.Error("Failed to kill process: %v", err)
 				} else {
 					lspLogger.Info("Process killed successfully")
@@ -266,15 +615,17 @@ func (c *Client) Close() error {
 	}()
 
 	// Close stdin to signal the server
-	if err := c.stdin.Close(); err != nil {
+	if err := stdin.Close(); err != nil {
 		lspLogger.Error("Failed to close stdin: %v", err)
 	}
 
 	// Wait for process to exit
-	err := c.Cmd.Wait()
+	<-exited
 	close(forcedKill) // Stop the force kill goroutine
 
-	return err
+	c.transportMu.RLock()
+	defer c.transportMu.RUnlock()
+	return c.exitErr
 }
 
 type ServerState int
@@ -285,22 +636,74 @@ const (
 	StateError
 )
 
-func (c *Client) WaitForServerReady(ctx context.Context) error {
-	// TODO: wait for specific messages or poll workspace/symbol
-	time.Sleep(time.Second * 1)
-	return nil
+// SemanticTokensLegend returns the legend the server uses to encode semantic tokens
+func (c *Client) SemanticTokensLegend() (protocol.SemanticTokensLegend, error) {
+	provider := c.serverCapabilities.SemanticTokensProvider
+	if provider == nil {
+		return protocol.SemanticTokensLegend{}, fmt.Errorf("server does not support semantic tokens")
+	}
+
+	// The provider is decoded generically, so round trip it through JSON
+	data, err := json.Marshal(provider)
+	if err != nil {
+		return protocol.SemanticTokensLegend{}, fmt.Errorf("failed to marshal semantic tokens provider: %w", err)
+	}
+	var options protocol.SemanticTokensOptions
+	if err := json.Unmarshal(data, &options); err != nil {
+		return protocol.SemanticTokensLegend{}, fmt.Errorf("failed to parse semantic tokens provider: %w", err)
+	}
+	return options.Legend, nil
+}
+
+// SupportsCommand reports whether the server advertises a command for workspace/executeCommand
+func (c *Client) SupportsCommand(command string) bool {
+	provider := c.serverCapabilities.ExecuteCommandProvider
+	if provider == nil {
+		return false
+	}
+	for _, supported := range provider.Commands {
+		if supported == command {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsCompletionResolve reports whether the server resolves completion items,
+// filling in details it left out of the completion list
+func (c *Client) SupportsCompletionResolve() bool {
+	provider := c.serverCapabilities.CompletionProvider
+	return provider != nil && provider.ResolveProvider
 }
 
 type OpenFileInfo struct {
 	Version int32
 	URI     protocol.DocumentUri
+
+	// Content last sent to the server, which incremental changes are computed from
+	// and rewrites of a file with the same bytes are compared with
+	content string
+
+	// Set when content has staged edits, with base the content on disk they were
+	// made to
+	staged bool
+	base   string
+
+	// Hash of the content last reported with didSave
+	savedHash [sha256.Size]byte
+
+	// When the file was opened and last opened again by a tool, which decides
+	// the files closed first when too many are open
+	openedAt time.Time
+	lastUsed time.Time
 }
 
 func (c *Client) OpenFile(ctx context.Context, filepath string) error {
-	uri := fmt.Sprintf("file://%s", filepath)
+	uri := string(fileuri.FromPath(filepath))
 
 	c.openFilesMu.Lock()
-	if _, exists := c.openFiles[uri]; exists {
+	if fileInfo, exists := c.openFiles[uri]; exists {
+		fileInfo.lastUsed = time.Now()
 		c.openFilesMu.Unlock()
 		return nil // Already open
 	}
@@ -315,7 +718,7 @@ func (c *Client) OpenFile(ctx context.Context, filepath string) error {
 	params := protocol.DidOpenTextDocumentParams{
 		TextDocument: protocol.TextDocumentItem{
 			URI:        protocol.DocumentUri(uri),
-			LanguageID: DetectLanguageID(uri),
+			LanguageID: c.languageID(uri),
 			Version:    1,
 			Text:       string(content),
 		},
@@ -325,26 +728,57 @@ func (c *Client) OpenFile(ctx context.Context, filepath string) error {
 		return err
 	}
 
+	now := time.Now()
 	c.openFilesMu.Lock()
 	c.openFiles[uri] = &OpenFileInfo{
-		Version: 1,
-		URI:     protocol.DocumentUri(uri),
+		Version:  1,
+		URI:      protocol.DocumentUri(uri),
+		content:  string(content),
+		openedAt: now,
+		lastUsed: now,
 	}
 	c.openFilesMu.Unlock()
 
 	lspLogger.Debug("Opened file: %s", filepath)
+	c.closeLeastRecentlyUsed(ctx, uri)
 
 	return nil
 }
 
 func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
-	uri := fmt.Sprintf("file://%s", filepath)
-
 	content, err := os.ReadFile(filepath)
 	if err != nil {
 		return fmt.Errorf("error reading file: %w", err)
 	}
 
+	// Tools and builds often rewrite files with the same bytes, which would only
+	// make the server analyze them again
+	c.openFilesMu.RLock()
+	fileInfo, isOpen := c.openFiles[string(fileuri.FromPath(filepath))]
+	unchanged := isOpen && !fileInfo.staged && fileInfo.content == string(content)
+	c.openFilesMu.RUnlock()
+	if unchanged {
+		c.skippedChanges.Add(1)
+		lspLogger.Debug("Not sending unchanged content of %s", filepath)
+		return nil
+	}
+
+	c.changeMu.Lock()
+	defer c.changeMu.Unlock()
+
+	// The server keeps the staged content until the edits are committed or discarded
+	if c.HasStagedEdits(filepath) {
+		lspLogger.Debug("Not sending changes on disk of %s, which has staged edits", filepath)
+		return nil
+	}
+	return c.sendContent(ctx, filepath, string(content))
+}
+
+// sendContent sends the new content of an open document to the server. The caller
+// holds changeMu.
+func (c *Client) sendContent(ctx context.Context, filepath string, content string) error {
+	uri := string(fileuri.FromPath(filepath))
+
 	c.openFilesMu.Lock()
 	fileInfo, isOpen := c.openFiles[uri]
 	if !isOpen {
@@ -352,10 +786,20 @@ func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
 		return fmt.Errorf("cannot notify change for unopened file: %s", filepath)
 	}
 
+	// Tools and the watcher both report the files tools write, so the second
+	// report finds the server already up to date
+	if fileInfo.content == content {
+		c.openFilesMu.Unlock()
+		return nil
+	}
+
 	// Increment version
 	fileInfo.Version++
 	version := fileInfo.Version
+	previous := fileInfo.content
+	fileInfo.content = content
 	c.openFilesMu.Unlock()
+	c.recordEdit(protocol.DocumentUri(uri))
 
 	params := protocol.DidChangeTextDocumentParams{
 		TextDocument: protocol.VersionedTextDocumentIdentifier{
@@ -364,26 +808,24 @@ func (c *Client) NotifyChange(ctx context.Context, filepath string) error {
 			},
 			Version: version,
 		},
-		ContentChanges: []protocol.TextDocumentContentChangeEvent{
-			{
-				Value: protocol.TextDocumentContentChangeWholeDocument{
-					Text: string(content),
-				},
-			},
-		},
+		ContentChanges: c.contentChanges(previous, content),
 	}
 
 	return c.Notify(ctx, "textDocument/didChange", params)
 }
 
 func (c *Client) CloseFile(ctx context.Context, filepath string) error {
-	uri := fmt.Sprintf("file://%s", filepath)
+	uri := string(fileuri.FromPath(filepath))
 
 	c.openFilesMu.Lock()
-	if _, exists := c.openFiles[uri]; !exists {
+	fileInfo, exists := c.openFiles[uri]
+	if !exists {
 		c.openFilesMu.Unlock()
 		return nil // Already closed
 	}
+	if fileInfo.staged {
+		lspLogger.Warn("Closing %s drops its staged edits", filepath)
+	}
 	c.openFilesMu.Unlock()
 
 	params := protocol.DidCloseTextDocumentParams{
@@ -404,7 +846,7 @@ func (c *Client) CloseFile(ctx context.Context, filepath string) error {
 }
 
 func (c *Client) IsFileOpen(filepath string) bool {
-	uri := fmt.Sprintf("file://%s", filepath)
+	uri := string(fileuri.FromPath(filepath))
 	c.openFilesMu.RLock()
 	defer c.openFilesMu.RUnlock()
 	_, exists := c.openFiles[uri]
@@ -418,8 +860,8 @@ func (c *Client) CloseAllFiles(ctx context.Context) {
 
 	// First collect all URIs that need to be closed
 	for uri := range c.openFiles {
-		// Convert URI back to file path by trimming "file://" prefix
-		filePath := strings.TrimPrefix(uri, "file://")
+		// Convert URI back to file path
+		filePath := fileuri.ToPath(uri)
 		filesToClose = append(filesToClose, filePath)
 	}
 	c.openFilesMu.Unlock()
@@ -435,6 +877,18 @@ func (c *Client) CloseAllFiles(ctx context.Context) {
 	lspLogger.Debug("Closed %d files", len(filesToClose))
 }
 
+// GetAllDiagnostics returns a copy of the diagnostics cache for all files
+func (c *Client) GetAllDiagnostics() map[protocol.DocumentUri][]protocol.Diagnostic {
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+
+	diagnostics := make(map[protocol.DocumentUri][]protocol.Diagnostic, len(c.diagnostics))
+	for uri, diags := range c.diagnostics {
+		diagnostics[uri] = diags
+	}
+	return diagnostics
+}
+
 func (c *Client) GetFileDiagnostics(uri protocol.DocumentUri) []protocol.Diagnostic {
 	c.diagnosticsMu.RLock()
 	defer c.diagnosticsMu.RUnlock()
@@ -0,0 +1,160 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/isaacphi/mcp-language-server/internal/codeindex"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// ErrNoServer is returned for requests that a client without a language server
+// cannot answer
+var ErrNoServer = errors.New("no language server is running")
+
+// NewIndexClient returns a client that answers definition, references and hover
+// requests from a code index alone, without a language server. Notifications are
+// dropped and other requests fail with ErrNoServer.
+func NewIndexClient(index *codeindex.Index) *Client {
+	client := newClient()
+	client.offline = true
+	client.serverName = "code-index"
+	client.codeIndex = index
+	return client
+}
+
+// NewPlainTextClient creates a client without a language server, for files no
+// server handles. It reads and edits files, tracking open files and edits like
+// any other client, and answers every other request with ErrNoServer.
+func NewPlainTextClient() *Client {
+	client := newClient()
+	client.offline = true
+	client.serverName = "plain-text"
+	return client
+}
+
+// SetCodeIndex sets a prebuilt index that definition, references and hover
+// requests are answered from, for files unchanged since it was produced
+func (c *Client) SetCodeIndex(index *codeindex.Index) {
+	c.codeIndex = index
+}
+
+// CodeIndex returns the prebuilt index, or nil when there is none
+func (c *Client) CodeIndex() *codeindex.Index {
+	return c.codeIndex
+}
+
+// indexQuery holds the fields of the position requests the index answers
+type indexQuery struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Position     protocol.Position               `json:"position"`
+	Context      struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+// answerFromIndex answers a request from the code index and reports whether it
+// did. Requests about files that changed since the index was produced, or have
+// staged edits, are left to the server.
+func (c *Client) answerFromIndex(method string, params any, result any) (bool, error) {
+	index := c.codeIndex
+	if index == nil {
+		return false, nil
+	}
+	switch method {
+	case "textDocument/definition", "textDocument/references", "textDocument/hover":
+	default:
+		return false, nil
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return false, nil
+	}
+	var query indexQuery
+	if err := json.Unmarshal(data, &query); err != nil {
+		return false, nil
+	}
+	path := query.TextDocument.URI.Path()
+	if !c.indexCurrent(path) {
+		return false, nil
+	}
+
+	var answer any
+	switch method {
+	case "textDocument/definition":
+		locations, ok := index.Definition(path, query.Position, c.PositionEncoding())
+		if !ok || !c.indexCurrent(codeindex.Paths(locations)...) {
+			return false, nil
+		}
+		answer = locations
+	case "textDocument/references":
+		locations, ok := index.References(path, query.Position, query.Context.IncludeDeclaration, c.PositionEncoding())
+		if !ok || !c.indexCurrent(codeindex.Paths(locations)...) {
+			return false, nil
+		}
+		answer = locations
+	case "textDocument/hover":
+		hover, ok := index.Hover(path, query.Position, c.PositionEncoding())
+		if !ok {
+			return false, nil
+		}
+		answer = hover
+	}
+
+	data, err = json.Marshal(answer)
+	if err != nil {
+		return true, fmt.Errorf("failed to marshal index result: %w", err)
+	}
+	lspLogger.Debug("Answered %s for %s from the %s index", method, path, index.Format)
+	return true, json.Unmarshal(data, result)
+}
+
+// indexCurrent reports whether the index can be trusted for files: they are in it,
+// unchanged on disk and have no staged edits
+func (c *Client) indexCurrent(paths ...string) bool {
+	for _, path := range paths {
+		if !c.codeIndex.Has(path) || c.codeIndex.Changed(path) || c.HasStagedEdits(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// callOffline answers a request of an index-only client
+func (c *Client) callOffline(method string, params any, result any) error {
+	switch method {
+	case "initialize":
+		answer := protocol.InitializeResult{
+			ServerInfo: &protocol.ServerInfo{Name: c.serverName},
+		}
+		// Without an index, the client only reads and edits files
+		if c.codeIndex != nil {
+			answer.Capabilities = protocol.ServerCapabilities{
+				HoverProvider:      &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+				DefinitionProvider: &protocol.Or_ServerCapabilities_definitionProvider{Value: true},
+				ReferencesProvider: &protocol.Or_ServerCapabilities_referencesProvider{Value: true},
+			}
+		}
+		data, err := json.Marshal(answer)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, result)
+	case "shutdown":
+		return nil
+	}
+
+	if handled, err := c.answerFromIndex(method, params, result); handled {
+		return err
+	}
+	switch method {
+	case "textDocument/definition", "textDocument/references", "textDocument/hover":
+		return fmt.Errorf("%s: the file is not in the code index or changed since it was produced: %w", method, ErrNoServer)
+	}
+	if c.codeIndex != nil {
+		return fmt.Errorf("%s: %w, only the code index is available", method, ErrNoServer)
+	}
+	return fmt.Errorf("%s: %w for this file", method, ErrNoServer)
+}
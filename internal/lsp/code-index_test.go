@@ -0,0 +1,101 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/codeindex"
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLSIF = `{"id":1,"type":"vertex","label":"metaData","version":"0.4.3","projectRoot":"file://ROOT","positionEncoding":"utf-16"}
+{"id":2,"type":"vertex","label":"document","uri":"file://ROOT/main.go","languageId":"go"}
+{"id":3,"type":"vertex","label":"range","start":{"line":2,"character":5},"end":{"line":2,"character":10}}
+{"id":4,"type":"vertex","label":"range","start":{"line":4,"character":14},"end":{"line":4,"character":19}}
+{"id":5,"type":"vertex","label":"resultSet"}
+{"id":6,"type":"edge","label":"next","outV":3,"inV":5}
+{"id":7,"type":"edge","label":"next","outV":4,"inV":5}
+{"id":8,"type":"vertex","label":"definitionResult"}
+{"id":9,"type":"edge","label":"textDocument/definition","outV":5,"inV":8}
+{"id":10,"type":"edge","label":"item","outV":8,"inVs":[3],"document":2}
+{"id":11,"type":"vertex","label":"hoverResult","result":{"contents":{"kind":"markdown","value":"hello says hello"}}}
+{"id":12,"type":"edge","label":"textDocument/hover","outV":5,"inV":11}
+{"id":13,"type":"edge","label":"contains","outV":2,"inVs":[3,4]}
+`
+
+func TestIndexClient(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "main.go")
+	require.NoError(t, os.WriteFile(source, []byte("package main\n\nfunc hello() {}\n\nfunc main() { hello() }\n"), 0o644))
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(source, past, past))
+	indexPath := filepath.Join(root, "dump.lsif")
+	require.NoError(t, os.WriteFile(indexPath, []byte(strings.ReplaceAll(testLSIF, "ROOT", filepath.ToSlash(root))), 0o644))
+	index, err := codeindex.Load(indexPath, root)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client := NewIndexClient(index)
+	result, err := client.InitializeLSPClient(ctx, []string{root}, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, result.Capabilities.DefinitionProvider)
+	require.NoError(t, client.WaitForServerReady(ctx))
+
+	position := protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: fileuri.FromPath(source)},
+		Position:     protocol.Position{Line: 4, Character: 16},
+	}
+	definition, err := client.Definition(ctx, protocol.DefinitionParams{TextDocumentPositionParams: position})
+	require.NoError(t, err)
+	locations, ok := definition.Value.(protocol.Definition).Value.([]protocol.Location)
+	require.True(t, ok, "definition is %T", definition.Value)
+	require.Len(t, locations, 1)
+	assert.Equal(t, uint32(2), locations[0].Range.Start.Line)
+
+	hover, err := client.Hover(ctx, protocol.HoverParams{TextDocumentPositionParams: position})
+	require.NoError(t, err)
+	assert.Equal(t, "hello says hello", hover.Contents.Value)
+
+	// Without a server, what the index cannot answer fails
+	_, err = client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: "hello"})
+	assert.True(t, errors.Is(err, ErrNoServer))
+
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(source, future, future))
+	_, err = client.Definition(ctx, protocol.DefinitionParams{TextDocumentPositionParams: position})
+	assert.True(t, errors.Is(err, ErrNoServer), "changed files are not answered from the index")
+
+	require.NoError(t, client.Close())
+}
+
+func TestPlainTextClient(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "notes.md")
+	require.NoError(t, os.WriteFile(path, []byte("# Notes\n"), 0o644))
+
+	ctx := context.Background()
+	client := NewPlainTextClient()
+	result, err := client.InitializeLSPClient(ctx, []string{root}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, result.Capabilities.HoverProvider)
+	assert.False(t, client.SupportsMethod("textDocument/hover"))
+
+	require.NoError(t, client.OpenFile(ctx, path))
+	assert.True(t, client.IsFileOpen(path))
+	require.NoError(t, client.NotifyChange(ctx, path))
+
+	_, err = client.Hover(ctx, protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: fileuri.FromPath(path)},
+	}})
+	assert.True(t, errors.Is(err, ErrNoServer))
+
+	require.NoError(t, client.Close())
+}
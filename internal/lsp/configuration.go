@@ -0,0 +1,53 @@
+package lsp
+
+import (
+	"context"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SetSettings sets the settings used to answer workspace/configuration requests,
+// keyed by section name such as "python.analysis" or "rust-analyzer"
+func (c *Client) SetSettings(settings map[string]any) {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+	c.settings = settings
+}
+
+// configurationSection returns the configured settings for a section. A section is
+// found either under its full name or by following its dotted path through the
+// settings, so "python.analysis" matches both {"python.analysis": ...} and
+// {"python": {"analysis": ...}}.
+func (c *Client) configurationSection(section string) any {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+
+	if len(c.settings) == 0 {
+		return nil
+	}
+	if value, ok := c.settings[section]; ok {
+		return value
+	}
+	return sectionSettings(c.settings, section)
+}
+
+// UpdateConfiguration pushes new settings to the server with a
+// workspace/didChangeConfiguration notification. The initialization options are
+// kept for when the server is restarted, and the settings answer later
+// workspace/configuration requests. Servers that read their settings from the
+// notification receive the settings, or the initialization options when there are
+// none.
+func (c *Client) UpdateConfiguration(ctx context.Context, initOptions map[string]any, settings map[string]any) error {
+	c.restartMu.Lock()
+	c.initOptions = initOptions
+	c.restartMu.Unlock()
+	c.SetSettings(settings)
+
+	pushed := settings
+	if len(pushed) == 0 {
+		pushed = initOptions
+	}
+	return c.DidChangeConfiguration(ctx, protocol.DidChangeConfigurationParams{
+		Settings: pushed,
+	})
+}
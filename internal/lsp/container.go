@@ -0,0 +1,118 @@
+package lsp
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultContainerRuntime runs containers when no runtime is configured
+	DefaultContainerRuntime = "docker"
+	// DefaultContainerMountPath is where the primary workspace is mounted
+	DefaultContainerMountPath = "/workspace"
+)
+
+// ContainerWorkspace describes a language server that runs in a container, with
+// the workspace roots mounted into it
+type ContainerWorkspace struct {
+	// Image is the container image that provides the language server
+	Image string `json:"image"`
+	// Runtime is the container CLI, docker or podman, defaulting to docker
+	Runtime string `json:"runtime,omitempty"`
+	// MountPath is where the primary workspace is mounted, defaulting to
+	// /workspace. Other roots are mounted next to it, at /workspace-2 and so on.
+	MountPath string `json:"mountPath,omitempty"`
+	// Env sets environment variables in the container
+	Env map[string]string `json:"env,omitempty"`
+	// Args are passed to the run command before the image, such as more volumes
+	Args []string `json:"args,omitempty"`
+}
+
+// Validate checks the container configuration
+func (w ContainerWorkspace) Validate() error {
+	if w.Image == "" {
+		return fmt.Errorf("image is required")
+	}
+	if w.MountPath != "" && !path.IsAbs(w.MountPath) {
+		return fmt.Errorf("mountPath must be an absolute path")
+	}
+	return nil
+}
+
+// RuntimeCommand returns the container CLI to run
+func (w ContainerWorkspace) RuntimeCommand() string {
+	if w.Runtime == "" {
+		return DefaultContainerRuntime
+	}
+	return w.Runtime
+}
+
+// mountPaths returns where each workspace root is mounted in the container
+func (w ContainerWorkspace) mountPaths(roots []string) []string {
+	base := w.MountPath
+	if base == "" {
+		base = DefaultContainerMountPath
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	paths := make([]string, len(roots))
+	for i := range roots {
+		paths[i] = base
+		if i > 0 {
+			paths[i] = base + "-" + strconv.Itoa(i+1)
+		}
+	}
+	return paths
+}
+
+// NewContainerClient runs the language server command in a new container of the
+// configured image, with every workspace root mounted into it, and talks to it
+// over the container's stdio. Paths and URIs are translated between the host and
+// the container in both directions, as for NewRemoteClient.
+func NewContainerClient(container ContainerWorkspace, workspaceDirs []string, command string, args ...string) (*Client, error) {
+	if err := container.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid container: %w", err)
+	}
+
+	client := newClient()
+	client.command = container.RuntimeCommand()
+	client.args = containerArgs(container, workspaceDirs, command, args)
+	client.serverName = strings.TrimSuffix(filepath.Base(command), filepath.Ext(command))
+	client.paths = newPathMapping(workspaceDirs, container.mountPaths(workspaceDirs))
+
+	if err := client.start(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// containerArgs returns the arguments of the run command. The container is
+// removed when the server exits, and --init forwards signals to the server so
+// that stopping the client stops it.
+func containerArgs(container ContainerWorkspace, workspaceDirs []string, command string, args []string) []string {
+	mounts := container.mountPaths(workspaceDirs)
+	runArgs := []string{"run", "--rm", "-i", "--init"}
+	for i, dir := range workspaceDirs {
+		runArgs = append(runArgs, "--volume", dir+":"+mounts[i])
+	}
+	if len(mounts) > 0 {
+		runArgs = append(runArgs, "--workdir", mounts[0])
+	}
+
+	keys := make([]string, 0, len(container.Env))
+	for key := range container.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		runArgs = append(runArgs, "--env", key+"="+container.Env[key])
+	}
+
+	runArgs = append(runArgs, container.Args...)
+	runArgs = append(runArgs, container.Image, command)
+	return append(runArgs, args...)
+}
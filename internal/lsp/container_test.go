@@ -0,0 +1,43 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerArgs(t *testing.T) {
+	container := ContainerWorkspace{
+		Image: "eclipse-temurin:21",
+		Env:   map[string]string{"JAVA_OPTS": "-Xmx2g", "A": "1"},
+		Args:  []string{"--volume", "m2:/root/.m2"},
+	}
+	args := containerArgs(container, []string{"/home/me/app", "/home/me/lib"}, "jdtls", []string{"-data", "/tmp/jdtls"})
+
+	assert.Equal(t, []string{
+		"run", "--rm", "-i", "--init",
+		"--volume", "/home/me/app:/workspace", "--volume", "/home/me/lib:/workspace-2",
+		"--workdir", "/workspace",
+		"--env", "A=1", "--env", "JAVA_OPTS=-Xmx2g",
+		"--volume", "m2:/root/.m2",
+		"eclipse-temurin:21", "jdtls", "-data", "/tmp/jdtls",
+	}, args)
+	assert.Equal(t, "docker", container.RuntimeCommand())
+}
+
+func TestContainerPathMapping(t *testing.T) {
+	roots := []string{"/home/me/app", "/home/me/lib"}
+	container := ContainerWorkspace{Image: "clangd", MountPath: "/src/"}
+	m := newPathMapping(roots, container.mountPaths(roots))
+
+	received := &Message{Params: json.RawMessage(`{"uri":"file:///src/main.c","related":"file:///src-2/lib.h"}`)}
+	m.localMessage(received)
+	assert.JSONEq(t, `{"uri":"file:///home/me/app/main.c","related":"file:///home/me/lib/lib.h"}`, string(received.Params))
+}
+
+func TestContainerValidate(t *testing.T) {
+	assert.Error(t, ContainerWorkspace{}.Validate())
+	assert.Error(t, ContainerWorkspace{Image: "clangd", MountPath: "src"}.Validate())
+	assert.NoError(t, ContainerWorkspace{Image: "clangd"}.Validate())
+}
@@ -7,6 +7,25 @@ import (
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
+// SetLanguageIDs overrides the language IDs sent for files with the given
+// extensions, such as ".h" for C++ headers, or with the given file names, such as
+// "Tiltfile". Extensions are lower case and start with a dot.
+func (c *Client) SetLanguageIDs(languageIDs map[string]protocol.LanguageKind) {
+	c.languageIDs = languageIDs
+}
+
+// languageID returns the language ID to open a file with. A file name override
+// takes precedence over an extension override.
+func (c *Client) languageID(uri string) protocol.LanguageKind {
+	if id, ok := c.languageIDs[filepath.Base(uri)]; ok {
+		return id
+	}
+	if id, ok := c.languageIDs[strings.ToLower(filepath.Ext(uri))]; ok {
+		return id
+	}
+	return DetectLanguageID(uri)
+}
+
 func DetectLanguageID(uri string) protocol.LanguageKind {
 	ext := strings.ToLower(filepath.Ext(uri))
 	switch ext {
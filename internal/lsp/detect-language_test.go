@@ -0,0 +1,23 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguageIDOverrides(t *testing.T) {
+	c := newClient()
+	c.SetLanguageIDs(map[string]protocol.LanguageKind{
+		".gohtml":  "html",
+		".star":    "starlark",
+		"Tiltfile": "starlark",
+		"main.go":  "gotmpl",
+	})
+
+	assert.Equal(t, protocol.LanguageKind("html"), c.languageID("file:///app/templates/index.GOHTML"))
+	assert.Equal(t, protocol.LanguageKind("starlark"), c.languageID("file:///app/Tiltfile"))
+	assert.Equal(t, protocol.LanguageKind("gotmpl"), c.languageID("file:///app/main.go"))
+	assert.Equal(t, protocol.LangGo, c.languageID("file:///app/other.go"))
+}
@@ -0,0 +1,93 @@
+package lsp
+
+import (
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// maxDiagnosticsHistory bounds how many diagnostics updates are kept per file
+const maxDiagnosticsHistory = 50
+
+// DiagnosticsSnapshot is the diagnostics of a file as of one update from the server
+type DiagnosticsSnapshot struct {
+	Time        time.Time
+	Diagnostics []protocol.Diagnostic
+}
+
+// recordDiagnostics appends an update to the history of a file, dropping the
+// oldest updates beyond the limit. The caller holds diagnosticsMu.
+func (c *Client) recordDiagnostics(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic, at time.Time) {
+	history := append(c.diagnosticHistory[uri], DiagnosticsSnapshot{Time: at, Diagnostics: diagnostics})
+	if len(history) > maxDiagnosticsHistory {
+		history = history[len(history)-maxDiagnosticsHistory:]
+	}
+	c.diagnosticHistory[uri] = history
+}
+
+// recordEdit remembers when the content of a file was last sent to the server
+func (c *Client) recordEdit(uri protocol.DocumentUri) {
+	c.diagnosticsMu.Lock()
+	defer c.diagnosticsMu.Unlock()
+	c.lastEdits[uri] = time.Now()
+}
+
+// DiagnosticsHistory returns the diagnostics updates of a file, oldest first
+func (c *Client) DiagnosticsHistory(uri protocol.DocumentUri) []DiagnosticsSnapshot {
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+	return append([]DiagnosticsSnapshot(nil), c.diagnosticHistory[uri]...)
+}
+
+// DiagnosticsAt returns the update that gave a file its diagnostics at a time, and
+// false when the server had not reported any for it yet, or the update is no
+// longer kept
+func (c *Client) DiagnosticsAt(uri protocol.DocumentUri, at time.Time) (DiagnosticsSnapshot, bool) {
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+
+	history := c.diagnosticHistory[uri]
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].Time.After(at) {
+			return history[i], true
+		}
+	}
+	return DiagnosticsSnapshot{}, false
+}
+
+// LastEdit returns when the content of a file was last sent to the server
+func (c *Client) LastEdit(uri protocol.DocumentUri) (time.Time, bool) {
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+	at, ok := c.lastEdits[uri]
+	return at, ok
+}
+
+// DiagnosticsHistoryFiles returns the files with a diagnostics history
+func (c *Client) DiagnosticsHistoryFiles() []protocol.DocumentUri {
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+	uris := make([]protocol.DocumentUri, 0, len(c.diagnosticHistory))
+	for uri := range c.diagnosticHistory {
+		uris = append(uris, uri)
+	}
+	return uris
+}
+
+// SetCheckpoint records the current time under a name, for comparing diagnostics
+// against later
+func (c *Client) SetCheckpoint(name string) time.Time {
+	c.diagnosticsMu.Lock()
+	defer c.diagnosticsMu.Unlock()
+	now := time.Now()
+	c.checkpoints[name] = now
+	return now
+}
+
+// Checkpoint returns the time recorded under a name
+func (c *Client) Checkpoint(name string) (time.Time, bool) {
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+	at, ok := c.checkpoints[name]
+	return at, ok
+}
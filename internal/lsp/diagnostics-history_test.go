@@ -0,0 +1,50 @@
+package lsp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnosticsAt(t *testing.T) {
+	c := newClient()
+	uri := protocol.DocumentUri("file:///src/main.go")
+	start := time.Now()
+	first := []protocol.Diagnostic{{Message: "first"}}
+	second := []protocol.Diagnostic{{Message: "second"}}
+
+	c.diagnosticsMu.Lock()
+	c.recordDiagnostics(uri, first, start)
+	c.recordDiagnostics(uri, second, start.Add(time.Second))
+	c.diagnosticsMu.Unlock()
+
+	_, ok := c.DiagnosticsAt(uri, start.Add(-time.Millisecond))
+	assert.False(t, ok, "no update before the first")
+
+	snapshot, ok := c.DiagnosticsAt(uri, start.Add(500*time.Millisecond))
+	require.True(t, ok)
+	assert.Equal(t, first, snapshot.Diagnostics)
+
+	snapshot, ok = c.DiagnosticsAt(uri, start.Add(time.Second))
+	require.True(t, ok)
+	assert.Equal(t, second, snapshot.Diagnostics)
+}
+
+func TestDiagnosticsHistoryLimit(t *testing.T) {
+	c := newClient()
+	uri := protocol.DocumentUri("file:///src/main.go")
+	start := time.Now()
+
+	c.diagnosticsMu.Lock()
+	for i := 0; i < maxDiagnosticsHistory+10; i++ {
+		c.recordDiagnostics(uri, nil, start.Add(time.Duration(i)*time.Second))
+	}
+	c.diagnosticsMu.Unlock()
+
+	history := c.DiagnosticsHistory(uri)
+	require.Len(t, history, maxDiagnosticsHistory)
+	assert.Equal(t, start.Add(10*time.Second), history[0].Time, "the oldest updates are dropped")
+}
@@ -0,0 +1,170 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// DiagnosticsHandler is called when the diagnostics of a file change
+type DiagnosticsHandler func(uri protocol.DocumentUri)
+
+// SetDiagnosticsHandler registers a handler for diagnostics updates, whether
+// published by the server or pulled
+func (c *Client) SetDiagnosticsHandler(handler DiagnosticsHandler) {
+	c.diagnosticsMu.Lock()
+	defer c.diagnosticsMu.Unlock()
+	c.diagnosticsHandler = handler
+}
+
+// SupportsPullDiagnostics reports whether the server answers textDocument/diagnostic requests
+func (c *Client) SupportsPullDiagnostics() bool {
+	return c.serverCapabilities.DiagnosticProvider != nil
+}
+
+// SupportsWorkspaceDiagnostics reports whether the server answers workspace/diagnostic requests
+func (c *Client) SupportsWorkspaceDiagnostics() bool {
+	if c.serverCapabilities.DiagnosticProvider == nil {
+		return false
+	}
+
+	// The provider is decoded generically, so round trip it through JSON
+	data, err := json.Marshal(c.serverCapabilities.DiagnosticProvider.Value)
+	if err != nil {
+		return false
+	}
+	var options protocol.DiagnosticOptions
+	if err := json.Unmarshal(data, &options); err != nil {
+		return false
+	}
+	return options.WorkspaceDiagnostics
+}
+
+// PullDiagnostics requests diagnostics for a document and merges them into the
+// diagnostics cache. Diagnostics reported for related documents are cached as well.
+func (c *Client) PullDiagnostics(ctx context.Context, uri protocol.DocumentUri) error {
+	c.diagnosticsMu.RLock()
+	previousResultID := c.diagnosticResultIDs[uri]
+	c.diagnosticsMu.RUnlock()
+
+	report, err := c.Diagnostic(ctx, protocol.DocumentDiagnosticParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: uri},
+		PreviousResultID: previousResultID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pull diagnostics: %w", err)
+	}
+
+	// Full and unchanged reports can only be told apart by their kind
+	var related map[protocol.DocumentUri]interface{}
+	switch v := report.Value.(type) {
+	case protocol.RelatedFullDocumentDiagnosticReport:
+		c.updateDiagnostics(uri, v.Kind, v.ResultID, v.Items)
+		related = v.RelatedDocuments
+	case protocol.RelatedUnchangedDocumentDiagnosticReport:
+		c.updateDiagnostics(uri, v.Kind, v.ResultID, nil)
+		related = v.RelatedDocuments
+	}
+
+	for relatedURI, value := range related {
+		data, err := json.Marshal(value)
+		if err != nil {
+			lspLogger.Error("Error marshaling related diagnostics for %s: %v", relatedURI, err)
+			continue
+		}
+		var relatedReport protocol.FullDocumentDiagnosticReport
+		if err := json.Unmarshal(data, &relatedReport); err != nil {
+			lspLogger.Error("Error unmarshaling related diagnostics for %s: %v", relatedURI, err)
+			continue
+		}
+		c.updateDiagnostics(relatedURI, relatedReport.Kind, relatedReport.ResultID, relatedReport.Items)
+	}
+
+	return nil
+}
+
+// PullWorkspaceDiagnostics requests diagnostics for the whole workspace and merges
+// them into the diagnostics cache
+func (c *Client) PullWorkspaceDiagnostics(ctx context.Context) error {
+	c.diagnosticsMu.RLock()
+	previousResultIDs := make([]protocol.PreviousResultId, 0, len(c.diagnosticResultIDs))
+	for uri, resultID := range c.diagnosticResultIDs {
+		previousResultIDs = append(previousResultIDs, protocol.PreviousResultId{URI: uri, Value: resultID})
+	}
+	c.diagnosticsMu.RUnlock()
+
+	// Keep requests deterministic
+	sort.Slice(previousResultIDs, func(i, j int) bool {
+		return previousResultIDs[i].URI < previousResultIDs[j].URI
+	})
+
+	report, err := c.DiagnosticWorkspace(ctx, protocol.WorkspaceDiagnosticParams{
+		PreviousResultIds: previousResultIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pull workspace diagnostics: %w", err)
+	}
+
+	for _, item := range report.Items {
+		switch v := item.Value.(type) {
+		case protocol.WorkspaceFullDocumentDiagnosticReport:
+			c.updateDiagnostics(v.URI, v.Kind, v.ResultID, v.Items)
+		case protocol.WorkspaceUnchangedDocumentDiagnosticReport:
+			c.updateDiagnostics(v.URI, v.Kind, v.ResultID, nil)
+		}
+	}
+
+	return nil
+}
+
+// updateDiagnostics stores a pulled report in the cache. Unchanged reports keep
+// the cached diagnostics and only refresh the result id.
+func (c *Client) updateDiagnostics(uri protocol.DocumentUri, kind string, resultID string, items []protocol.Diagnostic) {
+	c.diagnosticsMu.Lock()
+	now := time.Now()
+	if kind != "unchanged" {
+		c.diagnostics[uri] = items
+		c.recordDiagnostics(uri, items, now)
+	}
+	c.diagnosticTimes[uri] = now
+	if resultID != "" {
+		c.diagnosticResultIDs[uri] = resultID
+	} else {
+		delete(c.diagnosticResultIDs, uri)
+	}
+	count := len(c.diagnostics[uri])
+	handler := c.diagnosticsHandler
+	c.diagnosticsMu.Unlock()
+
+	lspLogger.Debug("Pulled %s diagnostics for %s: %d items", kind, uri, count)
+
+	if handler != nil && kind != "unchanged" {
+		handler(uri)
+	}
+}
+
+// HandleDiagnosticRefresh processes workspace/diagnostic/refresh requests by pulling
+// diagnostics again for all open files
+func HandleDiagnosticRefresh(client *Client) (any, error) {
+	client.openFilesMu.RLock()
+	uris := make([]protocol.DocumentUri, 0, len(client.openFiles))
+	for uri := range client.openFiles {
+		uris = append(uris, protocol.DocumentUri(uri))
+	}
+	client.openFilesMu.RUnlock()
+
+	// Pull in the background so the server gets its response right away
+	go func() {
+		for _, uri := range uris {
+			if err := client.PullDiagnostics(context.Background(), uri); err != nil {
+				lspLogger.Error("Error refreshing diagnostics for %s: %v", uri, err)
+			}
+		}
+	}()
+
+	return nil, nil
+}
@@ -0,0 +1,268 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// The client keeps the content and version of every document open in the server.
+// Edits can be staged on a document: they are sent to the server as if made in an
+// unsaved editor buffer, so its diagnostics and navigation see them, but the file
+// on disk is only written when they are committed. While a document has staged
+// edits, changes to the file on disk are not sent to the server.
+
+// Document is a snapshot of a document open in the language server
+type Document struct {
+	Path    string
+	URI     protocol.DocumentUri
+	Version int32
+	// Content is the text the server has for the document
+	Content string
+	// Staged is set when the content has edits that are not on disk yet
+	Staged bool
+}
+
+// stagedDocument is what restarting the server must restore of a staged document
+type stagedDocument struct {
+	content string
+	base    string
+}
+
+// Document returns a snapshot of an open document
+func (c *Client) Document(path string) (Document, bool) {
+	uri := string(fileuri.FromPath(path))
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+
+	fileInfo, ok := c.openFiles[uri]
+	if !ok {
+		return Document{}, false
+	}
+	return Document{
+		Path:    path,
+		URI:     fileInfo.URI,
+		Version: fileInfo.Version,
+		Content: fileInfo.content,
+		Staged:  fileInfo.staged,
+	}, true
+}
+
+// ReadDocument returns the current content of a file: its staged content when it
+// has staged edits, and what is on disk otherwise
+func (c *Client) ReadDocument(path string) ([]byte, error) {
+	if doc, ok := c.Document(path); ok && doc.Staged {
+		return []byte(doc.Content), nil
+	}
+	return os.ReadFile(path)
+}
+
+// HasStagedEdits reports whether a file has edits that are not on disk yet
+func (c *Client) HasStagedEdits(path string) bool {
+	doc, ok := c.Document(path)
+	return ok && doc.Staged
+}
+
+// StagedFiles returns the files with staged edits, sorted
+func (c *Client) StagedFiles() []string {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+
+	var paths []string
+	for uri, fileInfo := range c.openFiles {
+		if fileInfo.staged {
+			paths = append(paths, fileuri.ToPath(uri))
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// StageEdits applies text edits to the server's copy of a file without writing it
+// to disk. Edits staged earlier are kept, so positions refer to the staged content.
+func (c *Client) StageEdits(ctx context.Context, path string, edits []protocol.TextEdit) error {
+	if err := c.OpenFile(ctx, path); err != nil {
+		return fmt.Errorf("could not open file: %w", err)
+	}
+
+	c.changeMu.Lock()
+	defer c.changeMu.Unlock()
+
+	content, err := c.ReadDocument(path)
+	if err != nil {
+		return err
+	}
+	newContent, err := utilities.EditContent(content, edits, c.PositionEncoding())
+	if err != nil {
+		return err
+	}
+
+	c.openFilesMu.Lock()
+	fileInfo, ok := c.openFiles[string(fileuri.FromPath(path))]
+	if !ok {
+		c.openFilesMu.Unlock()
+		return fmt.Errorf("cannot stage edits for unopened file: %s", path)
+	}
+	if !fileInfo.staged {
+		fileInfo.staged = true
+		fileInfo.base = string(content)
+	}
+	c.openFilesMu.Unlock()
+
+	return c.sendContent(ctx, path, string(newContent))
+}
+
+// CommitStaged writes a file's staged content to disk. It refuses when the file
+// changed on disk after its edits were staged, since that change would be lost.
+func (c *Client) CommitStaged(ctx context.Context, path string) error {
+	c.changeMu.Lock()
+	defer c.changeMu.Unlock()
+
+	c.openFilesMu.RLock()
+	fileInfo, ok := c.openFiles[string(fileuri.FromPath(path))]
+	if !ok || !fileInfo.staged {
+		c.openFilesMu.RUnlock()
+		return fmt.Errorf("%s has no staged edits", path)
+	}
+	content, base := fileInfo.content, fileInfo.base
+	c.openFilesMu.RUnlock()
+
+	disk, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+	if string(disk) != base {
+		return fmt.Errorf("%s changed on disk after its edits were staged, discard them and stage them again", path)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	// The server already has the content, so only the flag changes
+	c.openFilesMu.Lock()
+	fileInfo.staged = false
+	fileInfo.base = ""
+	c.openFilesMu.Unlock()
+
+	lspLogger.Debug("Committed staged edits of %s", path)
+	return nil
+}
+
+// DiscardStaged drops a file's staged edits and sends the server its content on disk
+func (c *Client) DiscardStaged(ctx context.Context, path string) error {
+	c.changeMu.Lock()
+	defer c.changeMu.Unlock()
+
+	c.openFilesMu.Lock()
+	fileInfo, ok := c.openFiles[string(fileuri.FromPath(path))]
+	if !ok || !fileInfo.staged {
+		c.openFilesMu.Unlock()
+		return fmt.Errorf("%s has no staged edits", path)
+	}
+	fileInfo.staged = false
+	fileInfo.base = ""
+	c.openFilesMu.Unlock()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+	lspLogger.Debug("Discarded staged edits of %s", path)
+	return c.sendContent(ctx, path, string(content))
+}
+
+// stagedDocuments returns the staged documents, keyed by path
+func (c *Client) stagedDocuments() map[string]stagedDocument {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+
+	staged := make(map[string]stagedDocument)
+	for uri, fileInfo := range c.openFiles {
+		if fileInfo.staged {
+			staged[fileuri.ToPath(uri)] = stagedDocument{content: fileInfo.content, base: fileInfo.base}
+		}
+	}
+	return staged
+}
+
+// restoreStaged stages documents again after the server restarted and reopened
+// them from disk
+func (c *Client) restoreStaged(ctx context.Context, staged map[string]stagedDocument) {
+	c.changeMu.Lock()
+	defer c.changeMu.Unlock()
+
+	for path, doc := range staged {
+		c.openFilesMu.Lock()
+		fileInfo, ok := c.openFiles[string(fileuri.FromPath(path))]
+		if ok {
+			fileInfo.staged = true
+			fileInfo.base = doc.base
+		}
+		c.openFilesMu.Unlock()
+		if !ok {
+			lspLogger.Warn("Lost the staged edits of %s, which could not be reopened", path)
+			continue
+		}
+		if err := c.sendContent(ctx, path, doc.content); err != nil {
+			lspLogger.Warn("Error restoring the staged edits of %s: %v", path, err)
+		}
+	}
+}
+
+// OffsetAt converts a position in a file to a byte offset in its current content
+func (c *Client) OffsetAt(path string, pos protocol.Position) (int, error) {
+	content, err := c.ReadDocument(path)
+	if err != nil {
+		return 0, err
+	}
+	return positionOffset(string(content), pos, c.PositionEncoding())
+}
+
+// PositionAt converts a byte offset in a file's current content to a position
+func (c *Client) PositionAt(path string, offset int) (protocol.Position, error) {
+	content, err := c.ReadDocument(path)
+	if err != nil {
+		return protocol.Position{}, err
+	}
+	return offsetPosition(string(content), offset, c.PositionEncoding())
+}
+
+// positionOffset converts a position in an encoding to a byte offset in content. A
+// character past the end of its line refers to the end of the line.
+func positionOffset(content string, pos protocol.Position, encoding protocol.PositionEncodingKind) (int, error) {
+	offset := 0
+	for line := uint32(0); line < pos.Line; line++ {
+		next := strings.IndexByte(content[offset:], '\n')
+		if next < 0 {
+			return 0, fmt.Errorf("line %d is past the end of the document (%d lines)", pos.Line+1, line+1)
+		}
+		offset += next + 1
+	}
+
+	lineEnd := strings.IndexByte(content[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(content) - offset
+	}
+	lineEnd = offset + lineEnd
+	if lineEnd > offset && content[lineEnd-1] == '\r' {
+		lineEnd--
+	}
+	return offset + utilities.ByteOffset(content[offset:lineEnd], pos.Character, encoding), nil
+}
+
+// offsetPosition converts a byte offset in content to a position in an encoding
+func offsetPosition(content string, offset int, encoding protocol.PositionEncodingKind) (protocol.Position, error) {
+	if offset < 0 || offset > len(content) {
+		return protocol.Position{}, fmt.Errorf("offset %d is outside the document (0-%d)", offset, len(content))
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n")
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return protocol.Position{Line: uint32(line), Character: utilities.CharacterOf(content[lineStart:], offset-lineStart, encoding)}, nil
+}
@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositionOffset(t *testing.T) {
+	content := "package main\r\n\r\nfunc main() {}"
+
+	tests := []struct {
+		pos    protocol.Position
+		offset int
+	}{
+		{protocol.Position{Line: 0, Character: 0}, 0},
+		{protocol.Position{Line: 0, Character: 8}, 8},
+		{protocol.Position{Line: 0, Character: 100}, 12},
+		{protocol.Position{Line: 1, Character: 0}, 14},
+		{protocol.Position{Line: 2, Character: 5}, 21},
+		{protocol.Position{Line: 2, Character: 14}, 30},
+	}
+	for _, tt := range tests {
+		offset, err := positionOffset(content, tt.pos, protocol.UTF16)
+		require.NoError(t, err)
+		assert.Equal(t, tt.offset, offset, "position %v", tt.pos)
+	}
+
+	_, err := positionOffset(content, protocol.Position{Line: 3}, protocol.UTF16)
+	assert.Error(t, err)
+}
+
+func TestOffsetPosition(t *testing.T) {
+	content := "a\nbc\n"
+
+	for offset, pos := range []protocol.Position{
+		{Line: 0, Character: 0},
+		{Line: 0, Character: 1},
+		{Line: 1, Character: 0},
+		{Line: 1, Character: 1},
+		{Line: 1, Character: 2},
+		{Line: 2, Character: 0},
+	} {
+		got, err := offsetPosition(content, offset, protocol.UTF16)
+		require.NoError(t, err)
+		assert.Equal(t, pos, got, "offset %d", offset)
+
+		back, err := positionOffset(content, got, protocol.UTF16)
+		require.NoError(t, err)
+		assert.Equal(t, offset, back)
+	}
+
+	_, err := offsetPosition(content, len(content)+1, protocol.UTF16)
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package lsp
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// FileOperation is a workspace file operation a server can ask to take part in
+type FileOperation string
+
+const (
+	WillCreate FileOperation = "willCreate"
+	DidCreate  FileOperation = "didCreate"
+	WillRename FileOperation = "willRename"
+	DidRename  FileOperation = "didRename"
+	WillDelete FileOperation = "willDelete"
+	DidDelete  FileOperation = "didDelete"
+)
+
+// HandlesFileOperation reports whether the server registered for a file
+// operation on the given path in its fileOperations capability
+func (c *Client) HandlesFileOperation(operation FileOperation, path string, isDir bool) bool {
+	workspace := c.serverCapabilities.Workspace
+	if workspace == nil || workspace.FileOperations == nil {
+		return false
+	}
+
+	var options *protocol.FileOperationRegistrationOptions
+	switch operation {
+	case WillCreate:
+		options = workspace.FileOperations.WillCreate
+	case DidCreate:
+		options = workspace.FileOperations.DidCreate
+	case WillRename:
+		options = workspace.FileOperations.WillRename
+	case DidRename:
+		options = workspace.FileOperations.DidRename
+	case WillDelete:
+		options = workspace.FileOperations.WillDelete
+	case DidDelete:
+		options = workspace.FileOperations.DidDelete
+	}
+	if options == nil {
+		return false
+	}
+
+	for _, filter := range options.Filters {
+		if matchesFileOperationFilter(filter, path, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesFileOperationFilter checks a path against one of the server's filters
+func matchesFileOperationFilter(filter protocol.FileOperationFilter, path string, isDir bool) bool {
+	if filter.Scheme != "" && filter.Scheme != "file" {
+		return false
+	}
+
+	if kind := filter.Pattern.Matches; kind != nil {
+		if (*kind == protocol.FilePattern && isDir) || (*kind == protocol.FolderPattern && !isDir) {
+			return false
+		}
+	}
+
+	glob := filter.Pattern.Glob
+	path = filepath.ToSlash(path)
+	if filter.Pattern.Options != nil && filter.Pattern.Options.IgnoreCase {
+		glob = strings.ToLower(glob)
+		path = strings.ToLower(path)
+	}
+	return utilities.MatchGlob(glob, path)
+}
+
+// openFilesUnder lists the open documents at or under path
+func (c *Client) openFilesUnder(path string) []string {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+	var paths []string
+	for uri := range c.openFiles {
+		if fileuri.InDir(uri, path) {
+			paths = append(paths, fileuri.ToPath(uri))
+		}
+	}
+	return paths
+}
+
+// RenameOpenFiles closes the open documents at or under oldPath and opens them
+// again at their new location, after the files have been renamed on disk
+func (c *Client) RenameOpenFiles(ctx context.Context, oldPath, newPath string) {
+	for _, path := range c.openFilesUnder(oldPath) {
+		if err := c.CloseFile(ctx, path); err != nil {
+			lspLogger.Error("Error closing renamed file %s: %v", path, err)
+		}
+		moved := newPath + strings.TrimPrefix(path, oldPath)
+		if err := c.OpenFile(ctx, moved); err != nil {
+			lspLogger.Error("Error opening renamed file %s: %v", moved, err)
+		}
+	}
+}
+
+// CloseOpenFiles closes the open documents at or under path, before it is deleted
+func (c *Client) CloseOpenFiles(ctx context.Context, path string) {
+	for _, open := range c.openFilesUnder(path) {
+		if err := c.CloseFile(ctx, open); err != nil {
+			lspLogger.Error("Error closing deleted file %s: %v", open, err)
+		}
+	}
+}
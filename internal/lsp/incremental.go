@@ -0,0 +1,140 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// maxDiffLines bounds the size of the changed region that is diffed line by line.
+// Larger regions are sent as a single range, which is still much smaller than the
+// whole document when the change is in the middle of a large file.
+const maxDiffLines = 5000
+
+// syncKind returns how the server wants document changes to be sent. Servers that
+// do not say are sent the whole document, as before incremental sync was supported.
+func (c *Client) syncKind() protocol.TextDocumentSyncKind {
+	switch sync := c.serverCapabilities.TextDocumentSync.(type) {
+	case float64:
+		return protocol.TextDocumentSyncKind(sync)
+	case map[string]any:
+		if options := c.textDocumentSync(); options != nil && options.Change != protocol.None {
+			return options.Change
+		}
+	}
+	return protocol.Full
+}
+
+// contentChanges returns the didChange events that turn the server's copy of a
+// document into its new content, honoring the server's sync kind
+func (c *Client) contentChanges(previous, content string) []protocol.TextDocumentContentChangeEvent {
+	// Line numbers only line up when lines end in \n or \r\n, since the protocol
+	// also counts a lone \r as a line break
+	if c.syncKind() == protocol.Incremental && !hasLoneCarriageReturn(previous) && !hasLoneCarriageReturn(content) {
+		return incrementalChanges(previous, content, c.PositionEncoding())
+	}
+	return []protocol.TextDocumentContentChangeEvent{
+		{
+			Value: protocol.TextDocumentContentChangeWholeDocument{
+				Text: content,
+			},
+		},
+	}
+}
+
+// incrementalChanges computes range based changes from the old content of a
+// document to the new one. Changes cover whole lines and are ordered from the end
+// of the document to the start, so each range refers to the document as it was
+// before any of them was applied. Positions are counted in the given encoding.
+func incrementalChanges(oldText, newText string, encoding protocol.PositionEncodingKind) []protocol.TextDocumentContentChangeEvent {
+	oldLines, newLines := splitLines(oldText), splitLines(newText)
+
+	// Edits usually touch a small part of a file, so the unchanged lines at the
+	// start and end are skipped before diffing
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	oldEnd, newEnd := len(oldLines)-suffix, len(newLines)-suffix
+
+	type hunk struct{ i1, i2, j1, j2 int }
+	var hunks []hunk
+	switch {
+	case prefix == oldEnd && prefix == newEnd:
+		// No change
+	case prefix == oldEnd || prefix == newEnd || (oldEnd-prefix)+(newEnd-prefix) > maxDiffLines:
+		hunks = append(hunks, hunk{prefix, oldEnd, prefix, newEnd})
+	default:
+		matcher := difflib.NewMatcher(oldLines[prefix:oldEnd], newLines[prefix:newEnd])
+		for _, op := range matcher.GetOpCodes() {
+			if op.Tag != 'e' {
+				hunks = append(hunks, hunk{prefix + op.I1, prefix + op.I2, prefix + op.J1, prefix + op.J2})
+			}
+		}
+	}
+
+	changes := make([]protocol.TextDocumentContentChangeEvent, 0, len(hunks))
+	for i := len(hunks) - 1; i >= 0; i-- {
+		h := hunks[i]
+		rng := protocol.Range{
+			Start: linePosition(oldLines, h.i1, encoding),
+			End:   linePosition(oldLines, h.i2, encoding),
+		}
+		changes = append(changes, protocol.TextDocumentContentChangeEvent{
+			Value: protocol.TextDocumentContentChangePartial{
+				Range: &rng,
+				Text:  strings.Join(newLines[h.j1:h.j2], ""),
+			},
+		})
+	}
+	return changes
+}
+
+// splitLines splits content into lines that keep their line endings. Only the
+// last line may lack one.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// linePosition returns the position of the start of line i, where i may be one past
+// the last line to refer to the end of the document
+func linePosition(lines []string, i int, encoding protocol.PositionEncodingKind) protocol.Position {
+	if i < len(lines) || i == 0 {
+		return protocol.Position{Line: uint32(i)}
+	}
+	last := lines[i-1]
+	if strings.HasSuffix(last, "\n") {
+		return protocol.Position{Line: uint32(i)}
+	}
+	// The document does not end with a line break, so its end is on the last line
+	return protocol.Position{Line: uint32(i - 1), Character: utilities.CharacterLength(last, encoding)}
+}
+
+// hasLoneCarriageReturn reports whether content has a \r that is not part of \r\n
+func hasLoneCarriageReturn(content string) bool {
+	for i := strings.IndexByte(content, '\r'); i >= 0; {
+		if i+1 >= len(content) || content[i+1] != '\n' {
+			return true
+		}
+		next := strings.IndexByte(content[i+1:], '\r')
+		if next < 0 {
+			return false
+		}
+		i += 1 + next
+	}
+	return false
+}
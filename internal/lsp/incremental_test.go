@@ -0,0 +1,101 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyChanges applies didChange events to content the way a server would
+func applyChanges(t *testing.T, content string, changes []protocol.TextDocumentContentChangeEvent) string {
+	for _, change := range changes {
+		partial, ok := change.Value.(protocol.TextDocumentContentChangePartial)
+		require.True(t, ok, "expected a range change, got %T", change.Value)
+		start := utf16Offset(t, content, partial.Range.Start)
+		end := utf16Offset(t, content, partial.Range.End)
+		require.LessOrEqual(t, start, end)
+		content = content[:start] + partial.Text + content[end:]
+	}
+	return content
+}
+
+// utf16Offset converts a UTF-16 based position to a byte offset in content
+func utf16Offset(t *testing.T, content string, pos protocol.Position) int {
+	offset := 0
+	for line := uint32(0); line < pos.Line; line++ {
+		next := strings.IndexByte(content[offset:], '\n')
+		require.GreaterOrEqual(t, next, 0, "line %d is past the end of the document", pos.Line)
+		offset += next + 1
+	}
+	units := uint32(0)
+	for i, r := range content[offset:] {
+		if units >= pos.Character || r == '\n' {
+			require.Equal(t, pos.Character, units)
+			return offset + i
+		}
+		units += uint32(len(utf16.Encode([]rune{r})))
+	}
+	require.Equal(t, pos.Character, units)
+	return len(content)
+}
+
+func TestIncrementalChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		changes  int
+	}{
+		{"unchanged", "a\nb\nc\n", "a\nb\nc\n", 0},
+		{"edit middle line", "a\nb\nc\n", "a\nB\nc\n", 1},
+		{"insert lines", "a\nc\n", "a\nb1\nb2\nc\n", 1},
+		{"delete lines", "a\nb\nc\nd\n", "a\nd\n", 1},
+		{"separate edits", "a\nb\nc\nd\ne\n", "A\nb\nc\nd\nE\n", 2},
+		{"append without trailing newline", "a\nb", "a\nb\nc", 1},
+		{"edit last line without trailing newline", "a\n😀 b", "a\n😀 c", 1},
+		{"remove trailing newline", "a\nb\n", "a\nb", 1},
+		{"from empty", "", "package main\n", 1},
+		{"to empty", "package main\n", "", 1},
+		{"crlf", "a\r\nb\r\nc\r\n", "a\r\nx\r\nc\r\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := incrementalChanges(tt.old, tt.new, protocol.UTF16)
+			assert.Len(t, changes, tt.changes)
+			assert.Equal(t, tt.new, applyChanges(t, tt.old, changes))
+		})
+	}
+}
+
+func TestIncrementalChangesLargeFile(t *testing.T) {
+	var lines []string
+	for i := 0; i < 3*maxDiffLines; i++ {
+		lines = append(lines, strings.Repeat("x", i%7)+"\n")
+	}
+	old := strings.Join(lines, "")
+
+	edited := append([]string(nil), lines...)
+	edited[10] = "first\n"
+	edited[len(edited)-10] = "last\n"
+	changes := incrementalChanges(old, strings.Join(edited, ""), protocol.UTF16)
+	assert.Len(t, changes, 1, "a changed region larger than maxDiffLines is one change")
+	assert.Equal(t, strings.Join(edited, ""), applyChanges(t, old, changes))
+
+	edited = append([]string(nil), lines...)
+	edited[100] = "changed\n"
+	changes = incrementalChanges(old, strings.Join(edited, ""), protocol.UTF16)
+	require.Len(t, changes, 1)
+	partial := changes[0].Value.(protocol.TextDocumentContentChangePartial)
+	assert.Equal(t, "changed\n", partial.Text)
+	assert.Equal(t, protocol.Range{Start: protocol.Position{Line: 100}, End: protocol.Position{Line: 101}}, *partial.Range)
+}
+
+func TestHasLoneCarriageReturn(t *testing.T) {
+	assert.False(t, hasLoneCarriageReturn("a\nb\n"))
+	assert.False(t, hasLoneCarriageReturn("a\r\nb\r\n"))
+	assert.True(t, hasLoneCarriageReturn("a\rb"))
+	assert.True(t, hasLoneCarriageReturn("a\r\nb\r"))
+}
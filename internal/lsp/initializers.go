@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ServerInitializer customizes how a particular language server is initialized.
+// Every field is optional.
+type ServerInitializer struct {
+	// InitializationOptions returns the initializationOptions sent when the user
+	// has not configured any
+	InitializationOptions func() map[string]any
+
+	// Configuration answers a workspace/configuration request for a section. It
+	// returns nil for sections it has no settings for.
+	Configuration func(section string) any
+
+	// AfterInitialize runs for each workspace root once the server has been
+	// initialized, and for workspace folders added later
+	AfterInitialize func(ctx context.Context, client *Client, workspaceDir string) error
+}
+
+var (
+	initializers   = map[string]ServerInitializer{}
+	initializersMu sync.RWMutex
+)
+
+// RegisterInitializer registers an initializer for a language server. The name is
+// the server's executable name without extension, or its key in the config file.
+func RegisterInitializer(name string, initializer ServerInitializer) {
+	initializersMu.Lock()
+	defer initializersMu.Unlock()
+	initializers[name] = initializer
+}
+
+func lookupInitializer(name string) (ServerInitializer, bool) {
+	initializersMu.RLock()
+	defer initializersMu.RUnlock()
+	initializer, ok := initializers[name]
+	return initializer, ok
+}
+
+// ServerName returns the name used to select the server's initializer
+func (c *Client) ServerName() string {
+	if c.serverName != "" {
+		return c.serverName
+	}
+	if c.command == "" {
+		return ""
+	}
+	name := filepath.Base(c.command)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// SetServerName overrides the name used to select the server's initializer, which
+// defaults to the name of the server's executable
+func (c *Client) SetServerName(name string) {
+	c.serverName = name
+}
+
+// initializer returns the initializer registered for the server, if any
+func (c *Client) initializer() ServerInitializer {
+	initializer, _ := lookupInitializer(c.ServerName())
+	return initializer
+}
+
+// initializationOptions returns the user's options, falling back to the defaults
+// of the server's initializer
+func (c *Client) initializationOptions(customConfig map[string]any) map[string]any {
+	if len(customConfig) > 0 {
+		return customConfig
+	}
+	if initializer := c.initializer(); initializer.InitializationOptions != nil {
+		return initializer.InitializationOptions()
+	}
+	return nil
+}
+
+// afterInitialize runs the initializer's post-initialization step for a workspace root
+func (c *Client) afterInitialize(ctx context.Context, workspaceDir string) error {
+	if initializer := c.initializer(); initializer.AfterInitialize != nil {
+		return initializer.AfterInitialize(ctx, c, workspaceDir)
+	}
+	return nil
+}
+
+// sectionSettings picks a section such as "python.analysis" out of nested settings
+func sectionSettings(settings map[string]any, section string) any {
+	if section == "" {
+		return settings
+	}
+
+	var current any = settings
+	for _, key := range strings.Split(section, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if current, ok = m[key]; !ok {
+			return nil
+		}
+	}
+	return current
+}
+
+// Built in initializers for common language servers
+func init() {
+	RegisterInitializer("gopls", ServerInitializer{
+		InitializationOptions: func() map[string]any {
+			return map[string]any{
+				"codelenses": map[string]bool{
+					"generate":           true,
+					"regenerate_cgo":     true,
+					"test":               true,
+					"tidy":               true,
+					"upgrade_dependency": true,
+					"vendor":             true,
+					"vulncheck":          false,
+				},
+			}
+		},
+	})
+
+	RegisterInitializer("typescript-language-server", ServerInitializer{
+		AfterInitialize: initializeTypescriptLanguageServer,
+	})
+
+	pyright := ServerInitializer{
+		Configuration: func(section string) any {
+			return sectionSettings(map[string]any{
+				"python": map[string]any{
+					"analysis": map[string]any{
+						"autoSearchPaths":        true,
+						"useLibraryCodeForTypes": true,
+						"diagnosticMode":         "workspace",
+					},
+				},
+			}, section)
+		},
+	}
+	RegisterInitializer("pyright-langserver", pyright)
+	RegisterInitializer("pyright", pyright)
+
+	rustAnalyzer := map[string]any{
+		"cargo": map[string]any{
+			"buildScripts": map[string]any{"enable": true},
+		},
+		"procMacro": map[string]any{"enable": true},
+	}
+	RegisterInitializer("rust-analyzer", ServerInitializer{
+		InitializationOptions: func() map[string]any { return rustAnalyzer },
+		Configuration: func(section string) any {
+			return sectionSettings(map[string]any{"rust-analyzer": rustAnalyzer}, section)
+		},
+	})
+
+	RegisterInitializer("clangd", ServerInitializer{
+		InitializationOptions: func() map[string]any {
+			return map[string]any{"clangdFileStatus": true}
+		},
+	})
+
+	RegisterInitializer("jdtls", ServerInitializer{
+		InitializationOptions: func() map[string]any {
+			return map[string]any{
+				"extendedClientCapabilities": map[string]any{
+					"classFileContentsSupport": true,
+				},
+			}
+		},
+	})
+}
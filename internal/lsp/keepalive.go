@@ -0,0 +1,64 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// pingMethod is not implemented by any server. Servers must answer requests
+	// starting with $/ that they do not implement with an error, which is enough
+	// to know they are responsive.
+	pingMethod = "$/mcp-language-server/ping"
+
+	// pingTimeout is how long a server has to answer a ping
+	pingTimeout = 10 * time.Second
+)
+
+// Ping sends a request the server answers without doing any work, and reports an
+// error when it does not answer in time
+func (c *Client) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	err := c.Call(ctx, pingMethod, struct{}{}, nil)
+	var responseErr *ResponseError
+	if err == nil || errors.As(err, &responseErr) {
+		return nil
+	}
+	return err
+}
+
+// KeepAlive pings the server every interval until the context ends, which keeps
+// connections to servers at an address from being dropped as idle. A server that
+// does not answer is taken to be hung and is restarted.
+func (c *Client) KeepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Skip pings while the server is shutting down or being restarted
+		if c.closing.Load() || c.supervising.Load() || !c.initialized.Load() {
+			continue
+		}
+		err := c.Ping(ctx)
+		if err == nil || ctx.Err() != nil || c.closing.Load() {
+			continue
+		}
+
+		c.emitRestartEvent(RestartEvent{Message: "Language server did not answer a keep-alive ping, restarting it", Err: err})
+		restartCtx, cancel := context.WithTimeout(ctx, restartTimeout)
+		if err := c.Restart(restartCtx); err != nil {
+			c.emitRestartEvent(RestartEvent{Message: "Language server restart failed", Err: fmt.Errorf("after a missed keep-alive ping: %w", err)})
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,48 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPing(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	c := newClient()
+	c.stdin = clientConn
+	go c.handleMessages(bufio.NewReader(clientConn))
+
+	// Answer the first ping like a server that does not implement it, and leave
+	// later ones unanswered
+	go func() {
+		reader := bufio.NewReader(serverConn)
+		for answered := false; ; answered = true {
+			msg, err := ReadMessage(reader)
+			if err != nil {
+				return
+			}
+			if answered {
+				continue
+			}
+			_ = WriteMessage(serverConn, &Message{
+				JSONRPC: "2.0",
+				ID:      msg.ID,
+				Error:   &ResponseError{Code: -32601, Message: "method not found: " + msg.Method},
+			})
+		}
+	}()
+
+	require.NoError(t, c.Ping(context.Background()), "an error response still shows the server is responsive")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Error(t, c.Ping(ctx), "a ping without an answer fails")
+}
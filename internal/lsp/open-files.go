@@ -0,0 +1,93 @@
+package lsp
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+)
+
+// OpenDocumentInfo describes a file open in the server
+type OpenDocumentInfo struct {
+	Path     string
+	Version  int32
+	OpenedAt time.Time
+	LastUsed time.Time
+	Staged   bool
+}
+
+// SetMaxOpenFiles caps how many files are kept open in the server, 0 for no
+// limit. Past the cap, opening a file closes the least recently used ones, since
+// some servers slow down badly with hundreds of open documents.
+func (c *Client) SetMaxOpenFiles(limit int) {
+	c.maxOpenFiles = limit
+}
+
+// MaxOpenFiles returns the cap on open files, 0 for no limit
+func (c *Client) MaxOpenFiles() int {
+	return c.maxOpenFiles
+}
+
+// OpenDocuments returns the files open in the server, most recently used first
+func (c *Client) OpenDocuments() []OpenDocumentInfo {
+	c.openFilesMu.RLock()
+	docs := make([]OpenDocumentInfo, 0, len(c.openFiles))
+	for uri, fileInfo := range c.openFiles {
+		docs = append(docs, OpenDocumentInfo{
+			Path:     fileuri.ToPath(uri),
+			Version:  fileInfo.Version,
+			OpenedAt: fileInfo.openedAt,
+			LastUsed: fileInfo.lastUsed,
+			Staged:   fileInfo.staged,
+		})
+	}
+	c.openFilesMu.RUnlock()
+
+	sort.Slice(docs, func(i, j int) bool {
+		if !docs[i].LastUsed.Equal(docs[j].LastUsed) {
+			return docs[i].LastUsed.After(docs[j].LastUsed)
+		}
+		return docs[i].Path < docs[j].Path
+	})
+	return docs
+}
+
+// closeLeastRecentlyUsed closes the least recently used files while more are open
+// than the cap allows. The file just opened and files with staged edits, which
+// closing would drop, stay open.
+func (c *Client) closeLeastRecentlyUsed(ctx context.Context, keep string) {
+	if c.maxOpenFiles <= 0 {
+		return
+	}
+
+	c.openFilesMu.RLock()
+	excess := len(c.openFiles) - c.maxOpenFiles
+	type candidate struct {
+		path     string
+		lastUsed time.Time
+	}
+	var candidates []candidate
+	if excess > 0 {
+		for uri, fileInfo := range c.openFiles {
+			if uri != keep && !fileInfo.staged {
+				candidates = append(candidates, candidate{fileuri.ToPath(uri), fileInfo.lastUsed})
+			}
+		}
+	}
+	c.openFilesMu.RUnlock()
+	if excess <= 0 {
+		return
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].lastUsed.Before(candidates[j].lastUsed)
+	})
+	for _, candidate := range candidates[:min(excess, len(candidates))] {
+		path := candidate.path
+		lspLogger.Debug("Closing least recently used file %s", path)
+		if err := c.CloseFile(ctx, path); err != nil {
+			lspLogger.Warn("Error closing %s: %v", path, err)
+		}
+	}
+}
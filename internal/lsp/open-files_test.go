@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestCloseLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	for _, name := range []string{"a.go", "b.go", "c.go", "d.go"} {
+		require.NoError(t, os.WriteFile(path(name), []byte("package main\n"), 0644))
+	}
+
+	c := newClient()
+	c.stdin = nopWriteCloser{&bytes.Buffer{}}
+	c.SetMaxOpenFiles(2)
+	ctx := context.Background()
+
+	require.NoError(t, c.OpenFile(ctx, path("a.go")))
+	require.NoError(t, c.OpenFile(ctx, path("b.go")))
+	// Using a again leaves b the least recently used
+	require.NoError(t, c.OpenFile(ctx, path("a.go")))
+	require.NoError(t, c.OpenFile(ctx, path("c.go")))
+
+	assert.True(t, c.IsFileOpen(path("a.go")))
+	assert.False(t, c.IsFileOpen(path("b.go")))
+	assert.True(t, c.IsFileOpen(path("c.go")))
+
+	// Files with staged edits stay open
+	c.openFilesMu.Lock()
+	c.openFiles["file://"+path("a.go")].staged = true
+	c.openFilesMu.Unlock()
+	require.NoError(t, c.OpenFile(ctx, path("d.go")))
+
+	assert.True(t, c.IsFileOpen(path("a.go")))
+	assert.False(t, c.IsFileOpen(path("c.go")))
+	docs := c.OpenDocuments()
+	require.Len(t, docs, 2)
+	assert.Equal(t, path("d.go"), docs[0].Path)
+}
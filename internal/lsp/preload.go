@@ -0,0 +1,217 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// Preload strategies
+const (
+	// Open nothing at startup, leaving the server to index lazily
+	PreloadNone = "none"
+	// Open the files matching globs, relative to each workspace root
+	PreloadGlobs = "globs"
+	// Open the files changed in the most recent git commits and the working tree
+	PreloadGitRecent = "gitRecent"
+)
+
+const (
+	defaultPreloadMaxFiles = 50
+	defaultPreloadCommits  = 20
+)
+
+// globSkippedDirs are directories not searched by GlobFiles
+var globSkippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"dist":         true,
+	"build":        true,
+}
+
+// PreloadPolicy configures which files PreloadFiles opens at startup, so the
+// first queries do not come back empty while the server indexes lazily
+type PreloadPolicy struct {
+	Strategy string `json:"strategy,omitempty"`
+	// Globs matched by the globs strategy, relative to each workspace root
+	Globs []string `json:"globs,omitempty"`
+	// Most files to open, defaults to 50
+	MaxFiles int `json:"maxFiles,omitempty"`
+	// Commits searched by the gitRecent strategy, defaults to 20
+	Commits int `json:"commits,omitempty"`
+}
+
+// Validate checks that the policy names a known strategy and has the settings it needs
+func (p PreloadPolicy) Validate() error {
+	switch p.Strategy {
+	case "", PreloadNone, PreloadGitRecent:
+	case PreloadGlobs:
+		if len(p.Globs) == 0 {
+			return fmt.Errorf("the globs preload strategy requires globs")
+		}
+	default:
+		return fmt.Errorf("unknown preload strategy: %s", p.Strategy)
+	}
+	if p.MaxFiles < 0 {
+		return fmt.Errorf("preload maxFiles must not be negative")
+	}
+	if p.Commits < 0 {
+		return fmt.Errorf("preload commits must not be negative")
+	}
+	return nil
+}
+
+// SetPreloadPolicy sets the files PreloadFiles opens
+func (c *Client) SetPreloadPolicy(policy PreloadPolicy) {
+	c.preload = policy
+}
+
+// PreloadFiles opens the files chosen by the preload policy in every workspace
+// root, up to its limit, and returns how many it opened
+func (c *Client) PreloadFiles(ctx context.Context) int {
+	policy := c.preload
+	maxFiles := policy.MaxFiles
+	if maxFiles == 0 {
+		maxFiles = defaultPreloadMaxFiles
+	}
+
+	var paths []string
+	for _, root := range c.WorkspaceRoots() {
+		var found []string
+		var err error
+		switch policy.Strategy {
+		case PreloadGlobs:
+			found, err = GlobFiles(root, policy.Globs, maxFiles-len(paths))
+		case PreloadGitRecent:
+			commits := policy.Commits
+			if commits == 0 {
+				commits = defaultPreloadCommits
+			}
+			found, err = preloadGitRecent(ctx, root, commits, maxFiles-len(paths))
+		default:
+			return 0
+		}
+		if err != nil {
+			lspLogger.Warn("Failed to find files to preload in %s: %v", root, err)
+		}
+		paths = append(paths, found...)
+		if len(paths) >= maxFiles {
+			break
+		}
+	}
+
+	opened := 0
+	for _, path := range paths {
+		if err := c.OpenFile(ctx, path); err != nil {
+			lspLogger.Debug("Failed to preload %s: %v", path, err)
+			continue
+		}
+		opened++
+	}
+	lspLogger.Info("Preloaded %d files with the %s strategy", opened, policy.Strategy)
+	return opened
+}
+
+// GlobFiles returns up to limit files under root matching any of the globs,
+// relative to root, skipping hidden and dependency directories
+func GlobFiles(root string, globs []string, limit int) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if len(paths) >= limit {
+			return filepath.SkipAll
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || globSkippedDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		for _, glob := range globs {
+			if utilities.MatchGlob(glob, filepath.ToSlash(rel)) {
+				paths = append(paths, path)
+				break
+			}
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// preloadGitRecent returns up to limit files under root with uncommitted changes
+// or changed in the last commits, most recent first
+func preloadGitRecent(ctx context.Context, root string, commits int, limit int) ([]string, error) {
+	status, err := exec.CommandContext(ctx, "git", "-C", root, "status", "--porcelain", "--no-renames").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git status failed: %w", err)
+	}
+	var names []string
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		if line := scanner.Text(); len(line) > 3 {
+			names = append(names, line[3:])
+		}
+	}
+
+	log, err := exec.CommandContext(ctx, "git", "-C", root, "log", "--name-only", "--pretty=format:",
+		"--no-renames", fmt.Sprintf("-n%d", commits)).Output()
+	if err != nil {
+		// Repositories without commits have no log
+		lspLogger.Debug("git log failed in %s: %v", root, err)
+	}
+	scanner = bufio.NewScanner(bytes.NewReader(log))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			names = append(names, line)
+		}
+	}
+
+	// Paths are relative to the top of the repository, which may be above root
+	top, err := exec.CommandContext(ctx, "git", "-C", root, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	repo := strings.TrimSpace(string(top))
+	// git reports the repository with symlinks resolved
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		resolvedRoot = root
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	for _, name := range names {
+		if len(paths) >= limit {
+			break
+		}
+		rel, err := filepath.Rel(resolvedRoot, filepath.Join(repo, filepath.FromSlash(strings.Trim(name, `"`))))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		path := filepath.Join(root, rel)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
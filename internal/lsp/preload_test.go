@@ -0,0 +1,39 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"main.go", "README.md", "pkg/a.go", "pkg/b.go", "vendor/dep/c.go", ".cache/d.go"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+
+	paths, err := GlobFiles(root, []string{"**/*.go"}, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(root, "main.go"),
+		filepath.Join(root, "pkg", "a.go"),
+		filepath.Join(root, "pkg", "b.go"),
+	}, paths)
+
+	paths, err = GlobFiles(root, []string{"**/*.go"}, 2)
+	require.NoError(t, err)
+	assert.Len(t, paths, 2)
+}
+
+func TestPreloadPolicyValidate(t *testing.T) {
+	assert.NoError(t, PreloadPolicy{}.Validate())
+	assert.NoError(t, PreloadPolicy{Strategy: PreloadGitRecent, Commits: 5}.Validate())
+	assert.Error(t, PreloadPolicy{Strategy: PreloadGlobs}.Validate())
+	assert.Error(t, PreloadPolicy{Strategy: "everything"}.Validate())
+	assert.Error(t, PreloadPolicy{MaxFiles: -1}.Validate())
+}
@@ -0,0 +1,168 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// progressPollInterval is how often WaitForIdle checks for server activity
+const progressPollInterval = 50 * time.Millisecond
+
+// recordActivity notes that the server has just reported progress or diagnostics
+func (c *Client) recordActivity() {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+	c.lastActivity = time.Now()
+}
+
+// WaitForIdle blocks until the server has no work done progress in flight and has not
+// published diagnostics or progress for the debounce duration. It gives up after timeout.
+func (c *Client) WaitForIdle(ctx context.Context, debounce, timeout time.Duration) error {
+	start := time.Now()
+	deadline := start.Add(timeout)
+
+	ticker := time.NewTicker(progressPollInterval)
+	defer ticker.Stop()
+
+	for {
+		c.progressMu.Lock()
+		active := len(c.activeProgress)
+		lastActivity := c.lastActivity
+		c.progressMu.Unlock()
+
+		// Only activity after the wait started counts
+		if lastActivity.Before(start) {
+			lastActivity = start
+		}
+
+		if active == 0 && time.Since(lastActivity) >= debounce {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v waiting for the server to become idle (%d tasks in progress)", timeout, active)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitForProgress blocks until the server ends the work done progress with the given
+// token, such as one returned by a command that runs in the background, and returns
+// the final message the server reported. It gives up after timeout.
+func (c *Client) WaitForProgress(ctx context.Context, token string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+
+	ticker := time.NewTicker(progressPollInterval)
+	defer ticker.Stop()
+
+	for {
+		c.progressMu.Lock()
+		message, ended := c.endedTokens[token]
+		if ended {
+			delete(c.endedTokens, token)
+		}
+		c.progressMu.Unlock()
+
+		if ended {
+			return message, nil
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("timed out after %v waiting for the server to finish", timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProgressEvent is a work done progress report from the server
+type ProgressEvent struct {
+	Token string
+	// One of begin, report or end
+	Kind    string
+	Title   string
+	Message string
+	// Set when the server reports how far along the task is, from 0 to 100
+	Percentage *float64
+}
+
+// ProgressHandler is called for each work done progress report from the server
+type ProgressHandler func(event ProgressEvent)
+
+// SetProgressHandler registers a handler for work done progress reported by the
+// server, such as indexing a large workspace
+func (c *Client) SetProgressHandler(handler ProgressHandler) {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+	c.progressHandler = handler
+}
+
+// HandleWorkDoneProgressCreate processes window/workDoneProgress/create requests
+func HandleWorkDoneProgressCreate(client *Client, params json.RawMessage) (any, error) {
+	var createParams protocol.WorkDoneProgressCreateParams
+	if err := json.Unmarshal(params, &createParams); err != nil {
+		lspLogger.Error("Error unmarshaling progress create params: %v", err)
+		return nil, err
+	}
+
+	lspLogger.Debug("Progress created: %v", createParams.Token.Value)
+	client.recordActivity()
+	return nil, nil
+}
+
+// HandleProgress processes $/progress notifications, tracking which work done
+// progress tasks are still running
+func HandleProgress(client *Client, params json.RawMessage) {
+	var progressParams protocol.ProgressParams
+	if err := json.Unmarshal(params, &progressParams); err != nil {
+		lspLogger.Error("Error unmarshaling progress params: %v", err)
+		return
+	}
+
+	// Only work done progress has a kind, other progress is partial results
+	value, ok := progressParams.Value.(map[string]any)
+	if !ok {
+		return
+	}
+	kind, _ := value["kind"].(string)
+	token := fmt.Sprint(progressParams.Token.Value)
+	message, _ := value["message"].(string)
+
+	client.progressMu.Lock()
+	title := client.activeProgress[token]
+	switch kind {
+	case "begin":
+		title, _ = value["title"].(string)
+		client.activeProgress[token] = title
+	case "end":
+		delete(client.activeProgress, token)
+		client.endedProgress[title] = true
+		client.endedTokens[token] = message
+	}
+	client.lastActivity = time.Now()
+	handler := client.progressHandler
+	client.progressMu.Unlock()
+
+	lspLogger.Debug("Progress %s: %s %s %s", kind, token, title, message)
+
+	if handler != nil && kind != "" {
+		event := ProgressEvent{Token: token, Kind: kind, Title: title, Message: message}
+		if percentage, ok := value["percentage"].(float64); ok {
+			event.Percentage = &percentage
+		}
+		handler(event)
+	}
+}
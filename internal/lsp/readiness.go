@@ -0,0 +1,148 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// Readiness strategies
+const (
+	// Wait until the server stops reporting progress and diagnostics
+	ReadinessIdle = "idle"
+	// Poll workspace/symbol until it returns results
+	ReadinessWorkspaceSymbol = "workspaceSymbol"
+	// Wait for a work done progress task with a matching title to end
+	ReadinessProgress = "progress"
+	// Wait for the first diagnostics to be published
+	ReadinessDiagnostics = "diagnostics"
+)
+
+const (
+	defaultReadinessTimeout = 30 * time.Second
+	readinessPollInterval   = 500 * time.Millisecond
+)
+
+// ReadinessProbe configures how WaitForServerReady decides that the server has
+// finished starting up
+type ReadinessProbe struct {
+	Strategy string `json:"strategy,omitempty"`
+	// Query sent by the workspaceSymbol strategy
+	Query string `json:"query,omitempty"`
+	// Title, or part of the title, of the task the progress strategy waits for
+	ProgressTitle string `json:"progressTitle,omitempty"`
+	// How long to wait before giving up, defaults to 30 seconds
+	TimeoutMs int `json:"timeoutMs,omitempty"`
+}
+
+// Validate checks that the probe names a known strategy and has the settings it needs
+func (p ReadinessProbe) Validate() error {
+	switch p.Strategy {
+	case "", ReadinessIdle, ReadinessWorkspaceSymbol, ReadinessDiagnostics:
+	case ReadinessProgress:
+		if p.ProgressTitle == "" {
+			return fmt.Errorf("the progress readiness strategy requires progressTitle")
+		}
+	default:
+		return fmt.Errorf("unknown readiness strategy: %s", p.Strategy)
+	}
+	if p.TimeoutMs < 0 {
+		return fmt.Errorf("readiness timeoutMs must not be negative")
+	}
+	return nil
+}
+
+// Timeout returns how long the probe waits before startup continues without it
+func (p ReadinessProbe) Timeout() time.Duration {
+	if p.TimeoutMs > 0 {
+		return time.Duration(p.TimeoutMs) * time.Millisecond
+	}
+	return defaultReadinessTimeout
+}
+
+// SetReadinessProbe sets the strategy used by WaitForServerReady
+func (c *Client) SetReadinessProbe(probe ReadinessProbe) {
+	c.readiness = probe
+}
+
+// WaitForServerReady blocks until the readiness probe reports that the server has
+// finished starting up
+func (c *Client) WaitForServerReady(ctx context.Context) error {
+	// The code index of an index-only client is ready as soon as it is loaded
+	if c.offline {
+		c.serverReady.Store(true)
+		return nil
+	}
+
+	probe := c.readiness
+	timeout := probe.Timeout()
+
+	var err error
+	switch probe.Strategy {
+	case ReadinessWorkspaceSymbol:
+		err = c.pollUntilReady(ctx, timeout, func() bool {
+			result, err := c.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: probe.Query})
+			if err != nil {
+				return false
+			}
+			results, err := result.Results()
+			return err == nil && len(results) > 0
+		})
+	case ReadinessProgress:
+		err = c.pollUntilReady(ctx, timeout, func() bool {
+			return c.progressEnded(probe.ProgressTitle)
+		})
+	case ReadinessDiagnostics:
+		err = c.pollUntilReady(ctx, timeout, c.diagnosticsReceived.Load)
+	default:
+		// Wait for startup work such as indexing to finish
+		err = c.WaitForIdle(ctx, time.Second, timeout)
+	}
+
+	// Servers that are slow to start should not block startup, so a timeout is not an error
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		lspLogger.Warn("Server not ready after startup: %v", err)
+	}
+	c.serverReady.Store(true)
+	return nil
+}
+
+// pollUntilReady calls ready until it returns true or the timeout passes
+func (c *Client) pollUntilReady(ctx context.Context, timeout time.Duration, ready func() bool) error {
+	deadline := time.Now().Add(timeout)
+
+	ticker := time.NewTicker(readinessPollInterval)
+	defer ticker.Stop()
+
+	for !ready() {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v waiting for the server to become ready", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// progressEnded reports whether a work done progress task whose title contains
+// title has ended
+func (c *Client) progressEnded(title string) bool {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+
+	for ended := range c.endedProgress {
+		if strings.Contains(ended, title) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+)
+
+// RemoteWorkspace describes a language server that runs on another host over SSH,
+// on a copy of the workspace found at a different path there
+type RemoteWorkspace struct {
+	// Host is the SSH destination, such as user@devbox or a Host of ~/.ssh/config
+	Host string
+	// Root is the remote directory the local workspace root corresponds to
+	Root string
+	// Options are passed to ssh with -o, such as ConnectTimeout=10
+	Options []string
+}
+
+// NewRemoteClient runs the language server command on a remote host over SSH, in
+// the remote root, and talks to it over the tunneled stdio. Paths and URIs under
+// localRoot are rewritten to the remote root in messages sent to the server, and
+// back in messages it sends, so that the rest of the client only sees local paths.
+func NewRemoteClient(remote RemoteWorkspace, localRoot string, command string, args ...string) (*Client, error) {
+	client := newClient()
+	client.command = "ssh"
+	client.args = sshArgs(remote, command, args)
+	client.serverName = strings.TrimSuffix(filepath.Base(command), filepath.Ext(command))
+	client.paths = newPathMapping([]string{localRoot}, []string{remote.Root})
+
+	if err := client.start(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// sshArgs returns the ssh arguments that run command in the remote root. Without
+// a pseudo terminal the server's stdio is passed through unchanged.
+func sshArgs(remote RemoteWorkspace, command string, args []string) []string {
+	sshArgs := []string{"-T", "-o", "BatchMode=yes"}
+	for _, option := range remote.Options {
+		sshArgs = append(sshArgs, "-o", option)
+	}
+
+	// The remote shell runs the command line, so every word is quoted
+	words := []string{shellQuote(command)}
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+	script := "exec " + strings.Join(words, " ")
+	if remote.Root != "" {
+		script = "cd " + shellQuote(remote.Root) + " && " + script
+	}
+	return append(sshArgs, "--", remote.Host, script)
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// pathMapping rewrites workspace roots between their local and remote paths in
+// raw JSON messages. Both file URIs and plain paths are rewritten, when they start
+// a JSON string and are followed by a separator or the end of the string.
+type pathMapping struct {
+	toRemote []rootReplacement
+	toLocal  []rootReplacement
+}
+
+type rootReplacement struct {
+	from, to []byte
+}
+
+// newPathMapping maps each local root to the remote root at the same index. It
+// returns nil when every root has the same path on both sides.
+func newPathMapping(localRoots, remoteRoots []string) *pathMapping {
+	m := &pathMapping{}
+	for i, localRoot := range localRoots {
+		localRoot = filepath.Clean(localRoot)
+		remoteRoot := strings.TrimSuffix(remoteRoots[i], "/")
+		if remoteRoot == "" || remoteRoot == localRoot {
+			continue
+		}
+
+		localURI := []byte(fileuri.FromPath(localRoot))
+		// The remote root is a path on another host, so it is not made absolute
+		// against the local working directory
+		remoteURI := []byte((&url.URL{Scheme: "file", Path: remoteRoot}).String())
+		localPath := jsonString(localRoot)
+		remotePath := jsonString(remoteRoot)
+		m.toRemote = append(m.toRemote, rootReplacement{localURI, remoteURI}, rootReplacement{localPath, remotePath})
+		m.toLocal = append(m.toLocal, rootReplacement{remoteURI, localURI}, rootReplacement{remotePath, localPath})
+	}
+	if len(m.toRemote) == 0 {
+		return nil
+	}
+
+	// Nested roots are matched before the roots they are in
+	for _, replacements := range [][]rootReplacement{m.toRemote, m.toLocal} {
+		sort.SliceStable(replacements, func(i, j int) bool {
+			return len(replacements[i].from) > len(replacements[j].from)
+		})
+	}
+	return m
+}
+
+// jsonString returns s as it appears inside a JSON string
+func jsonString(s string) []byte {
+	data, _ := json.Marshal(s)
+	return data[1 : len(data)-1]
+}
+
+// remoteMessage returns msg with local paths rewritten to remote ones
+func (m *pathMapping) remoteMessage(msg *Message) *Message {
+	if m == nil {
+		return msg
+	}
+	rewritten := *msg
+	rewritten.Params = rewriteRoots(msg.Params, m.toRemote)
+	rewritten.Result = rewriteRoots(msg.Result, m.toRemote)
+	return &rewritten
+}
+
+// localMessage rewrites the remote paths in msg to local ones
+func (m *pathMapping) localMessage(msg *Message) {
+	if m == nil {
+		return
+	}
+	msg.Params = rewriteRoots(msg.Params, m.toLocal)
+	msg.Result = rewriteRoots(msg.Result, m.toLocal)
+}
+
+// rewriteRoots replaces the roots in data in a single pass, so a replacement is
+// never rewritten again
+func rewriteRoots(data json.RawMessage, replacements []rootReplacement) json.RawMessage {
+	found := false
+	for _, r := range replacements {
+		if bytes.Contains(data, r.from) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return data
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(data))
+	for i := 0; i < len(data); i++ {
+		out.WriteByte(data[i])
+		if data[i] != '"' {
+			continue
+		}
+		for _, r := range replacements {
+			rest := data[i+1:]
+			if !bytes.HasPrefix(rest, r.from) || len(rest) == len(r.from) {
+				continue
+			}
+			if next := rest[len(r.from)]; next == '/' || next == '"' || next == '\\' {
+				out.Write(r.to)
+				i += len(r.from)
+				break
+			}
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,39 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHArgs(t *testing.T) {
+	remote := RemoteWorkspace{Host: "me@devbox", Root: "/srv/my project", Options: []string{"ConnectTimeout=10"}}
+	args := sshArgs(remote, "gopls", []string{"-remote=auto", "it's"})
+
+	assert.Equal(t, []string{
+		"-T", "-o", "BatchMode=yes", "-o", "ConnectTimeout=10", "--", "me@devbox",
+		`cd '/srv/my project' && exec 'gopls' '-remote=auto' 'it'\''s'`,
+	}, args)
+}
+
+func TestPathMapping(t *testing.T) {
+	m := newPathMapping([]string{"/home/me/project"}, []string{"/srv/project"})
+
+	sent := m.remoteMessage(&Message{Params: json.RawMessage(`{"rootUri":"file:///home/me/project","rootPath":"/home/me/project",` +
+		`"textDocument":{"uri":"file:///home/me/project/main.go"},"other":"file:///home/me/project2/x.go","text":"see /home/me/project/a"}`)})
+	assert.JSONEq(t, `{"rootUri":"file:///srv/project","rootPath":"/srv/project",`+
+		`"textDocument":{"uri":"file:///srv/project/main.go"},"other":"file:///home/me/project2/x.go","text":"see /home/me/project/a"}`,
+		string(sent.Params), "only whole roots at the start of a string are rewritten")
+
+	received := &Message{Result: json.RawMessage(`[{"uri":"file:///srv/project/lib/a.go","range":{}},{"uri":"file:///usr/lib/go/src/fmt/print.go"}]`)}
+	m.localMessage(received)
+	assert.JSONEq(t, `[{"uri":"file:///home/me/project/lib/a.go","range":{}},{"uri":"file:///usr/lib/go/src/fmt/print.go"}]`, string(received.Result))
+
+	// Roots that contain each other are rewritten once
+	nested := newPathMapping([]string{"/work"}, []string{"/work/remote"})
+	sent = nested.remoteMessage(&Message{Params: json.RawMessage(`{"uri":"file:///work/a.go"}`)})
+	assert.JSONEq(t, `{"uri":"file:///work/remote/a.go"}`, string(sent.Params))
+
+	assert.Nil(t, newPathMapping([]string{"/same"}, []string{"/same/"}), "no mapping is needed for the same path")
+}
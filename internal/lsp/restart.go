@@ -0,0 +1,259 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// Restart tears down the language server, starts it again with the same command or
+// address, initializes it with the same workspace roots and configuration and reopens
+// the files that were open, with their staged edits. It recovers from a server that has crashed or hung.
+func (c *Client) Restart(ctx context.Context) error {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+
+	c.openFilesMu.RLock()
+	openFiles := make([]string, 0, len(c.openFiles))
+	for uri := range c.openFiles {
+		openFiles = append(openFiles, fileuri.ToPath(uri))
+	}
+	c.openFilesMu.RUnlock()
+	staged := c.stagedDocuments()
+
+	lspLogger.Info("Restarting language server with %d open files", len(openFiles))
+	c.teardown()
+	c.resetState()
+
+	if err := c.start(); err != nil {
+		return fmt.Errorf("failed to restart language server: %w", err)
+	}
+
+	if _, err := c.InitializeLSPClient(ctx, c.WorkspaceRoots(), c.initOptions); err != nil {
+		return fmt.Errorf("failed to initialize restarted language server: %w", err)
+	}
+
+	for _, path := range openFiles {
+		if err := c.OpenFile(ctx, path); err != nil {
+			lspLogger.Warn("Error reopening %s after restart: %v", path, err)
+		}
+	}
+	c.restoreStaged(ctx, staged)
+
+	c.totalRestarts.Add(1)
+	lspLogger.Info("Language server restarted")
+	return nil
+}
+
+// teardown stops the current server process or connection and fails requests that
+// are still waiting for a response
+func (c *Client) teardown() {
+	c.transportMu.Lock()
+	cmd, conn, stdin := c.Cmd, c.conn, c.stdin
+	c.Cmd, c.conn, c.stdin = nil, nil, nil
+	c.transportMu.Unlock()
+	c.initialized.Store(false)
+
+	if conn != nil {
+		if err := conn.Close(); err != nil {
+			lspLogger.Debug("Error closing connection: %v", err)
+		}
+	} else if stdin != nil {
+		if err := stdin.Close(); err != nil {
+			lspLogger.Debug("Error closing stdin: %v", err)
+		}
+	}
+
+	if cmd != nil && cmd.Process != nil {
+		if err := cmd.Process.Kill(); err != nil {
+			lspLogger.Debug("Error killing language server: %v", err)
+		}
+	}
+
+	c.failPendingRequests("language server restarted")
+}
+
+// failPendingRequests answers every request still waiting for a response with an
+// error, since the server that would have answered is gone
+func (c *Client) failPendingRequests(reason string) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+
+	for id, ch := range c.handlers {
+		ch <- &Message{
+			JSONRPC: "2.0",
+			Error:   &ResponseError{Code: -32099, Message: reason},
+		}
+		close(ch)
+		delete(c.handlers, id)
+	}
+}
+
+// resetState forgets everything the previous server reported
+func (c *Client) resetState() {
+	c.openFilesMu.Lock()
+	c.openFiles = make(map[string]*OpenFileInfo)
+	c.openFilesMu.Unlock()
+
+	c.diagnosticsMu.Lock()
+	c.diagnostics = make(map[protocol.DocumentUri][]protocol.Diagnostic)
+	c.diagnosticResultIDs = make(map[protocol.DocumentUri]string)
+	c.diagnosticTimes = make(map[protocol.DocumentUri]time.Time)
+	c.diagnosticsMu.Unlock()
+
+	c.registrationsMu.Lock()
+	c.registrations = make(map[string]string)
+	c.registrationsMu.Unlock()
+	c.diagnosticsReceived.Store(false)
+
+	c.progressMu.Lock()
+	c.activeProgress = make(map[string]string)
+	c.endedProgress = make(map[string]bool)
+	c.endedTokens = make(map[string]string)
+	c.progressMu.Unlock()
+}
+
+const (
+	// Delay before the first automatic restart, doubled after each failed attempt
+	restartInitialBackoff = time.Second
+	restartMaxBackoff     = 30 * time.Second
+
+	// Automatic restarts stop after this many consecutive crashes
+	restartMaxAttempts = 5
+
+	// A server that runs this long before crashing starts a fresh backoff
+	restartStablePeriod = time.Minute
+
+	// Time allowed for a restarted server to initialize
+	restartTimeout = time.Minute
+)
+
+// RestartEvent describes a step of the automatic recovery from a server crash
+type RestartEvent struct {
+	Message string
+	// Set when the server exited or a restart attempt failed
+	Err error
+}
+
+// RestartHandler is called for each automatic recovery step
+type RestartHandler func(event RestartEvent)
+
+// SetRestartHandler registers a handler that is told about server crashes and
+// automatic restarts
+func (c *Client) SetRestartHandler(handler RestartHandler) {
+	c.restartHandlerMu.Lock()
+	defer c.restartHandlerMu.Unlock()
+	c.restartHandler = handler
+}
+
+func (c *Client) emitRestartEvent(event RestartEvent) {
+	if event.Err != nil {
+		lspLogger.Warn("%s: %v", event.Message, event.Err)
+	} else {
+		lspLogger.Info("%s", event.Message)
+	}
+
+	c.restartHandlerMu.Lock()
+	handler := c.restartHandler
+	c.restartHandlerMu.Unlock()
+	if handler != nil {
+		handler(event)
+	}
+}
+
+// monitorProcess waits for a server process to exit and starts automatic recovery
+// when the exit was not requested
+func (c *Client) monitorProcess(cmd *exec.Cmd, exited chan struct{}, startedAt time.Time) {
+	err := cmd.Wait()
+
+	c.transportMu.Lock()
+	c.exitErr = err
+	current := c.Cmd == cmd
+	c.transportMu.Unlock()
+	close(exited)
+
+	// Replaced by a restart
+	if !current {
+		return
+	}
+	c.failPendingRequests("language server exited")
+
+	// Stopped on purpose
+	if c.closing.Load() {
+		return
+	}
+
+	if err == nil {
+		err = fmt.Errorf("exit status 0")
+	}
+	c.totalCrashes.Add(1)
+	c.emitRestartEvent(RestartEvent{Message: "Language server exited unexpectedly", Err: err})
+
+	if time.Since(startedAt) > restartStablePeriod {
+		c.crashes.Store(0)
+	}
+
+	// An active supervisor notices the exit through its failed or finished restart
+	if c.supervising.CompareAndSwap(false, true) {
+		go c.supervise()
+	}
+}
+
+// supervise restarts the server with exponential backoff until it comes back up or
+// too many attempts have failed
+func (c *Client) supervise() {
+	for {
+		attempt := int(c.crashes.Add(1))
+		if attempt > restartMaxAttempts {
+			c.supervising.Store(false)
+			c.emitRestartEvent(RestartEvent{
+				Message: fmt.Sprintf("Language server crashed %d times in a row, giving up. Use restart_language_server to try again", restartMaxAttempts),
+				Err:     fmt.Errorf("too many restart attempts"),
+			})
+			c.crashes.Store(0)
+			return
+		}
+
+		delay := restartInitialBackoff << (attempt - 1)
+		if delay > restartMaxBackoff {
+			delay = restartMaxBackoff
+		}
+		c.emitRestartEvent(RestartEvent{
+			Message: fmt.Sprintf("Restarting language server in %v (attempt %d of %d)", delay, attempt, restartMaxAttempts),
+		})
+		time.Sleep(delay)
+
+		if c.closing.Load() {
+			c.supervising.Store(false)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), restartTimeout)
+		err := c.Restart(ctx)
+		cancel()
+		if err != nil {
+			c.emitRestartEvent(RestartEvent{Message: "Language server restart failed", Err: err})
+			continue
+		}
+
+		c.emitRestartEvent(RestartEvent{Message: "Language server restarted after crash"})
+		c.supervising.Store(false)
+
+		// The new process may have exited while this supervisor was still active
+		c.transportMu.RLock()
+		exited := c.exited
+		c.transportMu.RUnlock()
+		select {
+		case <-exited:
+			if !c.closing.Load() && c.supervising.CompareAndSwap(false, true) {
+				continue
+			}
+		default:
+		}
+		return
+	}
+}
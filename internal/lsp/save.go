@@ -0,0 +1,163 @@
+package lsp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// defaultWillSaveTimeout bounds the wait for a server's pre-save edits. Editors
+// give servers about a second before saving without them.
+const defaultWillSaveTimeout = 2 * time.Second
+
+// SaveActions configures what happens when a tool saves a file it edited
+type SaveActions struct {
+	// WillSaveWaitUntil asks the server for the edits it makes before a file is
+	// saved, such as formatting or sorting imports, and applies them
+	WillSaveWaitUntil bool `json:"willSaveWaitUntil"`
+	// TimeoutMs is how long to wait for the server's edits, 0 for the default
+	TimeoutMs int `json:"timeoutMs"`
+}
+
+// SetSaveActions configures the save actions. It must be called before the client
+// is initialized, since the server only offers pre-save edits to clients that
+// announce support for them.
+func (c *Client) SetSaveActions(actions SaveActions) {
+	c.saveActions = actions
+}
+
+// SaveFile tells the server that a file a tool wrote to disk has been saved, as an
+// editor would. When save actions are enabled and the server supports them, the
+// server's pre-save edits are applied to the file first. It returns whether those
+// edits changed the file. Files that are not open are left alone.
+func (c *Client) SaveFile(ctx context.Context, path string) (bool, error) {
+	if !c.IsFileOpen(path) {
+		return false, nil
+	}
+	sync := c.textDocumentSync()
+	if sync == nil {
+		return false, nil
+	}
+
+	uri := fileuri.FromPath(path)
+	willSave := protocol.WillSaveTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Reason:       protocol.Manual,
+	}
+
+	changed := false
+	if c.saveActions.WillSaveWaitUntil {
+		if sync.WillSave {
+			if err := c.WillSave(ctx, willSave); err != nil {
+				return false, fmt.Errorf("failed to send willSave: %w", err)
+			}
+		}
+		if sync.WillSaveWaitUntil {
+			var err error
+			if changed, err = c.applyPreSaveEdits(ctx, path, willSave); err != nil {
+				// Saving goes ahead without the edits, like in an editor
+				lspLogger.Warn("Saving %s without pre-save edits: %v", path, err)
+			}
+		}
+	}
+
+	if err := c.NotifySave(ctx, path); err != nil {
+		return changed, err
+	}
+	return changed, nil
+}
+
+// NotifySave sends didSave for an open file that was saved to disk, when the server
+// asks for save notifications. Tools and the workspace watcher both report the
+// saves tools make, so a file is not reported again until its content changes.
+func (c *Client) NotifySave(ctx context.Context, path string) error {
+	sync := c.textDocumentSync()
+	if sync == nil || sync.Save == nil {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+	hash := sha256.Sum256(content)
+
+	uri := fileuri.FromPath(path)
+	c.openFilesMu.Lock()
+	fileInfo, isOpen := c.openFiles[string(uri)]
+	// A staged document is an unsaved buffer, whatever happens to the file on disk
+	if !isOpen || fileInfo.staged || fileInfo.savedHash == hash {
+		c.openFilesMu.Unlock()
+		return nil
+	}
+	fileInfo.savedHash = hash
+	c.openFilesMu.Unlock()
+
+	params := protocol.DidSaveTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	}
+	if sync.Save.IncludeText {
+		text := string(content)
+		params.Text = &text
+	}
+	if err := c.DidSave(ctx, params); err != nil {
+		return fmt.Errorf("failed to send didSave: %w", err)
+	}
+	return nil
+}
+
+// applyPreSaveEdits requests the server's willSaveWaitUntil edits and writes them
+// to the file, keeping the server's copy in sync
+func (c *Client) applyPreSaveEdits(ctx context.Context, path string, params protocol.WillSaveTextDocumentParams) (bool, error) {
+	timeout := defaultWillSaveTimeout
+	if c.saveActions.TimeoutMs > 0 {
+		timeout = time.Duration(c.saveActions.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	edits, err := c.WillSaveWaitUntil(ctx, params)
+	if err != nil {
+		return false, fmt.Errorf("willSaveWaitUntil failed: %w", err)
+	}
+	if len(edits) == 0 {
+		return false, nil
+	}
+
+	if err := utilities.ApplyTextEdits(params.TextDocument.URI, edits, c.PositionEncoding()); err != nil {
+		return false, fmt.Errorf("failed to apply pre-save edits: %w", err)
+	}
+	if err := c.NotifyChange(ctx, path); err != nil {
+		return true, fmt.Errorf("failed to notify pre-save edits: %w", err)
+	}
+	lspLogger.Debug("Applied %d pre-save edits to %s", len(edits), path)
+	return true, nil
+}
+
+// textDocumentSync returns the server's text document sync options, or nil when it
+// only gave a sync kind, which asks for no save notifications
+func (c *Client) textDocumentSync() *protocol.TextDocumentSyncOptions {
+	sync := c.serverCapabilities.TextDocumentSync
+	if _, ok := sync.(map[string]any); !ok {
+		return nil
+	}
+
+	// The options are decoded generically, so round trip them through JSON
+	data, err := json.Marshal(sync)
+	if err != nil {
+		return nil
+	}
+	var options protocol.TextDocumentSyncOptions
+	if err := json.Unmarshal(data, &options); err != nil {
+		lspLogger.Debug("Failed to parse text document sync options: %v", err)
+		return nil
+	}
+	return &options
+}
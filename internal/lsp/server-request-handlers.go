@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
@@ -10,21 +11,54 @@ import (
 // FileWatchHandler is called when file watchers are registered by the server
 type FileWatchHandler func(id string, watchers []protocol.FileSystemWatcher)
 
-// fileWatchHandler holds the current file watch handler
-var fileWatchHandler FileWatchHandler
+// FileUnwatchHandler is called when the server unregisters file watchers
+type FileUnwatchHandler func(id string)
+
+// SetFileWatchHandlers registers the handlers for the file watchers the client's
+// server registers and unregisters. Each client has its own, so that a server's
+// registrations reach the watcher of its workspace.
+func (c *Client) SetFileWatchHandlers(watch FileWatchHandler, unwatch FileUnwatchHandler) {
+	c.fileWatchHandlerMu.Lock()
+	defer c.fileWatchHandlerMu.Unlock()
+	c.fileWatchHandler = watch
+	c.fileUnwatchHandler = unwatch
+}
 
-// RegisterFileWatchHandler registers a handler for file watcher registrations
-func RegisterFileWatchHandler(handler FileWatchHandler) {
-	fileWatchHandler = handler
+// fileWatchHandlers returns the client's file watch handlers
+func (c *Client) fileWatchHandlers() (FileWatchHandler, FileUnwatchHandler) {
+	c.fileWatchHandlerMu.Lock()
+	defer c.fileWatchHandlerMu.Unlock()
+	return c.fileWatchHandler, c.fileUnwatchHandler
 }
 
 // Requests
 
-func HandleWorkspaceConfiguration(params json.RawMessage) (any, error) {
-	return []map[string]any{{}}, nil
+// HandleWorkspaceConfiguration answers workspace/configuration requests with one
+// result per requested item, using the configured settings for the item's section
+// and falling back to the defaults of the server's initializer
+func HandleWorkspaceConfiguration(client *Client, params json.RawMessage) (any, error) {
+	var configParams protocol.ConfigurationParams
+	if err := json.Unmarshal(params, &configParams); err != nil {
+		lspLogger.Error("Error unmarshaling configuration params: %v", err)
+		return nil, err
+	}
+
+	initializer := client.initializer()
+	results := make([]any, len(configParams.Items))
+	for i, item := range configParams.Items {
+		settings := client.configurationSection(item.Section)
+		if settings == nil && initializer.Configuration != nil {
+			settings = initializer.Configuration(item.Section)
+		}
+		if settings == nil {
+			settings = map[string]any{}
+		}
+		results[i] = settings
+	}
+	return results, nil
 }
 
-func HandleRegisterCapability(params json.RawMessage) (any, error) {
+func HandleRegisterCapability(client *Client, params json.RawMessage) (any, error) {
 	var registerParams protocol.RegistrationParams
 	if err := json.Unmarshal(params, &registerParams); err != nil {
 		lspLogger.Error("Error unmarshaling registration params: %v", err)
@@ -33,6 +67,7 @@ func HandleRegisterCapability(params json.RawMessage) (any, error) {
 
 	for _, reg := range registerParams.Registrations {
 		lspLogger.Info("Registration received for method: %s, id: %s", reg.Method, reg.ID)
+		client.recordRegistration(reg)
 
 		// Special handling for file watcher registrations
 		if reg.Method == "workspace/didChangeWatchedFiles" {
@@ -50,9 +85,30 @@ func HandleRegisterCapability(params json.RawMessage) (any, error) {
 				continue
 			}
 
-			// Notify file watchers
-			if fileWatchHandler != nil {
-				fileWatchHandler(reg.ID, opts.Watchers)
+			// Notify the client's file watcher
+			if watch, _ := client.fileWatchHandlers(); watch != nil {
+				watch(reg.ID, opts.Watchers)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func HandleUnregisterCapability(client *Client, params json.RawMessage) (any, error) {
+	var unregisterParams protocol.UnregistrationParams
+	if err := json.Unmarshal(params, &unregisterParams); err != nil {
+		lspLogger.Error("Error unmarshaling unregistration params: %v", err)
+		return nil, err
+	}
+
+	for _, unreg := range unregisterParams.Unregisterations {
+		lspLogger.Info("Unregistration received for method: %s, id: %s", unreg.Method, unreg.ID)
+		client.forgetRegistration(unreg.ID)
+
+		if unreg.Method == "workspace/didChangeWatchedFiles" {
+			if _, unwatch := client.fileWatchHandlers(); unwatch != nil {
+				unwatch(unreg.ID)
 			}
 		}
 	}
@@ -60,14 +116,23 @@ func HandleRegisterCapability(params json.RawMessage) (any, error) {
 	return nil, nil
 }
 
-func HandleApplyEdit(params json.RawMessage) (any, error) {
+// HandleApplyEdit applies an edit the server asks for, refusing it when a document
+// changed since the server computed it
+func HandleApplyEdit(client *Client, params json.RawMessage) (any, error) {
 	var workspaceEdit protocol.ApplyWorkspaceEditParams
 	if err := json.Unmarshal(params, &workspaceEdit); err != nil {
 		return protocol.ApplyWorkspaceEditResult{Applied: false}, err
 	}
 
+	description := "workspace/applyEdit"
+	if workspaceEdit.Label != "" {
+		description += " " + workspaceEdit.Label
+	}
+	entry := utilities.EditJournal.Begin(description)
+	entry.CaptureEdit(workspaceEdit.Edit)
+
 	// Apply the edits
-	err := utilities.ApplyWorkspaceEdit(workspaceEdit.Edit)
+	err := client.ApplyWorkspaceEdit(workspaceEdit.Edit)
 	if err != nil {
 		lspLogger.Error("Error applying workspace edit: %v", err)
 		return protocol.ApplyWorkspaceEditResult{
@@ -76,6 +141,8 @@ func HandleApplyEdit(params json.RawMessage) (any, error) {
 		}, nil
 	}
 
+	entry.Commit()
+
 	return protocol.ApplyWorkspaceEditResult{
 		Applied: true,
 	}, nil
@@ -90,25 +157,56 @@ func workspaceEditFailure(err error) string {
 
 // Notifications
 
-// HandleServerMessage processes window/showMessage notifications from the server
-func HandleServerMessage(params json.RawMessage) {
+// ServerMessage is a message the server showed or logged
+type ServerMessage struct {
+	Type    protocol.MessageType
+	Message string
+	// Show is set for window/showMessage, which the server means the user to see,
+	// and unset for window/logMessage
+	Show bool
+}
+
+// MessageHandler is called for each message the server shows or logs
+type MessageHandler func(msg ServerMessage)
+
+// SetMessageHandler registers a handler that receives the server's
+// window/showMessage and window/logMessage notifications
+func (c *Client) SetMessageHandler(handler MessageHandler) {
+	c.messageHandlerMu.Lock()
+	defer c.messageHandlerMu.Unlock()
+	c.messageHandler = handler
+}
+
+// HandleServerMessage processes window/showMessage and window/logMessage
+// notifications from the server, which have the same params
+func HandleServerMessage(client *Client, params json.RawMessage, show bool) {
 	var msg protocol.ShowMessageParams
 	if err := json.Unmarshal(params, &msg); err != nil {
 		lspLogger.Error("Error unmarshaling server message: %v", err)
 		return
 	}
 
-	// Log the message with appropriate level
-	switch msg.Type {
-	case protocol.Error:
+	// Log the message with appropriate level. Logged messages are too frequent for
+	// anything but debug output.
+	switch {
+	case !show:
+		lspLogger.Debug("Server log: %s", msg.Message)
+	case msg.Type == protocol.Error:
 		lspLogger.Error("Server error: %s", msg.Message)
-	case protocol.Warning:
+	case msg.Type == protocol.Warning:
 		lspLogger.Warn("Server warning: %s", msg.Message)
-	case protocol.Info:
+	case msg.Type == protocol.Info:
 		lspLogger.Info("Server info: %s", msg.Message)
 	default:
 		lspLogger.Debug("Server message: %s", msg.Message)
 	}
+
+	client.messageHandlerMu.Lock()
+	handler := client.messageHandler
+	client.messageHandlerMu.Unlock()
+	if handler != nil {
+		handler(ServerMessage{Type: msg.Type, Message: msg.Message, Show: show})
+	}
 }
 
 // HandleDiagnostics processes textDocument/publishDiagnostics notifications
@@ -121,8 +219,18 @@ func HandleDiagnostics(client *Client, params json.RawMessage) {
 
 	// Save diagnostics in client
 	client.diagnosticsMu.Lock()
+	now := time.Now()
 	client.diagnostics[diagParams.URI] = diagParams.Diagnostics
+	client.diagnosticTimes[diagParams.URI] = now
+	client.recordDiagnostics(diagParams.URI, diagParams.Diagnostics, now)
+	handler := client.diagnosticsHandler
 	client.diagnosticsMu.Unlock()
+	client.diagnosticsReceived.Store(true)
+	client.recordActivity()
 
 	lspLogger.Info("Received diagnostics for %s: %d items", diagParams.URI, len(diagParams.Diagnostics))
+
+	if handler != nil {
+		handler(diagParams.URI)
+	}
 }
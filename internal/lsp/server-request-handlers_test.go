@@ -0,0 +1,45 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWatchHandlersPerClient(t *testing.T) {
+	primary, route := newClient(), newClient()
+	watched := make(map[*Client][]string)
+	for _, c := range []*Client{primary, route} {
+		c.SetFileWatchHandlers(func(id string, watchers []protocol.FileSystemWatcher) {
+			watched[c] = append(watched[c], id)
+		}, func(id string) {
+			watched[c] = append(watched[c], "-"+id)
+		})
+	}
+
+	register, err := json.Marshal(protocol.RegistrationParams{Registrations: []protocol.Registration{{
+		ID:     "watch",
+		Method: "workspace/didChangeWatchedFiles",
+		RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{
+			Watchers: []protocol.FileSystemWatcher{{GlobPattern: protocol.GlobPattern{Value: "**/*.go"}}},
+		},
+	}}})
+	require.NoError(t, err)
+	_, err = HandleRegisterCapability(primary, register)
+	require.NoError(t, err)
+
+	unregister, err := json.Marshal(protocol.UnregistrationParams{Unregisterations: []protocol.Unregistration{{
+		ID:     "watch",
+		Method: "workspace/didChangeWatchedFiles",
+	}}})
+	require.NoError(t, err)
+	_, err = HandleUnregisterCapability(primary, unregister)
+	require.NoError(t, err)
+
+	// Registrations reach the watcher of the server that made them
+	assert.Equal(t, []string{"watch", "-watch"}, watched[primary])
+	assert.Empty(t, watched[route])
+}
@@ -0,0 +1,138 @@
+package lsp
+
+import (
+	"sort"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// RequestObserver is told the method, duration and outcome of every request sent to
+// the server
+type RequestObserver func(method string, elapsed time.Duration, err error)
+
+// SetRequestObserver registers an observer of request latencies, or removes it when
+// observer is nil
+func (c *Client) SetRequestObserver(observer RequestObserver) {
+	if observer == nil {
+		c.requestObserver.Store(nil)
+		return
+	}
+	c.requestObserver.Store(&observer)
+}
+
+// Stats are counts describing the health of the language server
+type Stats struct {
+	OpenFiles int
+	// Diagnostics counts the cached diagnostics of every file by severity
+	Diagnostics map[protocol.DiagnosticSeverity]int
+	// Crashes and Restarts count unexpected exits of the server and successful
+	// restarts, automatic or requested, since the client was created
+	Crashes  int64
+	Restarts int64
+	// SkippedChanges counts changes to open files that were not sent because
+	// the file was rewritten with the content the server already had
+	SkippedChanges int64
+}
+
+// Stats returns the current counts
+func (c *Client) Stats() Stats {
+	stats := Stats{
+		Diagnostics:    make(map[protocol.DiagnosticSeverity]int),
+		Crashes:        c.totalCrashes.Load(),
+		Restarts:       c.totalRestarts.Load(),
+		SkippedChanges: c.skippedChanges.Load(),
+	}
+
+	c.openFilesMu.RLock()
+	stats.OpenFiles = len(c.openFiles)
+	c.openFilesMu.RUnlock()
+
+	c.diagnosticsMu.RLock()
+	defer c.diagnosticsMu.RUnlock()
+	for _, diags := range c.diagnostics {
+		for _, diag := range diags {
+			severity := diag.Severity
+			// Servers may leave the severity out, which clients treat as an error
+			if severity == 0 {
+				severity = protocol.SeverityError
+			}
+			stats.Diagnostics[severity]++
+		}
+	}
+	return stats
+}
+
+// Status describes the state of the language server, for diagnosing why queries
+// return nothing
+type Status struct {
+	Stats
+	ServerName string
+	// PID is the server process, 0 when connected to a server at Address
+	PID     int
+	Address string
+	// StartedAt is when the current server was started or connected to, zero when
+	// it is not running
+	StartedAt   time.Time
+	Running     bool
+	Initialized bool
+	// Progress holds the titles of work done progress in flight, such as indexing
+	Progress []string
+	// LastActivity is when the server last reported progress or diagnostics
+	LastActivity time.Time
+	// OpenFilePaths lists the files open in the server, sorted
+	OpenFilePaths []string
+	// DiagnosticTimes is when each file's diagnostics were last published or pulled
+	DiagnosticTimes map[protocol.DocumentUri]time.Time
+}
+
+// Status returns the current state of the server
+func (c *Client) Status() Status {
+	status := Status{
+		Stats:           c.Stats(),
+		ServerName:      c.ServerName(),
+		Address:         c.address,
+		Initialized:     c.initialized.Load(),
+		DiagnosticTimes: make(map[protocol.DocumentUri]time.Time),
+	}
+
+	c.transportMu.RLock()
+	if c.Cmd != nil && c.Cmd.Process != nil {
+		status.PID = c.Cmd.Process.Pid
+	}
+	status.Running = c.stdin != nil
+	if status.Running {
+		status.StartedAt = c.startedAt
+		// A process that exited is still set until it is restarted. Connections
+		// have no exited channel.
+		select {
+		case <-c.exited:
+			status.Running = false
+		default:
+		}
+	}
+	c.transportMu.RUnlock()
+
+	c.progressMu.Lock()
+	for _, title := range c.activeProgress {
+		status.Progress = append(status.Progress, title)
+	}
+	status.LastActivity = c.lastActivity
+	c.progressMu.Unlock()
+	sort.Strings(status.Progress)
+
+	c.openFilesMu.RLock()
+	for uri := range c.openFiles {
+		status.OpenFilePaths = append(status.OpenFilePaths, fileuri.ToPath(uri))
+	}
+	c.openFilesMu.RUnlock()
+	sort.Strings(status.OpenFilePaths)
+
+	c.diagnosticsMu.RLock()
+	for uri, t := range c.diagnosticTimes {
+		status.DiagnosticTimes[uri] = t
+	}
+	c.diagnosticsMu.RUnlock()
+	return status
+}
@@ -0,0 +1,435 @@
+package lsp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SymbolIndexDir is the directory of the primary workspace the symbol index is
+// kept in
+const SymbolIndexDir = ".mcp-language-server"
+
+const (
+	symbolIndexFile    = "symbols.json"
+	symbolIndexVersion = 1
+
+	// symbolIndexSaveDelay collects the files recorded in quick succession, such
+	// as by one project overview, into one write
+	symbolIndexSaveDelay = 2 * time.Second
+)
+
+// IndexedSymbol is a symbol of a file in the symbol index, with the names of the
+// symbols containing it
+type IndexedSymbol struct {
+	Name      string              `json:"name"`
+	Kind      protocol.SymbolKind `json:"kind"`
+	Container string              `json:"container,omitempty"`
+	Range     protocol.Range      `json:"range"`
+}
+
+// indexedFile is the symbols of a file and the content they were found in. The
+// size and modification time let unchanged files be recognized without hashing.
+type indexedFile struct {
+	Hash    string          `json:"hash"`
+	Size    int64           `json:"size"`
+	ModTime time.Time       `json:"modTime"`
+	Symbols []IndexedSymbol `json:"symbols"`
+}
+
+type symbolIndexData struct {
+	Version int                     `json:"version"`
+	Files   map[string]*indexedFile `json:"files"`
+}
+
+// SymbolIndex keeps the document symbols the server reported for each file on
+// disk, with a hash of the file's content, so that symbol queries can be answered
+// after a restart before the server has indexed the workspace again. The symbols
+// of files that changed since are not used.
+type SymbolIndex struct {
+	path string
+
+	mu        sync.Mutex
+	files     map[string]*indexedFile
+	saveTimer *time.Timer
+}
+
+// LoadSymbolIndex loads the symbol index kept in root. An index that is missing,
+// unreadable or of another version starts out empty.
+func LoadSymbolIndex(root string) *SymbolIndex {
+	index := &SymbolIndex{
+		path:  filepath.Join(root, SymbolIndexDir, symbolIndexFile),
+		files: make(map[string]*indexedFile),
+	}
+
+	data, err := os.ReadFile(index.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			lspLogger.Warn("Failed to read symbol index: %v", err)
+		}
+		return index
+	}
+	var stored symbolIndexData
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != symbolIndexVersion {
+		lspLogger.Warn("Ignoring symbol index %s of an unknown format", index.path)
+		return index
+	}
+	if stored.Files != nil {
+		index.files = stored.Files
+	}
+	lspLogger.Info("Loaded the symbols of %d files from %s", len(index.files), index.path)
+	return index
+}
+
+// Len returns how many files the index has symbols for
+func (x *SymbolIndex) Len() int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return len(x.files)
+}
+
+// Record replaces the symbols of a file with those the server reported for
+// content. They are used for as long as the file on disk has that content.
+func (x *SymbolIndex) Record(path string, content []byte, symbols []protocol.DocumentSymbolResult) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	file := &indexedFile{
+		Hash:    contentHash(content),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Symbols: flattenSymbols(symbols),
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if old, ok := x.files[path]; ok && old.Hash == file.Hash && equalSymbols(old.Symbols, file.Symbols) {
+		return
+	}
+	x.files[path] = file
+	x.scheduleSave()
+}
+
+// Remove forgets the symbols of a file
+func (x *SymbolIndex) Remove(path string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.files[path]; ok {
+		delete(x.files, path)
+		x.scheduleSave()
+	}
+}
+
+// FileSymbols returns the indexed symbols of a file as flat symbol information,
+// if the file still has the content they were found in
+func (x *SymbolIndex) FileSymbols(path string) ([]protocol.DocumentSymbolResult, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	file, ok := x.files[path]
+	if !ok || !x.current(path, file) {
+		return nil, false
+	}
+	uri := fileuri.FromPath(path)
+	symbols := make([]protocol.DocumentSymbolResult, len(file.Symbols))
+	for i, symbol := range file.Symbols {
+		symbols[i] = symbol.information(uri)
+	}
+	return symbols, true
+}
+
+// Search returns the indexed symbols of unchanged files whose names contain the
+// letters of query in order, ignoring case, like the fuzzy matching of
+// workspace/symbol. Names containing query come first.
+func (x *SymbolIndex) Search(query string) []protocol.WorkspaceSymbolResult {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	type match struct {
+		symbol *protocol.SymbolInformation
+		rank   int
+	}
+	var matches []match
+	query = strings.ToLower(query)
+	for path, file := range x.files {
+		var fileMatches []match
+		for _, symbol := range file.Symbols {
+			if rank, ok := fuzzyRank(strings.ToLower(symbol.Name), query); ok {
+				fileMatches = append(fileMatches, match{symbol.information(fileuri.FromPath(path)), rank})
+			}
+		}
+		// Files are only checked for changes when they have matches
+		if len(fileMatches) > 0 && x.current(path, file) {
+			matches = append(matches, fileMatches...)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.symbol.Location.URI != b.symbol.Location.URI {
+			return a.symbol.Location.URI < b.symbol.Location.URI
+		}
+		return a.symbol.Location.Range.Start.Line < b.symbol.Location.Range.Start.Line
+	})
+	results := make([]protocol.WorkspaceSymbolResult, len(matches))
+	for i, m := range matches {
+		results[i] = m.symbol
+	}
+	return results
+}
+
+// Paths returns the files the index has symbols for
+func (x *SymbolIndex) Paths() []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	paths := make([]string, 0, len(x.files))
+	for path := range x.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Current reports whether a file still has the content its symbols were found in
+func (x *SymbolIndex) Current(path string) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	file, ok := x.files[path]
+	return ok && x.current(path, file)
+}
+
+// current reports whether the file on disk has the content its symbols were found
+// in, hashing it only when its size or modification time changed. Called with mu
+// held.
+func (x *SymbolIndex) current(path string, file *indexedFile) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.Size() == file.Size && info.ModTime().Equal(file.ModTime) {
+		return true
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || contentHash(content) != file.Hash {
+		return false
+	}
+	// Touched but not changed
+	file.Size, file.ModTime = info.Size(), info.ModTime()
+	x.scheduleSave()
+	return true
+}
+
+// Save writes the index to disk, with a .gitignore that keeps it out of version
+// control
+func (x *SymbolIndex) Save() error {
+	x.mu.Lock()
+	if x.saveTimer != nil {
+		x.saveTimer.Stop()
+		x.saveTimer = nil
+	}
+	data, err := json.Marshal(symbolIndexData{Version: symbolIndexVersion, Files: x.files})
+	x.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(x.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	gitignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(gitignore); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(gitignore, []byte("*\n"), 0o644); err != nil {
+			return err
+		}
+	}
+
+	// Written to a temporary file first so that a crash never leaves half an index
+	tmp := x.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, x.path)
+}
+
+// scheduleSave saves the index shortly, unless a save is already scheduled.
+// Called with mu held.
+func (x *SymbolIndex) scheduleSave() {
+	if x.saveTimer != nil {
+		return
+	}
+	x.saveTimer = time.AfterFunc(symbolIndexSaveDelay, func() {
+		if err := x.Save(); err != nil {
+			lspLogger.Error("Failed to save symbol index: %v", err)
+		}
+	})
+}
+
+// SetSymbolIndex sets the index symbol queries are answered from while the server
+// starts, and that the symbols the server reports are recorded in
+func (c *Client) SetSymbolIndex(index *SymbolIndex) {
+	c.symbolIndex = index
+}
+
+// SymbolIndex returns the symbol index, or nil when there is none
+func (c *Client) SymbolIndex() *SymbolIndex {
+	return c.symbolIndex
+}
+
+// ServerReady reports whether WaitForServerReady has returned, after which the
+// server's own results are preferred to the symbol index
+func (c *Client) ServerReady() bool {
+	return c.serverReady.Load()
+}
+
+// ReconcileSymbolIndex brings the symbol index up to date with the server once it
+// is ready: files that no longer exist are dropped, and the symbols of files that
+// changed since they were indexed are fetched again.
+func (c *Client) ReconcileSymbolIndex(ctx context.Context) {
+	index := c.symbolIndex
+	if index == nil {
+		return
+	}
+
+	var refreshed, removed int
+	for _, path := range index.Paths() {
+		if ctx.Err() != nil {
+			return
+		}
+		if index.Current(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			index.Remove(path)
+			removed++
+			continue
+		}
+		if err := c.refreshIndexedFile(ctx, path); err != nil {
+			lspLogger.Debug("Failed to refresh the symbols of %s: %v", path, err)
+			index.Remove(path)
+			removed++
+			continue
+		}
+		refreshed++
+	}
+
+	if err := index.Save(); err != nil {
+		lspLogger.Error("Failed to save symbol index: %v", err)
+	}
+	lspLogger.Info("Reconciled symbol index: %d files refreshed, %d removed", refreshed, removed)
+}
+
+// refreshIndexedFile fetches the symbols of a file from the server and records them
+func (c *Client) refreshIndexedFile(ctx context.Context, path string) error {
+	if err := c.OpenFile(ctx, path); err != nil {
+		return err
+	}
+	result, err := c.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: fileuri.FromPath(path)},
+	})
+	if err != nil {
+		return err
+	}
+	symbols, err := result.Results()
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	c.symbolIndex.Record(path, content, symbols)
+	return nil
+}
+
+// flattenSymbols lists document symbols and their children with the names of the
+// symbols containing them
+func flattenSymbols(symbols []protocol.DocumentSymbolResult) []IndexedSymbol {
+	flat := []IndexedSymbol{}
+	var walk func(symbols []protocol.DocumentSymbolResult, container string)
+	walk = func(symbols []protocol.DocumentSymbolResult, container string) {
+		for _, sym := range symbols {
+			switch v := sym.(type) {
+			case *protocol.DocumentSymbol:
+				flat = append(flat, IndexedSymbol{Name: v.Name, Kind: v.Kind, Container: container, Range: v.Range})
+				children := make([]protocol.DocumentSymbolResult, len(v.Children))
+				for i := range v.Children {
+					children[i] = &v.Children[i]
+				}
+				name := v.Name
+				if container != "" {
+					name = container + "." + name
+				}
+				walk(children, name)
+			case *protocol.SymbolInformation:
+				flat = append(flat, IndexedSymbol{Name: v.Name, Kind: v.Kind, Container: v.ContainerName, Range: v.Location.Range})
+			}
+		}
+	}
+	walk(symbols, "")
+	return flat
+}
+
+// information returns the symbol as flat symbol information in a file
+func (s IndexedSymbol) information(uri protocol.DocumentUri) *protocol.SymbolInformation {
+	return &protocol.SymbolInformation{
+		Name:          s.Name,
+		Kind:          s.Kind,
+		ContainerName: s.Container,
+		Location:      protocol.Location{URI: uri, Range: s.Range},
+	}
+}
+
+func equalSymbols(a, b []IndexedSymbol) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fuzzyRank matches a lower case name against a lower case query: 0 when they are
+// equal, 1 when the name starts with the query, 2 when it contains it and 3 when it
+// contains its letters in order
+func fuzzyRank(name, query string) (int, bool) {
+	switch {
+	case name == query:
+		return 0, true
+	case strings.HasPrefix(name, query):
+		return 1, true
+	case strings.Contains(name, query):
+		return 2, true
+	}
+	letters := []rune(query)
+	i := 0
+	for _, r := range name {
+		if i < len(letters) && r == letters[i] {
+			i++
+		}
+	}
+	return 3, i == len(letters)
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolIndex(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "client.go")
+	content := []byte("package lsp\n\ntype Client struct{}\n\nfunc (c *Client) OpenFile() {}\n")
+	require.NoError(t, os.WriteFile(path, content, 0o644))
+
+	index := LoadSymbolIndex(root)
+	assert.Equal(t, 0, index.Len())
+	index.Record(path, content, []protocol.DocumentSymbolResult{
+		&protocol.DocumentSymbol{
+			Name: "Client", Kind: protocol.Struct,
+			Range: protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 2, Character: 20}},
+			Children: []protocol.DocumentSymbol{
+				{Name: "OpenFile", Kind: protocol.Method, Range: protocol.Range{Start: protocol.Position{Line: 4}, End: protocol.Position{Line: 4, Character: 30}}},
+			},
+		},
+	})
+	require.NoError(t, index.Save())
+	assert.FileExists(t, filepath.Join(root, SymbolIndexDir, ".gitignore"))
+
+	// A new process finds the symbols on disk
+	loaded := LoadSymbolIndex(root)
+	require.Equal(t, 1, loaded.Len())
+	results := loaded.Search("opfl")
+	require.Len(t, results, 1)
+	symbol := results[0].(*protocol.SymbolInformation)
+	assert.Equal(t, "OpenFile", symbol.Name)
+	assert.Equal(t, "Client", symbol.ContainerName)
+	assert.Equal(t, uint32(4), symbol.Location.Range.Start.Line)
+
+	symbols, ok := loaded.FileSymbols(path)
+	require.True(t, ok)
+	assert.Len(t, symbols, 2)
+
+	// Symbols of a changed file are not used
+	require.NoError(t, os.WriteFile(path, append(content, "// changed\n"...), 0o644))
+	assert.Empty(t, loaded.Search("OpenFile"))
+	_, ok = loaded.FileSymbols(path)
+	assert.False(t, ok)
+}
+
+func TestFuzzyRank(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+		rank  int
+		ok    bool
+	}{
+		{"openfile", "openfile", 0, true},
+		{"openfile", "open", 1, true},
+		{"reopenfile", "open", 2, true},
+		{"openfile", "ofl", 3, true},
+		{"openfile", "close", 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name+"/"+tc.query, func(t *testing.T) {
+			rank, ok := fuzzyRank(tc.name, tc.query)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.rank, rank)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultTracePayloadBytes is how much of the params or result of a message is
+// traced before the rest is cut
+const DefaultTracePayloadBytes = 4096
+
+const (
+	traceSend    = "send"
+	traceReceive = "receive"
+)
+
+// Tracer writes every JSON-RPC message exchanged with the language server as a line
+// of JSON, like the message trace of VS Code's language clients. Responses are
+// traced with the method of their request and how long the request took.
+type Tracer struct {
+	w io.Writer
+	// maxPayload is how many bytes of params and results are traced, 0 for all
+	maxPayload int
+
+	mu sync.Mutex
+	// Requests waiting for a response, keyed by the direction they were sent in
+	// and their id
+	pending map[string]tracedRequest
+}
+
+// tracedRequest is a request whose response has not been traced yet
+type tracedRequest struct {
+	method string
+	start  time.Time
+}
+
+// traceEntry is a traced message
+type traceEntry struct {
+	Time      string          `json:"time"`
+	Direction string          `json:"direction"`
+	Kind      string          `json:"kind"`
+	Method    string          `json:"method,omitempty"`
+	ID        *MessageID      `json:"id,omitempty"`
+	ElapsedMs *float64        `json:"elapsedMs,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *ResponseError  `json:"error,omitempty"`
+	// Truncated is the full size of a params or result that was cut
+	Truncated int `json:"truncatedBytes,omitempty"`
+}
+
+// NewTracer creates a tracer writing to w, which keeps the first maxPayloadBytes of
+// params and results, or all of them when it is 0
+func NewTracer(w io.Writer, maxPayloadBytes int) *Tracer {
+	return &Tracer{
+		w:          w,
+		maxPayload: maxPayloadBytes,
+		pending:    make(map[string]tracedRequest),
+	}
+}
+
+// SetTracer traces the messages exchanged with the server from now on, or stops
+// tracing them when t is nil
+func (c *Client) SetTracer(t *Tracer) {
+	c.tracer.Store(t)
+}
+
+// trace writes a message sent or received in the given direction
+func (t *Tracer) trace(direction string, msg *Message) {
+	now := time.Now()
+	entry := traceEntry{
+		Time:      now.Format(time.RFC3339Nano),
+		Direction: direction,
+		Method:    msg.Method,
+		Error:     msg.Error,
+	}
+	hasID := msg.ID != nil && msg.ID.Value != nil
+	if hasID {
+		entry.ID = msg.ID
+	}
+
+	t.mu.Lock()
+	switch {
+	case msg.Method != "" && hasID:
+		entry.Kind = "request"
+		t.pending[direction+":"+msg.ID.String()] = tracedRequest{method: msg.Method, start: now}
+	case msg.Method != "":
+		entry.Kind = "notification"
+	default:
+		entry.Kind = "response"
+		// The request was sent the other way
+		requestDirection := traceSend
+		if direction == traceSend {
+			requestDirection = traceReceive
+		}
+		key := requestDirection + ":" + msg.ID.String()
+		if request, ok := t.pending[key]; ok {
+			delete(t.pending, key)
+			elapsed := float64(now.Sub(request.start).Microseconds()) / 1000
+			entry.Method = request.method
+			entry.ElapsedMs = &elapsed
+		}
+	}
+	t.mu.Unlock()
+
+	entry.Params = t.payload(msg.Params, &entry.Truncated)
+	entry.Result = t.payload(msg.Result, &entry.Truncated)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		lspLogger.Error("Failed to trace message: %v", err)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.w.Write(append(data, '\n')); err != nil {
+		lspLogger.Error("Failed to write trace: %v", err)
+	}
+}
+
+// payload returns the params or result of a message to trace. One that is larger
+// than the limit is replaced by a string holding its start, and its size is set
+// in truncated.
+func (t *Tracer) payload(raw json.RawMessage, truncated *int) json.RawMessage {
+	if t.maxPayload <= 0 || len(raw) <= t.maxPayload {
+		return raw
+	}
+	*truncated = len(raw)
+
+	cut := t.maxPayload
+	for cut > 0 && !utf8.RuneStart(raw[cut]) {
+		cut--
+	}
+	data, err := json.Marshal(fmt.Sprintf("%s...", raw[:cut]))
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// traceLines decodes the entries a tracer wrote
+func traceLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(&buf, 0)
+
+	request, err := NewRequest(int32(7), "textDocument/hover", map[string]any{"line": 1})
+	require.NoError(t, err)
+	tracer.trace(traceSend, request)
+	tracer.trace(traceReceive, &Message{JSONRPC: "2.0", ID: &MessageID{Value: int32(7)}, Result: json.RawMessage(`{"contents":"x"}`)})
+
+	notification, err := NewNotification("window/logMessage", map[string]any{"message": "hi"})
+	require.NoError(t, err)
+	tracer.trace(traceReceive, notification)
+
+	// A request from the server and the response to it
+	tracer.trace(traceReceive, &Message{JSONRPC: "2.0", ID: &MessageID{Value: "a"}, Method: "workspace/configuration"})
+	tracer.trace(traceSend, &Message{JSONRPC: "2.0", ID: &MessageID{Value: "a"}, Error: &ResponseError{Code: -32601, Message: "no"}})
+
+	entries := traceLines(t, &buf)
+	require.Len(t, entries, 5)
+
+	assert.Equal(t, "send", entries[0]["direction"])
+	assert.Equal(t, "request", entries[0]["kind"])
+	assert.Equal(t, float64(7), entries[0]["id"])
+	assert.Equal(t, map[string]any{"line": float64(1)}, entries[0]["params"])
+
+	assert.Equal(t, "receive", entries[1]["direction"])
+	assert.Equal(t, "response", entries[1]["kind"])
+	assert.Equal(t, "textDocument/hover", entries[1]["method"])
+	assert.Contains(t, entries[1], "elapsedMs")
+	assert.Equal(t, map[string]any{"contents": "x"}, entries[1]["result"])
+
+	assert.Equal(t, "notification", entries[2]["kind"])
+	assert.NotContains(t, entries[2], "id")
+
+	assert.Equal(t, "send", entries[4]["direction"])
+	assert.Equal(t, "workspace/configuration", entries[4]["method"])
+	assert.Contains(t, entries[4], "elapsedMs")
+	assert.Contains(t, entries[4], "error")
+}
+
+func TestTracerTruncatesPayloads(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(&buf, 12)
+
+	// The limit falls in the middle of the second é, which is not split
+	params := json.RawMessage(`{"text":"ééééééééé"}`)
+	tracer.trace(traceSend, &Message{JSONRPC: "2.0", Method: "textDocument/didChange", Params: params})
+
+	entries := traceLines(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, float64(len(params)), entries[0]["truncatedBytes"])
+	assert.Equal(t, `{"text":"é...`, entries[0]["params"])
+}
@@ -6,9 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/logging"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
 // Create component-specific loggers
@@ -94,10 +98,10 @@ func ReadMessage(r *bufio.Reader) (*Message, error) {
 	return &msg, nil
 }
 
-// handleMessages reads and dispatches messages in a loop
-func (c *Client) handleMessages() {
+// handleMessages reads and dispatches messages from the server in a loop
+func (c *Client) handleMessages(stdout *bufio.Reader) {
 	for {
-		msg, err := ReadMessage(c.stdout)
+		msg, err := ReadMessage(stdout)
 		if err != nil {
 			// Check if this is due to normal shutdown (EOF when closing connection)
 			if strings.Contains(err.Error(), "EOF") {
@@ -107,6 +111,10 @@ func (c *Client) handleMessages() {
 			}
 			return
 		}
+		c.paths.localMessage(msg)
+		if t := c.tracer.Load(); t != nil {
+			t.trace(traceReceive, msg)
+		}
 
 		// Handle server->client request (has both Method and ID)
 		if msg.Method != "" && msg.ID != nil && msg.ID.Value != nil {
@@ -150,7 +158,7 @@ func (c *Client) handleMessages() {
 			}
 
 			// Send response back to server
-			if err := WriteMessage(c.stdin, response); err != nil {
+			if err := c.write(response); err != nil {
 				lspLogger.Error("Error sending response to server: %v", err)
 			}
 
@@ -176,9 +184,11 @@ func (c *Client) handleMessages() {
 		if msg.ID != nil && msg.ID.Value != nil && msg.Method == "" {
 			// Convert ID to string for map lookup
 			idStr := msg.ID.String()
-			c.handlersMu.RLock()
+			// Remove the handler so that only one response is ever delivered to it
+			c.handlersMu.Lock()
 			ch, ok := c.handlers[idStr]
-			c.handlersMu.RUnlock()
+			delete(c.handlers, idStr)
+			c.handlersMu.Unlock()
 
 			if ok {
 				lspLogger.Debug("Sending response for ID %v to handler", msg.ID)
@@ -191,12 +201,44 @@ func (c *Client) handleMessages() {
 	}
 }
 
+// defaultRequestTimeout bounds requests whose context has no deadline
+const defaultRequestTimeout = 60 * time.Second
+
+// requestTimeout returns the timeout for requests, which can be overridden with the
+// LSP_REQUEST_TIMEOUT_MS environment variable
+func requestTimeout() time.Duration {
+	if envTimeout := os.Getenv("LSP_REQUEST_TIMEOUT_MS"); envTimeout != "" {
+		if val, err := strconv.Atoi(envTimeout); err == nil && val > 0 {
+			return time.Duration(val) * time.Millisecond
+		}
+	}
+	return defaultRequestTimeout
+}
+
 // Call makes a request and waits for the response
-func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+func (c *Client) Call(ctx context.Context, method string, params any, result any) (err error) {
 	id := c.nextID.Add(1)
 
+	// Pings are answered with an error by design
+	if observe := c.requestObserver.Load(); observe != nil && method != pingMethod {
+		start := time.Now()
+		defer func() { (*observe)(method, time.Since(start), err) }()
+	}
+
 	lspLogger.Debug("Making call: method=%s id=%v", method, id)
 
+	// The server is expected to exit after a shutdown request
+	if method == "shutdown" {
+		c.closing.Store(true)
+	}
+
+	if c.offline {
+		return c.callOffline(method, params, result)
+	}
+	if handled, err := c.answerFromIndex(method, params, result); handled {
+		return err
+	}
+
 	msg, err := NewRequest(id, method, params)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -217,20 +259,38 @@ func (c *Client) Call(ctx context.Context, method string, params any, result any
 	}()
 
 	// Send request
-	if err := WriteMessage(c.stdin, msg); err != nil {
+	if err := c.write(msg); err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 
 	lspLogger.Debug("Waiting for response to request ID: %v", msg.ID)
 
-	// Wait for response
-	resp := <-ch
+	// Wait for response, giving up when the context ends or the request takes too long
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout())
+		defer cancel()
+	}
+
+	var resp *Message
+	select {
+	case resp = <-ch:
+	case <-ctx.Done():
+		// Tell the server to stop working on the request
+		lspLogger.Warn("Canceling request %s (id: %v): %v", method, id, ctx.Err())
+		if err := c.Notify(context.Background(), "$/cancelRequest", protocol.CancelParams{ID: id}); err != nil {
+			lspLogger.Error("Failed to cancel request %v: %v", id, err)
+		}
+		return fmt.Errorf("request %s canceled: %w", method, ctx.Err())
+	}
 
 	lspLogger.Debug("Received response for request ID: %v", msg.ID)
 
 	if resp.Error != nil {
-		lspLogger.Error("Request failed: %s (code: %d)", resp.Error.Message, resp.Error.Code)
-		return fmt.Errorf("request failed: %s (code: %d)", resp.Error.Message, resp.Error.Code)
+		if method != pingMethod {
+			lspLogger.Error("Request failed: %s (code: %d)", resp.Error.Message, resp.Error.Code)
+		}
+		return fmt.Errorf("request failed: %w", resp.Error)
 	}
 
 	if result != nil {
@@ -258,12 +318,26 @@ func (c *Client) Notify(ctx context.Context, method string, params any) error {
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
 
-	if err := WriteMessage(c.stdin, msg); err != nil {
+	if err := c.write(msg); err != nil {
 		return fmt.Errorf("failed to send notification: %w", err)
 	}
 
 	return nil
 }
 
+// write sends a message to the current server connection
+func (c *Client) write(msg *Message) error {
+	if t := c.tracer.Load(); t != nil {
+		t.trace(traceSend, msg)
+	}
+	// Without a server there is no one to tell
+	if c.offline {
+		return nil
+	}
+	c.transportMu.RLock()
+	defer c.transportMu.RUnlock()
+	return WriteMessage(c.stdin, c.paths.remoteMessage(msg))
+}
+
 type NotificationHandler func(params json.RawMessage)
 type ServerRequestHandler func(params json.RawMessage) (any, error)
@@ -0,0 +1,228 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// workspaceFolder converts a workspace root into an LSP workspace folder
+func workspaceFolder(dir string) protocol.WorkspaceFolder {
+	return protocol.WorkspaceFolder{
+		URI:  string(fileuri.FromPath(dir)),
+		Name: dir,
+	}
+}
+
+// WorkspaceRoots returns the workspace roots the client was initialized with.
+// The first root is the primary workspace.
+func (c *Client) WorkspaceRoots() []string {
+	c.workspaceMu.RLock()
+	defer c.workspaceMu.RUnlock()
+	return append([]string(nil), c.workspaceRoots...)
+}
+
+// ResolvePath makes a path from a tool call absolute. Relative paths are resolved
+// against the first workspace root in which they exist, falling back to the
+// primary root so that new files are created there.
+func (c *Client) ResolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	roots := c.WorkspaceRoots()
+	if len(roots) == 0 {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+		return path
+	}
+
+	for _, root := range roots {
+		candidate := filepath.Join(root, path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return filepath.Join(roots[0], path)
+}
+
+// ResolveWorkspacePath resolves a path from a tool call like ResolvePath, and rejects
+// it unless it is inside one of the workspace roots once symlinks are followed
+func (c *Client) ResolveWorkspacePath(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+
+	resolved := filepath.Clean(c.ResolvePath(path))
+	real, err := evalSymlinksAllowMissing(resolved)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %v", path, err)
+	}
+
+	if !inRoots(real, c.WorkspaceRoots()) {
+		return "", fmt.Errorf("%s is outside the workspace", path)
+	}
+	return resolved, nil
+}
+
+// InRoots reports whether a path is one of the roots or inside one once symlinks
+// are followed
+func InRoots(path string, roots []string) bool {
+	real, err := evalSymlinksAllowMissing(filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return inRoots(real, roots)
+}
+
+// inRoots reports whether a path with its symlinks followed is inside one of the roots
+func inRoots(real string, roots []string) bool {
+	for _, root := range roots {
+		realRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			realRoot = root
+		}
+		if real == realRoot || strings.HasPrefix(real, realRoot+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// evalSymlinksAllowMissing follows the symlinks in the longest existing prefix of a
+// path, so that a file about to be created is checked by the directory it will be
+// created in
+func evalSymlinksAllowMissing(path string) (string, error) {
+	var missing []string
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{real}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
+
+// SupportsWorkspaceFolderChanges reports whether the server asked to be notified
+// when workspace folders are added or removed
+func (c *Client) SupportsWorkspaceFolderChanges() bool {
+	workspace := c.serverCapabilities.Workspace
+	if workspace == nil || workspace.WorkspaceFolders == nil || workspace.WorkspaceFolders.ChangeNotifications == nil {
+		return false
+	}
+
+	// Either true or a registration id
+	switch v := workspace.WorkspaceFolders.ChangeNotifications.Value.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	return false
+}
+
+// AddWorkspaceFolder adds a workspace root at runtime and notifies the server
+func (c *Client) AddWorkspaceFolder(ctx context.Context, dir string) error {
+	c.workspaceMu.Lock()
+	for _, root := range c.workspaceRoots {
+		if root == dir {
+			c.workspaceMu.Unlock()
+			return fmt.Errorf("%s is already a workspace folder", dir)
+		}
+	}
+	c.workspaceRoots = append(c.workspaceRoots, dir)
+	c.workspaceMu.Unlock()
+
+	err := c.DidChangeWorkspaceFolders(ctx, protocol.DidChangeWorkspaceFoldersParams{
+		Event: protocol.WorkspaceFoldersChangeEvent{
+			Added:   []protocol.WorkspaceFolder{workspaceFolder(dir)},
+			Removed: []protocol.WorkspaceFolder{},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to notify server of added workspace folder: %w", err)
+	}
+
+	// LSP sepecific Initialization
+	if err := c.afterInitialize(ctx, dir); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RemoveWorkspaceFolder removes a workspace root at runtime, closes its open files
+// and notifies the server. The last remaining root cannot be removed.
+func (c *Client) RemoveWorkspaceFolder(ctx context.Context, dir string) error {
+	c.workspaceMu.Lock()
+	index := -1
+	for i, root := range c.workspaceRoots {
+		if root == dir {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		c.workspaceMu.Unlock()
+		return fmt.Errorf("%s is not a workspace folder", dir)
+	}
+	if len(c.workspaceRoots) == 1 {
+		c.workspaceMu.Unlock()
+		return fmt.Errorf("cannot remove the only workspace folder")
+	}
+	c.workspaceRoots = append(c.workspaceRoots[:index:index], c.workspaceRoots[index+1:]...)
+	c.workspaceMu.Unlock()
+
+	// Close files that belong to the removed folder
+	c.openFilesMu.RLock()
+	var toClose []string
+	for uri := range c.openFiles {
+		if fileuri.InDir(uri, dir) {
+			toClose = append(toClose, fileuri.ToPath(uri))
+		}
+	}
+	c.openFilesMu.RUnlock()
+
+	for _, path := range toClose {
+		if err := c.CloseFile(ctx, path); err != nil {
+			lspLogger.Warn("Error closing file %s: %v", path, err)
+		}
+	}
+
+	err := c.DidChangeWorkspaceFolders(ctx, protocol.DidChangeWorkspaceFoldersParams{
+		Event: protocol.WorkspaceFoldersChangeEvent{
+			Added:   []protocol.WorkspaceFolder{},
+			Removed: []protocol.WorkspaceFolder{workspaceFolder(dir)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to notify server of removed workspace folder: %w", err)
+	}
+
+	return nil
+}
+
+// HandleWorkspaceFolders processes workspace/workspaceFolders requests
+func HandleWorkspaceFolders(client *Client) (any, error) {
+	roots := client.WorkspaceRoots()
+	folders := make([]protocol.WorkspaceFolder, 0, len(roots))
+	for _, root := range roots {
+		folders = append(folders, workspaceFolder(root))
+	}
+	return folders, nil
+}
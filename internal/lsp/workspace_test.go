@@ -0,0 +1,27 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInRoots(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "lib"), 0o755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+
+	roots := []string{root}
+	assert.True(t, InRoots(root, roots))
+	assert.True(t, InRoots(filepath.Join(root, "lib"), roots))
+	assert.True(t, InRoots(filepath.Join(root, "missing", "dir"), roots))
+	assert.False(t, InRoots(outside, roots))
+	assert.False(t, InRoots(filepath.Join(root, "escape"), roots))
+	assert.False(t, InRoots(filepath.Join(root, ".."), roots))
+	assert.False(t, InRoots("/", roots))
+	assert.False(t, InRoots(root, nil))
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	calls := r.NewCounter("tool_calls_total", "Tool calls.", "tool", "status")
+	latency := r.NewHistogram("request_duration_seconds", "Request latency.", []float64{0.1, 1}, "method")
+	r.NewGaugeFunc("open_files", "Open files.", "", func() map[string]float64 {
+		return map[string]float64{"": 3}
+	})
+	r.NewCounterFunc("events_total", "Events.", "type", func() map[string]float64 {
+		return map[string]float64{"deleted": 1, "created": 2}
+	})
+
+	calls.Inc("hover", "ok")
+	calls.Inc("hover", "ok")
+	calls.Inc("edit_file", `bad "value"`)
+	latency.Observe(0.05, "textDocument/hover")
+	latency.Observe(0.5, "textDocument/hover")
+	latency.Observe(2, "textDocument/hover")
+
+	var out strings.Builder
+	require.NoError(t, r.WriteText(&out))
+	assert.Equal(t, `# HELP tool_calls_total Tool calls.
+# TYPE tool_calls_total counter
+tool_calls_total{tool="edit_file",status="bad \"value\""} 1
+tool_calls_total{tool="hover",status="ok"} 2
+# HELP request_duration_seconds Request latency.
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{method="textDocument/hover",le="0.1"} 1
+request_duration_seconds_bucket{method="textDocument/hover",le="1"} 2
+request_duration_seconds_bucket{method="textDocument/hover",le="+Inf"} 3
+request_duration_seconds_sum{method="textDocument/hover"} 2.55
+request_duration_seconds_count{method="textDocument/hover"} 3
+# HELP open_files Open files.
+# TYPE open_files gauge
+open_files 3
+# HELP events_total Events.
+# TYPE events_total counter
+events_total{type="created"} 2
+events_total{type="deleted"} 1
+`, out.String())
+}
+
+func TestLabelCountMismatch(t *testing.T) {
+	c := NewRegistry().NewCounter("c", "A counter.", "a", "b")
+	assert.Panics(t, func() { c.Inc("only one") })
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("restarts_total", "Restarts.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "restarts_total 1\n")
+}
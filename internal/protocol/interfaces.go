@@ -115,3 +115,45 @@ func (e Or_TextDocumentEdit_edits_Elem) AsTextEdit() (TextEdit, error) {
 		return TextEdit{}, fmt.Errorf("unknown text edit type: %T", e.Value)
 	}
 }
+
+// locationsFromResult converts a Definition, Declaration or []LocationLink result to locations.
+// Links are converted to the location of the target's name.
+func locationsFromResult(value any) []Location {
+	switch v := value.(type) {
+	case Definition:
+		return locationsFromResult(v.Value)
+	case Declaration:
+		return locationsFromResult(v.Value)
+	case Location:
+		return []Location{v}
+	case []Location:
+		return v
+	case []LocationLink:
+		locations := make([]Location, len(v))
+		for i, link := range v {
+			locations[i] = Location{URI: link.TargetURI, Range: link.TargetSelectionRange}
+		}
+		return locations
+	}
+	return nil
+}
+
+// Locations converts the Value to a slice of Location
+func (r Or_Result_textDocument_definition) Locations() []Location {
+	return locationsFromResult(r.Value)
+}
+
+// Locations converts the Value to a slice of Location
+func (r Or_Result_textDocument_implementation) Locations() []Location {
+	return locationsFromResult(r.Value)
+}
+
+// Locations converts the Value to a slice of Location
+func (r Or_Result_textDocument_typeDefinition) Locations() []Location {
+	return locationsFromResult(r.Value)
+}
+
+// Locations converts the Value to a slice of Location
+func (r Or_Result_textDocument_declaration) Locations() []Location {
+	return locationsFromResult(r.Value)
+}
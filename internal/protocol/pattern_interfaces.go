@@ -41,13 +41,15 @@ func (g *GlobPattern) AsPattern() (PatternInfo, error) {
 	case string:
 		return StringPattern{Pattern: v}, nil
 	case RelativePattern:
-		// Handle BaseURI which could be string or DocumentUri
+		// Handle BaseURI which could be string, DocumentUri or WorkspaceFolder
 		basePath := ""
 		switch baseURI := v.BaseURI.Value.(type) {
 		case string:
-			basePath = strings.TrimPrefix(baseURI, "file://")
+			basePath = uriPath(baseURI)
 		case DocumentUri:
-			basePath = strings.TrimPrefix(string(baseURI), "file://")
+			basePath = uriPath(string(baseURI))
+		case WorkspaceFolder:
+			basePath = uriPath(baseURI.URI)
 		default:
 			return nil, fmt.Errorf("unknown BaseURI type: %T", v.BaseURI.Value)
 		}
@@ -56,3 +58,13 @@ func (g *GlobPattern) AsPattern() (PatternInfo, error) {
 		return nil, fmt.Errorf("unknown pattern type: %T", g.Value)
 	}
 }
+
+// uriPath returns the file path of a base URI, falling back to the text after
+// file:// for URIs that do not parse
+func uriPath(uri string) string {
+	parsed, err := ParseDocumentUri(uri)
+	if err != nil {
+		return strings.TrimPrefix(uri, "file://")
+	}
+	return parsed.Path()
+}
@@ -0,0 +1,258 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// When several language servers answer for the workspace, each for its own files,
+// the Across functions ask each of them and merge what they find. Results another
+// server already found are dropped, and each result names the server it is from.
+// A server that fails is skipped unless all of them do.
+
+// serverLabel names a server in merged results
+func serverLabel(client *lsp.Client) string {
+	if name := client.ServerName(); name != "" {
+		return name
+	}
+	return "language server"
+}
+
+// locationKey identifies a location when de-duplicating results
+func locationKey(loc protocol.Location) string {
+	return fmt.Sprintf("%s:%d:%d-%d:%d", loc.URI, loc.Range.Start.Line, loc.Range.Start.Character, loc.Range.End.Line, loc.Range.End.Character)
+}
+
+// acrossServers calls fn for each client, returning an error only when it fails
+// for all of them
+func acrossServers(clients []*lsp.Client, fn func(client *lsp.Client) error) error {
+	var errs []error
+	for _, client := range clients {
+		if err := fn(client); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", serverLabel(client), err))
+		}
+	}
+	if len(errs) == len(clients) {
+		return errors.Join(errs...)
+	}
+	for _, err := range errs {
+		toolsLogger.Warn("Leaving out the results of %v", err)
+	}
+	return nil
+}
+
+// serverSymbol is a workspace symbol and the server that found it
+type serverSymbol struct {
+	client *lsp.Client
+	symbol protocol.WorkspaceSymbolResult
+}
+
+// matchWorkspaceSymbolsAcross returns the workspace symbols matching a query
+// found by any of the servers, in the order of the servers
+func matchWorkspaceSymbolsAcross(ctx context.Context, clients []*lsp.Client, query string, exactMatch bool) ([]serverSymbol, error) {
+	var found []serverSymbol
+	seen := make(map[string]bool)
+	err := acrossServers(clients, func(client *lsp.Client) error {
+		matches, err := matchWorkspaceSymbols(ctx, client, query, exactMatch)
+		if err != nil {
+			return err
+		}
+		for _, symbol := range matches {
+			key := symbol.GetName() + "@" + locationKey(symbol.GetLocation())
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			found = append(found, serverSymbol{client: client, symbol: symbol})
+		}
+		return nil
+	})
+	return found, err
+}
+
+// SearchWorkspaceSymbolsAcross searches for symbols with every server, like
+// SearchWorkspaceSymbols, naming the server of each symbol
+func SearchWorkspaceSymbolsAcross(ctx context.Context, clients []*lsp.Client, query string, limit int, exactMatch bool) (string, error) {
+	matches, err := matchWorkspaceSymbolsAcross(ctx, clients, query, exactMatch)
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return fmt.Sprintf("No symbols found matching: %s", query), nil
+	}
+
+	total := len(matches)
+	if limit > 0 && total > limit {
+		matches = matches[:limit]
+	}
+
+	var output strings.Builder
+	if len(matches) < total {
+		output.WriteString(fmt.Sprintf("Found %d symbols matching %q with %d servers (showing first %d):\n\n", total, query, len(clients), len(matches)))
+	} else {
+		output.WriteString(fmt.Sprintf("Found %d symbols matching %q with %d servers:\n\n", total, query, len(clients)))
+	}
+
+	lines := make(map[*lsp.Client]*documentLines)
+	for _, match := range matches {
+		if lines[match.client] == nil {
+			lines[match.client] = newDocumentLines(match.client)
+		}
+		output.WriteString(formatWorkspaceSymbol(lines[match.client], match.symbol))
+		output.WriteString(fmt.Sprintf(" (%s)\n", serverLabel(match.client)))
+	}
+
+	return output.String(), nil
+}
+
+// CollectWorkspaceSymbolsAcross returns the symbols SearchWorkspaceSymbolsAcross
+// shows
+func CollectWorkspaceSymbolsAcross(ctx context.Context, clients []*lsp.Client, query string, limit int, exactMatch bool) (SymbolList, error) {
+	matches, err := matchWorkspaceSymbolsAcross(ctx, clients, query, exactMatch)
+	if err != nil {
+		return SymbolList{}, err
+	}
+
+	list := SymbolList{Query: query, Total: len(matches), Symbols: []SymbolEntry{}}
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	positions := make(map[*lsp.Client]*documentLines)
+	for _, match := range matches {
+		if positions[match.client] == nil {
+			positions[match.client] = newDocumentLines(match.client)
+		}
+		entry := symbolEntry(positions[match.client], match.symbol, match.symbol.GetLocation())
+		entry.Server = serverLabel(match.client)
+		list.Symbols = append(list.Symbols, entry)
+	}
+	return list, nil
+}
+
+// findReferencesAcross returns the references to a symbol each server found,
+// without those an earlier server found, sorted by file and position
+func findReferencesAcross(ctx context.Context, clients []*lsp.Client, symbolName string, includeDeclaration bool) ([]referenceGroup, error) {
+	var groups []referenceGroup
+	seen := make(map[string]bool)
+	err := acrossServers(clients, func(client *lsp.Client) error {
+		refsBySymbol, err := findReferences(ctx, client, symbolName, includeDeclaration, true)
+		if err != nil {
+			return err
+		}
+		var refs []protocol.Location
+		for _, symbolRefs := range refsBySymbol {
+			for _, ref := range symbolRefs {
+				if key := locationKey(ref); !seen[key] {
+					seen[key] = true
+					refs = append(refs, ref)
+				}
+			}
+		}
+		if len(refs) > 0 {
+			sortLocations(refs)
+			groups = append(groups, referenceGroup{
+				client:  client,
+				refs:    refs,
+				heading: fmt.Sprintf("=== References found by %s ===\n", serverLabel(client)),
+			})
+		}
+		return nil
+	})
+	return groups, err
+}
+
+// FindReferencesAcross finds references to a symbol with every server, like
+// FindReferencesWithOptions, under a heading for each server
+func FindReferencesAcross(ctx context.Context, clients []*lsp.Client, symbolName string, opts ReferenceOptions) (string, error) {
+	offset, err := parseCursor(opts.Cursor)
+	if err != nil {
+		return "", err
+	}
+	groups, err := findReferencesAcross(ctx, clients, symbolName, opts.IncludeDeclaration)
+	if err != nil {
+		return "", err
+	}
+	if opts.TextFallback && len(groups) == 0 {
+		groups, err = textualReferenceGroups(ctx, clients[0], symbolName)
+		if err != nil {
+			return "", err
+		}
+	}
+	return formatReferences(ctx, symbolName, groups, opts, offset)
+}
+
+// CollectReferencesAcross returns the references FindReferencesAcross shows
+func CollectReferencesAcross(ctx context.Context, clients []*lsp.Client, symbolName string, opts ReferenceOptions) (ReferenceList, error) {
+	offset, err := parseCursor(opts.Cursor)
+	if err != nil {
+		return ReferenceList{}, err
+	}
+	groups, err := findReferencesAcross(ctx, clients, symbolName, opts.IncludeDeclaration)
+	if err != nil {
+		return ReferenceList{}, err
+	}
+
+	var refs []Location
+	for _, group := range groups {
+		positions := newDocumentLines(group.client)
+		for _, ref := range group.refs {
+			loc := positions.location(ref)
+			loc.Server = serverLabel(group.client)
+			refs = append(refs, loc)
+		}
+	}
+	textual := false
+	if opts.TextFallback && len(refs) == 0 {
+		matches, err := textualReferences(ctx, clients[0], symbolName)
+		if err != nil {
+			return ReferenceList{}, err
+		}
+		refs = locationList(clients[0], matches).Locations
+		textual = len(refs) > 0
+	}
+
+	list := ReferenceList{Symbol: symbolName, Total: len(refs), References: []Location{}, TextualMatches: textual}
+	if len(refs) == 0 {
+		return list, nil
+	}
+	if offset >= len(refs) {
+		return ReferenceList{}, errCursorPastEnd(offset, len(refs))
+	}
+
+	end := len(refs)
+	if opts.Limit > 0 && offset+opts.Limit < end {
+		end = offset + opts.Limit
+		list.NextCursor = strconv.Itoa(end)
+	}
+	list.References = refs[offset:end]
+	return list, nil
+}
+
+// CollectBatchDiagnosticsAcross checks each server's files like
+// CollectBatchDiagnostics, merging the reports and naming the server of each file
+func CollectBatchDiagnosticsAcross(ctx context.Context, filesByClient map[*lsp.Client][]string, filter DiagnosticFilter) BatchDiagnosticsReport {
+	report := BatchDiagnosticsReport{Files: []FileDiagnostics{}}
+	for client, files := range filesByClient {
+		checked := CollectBatchDiagnostics(ctx, client, files, filter)
+		for _, file := range checked.Files {
+			file.Server = serverLabel(client)
+			report.Files = append(report.Files, file)
+		}
+		report.Failed = append(report.Failed, checked.Failed...)
+	}
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+	sort.Slice(report.Failed, func(i, j int) bool {
+		return report.Failed[i].Path < report.Failed[j].Path
+	})
+	return report
+}
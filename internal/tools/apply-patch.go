@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// ApplyPatch applies a unified diff to the workspace, notifies the language server of
+// each changed file and returns the resulting diagnostics for every file that still exists.
+// With dryRun the patch is only checked and the changes it would make are returned.
+func ApplyPatch(ctx context.Context, client *lsp.Client, patch string, dryRun bool) (string, error) {
+	patches, err := utilities.ParseUnifiedDiff(patch)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse patch: %v", err)
+	}
+
+	// Relative paths in the patch are relative to the workspace roots, and no path
+	// may lead outside of them. An empty path stands for /dev/null.
+	for i := range patches {
+		for _, path := range []*string{&patches[i].OldPath, &patches[i].NewPath} {
+			if *path == "" {
+				continue
+			}
+			if *path, err = client.ResolveWorkspacePath(*path); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if dryRun {
+		diff, err := utilities.PreviewPatch(patches)
+		if err != nil {
+			return "", fmt.Errorf("failed to apply patch: %v", err)
+		}
+		return dryRunResult(fmt.Sprintf("The patch applies cleanly to %d files.", len(patches)), diff), nil
+	}
+
+	entry := utilities.EditJournal.Begin("apply_patch")
+	for _, p := range patches {
+		for _, path := range []string{p.OldPath, p.NewPath} {
+			if path != "" {
+				entry.Capture(path)
+			}
+		}
+	}
+	summary, err := utilities.ApplyPatch(patches)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply patch: %v", err)
+	}
+	// Pre-save edits made below belong to the patch too
+	defer entry.Commit()
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Successfully applied patch to %d files:\n", len(summary)))
+	for _, line := range summary {
+		output.WriteString(line + "\n")
+	}
+
+	for _, p := range patches {
+		oldPath, newPath := p.OldPath, p.NewPath
+
+		// Close files that no longer exist
+		if oldPath != "" && oldPath != newPath && client.IsFileOpen(oldPath) {
+			if err := client.CloseFile(ctx, oldPath); err != nil {
+				toolsLogger.Error("Error closing file: %v", err)
+			}
+		}
+		if newPath == "" {
+			continue
+		}
+
+		// Send the new content to the server before asking for diagnostics
+		if client.IsFileOpen(newPath) {
+			err = client.NotifyChange(ctx, newPath)
+		} else {
+			err = client.OpenFile(ctx, newPath)
+		}
+		if err != nil {
+			toolsLogger.Error("Error notifying change: %v", err)
+		}
+		saveEditedFile(ctx, client, newPath)
+
+		diagnostics, err := GetDiagnosticsForFile(ctx, client, newPath, 0, true, DiagnosticFilter{}, false)
+		if err != nil {
+			toolsLogger.Error("Error getting diagnostics after patch: %v", err)
+			continue
+		}
+		output.WriteString("\n" + diagnostics)
+	}
+
+	return output.String(), nil
+}
@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+const (
+	// defaultBatchDiagnosticsFiles bounds how many files one batch opens
+	defaultBatchDiagnosticsFiles = 50
+	// batchDiagnosticsWorkers is how many files are opened or pulled at once
+	batchDiagnosticsWorkers = 8
+)
+
+// BatchDiagnosticsFiles returns the files a batch checks: the given paths followed
+// by the files matching the glob in each workspace root, without duplicates and
+// up to maxFiles. It reports how many more files there were.
+func BatchDiagnosticsFiles(client *lsp.Client, paths []string, glob string, maxFiles int) ([]string, int, error) {
+	if maxFiles <= 0 {
+		maxFiles = defaultBatchDiagnosticsFiles
+	}
+	// Files closed to stay under the cap may lose their diagnostics
+	if limit := client.MaxOpenFiles(); limit > 0 && limit < maxFiles {
+		maxFiles = limit
+	}
+	files := append([]string(nil), paths...)
+	if glob != "" {
+		for _, root := range client.WorkspaceRoots() {
+			// Look one file past the limit to know whether files are left out
+			matches, err := lsp.GlobFiles(root, []string{glob}, maxFiles+1)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to search %s: %v", root, err)
+			}
+			files = append(files, matches...)
+		}
+	}
+
+	seen := make(map[string]bool)
+	unique := files[:0]
+	for _, path := range files {
+		if !seen[path] {
+			seen[path] = true
+			unique = append(unique, path)
+		}
+	}
+	if len(unique) > maxFiles {
+		return unique[:maxFiles], len(unique) - maxFiles, nil
+	}
+	return unique, 0, nil
+}
+
+// BatchDiagnosticsReport is the diagnostics of several files, as reported by
+// batch_diagnostics
+type BatchDiagnosticsReport struct {
+	Files []FileDiagnostics `json:"files"`
+	// Failed holds the files that could not be opened
+	Failed []FailedFile `json:"failed,omitempty"`
+	// Skipped is how many more files matched than were checked
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// FailedFile is a file a batch could not check
+type FailedFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// CollectBatchDiagnostics opens every file, waits once for the server to finish
+// publishing diagnostics, or pulls them from servers that use the pull model, and
+// returns the diagnostics passing the filter for each file, sorted by path. Files
+// are opened and pulled concurrently.
+func CollectBatchDiagnostics(ctx context.Context, client *lsp.Client, files []string, filter DiagnosticFilter) BatchDiagnosticsReport {
+	failed := make(map[string]error)
+	var failedMu sync.Mutex
+	forEachFile(files, func(path string) {
+		if err := client.OpenFile(ctx, path); err != nil {
+			failedMu.Lock()
+			failed[path] = err
+			failedMu.Unlock()
+		}
+	})
+
+	if err := client.WaitForIdle(ctx, diagnosticsDebounce(), defaultDiagnosticsTimeout); err != nil {
+		toolsLogger.Warn("Returning diagnostics before the server is idle: %v", err)
+	}
+
+	var opened []string
+	for _, path := range files {
+		if failed[path] == nil {
+			opened = append(opened, path)
+		}
+	}
+	if client.SupportsPullDiagnostics() {
+		forEachFile(opened, func(path string) {
+			if err := client.PullDiagnostics(ctx, fileuri.FromPath(path)); err != nil {
+				toolsLogger.Error("Failed to get diagnostics for %s: %v", path, err)
+			}
+		})
+	}
+
+	positions := newDocumentLines(client)
+	report := BatchDiagnosticsReport{Files: make([]FileDiagnostics, 0, len(opened))}
+	for _, path := range opened {
+		uri := fileuri.FromPath(path)
+		file := FileDiagnostics{Path: path, Diagnostics: []DiagnosticEntry{}}
+		for _, diag := range filter.apply(client.GetFileDiagnostics(uri)) {
+			file.Diagnostics = append(file.Diagnostics, diagnosticEntry(positions, uri, diag))
+		}
+		report.Files = append(report.Files, file)
+	}
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+	for path, err := range failed {
+		report.Failed = append(report.Failed, FailedFile{Path: path, Error: err.Error()})
+	}
+	sort.Slice(report.Failed, func(i, j int) bool {
+		return report.Failed[i].Path < report.Failed[j].Path
+	})
+	return report
+}
+
+// BatchDiagnostics summarizes the diagnostics of several files, listing the files
+// without diagnostics on one line
+func BatchDiagnostics(report BatchDiagnosticsReport, filtered bool) string {
+	var output strings.Builder
+	var clean []string
+	count := 0
+	for _, file := range report.Files {
+		if len(file.Diagnostics) == 0 {
+			clean = append(clean, file.Path)
+			continue
+		}
+		count += len(file.Diagnostics)
+		if file.Server != "" {
+			fmt.Fprintf(&output, "%s (%s): %d diagnostics\n", file.Path, file.Server, len(file.Diagnostics))
+		} else {
+			fmt.Fprintf(&output, "%s: %d diagnostics\n", file.Path, len(file.Diagnostics))
+		}
+		for _, entry := range file.Diagnostics {
+			fmt.Fprintf(&output, "  %s at L%d:C%d: %s", entry.Severity, entry.Line, entry.Column, entry.Message)
+			if entry.Source != "" {
+				fmt.Fprintf(&output, " (Source: %s)", entry.Source)
+			}
+			output.WriteString("\n")
+		}
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "%d diagnostics in %d of %d files", count, len(report.Files)-len(clean), len(report.Files)+len(report.Failed))
+	if filtered {
+		result.WriteString(" matching the filter")
+	}
+	result.WriteString(":\n")
+	result.WriteString(output.String())
+	if len(clean) > 0 {
+		fmt.Fprintf(&result, "No diagnostics: %s\n", strings.Join(clean, ", "))
+	}
+
+	for _, file := range report.Failed {
+		fmt.Fprintf(&result, "Could not open %s: %s\n", file.Path, file.Error)
+	}
+	if report.Skipped > 0 {
+		fmt.Fprintf(&result, "%d more files were not checked, raise maxFiles or narrow the glob.\n", report.Skipped)
+	}
+	return result.String()
+}
+
+// forEachFile calls fn for every file, batchDiagnosticsWorkers at a time
+func forEachFile(files []string, fn func(path string)) {
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(batchDiagnosticsWorkers, len(files)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range work {
+				fn(path)
+			}
+		}()
+	}
+	for _, path := range files {
+		work <- path
+	}
+	close(work)
+	wg.Wait()
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEachFile(t *testing.T) {
+	files := []string{"a.go", "b.go", "c.go", "d.go", "e.go", "f.go", "g.go", "h.go", "i.go", "j.go"}
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	forEachFile(files, func(path string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[path]++
+	})
+
+	assert.Len(t, seen, len(files))
+	for _, path := range files {
+		assert.Equal(t, 1, seen[path], path)
+	}
+
+	forEachFile(nil, func(path string) {
+		t.Errorf("Unexpected call for %s", path)
+	})
+}
+
+func TestBatchDiagnostics(t *testing.T) {
+	report := BatchDiagnosticsReport{
+		Files: []FileDiagnostics{
+			{Path: "/src/a.go", Diagnostics: []DiagnosticEntry{
+				{Severity: "ERROR", Line: 3, Column: 2, Message: "undefined: x", Source: "compiler"},
+			}},
+			{Path: "/src/b.go", Diagnostics: []DiagnosticEntry{}},
+			{Path: "/src/c.go", Diagnostics: []DiagnosticEntry{}},
+		},
+		Failed:  []FailedFile{{Path: "/src/d.go", Error: "no such file"}},
+		Skipped: 4,
+	}
+
+	expected := "1 diagnostics in 1 of 4 files matching the filter:\n" +
+		"/src/a.go: 1 diagnostics\n" +
+		"  ERROR at L3:C2: undefined: x (Source: compiler)\n" +
+		"No diagnostics: /src/b.go, /src/c.go\n" +
+		"Could not open /src/d.go: no such file\n" +
+		"4 more files were not checked, raise maxFiles or narrow the glob.\n"
+	assert.Equal(t, expected, BatchDiagnostics(report, true))
+}
+
+func TestBatchDiagnosticsServers(t *testing.T) {
+	report := BatchDiagnosticsReport{
+		Files: []FileDiagnostics{
+			{Path: "/src/api.proto", Server: "buf", Diagnostics: []DiagnosticEntry{
+				{Severity: "WARNING", Line: 1, Column: 1, Message: "package should be versioned"},
+			}},
+			{Path: "/src/main.go", Server: "gopls", Diagnostics: []DiagnosticEntry{}},
+		},
+	}
+
+	expected := "1 diagnostics in 1 of 2 files:\n" +
+		"/src/api.proto (buf): 1 diagnostics\n" +
+		"  WARNING at L1:C1: package should be versioned\n" +
+		"No diagnostics: /src/main.go\n"
+	assert.Equal(t, expected, BatchDiagnostics(report, false))
+}
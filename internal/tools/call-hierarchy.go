@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// GetCallHierarchy builds a depth-limited tree of incoming and/or outgoing calls for the
+// symbol at the specified position. direction is one of "incoming", "outgoing" or "both".
+func GetCallHierarchy(ctx context.Context, client *lsp.Client, filePath string, line, column int, direction string, depth int) (string, error) {
+	// Open the file if not already open
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	if depth < 1 {
+		depth = 1
+	}
+
+	// Convert 1-indexed line/column to 0-indexed for LSP protocol
+	params := protocol.CallHierarchyPrepareParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: fileuri.FromPath(filePath),
+			},
+			Position: toPosition(client, filePath, line, column),
+		},
+	}
+
+	items, err := client.PrepareCallHierarchy(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare call hierarchy: %v", err)
+	}
+
+	if len(items) == 0 {
+		return fmt.Sprintf("No call hierarchy item found at L%d:C%d", line, column), nil
+	}
+
+	var output strings.Builder
+	for _, item := range items {
+		output.WriteString(fmt.Sprintf("Call hierarchy for %s\n", formatCallHierarchyItem(item)))
+
+		if direction == "incoming" || direction == "both" {
+			output.WriteString("\nIncoming calls (callers):\n")
+			visited := map[string]bool{callHierarchyItemKey(item): true}
+			if err := writeIncomingCalls(ctx, client, &output, item, 1, depth, visited); err != nil {
+				return "", err
+			}
+		}
+
+		if direction == "outgoing" || direction == "both" {
+			output.WriteString("\nOutgoing calls (callees):\n")
+			visited := map[string]bool{callHierarchyItemKey(item): true}
+			if err := writeOutgoingCalls(ctx, client, &output, item, 1, depth, visited); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return output.String(), nil
+}
+
+// writeIncomingCalls recursively writes the callers of item, indented by level
+func writeIncomingCalls(ctx context.Context, client *lsp.Client, output *strings.Builder, item protocol.CallHierarchyItem, level, maxDepth int, visited map[string]bool) error {
+	calls, err := client.IncomingCalls(ctx, protocol.CallHierarchyIncomingCallsParams{Item: item})
+	if err != nil {
+		return fmt.Errorf("failed to get incoming calls: %v", err)
+	}
+
+	indent := strings.Repeat("  ", level)
+	if len(calls) == 0 && level == 1 {
+		output.WriteString(indent + "(none)\n")
+	}
+
+	for _, call := range calls {
+		output.WriteString(fmt.Sprintf("%s- %s\n", indent, formatCallHierarchyItem(call.From)))
+		output.WriteString(fmt.Sprintf("%s  calls at: %s\n", indent, formatCallRanges(client, call.From.URI, call.FromRanges)))
+
+		key := callHierarchyItemKey(call.From)
+		if visited[key] {
+			output.WriteString(indent + "  (recursive)\n")
+			continue
+		}
+		if level < maxDepth {
+			visited[key] = true
+			if err := writeIncomingCalls(ctx, client, output, call.From, level+1, maxDepth, visited); err != nil {
+				return err
+			}
+			delete(visited, key)
+		}
+	}
+
+	return nil
+}
+
+// writeOutgoingCalls recursively writes the callees of item, indented by level
+func writeOutgoingCalls(ctx context.Context, client *lsp.Client, output *strings.Builder, item protocol.CallHierarchyItem, level, maxDepth int, visited map[string]bool) error {
+	calls, err := client.OutgoingCalls(ctx, protocol.CallHierarchyOutgoingCallsParams{Item: item})
+	if err != nil {
+		return fmt.Errorf("failed to get outgoing calls: %v", err)
+	}
+
+	indent := strings.Repeat("  ", level)
+	if len(calls) == 0 && level == 1 {
+		output.WriteString(indent + "(none)\n")
+	}
+
+	for _, call := range calls {
+		output.WriteString(fmt.Sprintf("%s- %s\n", indent, formatCallHierarchyItem(call.To)))
+		output.WriteString(fmt.Sprintf("%s  called at: %s\n", indent, formatCallRanges(client, item.URI, call.FromRanges)))
+
+		key := callHierarchyItemKey(call.To)
+		if visited[key] {
+			output.WriteString(indent + "  (recursive)\n")
+			continue
+		}
+		if level < maxDepth {
+			visited[key] = true
+			if err := writeOutgoingCalls(ctx, client, output, call.To, level+1, maxDepth, visited); err != nil {
+				return err
+			}
+			delete(visited, key)
+		}
+	}
+
+	return nil
+}
+
+// formatCallHierarchyItem renders an item as "Name (Kind) detail - file:line"
+func formatCallHierarchyItem(item protocol.CallHierarchyItem) string {
+	result := item.Name
+	if kind, ok := protocol.TableKindMap[item.Kind]; ok {
+		result += fmt.Sprintf(" (%s)", kind)
+	}
+	if item.Detail != "" {
+		result += " " + item.Detail
+	}
+	return fmt.Sprintf("%s - %s:L%d",
+		result,
+		fileuri.ToPath(item.URI),
+		item.SelectionRange.Start.Line+1,
+	)
+}
+
+// formatCallRanges renders call site ranges in the caller's document as a list of
+// positions
+func formatCallRanges(client *lsp.Client, uri protocol.DocumentUri, ranges []protocol.Range) string {
+	lines := newDocumentLines(client)
+	locs := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		locs = append(locs, fmt.Sprintf("L%d:C%d", r.Start.Line+1, lines.column(uri, r.Start)))
+	}
+	return strings.Join(locs, ", ")
+}
+
+// callHierarchyItemKey uniquely identifies an item for cycle detection
+func callHierarchyItemKey(item protocol.CallHierarchyItem) string {
+	return fmt.Sprintf("%s:%d:%d", item.URI, item.SelectionRange.Start.Line, item.SelectionRange.Start.Character)
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// CapabilityReport is what the language server supports, as reported by
+// lsp_capabilities
+type CapabilityReport struct {
+	Server           string          `json:"server"`
+	Name             string          `json:"name,omitempty"`
+	Version          string          `json:"version,omitempty"`
+	PositionEncoding string          `json:"positionEncoding"`
+	Methods          []MethodSupport `json:"methods"`
+	Tools            []ToolSupport   `json:"tools"`
+}
+
+// MethodSupport is whether the server supports an optional request method
+type MethodSupport struct {
+	Method    string `json:"method"`
+	Supported bool   `json:"supported"`
+}
+
+// ToolSupport is the state of a tool that depends on an optional capability:
+// active, unsupported when it explains that the server lacks the capability,
+// hidden when it was not registered for lacking it, or disabled by the
+// configuration
+type ToolSupport struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	State  string `json:"state"`
+}
+
+// CollectCapabilities returns what the language server supports, with the
+// state of the tools that depend on it
+func CollectCapabilities(client *lsp.Client, tools []ToolSupport) CapabilityReport {
+	name, version := client.ServerInfo()
+	report := CapabilityReport{
+		Server:           client.ServerName(),
+		Name:             name,
+		Version:          version,
+		PositionEncoding: string(client.PositionEncoding()),
+		Methods:          []MethodSupport{},
+		Tools:            tools,
+	}
+	for _, method := range lsp.OptionalMethods {
+		report.Methods = append(report.Methods, MethodSupport{
+			Method:    method,
+			Supported: client.SupportsMethod(method),
+		})
+	}
+	return report
+}
+
+// Capabilities summarizes what the language server supports and which tools are
+// consequently active
+func Capabilities(report CapabilityReport) string {
+	var output strings.Builder
+	fmt.Fprintf(&output, "Language server: %s\n", report.Server)
+	if report.Name != "" {
+		fmt.Fprintf(&output, "Reported name: %s", report.Name)
+		if report.Version != "" {
+			fmt.Fprintf(&output, " %s", report.Version)
+		}
+		output.WriteString("\n")
+	}
+	fmt.Fprintf(&output, "Position encoding: %s\n", report.PositionEncoding)
+
+	var supported, unsupported []string
+	for _, method := range report.Methods {
+		if method.Supported {
+			supported = append(supported, method.Method)
+		} else {
+			unsupported = append(unsupported, method.Method)
+		}
+	}
+	fmt.Fprintf(&output, "\nSupported methods (%d):\n", len(supported))
+	for _, method := range supported {
+		fmt.Fprintf(&output, "  %s\n", method)
+	}
+	fmt.Fprintf(&output, "Unsupported methods (%d):\n", len(unsupported))
+	for _, method := range unsupported {
+		fmt.Fprintf(&output, "  %s\n", method)
+	}
+
+	states := []string{"active", "unsupported", "hidden", "disabled"}
+	byState := make(map[string][]string)
+	for _, tool := range report.Tools {
+		byState[tool.State] = append(byState[tool.State], tool.Name)
+	}
+	output.WriteString("\nTools depending on these methods:\n")
+	for _, state := range states {
+		if names := byState[state]; len(names) > 0 {
+			fmt.Fprintf(&output, "  %s: %s\n", state, strings.Join(names, ", "))
+		}
+	}
+	if len(byState["unsupported"]) > 0 {
+		output.WriteString("\nUnsupported tools explain that the server lacks the capability when called.\n")
+	}
+	return output.String()
+}
@@ -0,0 +1,238 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// maxChangedFiles bounds how many changed files are opened to collect diagnostics
+const maxChangedFiles = 100
+
+// changedLines holds the 0-indexed lines changed in a file. A nil map means
+// every line, for files git does not track yet.
+type changedLines map[int]bool
+
+var diffHunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// ChangedDiagnostics reports the diagnostics on lines that changed relative to a
+// git ref, including uncommitted and untracked files, so that problems introduced
+// by a change can be told apart from existing ones. With mergeBase the changes are
+// taken relative to the merge base of ref and HEAD, as a pull request would show.
+func ChangedDiagnostics(ctx context.Context, client *lsp.Client, ref string, mergeBase bool) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	// Refs are passed to git as arguments, where a leading dash makes an option
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid git ref %q", ref)
+	}
+
+	// Workspace folders may share a repository, diff each repository once
+	changed := make(map[string]changedLines)
+	repos := make(map[string]bool)
+	var base string
+	for _, root := range client.WorkspaceRoots() {
+		repo, err := git(ctx, root, "rev-parse", "--show-toplevel")
+		if err != nil {
+			toolsLogger.Debug("Skipping %s, not in a git repository: %v", root, err)
+			continue
+		}
+		repo = strings.TrimSpace(repo)
+		if repos[repo] {
+			continue
+		}
+		repos[repo] = true
+
+		base, err = resolveCommit(ctx, repo, ref)
+		if err != nil {
+			return "", err
+		}
+		if mergeBase {
+			out, err := git(ctx, repo, "merge-base", base, "HEAD")
+			if err != nil {
+				return "", fmt.Errorf("failed to find the merge base of %s and HEAD in %s: %v", ref, repo, err)
+			}
+			base = strings.TrimSpace(out)
+		}
+
+		diff, err := git(ctx, repo, "-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff", "--unified=0", base, "--")
+		if err != nil {
+			return "", fmt.Errorf("failed to diff %s against %s: %v", repo, ref, err)
+		}
+		for path, lines := range parseChangedLines(diff) {
+			changed[filepath.Join(repo, path)] = lines
+		}
+
+		untracked, err := git(ctx, repo, "-c", "core.quotePath=false", "ls-files", "--others", "--exclude-standard")
+		if err != nil {
+			return "", fmt.Errorf("failed to list untracked files in %s: %v", repo, err)
+		}
+		for _, path := range strings.Split(strings.TrimSpace(untracked), "\n") {
+			if path != "" {
+				changed[filepath.Join(repo, path)] = nil
+			}
+		}
+	}
+	if len(repos) == 0 {
+		return "", fmt.Errorf("no workspace folder is in a git repository")
+	}
+
+	// Only files in the workspace and in a language the server may know are checked
+	var files []string
+	for path := range changed {
+		if inWorkspace(client, path) && lsp.DetectLanguageID(path) != "" {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+
+	since := ref
+	if mergeBase {
+		since = fmt.Sprintf("the merge base with %s", ref)
+	}
+	if len(files) == 0 {
+		return fmt.Sprintf("No source files changed since %s.", since), nil
+	}
+
+	var skipped int
+	if len(files) > maxChangedFiles {
+		skipped = len(files) - maxChangedFiles
+		files = files[:maxChangedFiles]
+	}
+
+	var output strings.Builder
+	var count, filesWithDiagnostics int
+	positions := newDocumentLines(client)
+	for _, path := range files {
+		diagnostics, err := refreshFileDiagnostics(ctx, client, path)
+		if err != nil {
+			toolsLogger.Debug("Skipping %s: %v", path, err)
+			continue
+		}
+
+		var found []string
+		for _, diag := range diagnostics {
+			if changed[path].overlaps(diag.Range) {
+				found = append(found, diagnosticSummary(positions, fileuri.FromPath(path), diag))
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		filesWithDiagnostics++
+		count += len(found)
+		output.WriteString(path + "\n")
+		for _, summary := range found {
+			output.WriteString("  " + summary + "\n")
+		}
+	}
+
+	var result strings.Builder
+	if count == 0 {
+		result.WriteString(fmt.Sprintf("No diagnostics on lines changed since %s in %d changed files.\n", since, len(files)))
+	} else {
+		result.WriteString(fmt.Sprintf("%d diagnostics on lines changed since %s, in %d of %d changed files:\n",
+			count, since, filesWithDiagnostics, len(files)))
+		result.WriteString(output.String())
+	}
+	if skipped > 0 {
+		result.WriteString(fmt.Sprintf("%d more changed files were not checked.\n", skipped))
+	}
+
+	return result.String(), nil
+}
+
+// overlaps reports whether any line of rng changed
+func (c changedLines) overlaps(rng protocol.Range) bool {
+	if c == nil {
+		return true
+	}
+	for line := int(rng.Start.Line); line <= int(rng.End.Line); line++ {
+		if c[line] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseChangedLines returns the lines added or modified in each file of a diff
+// produced with --unified=0, keyed by the file's new path. Lines next to a pure
+// deletion count as changed, since the deletion may cause problems there.
+func parseChangedLines(diff string) map[string]changedLines {
+	files := make(map[string]changedLines)
+	var current changedLines
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			current = nil
+			path := strings.TrimPrefix(line, "+++ ")
+			if path == "/dev/null" {
+				continue
+			}
+			current = make(changedLines)
+			files[strings.TrimPrefix(path, "b/")] = current
+			continue
+		}
+
+		match := diffHunkHeader.FindStringSubmatch(line)
+		if match == nil || current == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(match[1])
+		count := 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+
+		if count == 0 {
+			// A deletion after line start, which is 0 at the top of the file
+			current[max(start-1, 0)] = true
+			current[start] = true
+			continue
+		}
+		for i := 0; i < count; i++ {
+			current[start-1+i] = true
+		}
+	}
+	return files
+}
+
+// resolveCommit returns the hash of the commit a ref names in a repository, so
+// that only a hash is passed on to other git commands
+func resolveCommit(ctx context.Context, repo, ref string) (string, error) {
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid git ref %q", ref)
+	}
+	out, err := git(ctx, repo, "rev-parse", "--verify", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s to a commit in %s: %v", ref, repo, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// git runs a git command in dir and returns its output
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChangedLines(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -3 +3 @@ import "fmt"
+-func old() {}
++func new() {}
+@@ -10,0 +11,2 @@ func main() {
++	a := 1
++	b := 2
+@@ -20,2 +21,0 @@ func main() {
+-	c := 3
+-	d := 4
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+diff --git a/pkg/new.go b/pkg/new.go
+new file mode 100644
+--- /dev/null
++++ b/pkg/new.go
+@@ -0,0 +1,2 @@
++package pkg
++
+`
+
+	expected := map[string]changedLines{
+		"main.go":    {2: true, 10: true, 11: true, 20: true, 21: true},
+		"pkg/new.go": {0: true, 1: true},
+	}
+	assert.Equal(t, expected, parseChangedLines(diff))
+}
+
+func TestChangedLinesOverlaps(t *testing.T) {
+	lines := changedLines{4: true}
+	rng := func(start, end uint32) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: start},
+			End:   protocol.Position{Line: end},
+		}
+	}
+
+	assert.True(t, lines.overlaps(rng(4, 4)))
+	assert.True(t, lines.overlaps(rng(2, 6)))
+	assert.False(t, lines.overlaps(rng(5, 8)))
+
+	// Untracked files count as changed everywhere
+	assert.True(t, changedLines(nil).overlaps(rng(100, 100)))
+}
+
+func TestResolveCommit(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+
+	// Refs that git would take for options are rejected before git runs
+	_, err := resolveCommit(ctx, repo, "--output="+filepath.Join(repo, "written"))
+	assert.ErrorContains(t, err, "invalid git ref")
+	assert.NoFileExists(t, filepath.Join(repo, "written"))
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		_, err := git(ctx, repo, args...)
+		require.NoError(t, err)
+	}
+	head, err := git(ctx, repo, "rev-parse", "HEAD")
+	require.NoError(t, err)
+
+	hash, err := resolveCommit(ctx, repo, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(head), hash)
+
+	_, err = resolveCommit(ctx, repo, "no-such-branch")
+	assert.Error(t, err)
+}
@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// getCodeActions requests the code actions available for a range in a file.
+// Diagnostics overlapping the range are passed along so that quick fixes are offered.
+func getCodeActions(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kind string) ([]protocol.Or_Result_textDocument_codeAction_Item0_Elem, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Convert 1-indexed line/column to 0-indexed for LSP protocol
+	uri := fileuri.FromPath(filePath)
+	rng := protocol.Range{
+		Start: toPosition(client, filePath, startLine, startColumn),
+		End:   toPosition(client, filePath, endLine, endColumn),
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	for _, diag := range client.GetFileDiagnostics(uri) {
+		if utilities.RangesOverlap(diag.Range, rng) {
+			diagnostics = append(diagnostics, diag)
+		}
+	}
+
+	triggerKind := protocol.CodeActionInvoked
+	params := protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: uri,
+		},
+		Range: rng,
+		Context: protocol.CodeActionContext{
+			Diagnostics: diagnostics,
+			TriggerKind: &triggerKind,
+		},
+	}
+	if kind != "" {
+		params.Context.Only = []protocol.CodeActionKind{protocol.CodeActionKind(kind)}
+	}
+
+	actions, err := client.CodeAction(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code actions: %v", err)
+	}
+	return actions, nil
+}
+
+// QuickFix is a quick fix the server offers for a diagnostic. It is applied with
+// apply_code_action, passing the diagnostic's range, the quickfix kind and Index.
+type QuickFix struct {
+	Index     int    `json:"index"`
+	Title     string `json:"title"`
+	Preferred bool   `json:"preferred,omitempty"`
+}
+
+// diagnosticQuickFixes requests the quick fixes for a diagnostic in a file. Actions
+// keep their position in the list, so that disabled ones, which are left out, do
+// not shift the index of the others.
+func diagnosticQuickFixes(ctx context.Context, client *lsp.Client, filePath string, positions *documentLines, diag protocol.Diagnostic) []QuickFix {
+	uri := fileuri.FromPath(filePath)
+	actions, err := getCodeActions(ctx, client, filePath,
+		int(diag.Range.Start.Line)+1, positions.column(uri, diag.Range.Start),
+		int(diag.Range.End.Line)+1, positions.column(uri, diag.Range.End),
+		string(protocol.QuickFix))
+	if err != nil {
+		toolsLogger.Debug("Failed to get quick fixes for %s: %v", filePath, err)
+		return nil
+	}
+
+	var fixes []QuickFix
+	for i, item := range actions {
+		switch action := item.Value.(type) {
+		case protocol.CodeAction:
+			if action.Disabled == nil {
+				fixes = append(fixes, QuickFix{Index: i + 1, Title: action.Title, Preferred: action.IsPreferred})
+			}
+		case protocol.Command:
+			fixes = append(fixes, QuickFix{Index: i + 1, Title: action.Title})
+		}
+	}
+	return fixes
+}
+
+// ListCodeActions lists the quick fixes, refactorings and source actions available
+// for a range in a file. Each action is given an index that can be passed to ApplyCodeAction.
+func ListCodeActions(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kind string) (string, error) {
+	actions, err := getCodeActions(ctx, client, filePath, startLine, startColumn, endLine, endColumn, kind)
+	if err != nil {
+		return "", err
+	}
+
+	if len(actions) == 0 {
+		return fmt.Sprintf("No code actions available for %s L%d:C%d-L%d:C%d", filePath, startLine, startColumn, endLine, endColumn), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Code actions for %s L%d:C%d-L%d:C%d:\n\n", filePath, startLine, startColumn, endLine, endColumn))
+
+	for i, item := range actions {
+		switch action := item.Value.(type) {
+		case protocol.CodeAction:
+			output.WriteString(fmt.Sprintf("[%d] %s", i+1, action.Title))
+			if action.Kind != "" {
+				output.WriteString(fmt.Sprintf(" (%s)", action.Kind))
+			}
+			if action.IsPreferred {
+				output.WriteString(" [preferred]")
+			}
+			output.WriteString("\n")
+			if action.Disabled != nil {
+				output.WriteString(fmt.Sprintf("    Disabled: %s\n", action.Disabled.Reason))
+			}
+			for _, diag := range action.Diagnostics {
+				output.WriteString(fmt.Sprintf("    Fixes: %s\n", diag.Message))
+			}
+			if action.Command != nil {
+				output.WriteString(fmt.Sprintf("    Command: %s\n", action.Command.Command))
+			}
+		case protocol.Command:
+			output.WriteString(fmt.Sprintf("[%d] %s\n", i+1, action.Title))
+			output.WriteString(fmt.Sprintf("    Command: %s\n", action.Command))
+		}
+	}
+
+	output.WriteString(fmt.Sprintf("\nFound %d code actions.\n", len(actions)))
+
+	return output.String(), nil
+}
+
+// ApplyCodeAction applies the code action with the given 1-indexed position in the list
+// returned by ListCodeActions for the same range. The action's workspace edit is applied
+// first, then its command, if any, is executed on the server. With dryRun the edit is
+// returned as a diff and the command is not run.
+func ApplyCodeAction(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kind string, index int, dryRun bool) (string, error) {
+	action, err := findCodeAction(ctx, client, filePath, startLine, startColumn, endLine, endColumn, kind, index)
+	if err != nil {
+		return "", err
+	}
+
+	if dryRun {
+		return previewCodeAction(client, action.Title, action.Edit, action.Command)
+	}
+
+	if err := performCodeAction(ctx, client, "apply_code_action", action); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Successfully applied code action: %s", action.Title), nil
+}
+
+// findCodeAction returns the code action with the given 1-indexed position in the
+// list for a range, resolving its edit if the server deferred computing it. Bare
+// commands are returned as code actions that only run the command.
+func findCodeAction(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kind string, index int) (protocol.CodeAction, error) {
+	actions, err := getCodeActions(ctx, client, filePath, startLine, startColumn, endLine, endColumn, kind)
+	if err != nil {
+		return protocol.CodeAction{}, err
+	}
+
+	if len(actions) == 0 {
+		return protocol.CodeAction{}, fmt.Errorf("no code actions available for this range")
+	}
+
+	if index < 1 || index > len(actions) {
+		return protocol.CodeAction{}, fmt.Errorf("invalid code action index: %d. Available range: 1-%d", index, len(actions))
+	}
+
+	switch action := actions[index-1].Value.(type) {
+	case protocol.CodeAction:
+		if action.Disabled != nil {
+			return protocol.CodeAction{}, fmt.Errorf("code action is disabled: %s", action.Disabled.Reason)
+		}
+
+		// Resolve the code action if the server deferred computing its edit
+		if action.Edit == nil && action.Data != nil {
+			resolved, err := client.ResolveCodeAction(ctx, action)
+			if err != nil {
+				return protocol.CodeAction{}, fmt.Errorf("failed to resolve code action: %v", err)
+			}
+			action = resolved
+		}
+
+		if action.Edit == nil && action.Command == nil {
+			return protocol.CodeAction{}, fmt.Errorf("code action has no edit or command")
+		}
+		return action, nil
+	case protocol.Command:
+		return protocol.CodeAction{Title: action.Title, Command: &action}, nil
+	default:
+		return protocol.CodeAction{}, fmt.Errorf("unknown code action type: %T", action)
+	}
+}
+
+// performCodeAction applies the workspace edit of a code action, recording it in
+// the edit journal under the tool's name, then executes its command on the server
+func performCodeAction(ctx context.Context, client *lsp.Client, toolName string, action protocol.CodeAction) error {
+	if action.Edit != nil {
+		entry := utilities.EditJournal.Begin(fmt.Sprintf("%s %s", toolName, action.Title))
+		entry.CaptureEdit(*action.Edit)
+		if err := client.ApplyWorkspaceEdit(*action.Edit); err != nil {
+			return fmt.Errorf("failed to apply code action edit: %v", err)
+		}
+		syncEditedFiles(ctx, client, *action.Edit)
+		entry.Commit()
+	}
+
+	if action.Command != nil {
+		// Edits made by the command arrive through workspace/applyEdit
+		_, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
+			Command:   action.Command.Command,
+			Arguments: action.Command.Arguments,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute code action command: %v", err)
+		}
+	}
+	return nil
+}
+
+// previewCodeAction renders the edit of a code action for a dry run. Commands run
+// by the server cannot be previewed, so they are only named.
+func previewCodeAction(client *lsp.Client, title string, edit *protocol.WorkspaceEdit, command *protocol.Command) (string, error) {
+	summary := fmt.Sprintf("Code action: %s", title)
+	if command != nil {
+		summary += fmt.Sprintf("\nApplying it also runs the server command %q, whose changes cannot be previewed.", command.Command)
+	}
+	if edit == nil {
+		return dryRunResult(summary, ""), nil
+	}
+	return previewEdit(client, summary, *edit)
+}
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// GetCompletions retrieves completion candidates at the specified position, resolving
+// items whose detail or documentation the server defers to completionItem/resolve
+func GetCompletions(ctx context.Context, client *lsp.Client, filePath string, line, column int, limit int) (string, error) {
+	// Open the file if not already open
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Convert 1-indexed line/column to 0-indexed for LSP protocol
+	uri := fileuri.FromPath(filePath)
+	position := toPosition(client, filePath, line, column)
+
+	params := protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: uri,
+			},
+			Position: position,
+		},
+	}
+
+	completionResult, err := client.Completion(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to get completions: %v", err)
+	}
+
+	var items []protocol.CompletionItem
+	isIncomplete := false
+	switch v := completionResult.Value.(type) {
+	case protocol.CompletionList:
+		items = v.Items
+		isIncomplete = v.IsIncomplete
+	case []protocol.CompletionItem:
+		items = v
+	}
+
+	var output strings.Builder
+
+	// Show the line being completed with a cursor marker for context
+	lineText, err := ExtractTextFromLocation(protocol.Location{
+		URI: uri,
+		Range: protocol.Range{
+			Start: protocol.Position{Line: position.Line, Character: 0},
+			End:   protocol.Position{Line: position.Line + 1, Character: 0},
+		},
+	}, client.PositionEncoding())
+	if err == nil {
+		lineText = strings.TrimRight(lineText, "\r\n")
+		if position.Character <= utilities.CharacterLength(lineText, client.PositionEncoding()) {
+			cursor := utilities.ByteOffset(lineText, position.Character, client.PositionEncoding())
+			lineText = lineText[:cursor] + "‸" + lineText[cursor:]
+		}
+		output.WriteString(fmt.Sprintf("Completions at L%d:C%d:\n%s\n\n", line, column, lineText))
+	} else {
+		toolsLogger.Warn("failed to extract line at position: %v", err)
+	}
+
+	if len(items) == 0 {
+		output.WriteString("No completions available at this position.")
+		return output.String(), nil
+	}
+
+	total := len(items)
+	if limit > 0 && total > limit {
+		items = items[:limit]
+	}
+
+	resolve := client.SupportsCompletionResolve()
+	for i, item := range items {
+		// Servers may defer computing detail and documentation until the item is resolved
+		if resolve && item.Detail == "" && item.Documentation == nil {
+			resolved, err := client.ResolveCompletionItem(ctx, item)
+			if err != nil {
+				toolsLogger.Debug("Failed to resolve completion item %s: %v", item.Label, err)
+			} else {
+				item = resolved
+			}
+		}
+
+		output.WriteString(fmt.Sprintf("[%d] %s", i+1, item.Label))
+		if kind, ok := protocol.TableCompletionKindMap[item.Kind]; ok {
+			output.WriteString(fmt.Sprintf(" (%s)", kind))
+		}
+		if item.Deprecated {
+			output.WriteString(" [deprecated]")
+		}
+		output.WriteString("\n")
+
+		if item.Detail != "" {
+			output.WriteString(fmt.Sprintf("    Detail: %s\n", item.Detail))
+		}
+		if doc := completionDocumentation(item); doc != "" {
+			output.WriteString("    Documentation:\n")
+			for _, docLine := range strings.Split(doc, "\n") {
+				output.WriteString("      " + docLine + "\n")
+			}
+		}
+	}
+
+	if len(items) < total || isIncomplete {
+		output.WriteString(fmt.Sprintf("\nShowing %d of %d completions", len(items), total))
+		if isIncomplete {
+			output.WriteString(" (list is incomplete, type more characters to narrow it down)")
+		}
+		output.WriteString(".\n")
+	}
+
+	return output.String(), nil
+}
+
+// completionDocumentation returns the documentation of a completion item as plain text
+func completionDocumentation(item protocol.CompletionItem) string {
+	if item.Documentation == nil {
+		return ""
+	}
+	switch v := item.Documentation.Value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case protocol.MarkupContent:
+		return strings.TrimSpace(v.Value)
+	}
+	return ""
+}
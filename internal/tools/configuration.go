@@ -0,0 +1,23 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// UpdateConfiguration sends new settings to the language server without restarting it
+func UpdateConfiguration(ctx context.Context, client *lsp.Client, initOptions map[string]any, settings map[string]any) (string, error) {
+	if err := client.UpdateConfiguration(ctx, initOptions, settings); err != nil {
+		return "", err
+	}
+
+	if len(settings) == 0 && len(initOptions) == 0 {
+		return "Sent empty settings to the language server.", nil
+	}
+	if len(settings) == 0 {
+		return fmt.Sprintf("Sent %d top-level settings to the language server.", len(initOptions)), nil
+	}
+	return fmt.Sprintf("Sent %d settings sections to the language server.", len(settings)), nil
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// CreateFile writes a new file and tells the language server about it, so that
+// the server's view of the workspace and its diagnostics stay up to date. An
+// existing file is only replaced when overwrite is set.
+func CreateFile(ctx context.Context, client *lsp.Client, filePath, content string, overwrite bool) (string, error) {
+	_, err := os.Stat(filePath)
+	exists := err == nil
+	if exists && !overwrite {
+		return "", fmt.Errorf("%s already exists", filePath)
+	}
+
+	params := protocol.CreateFilesParams{
+		Files: []protocol.FileCreate{{URI: string(fileuri.FromPath(filePath))}},
+	}
+
+	// Ask the server for the edits creating the file needs
+	var edit protocol.WorkspaceEdit
+	if !exists && client.HandlesFileOperation(lsp.WillCreate, filePath, false) {
+		edit, err = client.WillCreateFiles(ctx, params)
+		if err != nil {
+			return "", fmt.Errorf("failed to get edits for the new file: %v", err)
+		}
+	}
+
+	entry := utilities.EditJournal.Begin(fmt.Sprintf("create_file %s", filePath))
+	entry.CaptureEdit(edit)
+	entry.Capture(filePath)
+	// Commit even if writing fails, so the edits already made can be undone
+	defer entry.Commit()
+
+	if err := utilities.ApplyWorkspaceEdit(edit, client.PositionEncoding()); err != nil {
+		return "", fmt.Errorf("failed to apply changes: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+
+	if !exists && client.HandlesFileOperation(lsp.DidCreate, filePath, false) {
+		if err := client.DidCreateFiles(ctx, params); err != nil {
+			toolsLogger.Error("Failed to notify the server of the new file: %v", err)
+		}
+	}
+
+	// Send the content to the server before asking for diagnostics
+	if client.IsFileOpen(filePath) {
+		err = client.NotifyChange(ctx, filePath)
+	} else {
+		err = client.OpenFile(ctx, filePath)
+	}
+	if err != nil {
+		toolsLogger.Error("Error notifying change: %v", err)
+	}
+	saveEditedFile(ctx, client, filePath)
+
+	var output strings.Builder
+	if exists {
+		output.WriteString(fmt.Sprintf("Replaced %s\n", filePath))
+	} else {
+		output.WriteString(fmt.Sprintf("Created %s\n", filePath))
+	}
+	if edited := editedFiles(edit); len(edited) > 0 {
+		output.WriteString(fmt.Sprintf("Updated %d files:\n", len(edited)))
+		for _, path := range edited {
+			output.WriteString(path + "\n")
+		}
+	}
+
+	diagnostics, err := GetDiagnosticsForFile(ctx, client, filePath, 0, true, DiagnosticFilter{}, false)
+	if err != nil {
+		toolsLogger.Error("Error getting diagnostics for new file: %v", err)
+		return output.String(), nil
+	}
+	output.WriteString("\n" + diagnostics)
+
+	return output.String(), nil
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// GetDeclaration returns the declaration of the symbol at the specified position. This
+// differs from the definition in languages that separate the two, such as C headers
+func GetDeclaration(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	locations, err := declarationLocations(ctx, client, filePath, line, column)
+	if err != nil {
+		return "", err
+	}
+
+	definitions := formatDefinitionLocations(ctx, client, locations)
+	if len(definitions) == 0 {
+		return fmt.Sprintf("No declaration found at %s L%d:C%d", filePath, line, column), nil
+	}
+
+	return strings.Join(definitions, ""), nil
+}
+
+// declarationLocations returns the declarations of the symbol at a position
+func declarationLocations(ctx context.Context, client *lsp.Client, filePath string, line, column int) ([]protocol.Location, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Convert 1-indexed line/column to 0-indexed for LSP protocol
+	params := protocol.DeclarationParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: fileuri.FromPath(filePath),
+			},
+			Position: toPosition(client, filePath, line, column),
+		},
+	}
+
+	result, err := client.Declaration(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get declaration: %v", err)
+	}
+	return result.Locations(), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
@@ -23,42 +24,22 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string)
 	}
 
 	var definitions []string
+	positions := newDocumentLines(client)
 	for _, symbol := range results {
-		kind := ""
-		container := ""
-
 		// Skip symbols that we are not looking for. workspace/symbol may return
 		// a large number of fuzzy matches.
-		switch v := symbol.(type) {
-		case *protocol.SymbolInformation:
+		if !definitionMatches(symbol, symbolName) {
+			continue
+		}
+
+		kind := ""
+		container := ""
+		if v, ok := symbol.(*protocol.SymbolInformation); ok {
 			// SymbolInformation results have richer data.
 			kind = fmt.Sprintf("Kind: %s\n", protocol.TableKindMap[v.Kind])
 			if v.ContainerName != "" {
 				container = fmt.Sprintf("Container Name: %s\n", v.ContainerName)
 			}
-
-			// Handle different matching strategies based on the search term
-			if strings.Contains(symbolName, ".") {
-				// For qualified names like "Type.Method", require exact match
-				if symbol.GetName() != symbolName {
-					continue
-				}
-			} else {
-				// For unqualified names like "Method"
-				if v.Kind == protocol.Method {
-					// For methods, only match if the method name matches exactly Type.symbolName or Type::symbolName or symbolName
-					if !strings.HasSuffix(symbol.GetName(), "::"+symbolName) && !strings.HasSuffix(symbol.GetName(), "."+symbolName) && symbol.GetName() != symbolName {
-						continue
-					}
-				} else if symbol.GetName() != symbolName {
-					// For non-methods, exact match only
-					continue
-				}
-			}
-		default:
-			if symbol.GetName() != symbolName {
-				continue
-			}
 		}
 
 		toolsLogger.Debug("Found symbol: %s", symbol.GetName())
@@ -79,11 +60,11 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string)
 				container+
 				"Range: L%d:C%d - L%d:C%d\n\n",
 			symbol.GetName(),
-			strings.TrimPrefix(string(loc.URI), "file://"),
+			fileuri.ToPath(loc.URI),
 			loc.Range.Start.Line+1,
-			loc.Range.Start.Character+1,
+			positions.column(loc.URI, loc.Range.Start),
 			loc.Range.End.Line+1,
-			loc.Range.End.Character+1,
+			positions.column(loc.URI, loc.Range.End),
 		)
 
 		if err != nil {
@@ -97,8 +78,75 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string)
 	}
 
 	if len(definitions) == 0 {
-		return fmt.Sprintf("%s not found", symbolName), nil
+		match, found, err := resolveFallback(ctx, client, symbolName)
+		if err != nil || !found {
+			return fmt.Sprintf("%s not found", symbolName), err
+		}
+		return ReadDefinitionAtPosition(ctx, client, match.Path, match.Line, match.Column, true)
+	}
+
+	return strings.Join(definitions, ""), nil
+}
+
+// definitionMatches reports whether a workspace symbol is the one ReadDefinition
+// looks for
+func definitionMatches(symbol protocol.WorkspaceSymbolResult, symbolName string) bool {
+	v, ok := symbol.(*protocol.SymbolInformation)
+	if !ok {
+		return symbol.GetName() == symbolName
+	}
+
+	// Handle different matching strategies based on the search term
+	if strings.Contains(symbolName, ".") {
+		// For qualified names like "Type.Method", require exact match
+		return symbol.GetName() == symbolName
+	}
+	// For unqualified names like "Method"
+	if v.Kind == protocol.Method {
+		// For methods, only match if the method name matches exactly Type.symbolName or Type::symbolName or symbolName
+		return strings.HasSuffix(symbol.GetName(), "::"+symbolName) || strings.HasSuffix(symbol.GetName(), "."+symbolName) || symbol.GetName() == symbolName
+	}
+	// For non-methods, exact match only
+	return symbol.GetName() == symbolName
+}
+
+// ReadDefinitionAtPosition goes to the definition of the symbol at the specified
+// position. With fullBody, the entire enclosing symbol of each definition, such as a
+// function or type body, is returned instead of just the declaration line.
+func ReadDefinitionAtPosition(ctx context.Context, client *lsp.Client, filePath string, line, column int, fullBody bool) (string, error) {
+	locations, err := definitionLocations(ctx, client, filePath, line, column)
+	if err != nil {
+		return "", err
+	}
+
+	definitions := formatTargetLocations(ctx, client, locations, fullBody)
+	if len(definitions) == 0 {
+		return fmt.Sprintf("No definition found at %s L%d:C%d", filePath, line, column), nil
 	}
 
 	return strings.Join(definitions, ""), nil
 }
+
+// definitionLocations returns the definitions of the symbol at a position
+func definitionLocations(ctx context.Context, client *lsp.Client, filePath string, line, column int) ([]protocol.Location, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Convert 1-indexed line/column to 0-indexed for LSP protocol
+	params := protocol.DefinitionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: fileuri.FromPath(filePath),
+			},
+			Position: toPosition(client, filePath, line, column),
+		},
+	}
+
+	result, err := client.Definition(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get definition: %v", err)
+	}
+	return result.Locations(), nil
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// DeleteFile deletes a file, or a directory when recursive is set, and tells the
+// language server about it so it drops the file from its index and diagnostics
+func DeleteFile(ctx context.Context, client *lsp.Client, path string, recursive bool) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("could not stat %s: %v", path, err)
+	}
+	for _, root := range client.WorkspaceRoots() {
+		if path == root {
+			return "", fmt.Errorf("%s is a workspace folder and cannot be deleted", path)
+		}
+	}
+	isDir := info.IsDir()
+	if isDir && !recursive {
+		return "", fmt.Errorf("%s is a directory, set recursive to delete it and its contents", path)
+	}
+
+	params := protocol.DeleteFilesParams{
+		Files: []protocol.FileDelete{{URI: string(fileuri.FromPath(path))}},
+	}
+
+	// Ask the server for the edits deleting the file needs
+	var edit protocol.WorkspaceEdit
+	if client.HandlesFileOperation(lsp.WillDelete, path, isDir) {
+		edit, err = client.WillDeleteFiles(ctx, params)
+		if err != nil {
+			return "", fmt.Errorf("failed to get edits for the deletion: %v", err)
+		}
+	}
+
+	entry := utilities.EditJournal.Begin(fmt.Sprintf("delete_file %s", path))
+	entry.CaptureEdit(edit)
+	entry.Capture(path)
+	// Commit even if the deletion fails, so the edits already made can be undone
+	defer entry.Commit()
+
+	if err := utilities.ApplyWorkspaceEdit(edit, client.PositionEncoding()); err != nil {
+		return "", fmt.Errorf("failed to apply changes: %v", err)
+	}
+
+	client.CloseOpenFiles(ctx, path)
+	if isDir {
+		err = os.RemoveAll(path)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to delete: %v", err)
+	}
+
+	if client.HandlesFileOperation(lsp.DidDelete, path, isDir) {
+		if err := client.DidDeleteFiles(ctx, params); err != nil {
+			toolsLogger.Error("Failed to notify the server of the deletion: %v", err)
+		}
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Deleted %s\n", path))
+	if edited := editedFiles(edit); len(edited) > 0 {
+		output.WriteString(fmt.Sprintf("Updated %d files:\n", len(edited)))
+		for _, edited := range edited {
+			output.WriteString(edited + "\n")
+		}
+	}
+
+	return output.String(), nil
+}
@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SinceLastEdit compares each file's diagnostics with those it had just before
+// its content was last sent to the server
+const SinceLastEdit = "lastEdit"
+
+// DiagnosticsDeltaReport is the diagnostics that appeared and disappeared since a
+// checkpoint, as reported by diagnostics_delta
+type DiagnosticsDeltaReport struct {
+	Since string                 `json:"since"`
+	Files []FileDiagnosticsDelta `json:"files"`
+}
+
+// FileDiagnosticsDelta is the change in the diagnostics of one file
+type FileDiagnosticsDelta struct {
+	Path string `json:"path"`
+	// Baseline is when the diagnostics compared against were reported, empty when
+	// none were recorded before the checkpoint
+	Baseline    string            `json:"baseline,omitempty"`
+	Appeared    []DiagnosticEntry `json:"appeared"`
+	Disappeared []DiagnosticEntry `json:"disappeared"`
+	Unchanged   int               `json:"unchanged"`
+}
+
+// CollectDiagnosticsDelta compares the current diagnostics of a file, or of every
+// file with a diagnostics history when filePath is empty, with the diagnostics
+// they had at a checkpoint. since is lastEdit, the name of a checkpoint or an
+// RFC 3339 time. Files whose diagnostics did not change are left out when
+// comparing every file.
+func CollectDiagnosticsDelta(ctx context.Context, client *lsp.Client, filePath string, since string) (DiagnosticsDeltaReport, error) {
+	if since == "" {
+		since = SinceLastEdit
+	}
+	var checkpoint time.Time
+	if since != SinceLastEdit {
+		at, ok := client.Checkpoint(since)
+		if !ok {
+			parsed, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				return DiagnosticsDeltaReport{}, fmt.Errorf("since must be %s, the name of a checkpoint or an RFC 3339 time, got %q", SinceLastEdit, since)
+			}
+			at = parsed
+		}
+		checkpoint = at
+	}
+
+	report := DiagnosticsDeltaReport{Since: since, Files: []FileDiagnosticsDelta{}}
+	var uris []protocol.DocumentUri
+	if filePath != "" {
+		if _, err := refreshFileDiagnostics(ctx, client, filePath); err != nil {
+			return report, err
+		}
+		uris = []protocol.DocumentUri{fileuri.FromPath(filePath)}
+	} else {
+		if err := client.WaitForIdle(ctx, defaultDiagnosticsDebounce, defaultDiagnosticsTimeout); err != nil {
+			toolsLogger.Warn("Comparing diagnostics before the server is idle: %v", err)
+		}
+		uris = client.DiagnosticsHistoryFiles()
+		sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	}
+
+	positions := newDocumentLines(client)
+	for _, uri := range uris {
+		at := checkpoint
+		if since == SinceLastEdit {
+			edited, ok := client.LastEdit(uri)
+			if !ok {
+				if filePath != "" {
+					return report, fmt.Errorf("%s has not been edited since it was opened", filePath)
+				}
+				continue
+			}
+			// Diagnostics reported just before the edit describe the old content
+			at = edited.Add(-time.Nanosecond)
+		}
+
+		file := FileDiagnosticsDelta{
+			Path:        fileuri.ToPath(uri),
+			Appeared:    []DiagnosticEntry{},
+			Disappeared: []DiagnosticEntry{},
+		}
+		baseline, known := client.DiagnosticsAt(uri, at)
+		if known {
+			file.Baseline = baseline.Time.Format(time.RFC3339)
+		}
+		appeared, disappeared, unchanged := diffDiagnostics(baseline.Diagnostics, client.GetFileDiagnostics(uri))
+		for _, diag := range appeared {
+			file.Appeared = append(file.Appeared, diagnosticEntry(positions, uri, diag))
+		}
+		for _, diag := range disappeared {
+			file.Disappeared = append(file.Disappeared, diagnosticEntry(positions, uri, diag))
+		}
+		file.Unchanged = unchanged
+
+		if filePath == "" && len(appeared) == 0 && len(disappeared) == 0 {
+			continue
+		}
+		report.Files = append(report.Files, file)
+	}
+	return report, nil
+}
+
+// DiagnosticsDelta describes which diagnostics appeared and disappeared
+func DiagnosticsDelta(report DiagnosticsDeltaReport) string {
+	since := "the checkpoint " + report.Since
+	if report.Since == SinceLastEdit {
+		since = "the last edit"
+	}
+	if len(report.Files) == 0 {
+		return fmt.Sprintf("No diagnostics appeared or disappeared since %s.\n", since)
+	}
+
+	var output strings.Builder
+	for _, file := range report.Files {
+		output.WriteString(file.Path + "\n")
+		if file.Baseline == "" {
+			fmt.Fprintf(&output, "  No diagnostics were recorded before %s, every current diagnostic is listed as new\n", since)
+		}
+		if len(file.Appeared) == 0 && len(file.Disappeared) == 0 {
+			fmt.Fprintf(&output, "  No change since %s, %d diagnostics remain\n", since, file.Unchanged)
+			continue
+		}
+		for _, entry := range file.Disappeared {
+			fmt.Fprintf(&output, "  - %s\n", deltaEntrySummary(entry))
+		}
+		for _, entry := range file.Appeared {
+			fmt.Fprintf(&output, "  + %s\n", deltaEntrySummary(entry))
+		}
+		fmt.Fprintf(&output, "  %d appeared, %d disappeared, %d unchanged\n", len(file.Appeared), len(file.Disappeared), file.Unchanged)
+	}
+	return output.String()
+}
+
+// deltaEntrySummary formats a diagnostic entry like diagnosticSummary
+func deltaEntrySummary(entry DiagnosticEntry) string {
+	summary := fmt.Sprintf("%s at L%d:C%d: %s", entry.Severity, entry.Line, entry.Column, entry.Message)
+	if entry.Source != "" {
+		summary += fmt.Sprintf(" (Source: %s)", entry.Source)
+	}
+	return summary
+}
+
+// diffDiagnostics matches diagnostics by severity, source, code and message, since
+// their ranges move as the file is edited, and returns those only in after, those
+// only in before and how many are in both
+func diffDiagnostics(before, after []protocol.Diagnostic) (appeared, disappeared []protocol.Diagnostic, unchanged int) {
+	remaining := make(map[string]int)
+	for _, diag := range before {
+		remaining[diagnosticKey(diag)]++
+	}
+	for _, diag := range after {
+		key := diagnosticKey(diag)
+		if remaining[key] > 0 {
+			remaining[key]--
+			unchanged++
+			continue
+		}
+		appeared = append(appeared, diag)
+	}
+	for _, diag := range before {
+		key := diagnosticKey(diag)
+		if remaining[key] > 0 {
+			remaining[key]--
+			disappeared = append(disappeared, diag)
+		}
+	}
+	return appeared, disappeared, unchanged
+}
+
+// diagnosticKey identifies a diagnostic independently of its position
+func diagnosticKey(diag protocol.Diagnostic) string {
+	return fmt.Sprintf("%d\x00%s\x00%v\x00%s", diag.Severity, diag.Source, diag.Code, diag.Message)
+}
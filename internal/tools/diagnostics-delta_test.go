@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDiagnostics(t *testing.T) {
+	diag := func(line uint32, message string) protocol.Diagnostic {
+		return protocol.Diagnostic{
+			Range:    protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line}},
+			Severity: protocol.SeverityError,
+			Source:   "compiler",
+			Message:  message,
+		}
+	}
+
+	before := []protocol.Diagnostic{
+		diag(3, "undefined: foo"),
+		diag(7, "unused variable x"),
+		diag(9, "unused variable x"),
+	}
+	// Lines moved after an edit, one of the two identical diagnostics was fixed
+	after := []protocol.Diagnostic{
+		diag(5, "unused variable x"),
+		diag(12, "missing return"),
+	}
+
+	appeared, disappeared, unchanged := diffDiagnostics(before, after)
+	assert.Equal(t, []protocol.Diagnostic{diag(12, "missing return")}, appeared)
+	assert.Equal(t, []protocol.Diagnostic{diag(3, "undefined: foo"), diag(7, "unused variable x")}, disappeared)
+	assert.Equal(t, 1, unchanged)
+
+	warning := diag(3, "undefined: foo")
+	warning.Severity = protocol.SeverityWarning
+	appeared, disappeared, unchanged = diffDiagnostics(before[:1], []protocol.Diagnostic{warning})
+	assert.Len(t, appeared, 1, "a change of severity is a different diagnostic")
+	assert.Len(t, disappeared, 1)
+	assert.Equal(t, 0, unchanged)
+}
@@ -4,48 +4,109 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
-// GetDiagnosticsForFile retrieves diagnostics for a specific file from the language server
-func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath string, contextLines int, showLineNumbers bool) (string, error) {
-	// Override with environment variable if specified
-	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
-		if val, err := strconv.Atoi(envLines); err == nil && val >= 0 {
-			contextLines = val
-		}
+const (
+	// defaultDiagnosticsDebounce is how long the server must be quiet before
+	// its diagnostics are considered complete
+	defaultDiagnosticsDebounce = time.Second
+	// defaultDiagnosticsTimeout bounds the wait for a busy server
+	defaultDiagnosticsTimeout = 30 * time.Second
+)
+
+// DiagnosticFilter selects diagnostics by severity, source and code. Empty fields
+// match every diagnostic.
+type DiagnosticFilter struct {
+	// MinSeverity keeps the diagnostics at least this severe, 0 keeps all
+	MinSeverity protocol.DiagnosticSeverity
+	// Sources keeps the diagnostics from these sources, such as compiler or go vet
+	Sources []string
+	// Codes keeps the diagnostics with these codes
+	Codes []string
+}
+
+// ParseSeverity converts a severity name, as printed in diagnostics, to a severity
+func ParseSeverity(name string) (protocol.DiagnosticSeverity, error) {
+	switch strings.ToLower(name) {
+	case "error":
+		return protocol.SeverityError, nil
+	case "warning":
+		return protocol.SeverityWarning, nil
+	case "info", "information":
+		return protocol.SeverityInformation, nil
+	case "hint":
+		return protocol.SeverityHint, nil
 	}
+	return 0, fmt.Errorf("unknown severity %q, expected error, warning, info or hint", name)
+}
 
-	err := client.OpenFile(ctx, filePath)
-	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
+// Matches reports whether a diagnostic passes the filter. Diagnostics without a
+// severity do not pass a severity filter.
+func (f DiagnosticFilter) Matches(diag protocol.Diagnostic) bool {
+	if f.MinSeverity != 0 && (diag.Severity == 0 || diag.Severity > f.MinSeverity) {
+		return false
+	}
+	if len(f.Sources) > 0 && !slices.ContainsFunc(f.Sources, func(source string) bool {
+		return strings.EqualFold(source, diag.Source)
+	}) {
+		return false
+	}
+	if len(f.Codes) > 0 && (diag.Code == nil || !slices.Contains(f.Codes, fmt.Sprint(diag.Code))) {
+		return false
 	}
+	return true
+}
 
-	// Wait for diagnostics
-	// TODO: wait for notification
-	time.Sleep(time.Second * 3)
+// IsEmpty reports whether the filter matches every diagnostic
+func (f DiagnosticFilter) IsEmpty() bool {
+	return f.MinSeverity == 0 && len(f.Sources) == 0 && len(f.Codes) == 0
+}
 
-	// Convert the file path to URI format
-	uri := protocol.DocumentUri("file://" + filePath)
+// apply returns the diagnostics that pass the filter
+func (f DiagnosticFilter) apply(diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	if f.IsEmpty() {
+		return diagnostics
+	}
+	var matched []protocol.Diagnostic
+	for _, diag := range diagnostics {
+		if f.Matches(diag) {
+			matched = append(matched, diag)
+		}
+	}
+	return matched
+}
 
-	// Request fresh diagnostics
-	diagParams := protocol.DocumentDiagnosticParams{
-		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+// GetDiagnosticsForFile retrieves diagnostics for a specific file from the language
+// server. With includeFixes the quick fixes offered for each diagnostic are listed
+// under it.
+func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath string, contextLines int, showLineNumbers bool, filter DiagnosticFilter, includeFixes bool) (string, error) {
+	// Override with environment variable if specified
+	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
+		if val, err := strconv.Atoi(envLines); err == nil && val >= 0 {
+			contextLines = val
+		}
 	}
-	_, err = client.Diagnostic(ctx, diagParams)
+
+	all, err := refreshFileDiagnostics(ctx, client, filePath)
 	if err != nil {
-		toolsLogger.Error("Failed to get diagnostics: %v", err)
+		return "", err
 	}
+	uri := fileuri.FromPath(filePath)
 
-	// Get diagnostics from the cache
-	diagnostics := client.GetFileDiagnostics(uri)
-
+	diagnostics := filter.apply(all)
 	if len(diagnostics) == 0 {
+		if len(all) > 0 {
+			return fmt.Sprintf("No diagnostics matching the filter found for %s, %d others were left out", filePath, len(all)), nil
+		}
 		return "No diagnostics found for " + filePath, nil
 	}
 
@@ -54,35 +115,30 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 		filePath,
 		len(diagnostics),
 	)
+	if len(diagnostics) < len(all) {
+		fileInfo = fmt.Sprintf("%s\nDiagnostics in File: %d matching the filter, of %d\n",
+			filePath,
+			len(diagnostics),
+			len(all),
+		)
+	}
 
 	// Create a summary of all the diagnostics
 	var diagSummaries []string
 	var diagLocations []protocol.Location
 
+	positions := newDocumentLines(client)
 	for _, diag := range diagnostics {
-		severity := getSeverityString(diag.Severity)
-		location := fmt.Sprintf("L%d:C%d",
-			diag.Range.Start.Line+1,
-			diag.Range.Start.Character+1)
-
-		summary := fmt.Sprintf("%s at %s: %s",
-			severity,
-			location,
-			diag.Message)
-
-		// Add source and code if available
-		if diag.Source != "" {
-			summary += fmt.Sprintf(" (Source: %s", diag.Source)
-			if diag.Code != nil {
-				summary += fmt.Sprintf(", Code: %v", diag.Code)
+		diagSummaries = append(diagSummaries, diagnosticSummary(positions, uri, diag))
+		for _, detail := range diagnosticDetails(positions, diag) {
+			diagSummaries = append(diagSummaries, "  "+detail)
+		}
+		if includeFixes {
+			for _, fix := range diagnosticQuickFixes(ctx, client, filePath, positions, diag) {
+				diagSummaries = append(diagSummaries, "  "+quickFixSummary(positions, uri, diag, fix))
 			}
-			summary += ")"
-		} else if diag.Code != nil {
-			summary += fmt.Sprintf(" (Code: %v)", diag.Code)
 		}
 
-		diagSummaries = append(diagSummaries, summary)
-
 		// Create a location for this diagnostic to use with line ranges
 		diagLocations = append(diagLocations, protocol.Location{
 			URI:   uri,
@@ -91,7 +147,7 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 	}
 
 	// Format content with context
-	fileContent, err := os.ReadFile(filePath)
+	fileContent, err := client.ReadDocument(filePath)
 	if err != nil {
 		return fileInfo + "\nError reading file: " + err.Error(), nil
 	}
@@ -135,6 +191,146 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 	return result, nil
 }
 
+// refreshFileDiagnostics opens a file, waits for the server to finish publishing
+// its diagnostics, or pulls them from servers that use the pull model, and
+// returns them
+func refreshFileDiagnostics(ctx context.Context, client *lsp.Client, filePath string) ([]protocol.Diagnostic, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Wait for the server to finish publishing diagnostics for the opened file
+	if err := client.WaitForIdle(ctx, diagnosticsDebounce(), defaultDiagnosticsTimeout); err != nil {
+		toolsLogger.Warn("Returning diagnostics before the server is idle: %v", err)
+	}
+
+	// Convert the file path to URI format
+	uri := fileuri.FromPath(filePath)
+
+	// Request fresh diagnostics from servers that use the pull model
+	if client.SupportsPullDiagnostics() {
+		err = client.PullDiagnostics(ctx, uri)
+		if err != nil {
+			toolsLogger.Error("Failed to get diagnostics: %v", err)
+		}
+	}
+
+	// Get diagnostics from the cache
+	return client.GetFileDiagnostics(uri), nil
+}
+
+// diagnosticsDebounce returns how long the server must be quiet before its
+// diagnostics are considered complete, which LSP_DIAGNOSTICS_DEBOUNCE_MS overrides
+func diagnosticsDebounce() time.Duration {
+	if envDebounce := os.Getenv("LSP_DIAGNOSTICS_DEBOUNCE_MS"); envDebounce != "" {
+		if val, err := strconv.Atoi(envDebounce); err == nil && val >= 0 {
+			return time.Duration(val) * time.Millisecond
+		}
+	}
+	return defaultDiagnosticsDebounce
+}
+
+// WaitForDiagnostics blocks until the language server has stopped reporting progress and
+// publishing diagnostics for the debounce duration, then summarizes the cached diagnostics
+func WaitForDiagnostics(ctx context.Context, client *lsp.Client, debounce, timeout time.Duration) (string, error) {
+	start := time.Now()
+	if err := client.WaitForIdle(ctx, debounce, timeout); err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Language server idle after %v.\n", time.Since(start).Round(time.Millisecond)))
+
+	allDiagnostics := client.GetAllDiagnostics()
+	uris := make([]string, 0, len(allDiagnostics))
+	for uri, diags := range allDiagnostics {
+		if len(diags) > 0 {
+			uris = append(uris, string(uri))
+		}
+	}
+	sort.Strings(uris)
+
+	if len(uris) == 0 {
+		output.WriteString("No diagnostics reported.\n")
+		return output.String(), nil
+	}
+
+	output.WriteString(fmt.Sprintf("Diagnostics reported in %d files:\n", len(uris)))
+	for _, uri := range uris {
+		counts := make(map[string]int)
+		for _, diag := range allDiagnostics[protocol.DocumentUri(uri)] {
+			counts[getSeverityString(diag.Severity)]++
+		}
+
+		var parts []string
+		for _, severity := range []string{"ERROR", "WARNING", "INFO", "HINT", "UNKNOWN"} {
+			if counts[severity] > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+			}
+		}
+		output.WriteString(fmt.Sprintf("%s: %s\n", fileuri.ToPath(uri), strings.Join(parts, ", ")))
+	}
+
+	return output.String(), nil
+}
+
+// diagnosticSummary describes a diagnostic in a document on one line, with its
+// severity, 1-indexed position, source and code
+func diagnosticSummary(positions *documentLines, uri protocol.DocumentUri, diag protocol.Diagnostic) string {
+	severity := getSeverityString(diag.Severity)
+	location := fmt.Sprintf("L%d:C%d",
+		diag.Range.Start.Line+1,
+		positions.column(uri, diag.Range.Start))
+
+	summary := fmt.Sprintf("%s at %s: %s",
+		severity,
+		location,
+		diag.Message)
+
+	// Add source and code if available
+	if diag.Source != "" {
+		summary += fmt.Sprintf(" (Source: %s", diag.Source)
+		if diag.Code != nil {
+			summary += fmt.Sprintf(", Code: %v", diag.Code)
+		}
+		summary += ")"
+	} else if diag.Code != nil {
+		summary += fmt.Sprintf(" (Code: %v)", diag.Code)
+	}
+	return summary
+}
+
+// diagnosticDetails describes the documentation link and related locations of a
+// diagnostic, one per line
+func diagnosticDetails(positions *documentLines, diag protocol.Diagnostic) []string {
+	var details []string
+	if diag.CodeDescription != nil && diag.CodeDescription.Href != "" {
+		details = append(details, fmt.Sprintf("Docs: %s", diag.CodeDescription.Href))
+	}
+	for _, related := range diag.RelatedInformation {
+		details = append(details, fmt.Sprintf("Related at %s:L%d:C%d: %s",
+			fileuri.ToPath(related.Location.URI),
+			related.Location.Range.Start.Line+1,
+			positions.column(related.Location.URI, related.Location.Range.Start),
+			related.Message))
+	}
+	return details
+}
+
+// quickFixSummary describes a quick fix for a diagnostic with the arguments
+// apply_code_action takes to apply it
+func quickFixSummary(positions *documentLines, uri protocol.DocumentUri, diag protocol.Diagnostic, fix QuickFix) string {
+	summary := fmt.Sprintf("Quick fix [%d]: %s", fix.Index, fix.Title)
+	if fix.Preferred {
+		summary += " [preferred]"
+	}
+	return summary + fmt.Sprintf(" (apply_code_action L%d:C%d-L%d:C%d, kind quickfix, index %d)",
+		diag.Range.Start.Line+1, positions.column(uri, diag.Range.Start),
+		diag.Range.End.Line+1, positions.column(uri, diag.Range.End),
+		fix.Index)
+}
+
 func getSeverityString(severity protocol.DiagnosticSeverity) string {
 	switch severity {
 	case protocol.SeverityError:
@@ -149,3 +345,122 @@ func getSeverityString(severity protocol.DiagnosticSeverity) string {
 		return "UNKNOWN"
 	}
 }
+
+// FileDiagnostics is the diagnostics of one file, in a form suitable for JSON
+type FileDiagnostics struct {
+	Path        string            `json:"path"`
+	Diagnostics []DiagnosticEntry `json:"diagnostics"`
+	// Server names the language server that checked the file, when files were
+	// checked by several servers
+	Server string `json:"server,omitempty"`
+}
+
+// DiagnosticEntry is a single diagnostic with 1-indexed positions
+type DiagnosticEntry struct {
+	Severity  string `json:"severity"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Message   string `json:"message"`
+	Source    string `json:"source,omitempty"`
+	Code      any    `json:"code,omitempty"`
+	// CodeDescription links to documentation of the code
+	CodeDescription string `json:"codeDescription,omitempty"`
+	// Related holds secondary locations, such as a previous declaration
+	Related []RelatedDiagnosticEntry `json:"related,omitempty"`
+	// Fixes holds the quick fixes offered for the diagnostic, when requested.
+	// They are applied with apply_code_action over the diagnostic's range.
+	Fixes []QuickFix `json:"fixes,omitempty"`
+}
+
+// RelatedDiagnosticEntry is a secondary location of a diagnostic with 1-indexed
+// positions
+type RelatedDiagnosticEntry struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// CollectDiagnostics returns the cached diagnostics of the given files, or of every
+// file with diagnostics when none are given, sorted by path
+func CollectDiagnostics(client *lsp.Client, uris ...protocol.DocumentUri) []FileDiagnostics {
+	all := client.GetAllDiagnostics()
+	if len(uris) == 0 {
+		for uri, diagnostics := range all {
+			if len(diagnostics) > 0 {
+				uris = append(uris, uri)
+			}
+		}
+	}
+
+	positions := newDocumentLines(client)
+	files := make([]FileDiagnostics, 0, len(uris))
+	for _, uri := range uris {
+		file := FileDiagnostics{
+			Path:        fileuri.ToPath(uri),
+			Diagnostics: []DiagnosticEntry{},
+		}
+		for _, diag := range all[uri] {
+			file.Diagnostics = append(file.Diagnostics, diagnosticEntry(positions, uri, diag))
+		}
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// CollectFileDiagnostics refreshes the diagnostics of a file like
+// GetDiagnosticsForFile, and returns those passing the filter in a form suitable
+// for JSON
+func CollectFileDiagnostics(ctx context.Context, client *lsp.Client, filePath string, filter DiagnosticFilter, includeFixes bool) (FileDiagnostics, error) {
+	diagnostics, err := refreshFileDiagnostics(ctx, client, filePath)
+	if err != nil {
+		return FileDiagnostics{}, err
+	}
+
+	uri := fileuri.FromPath(filePath)
+	positions := newDocumentLines(client)
+	file := FileDiagnostics{
+		Path:        filePath,
+		Diagnostics: []DiagnosticEntry{},
+	}
+	for _, diag := range filter.apply(diagnostics) {
+		entry := diagnosticEntry(positions, uri, diag)
+		if includeFixes {
+			entry.Fixes = diagnosticQuickFixes(ctx, client, filePath, positions, diag)
+		}
+		file.Diagnostics = append(file.Diagnostics, entry)
+	}
+	return file, nil
+}
+
+// diagnosticEntry converts a diagnostic to an entry with 1-indexed positions
+func diagnosticEntry(positions *documentLines, uri protocol.DocumentUri, diag protocol.Diagnostic) DiagnosticEntry {
+	entry := DiagnosticEntry{
+		Severity:  getSeverityString(diag.Severity),
+		Line:      int(diag.Range.Start.Line) + 1,
+		Column:    positions.column(uri, diag.Range.Start),
+		EndLine:   int(diag.Range.End.Line) + 1,
+		EndColumn: positions.column(uri, diag.Range.End),
+		Message:   diag.Message,
+		Source:    diag.Source,
+		Code:      diag.Code,
+	}
+	if diag.CodeDescription != nil {
+		entry.CodeDescription = string(diag.CodeDescription.Href)
+	}
+	for _, related := range diag.RelatedInformation {
+		entry.Related = append(entry.Related, RelatedDiagnosticEntry{
+			Path:    fileuri.ToPath(related.Location.URI),
+			Line:    int(related.Location.Range.Start.Line) + 1,
+			Column:  positions.column(related.Location.URI, related.Location.Range.Start),
+			Message: related.Message,
+		})
+	}
+	return entry
+}
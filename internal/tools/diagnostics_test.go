@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnosticFilter(t *testing.T) {
+	vetWarning := protocol.Diagnostic{Severity: protocol.SeverityWarning, Source: "go vet", Code: "printf", Message: "wrong verb"}
+	compilerError := protocol.Diagnostic{Severity: protocol.SeverityError, Source: "compiler", Code: float64(2304), Message: "undefined: x"}
+	hint := protocol.Diagnostic{Severity: protocol.SeverityHint, Message: "could be simplified"}
+	noSeverity := protocol.Diagnostic{Message: "unknown"}
+
+	tests := []struct {
+		name    string
+		filter  DiagnosticFilter
+		matches []bool
+	}{
+		{"Empty", DiagnosticFilter{}, []bool{true, true, true, true}},
+		{"Errors", DiagnosticFilter{MinSeverity: protocol.SeverityError}, []bool{false, true, false, false}},
+		{"Warnings and errors", DiagnosticFilter{MinSeverity: protocol.SeverityWarning}, []bool{true, true, false, false}},
+		{"Source ignores case", DiagnosticFilter{Sources: []string{"Go Vet"}}, []bool{true, false, false, false}},
+		{"Numeric code", DiagnosticFilter{Codes: []string{"2304"}}, []bool{false, true, false, false}},
+		{"Combined", DiagnosticFilter{MinSeverity: protocol.SeverityError, Sources: []string{"go vet"}}, []bool{false, false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, diag := range []protocol.Diagnostic{vetWarning, compilerError, hint, noSeverity} {
+				assert.Equal(t, tt.matches[i], tt.filter.Matches(diag), "diagnostic %q", diag.Message)
+			}
+		})
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	severity, err := ParseSeverity("Warning")
+	assert.NoError(t, err)
+	assert.Equal(t, protocol.SeverityWarning, severity)
+
+	severity, err = ParseSeverity("information")
+	assert.NoError(t, err)
+	assert.Equal(t, protocol.SeverityInformation, severity)
+
+	_, err = ParseSeverity("fatal")
+	assert.Error(t, err)
+}
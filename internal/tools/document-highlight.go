@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+var highlightKindNames = map[protocol.DocumentHighlightKind]string{
+	protocol.Text:  "text",
+	protocol.Read:  "read",
+	protocol.Write: "write",
+}
+
+// GetDocumentHighlights lists the occurrences within a file of the symbol at the
+// specified position, each marked as a read, a write, or a plain text match. This
+// is cheaper than finding references across the workspace when only local usage
+// matters.
+func GetDocumentHighlights(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	uri, highlights, err := documentHighlights(ctx, client, filePath, line, column)
+	if err != nil {
+		return "", err
+	}
+	if len(highlights) == 0 {
+		return fmt.Sprintf("No occurrences found at %s L%d:C%d", filePath, line, column), nil
+	}
+
+	content, err := client.ReadDocument(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	lines := strings.Split(string(content), "\n")
+
+	counts := make(map[string]int)
+	positions := newDocumentLines(client)
+	var output strings.Builder
+	for _, highlight := range highlights {
+		kind := highlightKind(highlight)
+		counts[kind]++
+
+		start := highlight.Range.Start
+		output.WriteString(fmt.Sprintf("L%d:C%d %s", start.Line+1, positions.column(uri, start), kind))
+		if int(start.Line) < len(lines) {
+			output.WriteString(": " + strings.TrimSpace(lines[start.Line]))
+		}
+		output.WriteString("\n")
+	}
+
+	name := ""
+	if rng := highlights[0].Range; rng.Start.Line == rng.End.Line && int(rng.Start.Line) < len(lines) {
+		text := lines[rng.Start.Line]
+		if rng.End.Character <= utilities.CharacterLength(text, client.PositionEncoding()) && rng.Start.Character < rng.End.Character {
+			name = fmt.Sprintf(" of %s", text[utilities.ByteOffset(text, rng.Start.Character, client.PositionEncoding()):utilities.ByteOffset(text, rng.End.Character, client.PositionEncoding())])
+		}
+	}
+
+	var summary []string
+	for _, kind := range []string{"write", "read", "text"} {
+		if counts[kind] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[kind], kind))
+		}
+	}
+
+	return fmt.Sprintf("%d occurrences%s in %s (%s):\n%s",
+		len(highlights), name, filePath, strings.Join(summary, ", "), output.String()), nil
+}
+
+// documentHighlights returns the occurrences of the symbol at a position in a file,
+// ordered by position
+func documentHighlights(ctx context.Context, client *lsp.Client, filePath string, line, column int) (protocol.DocumentUri, []protocol.DocumentHighlight, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Convert 1-indexed line/column to 0-indexed for LSP protocol
+	params := protocol.DocumentHighlightParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: fileuri.FromPath(filePath),
+			},
+			Position: toPosition(client, filePath, line, column),
+		},
+	}
+
+	highlights, err := client.DocumentHighlight(ctx, params)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get document highlights: %v", err)
+	}
+
+	sort.Slice(highlights, func(i, j int) bool {
+		a, b := highlights[i].Range.Start, highlights[j].Range.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+	return params.TextDocument.URI, highlights, nil
+}
+
+// highlightKind names the kind of a highlight
+func highlightKind(highlight protocol.DocumentHighlight) string {
+	// Servers may leave the kind out, which means a text match
+	if kind, ok := highlightKindNames[highlight.Kind]; ok {
+		return kind
+	}
+	return highlightKindNames[protocol.Text]
+}
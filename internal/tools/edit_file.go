@@ -7,6 +7,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
@@ -14,55 +15,166 @@ import (
 )
 
 type TextEdit struct {
-	StartLine int    `json:"startLine" jsonschema:"required,description=Start line to replace, inclusive"`
-	EndLine   int    `json:"endLine" jsonschema:"required,description=End line to replace, inclusive"`
-	NewText   string `json:"newText" jsonschema:"description=Replacement text. Replace with the new text. Leave blank to remove lines."`
+	StartLine   int    `json:"startLine" jsonschema:"required,description=Start line to replace, inclusive"`
+	EndLine     int    `json:"endLine" jsonschema:"required,description=End line to replace, inclusive"`
+	StartColumn int    `json:"startColumn,omitempty" jsonschema:"description=Start column to replace, inclusive. Replaces whole lines if both columns are omitted"`
+	EndColumn   int    `json:"endColumn,omitempty" jsonschema:"description=End column to replace, exclusive. Replaces whole lines if both columns are omitted"`
+	NewText     string `json:"newText" jsonschema:"description=Replacement text. Replace with the new text. Leave blank to remove lines."`
 }
 
-func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, edits []TextEdit) (string, error) {
+// ApplyTextEdits replaces line ranges of a file with new text. With dryRun the
+// changes are returned as a diff instead of being applied.
+func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, edits []TextEdit, dryRun bool) (string, error) {
 	err := client.OpenFile(ctx, filePath)
 	if err != nil {
 		return "", fmt.Errorf("could not open file: %v", err)
 	}
+	if err := checkNotStaged(client, filePath); err != nil {
+		return "", err
+	}
 
-	// Create a sorted copy of edits for reporting
-	sortedEdits := make([]TextEdit, len(edits))
-	copy(sortedEdits, edits)
-	sort.Slice(sortedEdits, func(i, j int) bool {
-		return sortedEdits[i].StartLine < sortedEdits[j].StartLine
-	})
+	content, err := client.ReadDocument(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	textEdits, linesRemoved, linesAdded, err := convertTextEdits(edits, content, client.PositionEncoding())
+	if err != nil {
+		return "", err
+	}
+
+	// The edits replace whole lines, so they are applied line by line rather than
+	// by position like the edits language servers compute
+	newContent, err := utilities.EditContent(content, textEdits, client.PositionEncoding())
+	if err != nil {
+		return "", fmt.Errorf("failed to apply text edits: %v", err)
+	}
+
+	if dryRun {
+		diff, err := utilities.UnifiedDiff(filePath, filePath, content, newContent)
+		if err != nil {
+			return "", fmt.Errorf("failed to preview changes: %v", err)
+		}
+		return dryRunResult(fmt.Sprintf("The edits would remove %d lines and add %d lines.", linesRemoved, linesAdded), diff), nil
+	}
+
+	entry := utilities.EditJournal.Begin(fmt.Sprintf("edit_file %s", filePath))
+	entry.Capture(filePath)
+	if err := os.WriteFile(filePath, newContent, 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+
+	// Send the new content to the server so diagnostics and later requests see it
+	if err := client.NotifyChange(ctx, filePath); err != nil {
+		toolsLogger.Error("Error notifying change: %v", err)
+	}
+	preSaved := saveEditedFile(ctx, client, filePath)
+	entry.Commit()
+
+	result := fmt.Sprintf("Successfully applied text edits. %d lines removed, %d lines added.", linesRemoved, linesAdded)
+	if preSaved {
+		result += preSaveNote
+	}
+	return result, nil
+}
+
+// StageTextEdits makes the same edits as ApplyTextEdits to the language server's
+// copy of a file, leaving the file on disk alone until they are committed. Line
+// numbers refer to the file with the edits staged before. With dryRun the changes
+// are returned as a diff instead of being staged.
+func StageTextEdits(ctx context.Context, client *lsp.Client, filePath string, edits []TextEdit, dryRun bool) (string, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	content, err := client.ReadDocument(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	textEdits, linesRemoved, linesAdded, err := convertTextEdits(edits, content, client.PositionEncoding())
+	if err != nil {
+		return "", err
+	}
+
+	if dryRun {
+		newContent, err := utilities.EditContent(content, textEdits, client.PositionEncoding())
+		if err != nil {
+			return "", fmt.Errorf("failed to preview changes: %v", err)
+		}
+		diff, err := utilities.UnifiedDiff(filePath, filePath, content, newContent)
+		if err != nil {
+			return "", fmt.Errorf("failed to preview changes: %v", err)
+		}
+		return dryRunResult(fmt.Sprintf("Staging the edits would remove %d lines and add %d lines.", linesRemoved, linesAdded), diff), nil
+	}
+
+	if err := client.StageEdits(ctx, filePath, textEdits); err != nil {
+		return "", fmt.Errorf("failed to stage text edits: %v", err)
+	}
+	return fmt.Sprintf("Staged text edits. %d lines removed, %d lines added. The file on disk is unchanged until commit_staged writes the staged edits.",
+		linesRemoved, linesAdded), nil
+}
 
-	// Track lines added and removed for sorted edits
-	linesRemovedSorted := 0
-	linesAddedSorted := 0
-	for _, edit := range sortedEdits {
+// ApplyTextEditsWithDiagnostics applies or stages the edits like ApplyTextEdits and
+// StageTextEdits and then reports the diagnostics for the edited file, so that
+// mistakes in the edit are caught immediately
+func ApplyTextEditsWithDiagnostics(ctx context.Context, client *lsp.Client, filePath string, edits []TextEdit, dryRun bool, stage bool) (string, error) {
+	apply := ApplyTextEdits
+	if stage {
+		apply = StageTextEdits
+	}
+	result, err := apply(ctx, client, filePath, edits, dryRun)
+	if err != nil || dryRun {
+		return result, err
+	}
+
+	diagnostics, err := GetDiagnosticsForFile(ctx, client, filePath, 0, true, DiagnosticFilter{}, false)
+	if err != nil {
+		toolsLogger.Error("Error getting diagnostics after edit: %v", err)
+		return result, nil
+	}
+
+	return result + "\n\n" + diagnostics, nil
+}
+
+// convertTextEdits converts edits to protocol edits against content, ordered from
+// the bottom of the file to the top, with positions in the given encoding, and
+// counts the lines they remove and add
+func convertTextEdits(edits []TextEdit, content []byte, encoding protocol.PositionEncodingKind) ([]protocol.TextEdit, int, int, error) {
+	// Track lines added and removed
+	linesRemoved := 0
+	linesAdded := 0
+	for _, edit := range edits {
 		// Calculate lines removed: end - start + 1
-		removedLineCount := edit.EndLine - edit.StartLine + 1
-		linesRemovedSorted += removedLineCount
+		linesRemoved += edit.EndLine - edit.StartLine + 1
 
 		// Calculate lines added: count newlines in the replacement text + 1
-		addedLineCount := 1
 		if edit.NewText != "" {
-			addedLineCount = strings.Count(edit.NewText, "\n") + 1
-		} else if edit.NewText == "" {
-			addedLineCount = 0
+			linesAdded += strings.Count(edit.NewText, "\n") + 1
 		}
-		linesAddedSorted += addedLineCount
 	}
 
 	// Sort edits by line number in descending order to process from bottom to top
 	// This way line numbers don't shift under us as we make edits
-	sort.Slice(edits, func(i, j int) bool {
-		return edits[i].StartLine > edits[j].StartLine
+	sorted := make([]TextEdit, len(edits))
+	copy(sorted, edits)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartLine > sorted[j].StartLine
 	})
 
 	// Convert from input format to protocol.TextEdit
 	var textEdits []protocol.TextEdit
-	for _, edit := range edits {
-		// Get the range covering the requested lines
-		rng, err := getRange(edit.StartLine, edit.EndLine, filePath)
+	for _, edit := range sorted {
+		// Get the range covering the requested lines, or the exact characters if columns are given
+		var rng protocol.Range
+		var err error
+		if edit.StartColumn > 0 || edit.EndColumn > 0 {
+			rng, err = getColumnRange(edit, content, encoding)
+		} else {
+			rng, err = getRange(edit.StartLine, edit.EndLine, content, encoding)
+		}
 		if err != nil {
-			return "", fmt.Errorf("invalid position: %v", err)
+			return nil, 0, 0, fmt.Errorf("invalid position: %v", err)
 		}
 
 		// Always do a replacement
@@ -71,27 +183,67 @@ func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, ed
 			NewText: edit.NewText,
 		})
 	}
+	return textEdits, linesRemoved, linesAdded, nil
+}
 
-	edit := protocol.WorkspaceEdit{
-		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
-			protocol.DocumentUri(filePath): textEdits,
-		},
+// getColumnRange creates a protocol.Range for an edit with columns, checking that both
+// positions exist in the file. Columns count Unicode characters. Missing columns
+// default to the start of the first line and the end of the last line.
+func getColumnRange(edit TextEdit, content []byte, encoding protocol.PositionEncodingKind) (protocol.Range, error) {
+	// Detect line ending style
+	var lineEnding string
+	if bytes.Contains(content, []byte("\r\n")) {
+		lineEnding = "\r\n"
+	} else {
+		lineEnding = "\n"
 	}
 
-	if err := utilities.ApplyWorkspaceEdit(edit); err != nil {
-		return "", fmt.Errorf("failed to apply text edits: %v", err)
+	lines := strings.Split(string(content), lineEnding)
+
+	if edit.StartLine < 1 || edit.StartLine > len(lines) {
+		return protocol.Range{}, fmt.Errorf("start line %d is outside the file (1-%d)", edit.StartLine, len(lines))
+	}
+	if edit.EndLine < edit.StartLine || edit.EndLine > len(lines) {
+		return protocol.Range{}, fmt.Errorf("end line %d is outside the file or before the start line", edit.EndLine)
 	}
 
-	return fmt.Sprintf("Successfully applied text edits. %d lines removed, %d lines added.", linesRemovedSorted, linesAddedSorted), nil
-}
+	startText, endText := lines[edit.StartLine-1], lines[edit.EndLine-1]
+	startLength, endLength := utf8.RuneCountInString(startText), utf8.RuneCountInString(endText)
 
-// getRange creates a protocol.Range that covers the specified start and end lines
-func getRange(startLine, endLine int, filePath string) (protocol.Range, error) {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return protocol.Range{}, fmt.Errorf("failed to read file: %w", err)
+	startColumn := edit.StartColumn
+	if startColumn == 0 {
+		startColumn = 1
+	}
+	endColumn := edit.EndColumn
+	if endColumn == 0 {
+		endColumn = endLength + 1
+	}
+
+	// Columns may point one past the last character to address the end of the line
+	if startColumn < 1 || startColumn > startLength+1 {
+		return protocol.Range{}, fmt.Errorf("start column %d is outside line %d (1-%d)", startColumn, edit.StartLine, startLength+1)
+	}
+	if endColumn < 1 || endColumn > endLength+1 {
+		return protocol.Range{}, fmt.Errorf("end column %d is outside line %d (1-%d)", endColumn, edit.EndLine, endLength+1)
 	}
+	if edit.StartLine == edit.EndLine && endColumn < startColumn {
+		return protocol.Range{}, fmt.Errorf("end column %d is before start column %d", endColumn, startColumn)
+	}
+
+	return protocol.Range{
+		Start: protocol.Position{
+			Line:      uint32(edit.StartLine - 1),
+			Character: utilities.ColumnToCharacter(startText, startColumn, encoding),
+		},
+		End: protocol.Position{
+			Line:      uint32(edit.EndLine - 1),
+			Character: utilities.ColumnToCharacter(endText, endColumn, encoding),
+		},
+	}, nil
+}
 
+// getRange creates a protocol.Range that covers the specified start and end lines
+func getRange(startLine, endLine int, content []byte, encoding protocol.PositionEncodingKind) (protocol.Range, error) {
 	// Detect line ending style
 	var lineEnding string
 	if bytes.Contains(content, []byte("\r\n")) {
@@ -126,7 +278,7 @@ func getRange(startLine, endLine int, filePath string) (protocol.Range, error) {
 
 		pos := protocol.Position{
 			Line:      uint32(lastContentLineIdx),
-			Character: uint32(len(lines[lastContentLineIdx])),
+			Character: utilities.CharacterLength(lines[lastContentLineIdx], encoding),
 		}
 
 		return protocol.Range{
@@ -148,7 +300,7 @@ func getRange(startLine, endLine int, filePath string) (protocol.Range, error) {
 		},
 		End: protocol.Position{
 			Line:      uint32(endIdx),
-			Character: uint32(len(lines[endIdx])), // Go to end of last line
+			Character: utilities.CharacterLength(lines[endIdx], encoding), // Go to end of last line
 		},
 	}, nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
@@ -21,7 +22,7 @@ func ExecuteCodeLens(ctx context.Context, client *lsp.Client, filePath string, i
 
 	// Get code lenses
 	docIdentifier := protocol.TextDocumentIdentifier{
-		URI: protocol.DocumentUri("file://" + filePath),
+		URI: fileuri.FromPath(filePath),
 	}
 
 	params := protocol.CodeLensParams{
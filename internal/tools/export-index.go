@@ -0,0 +1,272 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/codeindex"
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// ExportOptions selects the files ExportIndex indexes
+type ExportOptions struct {
+	// Include limits the files to those matching a glob relative to the workspace
+	Include string
+	// Progress is told about each file before it is indexed, when set
+	Progress func(done, total int, path string)
+}
+
+// exportedSymbol is a symbol defined in a file, with the range of its name
+type exportedSymbol struct {
+	id        string
+	selection protocol.Range
+}
+
+// occurrenceKey tells apart the occurrences of an export, which references
+// requests for different symbols may report more than once
+type occurrenceKey struct {
+	path   string
+	rng    protocol.Range
+	symbol string
+}
+
+// exporter gathers an export, keeping the lines of the files it converts positions in
+type exporter struct {
+	client    *lsp.Client
+	root      string
+	export    *codeindex.Export
+	documents map[string]*codeindex.ExportDocument
+	seen      map[occurrenceKey]bool
+	ids       map[string]bool
+	lines     map[string][]string
+}
+
+// ExportIndex gathers the code intelligence of the code files of the primary
+// workspace from the language server: each file's document symbols are their
+// definitions, and the server's references and hover for each one complete them.
+// References outside the workspace are left out.
+func ExportIndex(ctx context.Context, client *lsp.Client, opts ExportOptions) (*codeindex.Export, error) {
+	roots := client.WorkspaceRoots()
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no workspace folders")
+	}
+	root := roots[0]
+	if !client.SupportsMethod("textDocument/documentSymbol") {
+		return nil, fmt.Errorf("the language server does not support document symbols")
+	}
+
+	var files []string
+	err := watcher.WalkWorkspaceFiles(root, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if nonCodeLanguages[lsp.DetectLanguageID(path)] {
+			return nil
+		}
+		if opts.Include != "" {
+			rel, _ := filepath.Rel(root, path)
+			if !utilities.MatchGlob(opts.Include, filepath.ToSlash(rel)) {
+				return nil
+			}
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %v", root, err)
+	}
+
+	e := &exporter{
+		client: client,
+		root:   root,
+		export: &codeindex.Export{
+			Root:     root,
+			ToolName: "mcp-language-server",
+			Symbols:  make(map[string]string),
+		},
+		documents: make(map[string]*codeindex.ExportDocument),
+		seen:      make(map[occurrenceKey]bool),
+		ids:       make(map[string]bool),
+		lines:     make(map[string][]string),
+	}
+	for i, path := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if opts.Progress != nil {
+			opts.Progress(i, len(files), path)
+		}
+		e.exportFile(ctx, path)
+	}
+
+	for _, doc := range e.documents {
+		sort.SliceStable(doc.Occurrences, func(i, j int) bool {
+			a, b := doc.Occurrences[i].Range.Start, doc.Occurrences[j].Range.Start
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Character < b.Character
+		})
+		e.export.Documents = append(e.export.Documents, doc)
+	}
+	sort.Slice(e.export.Documents, func(i, j int) bool {
+		return e.export.Documents[i].Path < e.export.Documents[j].Path
+	})
+	return e.export, nil
+}
+
+// exportFile adds the symbols defined in a file, with their references and hovers
+func (e *exporter) exportFile(ctx context.Context, path string) {
+	wasOpen := e.client.IsFileOpen(path)
+	uri := fileuri.FromPath(path)
+	doc, err := loadDocumentSymbols(ctx, e.client, uri)
+	if err != nil || doc == nil {
+		toolsLogger.Warn("Not indexing %s: %v", path, err)
+		return
+	}
+	defer func() {
+		if !wasOpen {
+			if err := e.client.CloseFile(ctx, path); err != nil {
+				toolsLogger.Debug("Error closing %s: %v", path, err)
+			}
+		}
+	}()
+
+	rel, _ := filepath.Rel(e.root, path)
+	rel = filepath.ToSlash(rel)
+	hover := e.client.SupportsMethod("textDocument/hover")
+	references := e.client.SupportsMethod("textDocument/references")
+	for _, symbol := range e.fileSymbols(rel, doc) {
+		if err := ctx.Err(); err != nil {
+			return
+		}
+		e.add(path, symbol.selection, symbol.id, true)
+		position := protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     symbol.selection.Start,
+		}
+
+		if hover {
+			result, err := e.client.Hover(ctx, protocol.HoverParams{TextDocumentPositionParams: position})
+			if err == nil && result.Contents.Value != "" {
+				e.export.Symbols[symbol.id] = result.Contents.Value
+			}
+		}
+		if references {
+			locations, err := e.client.References(ctx, protocol.ReferenceParams{TextDocumentPositionParams: position})
+			if err != nil {
+				toolsLogger.Debug("No references to %s: %v", symbol.id, err)
+				continue
+			}
+			for _, loc := range locations {
+				if loc.URI.Path() == path && loc.Range == symbol.selection {
+					continue
+				}
+				e.add(loc.URI.Path(), loc.Range, symbol.id, false)
+			}
+		}
+	}
+}
+
+// fileSymbols returns the symbols defined in a file with unique IDs
+func (e *exporter) fileSymbols(rel string, doc *documentSymbols) []exportedSymbol {
+	var symbols []exportedSymbol
+	var walk func(symbol protocol.DocumentSymbol, descriptors []string)
+	walk = func(symbol protocol.DocumentSymbol, descriptors []string) {
+		descriptors = append(descriptors[:len(descriptors):len(descriptors)], e.descriptor(rel, descriptors, symbol.Name, symbol.Kind))
+		symbols = append(symbols, exportedSymbol{codeindex.SymbolID(rel, descriptors...), symbol.SelectionRange})
+		for _, child := range symbol.Children {
+			walk(child, descriptors)
+		}
+	}
+
+	for _, result := range doc.symbols {
+		switch symbol := result.(type) {
+		case *protocol.DocumentSymbol:
+			walk(*symbol, nil)
+		case *protocol.SymbolInformation:
+			var descriptors []string
+			if symbol.ContainerName != "" {
+				descriptors = append(descriptors, codeindex.Descriptor(symbol.ContainerName, protocol.Class, ""))
+			}
+			descriptors = append(descriptors, e.descriptor(rel, descriptors, symbol.Name, symbol.Kind))
+			symbols = append(symbols, exportedSymbol{codeindex.SymbolID(rel, descriptors...), nameRange(doc.lines, symbol.Location.Range, symbol.Name, e.client.PositionEncoding())})
+		}
+	}
+	return symbols
+}
+
+// descriptor returns the descriptor of a symbol, disambiguated when a symbol of the
+// same file already has its ID, such as an overload
+func (e *exporter) descriptor(rel string, parents []string, name string, kind protocol.SymbolKind) string {
+	descriptor := codeindex.Descriptor(name, kind, "")
+	for n := 1; e.ids[codeindex.SymbolID(rel, append(parents[:len(parents):len(parents)], descriptor)...)]; n++ {
+		descriptor = codeindex.Descriptor(name, kind, fmt.Sprintf("+%d", n))
+	}
+	e.ids[codeindex.SymbolID(rel, append(parents[:len(parents):len(parents)], descriptor)...)] = true
+	return descriptor
+}
+
+// nameRange returns the range of a symbol's name on the first line of its range,
+// or the start of the range when the name is not there
+func nameRange(lines []string, rng protocol.Range, name string, encoding protocol.PositionEncodingKind) protocol.Range {
+	if int(rng.Start.Line) < len(lines) {
+		line := lines[rng.Start.Line]
+		start := utilities.ByteOffset(line, rng.Start.Character, encoding)
+		if i := strings.Index(line[start:], name); i >= 0 {
+			return protocol.Range{
+				Start: protocol.Position{Line: rng.Start.Line, Character: utilities.CharacterOf(line, start+i, encoding)},
+				End:   protocol.Position{Line: rng.Start.Line, Character: utilities.CharacterOf(line, start+i+len(name), encoding)},
+			}
+		}
+	}
+	return protocol.Range{Start: rng.Start, End: rng.Start}
+}
+
+// add records an occurrence of a symbol in a file of the workspace, converting its
+// range to UTF-16
+func (e *exporter) add(path string, rng protocol.Range, symbol string, definition bool) {
+	rel, err := filepath.Rel(e.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+
+	rng.Start.Character = utilities.ConvertCharacter(e.line(path, rng.Start.Line), rng.Start.Character, e.client.PositionEncoding(), protocol.UTF16)
+	rng.End.Character = utilities.ConvertCharacter(e.line(path, rng.End.Line), rng.End.Character, e.client.PositionEncoding(), protocol.UTF16)
+	key := occurrenceKey{rel, rng, symbol}
+	if e.seen[key] {
+		return
+	}
+	e.seen[key] = true
+
+	doc, ok := e.documents[rel]
+	if !ok {
+		doc = &codeindex.ExportDocument{Path: rel, Language: string(lsp.DetectLanguageID(path))}
+		e.documents[rel] = doc
+	}
+	doc.Occurrences = append(doc.Occurrences, codeindex.ExportOccurrence{Range: rng, Symbol: symbol, Definition: definition})
+}
+
+// line returns a line of a file, or "" when it cannot be read
+func (e *exporter) line(path string, line uint32) string {
+	lines, ok := e.lines[path]
+	if !ok {
+		if content, err := os.ReadFile(path); err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		e.lines[path] = lines
+	}
+	if int(line) >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line], "\r")
+}
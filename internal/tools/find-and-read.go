@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// FindAndRead searches the workspace for a symbol and returns its definition, hover
+// information and the diagnostics within it in a single call. When several symbols
+// match equally well they are listed instead, and choice (1-indexed) selects one of
+// them on a later call.
+func FindAndRead(ctx context.Context, client *lsp.Client, query string, choice int) (string, error) {
+	symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{
+		Query: query,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch symbol: %v", err)
+	}
+
+	results, err := symbolResult.Results()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse results: %v", err)
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("No symbols found matching: %s", query), nil
+	}
+
+	// Keep the candidates that match best
+	bestScore := -1
+	var candidates []protocol.WorkspaceSymbolResult
+	for _, symbol := range results {
+		score := symbolMatchScore(symbol, query)
+		if score > bestScore {
+			bestScore = score
+			candidates = candidates[:0]
+		}
+		if score == bestScore {
+			candidates = append(candidates, symbol)
+		}
+	}
+
+	lines := newDocumentLines(client)
+	var symbol protocol.WorkspaceSymbolResult
+	switch {
+	case choice > 0 && choice <= len(candidates):
+		symbol = candidates[choice-1]
+	case choice > len(candidates):
+		return "", fmt.Errorf("choice %d is out of range, there are %d candidates", choice, len(candidates))
+	case len(candidates) == 1:
+		symbol = candidates[0]
+	default:
+		var output strings.Builder
+		output.WriteString(fmt.Sprintf("Found %d symbols matching %q. Call again with a choice to read one of them:\n\n", len(candidates), query))
+		for i, candidate := range candidates {
+			output.WriteString(fmt.Sprintf("%d. %s\n", i+1, formatWorkspaceSymbol(lines, candidate)))
+		}
+		return output.String(), nil
+	}
+
+	loc := symbol.GetLocation()
+	filePath := fileuri.ToPath(loc.URI)
+
+	// Diagnostics for a file that was not open yet arrive after it is opened
+	wasOpen := client.IsFileOpen(filePath)
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	definition, fullLoc, err := GetFullDefinition(ctx, client, loc)
+	if err != nil {
+		return "", fmt.Errorf("failed to get definition: %v", err)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Symbol: %s\n", formatWorkspaceSymbol(lines, symbol)))
+	output.WriteString(fmt.Sprintf("File: %s\nRange: L%d:C%d - L%d:C%d\n",
+		filePath,
+		fullLoc.Range.Start.Line+1,
+		lines.column(fullLoc.URI, fullLoc.Range.Start),
+		fullLoc.Range.End.Line+1,
+		lines.column(fullLoc.URI, fullLoc.Range.End),
+	))
+
+	output.WriteString("\n---\n\nDefinition:\n\n")
+	output.WriteString(addLineNumbers(definition, int(fullLoc.Range.Start.Line)+1))
+
+	line, column := symbolNamePosition(loc, symbol.GetName(), client.PositionEncoding())
+	hover, err := GetHoverInfo(ctx, client, filePath, line, column)
+	if err != nil {
+		toolsLogger.Error("Error getting hover for %s: %v", symbol.GetName(), err)
+	} else {
+		output.WriteString("\n---\n\nHover:\n\n")
+		output.WriteString(hover)
+		output.WriteString("\n")
+	}
+
+	output.WriteString("\n---\n\nDiagnostics:\n\n")
+	diagnostics := definitionDiagnostics(ctx, client, loc.URI, fullLoc.Range, !wasOpen)
+	if len(diagnostics) == 0 {
+		output.WriteString("No diagnostics in this definition\n")
+	}
+	for _, diag := range diagnostics {
+		output.WriteString(fmt.Sprintf("%s at L%d:C%d: %s\n",
+			getSeverityString(diag.Severity),
+			diag.Range.Start.Line+1,
+			lines.column(loc.URI, diag.Range.Start),
+			diag.Message))
+	}
+
+	return output.String(), nil
+}
+
+// symbolMatchScore ranks how well a workspace symbol matches a query
+func symbolMatchScore(symbol protocol.WorkspaceSymbolResult, query string) int {
+	switch {
+	case symbol.GetName() == query:
+		return 3
+	case symbolNameMatches(symbol, query):
+		return 2
+	case strings.EqualFold(symbol.GetName(), query):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// definitionDiagnostics returns the diagnostics of a file that fall within a range.
+// When the file was only just opened, it first waits for the server to publish them.
+func definitionDiagnostics(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, rng protocol.Range, justOpened bool) []protocol.Diagnostic {
+	if client.SupportsPullDiagnostics() {
+		if err := client.PullDiagnostics(ctx, uri); err != nil {
+			toolsLogger.Error("Failed to get diagnostics: %v", err)
+		}
+	} else if justOpened {
+		if err := client.WaitForIdle(ctx, defaultDiagnosticsDebounce, defaultDiagnosticsTimeout); err != nil {
+			toolsLogger.Warn("Returning diagnostics before the server is idle: %v", err)
+		}
+	}
+
+	var diagnostics []protocol.Diagnostic
+	for _, diag := range client.GetFileDiagnostics(uri) {
+		if diag.Range.Start.Line >= rng.Start.Line && diag.Range.Start.Line <= rng.End.Line {
+			diagnostics = append(diagnostics, diag)
+		}
+	}
+	return diagnostics
+}
@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// maxFixRounds bounds how many fixes are applied to a single file
+const maxFixRounds = 100
+
+// appliedFix is a quick fix applied for a diagnostic
+type appliedFix struct {
+	title      string
+	diagnostic protocol.Diagnostic
+}
+
+// FixDiagnostics applies quick fixes for the diagnostics of a file, or of every
+// workspace file with diagnostics when filePath is empty. Only fixes that are
+// plain edits are applied, never ones that run a server command, and with
+// preferredOnly only those the server marks as preferred. Diagnostics are fetched
+// again after each fix, since a fix moves or resolves the others.
+func FixDiagnostics(ctx context.Context, client *lsp.Client, filePath string, preferredOnly bool) (string, error) {
+	var files []string
+	if filePath != "" {
+		files = []string{filePath}
+	} else {
+		if err := client.WaitForIdle(ctx, defaultDiagnosticsDebounce, defaultDiagnosticsTimeout); err != nil {
+			toolsLogger.Warn("Fixing diagnostics before the server is idle: %v", err)
+		}
+		for uri, diags := range client.GetAllDiagnostics() {
+			path := uri.Path()
+			if len(diags) > 0 && inWorkspace(client, path) {
+				files = append(files, path)
+			}
+		}
+		sort.Strings(files)
+	}
+
+	// All fixes are recorded as a single edit, so they can be undone together
+	entry := utilities.EditJournal.Begin("fix_diagnostics")
+	defer entry.Commit()
+
+	var output strings.Builder
+	var fixedCount, fixedFiles int
+	var remaining []string
+	for _, file := range files {
+		fixes, left, err := fixFileDiagnostics(ctx, client, entry, file, preferredOnly)
+		if err != nil {
+			return "", err
+		}
+
+		if len(fixes) > 0 {
+			fixedCount += len(fixes)
+			fixedFiles++
+			output.WriteString(file + "\n")
+			for _, fix := range fixes {
+				output.WriteString(fmt.Sprintf("  L%d: %s (fixes %s: %s)\n",
+					fix.diagnostic.Range.Start.Line+1, fix.title,
+					getSeverityString(fix.diagnostic.Severity), fix.diagnostic.Message))
+			}
+		}
+		for _, diag := range left {
+			remaining = append(remaining, fmt.Sprintf("%s L%d: %s: %s",
+				file, diag.Range.Start.Line+1, getSeverityString(diag.Severity), diag.Message))
+		}
+	}
+
+	var result strings.Builder
+	if fixedCount == 0 {
+		result.WriteString("No quick fixes applied.\n")
+	} else {
+		result.WriteString(fmt.Sprintf("Applied %d quick fixes in %d files:\n", fixedCount, fixedFiles))
+		result.WriteString(output.String())
+	}
+	if len(remaining) > 0 {
+		result.WriteString(fmt.Sprintf("\n%d diagnostics remain:\n", len(remaining)))
+		for _, line := range remaining {
+			result.WriteString(line + "\n")
+		}
+	}
+
+	return result.String(), nil
+}
+
+// fixFileDiagnostics applies quick fixes to one file until none are left to apply,
+// returning the fixes and the diagnostics that remain
+func fixFileDiagnostics(ctx context.Context, client *lsp.Client, entry *utilities.JournalEntry, filePath string, preferredOnly bool) ([]appliedFix, []protocol.Diagnostic, error) {
+	uri := fileuri.FromPath(filePath)
+
+	// Diagnostics are told apart by message and code, since fixes move the others.
+	// Each is fixed at most as often as it was first reported, so a fix that does
+	// not resolve its diagnostic is not applied over and over.
+	key := func(diag protocol.Diagnostic) string {
+		return fmt.Sprintf("%v:%s", diag.Code, diag.Message)
+	}
+	budget := make(map[string]int)
+	unfixable := make(map[string]bool)
+
+	var fixes []appliedFix
+	var diagnostics []protocol.Diagnostic
+	for round := 0; round < maxFixRounds; round++ {
+		var err error
+		diagnostics, err = refreshFileDiagnostics(ctx, client, filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if round == 0 {
+			for _, diag := range diagnostics {
+				budget[key(diag)]++
+			}
+		}
+
+		applied := false
+		for _, diag := range diagnostics {
+			k := key(diag)
+			if unfixable[k] || budget[k] == 0 {
+				continue
+			}
+
+			action, err := quickFixFor(ctx, client, uri, diag, preferredOnly)
+			if err != nil {
+				toolsLogger.Error("Error getting quick fixes for %s: %v", filePath, err)
+			}
+			if action == nil {
+				unfixable[k] = true
+				continue
+			}
+
+			entry.CaptureEdit(*action.Edit)
+			if err := client.ApplyWorkspaceEdit(*action.Edit); err != nil {
+				return nil, nil, fmt.Errorf("failed to apply %q: %v", action.Title, err)
+			}
+			syncEditedFiles(ctx, client, *action.Edit)
+
+			budget[k]--
+			fixes = append(fixes, appliedFix{title: action.Title, diagnostic: diag})
+			applied = true
+			break
+		}
+
+		if !applied {
+			break
+		}
+	}
+
+	return fixes, diagnostics, nil
+}
+
+// quickFixFor returns a quick fix for a diagnostic that only edits files, or nil
+// when the server offers none
+func quickFixFor(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, diag protocol.Diagnostic, preferredOnly bool) (*protocol.CodeAction, error) {
+	triggerKind := protocol.CodeActionInvoked
+	items, err := client.CodeAction(ctx, protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        diag.Range,
+		Context: protocol.CodeActionContext{
+			Diagnostics: []protocol.Diagnostic{diag},
+			Only:        []protocol.CodeActionKind{protocol.QuickFix},
+			TriggerKind: &triggerKind,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		action, ok := item.Value.(protocol.CodeAction)
+		if !ok || action.Disabled != nil || action.Command != nil {
+			continue
+		}
+		if action.Kind != "" && !strings.HasPrefix(string(action.Kind), string(protocol.QuickFix)) {
+			continue
+		}
+		if preferredOnly && !action.IsPreferred {
+			continue
+		}
+
+		// Resolve the code action if the server deferred computing its edit
+		if action.Edit == nil && action.Data != nil {
+			resolved, err := client.ResolveCodeAction(ctx, action)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve code action: %v", err)
+			}
+			action = resolved
+		}
+		if action.Edit == nil || action.Command != nil {
+			continue
+		}
+		return &action, nil
+	}
+	return nil, nil
+}
+
+// inWorkspace reports whether path is inside one of the workspace folders
+func inWorkspace(client *lsp.Client, path string) bool {
+	for _, root := range client.WorkspaceRoots() {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// GetFoldingRanges lists the structural blocks of a file (imports, functions, comments,
+// regions) as an indented outline of line ranges, so that only the relevant sections
+// of a large file need to be read
+func GetFoldingRanges(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	ranges, err := client.FoldingRange(ctx, protocol.FoldingRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: fileuri.FromPath(filePath),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get folding ranges: %v", err)
+	}
+
+	if len(ranges) == 0 {
+		return fmt.Sprintf("No folding ranges found in %s", filePath), nil
+	}
+
+	content, err := client.ReadDocument(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	lines := strings.Split(string(content), "\n")
+
+	// Outer ranges first, so that nested ranges follow their parents
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].StartLine != ranges[j].StartLine {
+			return ranges[i].StartLine < ranges[j].StartLine
+		}
+		return ranges[i].EndLine > ranges[j].EndLine
+	})
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Folding ranges for %s (%d lines):\n\n", filePath, len(lines)))
+
+	// Ranges that enclose the current one
+	var parents []protocol.FoldingRange
+	for _, rng := range ranges {
+		for len(parents) > 0 && parents[len(parents)-1].EndLine < rng.StartLine {
+			parents = parents[:len(parents)-1]
+		}
+
+		output.WriteString(strings.Repeat("  ", len(parents)))
+		output.WriteString(fmt.Sprintf("L%d-L%d", rng.StartLine+1, rng.EndLine+1))
+		if rng.Kind != "" {
+			output.WriteString(fmt.Sprintf(" [%s]", rng.Kind))
+		}
+		if rng.CollapsedText != "" {
+			output.WriteString(fmt.Sprintf(" %s", rng.CollapsedText))
+		} else if int(rng.StartLine) < len(lines) {
+			output.WriteString(fmt.Sprintf(" %s", strings.TrimSpace(lines[rng.StartLine])))
+		}
+		output.WriteString("\n")
+
+		parents = append(parents, rng)
+	}
+
+	return output.String(), nil
+}
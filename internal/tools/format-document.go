@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// FormatDocument formats a file, or the given 1-indexed inclusive line range of it, using
+// the language server's formatter. The edits are written to disk and a unified diff of the
+// changes is returned. With dryRun only the diff is returned.
+func FormatDocument(ctx context.Context, client *lsp.Client, filePath string, startLine, endLine int, tabSize int, insertSpaces bool, dryRun bool) (string, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+	if err := checkNotStaged(client, filePath); err != nil {
+		return "", err
+	}
+
+	uri := fileuri.FromPath(filePath)
+	options := protocol.FormattingOptions{
+		TabSize:      uint32(tabSize),
+		InsertSpaces: insertSpaces,
+	}
+
+	var edits []protocol.TextEdit
+	if startLine > 0 {
+		if endLine < startLine {
+			endLine = startLine
+		}
+		content, err := client.ReadDocument(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %v", err)
+		}
+		rng, err := getRange(startLine, endLine, content, client.PositionEncoding())
+		if err != nil {
+			return "", fmt.Errorf("invalid range: %v", err)
+		}
+		edits, err = client.RangeFormatting(ctx, protocol.DocumentRangeFormattingParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Range:        rng,
+			Options:      options,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to format range: %v", err)
+		}
+	} else {
+		edits, err = client.Formatting(ctx, protocol.DocumentFormattingParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Options:      options,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to format document: %v", err)
+		}
+	}
+
+	if len(edits) == 0 {
+		return fmt.Sprintf("%s is already formatted.", filePath), nil
+	}
+
+	if dryRun {
+		return previewEdit(client, fmt.Sprintf("Formatting %s would make %d edits.", filePath, len(edits)),
+			protocol.WorkspaceEdit{Changes: map[protocol.DocumentUri][]protocol.TextEdit{uri: edits}})
+	}
+
+	before, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+
+	entry := utilities.EditJournal.Begin(fmt.Sprintf("format_document %s", filePath))
+	entry.Capture(filePath)
+	if err := utilities.ApplyTextEdits(uri, edits, client.PositionEncoding()); err != nil {
+		return "", fmt.Errorf("failed to apply formatting edits: %v", err)
+	}
+
+	// Let the server know about the new content so later requests see it
+	if err := client.NotifyChange(ctx, filePath); err != nil {
+		toolsLogger.Error("Error notifying change: %v", err)
+	}
+	saveEditedFile(ctx, client, filePath)
+	entry.Commit()
+
+	after, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read formatted file: %v", err)
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: filePath,
+		ToFile:   filePath,
+		Context:  3,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate diff: %v", err)
+	}
+
+	if diff == "" {
+		return fmt.Sprintf("%s is already formatted.", filePath), nil
+	}
+
+	return fmt.Sprintf("Formatted %s with %d edits:\n\n%s", filePath, len(edits), diff), nil
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
@@ -21,7 +22,7 @@ func GetCodeLens(ctx context.Context, client *lsp.Client, filePath string) (stri
 
 	// Create document identifier
 	docIdentifier := protocol.TextDocumentIdentifier{
-		URI: protocol.DocumentUri("file://" + filePath),
+		URI: fileuri.FromPath(filePath),
 	}
 
 	// Request code lens from LSP
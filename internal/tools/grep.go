@@ -0,0 +1,288 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+const (
+	// defaultGrepLimit bounds how many matching lines are returned
+	defaultGrepLimit = 100
+
+	// maxGrepFileSize is the largest file searched, larger ones are usually
+	// generated or data
+	maxGrepFileSize = 2 * 1024 * 1024
+
+	// maxGrepLineLength is how much of a matching line is shown
+	maxGrepLineLength = 300
+
+	// maxGrepSymbolFiles bounds how many files with matches are opened to find the
+	// symbols enclosing them
+	maxGrepSymbolFiles = 50
+)
+
+var errGrepLimit = errors.New("match limit reached")
+
+// GrepOptions select what CollectGrep searches and returns
+type GrepOptions struct {
+	// Include is a glob the slash separated path of a file relative to its
+	// workspace folder must match, such as "**/*.go"
+	Include string
+	// IgnoreCase matches the pattern without regard to case
+	IgnoreCase bool
+	// Symbols finds the symbol enclosing each match with documentSymbol
+	Symbols bool
+	// Limit bounds how many matching lines are returned
+	Limit int
+}
+
+// GrepMatch is a line matching the pattern, with the innermost symbol enclosing
+// the first match on it when symbols were requested
+type GrepMatch struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Text   string `json:"text"`
+	Symbol string `json:"symbol,omitempty"`
+	Kind   string `json:"kind,omitempty"`
+	// SymbolStartLine and SymbolEndLine are the lines of the enclosing symbol
+	SymbolStartLine int `json:"symbolStartLine,omitempty"`
+	SymbolEndLine   int `json:"symbolEndLine,omitempty"`
+
+	position protocol.Position
+}
+
+// GrepResult is the lines of the workspace matching a pattern
+type GrepResult struct {
+	Pattern   string      `json:"pattern"`
+	Matches   []GrepMatch `json:"matches"`
+	Files     int         `json:"files"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// CollectGrep searches the files of every workspace folder for a regular
+// expression, skipping the files the file watcher ignores, such as those excluded
+// by .gitignore, as well as binary and very large files. With Symbols, the files
+// with matches are opened and each match is given the innermost document symbol
+// enclosing it, so that text hits can be mapped to functions and types.
+func CollectGrep(ctx context.Context, client *lsp.Client, pattern string, opts GrepOptions) (GrepResult, error) {
+	result := GrepResult{Pattern: pattern, Matches: []GrepMatch{}}
+	if pattern == "" {
+		return result, fmt.Errorf("pattern is required")
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return result, fmt.Errorf("invalid pattern: %v", err)
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultGrepLimit
+	}
+
+	roots := client.WorkspaceRoots()
+	if len(roots) == 0 {
+		return result, fmt.Errorf("no workspace folders")
+	}
+
+	var files []string
+	for _, root := range roots {
+		err := watcher.WalkWorkspaceFiles(root, func(path string) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if opts.Include != "" {
+				rel, _ := filepath.Rel(root, path)
+				if !utilities.MatchGlob(opts.Include, filepath.ToSlash(rel)) {
+					return nil
+				}
+			}
+
+			matches := grepFile(client, path, re)
+			if len(matches) == 0 {
+				return nil
+			}
+			files = append(files, path)
+			for _, match := range matches {
+				if len(result.Matches) >= opts.Limit {
+					result.Truncated = true
+					return errGrepLimit
+				}
+				result.Matches = append(result.Matches, match)
+			}
+			return nil
+		})
+		if errors.Is(err, errGrepLimit) {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to search %s: %v", root, err)
+		}
+	}
+	result.Files = len(files)
+
+	if opts.Symbols && client.SupportsMethod("textDocument/documentSymbol") {
+		addEnclosingSymbols(ctx, client, result.Matches, files)
+	}
+	return result, nil
+}
+
+// grepFile returns the lines of a file matching re. Files that cannot be read or
+// hold binary data have no matches.
+func grepFile(client *lsp.Client, path string, re *regexp.Regexp) []GrepMatch {
+	if info, err := os.Stat(path); err != nil || info.Size() > maxGrepFileSize {
+		return nil
+	}
+	content, err := client.ReadDocument(path)
+	if err != nil || bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		return nil
+	}
+
+	var matches []GrepMatch
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		text := line
+		if utf8.RuneCountInString(text) > maxGrepLineLength {
+			text = string([]rune(text)[:maxGrepLineLength]) + "..."
+		}
+		matches = append(matches, GrepMatch{
+			Path:     path,
+			Line:     i + 1,
+			Column:   utf8.RuneCountInString(line[:loc[0]]) + 1,
+			Text:     text,
+			position: protocol.Position{Line: uint32(i), Character: utilities.CharacterOf(line, loc[0], client.PositionEncoding())},
+		})
+	}
+	return matches
+}
+
+// addEnclosingSymbols sets the symbol enclosing each match, fetching the document
+// symbols of the first files with matches
+func addEnclosingSymbols(ctx context.Context, client *lsp.Client, matches []GrepMatch, files []string) {
+	symbols := make(map[string][]protocol.DocumentSymbolResult)
+	for _, path := range files[:min(len(files), maxGrepSymbolFiles)] {
+		doc, err := loadDocumentSymbols(ctx, client, fileuri.FromPath(path))
+		if err != nil {
+			// Files in languages the server does not handle have no symbols
+			toolsLogger.Debug("No document symbols for %s: %v", path, err)
+			continue
+		}
+		if doc != nil {
+			symbols[path] = doc.symbols
+		}
+	}
+
+	for i := range matches {
+		match, ok := enclosingSymbol(symbols[matches[i].Path], matches[i].position)
+		if !ok {
+			continue
+		}
+		matches[i].Symbol = match.name
+		matches[i].Kind = protocol.TableKindMap[match.kind]
+		matches[i].SymbolStartLine = int(match.rng.Start.Line) + 1
+		matches[i].SymbolEndLine = int(match.rng.End.Line) + 1
+	}
+}
+
+// enclosingSymbol returns the innermost symbol whose range contains pos, named
+// with the symbols containing it. Flat results are not nested, so the smallest
+// range containing pos is taken.
+func enclosingSymbol(symbols []protocol.DocumentSymbolResult, pos protocol.Position) (documentSymbolMatch, bool) {
+	var best documentSymbolMatch
+	found := false
+
+	var walk func(symbols []protocol.DocumentSymbolResult, parents []string)
+	walk = func(symbols []protocol.DocumentSymbolResult, parents []string) {
+		for _, sym := range symbols {
+			rng := sym.GetRange()
+			if !containsPosition(rng, pos) {
+				continue
+			}
+
+			var kind protocol.SymbolKind
+			path := parents
+			var children []protocol.DocumentSymbolResult
+			switch v := sym.(type) {
+			case *protocol.DocumentSymbol:
+				kind = v.Kind
+				for i := range v.Children {
+					children = append(children, &v.Children[i])
+				}
+			case *protocol.SymbolInformation:
+				kind = v.Kind
+				if v.ContainerName != "" {
+					path = splitSymbolPath(v.ContainerName)
+				}
+			}
+
+			// Children are within their parent, so they replace it
+			if !found || rangeLines(rng) <= rangeLines(best.rng) {
+				best = documentSymbolMatch{
+					name:      strings.Join(append(path[:len(path):len(path)], sym.GetName()), "."),
+					kind:      kind,
+					rng:       rng,
+					selection: rng,
+				}
+				found = true
+			}
+			walk(children, append(path[:len(path):len(path)], splitSymbolPath(sym.GetName())...))
+		}
+	}
+	walk(symbols, nil)
+
+	return best, found
+}
+
+// rangeLines returns how many lines a range spans, to compare symbol sizes
+func rangeLines(r protocol.Range) uint32 {
+	return r.End.Line - r.Start.Line
+}
+
+// Grep formats the matches like ripgrep, grouped under the path of each file, with
+// the enclosing symbol shown above the matches within it
+func Grep(result GrepResult) string {
+	if len(result.Matches) == 0 {
+		return fmt.Sprintf("No matches found for: %s", result.Pattern)
+	}
+
+	var output strings.Builder
+	if result.Truncated {
+		fmt.Fprintf(&output, "Showing the first %d matches for %q in %d files, narrow the pattern or the files to see more\n", len(result.Matches), result.Pattern, result.Files)
+	} else {
+		fmt.Fprintf(&output, "Found %d matches for %q in %d files\n", len(result.Matches), result.Pattern, result.Files)
+	}
+
+	var path, symbol string
+	for _, match := range result.Matches {
+		if match.Path != path {
+			path, symbol = match.Path, ""
+			fmt.Fprintf(&output, "\n%s\n", match.Path)
+		}
+		if match.Symbol != symbol {
+			symbol = match.Symbol
+			if symbol != "" {
+				fmt.Fprintf(&output, "  %s %s (L%d-L%d)\n", match.Kind, match.Symbol, match.SymbolStartLine, match.SymbolEndLine)
+			}
+		}
+		fmt.Fprintf(&output, "%d:%d:%s\n", match.Line, match.Column, match.Text)
+	}
+	return output.String()
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnclosingSymbol(t *testing.T) {
+	nested := []protocol.DocumentSymbolResult{
+		&protocol.DocumentSymbol{
+			Name: "Client", Kind: protocol.Class,
+			Range: protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 20, Character: 1}},
+			Children: []protocol.DocumentSymbol{
+				{
+					Name: "OpenFile", Kind: protocol.Method,
+					Range: protocol.Range{Start: protocol.Position{Line: 4}, End: protocol.Position{Line: 8, Character: 2}},
+				},
+			},
+		},
+		&protocol.DocumentSymbol{
+			Name: "main", Kind: protocol.Function,
+			Range: protocol.Range{Start: protocol.Position{Line: 22}, End: protocol.Position{Line: 25, Character: 1}},
+		},
+	}
+
+	match, ok := enclosingSymbol(nested, protocol.Position{Line: 6, Character: 4})
+	require.True(t, ok)
+	assert.Equal(t, "Client.OpenFile", match.name)
+	assert.Equal(t, protocol.Method, match.kind)
+
+	match, ok = enclosingSymbol(nested, protocol.Position{Line: 12})
+	require.True(t, ok)
+	assert.Equal(t, "Client", match.name)
+
+	_, ok = enclosingSymbol(nested, protocol.Position{Line: 21})
+	assert.False(t, ok, "between symbols")
+
+	flat := []protocol.DocumentSymbolResult{
+		&protocol.SymbolInformation{
+			Name: "Widget", Kind: protocol.Class,
+			Location: protocol.Location{Range: protocol.Range{Start: protocol.Position{Line: 0}, End: protocol.Position{Line: 30}}},
+		},
+		&protocol.SymbolInformation{
+			Name: "draw", Kind: protocol.Method, ContainerName: "Widget",
+			Location: protocol.Location{Range: protocol.Range{Start: protocol.Position{Line: 10}, End: protocol.Position{Line: 15}}},
+		},
+	}
+	match, ok = enclosingSymbol(flat, protocol.Position{Line: 12})
+	require.True(t, ok)
+	assert.Equal(t, "Widget.draw", match.name, "the smallest flat symbol wins")
+}
+
+func TestGrep(t *testing.T) {
+	result := GrepResult{
+		Pattern: "OpenFile",
+		Files:   2,
+		Matches: []GrepMatch{
+			{Path: "/work/client.go", Line: 5, Column: 18, Text: "func (c *Client) OpenFile() {", Symbol: "(*Client).OpenFile", Kind: "Method", SymbolStartLine: 5, SymbolEndLine: 9},
+			{Path: "/work/client.go", Line: 7, Column: 2, Text: "\tOpenFile()", Symbol: "(*Client).OpenFile", Kind: "Method", SymbolStartLine: 5, SymbolEndLine: 9},
+			{Path: "/work/README.md", Line: 3, Column: 5, Text: "Use OpenFile"},
+		},
+	}
+
+	assert.Equal(t, `Found 3 matches for "OpenFile" in 2 files
+
+/work/client.go
+  Method (*Client).OpenFile (L5-L9)
+5:18:func (c *Client) OpenFile() {
+7:2:	OpenFile()
+
+/work/README.md
+3:5:Use OpenFile
+`, Grep(result))
+
+	assert.Equal(t, "No matches found for: nothing", Grep(GrepResult{Pattern: "nothing"}))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
@@ -20,11 +21,8 @@ func GetHoverInfo(ctx context.Context, client *lsp.Client, filePath string, line
 	params := protocol.HoverParams{}
 
 	// Convert 1-indexed line/column to 0-indexed for LSP protocol
-	position := protocol.Position{
-		Line:      uint32(line - 1),
-		Character: uint32(column - 1),
-	}
-	uri := protocol.DocumentUri("file://" + filePath)
+	position := toPosition(client, filePath, line, column)
+	uri := fileuri.FromPath(filePath)
 	params.TextDocument = protocol.TextDocumentIdentifier{
 		URI: uri,
 	}
@@ -53,7 +51,7 @@ func GetHoverInfo(ctx context.Context, client *lsp.Client, filePath string, line
 					Character: 0,
 				},
 			},
-		})
+		}, client.PositionEncoding())
 		if err != nil {
 			toolsLogger.Warn("failed to extract line at position: %v", err)
 		}
@@ -64,3 +62,53 @@ func GetHoverInfo(ctx context.Context, client *lsp.Client, filePath string, line
 
 	return result.String(), nil
 }
+
+// GetHoverInfoForSymbol retrieves hover information for every workspace symbol matching symbolName
+func GetHoverInfoForSymbol(ctx context.Context, client *lsp.Client, symbolName string) (string, error) {
+	symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{
+		Query: symbolName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch symbol: %v", err)
+	}
+
+	results, err := symbolResult.Results()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse results: %v", err)
+	}
+
+	var hovers []string
+	for _, symbol := range results {
+		if !symbolNameMatches(symbol, symbolName) {
+			continue
+		}
+
+		loc := symbol.GetLocation()
+		filePath := fileuri.ToPath(loc.URI)
+		line, column := symbolNamePosition(loc, symbol.GetName(), client.PositionEncoding())
+
+		text, err := GetHoverInfo(ctx, client, filePath, line, column)
+		if err != nil {
+			toolsLogger.Error("Error getting hover for %s: %v", symbol.GetName(), err)
+			continue
+		}
+
+		hovers = append(hovers, fmt.Sprintf("---\n\nSymbol: %s\nFile: %s\nPosition: L%d:C%d\n\n%s\n",
+			symbol.GetName(), filePath, line, column, text))
+	}
+
+	if len(hovers) == 0 {
+		match, found, err := resolveFallback(ctx, client, symbolName)
+		if err != nil || !found {
+			return fmt.Sprintf("%s not found", symbolName), err
+		}
+		text, err := GetHoverInfo(ctx, client, match.Path, match.Line, match.Column)
+		if err != nil {
+			return "", err
+		}
+		hovers = append(hovers, fmt.Sprintf("---\n\nSymbol: %s\nFile: %s\nPosition: L%d:C%d\n\n%s\n",
+			match.Name, match.Path, match.Line, match.Column, text))
+	}
+
+	return strings.Join(hovers, ""), nil
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// FindImplementations finds all implementations of an interface, abstract type or method
+// and presents them with surrounding code context, like FindReferences
+func FindImplementations(ctx context.Context, client *lsp.Client, symbolName string) (string, error) {
+	// Get context lines from environment variable
+	contextLines := 5
+	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
+		if val, err := strconv.Atoi(envLines); err == nil && val >= 0 {
+			contextLines = val
+		}
+	}
+
+	implsBySymbol, err := findImplementations(ctx, client, symbolName)
+	if err != nil {
+		return "", err
+	}
+
+	var allImplementations []string
+	for _, impls := range implsBySymbol {
+		allImplementations = append(allImplementations, formatLocationsByFile(ctx, client, impls, contextLines, "Implementations")...)
+	}
+
+	if len(allImplementations) == 0 {
+		return fmt.Sprintf("No implementations found for symbol: %s", symbolName), nil
+	}
+
+	return strings.Join(allImplementations, "\n"), nil
+}
+
+// findImplementations returns the implementations of each symbol matching
+// symbolName, in the order the symbols were found
+func findImplementations(ctx context.Context, client *lsp.Client, symbolName string) ([][]protocol.Location, error) {
+	symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{
+		Query: symbolName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch symbol: %v", err)
+	}
+
+	results, err := symbolResult.Results()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse results: %v", err)
+	}
+
+	var implsBySymbol [][]protocol.Location
+	for _, symbol := range results {
+		if !symbolNameMatches(symbol, symbolName) {
+			continue
+		}
+
+		loc := symbol.GetLocation()
+
+		// File is likely to be opened already, but may not be.
+		err := client.OpenFile(ctx, loc.URI.Path())
+		if err != nil {
+			toolsLogger.Error("Error opening file: %v", err)
+			continue
+		}
+
+		// Request implementations at the symbol's name rather than the start of its range
+		line, column := symbolNamePosition(loc, symbol.GetName(), client.PositionEncoding())
+		implParams := protocol.ImplementationParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{
+					URI: loc.URI,
+				},
+				Position: toPosition(client, loc.URI.Path(), line, column),
+			},
+		}
+		implResult, err := client.Implementation(ctx, implParams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get implementations: %v", err)
+		}
+
+		implsBySymbol = append(implsBySymbol, implResult.Locations())
+	}
+	return implsBySymbol, nil
+}
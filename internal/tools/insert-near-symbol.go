@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// InsertNearSymbol inserts source on its own lines immediately before or after
+// a symbol found by name in a file, separated from it by a blank line, and
+// returns the file's diagnostics. Code inserted before a symbol goes above its
+// doc comments and annotations so they stay attached to the symbol. With dryRun
+// the change is returned as a diff instead of being applied.
+func InsertNearSymbol(ctx context.Context, client *lsp.Client, filePath string, symbolPath string, source string, before bool, dryRun bool) (string, error) {
+	if err := checkNotStaged(client, filePath); err != nil {
+		return "", err
+	}
+
+	doc, match, err := findSymbolInFile(ctx, client, filePath, symbolPath)
+	if err != nil {
+		return "", err
+	}
+
+	source = strings.TrimRight(source, "\n")
+	var position protocol.Position
+	var newText string
+	if before {
+		line := leadingCommentStart(doc.lines, int(match.rng.Start.Line))
+		position = protocol.Position{Line: uint32(line)}
+		newText = source + "\n\n"
+	} else {
+		line := int(match.rng.End.Line)
+		position = protocol.Position{Line: uint32(line), Character: utilities.CharacterLength(doc.lines[line], client.PositionEncoding())}
+		newText = "\n\n" + source
+	}
+
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			doc.uri: {
+				{
+					Range:   protocol.Range{Start: position, End: position},
+					NewText: newText,
+				},
+			},
+		},
+	}
+	where := "after"
+	if before {
+		where = "before"
+	}
+	if dryRun {
+		return previewEdit(client, fmt.Sprintf("Inserting %d lines %s %s %s.",
+			strings.Count(source, "\n")+1, where, protocol.TableKindMap[match.kind], match.name), edit)
+	}
+	entry := utilities.EditJournal.Begin(fmt.Sprintf("insert_near_symbol %s %s in %s", where, match.name, filePath))
+	entry.Capture(filePath)
+	if err := utilities.ApplyWorkspaceEdit(edit, client.PositionEncoding()); err != nil {
+		return "", fmt.Errorf("failed to insert code: %v", err)
+	}
+
+	// Send the new content to the server so diagnostics and later requests see it
+	if err := client.NotifyChange(ctx, filePath); err != nil {
+		toolsLogger.Error("Error notifying change: %v", err)
+	}
+	preSaved := saveEditedFile(ctx, client, filePath)
+	entry.Commit()
+
+	// The inserted lines start at the position, or after the blank line that follows the symbol
+	startLine := int(position.Line) + 1
+	if !before {
+		startLine += 2
+	}
+	endLine := startLine + strings.Count(source, "\n")
+	result := fmt.Sprintf("Inserted %d lines %s %s %s at L%d-L%d.",
+		endLine-startLine+1, where, protocol.TableKindMap[match.kind], match.name, startLine, endLine)
+	if preSaved {
+		result += preSaveNote
+	}
+
+	diagnostics, err := GetDiagnosticsForFile(ctx, client, filePath, 0, true, DiagnosticFilter{}, false)
+	if err != nil {
+		toolsLogger.Error("Error getting diagnostics after edit: %v", err)
+		return result, nil
+	}
+
+	return result + "\n\n" + diagnostics, nil
+}
+
+// leadingCommentStart returns the first line of the comments, decorators and
+// annotations directly above line, or line itself when there are none
+func leadingCommentStart(lines []string, line int) int {
+	for line > 0 {
+		previous := strings.TrimSpace(lines[line-1])
+		if !strings.HasPrefix(previous, "//") && !strings.HasPrefix(previous, "#") &&
+			!strings.HasPrefix(previous, "/*") && !strings.HasPrefix(previous, "*") &&
+			!strings.HasPrefix(previous, "@") {
+			break
+		}
+		line--
+	}
+	return line
+}
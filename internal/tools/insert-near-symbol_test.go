@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeadingCommentStart(t *testing.T) {
+	testCases := []struct {
+		name     string
+		source   string
+		line     int
+		expected int
+	}{
+		{
+			name:     "No comments",
+			source:   "package main\n\nfunc main() {}",
+			line:     2,
+			expected: 2,
+		},
+		{
+			name:     "Doc comment",
+			source:   "package main\n\n// Run starts the server\n// and blocks.\nfunc Run() {}",
+			line:     4,
+			expected: 2,
+		},
+		{
+			name:     "Decorators and comments",
+			source:   "class A:\n    # cached\n    @property\n    def value(self):\n        pass",
+			line:     3,
+			expected: 1,
+		},
+		{
+			name:     "Block comment",
+			source:   "/**\n * Adds numbers.\n */\nint add(int a, int b);",
+			line:     3,
+			expected: 0,
+		},
+		{
+			name:     "Stops at code",
+			source:   "x := 1\n// Comment\nfunc f() {}",
+			line:     2,
+			expected: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := strings.Split(tc.source, "\n")
+			assert.Equal(t, tc.expected, leadingCommentStart(lines, tc.line))
+		})
+	}
+}
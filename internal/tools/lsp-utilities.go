@@ -3,12 +3,13 @@ package tools
 import (
 	"context"
 	"fmt"
-	"net/url"
-	"os"
+	"sort"
 	"strings"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
 // Gets the full code block surrounding the start of the input location
@@ -60,14 +61,11 @@ func GetFullDefinition(ctx context.Context, client *lsp.Client, startLocation pr
 
 	if found {
 		// Convert URI to filesystem path
-		filePath, err := url.PathUnescape(strings.TrimPrefix(string(startLocation.URI), "file://"))
-		if err != nil {
-			return "", protocol.Location{}, fmt.Errorf("failed to unescape URI: %w", err)
-		}
+		filePath := fileuri.ToPath(startLocation.URI)
 
 		// Read the file to get the full lines of the definition
 		// because we may have a start and end column
-		content, err := os.ReadFile(filePath)
+		content, err := client.ReadDocument(filePath)
 		if err != nil {
 			return "", protocol.Location{}, fmt.Errorf("failed to read file: %w", err)
 		}
@@ -110,7 +108,7 @@ func GetFullDefinition(ctx context.Context, client *lsp.Client, startLocation pr
 									if len(bracketStack) == 0 {
 										// Found matching bracket - update range
 										symbolRange.End.Line = lineNum
-										symbolRange.End.Character = uint32(pos + 1)
+										symbolRange.End.Character = utilities.CharacterOf(line, pos+1, client.PositionEncoding())
 										goto foundClosing
 									}
 								}
@@ -140,6 +138,12 @@ func GetFullDefinition(ctx context.Context, client *lsp.Client, startLocation pr
 
 // GetLineRangesToDisplay determines which lines should be displayed for a set of locations
 func GetLineRangesToDisplay(ctx context.Context, client *lsp.Client, locations []protocol.Location, totalLines int, contextLines int) (map[int]bool, error) {
+	return lineRangesToDisplay(ctx, client, locations, totalLines, contextLines, contextLines)
+}
+
+// lineRangesToDisplay is GetLineRangesToDisplay with separate amounts of context
+// before and after each location
+func lineRangesToDisplay(ctx context.Context, client *lsp.Client, locations []protocol.Location, totalLines int, contextBefore int, contextAfter int) (map[int]bool, error) {
 	// Set to track which lines need to be displayed
 	linesToShow := make(map[int]bool)
 
@@ -153,7 +157,7 @@ func GetLineRangesToDisplay(ctx context.Context, client *lsp.Client, locations [
 			linesToShow[refLine] = true
 
 			// Add context lines
-			for i := refLine - contextLines; i <= refLine+contextLines; i++ {
+			for i := refLine - contextBefore; i <= refLine+contextAfter; i++ {
 				if i >= 0 && i < totalLines {
 					linesToShow[i] = true
 				}
@@ -172,7 +176,7 @@ func GetLineRangesToDisplay(ctx context.Context, client *lsp.Client, locations [
 		linesToShow[refLine] = true
 
 		// Add context lines around the reference
-		for i := refLine - contextLines; i <= refLine+contextLines; i++ {
+		for i := refLine - contextBefore; i <= refLine+contextAfter; i++ {
 			if i >= 0 && i < totalLines && i >= containerStart && i <= containerEnd {
 				linesToShow[i] = true
 			}
@@ -181,3 +185,155 @@ func GetLineRangesToDisplay(ctx context.Context, client *lsp.Client, locations [
 
 	return linesToShow, nil
 }
+
+// formatLocationsByFile groups locations by file and renders each file's locations with
+// surrounding context, in the same format used by the references tool. label names the
+// kind of location in the file header, e.g. "References".
+func formatLocationsByFile(ctx context.Context, client *lsp.Client, locations []protocol.Location, contextLines int, label string) []string {
+	return formatLocationsByFileWithContext(ctx, client, locations, contextLines, contextLines, label)
+}
+
+// formatLocationsByFileWithContext is formatLocationsByFile with separate amounts of
+// context before and after each location
+func formatLocationsByFileWithContext(ctx context.Context, client *lsp.Client, locations []protocol.Location, contextBefore int, contextAfter int, label string) []string {
+	var formatted []string
+
+	// Group locations by file
+	locsByFile := make(map[protocol.DocumentUri][]protocol.Location)
+	for _, loc := range locations {
+		locsByFile[loc.URI] = append(locsByFile[loc.URI], loc)
+	}
+
+	// Get sorted list of URIs
+	uris := make([]string, 0, len(locsByFile))
+	for uri := range locsByFile {
+		uris = append(uris, string(uri))
+	}
+	sort.Strings(uris)
+
+	// Process each file's locations in sorted order
+	for _, uriStr := range uris {
+		uri := protocol.DocumentUri(uriStr)
+		fileLocs := locsByFile[uri]
+		filePath := fileuri.ToPath(uriStr)
+
+		// Format file header
+		fileInfo := fmt.Sprintf("---\n\n%s\n%s in File: %d\n",
+			filePath,
+			label,
+			len(fileLocs),
+		)
+
+		// Format locations with context
+		fileContent, err := client.ReadDocument(filePath)
+		if err != nil {
+			// Log error but continue with other files
+			formatted = append(formatted, fileInfo+"\nError reading file: "+err.Error())
+			continue
+		}
+
+		lines := strings.Split(string(fileContent), "\n")
+
+		// Track locations for header display
+		var locStrings []string
+		for _, loc := range fileLocs {
+			line := ""
+			if int(loc.Range.Start.Line) < len(lines) {
+				line = lines[loc.Range.Start.Line]
+			}
+			locStr := fmt.Sprintf("L%d:C%d",
+				loc.Range.Start.Line+1,
+				utilities.CharacterToColumn(line, loc.Range.Start.Character, client.PositionEncoding()))
+			locStrings = append(locStrings, locStr)
+		}
+
+		// Collect lines to display using the utility function
+		linesToShow, err := lineRangesToDisplay(ctx, client, fileLocs, len(lines), contextBefore, contextAfter)
+		if err != nil {
+			// Log error but continue with other files
+			continue
+		}
+
+		// Convert to line ranges using the utility function
+		lineRanges := ConvertLinesToRanges(linesToShow, len(lines))
+
+		// Format with locations in header
+		formattedOutput := fileInfo
+		if len(locStrings) > 0 {
+			formattedOutput += "At: " + strings.Join(locStrings, ", ") + "\n"
+		}
+
+		// Format the content with ranges
+		formattedOutput += "\n" + FormatLinesWithRanges(lines, lineRanges)
+		formatted = append(formatted, formattedOutput)
+	}
+
+	return formatted
+}
+
+// formatDefinitionLocations renders the full definition surrounding each location, in the
+// same layout used by ReadDefinition
+func formatDefinitionLocations(ctx context.Context, client *lsp.Client, locations []protocol.Location) []string {
+	return formatTargetLocations(ctx, client, locations, true)
+}
+
+// formatTargetLocations renders the lines at each location, expanded to the full
+// enclosing symbol when fullBody is set
+func formatTargetLocations(ctx context.Context, client *lsp.Client, locations []protocol.Location, fullBody bool) []string {
+	var definitions []string
+	positions := newDocumentLines(client)
+	for _, loc := range locations {
+		// File may be outside of the workspace, such as a dependency
+		err := client.OpenFile(ctx, loc.URI.Path())
+		if err != nil {
+			toolsLogger.Error("Error opening file: %v", err)
+			continue
+		}
+
+		var definition string
+		var fullLoc protocol.Location
+		if fullBody {
+			definition, fullLoc, err = GetFullDefinition(ctx, client, loc)
+			if err != nil {
+				// Not inside a document symbol, so show just the target lines
+				toolsLogger.Debug("Could not get full definition, using target lines: %v", err)
+			}
+		}
+		if !fullBody || err != nil {
+			content, err := client.ReadDocument(loc.URI.Path())
+			if err != nil {
+				toolsLogger.Error("Error reading file: %v", err)
+				continue
+			}
+			lines := strings.Split(string(content), "\n")
+			if int(loc.Range.End.Line) >= len(lines) {
+				toolsLogger.Error("Location out of range: %v", loc.Range)
+				continue
+			}
+			selectedLines := lines[loc.Range.Start.Line : loc.Range.End.Line+1]
+			definition = strings.Join(selectedLines, "\n")
+			fullLoc = protocol.Location{
+				URI: loc.URI,
+				Range: protocol.Range{
+					Start: protocol.Position{Line: loc.Range.Start.Line},
+					End:   protocol.Position{Line: loc.Range.End.Line, Character: utilities.CharacterLength(selectedLines[len(selectedLines)-1], client.PositionEncoding())},
+				},
+			}
+		}
+
+		locationInfo := fmt.Sprintf(
+			"File: %s\n"+
+				"Range: L%d:C%d - L%d:C%d\n\n",
+			fileuri.ToPath(fullLoc.URI),
+			fullLoc.Range.Start.Line+1,
+			positions.column(fullLoc.URI, fullLoc.Range.Start),
+			fullLoc.Range.End.Line+1,
+			positions.column(fullLoc.URI, fullLoc.Range.End),
+		)
+
+		definition = addLineNumbers(definition, int(fullLoc.Range.Start.Line)+1)
+
+		definitions = append(definitions, "---\n\n"+locationInfo+definition+"\n")
+	}
+	return definitions
+}
@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// GetMonikers returns the monikers of the symbol at the specified position. A
+// moniker identifies a symbol across projects and repositories by a scheme, such
+// as the package manager it comes from, and an identifier that is stable within
+// that scheme, for linking into LSIF or SCIP based code search.
+func GetMonikers(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Convert 1-indexed line/column to 0-indexed for LSP protocol
+	params := protocol.MonikerParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: fileuri.FromPath(filePath),
+			},
+			Position: toPosition(client, filePath, line, column),
+		},
+	}
+
+	monikers, err := client.Moniker(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to get monikers: %v", err)
+	}
+	if len(monikers) == 0 {
+		return fmt.Sprintf("No monikers found at %s L%d:C%d", filePath, line, column), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Monikers at %s L%d:C%d:\n", filePath, line, column))
+	for _, moniker := range monikers {
+		output.WriteString(fmt.Sprintf("- Scheme: %s\n  Identifier: %s\n  Unique: %s\n", moniker.Scheme, moniker.Identifier, moniker.Unique))
+		if moniker.Kind != nil {
+			output.WriteString(fmt.Sprintf("  Kind: %s\n", *moniker.Kind))
+		}
+	}
+	return output.String(), nil
+}
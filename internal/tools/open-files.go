@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// OpenFilesReport is the files open in the language server, as reported by
+// open_files
+type OpenFilesReport struct {
+	// MaxOpenFiles is the cap on open files, 0 for no limit
+	MaxOpenFiles int             `json:"maxOpenFiles"`
+	Files        []OpenFileEntry `json:"files"`
+}
+
+// OpenFileEntry is a file open in the language server
+type OpenFileEntry struct {
+	Path     string `json:"path"`
+	Version  int32  `json:"version"`
+	OpenedAt string `json:"openedAt"`
+	LastUsed string `json:"lastUsed"`
+	Staged   bool   `json:"staged,omitempty"`
+}
+
+// CollectOpenFiles returns the files open in the language server, most recently
+// used first
+func CollectOpenFiles(client *lsp.Client) OpenFilesReport {
+	report := OpenFilesReport{
+		MaxOpenFiles: client.MaxOpenFiles(),
+		Files:        []OpenFileEntry{},
+	}
+	for _, doc := range client.OpenDocuments() {
+		report.Files = append(report.Files, OpenFileEntry{
+			Path:     doc.Path,
+			Version:  doc.Version,
+			OpenedAt: doc.OpenedAt.Format(time.RFC3339),
+			LastUsed: doc.LastUsed.Format(time.RFC3339),
+			Staged:   doc.Staged,
+		})
+	}
+	return report
+}
+
+// OpenFiles lists the files open in the language server, most recently used
+// first, with the cap past which the least recently used are closed
+func OpenFiles(client *lsp.Client) string {
+	docs := client.OpenDocuments()
+	now := time.Now()
+
+	var output strings.Builder
+	if limit := client.MaxOpenFiles(); limit > 0 {
+		fmt.Fprintf(&output, "Open files: %d of at most %d, least recently used are closed past the limit\n", len(docs), limit)
+	} else {
+		fmt.Fprintf(&output, "Open files: %d, no limit\n", len(docs))
+	}
+	for _, doc := range docs {
+		fmt.Fprintf(&output, "  %s (version %d, used %v ago", doc.Path, doc.Version, now.Sub(doc.LastUsed).Round(time.Second))
+		if doc.Staged {
+			output.WriteString(", staged edits")
+		}
+		output.WriteString(")\n")
+	}
+	return output.String()
+}
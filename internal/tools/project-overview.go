@@ -0,0 +1,433 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+const (
+	// maxOverviewFiles bounds how many files of each workspace folder are counted
+	maxOverviewFiles = 50000
+
+	// maxOverviewPackages and maxOverviewPackageFiles bound how many packages are
+	// listed with their symbols, and how many files of each are opened to find them
+	maxOverviewPackages     = 40
+	maxOverviewPackageFiles = 20
+
+	// maxEntryPointSize is the largest file checked for a main function
+	maxEntryPointSize = 1024 * 1024
+)
+
+var errTooManyFiles = errors.New("too many files")
+
+// Languages that are counted but hold no code to outline
+var nonCodeLanguages = map[protocol.LanguageKind]bool{
+	"":                      true,
+	protocol.LangBibTeX:     true,
+	protocol.LangCSS:        true,
+	protocol.LangDiff:       true,
+	protocol.LangDockerfile: true,
+	protocol.LangGitCommit:  true,
+	protocol.LangGitRebase:  true,
+	protocol.LangHandlebars: true,
+	protocol.LangHTML:       true,
+	protocol.LangIni:        true,
+	protocol.LangJSON:       true,
+	protocol.LangLaTeX:      true,
+	protocol.LangLess:       true,
+	protocol.LangMakefile:   true,
+	protocol.LangMarkdown:   true,
+	protocol.LangPug:        true,
+	protocol.LangRazor:      true,
+	protocol.LangSCSS:       true,
+	protocol.LangXML:        true,
+	protocol.LangYAML:       true,
+}
+
+// Patterns that mark a file as a program's entry point, by language
+var entryPointPatterns = map[protocol.LanguageKind]*regexp.Regexp{
+	protocol.LangGo:     regexp.MustCompile(`(?m)^func main\(\)`),
+	protocol.LangRust:   regexp.MustCompile(`(?m)^\s*(pub\s+)?(async\s+)?fn main\(`),
+	protocol.LangPython: regexp.MustCompile(`(?m)^if __name__ == ['"]__main__['"]`),
+	protocol.LangC:      regexp.MustCompile(`(?m)^\s*int\s+main\s*\(`),
+	protocol.LangCPP:    regexp.MustCompile(`(?m)^\s*(auto|int)\s+main\s*\(`),
+	protocol.LangJava:   regexp.MustCompile(`public\s+static\s+void\s+main\s*\(`),
+}
+
+var goMainPackage = regexp.MustCompile(`(?m)^package main\b`)
+
+// Files that define a module or package, and how to find its name
+var manifestNames = map[string]*regexp.Regexp{
+	"go.mod":           regexp.MustCompile(`(?m)^module\s+(\S+)`),
+	"Cargo.toml":       regexp.MustCompile(`(?m)^name\s*=\s*"([^"]+)"`),
+	"pyproject.toml":   regexp.MustCompile(`(?m)^name\s*=\s*["']([^"']+)["']`),
+	"setup.py":         regexp.MustCompile(`name\s*=\s*["']([^"']+)["']`),
+	"pom.xml":          regexp.MustCompile(`<artifactId>([^<]+)</artifactId>`),
+	"build.gradle":     nil,
+	"build.gradle.kts": nil,
+	"CMakeLists.txt":   regexp.MustCompile(`(?i)project\s*\(\s*([^\s)]+)`),
+	"package.json":     nil,
+}
+
+// overviewPackage is a directory holding source files of the primary language
+type overviewPackage struct {
+	dir   string
+	files []string
+}
+
+// ProjectOverview summarizes each workspace folder for orientation: its files by
+// language, the modules and packages it defines, its programs' entry points, and
+// the main exported symbols of its packages, found with documentSymbol
+func ProjectOverview(ctx context.Context, client *lsp.Client, symbolsPerPackage int) (string, error) {
+	roots := client.WorkspaceRoots()
+	if len(roots) == 0 {
+		return "", fmt.Errorf("no workspace folders")
+	}
+
+	var sections []string
+	for _, root := range roots {
+		section, err := overviewRoot(ctx, client, root, symbolsPerPackage)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, section)
+	}
+	return strings.Join(sections, "\n"), nil
+}
+
+// overviewRoot summarizes one workspace folder
+func overviewRoot(ctx context.Context, client *lsp.Client, root string, symbolsPerPackage int) (string, error) {
+	languages := make(map[protocol.LanguageKind]int)
+	topLevel := make(map[string]int)
+	byDir := make(map[string]map[protocol.LanguageKind][]string)
+	var manifests, entryPoints []string
+	var total int
+
+	err := watcher.WalkWorkspaceFiles(root, func(path string) error {
+		if total >= maxOverviewFiles {
+			return errTooManyFiles
+		}
+		total++
+
+		rel, _ := filepath.Rel(root, path)
+		if parts := strings.SplitN(filepath.ToSlash(rel), "/", 2); len(parts) == 2 {
+			topLevel[parts[0]]++
+		}
+
+		language := lsp.DetectLanguageID(path)
+		languages[language]++
+
+		name := filepath.Base(path)
+		if _, ok := manifestNames[name]; ok {
+			manifest, entries := readManifest(root, path)
+			manifests = append(manifests, manifest)
+			entryPoints = append(entryPoints, entries...)
+		}
+
+		if nonCodeLanguages[language] {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		if byDir[dir] == nil {
+			byDir[dir] = make(map[protocol.LanguageKind][]string)
+		}
+		byDir[dir][language] = append(byDir[dir][language], path)
+
+		if isEntryPoint(language, path) {
+			entryPoints = append(entryPoints, fmt.Sprintf("%s (%s)", filepath.ToSlash(rel), language))
+		}
+		return nil
+	})
+	truncated := errors.Is(err, errTooManyFiles)
+	if err != nil && !truncated {
+		return "", fmt.Errorf("failed to walk %s: %v", root, err)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Project overview of %s: %d files", root, total))
+	if truncated {
+		output.WriteString(fmt.Sprintf(", stopped counting at %d", maxOverviewFiles))
+	}
+	output.WriteString("\n")
+
+	output.WriteString("\nLanguages:\n")
+	for _, language := range sortedByCount(languages) {
+		name := string(language)
+		if name == "" {
+			name = "other"
+		}
+		output.WriteString(fmt.Sprintf("  %s: %d files\n", name, languages[language]))
+	}
+
+	if len(manifests) > 0 {
+		sort.Strings(manifests)
+		output.WriteString("\nModules:\n")
+		for _, manifest := range manifests {
+			output.WriteString("  " + manifest + "\n")
+		}
+	}
+
+	if len(entryPoints) > 0 {
+		sort.Strings(entryPoints)
+		output.WriteString("\nEntry points:\n")
+		for _, entry := range entryPoints {
+			output.WriteString("  " + entry + "\n")
+		}
+	}
+
+	if len(topLevel) > 0 {
+		output.WriteString("\nTop-level directories:\n")
+		for _, dir := range sortedByCount(topLevel) {
+			output.WriteString(fmt.Sprintf("  %s/: %d files\n", dir, topLevel[dir]))
+		}
+	}
+
+	// Symbols come from the language with the most code, which the server is
+	// most likely to handle
+	primary := primaryLanguage(languages)
+	if primary == "" || symbolsPerPackage <= 0 {
+		return output.String(), nil
+	}
+
+	var packages []overviewPackage
+	for dir, files := range byDir {
+		if len(files[primary]) > 0 {
+			packages = append(packages, overviewPackage{dir: dir, files: files[primary]})
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].dir < packages[j].dir })
+
+	output.WriteString(fmt.Sprintf("\nExported %s symbols by package:\n", primary))
+	for i, pkg := range packages {
+		if i == maxOverviewPackages {
+			output.WriteString(fmt.Sprintf("  %d more packages\n", len(packages)-maxOverviewPackages))
+			break
+		}
+		rel, _ := filepath.Rel(root, pkg.dir)
+		symbols, more := packageSymbols(ctx, client, primary, pkg.files, symbolsPerPackage)
+		output.WriteString(fmt.Sprintf("  %s (%d files)", filepath.ToSlash(rel), len(pkg.files)))
+		if len(symbols) > 0 {
+			output.WriteString(": " + strings.Join(symbols, ", "))
+		}
+		if more > 0 {
+			output.WriteString(fmt.Sprintf(" and %d more", more))
+		}
+		output.WriteString("\n")
+	}
+
+	return output.String(), nil
+}
+
+// readManifest describes a module manifest by its path and name, and returns the
+// entry points declared by package.json files
+func readManifest(root, path string) (string, []string) {
+	rel, _ := filepath.Rel(root, path)
+	rel = filepath.ToSlash(rel)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return rel, nil
+	}
+
+	name := filepath.Base(path)
+	if name == "package.json" {
+		var pkg struct {
+			Name string          `json:"name"`
+			Main string          `json:"main"`
+			Bin  json.RawMessage `json:"bin"`
+		}
+		if json.Unmarshal(content, &pkg) != nil {
+			return rel, nil
+		}
+
+		dir := filepath.Dir(rel)
+		var entries []string
+		if pkg.Main != "" {
+			entries = append(entries, fmt.Sprintf("%s (package.json main)", filepath.ToSlash(filepath.Join(dir, pkg.Main))))
+		}
+		var bin string
+		var bins map[string]string
+		if json.Unmarshal(pkg.Bin, &bin) == nil && bin != "" {
+			entries = append(entries, fmt.Sprintf("%s (package.json bin)", filepath.ToSlash(filepath.Join(dir, bin))))
+		} else if json.Unmarshal(pkg.Bin, &bins) == nil {
+			for command, target := range bins {
+				entries = append(entries, fmt.Sprintf("%s (package.json bin %s)", filepath.ToSlash(filepath.Join(dir, target)), command))
+			}
+		}
+		if pkg.Name == "" {
+			return rel, entries
+		}
+		return fmt.Sprintf("%s: %s", rel, pkg.Name), entries
+	}
+
+	if pattern := manifestNames[name]; pattern != nil {
+		if match := pattern.FindSubmatch(content); match != nil {
+			return fmt.Sprintf("%s: %s", rel, match[1]), nil
+		}
+	}
+	return rel, nil
+}
+
+// isEntryPoint reports whether a source file defines a program's main function
+func isEntryPoint(language protocol.LanguageKind, path string) bool {
+	pattern := entryPointPatterns[language]
+	if pattern == nil || isTestFile(path) {
+		return false
+	}
+	if language == protocol.LangPython && filepath.Base(path) == "__main__.py" {
+		return true
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxEntryPointSize {
+		return false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if language == protocol.LangGo && !goMainPackage.Match(content) {
+		return false
+	}
+	return pattern.Match(content)
+}
+
+// isTestFile reports whether a file holds tests by the usual naming conventions
+func isTestFile(path string) bool {
+	name := filepath.Base(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.HasSuffix(stem, "_test") || strings.HasPrefix(stem, "test_") ||
+		strings.HasSuffix(stem, ".test") || strings.HasSuffix(stem, ".spec")
+}
+
+// primaryLanguage returns the language with the most source files
+func primaryLanguage(languages map[protocol.LanguageKind]int) protocol.LanguageKind {
+	for _, language := range sortedByCount(languages) {
+		if !nonCodeLanguages[language] {
+			return language
+		}
+	}
+	return ""
+}
+
+// packageSymbols returns up to limit of the exported top-level symbols of a
+// package's files, types first, and how many more there are. Files opened to find
+// them are closed again.
+func packageSymbols(ctx context.Context, client *lsp.Client, language protocol.LanguageKind, files []string, limit int) ([]string, int) {
+	sort.Strings(files)
+
+	type exported struct {
+		name string
+		rank int
+	}
+	var found []exported
+	var checked int
+	for _, path := range files {
+		if isTestFile(path) {
+			continue
+		}
+		if checked == maxOverviewPackageFiles {
+			break
+		}
+		checked++
+
+		symbols, ok := indexedSymbols(client, path)
+		if !ok {
+			wasOpen := client.IsFileOpen(path)
+			doc, err := loadDocumentSymbols(ctx, client, fileuri.FromPath(path))
+			if !wasOpen {
+				if err := client.CloseFile(ctx, path); err != nil {
+					toolsLogger.Debug("Failed to close %s: %v", path, err)
+				}
+			}
+			if err != nil || doc == nil {
+				toolsLogger.Debug("Skipping symbols of %s: %v", path, err)
+				continue
+			}
+			symbols = doc.symbols
+		}
+
+		for _, sym := range symbols {
+			var kind protocol.SymbolKind
+			switch v := sym.(type) {
+			case *protocol.DocumentSymbol:
+				kind = v.Kind
+			case *protocol.SymbolInformation:
+				// Flat results list members too, keep only top-level symbols
+				if v.ContainerName != "" {
+					continue
+				}
+				kind = v.Kind
+			}
+			rank, ok := symbolRank(kind)
+			if !ok || !isExportedName(language, sym.GetName()) {
+				continue
+			}
+			found = append(found, exported{
+				name: fmt.Sprintf("%s %s", strings.ToLower(protocol.TableKindMap[kind]), sym.GetName()),
+				rank: rank,
+			})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].rank < found[j].rank })
+	var names []string
+	for i := 0; i < len(found) && i < limit; i++ {
+		names = append(names, found[i].name)
+	}
+	return names, len(found) - len(names)
+}
+
+// symbolRank orders symbol kinds by how much they say about a package's API, and
+// reports false for kinds that are not listed, such as methods and fields
+func symbolRank(kind protocol.SymbolKind) (int, bool) {
+	switch kind {
+	case protocol.Class, protocol.Interface, protocol.Struct, protocol.Enum, protocol.Module, protocol.Namespace:
+		return 0, true
+	case protocol.Function:
+		return 1, true
+	case protocol.Constant, protocol.Variable:
+		return 2, true
+	}
+	return 0, false
+}
+
+// isExportedName reports whether a symbol is visible outside its package. Go
+// exports capitalized names, and other languages mark private names with a
+// leading underscore by convention.
+func isExportedName(language protocol.LanguageKind, name string) bool {
+	if language == protocol.LangGo {
+		r, _ := utf8.DecodeRuneInString(name)
+		return unicode.IsUpper(r)
+	}
+	return name != "" && !strings.HasPrefix(name, "_")
+}
+
+// sortedByCount returns the keys of counts, the largest count first
+func sortedByCount[K ~string](counts map[K]int) []K {
+	keys := make([]K, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadManifest(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) string {
+		path := filepath.Join(root, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	manifest, entries := readManifest(root, write("go.mod", "module example.com/app\n\ngo 1.24\n"))
+	assert.Equal(t, "go.mod: example.com/app", manifest)
+	assert.Empty(t, entries)
+
+	manifest, _ = readManifest(root, write("crates/core/Cargo.toml", "[package]\nname = \"core\"\nversion = \"0.1.0\"\n"))
+	assert.Equal(t, "crates/core/Cargo.toml: core", manifest)
+
+	manifest, entries = readManifest(root, write("web/package.json", `{"name": "web", "main": "dist/index.js", "bin": {"web-cli": "bin/cli.js"}}`))
+	assert.Equal(t, "web/package.json: web", manifest)
+	assert.Equal(t, []string{"web/dist/index.js (package.json main)", "web/bin/cli.js (package.json bin web-cli)"}, entries)
+
+	manifest, _ = readManifest(root, write("build.gradle", "plugins {}\n"))
+	assert.Equal(t, "build.gradle", manifest)
+}
+
+func TestIsEntryPoint(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	assert.True(t, isEntryPoint(protocol.LangGo, write("main.go", "package main\n\nfunc main() {}\n")))
+	assert.False(t, isEntryPoint(protocol.LangGo, write("lib.go", "package lib\n\nfunc main() {}\n")))
+	assert.False(t, isEntryPoint(protocol.LangGo, write("main_test.go", "package main\n\nfunc main() {}\n")))
+	assert.True(t, isEntryPoint(protocol.LangPython, write("cli.py", "def run(): pass\n\nif __name__ == \"__main__\":\n    run()\n")))
+	assert.True(t, isEntryPoint(protocol.LangRust, write("main.rs", "#[tokio::main]\nasync fn main() {}\n")))
+	assert.False(t, isEntryPoint(protocol.LangMarkdown, write("README.md", "func main() {}\n")))
+}
+
+func TestIsExportedName(t *testing.T) {
+	assert.True(t, isExportedName(protocol.LangGo, "Client"))
+	assert.False(t, isExportedName(protocol.LangGo, "client"))
+	assert.False(t, isExportedName(protocol.LangGo, "(*Client).Start"))
+	assert.True(t, isExportedName(protocol.LangPython, "parse"))
+	assert.False(t, isExportedName(protocol.LangPython, "_parse"))
+}
+
+func TestPrimaryLanguage(t *testing.T) {
+	languages := map[protocol.LanguageKind]int{
+		protocol.LangMarkdown: 40,
+		protocol.LangGo:       25,
+		protocol.LangPython:   25,
+		"":                    100,
+	}
+	assert.Equal(t, protocol.LangGo, primaryLanguage(languages))
+	assert.Equal(t, []protocol.LanguageKind{"", protocol.LangMarkdown, protocol.LangGo, protocol.LangPython}, sortedByCount(languages))
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// ReadFile returns a slice of a file's lines with line numbers. The file is read
+// as the language server sees it, with staged edits. endLine 0 reads to the end
+// of the file.
+func ReadFile(client *lsp.Client, filePath string, startLine, endLine int) (string, error) {
+	content, err := client.ReadDocument(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	if len(content) == 0 {
+		return fmt.Sprintf("%s is empty", filePath), nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if startLine < 1 {
+		startLine = 1
+	}
+	if endLine == 0 || endLine > len(lines) {
+		endLine = len(lines)
+	}
+	if startLine > len(lines) {
+		return "", fmt.Errorf("startLine %d is past the end of %s, which has %d lines", startLine, filePath, len(lines))
+	}
+	if endLine < startLine {
+		return "", fmt.Errorf("endLine %d is before startLine %d", endLine, startLine)
+	}
+
+	header := fmt.Sprintf("%s (%d lines)\n\n", filePath, len(lines))
+	if startLine > 1 || endLine < len(lines) {
+		header = fmt.Sprintf("%s, lines %d-%d of %d\n\n", filePath, startLine, endLine, len(lines))
+	}
+	return header + addLineNumbers(strings.Join(lines[startLine-1:endLine], "\n"), startLine), nil
+}
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFilePlainText(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("name: demo\nreplicas: 1\nport: 8080\n"), 0o644))
+
+	ctx := context.Background()
+	client := lsp.NewPlainTextClient()
+	_, err := client.InitializeLSPClient(ctx, []string{root}, nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	text, err := ReadFile(client, path, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, path+" (3 lines)\n\n1|name: demo\n2|replicas: 1\n3|port: 8080\n", text)
+
+	text, err = ReadFile(client, path, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, path+", lines 2-2 of 3\n\n2|replicas: 1\n", text)
+
+	_, err = ReadFile(client, path, 5, 0)
+	assert.Error(t, err)
+
+	// Line edits need no language server
+	_, err = ApplyTextEdits(ctx, client, path, []TextEdit{{StartLine: 2, EndLine: 2, NewText: "replicas: 3"}}, false)
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "name: demo\nreplicas: 3\nport: 8080\n", string(content))
+}
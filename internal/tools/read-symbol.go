@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// documentSymbolMatch is a symbol found in a document
+type documentSymbolMatch struct {
+	// Name qualified by the symbols containing it, e.g. "Type.Method"
+	name string
+	kind protocol.SymbolKind
+	rng  protocol.Range
+	// selection is the range of the symbol's name, or its whole range for flat
+	// results that do not report one
+	selection protocol.Range
+}
+
+// documentSymbols are the symbols and lines of a file, with the encoding of the
+// server the symbols came from
+type documentSymbols struct {
+	uri      protocol.DocumentUri
+	symbols  []protocol.DocumentSymbolResult
+	lines    []string
+	encoding protocol.PositionEncodingKind
+}
+
+// ReadSymbol returns the source of a single symbol. symbolPath is a name or a
+// qualified path such as "pkg.Type.Method" or "Class::method", matched against the
+// end of each symbol's chain of containers. With a filePath the symbol is looked up
+// in that file's document symbols, otherwise it is searched for in the workspace.
+func ReadSymbol(ctx context.Context, client *lsp.Client, filePath string, symbolPath string) (string, error) {
+	wanted := splitSymbolPath(symbolPath)
+	if len(wanted) == 0 {
+		return "", fmt.Errorf("symbol name is required")
+	}
+
+	var uris []protocol.DocumentUri
+	if filePath != "" {
+		uris = append(uris, fileuri.FromPath(filePath))
+	} else {
+		// Search by the last part of the path, then narrow down using each file's
+		// document symbols
+		symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{
+			Query: wanted[len(wanted)-1],
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch symbol: %v", err)
+		}
+
+		results, err := symbolResult.Results()
+		if err != nil {
+			return "", fmt.Errorf("failed to parse results: %v", err)
+		}
+
+		seen := make(map[protocol.DocumentUri]bool)
+		for _, symbol := range results {
+			if !symbolNameMatches(symbol, wanted[len(wanted)-1]) && !symbolNameMatches(symbol, symbolPath) {
+				continue
+			}
+			uri := symbol.GetLocation().URI
+			if !seen[uri] {
+				seen[uri] = true
+				uris = append(uris, uri)
+			}
+		}
+	}
+
+	var documents []documentSymbols
+	for _, uri := range uris {
+		doc, err := loadDocumentSymbols(ctx, client, uri)
+		if err != nil {
+			return "", err
+		}
+		if doc != nil {
+			documents = append(documents, *doc)
+		}
+	}
+
+	// Document symbols rarely include the package or module, so when nothing matches
+	// the full path, drop its leading qualifiers one at a time
+	var sections []string
+	for ; len(wanted) > 0 && len(sections) == 0; wanted = wanted[1:] {
+		for _, doc := range documents {
+			for _, match := range findDocumentSymbols(doc.symbols, wanted) {
+				if int(match.rng.End.Line) >= len(doc.lines) {
+					toolsLogger.Error("Symbol range out of bounds: %v", match.rng)
+					continue
+				}
+
+				source := strings.Join(doc.lines[match.rng.Start.Line:match.rng.End.Line+1], "\n")
+				info := fmt.Sprintf(
+					"Symbol: %s\n"+
+						"File: %s\n"+
+						"Kind: %s\n"+
+						"Range: L%d:C%d - L%d:C%d\n\n",
+					match.name,
+					doc.uri.Path(),
+					protocol.TableKindMap[match.kind],
+					match.rng.Start.Line+1,
+					utilities.CharacterToColumn(doc.lines[match.rng.Start.Line], match.rng.Start.Character, client.PositionEncoding()),
+					match.rng.End.Line+1,
+					utilities.CharacterToColumn(doc.lines[match.rng.End.Line], match.rng.End.Character, client.PositionEncoding()),
+				)
+				sections = append(sections, "---\n\n"+info+addLineNumbers(source, int(match.rng.Start.Line)+1))
+			}
+		}
+	}
+
+	if len(sections) == 0 {
+		if filePath != "" {
+			return fmt.Sprintf("%s not found in %s", symbolPath, filePath), nil
+		}
+		return fmt.Sprintf("%s not found", symbolPath), nil
+	}
+
+	return strings.Join(sections, ""), nil
+}
+
+// loadDocumentSymbols opens a file and fetches its document symbols and lines.
+// It returns nil when the file cannot be opened.
+func loadDocumentSymbols(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri) (*documentSymbols, error) {
+	err := client.OpenFile(ctx, uri.Path())
+	if err != nil {
+		toolsLogger.Error("Error opening file: %v", err)
+		return nil, nil
+	}
+
+	symResult, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document symbols: %v", err)
+	}
+	symbols, err := symResult.Results()
+	if err != nil {
+		return nil, fmt.Errorf("failed to process document symbols: %v", err)
+	}
+
+	content, err := client.ReadDocument(uri.Path())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	if index := client.SymbolIndex(); index != nil {
+		index.Record(uri.Path(), content, symbols)
+	}
+	return &documentSymbols{
+		uri:      uri,
+		symbols:  symbols,
+		lines:    strings.Split(string(content), "\n"),
+		encoding: client.PositionEncoding(),
+	}, nil
+}
+
+// indexedSymbols returns the symbols of a file from the symbol index while the
+// server is starting, so that queries need not wait for it to index the workspace
+func indexedSymbols(client *lsp.Client, path string) ([]protocol.DocumentSymbolResult, bool) {
+	index := client.SymbolIndex()
+	if index == nil || client.ServerReady() {
+		return nil, false
+	}
+	return index.FileSymbols(path)
+}
+
+// findSymbolInFile finds the one symbol in a file matching symbolPath, dropping
+// leading qualifiers like ReadSymbol when the full path matches nothing. It is an
+// error for the path to match no symbol or several.
+func findSymbolInFile(ctx context.Context, client *lsp.Client, filePath string, symbolPath string) (*documentSymbols, documentSymbolMatch, error) {
+	wanted := splitSymbolPath(symbolPath)
+	if len(wanted) == 0 {
+		return nil, documentSymbolMatch{}, fmt.Errorf("symbol name is required")
+	}
+
+	doc, err := loadDocumentSymbols(ctx, client, fileuri.FromPath(filePath))
+	if err != nil {
+		return nil, documentSymbolMatch{}, err
+	}
+	if doc == nil {
+		return nil, documentSymbolMatch{}, fmt.Errorf("could not open %s", filePath)
+	}
+
+	var matches []documentSymbolMatch
+	for ; len(wanted) > 0 && len(matches) == 0; wanted = wanted[1:] {
+		matches = findDocumentSymbols(doc.symbols, wanted)
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, documentSymbolMatch{}, fmt.Errorf("%s not found in %s", symbolPath, filePath)
+	case 1:
+		if int(matches[0].rng.End.Line) >= len(doc.lines) {
+			return nil, documentSymbolMatch{}, fmt.Errorf("symbol range out of bounds: %v", matches[0].rng)
+		}
+		return doc, matches[0], nil
+	}
+
+	candidates := make([]string, len(matches))
+	for i, match := range matches {
+		candidates[i] = fmt.Sprintf("%s (%s, L%d)", match.name, protocol.TableKindMap[match.kind], match.rng.Start.Line+1)
+	}
+	return nil, documentSymbolMatch{}, fmt.Errorf("%s matches %d symbols, use a qualified path to choose one: %s",
+		symbolPath, len(matches), strings.Join(candidates, ", "))
+}
+
+// findDocumentSymbols returns the symbols whose qualified names end with wanted
+func findDocumentSymbols(symbols []protocol.DocumentSymbolResult, wanted []string) []documentSymbolMatch {
+	return matchDocumentSymbols(symbols, wanted, false)
+}
+
+// matchDocumentSymbols returns the symbols whose qualified names end with wanted,
+// ignoring case when fold is set
+func matchDocumentSymbols(symbols []protocol.DocumentSymbolResult, wanted []string, fold bool) []documentSymbolMatch {
+	var matches []documentSymbolMatch
+
+	var walk func(symbols []protocol.DocumentSymbolResult, parents []string)
+	walk = func(symbols []protocol.DocumentSymbolResult, parents []string) {
+		for _, sym := range symbols {
+			var kind protocol.SymbolKind
+			path := parents
+			var children []protocol.DocumentSymbolResult
+			selection := sym.GetRange()
+
+			switch v := sym.(type) {
+			case *protocol.DocumentSymbol:
+				kind = v.Kind
+				selection = v.SelectionRange
+				for i := range v.Children {
+					children = append(children, &v.Children[i])
+				}
+			case *protocol.SymbolInformation:
+				// Flat results only record their immediate container
+				kind = v.Kind
+				if v.ContainerName != "" {
+					path = splitSymbolPath(v.ContainerName)
+				}
+			}
+
+			// Names such as "(*Type).Method" carry their own qualifier
+			qualified := append(append([]string{}, path...), splitSymbolPath(sym.GetName())...)
+			if hasSuffix(qualified, wanted, fold) {
+				matches = append(matches, documentSymbolMatch{
+					name:      strings.Join(append(path[:len(path):len(path)], sym.GetName()), "."),
+					kind:      kind,
+					rng:       sym.GetRange(),
+					selection: selection,
+				})
+			}
+
+			if len(children) > 0 {
+				walk(children, qualified)
+			}
+		}
+	}
+	walk(symbols, nil)
+
+	return matches
+}
+
+// splitSymbolPath splits a qualified name like "pkg.Type.Method", "Class::method" or
+// "(*Type).Method" into its parts
+func splitSymbolPath(name string) []string {
+	name = strings.NewReplacer("::", ".", "(", "", ")", "", "*", "").Replace(name)
+
+	var parts []string
+	for _, part := range strings.Split(name, ".") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// hasSuffix reports whether path ends with suffix, ignoring case when fold is set
+func hasSuffix(path []string, suffix []string, fold bool) bool {
+	if len(suffix) > len(path) {
+		return false
+	}
+	offset := len(path) - len(suffix)
+	for i, part := range suffix {
+		if path[offset+i] != part && !(fold && strings.EqualFold(path[offset+i], part)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,274 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// ExtractKinds maps the kinds of refactoring the extract tool takes to code
+// action kinds. The empty kind lists every extract refactoring.
+var ExtractKinds = map[string]protocol.CodeActionKind{
+	"":         protocol.RefactorExtract,
+	"function": protocol.RefactorExtract + ".function",
+	"method":   protocol.RefactorExtract + ".method",
+	"variable": protocol.RefactorExtract + ".variable",
+	"constant": protocol.RefactorExtract + ".constant",
+}
+
+// refactorAction is a refactoring the server offers for a range, with the position
+// of the code action in the server's list, which is passed back to apply it
+type refactorAction struct {
+	index    int
+	title    string
+	kind     protocol.CodeActionKind
+	disabled string
+}
+
+// listRefactorings returns the refactorings of a kind or its sub-kinds the server
+// offers for a range. Servers may ignore the requested kind, so actions of other
+// kinds are left out, keeping the index of the others.
+func listRefactorings(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kind protocol.CodeActionKind) ([]refactorAction, error) {
+	actions, err := getCodeActions(ctx, client, filePath, startLine, startColumn, endLine, endColumn, string(kind))
+	if err != nil {
+		return nil, err
+	}
+
+	var refactorings []refactorAction
+	for i, item := range actions {
+		action, ok := item.Value.(protocol.CodeAction)
+		if !ok || !isKindOf(action.Kind, kind) {
+			continue
+		}
+		refactoring := refactorAction{index: i + 1, title: action.Title, kind: action.Kind}
+		if action.Disabled != nil {
+			refactoring.disabled = action.Disabled.Reason
+		}
+		refactorings = append(refactorings, refactoring)
+	}
+	return refactorings, nil
+}
+
+// isKindOf reports whether a code action kind is kind or one of its sub-kinds
+func isKindOf(actionKind, kind protocol.CodeActionKind) bool {
+	return actionKind == kind || strings.HasPrefix(string(actionKind), string(kind)+".")
+}
+
+// ListRefactorings lists the refactorings of a kind, such as refactor.extract,
+// the server offers for a range. Each is numbered so it can be passed to
+// ApplyExtract or ApplyInline.
+func ListRefactorings(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kind protocol.CodeActionKind) (string, error) {
+	refactorings, err := listRefactorings(ctx, client, filePath, startLine, startColumn, endLine, endColumn, kind)
+	if err != nil {
+		return "", err
+	}
+
+	if len(refactorings) == 0 {
+		return fmt.Sprintf("No %s refactorings available for %s L%d:C%d-L%d:C%d", kind, filePath, startLine, startColumn, endLine, endColumn), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s refactorings for %s L%d:C%d-L%d:C%d:\n\n", kind, filePath, startLine, startColumn, endLine, endColumn))
+	for _, refactoring := range refactorings {
+		output.WriteString(fmt.Sprintf("[%d] %s (%s)\n", refactoring.index, refactoring.title, refactoring.kind))
+		if refactoring.disabled != "" {
+			output.WriteString(fmt.Sprintf("    Disabled: %s\n", refactoring.disabled))
+		}
+	}
+	return output.String(), nil
+}
+
+// ApplyExtract applies the extract refactoring with the given index from
+// ListRefactorings for the same range and kind.
+//
+// Editors follow refactorings that introduce a symbol, such as extracting a
+// function, with a rename of the name the server made up. The new name is found
+// by comparing the identifiers of the file before and after the refactoring, and
+// with newName it is renamed right away. Without newName, the result tells where
+// the name is, to rename it with rename_symbol. Client-side follow-up commands,
+// such as editor.action.rename, are not sent to the server.
+func ApplyExtract(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kind protocol.CodeActionKind, index int, newName string, dryRun bool) (string, error) {
+	action, err := findRefactoring(ctx, client, filePath, startLine, startColumn, endLine, endColumn, kind, index)
+	if err != nil {
+		return "", err
+	}
+
+	if dryRun {
+		result, err := previewCodeAction(client, action.Title, action.Edit, action.Command)
+		if err != nil || newName == "" {
+			return result, err
+		}
+		return result + fmt.Sprintf("\nRenaming the new symbol to '%s' is not part of the preview.", newName), nil
+	}
+
+	before, err := client.ReadDocument(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	if err := performCodeAction(ctx, client, "extract", action); err != nil {
+		return "", err
+	}
+	result := fmt.Sprintf("Successfully applied refactoring: %s", action.Title)
+
+	after, err := client.ReadDocument(filePath)
+	if err != nil {
+		toolsLogger.Error("Error reading file after refactoring: %v", err)
+		return result, nil
+	}
+	name, line, column, found := newIdentifier(before, after)
+	if !found {
+		return result, nil
+	}
+	if newName == name {
+		return result + fmt.Sprintf("\nThe new symbol is named '%s' at L%d:C%d.", name, line, column), nil
+	}
+	if newName == "" {
+		return result + fmt.Sprintf("\nThe new symbol is named '%s' at L%d:C%d. Pass newName, or use rename_symbol at that position, to choose another name.", name, line, column), nil
+	}
+
+	renamed, err := RenameSymbol(ctx, client, filePath, line, column, newName, false)
+	if err != nil {
+		return "", fmt.Errorf("applied refactoring %q, but failed to rename the new symbol '%s' at L%d:C%d: %v", action.Title, name, line, column, err)
+	}
+	return result + "\n" + renamed, nil
+}
+
+// findRefactoring returns the refactoring of a kind with the given index from
+// listRefactorings, without any client-side follow-up command
+func findRefactoring(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, kind protocol.CodeActionKind, index int) (protocol.CodeAction, error) {
+	action, err := findCodeAction(ctx, client, filePath, startLine, startColumn, endLine, endColumn, string(kind), index)
+	if err != nil {
+		return protocol.CodeAction{}, err
+	}
+	if action.Kind != "" && !isKindOf(action.Kind, kind) {
+		return protocol.CodeAction{}, fmt.Errorf("code action %d is %s, not a %s refactoring", index, action.Kind, kind)
+	}
+	if action.Command != nil && isClientCommand(action.Command.Command) {
+		action.Command = nil
+		if action.Edit == nil {
+			return protocol.CodeAction{}, fmt.Errorf("refactoring %q only runs an editor command", action.Title)
+		}
+	}
+	return action, nil
+}
+
+// ApplyInline applies the inline refactoring with the given index from
+// ListRefactorings for the same range, then reports the diagnostics of every file
+// it changed. Inlining a function or variable rewrites each of its uses, which
+// can be in many files, and the server may make the edit through a command, so
+// the changed files are taken from the edit journal.
+func ApplyInline(ctx context.Context, client *lsp.Client, filePath string, startLine, startColumn, endLine, endColumn int, index int, dryRun bool) (string, error) {
+	action, err := findRefactoring(ctx, client, filePath, startLine, startColumn, endLine, endColumn, protocol.RefactorInline, index)
+	if err != nil {
+		return "", err
+	}
+
+	if dryRun {
+		return previewCodeAction(client, action.Title, action.Edit, action.Command)
+	}
+
+	lastEntry := 0
+	if entries := utilities.EditJournal.Entries(); len(entries) > 0 {
+		lastEntry = entries[len(entries)-1].ID
+	}
+	if err := performCodeAction(ctx, client, "inline", action); err != nil {
+		return "", err
+	}
+
+	files := filesChangedSince(lastEntry)
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Successfully applied refactoring: %s\n", action.Title))
+	if len(files) == 0 {
+		output.WriteString("No files were changed.\n")
+		return output.String(), nil
+	}
+	output.WriteString(fmt.Sprintf("Changed %d files:\n", len(files)))
+	for _, path := range files {
+		output.WriteString(path + "\n")
+	}
+
+	skipped := 0
+	if len(files) > maxChangedFiles {
+		skipped = len(files) - maxChangedFiles
+		files = files[:maxChangedFiles]
+	}
+	for _, path := range files {
+		// Deleted files have no diagnostics
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		diagnostics, err := GetDiagnosticsForFile(ctx, client, path, 0, true, DiagnosticFilter{}, false)
+		if err != nil {
+			toolsLogger.Error("Error getting diagnostics after inlining: %v", err)
+			continue
+		}
+		output.WriteString("\n" + diagnostics + "\n")
+	}
+	if skipped > 0 {
+		output.WriteString(fmt.Sprintf("\nDiagnostics of %d more files were not checked.\n", skipped))
+	}
+	return output.String(), nil
+}
+
+// filesChangedSince lists the files changed by the edits recorded in the journal
+// after the entry with the given id, sorted
+func filesChangedSince(id int) []string {
+	var files []string
+	for _, entry := range utilities.EditJournal.Entries() {
+		if entry.ID <= id {
+			continue
+		}
+		for _, path := range entry.Files() {
+			if !slices.Contains(files, path) {
+				files = append(files, path)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// isClientCommand reports whether a command is one editors run themselves, such
+// as the rename that follows extracting a function
+func isClientCommand(command string) bool {
+	return strings.HasPrefix(command, "editor.action.")
+}
+
+var identifierPattern = regexp.MustCompile(`[\p{L}_][\p{L}\p{N}_]*`)
+
+// newIdentifier finds the first identifier of after that before does not have,
+// returning its 1-indexed line and column
+func newIdentifier(before, after []byte) (string, int, int, bool) {
+	existing := make(map[string]bool)
+	for _, name := range identifierPattern.FindAll(before, -1) {
+		existing[string(name)] = true
+	}
+	for _, loc := range identifierPattern.FindAllIndex(after, -1) {
+		name := string(after[loc[0]:loc[1]])
+		if existing[name] {
+			continue
+		}
+		// Identifiers are only matched at word boundaries
+		if loc[0] > 0 {
+			if r, _ := utf8.DecodeLastRune(after[:loc[0]]); unicode.IsDigit(r) {
+				continue
+			}
+		}
+		prefix := after[:loc[0]]
+		line := strings.Count(string(prefix), "\n") + 1
+		lineStart := strings.LastIndex(string(prefix), "\n") + 1
+		column := utf8.RuneCount(prefix[lineStart:]) + 1
+		return name, line, column, true
+	}
+	return "", 0, 0, false
+}
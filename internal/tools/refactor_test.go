@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIdentifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+		line   int
+		column int
+		found  bool
+	}{
+		{
+			name:   "extract variable",
+			before: "func f(a, b int) int {\n\treturn a + b\n}\n",
+			after:  "func f(a, b int) int {\n\tx := a + b\n\treturn x\n}\n",
+			want:   "x",
+			line:   2,
+			column: 2,
+			found:  true,
+		},
+		{
+			name:   "extract function",
+			before: "func f() {\n\tprintln(\"é\", 1)\n}\n",
+			after:  "func f() {\n\té := 0; newFunction()\n}\n\nfunc newFunction() {\n\tprintln(\"é\", 1)\n}\n",
+			want:   "newFunction",
+			line:   2,
+			column: 10,
+			found:  true,
+		},
+		{
+			name:   "number literals are not identifiers",
+			before: "x := 1\n",
+			after:  "x := 0x1f\n",
+			found:  false,
+		},
+		{
+			name:   "nothing new",
+			before: "a := b\n",
+			after:  "b := a\n",
+			found:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, line, column, found := newIdentifier([]byte(tt.before), []byte(tt.after))
+			assert.Equal(t, tt.found, found)
+			if tt.found {
+				assert.Equal(t, tt.want, name)
+				assert.Equal(t, tt.line, line)
+				assert.Equal(t, tt.column, column)
+			}
+		})
+	}
+}
+
+func TestIsKindOf(t *testing.T) {
+	assert.True(t, isKindOf("refactor.extract.function", protocol.RefactorExtract))
+	assert.True(t, isKindOf("refactor.extract", protocol.RefactorExtract))
+	assert.False(t, isKindOf("refactor.extractor", protocol.RefactorExtract))
+	assert.False(t, isKindOf("refactor.inline", protocol.RefactorExtract))
+}
+
+func TestFilesChangedSince(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("package a\n"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("package b\n"), 0o644))
+
+	edit := func(paths ...string) {
+		entry := utilities.EditJournal.Begin("test")
+		entry.Capture(paths...)
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, append(content, "// edited\n"...), 0o644))
+		}
+		entry.Commit()
+	}
+
+	edit(a)
+	entries := utilities.EditJournal.Entries()
+	last := entries[len(entries)-1].ID
+
+	// An edit of the inlined file, then edits the server made through a command
+	edit(b)
+	edit(b, a)
+	assert.Equal(t, []string{a, b}, filesChangedSince(last))
+	assert.Empty(t, filesChangedSince(last+2))
+}
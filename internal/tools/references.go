@@ -4,15 +4,154 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
+// ReferenceOptions controls which references are returned and how they are shown
+type ReferenceOptions struct {
+	// Lines of context shown before and after each reference. Negative values use
+	// the LSP_CONTEXT_LINES environment variable, which defaults to 5.
+	ContextBefore int
+	ContextAfter  int
+
+	// GroupByFile shows references grouped under a header per file. Otherwise each
+	// reference is listed on its own with its context.
+	GroupByFile bool
+
+	// IncludeDeclaration also returns the symbol's declaration
+	IncludeDeclaration bool
+
+	// Limit is the maximum number of references to return, 0 for all of them.
+	// Cursor continues from where a previous, limited call stopped.
+	Limit  int
+	Cursor string
+
+	// TextFallback searches the workspace for the identifier when the server
+	// finds no references, as it may not while it is still indexing. The lines
+	// found are returned as textual matches the server has not verified.
+	TextFallback bool
+}
+
+// DefaultReferenceOptions returns all references except the declaration, grouped by file
+func DefaultReferenceOptions() ReferenceOptions {
+	return ReferenceOptions{
+		ContextBefore: -1,
+		ContextAfter:  -1,
+		GroupByFile:   true,
+	}
+}
+
 func FindReferences(ctx context.Context, client *lsp.Client, symbolName string) (string, error) {
+	return FindReferencesWithOptions(ctx, client, symbolName, DefaultReferenceOptions())
+}
+
+// FindReferencesWithOptions finds references to a symbol, shown according to opts
+func FindReferencesWithOptions(ctx context.Context, client *lsp.Client, symbolName string, opts ReferenceOptions) (string, error) {
+	offset, err := parseCursor(opts.Cursor)
+	if err != nil {
+		return "", err
+	}
+
+	// Pages and flat lists need a stable order
+	sorted := opts.Limit > 0 || offset > 0 || !opts.GroupByFile
+	refsBySymbol, err := findReferences(ctx, client, symbolName, opts.IncludeDeclaration, sorted)
+	if err != nil {
+		return "", err
+	}
+
+	groups := make([]referenceGroup, 0, len(refsBySymbol))
+	for _, refs := range refsBySymbol {
+		groups = append(groups, referenceGroup{client: client, refs: refs})
+	}
+	if opts.TextFallback && countReferences(groups) == 0 {
+		groups, err = textualReferenceGroups(ctx, client, symbolName)
+		if err != nil {
+			return "", err
+		}
+	}
+	return formatReferences(ctx, symbolName, groups, opts, offset)
+}
+
+// referenceGroup is the references a server found to one of the symbols matching
+// a name. A heading, when set, is shown before the group, and label names the
+// references in the header of each file.
+type referenceGroup struct {
+	client  *lsp.Client
+	refs    []protocol.Location
+	heading string
+	label   string
+}
+
+// countReferences returns the number of references in all the groups
+func countReferences(groups []referenceGroup) int {
+	total := 0
+	for _, group := range groups {
+		total += len(group.refs)
+	}
+	return total
+}
+
+// maxTextualReferences bounds how many lines textual matches are searched for
+const maxTextualReferences = 200
+
+// textualMatchesLabel marks references found by searching for the identifier
+const textualMatchesLabel = "Textual matches (unverified)"
+
+// textualReferences searches the workspace for the identifier a symbol name ends
+// with, as a whole word
+func textualReferences(ctx context.Context, client *lsp.Client, symbolName string) ([]protocol.Location, error) {
+	identifier := symbolName
+	if i := strings.LastIndexAny(identifier, ".:/#"); i >= 0 {
+		identifier = identifier[i+1:]
+	}
+	if identifier == "" {
+		return nil, nil
+	}
+
+	result, err := CollectGrep(ctx, client, `\b`+regexp.QuoteMeta(identifier)+`\b`, GrepOptions{Limit: maxTextualReferences})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for %s: %v", identifier, err)
+	}
+	length := utilities.CharacterLength(identifier, client.PositionEncoding())
+	locations := make([]protocol.Location, 0, len(result.Matches))
+	for _, match := range result.Matches {
+		end := match.position
+		end.Character += length
+		locations = append(locations, protocol.Location{
+			URI:   fileuri.FromPath(match.Path),
+			Range: protocol.Range{Start: match.position, End: end},
+		})
+	}
+	sortLocations(locations)
+	return locations, nil
+}
+
+// textualReferenceGroups returns the textual matches of a symbol's identifier as
+// a single group, headed by a warning that the server has not verified them
+func textualReferenceGroups(ctx context.Context, client *lsp.Client, symbolName string) ([]referenceGroup, error) {
+	refs, err := textualReferences(ctx, client, symbolName)
+	if err != nil || len(refs) == 0 {
+		return nil, err
+	}
+	return []referenceGroup{{
+		client:  client,
+		refs:    refs,
+		heading: fmt.Sprintf("No references found by the language server. %s of the identifier, which may include unrelated uses of the same name:\n", textualMatchesLabel),
+		label:   textualMatchesLabel,
+	}}, nil
+}
+
+// formatReferences shows the page of references starting at offset, keeping each
+// group together
+func formatReferences(ctx context.Context, symbolName string, groups []referenceGroup, opts ReferenceOptions, offset int) (string, error) {
 	// Get context lines from environment variable
 	contextLines := 5
 	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
@@ -20,21 +159,80 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string)
 			contextLines = val
 		}
 	}
+	if opts.ContextBefore < 0 {
+		opts.ContextBefore = contextLines
+	}
+	if opts.ContextAfter < 0 {
+		opts.ContextAfter = contextLines
+	}
+
+	total := countReferences(groups)
+	if total == 0 {
+		return fmt.Sprintf("No references found for symbol: %s", symbolName), nil
+	}
+	if offset >= total {
+		return "", errCursorPastEnd(offset, total)
+	}
+
+	end := total
+	if opts.Limit > 0 && offset+opts.Limit < total {
+		end = offset + opts.Limit
+	}
+
+	// Format the requested page, keeping each group's references together
+	var allReferences []string
+	start := 0
+	for _, group := range groups {
+		lo, hi := max(offset-start, 0), min(end-start, len(group.refs))
+		start += len(group.refs)
+		if lo >= hi {
+			continue
+		}
+
+		page := group.refs[lo:hi]
+		if group.heading != "" {
+			allReferences = append(allReferences, group.heading)
+		}
+		label := group.label
+		if label == "" {
+			label = "References"
+		}
+		if opts.GroupByFile {
+			allReferences = append(allReferences, formatLocationsByFileWithContext(ctx, group.client, page, opts.ContextBefore, opts.ContextAfter, label)...)
+		} else {
+			allReferences = append(allReferences, formatLocationsFlat(group.client, page, opts.ContextBefore, opts.ContextAfter)...)
+		}
+	}
 
+	text := strings.Join(allReferences, "\n")
+	if offset > 0 || end < total {
+		text += fmt.Sprintf("\n---\n\nShowing references %d-%d of %d.", offset+1, end, total)
+		if end < total {
+			text += fmt.Sprintf(" Pass cursor %q to get the next page.", strconv.Itoa(end))
+		}
+	}
+	return text, nil
+}
+
+// findReferences returns the references to each symbol matching symbolName, in the
+// order the symbols were found. With sorted, the references of each symbol are
+// ordered by file and position.
+func findReferences(ctx context.Context, client *lsp.Client, symbolName string, includeDeclaration, sorted bool) ([][]protocol.Location, error) {
 	// First get the symbol location like ReadDefinition does
 	symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{
 		Query: symbolName,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch symbol: %v", err)
+		return nil, fmt.Errorf("failed to fetch symbol: %v", err)
 	}
 
 	results, err := symbolResult.Results()
 	if err != nil {
-		return "", fmt.Errorf("failed to parse results: %v", err)
+		return nil, fmt.Errorf("failed to parse results: %v", err)
 	}
 
-	var allReferences []string
+	// References of each matching symbol, in the order the symbols were found
+	var refsBySymbol [][]protocol.Location
 	for _, symbol := range results {
 		// Handle different matching strategies based on the search term
 		if strings.Contains(symbolName, ".") {
@@ -63,7 +261,7 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string)
 				Position: loc.Range.Start,
 			},
 			Context: protocol.ReferenceContext{
-				IncludeDeclaration: false,
+				IncludeDeclaration: includeDeclaration,
 			},
 		}
 		// File is likely to be opened already, but may not be.
@@ -74,78 +272,101 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string)
 		}
 		refs, err := client.References(ctx, refsParams)
 		if err != nil {
-			return "", fmt.Errorf("failed to get references: %v", err)
+			return nil, fmt.Errorf("failed to get references: %v", err)
 		}
 
-		// Group references by file
-		refsByFile := make(map[protocol.DocumentUri][]protocol.Location)
-		for _, ref := range refs {
-			refsByFile[ref.URI] = append(refsByFile[ref.URI], ref)
+		if sorted {
+			sortLocations(refs)
 		}
+		refsBySymbol = append(refsBySymbol, refs)
+	}
 
-		// Get sorted list of URIs
-		uris := make([]string, 0, len(refsByFile))
-		for uri := range refsByFile {
-			uris = append(uris, string(uri))
+	if len(refsBySymbol) == 0 {
+		match, found, err := resolveFallback(ctx, client, symbolName)
+		if err != nil || !found {
+			return nil, err
+		}
+		refs, err := client.References(ctx, protocol.ReferenceParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: match.uri},
+				Position:     match.position,
+			},
+			Context: protocol.ReferenceContext{IncludeDeclaration: includeDeclaration},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get references: %v", err)
+		}
+		if sorted {
+			sortLocations(refs)
 		}
-		sort.Strings(uris)
+		refsBySymbol = append(refsBySymbol, refs)
+	}
+	return refsBySymbol, nil
+}
 
-		// Process each file's references in sorted order
-		for _, uriStr := range uris {
-			uri := protocol.DocumentUri(uriStr)
-			fileRefs := refsByFile[uri]
-			filePath := strings.TrimPrefix(uriStr, "file://")
+// parseCursor returns the offset a cursor from a previous page continues from
+func parseCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(cursor)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	return offset, nil
+}
 
-			// Format file header
-			fileInfo := fmt.Sprintf("---\n\n%s\nReferences in File: %d\n",
-				filePath,
-				len(fileRefs),
-			)
+// errCursorPastEnd is the error for a cursor beyond the last reference
+func errCursorPastEnd(offset, total int) error {
+	return fmt.Errorf("cursor %d is past the last of %d references", offset, total)
+}
 
-			// Format locations with context
-			fileContent, err := os.ReadFile(filePath)
-			if err != nil {
-				// Log error but continue with other files
-				allReferences = append(allReferences, fileInfo+"\nError reading file: "+err.Error())
-				continue
-			}
+// sortLocations orders locations by file and position
+func sortLocations(locations []protocol.Location) {
+	sort.SliceStable(locations, func(i, j int) bool {
+		a, b := locations[i], locations[j]
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		if a.Range.Start.Line != b.Range.Start.Line {
+			return a.Range.Start.Line < b.Range.Start.Line
+		}
+		return a.Range.Start.Character < b.Range.Start.Character
+	})
+}
 
-			lines := strings.Split(string(fileContent), "\n")
+// formatLocationsFlat renders each location on its own, with the given number of
+// lines around it
+func formatLocationsFlat(client *lsp.Client, locations []protocol.Location, contextBefore int, contextAfter int) []string {
+	var formatted []string
+	fileLines := make(map[string][]string)
+	positions := newDocumentLines(client)
 
-			// Track reference locations for header display
-			var locStrings []string
-			for _, ref := range fileRefs {
-				locStr := fmt.Sprintf("L%d:C%d",
-					ref.Range.Start.Line+1,
-					ref.Range.Start.Character+1)
-				locStrings = append(locStrings, locStr)
-			}
+	for _, loc := range locations {
+		filePath := fileuri.ToPath(loc.URI)
+		header := fmt.Sprintf("---\n\n%s:L%d:C%d\n", filePath, loc.Range.Start.Line+1, positions.column(loc.URI, loc.Range.Start))
 
-			// Collect lines to display using the utility function
-			linesToShow, err := GetLineRangesToDisplay(ctx, client, fileRefs, len(lines), contextLines)
+		lines, ok := fileLines[filePath]
+		if !ok {
+			content, err := client.ReadDocument(filePath)
 			if err != nil {
-				// Log error but continue with other files
+				formatted = append(formatted, header+"\nError reading file: "+err.Error())
 				continue
 			}
+			lines = strings.Split(string(content), "\n")
+			fileLines[filePath] = lines
+		}
 
-			// Convert to line ranges using the utility function
-			lineRanges := ConvertLinesToRanges(linesToShow, len(lines))
-
-			// Format with locations in header
-			formattedOutput := fileInfo
-			if len(locStrings) > 0 {
-				formattedOutput += "At: " + strings.Join(locStrings, ", ") + "\n"
+		refLine := int(loc.Range.Start.Line)
+		linesToShow := make(map[int]bool)
+		for i := refLine - contextBefore; i <= refLine+contextAfter; i++ {
+			if i >= 0 && i < len(lines) {
+				linesToShow[i] = true
 			}
-
-			// Format the content with ranges
-			formattedOutput += "\n" + FormatLinesWithRanges(lines, lineRanges)
-			allReferences = append(allReferences, formattedOutput)
 		}
-	}
 
-	if len(allReferences) == 0 {
-		return fmt.Sprintf("No references found for symbol: %s", symbolName), nil
+		formatted = append(formatted, header+"\n"+FormatLinesWithRanges(lines, ConvertLinesToRanges(linesToShow, len(lines))))
 	}
 
-	return strings.Join(allReferences, "\n"), nil
+	return formatted
 }
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextualReferences(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.go"), []byte("package a\n\nfunc OpenFile() {}\n\nfunc OpenFiles() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.go"), []byte("package a\n\nfunc use() {\n\tOpenFile()\n}\n"), 0o644))
+
+	ctx := context.Background()
+	client := lsp.NewPlainTextClient()
+	_, err := client.InitializeLSPClient(ctx, []string{root}, nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	refs, err := textualReferences(ctx, client, "lsp.Client.OpenFile")
+	require.NoError(t, err)
+	require.Len(t, refs, 2, "whole words only")
+	assert.Equal(t, uint32(2), refs[0].Range.Start.Line)
+	assert.Equal(t, uint32(5), refs[0].Range.Start.Character)
+	assert.Equal(t, uint32(13), refs[0].Range.End.Character)
+	assert.Equal(t, uint32(3), refs[1].Range.Start.Line)
+
+	groups, err := textualReferenceGroups(ctx, client, "OpenFile")
+	require.NoError(t, err)
+	opts := DefaultReferenceOptions()
+	opts.ContextBefore, opts.ContextAfter = 0, 0
+	text, err := formatReferences(ctx, "OpenFile", groups, opts, 0)
+	require.NoError(t, err)
+	assert.Contains(t, text, "No references found by the language server. Textual matches (unverified) of the identifier")
+	assert.Contains(t, text, "Textual matches (unverified) in File: 1")
+}
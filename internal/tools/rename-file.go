@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// RenameFile renames or moves a file or directory. Servers that support it are
+// asked for edits to make first, such as updating import paths, and are told
+// about the rename once it is done. With dryRun the rename and the edits are
+// returned as a diff instead of being applied.
+func RenameFile(ctx context.Context, client *lsp.Client, oldPath, newPath string, dryRun bool) (string, error) {
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		return "", fmt.Errorf("could not stat %s: %v", oldPath, err)
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return "", fmt.Errorf("%s already exists", newPath)
+	}
+	isDir := info.IsDir()
+
+	params := protocol.RenameFilesParams{
+		Files: []protocol.FileRename{
+			{
+				OldURI: string(fileuri.FromPath(oldPath)),
+				NewURI: string(fileuri.FromPath(newPath)),
+			},
+		},
+	}
+
+	// Ask the server for the edits the rename needs before moving anything
+	var edit protocol.WorkspaceEdit
+	if client.HandlesFileOperation(lsp.WillRename, oldPath, isDir) {
+		if !isDir {
+			// Servers such as typescript-language-server only know about open files' projects
+			if err := client.OpenFile(ctx, oldPath); err != nil {
+				toolsLogger.Debug("Could not open %s before renaming: %v", oldPath, err)
+			}
+		}
+
+		edit, err = client.WillRenameFiles(ctx, params)
+		if err != nil {
+			return "", fmt.Errorf("failed to get edits for the rename: %v", err)
+		}
+	}
+
+	if dryRun {
+		// Edits are made before the move, so preview them followed by the rename itself
+		preview := edit
+		preview.DocumentChanges = append(append([]protocol.DocumentChange{}, edit.DocumentChanges...),
+			protocol.DocumentChange{RenameFile: &protocol.RenameFile{Kind: "rename", OldURI: protocol.DocumentUri(params.Files[0].OldURI), NewURI: protocol.DocumentUri(params.Files[0].NewURI)}})
+		return previewEdit(client, fmt.Sprintf("Renaming %s to %s would update %d files.", oldPath, newPath, len(editedFiles(edit))), preview)
+	}
+
+	entry := utilities.EditJournal.Begin(fmt.Sprintf("rename_file %s to %s", oldPath, newPath))
+	entry.CaptureEdit(edit)
+	entry.Capture(oldPath, newPath)
+	// Commit even if the rename fails, so the edits already made can be undone
+	defer entry.Commit()
+
+	if err := utilities.ApplyWorkspaceEdit(edit, client.PositionEncoding()); err != nil {
+		return "", fmt.Errorf("failed to apply changes: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return "", fmt.Errorf("failed to rename: %v", err)
+	}
+	client.RenameOpenFiles(ctx, oldPath, newPath)
+
+	if client.HandlesFileOperation(lsp.DidRename, newPath, isDir) {
+		if err := client.DidRenameFiles(ctx, params); err != nil {
+			toolsLogger.Error("Failed to notify the server of the rename: %v", err)
+		}
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Renamed %s to %s\n", oldPath, newPath))
+
+	edited := editedFiles(edit)
+	if len(edited) > 0 {
+		output.WriteString(fmt.Sprintf("Updated %d files:\n", len(edited)))
+		for _, path := range edited {
+			// Edits to the renamed file itself were made before it moved
+			if path == oldPath || strings.HasPrefix(path, oldPath+string(filepath.Separator)) {
+				path = newPath + strings.TrimPrefix(path, oldPath)
+			}
+			output.WriteString(path + "\n")
+		}
+	}
+
+	return output.String(), nil
+}
+
+// editedFiles lists the files a workspace edit changes, sorted
+func editedFiles(edit protocol.WorkspaceEdit) []string {
+	seen := make(map[string]bool)
+	for uri := range edit.Changes {
+		seen[fileuri.ToPath(uri)] = true
+	}
+	for _, change := range edit.DocumentChanges {
+		if change.TextDocumentEdit != nil {
+			seen[fileuri.ToPath(change.TextDocumentEdit.TextDocument.URI)] = true
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+	for path := range seen {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files
+}
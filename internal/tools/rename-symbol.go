@@ -6,14 +6,16 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
 // RenameSymbol renames a symbol (variable, function, class, etc.) at the specified position
-// It uses the LSP rename functionality to handle all references across files
-func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line, column int, newName string) (string, error) {
+// It uses the LSP rename functionality to handle all references across files.
+// With dryRun the changes are returned as a diff instead of being applied.
+func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line, column int, newName string, dryRun bool) (string, error) {
 	// Open the file if not already open
 	err := client.OpenFile(ctx, filePath)
 	if err != nil {
@@ -21,11 +23,8 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 	}
 
 	// Convert 1-indexed line/column to 0-indexed for LSP protocol
-	uri := protocol.DocumentUri("file://" + filePath)
-	position := protocol.Position{
-		Line:      uint32(line - 1),
-		Character: uint32(column - 1),
-	}
+	uri := fileuri.FromPath(filePath)
+	position := toPosition(client, filePath, line, column)
 
 	// Create the rename parameters
 	params := protocol.RenameParams{
@@ -49,8 +48,9 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 	changeCount := 0
 	fileCount := 0
 
-	// Build output
+	// Build output, with columns read from the files before the rename
 	var locationsBuilder strings.Builder
+	lines := newDocumentLines(client)
 
 	// Create a slice to store all changes before sorting and writing
 	type FileChanges struct {
@@ -67,7 +67,7 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 			var locs strings.Builder
 			for i, change := range edits {
 				locs.WriteString(
-					fmt.Sprintf("L%d:C%d", change.Range.Start.Line+1, change.Range.Start.Character+1),
+					fmt.Sprintf("L%d:C%d", change.Range.Start.Line+1, lines.column(uri, change.Range.Start)),
 				)
 				if i != len(edits)-1 {
 					locs.WriteString(", ")
@@ -85,9 +85,10 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 		if change.TextDocumentEdit != nil {
 			var locs strings.Builder
 			for i, edit := range change.TextDocumentEdit.Edits {
-				textEdit, err := edit.AsTextEdit()
+				textEdit, err := utilities.TextEditOf(edit)
 				if err == nil {
-					locs.WriteString(fmt.Sprintf("L%d:C%d", textEdit.Range.Start.Line+1, textEdit.Range.Start.Character+1))
+					locs.WriteString(fmt.Sprintf("L%d:C%d", textEdit.Range.Start.Line+1,
+						lines.column(change.TextDocumentEdit.TextDocument.URI, textEdit.Range.Start)))
 					if i != len(change.TextDocumentEdit.Edits)-1 {
 						locs.WriteString(", ")
 					}
@@ -112,10 +113,21 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 		locationsBuilder.WriteString(fmt.Sprintf("%s: %s\n", change.URI, change.Locations))
 	}
 
+	if dryRun {
+		if fileCount == 0 || changeCount == 0 {
+			return "Failed to rename symbol. 0 occurrences found.", nil
+		}
+		return previewEdit(client, fmt.Sprintf("Renaming the symbol to '%s' would update %d occurrences across %d files.", newName, changeCount, fileCount), workspaceEdit)
+	}
+
 	// Apply the workspace edit to files:workspaceEdit
-	if err := utilities.ApplyWorkspaceEdit(workspaceEdit); err != nil {
+	entry := utilities.EditJournal.Begin(fmt.Sprintf("rename_symbol to '%s'", newName))
+	entry.CaptureEdit(workspaceEdit)
+	if err := client.ApplyWorkspaceEdit(workspaceEdit); err != nil {
 		return "", fmt.Errorf("failed to apply changes: %v", err)
 	}
+	syncEditedFiles(ctx, client, workspaceEdit)
+	entry.Commit()
 
 	if fileCount == 0 || changeCount == 0 {
 		return "Failed to rename symbol. 0 occurrences found.", nil
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// ReplaceSymbolBody replaces the whole definition of a symbol, found by name in
+// a file's document symbols, with newSource and returns the file's diagnostics.
+// newSource should be the complete definition, including its signature. With
+// dryRun the change is returned as a diff instead of being applied.
+func ReplaceSymbolBody(ctx context.Context, client *lsp.Client, filePath string, symbolPath string, newSource string, dryRun bool) (string, error) {
+	if err := checkNotStaged(client, filePath); err != nil {
+		return "", err
+	}
+
+	doc, match, err := findSymbolInFile(ctx, client, filePath, symbolPath)
+	if err != nil {
+		return "", err
+	}
+
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			doc.uri: {
+				{
+					Range:   match.rng,
+					NewText: newSource,
+				},
+			},
+		},
+	}
+	if dryRun {
+		return previewEdit(client, fmt.Sprintf("Replacing %s %s at L%d-L%d.",
+			protocol.TableKindMap[match.kind], match.name, match.rng.Start.Line+1, match.rng.End.Line+1), edit)
+	}
+	entry := utilities.EditJournal.Begin(fmt.Sprintf("replace_symbol_body %s in %s", match.name, filePath))
+	entry.Capture(filePath)
+	if err := utilities.ApplyWorkspaceEdit(edit, client.PositionEncoding()); err != nil {
+		return "", fmt.Errorf("failed to replace %s: %v", match.name, err)
+	}
+
+	// Send the new content to the server so diagnostics and later requests see it
+	if err := client.NotifyChange(ctx, filePath); err != nil {
+		toolsLogger.Error("Error notifying change: %v", err)
+	}
+	preSaved := saveEditedFile(ctx, client, filePath)
+	entry.Commit()
+
+	startLine := int(match.rng.Start.Line) + 1
+	endLine := startLine + strings.Count(newSource, "\n")
+	result := fmt.Sprintf("Replaced %s %s, which was L%d-L%d and is now L%d-L%d.",
+		protocol.TableKindMap[match.kind], match.name,
+		startLine, match.rng.End.Line+1, startLine, endLine)
+	if preSaved {
+		result += preSaveNote
+	}
+
+	diagnostics, err := GetDiagnosticsForFile(ctx, client, filePath, 0, true, DiagnosticFilter{}, false)
+	if err != nil {
+		toolsLogger.Error("Error getting diagnostics after edit: %v", err)
+		return result, nil
+	}
+
+	return result + "\n\n" + diagnostics, nil
+}
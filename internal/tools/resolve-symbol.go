@@ -0,0 +1,329 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// defaultResolveLimit bounds how many symbols a name resolves to
+const defaultResolveLimit = 10
+
+// ErrSymbolNotFound is returned when a name resolves to no symbol
+var ErrSymbolNotFound = errors.New("symbol not found")
+
+// SymbolMatch is a symbol a qualified name resolved to. Line and Column are the
+// position of the symbol's name, where tools that take a position should point.
+type SymbolMatch struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Location
+	// Exact is false when qualifiers of the name were ignored, or matched only
+	// when ignoring case
+	Exact bool `json:"exact"`
+
+	// The symbol's name as an LSP position, and its rank, lower being better
+	uri      protocol.DocumentUri
+	position protocol.Position
+	rank     resolveRank
+}
+
+// resolveRank orders the symbols a name resolves to: names that matched in full
+// come first, then those matching with case, then files whose directory matches
+// more of the path qualifier
+type resolveRank struct {
+	ignoredQualifiers int
+	folded            bool
+	pathMatch         int
+}
+
+func (r resolveRank) less(other resolveRank) bool {
+	if r.ignoredQualifiers != other.ignoredQualifiers {
+		return r.ignoredQualifiers < other.ignoredQualifiers
+	}
+	if r.folded != other.folded {
+		return !r.folded
+	}
+	return r.pathMatch > other.pathMatch
+}
+
+// SymbolResolution is the symbols a qualified name resolved to, best first
+type SymbolResolution struct {
+	Query   string        `json:"query"`
+	Matches []SymbolMatch `json:"matches"`
+}
+
+// CollectResolveSymbol finds the symbols a qualified name such as
+// "internal/lsp.Client.OpenFile", "lsp.Client.OpenFile", "Client.OpenFile" or
+// "Namespace::Class::method" refers to. Candidate files come from workspace/symbol,
+// searched by the last part of the name, and the symbol is then located precisely
+// with each file's document symbols. A slash separated prefix narrows the files
+// to those in a matching directory. Leading qualifiers that match no symbol, such
+// as a package name, and differences in case are tolerated, and ranked below
+// exact matches.
+func CollectResolveSymbol(ctx context.Context, client *lsp.Client, name string, limit int) (SymbolResolution, error) {
+	if limit <= 0 {
+		limit = defaultResolveLimit
+	}
+	resolution := SymbolResolution{Query: name, Matches: []SymbolMatch{}}
+
+	dirQualifier, wanted := parseQualifiedName(name)
+	if len(wanted) == 0 {
+		return resolution, fmt.Errorf("symbol name is required")
+	}
+	last := wanted[len(wanted)-1]
+
+	symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: last})
+	if err != nil {
+		return resolution, fmt.Errorf("failed to fetch symbol: %v", err)
+	}
+	results, err := symbolResult.Results()
+	if err != nil {
+		return resolution, fmt.Errorf("failed to parse results: %v", err)
+	}
+
+	// Files with a symbol named like the last part, preferring those in a directory
+	// matching the qualifier when there are any
+	seen := make(map[protocol.DocumentUri]bool)
+	var uris, qualifiedURIs []protocol.DocumentUri
+	for _, symbol := range results {
+		parts := splitSymbolPath(symbol.GetName())
+		if len(parts) == 0 || !strings.EqualFold(parts[len(parts)-1], last) {
+			continue
+		}
+		uri := symbol.GetLocation().URI
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		uris = append(uris, uri)
+		if dirQualifier != "" && pathMatch(fileuri.ToPath(uri), dirQualifier) > 0 {
+			qualifiedURIs = append(qualifiedURIs, uri)
+		}
+	}
+	qualifierIgnored := dirQualifier != "" && len(qualifiedURIs) == 0
+	if len(qualifiedURIs) > 0 {
+		uris = qualifiedURIs
+	}
+
+	for _, uri := range uris {
+		doc, err := loadDocumentSymbols(ctx, client, uri)
+		if err != nil {
+			return resolution, err
+		}
+		if doc == nil {
+			continue
+		}
+		path := fileuri.ToPath(uri)
+		for _, match := range resolveInDocument(doc, path, wanted) {
+			match.rank.pathMatch = pathMatch(path, dirQualifier)
+			if qualifierIgnored {
+				match.rank.ignoredQualifiers++
+			}
+			match.Exact = match.rank.ignoredQualifiers == 0 && !match.rank.folded
+			resolution.Matches = append(resolution.Matches, match)
+		}
+	}
+
+	sort.SliceStable(resolution.Matches, func(i, j int) bool {
+		a, b := resolution.Matches[i], resolution.Matches[j]
+		if a.rank != b.rank {
+			return a.rank.less(b.rank)
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	if len(resolution.Matches) > limit {
+		resolution.Matches = resolution.Matches[:limit]
+	}
+	return resolution, nil
+}
+
+// resolveInDocument finds the symbols of a document matching wanted. When nothing
+// matches the whole name, leading qualifiers are dropped one at a time, and those
+// naming the file or its directory, such as a package or module, do not count as
+// ignored. Case is ignored only when nothing matches with it.
+func resolveInDocument(doc *documentSymbols, path string, wanted []string) []SymbolMatch {
+	for _, fold := range []bool{false, true} {
+		ignored := 0
+		for parts := wanted; len(parts) > 0; parts = parts[1:] {
+			matches := matchDocumentSymbols(doc.symbols, parts, fold)
+			if len(matches) == 0 {
+				if !namesFile(path, parts[0]) {
+					ignored++
+				}
+				continue
+			}
+
+			var resolved []SymbolMatch
+			for _, match := range matches {
+				if int(match.rng.End.Line) >= len(doc.lines) {
+					toolsLogger.Error("Symbol range out of bounds: %v", match.rng)
+					continue
+				}
+				resolved = append(resolved, doc.symbolMatch(match, ignored, fold))
+			}
+			return resolved
+		}
+	}
+	return nil
+}
+
+// symbolMatch converts a document symbol match
+func (doc *documentSymbols) symbolMatch(match documentSymbolMatch, ignored int, fold bool) SymbolMatch {
+	column := func(pos protocol.Position) int {
+		if int(pos.Line) >= len(doc.lines) {
+			return int(pos.Character) + 1
+		}
+		return utilities.CharacterToColumn(doc.lines[pos.Line], pos.Character, doc.encoding)
+	}
+
+	// Flat results have no selection range, so look for the name on the first line
+	position := match.selection.Start
+	if match.selection == match.rng {
+		parts := splitSymbolPath(match.name)
+		name := parts[len(parts)-1]
+		if start := int(match.rng.Start.Line); start < len(doc.lines) {
+			text := doc.lines[start]
+			offset := utilities.ByteOffset(text, match.rng.Start.Character, doc.encoding)
+			if idx := strings.Index(text[offset:], name); idx >= 0 {
+				position.Character = utilities.CharacterOf(text, offset+idx, doc.encoding)
+			}
+		}
+	}
+
+	return SymbolMatch{
+		Name:   match.name,
+		Kind:   protocol.TableKindMap[match.kind],
+		Line:   int(position.Line) + 1,
+		Column: column(position),
+		Location: Location{
+			Path:        fileuri.ToPath(doc.uri),
+			StartLine:   int(match.rng.Start.Line) + 1,
+			StartColumn: column(match.rng.Start),
+			EndLine:     int(match.rng.End.Line) + 1,
+			EndColumn:   column(match.rng.End),
+		},
+		uri:      doc.uri,
+		position: position,
+		rank:     resolveRank{ignoredQualifiers: ignored, folded: fold},
+	}
+}
+
+// ResolveSymbol lists the symbols a qualified name resolved to
+func ResolveSymbol(resolution SymbolResolution) string {
+	if len(resolution.Matches) == 0 {
+		return fmt.Sprintf("%s not found", resolution.Query)
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "%s resolved to %d symbols:\n", resolution.Query, len(resolution.Matches))
+	for _, match := range resolution.Matches {
+		fmt.Fprintf(&output, "%s (%s) at %s:L%d:C%d, L%d-L%d", match.Name, match.Kind, match.Path, match.Line, match.Column, match.StartLine, match.EndLine)
+		if !match.Exact {
+			output.WriteString(" (partial match)")
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// ResolveSymbolPosition returns the file and the 1-indexed position of the name
+// of the one symbol a qualified name refers to, for tools that accept symbol
+// names in place of positions. It is an error for the name to match nothing, or
+// several symbols equally well.
+func ResolveSymbolPosition(ctx context.Context, client *lsp.Client, name string) (SymbolMatch, error) {
+	resolution, err := CollectResolveSymbol(ctx, client, name, defaultResolveLimit)
+	if err != nil {
+		return SymbolMatch{}, err
+	}
+	if len(resolution.Matches) == 0 {
+		return SymbolMatch{}, fmt.Errorf("%s: %w", name, ErrSymbolNotFound)
+	}
+
+	best := resolution.Matches[0]
+	var tied []string
+	for _, match := range resolution.Matches {
+		if match.rank == best.rank {
+			tied = append(tied, fmt.Sprintf("%s (%s, %s:L%d)", match.Name, match.Kind, match.Path, match.Line))
+		}
+	}
+	if len(tied) > 1 {
+		return SymbolMatch{}, fmt.Errorf("%s matches %d symbols, qualify the name to choose one: %s",
+			name, len(tied), strings.Join(tied, ", "))
+	}
+	return best, nil
+}
+
+// resolveFallback resolves a name that the workspace symbol matching of a tool
+// found nothing for, such as one qualified by its package or directory. It
+// reports false when the name resolves to no symbol either.
+func resolveFallback(ctx context.Context, client *lsp.Client, name string) (SymbolMatch, bool, error) {
+	match, err := ResolveSymbolPosition(ctx, client, name)
+	if errors.Is(err, ErrSymbolNotFound) {
+		return SymbolMatch{}, false, nil
+	}
+	if err != nil {
+		return SymbolMatch{}, false, err
+	}
+	return match, true, nil
+}
+
+// parseQualifiedName splits a name such as "internal/lsp.Client.OpenFile" into
+// the directory qualifier "internal/lsp" and the symbol path Client, OpenFile.
+// Names without a slash have no directory qualifier.
+func parseQualifiedName(name string) (string, []string) {
+	name = strings.TrimSpace(name)
+	slash := strings.LastIndex(name, "/")
+	if slash < 0 {
+		return "", splitSymbolPath(name)
+	}
+	rest := name[slash+1:]
+	dot := strings.Index(rest, ".")
+	if dot < 0 {
+		return "", splitSymbolPath(rest)
+	}
+	return name[:slash+1+dot], splitSymbolPath(rest[dot+1:])
+}
+
+// pathMatch returns how many trailing parts of a slash separated qualifier match
+// the directory of path, or the directory and the file name without extension,
+// so that a module path matches the end of the file's location
+func pathMatch(path, qualifier string) int {
+	if qualifier == "" {
+		return 0
+	}
+	wanted := strings.Split(strings.Trim(qualifier, "/"), "/")
+	dir := strings.Split(filepath.ToSlash(filepath.Dir(path)), "/")
+	file := append(dir[:len(dir):len(dir)], strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	return max(trailingMatch(dir, wanted), trailingMatch(file, wanted))
+}
+
+// trailingMatch counts the parts at the end of wanted that path ends with
+func trailingMatch(path, wanted []string) int {
+	count := 0
+	for count < len(path) && count < len(wanted) &&
+		path[len(path)-1-count] == wanted[len(wanted)-1-count] {
+		count++
+	}
+	return count
+}
+
+// namesFile reports whether a qualifier names the directory or the file of path,
+// as a package or module name would
+func namesFile(path, qualifier string) bool {
+	base := filepath.Base(path)
+	return qualifier == filepath.Base(filepath.Dir(path)) || qualifier == strings.TrimSuffix(base, filepath.Ext(base))
+}
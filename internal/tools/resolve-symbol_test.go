@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQualifiedName(t *testing.T) {
+	testCases := []struct {
+		name      string
+		qualifier string
+		parts     []string
+	}{
+		{"internal/lsp.Client.OpenFile", "internal/lsp", []string{"Client", "OpenFile"}},
+		{"github.com/isaacphi/mcp-language-server/internal/lsp.Client", "github.com/isaacphi/mcp-language-server/internal/lsp", []string{"Client"}},
+		{"lsp.Client.OpenFile", "", []string{"lsp", "Client", "OpenFile"}},
+		{"ns::Widget::draw", "", []string{"ns", "Widget", "draw"}},
+		{"(*Client).OpenFile", "", []string{"Client", "OpenFile"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			qualifier, parts := parseQualifiedName(tc.name)
+			assert.Equal(t, tc.qualifier, qualifier)
+			assert.Equal(t, tc.parts, parts)
+		})
+	}
+}
+
+func TestPathMatch(t *testing.T) {
+	path := "/home/me/mcp-language-server/internal/lsp/client.go"
+	assert.Equal(t, 2, pathMatch(path, "internal/lsp"))
+	assert.Equal(t, 3, pathMatch(path, "github.com/isaacphi/mcp-language-server/internal/lsp"))
+	assert.Equal(t, 2, pathMatch(path, "lsp/client"), "the file name counts as a module")
+	assert.Equal(t, 0, pathMatch(path, "internal/tools"))
+	assert.Equal(t, 0, pathMatch(path, ""))
+}
+
+// resolveDocument is a Go file with a type and a method, as gopls reports it
+func resolveDocument() *documentSymbols {
+	source := "package lsp\n\ntype Client struct{}\n\nfunc (c *Client) OpenFile(path string) error {\n\treturn nil\n}\n"
+	return &documentSymbols{
+		uri: "file:///work/internal/lsp/client.go",
+		symbols: []protocol.DocumentSymbolResult{
+			&protocol.DocumentSymbol{
+				Name: "Client", Kind: protocol.Struct,
+				Range:          protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 2, Character: 20}},
+				SelectionRange: protocol.Range{Start: protocol.Position{Line: 2, Character: 5}, End: protocol.Position{Line: 2, Character: 11}},
+			},
+			&protocol.DocumentSymbol{
+				Name: "(*Client).OpenFile", Kind: protocol.Method,
+				Range:          protocol.Range{Start: protocol.Position{Line: 4}, End: protocol.Position{Line: 6, Character: 1}},
+				SelectionRange: protocol.Range{Start: protocol.Position{Line: 4, Character: 17}, End: protocol.Position{Line: 4, Character: 25}},
+			},
+		},
+		lines:    strings.Split(source, "\n"),
+		encoding: protocol.UTF16,
+	}
+}
+
+func TestResolveInDocument(t *testing.T) {
+	doc := resolveDocument()
+	path := "/work/internal/lsp/client.go"
+
+	matches := resolveInDocument(doc, path, []string{"lsp", "Client", "OpenFile"})
+	require.Len(t, matches, 1)
+	assert.Equal(t, "(*Client).OpenFile", matches[0].Name)
+	assert.Equal(t, 5, matches[0].Line)
+	assert.Equal(t, 18, matches[0].Column, "the position is the method name")
+	assert.Equal(t, 7, matches[0].EndLine)
+	assert.Equal(t, resolveRank{}, matches[0].rank, "the package qualifier names the directory")
+
+	matches = resolveInDocument(doc, path, []string{"other", "Client", "OpenFile"})
+	require.Len(t, matches, 1)
+	assert.Equal(t, 1, matches[0].rank.ignoredQualifiers)
+
+	matches = resolveInDocument(doc, path, []string{"client", "openfile"})
+	require.Len(t, matches, 1)
+	assert.True(t, matches[0].rank.folded)
+
+	assert.Empty(t, resolveInDocument(doc, path, []string{"Server"}))
+}
+
+func TestResolveRank(t *testing.T) {
+	exact := resolveRank{pathMatch: 1}
+	assert.True(t, exact.less(resolveRank{ignoredQualifiers: 1, pathMatch: 3}))
+	assert.True(t, exact.less(resolveRank{folded: true, pathMatch: 3}))
+	assert.True(t, resolveRank{pathMatch: 2}.less(exact))
+}
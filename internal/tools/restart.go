@@ -0,0 +1,22 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// RestartLanguageServer restarts a crashed or hung language server and waits for it
+// to finish loading the workspace
+func RestartLanguageServer(ctx context.Context, client *lsp.Client) (string, error) {
+	if err := client.Restart(ctx); err != nil {
+		return "", err
+	}
+
+	if err := client.WaitForServerReady(ctx); err != nil {
+		return "", fmt.Errorf("language server restarted but did not become ready: %v", err)
+	}
+
+	return "Language server restarted. Open files were reopened and workspace folders restored.", nil
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// semanticToken is a single decoded entry of a semantic tokens response
+type semanticToken struct {
+	Line      uint32
+	Character uint32
+	Length    uint32
+	Type      string
+	Modifiers []string
+}
+
+// GetSemanticTokens decodes the semantic tokens of a file, or of the given 1-indexed
+// inclusive line range, into one line per token with its position, type, modifiers and text
+func GetSemanticTokens(ctx context.Context, client *lsp.Client, filePath string, startLine, endLine int) (string, error) {
+	legend, err := client.SemanticTokensLegend()
+	if err != nil {
+		return "", err
+	}
+
+	err = client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	content, err := client.ReadDocument(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	lines := strings.Split(string(content), "\n")
+
+	uri := fileuri.FromPath(filePath)
+
+	var result protocol.SemanticTokens
+	if startLine > 0 {
+		if endLine < startLine {
+			endLine = startLine
+		}
+		result, err = client.SemanticTokensRange(ctx, protocol.SemanticTokensRangeParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Range: protocol.Range{
+				Start: protocol.Position{Line: uint32(startLine - 1)},
+				End:   protocol.Position{Line: uint32(endLine)},
+			},
+		})
+	} else {
+		result, err = client.SemanticTokensFull(ctx, protocol.SemanticTokensParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get semantic tokens: %v", err)
+	}
+
+	tokens := decodeSemanticTokens(result.Data, legend)
+	if len(tokens) == 0 {
+		return fmt.Sprintf("No semantic tokens found in %s", filePath), nil
+	}
+
+	encoding := client.PositionEncoding()
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Semantic tokens for %s:\n\n", filePath))
+	for _, token := range tokens {
+		// Servers may return tokens outside of the requested range
+		if startLine > 0 && (int(token.Line) < startLine-1 || int(token.Line) > endLine-1) {
+			continue
+		}
+
+		line := ""
+		if int(token.Line) < len(lines) {
+			line = strings.TrimSuffix(lines[token.Line], "\r")
+		}
+		end := token.Character + token.Length
+		output.WriteString(fmt.Sprintf("L%d:C%d-C%d %s", token.Line+1,
+			utilities.CharacterToColumn(line, token.Character, encoding), utilities.CharacterToColumn(line, end, encoding), token.Type))
+		if len(token.Modifiers) > 0 {
+			output.WriteString(fmt.Sprintf(" [%s]", strings.Join(token.Modifiers, ", ")))
+		}
+		if end <= utilities.CharacterLength(line, encoding) {
+			output.WriteString(fmt.Sprintf(" %q", line[utilities.ByteOffset(line, token.Character, encoding):utilities.ByteOffset(line, end, encoding)]))
+		}
+		output.WriteString("\n")
+	}
+
+	return output.String(), nil
+}
+
+// decodeSemanticTokens converts the relative encoding used by the LSP, five integers per
+// token, into absolute positions with type and modifier names taken from the legend
+func decodeSemanticTokens(data []uint32, legend protocol.SemanticTokensLegend) []semanticToken {
+	var tokens []semanticToken
+	var line, character uint32
+
+	for i := 0; i+4 < len(data); i += 5 {
+		deltaLine, deltaStart := data[i], data[i+1]
+		if deltaLine > 0 {
+			line += deltaLine
+			character = deltaStart
+		} else {
+			character += deltaStart
+		}
+
+		token := semanticToken{
+			Line:      line,
+			Character: character,
+			Length:    data[i+2],
+			Type:      fmt.Sprintf("unknown(%d)", data[i+3]),
+		}
+		if int(data[i+3]) < len(legend.TokenTypes) {
+			token.Type = legend.TokenTypes[data[i+3]]
+		}
+		for bit, modifier := range legend.TokenModifiers {
+			if data[i+4]&(1<<uint(bit)) != 0 {
+				token.Modifiers = append(token.Modifiers, modifier)
+			}
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
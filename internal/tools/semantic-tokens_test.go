@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSemanticTokens(t *testing.T) {
+	legend := protocol.SemanticTokensLegend{
+		TokenTypes:     []string{"keyword", "function", "variable"},
+		TokenModifiers: []string{"declaration", "readonly"},
+	}
+
+	testCases := []struct {
+		name     string
+		data     []uint32
+		expected []semanticToken
+	}{
+		{
+			name:     "Empty data",
+			data:     []uint32{},
+			expected: nil,
+		},
+		{
+			name: "Tokens on the same line are relative to the previous token",
+			data: []uint32{
+				2, 0, 4, 0, 0,
+				0, 5, 3, 1, 1,
+			},
+			expected: []semanticToken{
+				{Line: 2, Character: 0, Length: 4, Type: "keyword"},
+				{Line: 2, Character: 5, Length: 3, Type: "function", Modifiers: []string{"declaration"}},
+			},
+		},
+		{
+			name: "New line resets the start character",
+			data: []uint32{
+				0, 4, 3, 2, 3,
+				1, 2, 5, 2, 2,
+			},
+			expected: []semanticToken{
+				{Line: 0, Character: 4, Length: 3, Type: "variable", Modifiers: []string{"declaration", "readonly"}},
+				{Line: 1, Character: 2, Length: 5, Type: "variable", Modifiers: []string{"readonly"}},
+			},
+		},
+		{
+			name:     "Unknown token type",
+			data:     []uint32{0, 0, 1, 7, 0},
+			expected: []semanticToken{{Line: 0, Character: 0, Length: 1, Type: "unknown(7)"}},
+		},
+		{
+			name:     "Incomplete trailing token is ignored",
+			data:     []uint32{0, 0, 1, 0, 0, 1, 2},
+			expected: []semanticToken{{Line: 0, Character: 0, Length: 1, Type: "keyword"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, decodeSemanticTokens(tc.data, legend))
+		})
+	}
+}
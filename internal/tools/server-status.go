@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+)
+
+// StatusReport is the state of the language server, as reported by server_status
+// and the health endpoint
+type StatusReport struct {
+	Server string `json:"server"`
+	// PID is 0 when connected to a running server at Address
+	PID           int      `json:"pid,omitempty"`
+	Address       string   `json:"address,omitempty"`
+	Running       bool     `json:"running"`
+	Initialized   bool     `json:"initialized"`
+	UptimeSeconds float64  `json:"uptimeSeconds,omitempty"`
+	Indexing      bool     `json:"indexing"`
+	Progress      []string `json:"progress,omitempty"`
+	// IdleSeconds is the time since the server last reported progress or
+	// diagnostics, unset when it never has
+	IdleSeconds *float64         `json:"idleSeconds,omitempty"`
+	OpenFiles   []OpenFileStatus `json:"openFiles"`
+	// Diagnostics counts the cached diagnostics by severity
+	Diagnostics map[string]int `json:"diagnostics"`
+	// LastDiagnostics is when diagnostics were last published for any file
+	LastDiagnostics string `json:"lastDiagnostics,omitempty"`
+	Crashes         int64  `json:"crashes"`
+	Restarts        int64  `json:"restarts"`
+}
+
+// OpenFileStatus is a file open in the server, with when its diagnostics were last
+// published, if they have been
+type OpenFileStatus struct {
+	Path            string `json:"path"`
+	LastDiagnostics string `json:"lastDiagnostics,omitempty"`
+}
+
+// Healthy reports whether the server is running and initialized
+func (r StatusReport) Healthy() bool {
+	return r.Running && r.Initialized
+}
+
+// CollectServerStatus returns the state of the language server
+func CollectServerStatus(client *lsp.Client) StatusReport {
+	status := client.Status()
+	now := time.Now()
+
+	report := StatusReport{
+		Server:      status.ServerName,
+		PID:         status.PID,
+		Address:     status.Address,
+		Running:     status.Running,
+		Initialized: status.Initialized,
+		Indexing:    len(status.Progress) > 0,
+		Progress:    status.Progress,
+		OpenFiles:   []OpenFileStatus{},
+		Diagnostics: make(map[string]int),
+		Crashes:     status.Crashes,
+		Restarts:    status.Restarts,
+	}
+	if !status.StartedAt.IsZero() {
+		report.UptimeSeconds = now.Sub(status.StartedAt).Round(time.Second).Seconds()
+	}
+	if !status.LastActivity.IsZero() {
+		idle := now.Sub(status.LastActivity).Round(time.Millisecond).Seconds()
+		report.IdleSeconds = &idle
+	}
+
+	for _, path := range status.OpenFilePaths {
+		file := OpenFileStatus{Path: path}
+		if t, ok := status.DiagnosticTimes[fileuri.FromPath(path)]; ok {
+			file.LastDiagnostics = t.Format(time.RFC3339)
+		}
+		report.OpenFiles = append(report.OpenFiles, file)
+	}
+
+	var last time.Time
+	for _, t := range status.DiagnosticTimes {
+		if t.After(last) {
+			last = t
+		}
+	}
+	if !last.IsZero() {
+		report.LastDiagnostics = last.Format(time.RFC3339)
+	}
+
+	for severity, count := range status.Diagnostics {
+		report.Diagnostics[strings.ToLower(getSeverityString(severity))] += count
+	}
+	return report
+}
+
+// ServerStatus describes the state of the language server, with hints for why
+// queries may come back empty
+func ServerStatus(client *lsp.Client) string {
+	report := CollectServerStatus(client)
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "Language server: %s\n", report.Server)
+	switch {
+	case report.PID != 0:
+		fmt.Fprintf(&output, "Process: PID %d", report.PID)
+	case report.Address != "":
+		fmt.Fprintf(&output, "Connection: %s", report.Address)
+	default:
+		output.WriteString("Process: none")
+	}
+	if report.Running {
+		fmt.Fprintf(&output, ", up %v\n", time.Duration(report.UptimeSeconds)*time.Second)
+	} else {
+		output.WriteString(", not running\n")
+	}
+	fmt.Fprintf(&output, "Initialized: %s\n", yesNo(report.Initialized))
+
+	if report.Indexing {
+		fmt.Fprintf(&output, "Indexing: %d tasks in progress\n", len(report.Progress))
+		for _, title := range report.Progress {
+			fmt.Fprintf(&output, "  %s\n", title)
+		}
+	} else {
+		output.WriteString("Indexing: idle\n")
+	}
+	if report.IdleSeconds != nil {
+		fmt.Fprintf(&output, "Last activity: %v ago\n", time.Duration(*report.IdleSeconds*float64(time.Second)).Round(time.Second))
+	}
+
+	fmt.Fprintf(&output, "Open files: %d\n", len(report.OpenFiles))
+	for _, file := range report.OpenFiles {
+		if file.LastDiagnostics != "" {
+			fmt.Fprintf(&output, "  %s (diagnostics at %s)\n", file.Path, file.LastDiagnostics)
+		} else {
+			fmt.Fprintf(&output, "  %s (no diagnostics yet)\n", file.Path)
+		}
+	}
+
+	var severities []string
+	for _, severity := range []string{"error", "warning", "info", "hint", "unknown"} {
+		if count := report.Diagnostics[severity]; count > 0 {
+			severities = append(severities, fmt.Sprintf("%d %s", count, severity))
+		}
+	}
+	if len(severities) == 0 {
+		output.WriteString("Diagnostics: none")
+	} else {
+		fmt.Fprintf(&output, "Diagnostics: %s", strings.Join(severities, ", "))
+	}
+	if report.LastDiagnostics != "" {
+		fmt.Fprintf(&output, ", last published at %s\n", report.LastDiagnostics)
+	} else {
+		output.WriteString(", never published\n")
+	}
+	fmt.Fprintf(&output, "Crashes: %d, restarts: %d\n", report.Crashes, report.Restarts)
+
+	// Explain the usual reasons for empty results
+	switch {
+	case !report.Running:
+		output.WriteString("\nThe language server is not running. Use restart_language_server to start it again.\n")
+	case !report.Initialized:
+		output.WriteString("\nThe language server has not finished initializing, so queries return nothing yet.\n")
+	case report.Indexing:
+		output.WriteString("\nThe language server is still indexing, so results may be incomplete until it finishes.\n")
+	}
+	return output.String()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// stagedFiles returns filePath, or every file with staged edits when it is empty
+func stagedFiles(client *lsp.Client, filePath string) []string {
+	if filePath != "" {
+		return []string{filePath}
+	}
+	return client.StagedFiles()
+}
+
+// CommitStaged writes the staged edits of a file to disk, or those of every file
+// with staged edits when filePath is empty. The files are saved like the ones
+// other tools edit, and the commit can be undone as a single edit.
+func CommitStaged(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
+	paths := stagedFiles(client, filePath)
+	if len(paths) == 0 {
+		return "No files have staged edits.", nil
+	}
+
+	entry := utilities.EditJournal.Begin(fmt.Sprintf("commit_staged %s", strings.Join(paths, ", ")))
+	for _, path := range paths {
+		entry.Capture(path)
+	}
+
+	var committed, failed []string
+	preSaved := false
+	for _, path := range paths {
+		if err := client.CommitStaged(ctx, path); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		committed = append(committed, path)
+		preSaved = saveEditedFile(ctx, client, path) || preSaved
+	}
+	entry.Commit()
+
+	if len(committed) == 0 {
+		return "", fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Committed the staged edits of %d files:\n", len(committed)))
+	for _, path := range committed {
+		output.WriteString(path + "\n")
+	}
+	if len(failed) > 0 {
+		output.WriteString("\nCould not commit:\n")
+		for _, failure := range failed {
+			output.WriteString(failure + "\n")
+		}
+	}
+	if preSaved {
+		output.WriteString(strings.TrimSpace(preSaveNote) + "\n")
+	}
+	return output.String(), nil
+}
+
+// DiscardStaged drops the staged edits of a file, or those of every file with
+// staged edits when filePath is empty, so the server sees the files on disk again
+func DiscardStaged(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
+	paths := stagedFiles(client, filePath)
+	if len(paths) == 0 {
+		return "No files have staged edits.", nil
+	}
+
+	var output strings.Builder
+	discarded := 0
+	for _, path := range paths {
+		if err := client.DiscardStaged(ctx, path); err != nil {
+			if filePath != "" {
+				return "", err
+			}
+			output.WriteString(fmt.Sprintf("%s: %v\n", path, err))
+			continue
+		}
+		discarded++
+		output.WriteString(path + "\n")
+	}
+
+	return fmt.Sprintf("Discarded the staged edits of %d files:\n%s", discarded, output.String()), nil
+}
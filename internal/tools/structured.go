@@ -0,0 +1,264 @@
+package tools
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// The Collect functions return the results of the tools that find locations and
+// symbols as values suitable for JSON, for clients that parse results rather than
+// read them. Lines and columns are one-indexed and columns count Unicode
+// characters, like in the text the other functions return.
+
+// Location is a range in a file
+type Location struct {
+	Path        string `json:"path"`
+	StartLine   int    `json:"startLine"`
+	StartColumn int    `json:"startColumn"`
+	EndLine     int    `json:"endLine"`
+	EndColumn   int    `json:"endColumn"`
+	// Server names the language server that found the location, when results
+	// of several servers are merged
+	Server string `json:"server,omitempty"`
+}
+
+// LocationList is the locations a request returned
+type LocationList struct {
+	Locations []Location `json:"locations"`
+}
+
+// SymbolEntry is a symbol and the location of its declaration
+type SymbolEntry struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind,omitempty"`
+	Container string `json:"container,omitempty"`
+	Location
+}
+
+// SymbolList is the symbols matching a query. Total counts all of them when the
+// list was limited.
+type SymbolList struct {
+	Query   string        `json:"query"`
+	Total   int           `json:"total"`
+	Symbols []SymbolEntry `json:"symbols"`
+}
+
+// ReferenceList is a page of the references to a symbol. NextCursor, when set,
+// continues with the next page.
+type ReferenceList struct {
+	Symbol     string     `json:"symbol"`
+	Total      int        `json:"total"`
+	References []Location `json:"references"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+	// TextualMatches is set when the server found no references and the lines
+	// containing the identifier are returned instead, unverified
+	TextualMatches bool `json:"textualMatches,omitempty"`
+}
+
+// HighlightEntry is an occurrence of a symbol in a file, with its kind: read,
+// write or text
+type HighlightEntry struct {
+	Kind string `json:"kind"`
+	Location
+}
+
+// HighlightList is the occurrences of a symbol in a file
+type HighlightList struct {
+	Highlights []HighlightEntry `json:"highlights"`
+}
+
+// location converts a protocol location
+func (d *documentLines) location(loc protocol.Location) Location {
+	return Location{
+		Path:        fileuri.ToPath(loc.URI),
+		StartLine:   int(loc.Range.Start.Line) + 1,
+		StartColumn: d.column(loc.URI, loc.Range.Start),
+		EndLine:     int(loc.Range.End.Line) + 1,
+		EndColumn:   d.column(loc.URI, loc.Range.End),
+	}
+}
+
+// locationList converts protocol locations
+func locationList(client *lsp.Client, locations []protocol.Location) LocationList {
+	positions := newDocumentLines(client)
+	list := LocationList{Locations: []Location{}}
+	for _, loc := range locations {
+		list.Locations = append(list.Locations, positions.location(loc))
+	}
+	return list
+}
+
+// symbolEntry converts a workspace symbol found at loc
+func symbolEntry(positions *documentLines, symbol protocol.WorkspaceSymbolResult, loc protocol.Location) SymbolEntry {
+	kind, container := symbolDetails(symbol)
+	return SymbolEntry{
+		Name:      symbol.GetName(),
+		Kind:      protocol.TableKindMap[kind],
+		Container: container,
+		Location:  positions.location(loc),
+	}
+}
+
+// CollectWorkspaceSymbols returns the symbols SearchWorkspaceSymbols shows
+func CollectWorkspaceSymbols(ctx context.Context, client *lsp.Client, query string, limit int, exactMatch bool) (SymbolList, error) {
+	matches, err := matchWorkspaceSymbols(ctx, client, query, exactMatch)
+	if err != nil {
+		return SymbolList{}, err
+	}
+
+	list := SymbolList{Query: query, Total: len(matches), Symbols: []SymbolEntry{}}
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	positions := newDocumentLines(client)
+	for _, symbol := range matches {
+		list.Symbols = append(list.Symbols, symbolEntry(positions, symbol, symbol.GetLocation()))
+	}
+	return list, nil
+}
+
+// CollectDefinitionsByName returns the symbols ReadDefinition shows, each with the
+// range of its full definition
+func CollectDefinitionsByName(ctx context.Context, client *lsp.Client, symbolName string) (SymbolList, error) {
+	matches, err := matchWorkspaceSymbols(ctx, client, symbolName, false)
+	if err != nil {
+		return SymbolList{}, err
+	}
+
+	list := SymbolList{Query: symbolName, Symbols: []SymbolEntry{}}
+	positions := newDocumentLines(client)
+	for _, symbol := range matches {
+		if !definitionMatches(symbol, symbolName) {
+			continue
+		}
+		loc := symbol.GetLocation()
+		if err := client.OpenFile(ctx, loc.URI.Path()); err != nil {
+			toolsLogger.Error("Error opening file: %v", err)
+			continue
+		}
+		_, loc, err := GetFullDefinition(ctx, client, loc)
+		if err != nil {
+			toolsLogger.Error("Error getting definition: %v", err)
+			continue
+		}
+		list.Symbols = append(list.Symbols, symbolEntry(positions, symbol, loc))
+	}
+
+	if len(list.Symbols) == 0 {
+		match, found, err := resolveFallback(ctx, client, symbolName)
+		if err != nil {
+			return list, err
+		}
+		if found {
+			list.Symbols = append(list.Symbols, SymbolEntry{Name: match.Name, Kind: match.Kind, Location: match.Location})
+		}
+	}
+	list.Total = len(list.Symbols)
+	return list, nil
+}
+
+// CollectDefinitions returns the definitions of the symbol at a position
+func CollectDefinitions(ctx context.Context, client *lsp.Client, filePath string, line, column int) (LocationList, error) {
+	locations, err := definitionLocations(ctx, client, filePath, line, column)
+	if err != nil {
+		return LocationList{}, err
+	}
+	return locationList(client, locations), nil
+}
+
+// CollectDeclarations returns the declarations of the symbol at a position
+func CollectDeclarations(ctx context.Context, client *lsp.Client, filePath string, line, column int) (LocationList, error) {
+	locations, err := declarationLocations(ctx, client, filePath, line, column)
+	if err != nil {
+		return LocationList{}, err
+	}
+	return locationList(client, locations), nil
+}
+
+// CollectTypeDefinitions returns the definitions of the type of the symbol at a
+// position
+func CollectTypeDefinitions(ctx context.Context, client *lsp.Client, filePath string, line, column int) (LocationList, error) {
+	locations, err := typeDefinitionLocations(ctx, client, filePath, line, column)
+	if err != nil {
+		return LocationList{}, err
+	}
+	return locationList(client, locations), nil
+}
+
+// CollectImplementations returns the implementations of the symbols matching
+// symbolName
+func CollectImplementations(ctx context.Context, client *lsp.Client, symbolName string) (LocationList, error) {
+	implsBySymbol, err := findImplementations(ctx, client, symbolName)
+	if err != nil {
+		return LocationList{}, err
+	}
+
+	var locations []protocol.Location
+	for _, impls := range implsBySymbol {
+		locations = append(locations, impls...)
+	}
+	return locationList(client, locations), nil
+}
+
+// CollectReferences returns the references FindReferencesWithOptions shows, ordered
+// by file and position. Context lines and grouping do not apply.
+func CollectReferences(ctx context.Context, client *lsp.Client, symbolName string, opts ReferenceOptions) (ReferenceList, error) {
+	offset, err := parseCursor(opts.Cursor)
+	if err != nil {
+		return ReferenceList{}, err
+	}
+
+	refsBySymbol, err := findReferences(ctx, client, symbolName, opts.IncludeDeclaration, true)
+	if err != nil {
+		return ReferenceList{}, err
+	}
+	var refs []protocol.Location
+	for _, symbolRefs := range refsBySymbol {
+		refs = append(refs, symbolRefs...)
+	}
+	textual := false
+	if opts.TextFallback && len(refs) == 0 {
+		if refs, err = textualReferences(ctx, client, symbolName); err != nil {
+			return ReferenceList{}, err
+		}
+		textual = len(refs) > 0
+	}
+
+	list := ReferenceList{Symbol: symbolName, Total: len(refs), References: []Location{}, TextualMatches: textual}
+	if len(refs) == 0 {
+		return list, nil
+	}
+	if offset >= len(refs) {
+		return ReferenceList{}, errCursorPastEnd(offset, len(refs))
+	}
+
+	end := len(refs)
+	if opts.Limit > 0 && offset+opts.Limit < end {
+		end = offset + opts.Limit
+		list.NextCursor = strconv.Itoa(end)
+	}
+	list.References = locationList(client, refs[offset:end]).Locations
+	return list, nil
+}
+
+// CollectDocumentHighlights returns the occurrences GetDocumentHighlights shows
+func CollectDocumentHighlights(ctx context.Context, client *lsp.Client, filePath string, line, column int) (HighlightList, error) {
+	uri, highlights, err := documentHighlights(ctx, client, filePath, line, column)
+	if err != nil {
+		return HighlightList{}, err
+	}
+
+	positions := newDocumentLines(client)
+	list := HighlightList{Highlights: []HighlightEntry{}}
+	for _, highlight := range highlights {
+		list.Highlights = append(list.Highlights, HighlightEntry{
+			Kind:     highlightKind(highlight),
+			Location: positions.location(protocol.Location{URI: uri, Range: highlight.Range}),
+		})
+	}
+	return list, nil
+}
@@ -0,0 +1,351 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// maxTestOutput is how much test output is returned, keeping the end where
+// failures and the summary are
+const maxTestOutput = 20000
+
+// testRun is a command line that runs tests
+type testRun struct {
+	dir  string
+	args []string
+	env  []string
+}
+
+// testLens is a code lens that runs tests
+type testLens struct {
+	rng     protocol.Range
+	command protocol.Command
+}
+
+// goTestArgs is the argument of gopls' gopls.run_tests command
+type goTestArgs struct {
+	URI        protocol.DocumentUri
+	Tests      []string
+	Benchmarks []string
+}
+
+// rustRunnable is the argument of rust-analyzer's rust-analyzer.runSingle command
+type rustRunnable struct {
+	Label string `json:"label"`
+	Kind  string `json:"kind"`
+	Args  struct {
+		Cwd            string            `json:"cwd"`
+		WorkspaceRoot  string            `json:"workspaceRoot"`
+		Environment    map[string]string `json:"environment"`
+		OverrideCargo  string            `json:"overrideCargo"`
+		CargoArgs      []string          `json:"cargoArgs"`
+		CargoExtraArgs []string          `json:"cargoExtraArgs"`
+		ExecutableArgs []string          `json:"executableArgs"`
+		Program        string            `json:"program"`
+		Args           []string          `json:"args"`
+	} `json:"args"`
+}
+
+var testTitle = regexp.MustCompile(`(?i)\btests?\b`)
+
+// findTestLenses returns the code lenses of a file that run tests. With a
+// 1-indexed line, only the lenses of the test at or above that line are kept.
+func findTestLenses(ctx context.Context, client *lsp.Client, filePath string, line int) ([]testLens, error) {
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+	if err := client.WaitForIdle(ctx, defaultDiagnosticsDebounce, defaultDiagnosticsTimeout); err != nil {
+		toolsLogger.Warn("Listing tests before the server is idle: %v", err)
+	}
+
+	lenses, err := client.CodeLens(ctx, protocol.CodeLensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: fileuri.FromPath(filePath)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code lenses: %v", err)
+	}
+
+	var tests []testLens
+	for _, lens := range lenses {
+		if lens.Command == nil {
+			resolved, err := client.ResolveCodeLens(ctx, lens)
+			if err != nil {
+				toolsLogger.Debug("Failed to resolve code lens: %v", err)
+				continue
+			}
+			lens = resolved
+		}
+		if lens.Command != nil && isTestCommand(*lens.Command) {
+			tests = append(tests, testLens{rng: lens.Range, command: *lens.Command})
+		}
+	}
+
+	if line > 0 {
+		nearest := -1
+		for _, test := range tests {
+			if start := int(test.rng.Start.Line); start <= line-1 && start > nearest {
+				nearest = start
+			}
+		}
+		var near []testLens
+		for _, test := range tests {
+			if int(test.rng.Start.Line) == nearest {
+				near = append(near, test)
+			}
+		}
+		tests = near
+	}
+
+	return tests, nil
+}
+
+// isTestCommand reports whether a code lens command runs tests
+func isTestCommand(command protocol.Command) bool {
+	switch command.Command {
+	case "gopls.run_tests", "gopls.test":
+		return true
+	case "rust-analyzer.runSingle":
+		var runnable rustRunnable
+		if len(command.Arguments) == 0 || json.Unmarshal(command.Arguments[0], &runnable) != nil {
+			return false
+		}
+		args := append(append([]string{}, runnable.Args.CargoArgs...), runnable.Args.Args...)
+		return (len(args) > 0 && args[0] == "test") || testTitle.MatchString(command.Title)
+	}
+	return !strings.Contains(strings.ToLower(command.Command), "debug") && testTitle.MatchString(command.Title)
+}
+
+// ListTests lists the tests in a file, or the test at or above a 1-indexed line,
+// that the language server offers to run through code lenses
+func ListTests(ctx context.Context, client *lsp.Client, filePath string, line int) (string, error) {
+	tests, err := findTestLenses(ctx, client, filePath, line)
+	if err != nil {
+		return "", err
+	}
+	if len(tests) == 0 {
+		return fmt.Sprintf("No tests found in %s. The language server may not offer test code lenses for this file.", filePath), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Tests in %s:\n", filePath))
+	for i, test := range tests {
+		output.WriteString(fmt.Sprintf("[%d] L%d: %s", i+1, test.rng.Start.Line+1, strings.TrimSpace(test.command.Title)))
+		if names := testNames(test.command); len(names) > 0 {
+			output.WriteString(" (" + strings.Join(names, ", ") + ")")
+		}
+		output.WriteString("\n")
+	}
+	return output.String(), nil
+}
+
+// testNames returns the names of the tests a command runs, when it lists them
+func testNames(command protocol.Command) []string {
+	if command.Command == "gopls.run_tests" && len(command.Arguments) > 0 {
+		var args goTestArgs
+		if json.Unmarshal(command.Arguments[0], &args) == nil {
+			return append(args.Tests, args.Benchmarks...)
+		}
+	}
+	return nil
+}
+
+// RunTest runs a test from ListTests, chosen by its 1-indexed position in the list
+// for the same file and line. Tests from gopls and rust-analyzer are run with the
+// go or cargo command their code lens describes, sending each line of output to
+// onOutput as it arrives. Other servers run the test themselves.
+func RunTest(ctx context.Context, client *lsp.Client, filePath string, line, index int, timeout time.Duration, onOutput func(string)) (string, error) {
+	tests, err := findTestLenses(ctx, client, filePath, line)
+	if err != nil {
+		return "", err
+	}
+	if len(tests) == 0 {
+		return "", fmt.Errorf("no tests found in %s", filePath)
+	}
+	if index < 1 || index > len(tests) {
+		return "", fmt.Errorf("invalid test index: %d. Available range: 1-%d", index, len(tests))
+	}
+	command := tests[index-1].command
+
+	run, err := testCommand(filePath, command)
+	if err != nil {
+		return "", err
+	}
+	if run == nil {
+		// The server knows how to run the test, but its output is not available
+		result, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
+			Command:   command.Command,
+			Arguments: command.Arguments,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to run test: %v", err)
+		}
+		output := fmt.Sprintf("Ran %s with the language server's %s command.", strings.TrimSpace(command.Title), command.Command)
+		if result != nil {
+			if data, err := json.Marshal(result); err == nil && string(data) != "null" {
+				output += "\nResult: " + string(data)
+			}
+		}
+		return output, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return runTestCommand(ctx, run, timeout, onOutput)
+}
+
+// testCommand builds the command line a test code lens stands for, or returns nil
+// when the lens has to be run by the language server
+func testCommand(filePath string, command protocol.Command) (*testRun, error) {
+	if len(command.Arguments) == 0 {
+		return nil, nil
+	}
+
+	switch command.Command {
+	case "gopls.run_tests":
+		var args goTestArgs
+		if err := json.Unmarshal(command.Arguments[0], &args); err != nil {
+			return nil, fmt.Errorf("invalid gopls.run_tests arguments: %v", err)
+		}
+		return goTestCommand(filePath, args), nil
+
+	case "gopls.test":
+		// The older form passes the file, tests and benchmarks as separate arguments
+		var args goTestArgs
+		if len(command.Arguments) > 1 {
+			_ = json.Unmarshal(command.Arguments[1], &args.Tests)
+		}
+		if len(command.Arguments) > 2 {
+			_ = json.Unmarshal(command.Arguments[2], &args.Benchmarks)
+		}
+		return goTestCommand(filePath, args), nil
+
+	case "rust-analyzer.runSingle":
+		var runnable rustRunnable
+		if err := json.Unmarshal(command.Arguments[0], &runnable); err != nil {
+			return nil, fmt.Errorf("invalid rust-analyzer.runSingle arguments: %v", err)
+		}
+		run := &testRun{}
+		switch runnable.Kind {
+		case "cargo":
+			cargo := "cargo"
+			if runnable.Args.OverrideCargo != "" {
+				cargo = runnable.Args.OverrideCargo
+			}
+			args := append(append([]string{}, runnable.Args.CargoArgs...), runnable.Args.CargoExtraArgs...)
+			if len(runnable.Args.ExecutableArgs) > 0 {
+				args = append(append(args, "--"), runnable.Args.ExecutableArgs...)
+			}
+			run.args = append([]string{cargo}, args...)
+		case "shell":
+			run.args = append([]string{runnable.Args.Program}, runnable.Args.Args...)
+		default:
+			return nil, nil
+		}
+		run.dir = runnable.Args.Cwd
+		if run.dir == "" {
+			run.dir = runnable.Args.WorkspaceRoot
+		}
+		if len(runnable.Args.Environment) > 0 {
+			run.env = os.Environ()
+			for key, value := range runnable.Args.Environment {
+				run.env = append(run.env, key+"="+value)
+			}
+		}
+		return run, nil
+	}
+
+	return nil, nil
+}
+
+// goTestCommand builds the go test command that runs the given tests and
+// benchmarks in the package of a file
+func goTestCommand(filePath string, args goTestArgs) *testRun {
+	quoted := func(names []string) string {
+		patterns := make([]string, len(names))
+		for i, name := range names {
+			patterns[i] = regexp.QuoteMeta(name)
+		}
+		return "^(" + strings.Join(patterns, "|") + ")$"
+	}
+
+	cmdArgs := []string{"go", "test", "-v"}
+	if len(args.Tests) > 0 {
+		cmdArgs = append(cmdArgs, "-run", quoted(args.Tests))
+	} else if len(args.Benchmarks) > 0 {
+		cmdArgs = append(cmdArgs, "-run", "^$")
+	}
+	if len(args.Benchmarks) > 0 {
+		cmdArgs = append(cmdArgs, "-bench", quoted(args.Benchmarks))
+	}
+	cmdArgs = append(cmdArgs, ".")
+
+	return &testRun{dir: filepath.Dir(filePath), args: cmdArgs}
+}
+
+// runTestCommand runs a test command, streaming its combined output line by line,
+// and returns the outcome followed by the end of the output
+func runTestCommand(ctx context.Context, run *testRun, timeout time.Duration, onOutput func(string)) (string, error) {
+	commandLine := strings.Join(run.args, " ")
+	cmd := exec.CommandContext(ctx, run.args[0], run.args[1:]...)
+	cmd.Dir, cmd.Env = run.dir, run.env
+
+	reader, writer := io.Pipe()
+	cmd.Stdout, cmd.Stderr = writer, writer
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start %s: %v", commandLine, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		writer.Close()
+		done <- err
+	}()
+
+	var output strings.Builder
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if onOutput != nil {
+			onOutput(line)
+		}
+		output.WriteString(line + "\n")
+	}
+	// Drain whatever the scanner could not read so the command can finish
+	_, _ = io.Copy(io.Discard, reader)
+	err := <-done
+
+	var status string
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		status = fmt.Sprintf("TIMEOUT after %v", timeout)
+	case err == nil:
+		status = "PASS"
+	case errors.As(err, &exitErr):
+		status = fmt.Sprintf("FAIL (exit code %d)", exitErr.ExitCode())
+	default:
+		return "", fmt.Errorf("failed to run %s: %v", commandLine, err)
+	}
+
+	text := output.String()
+	if len(text) > maxTestOutput {
+		text = "...\n" + text[len(text)-maxTestOutput:]
+	}
+	return fmt.Sprintf("%s: %s in %s\n\n%s", status, commandLine, run.dir, text), nil
+}
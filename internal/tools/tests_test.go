@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestCommand(t *testing.T) {
+	arguments := func(args ...string) []json.RawMessage {
+		raw := make([]json.RawMessage, len(args))
+		for i, arg := range args {
+			raw[i] = json.RawMessage(arg)
+		}
+		return raw
+	}
+
+	testCases := []struct {
+		name     string
+		command  protocol.Command
+		isTest   bool
+		expected *testRun
+	}{
+		{
+			name: "gopls test",
+			command: protocol.Command{
+				Title:     "run test",
+				Command:   "gopls.run_tests",
+				Arguments: arguments(`{"URI":"file:///ws/pkg/a_test.go","Tests":["TestA","TestB/sub"]}`),
+			},
+			isTest:   true,
+			expected: &testRun{dir: "/ws/pkg", args: []string{"go", "test", "-v", "-run", "^(TestA|TestB/sub)$", "."}},
+		},
+		{
+			name: "gopls benchmark",
+			command: protocol.Command{
+				Title:     "run benchmark",
+				Command:   "gopls.run_tests",
+				Arguments: arguments(`{"URI":"file:///ws/pkg/a_test.go","Benchmarks":["BenchmarkA"]}`),
+			},
+			isTest:   true,
+			expected: &testRun{dir: "/ws/pkg", args: []string{"go", "test", "-v", "-run", "^$", "-bench", "^(BenchmarkA)$", "."}},
+		},
+		{
+			name: "rust-analyzer test",
+			command: protocol.Command{
+				Title:   "▶︎ Run Test",
+				Command: "rust-analyzer.runSingle",
+				Arguments: arguments(`{"label":"test tests::it_works","kind":"cargo","args":{"workspaceRoot":"/ws",` +
+					`"cargoArgs":["test","--package","demo","--lib"],"executableArgs":["tests::it_works","--exact","--nocapture"]}}`),
+			},
+			isTest: true,
+			expected: &testRun{dir: "/ws", args: []string{"cargo", "test", "--package", "demo", "--lib", "--",
+				"tests::it_works", "--exact", "--nocapture"}},
+		},
+		{
+			name: "rust-analyzer binary",
+			command: protocol.Command{
+				Title:     "▶︎ Run",
+				Command:   "rust-analyzer.runSingle",
+				Arguments: arguments(`{"label":"run demo","kind":"cargo","args":{"workspaceRoot":"/ws","cargoArgs":["run","--bin","demo"]}}`),
+			},
+			isTest:   false,
+			expected: &testRun{dir: "/ws", args: []string{"cargo", "run", "--bin", "demo"}},
+		},
+		{
+			name: "Other server",
+			command: protocol.Command{
+				Title:     "Run Test",
+				Command:   "java.test.run",
+				Arguments: arguments(`"file:///ws/ATest.java"`),
+			},
+			isTest: true,
+		},
+		{
+			name:    "Debug lens",
+			command: protocol.Command{Title: "Debug Test", Command: "java.test.debug"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.isTest, isTestCommand(tc.command))
+
+			run, err := testCommand("/ws/pkg/a_test.go", tc.command)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, run)
+		})
+	}
+}
+
+func TestRunTestCommand(t *testing.T) {
+	var lines []string
+	result, err := runTestCommand(context.Background(), &testRun{dir: t.TempDir(), args: []string{"sh", "-c", "echo one; echo two >&2; exit 3"}},
+		time.Minute, func(line string) { lines = append(lines, line) })
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(result, "FAIL (exit code 3): sh -c"), result)
+	assert.ElementsMatch(t, []string{"one", "two"}, lines)
+}
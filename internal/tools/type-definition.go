@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// GetTypeDefinition returns the definition of the type of the symbol at the specified
+// position, such as the struct or class of a variable
+func GetTypeDefinition(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	locations, err := typeDefinitionLocations(ctx, client, filePath, line, column)
+	if err != nil {
+		return "", err
+	}
+
+	definitions := formatDefinitionLocations(ctx, client, locations)
+	if len(definitions) == 0 {
+		return fmt.Sprintf("No type definition found at %s L%d:C%d", filePath, line, column), nil
+	}
+
+	return strings.Join(definitions, ""), nil
+}
+
+// typeDefinitionLocations returns the definitions of the type of the symbol at a position
+func typeDefinitionLocations(ctx context.Context, client *lsp.Client, filePath string, line, column int) ([]protocol.Location, error) {
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Convert 1-indexed line/column to 0-indexed for LSP protocol
+	params := protocol.TypeDefinitionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: fileuri.FromPath(filePath),
+			},
+			Position: toPosition(client, filePath, line, column),
+		},
+	}
+
+	result, err := client.TypeDefinition(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get type definition: %v", err)
+	}
+	return result.Locations(), nil
+}
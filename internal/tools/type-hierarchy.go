@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// GetTypeHierarchy builds a depth-limited tree of supertypes and/or subtypes for the type at
+// the specified position. direction is one of "supertypes", "subtypes" or "both".
+func GetTypeHierarchy(ctx context.Context, client *lsp.Client, filePath string, line, column int, direction string, depth int) (string, error) {
+	// Open the file if not already open
+	err := client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	if depth < 1 {
+		depth = 1
+	}
+
+	// Convert 1-indexed line/column to 0-indexed for LSP protocol
+	params := protocol.TypeHierarchyPrepareParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: fileuri.FromPath(filePath),
+			},
+			Position: toPosition(client, filePath, line, column),
+		},
+	}
+
+	items, err := client.PrepareTypeHierarchy(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare type hierarchy: %v", err)
+	}
+
+	if len(items) == 0 {
+		return fmt.Sprintf("No type hierarchy item found at L%d:C%d", line, column), nil
+	}
+
+	var output strings.Builder
+	for _, item := range items {
+		output.WriteString(fmt.Sprintf("Type hierarchy for %s\n", formatTypeHierarchyItem(item)))
+
+		if direction == "supertypes" || direction == "both" {
+			output.WriteString("\nSupertypes:\n")
+			visited := map[string]bool{typeHierarchyItemKey(item): true}
+			supertypes := func(item protocol.TypeHierarchyItem) ([]protocol.TypeHierarchyItem, error) {
+				return client.Supertypes(ctx, protocol.TypeHierarchySupertypesParams{Item: item})
+			}
+			if err := writeTypeHierarchy(&output, item, supertypes, 1, depth, visited); err != nil {
+				return "", fmt.Errorf("failed to get supertypes: %v", err)
+			}
+		}
+
+		if direction == "subtypes" || direction == "both" {
+			output.WriteString("\nSubtypes:\n")
+			visited := map[string]bool{typeHierarchyItemKey(item): true}
+			subtypes := func(item protocol.TypeHierarchyItem) ([]protocol.TypeHierarchyItem, error) {
+				return client.Subtypes(ctx, protocol.TypeHierarchySubtypesParams{Item: item})
+			}
+			if err := writeTypeHierarchy(&output, item, subtypes, 1, depth, visited); err != nil {
+				return "", fmt.Errorf("failed to get subtypes: %v", err)
+			}
+		}
+	}
+
+	return output.String(), nil
+}
+
+// writeTypeHierarchy recursively writes the types related to item by fetch, indented by level
+func writeTypeHierarchy(output *strings.Builder, item protocol.TypeHierarchyItem, fetch func(protocol.TypeHierarchyItem) ([]protocol.TypeHierarchyItem, error), level, maxDepth int, visited map[string]bool) error {
+	related, err := fetch(item)
+	if err != nil {
+		return err
+	}
+
+	indent := strings.Repeat("  ", level)
+	if len(related) == 0 && level == 1 {
+		output.WriteString(indent + "(none)\n")
+	}
+
+	for _, relatedItem := range related {
+		output.WriteString(fmt.Sprintf("%s- %s\n", indent, formatTypeHierarchyItem(relatedItem)))
+
+		key := typeHierarchyItemKey(relatedItem)
+		if visited[key] {
+			continue
+		}
+		if level < maxDepth {
+			visited[key] = true
+			if err := writeTypeHierarchy(output, relatedItem, fetch, level+1, maxDepth, visited); err != nil {
+				return err
+			}
+			delete(visited, key)
+		}
+	}
+
+	return nil
+}
+
+// formatTypeHierarchyItem renders an item as "Name (Kind) detail - file:line"
+func formatTypeHierarchyItem(item protocol.TypeHierarchyItem) string {
+	result := item.Name
+	if kind, ok := protocol.TableKindMap[item.Kind]; ok {
+		result += fmt.Sprintf(" (%s)", kind)
+	}
+	if item.Detail != "" {
+		result += " " + item.Detail
+	}
+	return fmt.Sprintf("%s - %s:L%d",
+		result,
+		fileuri.ToPath(item.URI),
+		item.SelectionRange.Start.Line+1,
+	)
+}
+
+// typeHierarchyItemKey uniquely identifies an item for cycle detection
+func typeHierarchyItemKey(item protocol.TypeHierarchyItem) string {
+	return fmt.Sprintf("%s:%d:%d", item.URI, item.SelectionRange.Start.Line, item.SelectionRange.Start.Character)
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// UndoEdit reverts an edit from the edit journal, or the most recent one when id
+// is 0, and sends the restored content to the language server. Unless force is
+// set, an edit whose files changed again afterwards is not undone.
+func UndoEdit(ctx context.Context, client *lsp.Client, id int, force bool) (string, error) {
+	entry, err := utilities.EditJournal.Undo(id, force)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Undid edit %d (%s), restoring %d files:\n", entry.ID, entry.Description, len(entry.Files())))
+	for _, path := range entry.Files() {
+		output.WriteString(path + "\n")
+
+		// Files the edit created are gone again, the rest have their old content
+		if _, err := os.Stat(path); err != nil {
+			client.CloseOpenFiles(ctx, path)
+			continue
+		}
+		if client.IsFileOpen(path) {
+			if err := client.NotifyChange(ctx, path); err != nil {
+				toolsLogger.Error("Error notifying change: %v", err)
+			}
+		}
+	}
+
+	return output.String(), nil
+}
+
+// EditHistory lists the edits in the edit journal, most recent first
+func EditHistory() string {
+	entries := utilities.EditJournal.Entries()
+	if len(entries) == 0 {
+		return "No edits have been recorded."
+	}
+
+	var output strings.Builder
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		files := entry.Files()
+		output.WriteString(fmt.Sprintf("%d. %s %s (%d files)\n",
+			entry.ID, entry.Time.Format("15:04:05"), entry.Description, len(files)))
+		for _, path := range files {
+			output.WriteString("   " + path + "\n")
+		}
+	}
+	return output.String()
+}
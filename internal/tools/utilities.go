@@ -1,17 +1,22 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
-func ExtractTextFromLocation(loc protocol.Location) (string, error) {
-	path := strings.TrimPrefix(string(loc.URI), "file://")
+func ExtractTextFromLocation(loc protocol.Location, encoding protocol.PositionEncodingKind) (string, error) {
+	path := fileuri.ToPath(loc.URI)
 
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -29,13 +34,16 @@ func ExtractTextFromLocation(loc protocol.Location) (string, error) {
 	// Handle single-line case
 	if startLine == endLine {
 		line := lines[startLine]
-		startChar := int(loc.Range.Start.Character)
-		endChar := int(loc.Range.End.Character)
-
-		if startChar < 0 || startChar > len(line) || endChar < 0 || endChar > len(line) {
+		length := utilities.CharacterLength(line, encoding)
+		if loc.Range.Start.Character > length || loc.Range.End.Character > length {
 			return "", fmt.Errorf("invalid character range: %v", loc.Range)
 		}
 
+		startChar := utilities.ByteOffset(line, loc.Range.Start.Character, encoding)
+		endChar := utilities.ByteOffset(line, loc.Range.End.Character, encoding)
+		if endChar < startChar {
+			return "", fmt.Errorf("invalid character range: %v", loc.Range)
+		}
 		return line[startChar:endChar], nil
 	}
 
@@ -44,11 +52,10 @@ func ExtractTextFromLocation(loc protocol.Location) (string, error) {
 
 	// First line
 	firstLine := lines[startLine]
-	startChar := int(loc.Range.Start.Character)
-	if startChar < 0 || startChar > len(firstLine) {
+	if loc.Range.Start.Character > utilities.CharacterLength(firstLine, encoding) {
 		return "", fmt.Errorf("invalid start character: %v", loc.Range.Start)
 	}
-	result.WriteString(firstLine[startChar:])
+	result.WriteString(firstLine[utilities.ByteOffset(firstLine, loc.Range.Start.Character, encoding):])
 
 	// Middle lines
 	for i := startLine + 1; i < endLine; i++ {
@@ -58,12 +65,11 @@ func ExtractTextFromLocation(loc protocol.Location) (string, error) {
 
 	// Last line
 	lastLine := lines[endLine]
-	endChar := int(loc.Range.End.Character)
-	if endChar < 0 || endChar > len(lastLine) {
+	if loc.Range.End.Character > utilities.CharacterLength(lastLine, encoding) {
 		return "", fmt.Errorf("invalid end character: %v", loc.Range.End)
 	}
 	result.WriteString("\n")
-	result.WriteString(lastLine[:endChar])
+	result.WriteString(lastLine[:utilities.ByteOffset(lastLine, loc.Range.End.Character, encoding)])
 
 	return result.String(), nil
 }
@@ -81,6 +87,63 @@ func containsPosition(r protocol.Range, p protocol.Position) bool {
 	return true
 }
 
+// symbolNameMatches reports whether a workspace symbol is an exact match for the
+// requested name. workspace/symbol may return a large number of fuzzy matches.
+func symbolNameMatches(symbol protocol.WorkspaceSymbolResult, symbolName string) bool {
+	name := symbol.GetName()
+	if name == symbolName {
+		return true
+	}
+
+	// For qualified names like "Type.Method", also accept the unqualified method
+	// name for languages that don't use qualified names in symbols
+	if strings.Contains(symbolName, ".") {
+		parts := strings.Split(symbolName, ".")
+		return name == parts[len(parts)-1]
+	}
+
+	// For unqualified method names, match Type.symbolName or Type::symbolName
+	if si, ok := symbol.(*protocol.SymbolInformation); ok && si.Kind == protocol.Method {
+		return strings.HasSuffix(name, "."+symbolName) || strings.HasSuffix(name, "::"+symbolName)
+	}
+
+	return false
+}
+
+// symbolNamePosition returns the 1-indexed line and column of the symbol's name
+// within its location. Symbol ranges often start at a keyword such as "func" or
+// "class", which has no useful hover or rename target, so look for the name itself.
+func symbolNamePosition(loc protocol.Location, name string, encoding protocol.PositionEncodingKind) (int, int) {
+	line := int(loc.Range.Start.Line) + 1
+	column := int(loc.Range.Start.Character) + 1
+
+	// Strip any qualifier from the name, e.g. "Type.Method" or "Type::Method"
+	if i := strings.LastIndexAny(name, ".:"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	lineText, err := ExtractTextFromLocation(protocol.Location{
+		URI: loc.URI,
+		Range: protocol.Range{
+			Start: protocol.Position{Line: loc.Range.Start.Line, Character: 0},
+			End:   protocol.Position{Line: loc.Range.Start.Line + 1, Character: 0},
+		},
+	}, encoding)
+	if err != nil {
+		return line, column
+	}
+	column = utilities.CharacterToColumn(lineText, loc.Range.Start.Character, encoding)
+	if name == "" {
+		return line, column
+	}
+
+	start := utilities.ByteOffset(lineText, loc.Range.Start.Character, encoding)
+	if idx := strings.Index(lineText[start:], name); idx >= 0 {
+		column += utf8.RuneCountInString(lineText[start : start+idx])
+	}
+	return line, column
+}
+
 // addLineNumbers adds line numbers to each line of text with proper padding, starting from startLine
 func addLineNumbers(text string, startLine int) string {
 	lines := strings.Split(text, "\n")
@@ -167,3 +230,109 @@ func FormatLinesWithRanges(lines []string, ranges []LineRange) string {
 
 	return result.String()
 }
+
+// previewEdit renders the changes a workspace edit would make as unified diffs
+// for a dry run, after a summary of what the tool would do
+func previewEdit(client *lsp.Client, summary string, edit protocol.WorkspaceEdit) (string, error) {
+	diff, err := utilities.PreviewWorkspaceEdit(edit, client.PositionEncoding())
+	if err != nil {
+		return "", fmt.Errorf("failed to preview changes: %v", err)
+	}
+	return dryRunResult(summary, diff), nil
+}
+
+// dryRunResult formats the result of a dry run
+func dryRunResult(summary string, diff string) string {
+	if diff == "" {
+		return fmt.Sprintf("Dry run, no files were changed. %s\nThe edit makes no changes.", summary)
+	}
+	return fmt.Sprintf("Dry run, no files were changed. %s\n\n%s", summary, diff)
+}
+
+// checkNotStaged refuses to edit a file on disk while it has staged edits, since the
+// server would not see the change and committing the staged edits would undo it
+func checkNotStaged(client *lsp.Client, path string) error {
+	if client.HasStagedEdits(path) {
+		return fmt.Errorf("%s has staged edits, commit or discard them before editing the file on disk", path)
+	}
+	return nil
+}
+
+// preSaveNote is added to a tool's result when the server's pre-save edits changed
+// a file after the tool wrote it
+const preSaveNote = " The language server's pre-save edits also changed the file, so line numbers may differ."
+
+// saveEditedFile tells the server that a file a tool edited was saved, which applies
+// the server's pre-save edits when save actions are enabled. It reports whether
+// those edits changed the file.
+func saveEditedFile(ctx context.Context, client *lsp.Client, path string) bool {
+	changed, err := client.SaveFile(ctx, path)
+	if err != nil {
+		toolsLogger.Error("Error saving file: %v", err)
+	}
+	return changed
+}
+
+// syncEditedFiles sends the new content of the open files a workspace edit changed
+// to the server and saves them
+func syncEditedFiles(ctx context.Context, client *lsp.Client, edit protocol.WorkspaceEdit) {
+	for _, path := range editedFiles(edit) {
+		if !client.IsFileOpen(path) {
+			continue
+		}
+		if err := client.NotifyChange(ctx, path); err != nil {
+			toolsLogger.Error("Error notifying change: %v", err)
+			continue
+		}
+		saveEditedFile(ctx, client, path)
+	}
+}
+
+// documentLines converts between the one-indexed columns tools take and show, which
+// count Unicode characters, and the characters of positions, which are counted in
+// the server's position encoding. Each file is read once.
+type documentLines struct {
+	client *lsp.Client
+	files  map[string][]string
+}
+
+func newDocumentLines(client *lsp.Client) *documentLines {
+	return &documentLines{client: client, files: make(map[string][]string)}
+}
+
+// line returns a line of a file without its line ending, or "" when it cannot be
+// read, in which case columns and characters are taken to be the same
+func (d *documentLines) line(path string, line uint32) string {
+	lines, ok := d.files[path]
+	if !ok {
+		if content, err := d.client.ReadDocument(path); err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		d.files[path] = lines
+	}
+	if int(line) >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line], "\r")
+}
+
+// position converts a one-indexed line and column in a file to a position
+func (d *documentLines) position(path string, line, column int) protocol.Position {
+	if line < 1 {
+		return protocol.Position{Character: uint32(max(column-1, 0))}
+	}
+	return protocol.Position{
+		Line:      uint32(line - 1),
+		Character: utilities.ColumnToCharacter(d.line(path, uint32(line-1)), column, d.client.PositionEncoding()),
+	}
+}
+
+// column returns the one-indexed column of a position in a document
+func (d *documentLines) column(uri protocol.DocumentUri, pos protocol.Position) int {
+	return utilities.CharacterToColumn(d.line(uri.Path(), pos.Line), pos.Character, d.client.PositionEncoding())
+}
+
+// toPosition converts a one-indexed line and column in a file to a position
+func toPosition(client *lsp.Client, filePath string, line, column int) protocol.Position {
+	return newDocumentLines(client).position(filePath, line, column)
+}
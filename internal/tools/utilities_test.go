@@ -454,3 +454,55 @@ func TestFormatLinesWithRanges(t *testing.T) {
 		})
 	}
 }
+
+func TestSymbolNameMatches(t *testing.T) {
+	testCases := []struct {
+		name       string
+		symbol     protocol.WorkspaceSymbolResult
+		symbolName string
+		expected   bool
+	}{
+		{
+			name:       "Exact match",
+			symbol:     &protocol.SymbolInformation{Name: "FooBar", Kind: protocol.Function},
+			symbolName: "FooBar",
+			expected:   true,
+		},
+		{
+			name:       "Fuzzy match is rejected",
+			symbol:     &protocol.SymbolInformation{Name: "FooBarBaz", Kind: protocol.Function},
+			symbolName: "FooBar",
+			expected:   false,
+		},
+		{
+			name:       "Qualified name matches unqualified symbol",
+			symbol:     &protocol.SymbolInformation{Name: "Method", Kind: protocol.Method},
+			symbolName: "Type.Method",
+			expected:   true,
+		},
+		{
+			name:       "Unqualified method name matches qualified symbol",
+			symbol:     &protocol.SymbolInformation{Name: "Type.Method", Kind: protocol.Method},
+			symbolName: "Method",
+			expected:   true,
+		},
+		{
+			name:       "Unqualified method name matches C++ style symbol",
+			symbol:     &protocol.SymbolInformation{Name: "Type::Method", Kind: protocol.Method},
+			symbolName: "Method",
+			expected:   true,
+		},
+		{
+			name:       "Suffix match only applies to methods",
+			symbol:     &protocol.SymbolInformation{Name: "pkg.Method", Kind: protocol.Function},
+			symbolName: "Method",
+			expected:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, symbolNameMatches(tc.symbol, tc.symbolName))
+		})
+	}
+}
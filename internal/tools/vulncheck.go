@@ -0,0 +1,287 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// maxVulnCallSites bounds how many call sites are listed for each vulnerability
+const maxVulnCallSites = 5
+
+// vulncheckArgs is the argument of gopls' gopls.run_govulncheck and
+// gopls.fetch_vulncheck_result commands
+type vulncheckArgs struct {
+	URI     protocol.DocumentUri
+	Pattern string `json:",omitempty"`
+}
+
+// vulncheckResult is the result gopls.fetch_vulncheck_result reports for a go.mod file
+type vulncheckResult struct {
+	Entries  map[string]*vulnEntry
+	Findings []*vulnFinding
+	Mode     string
+	AsOf     time.Time
+}
+
+// vulnEntry is the part of an OSV entry that is reported
+type vulnEntry struct {
+	ID      string   `json:"id"`
+	Summary string   `json:"summary"`
+	Details string   `json:"details"`
+	Aliases []string `json:"aliases"`
+}
+
+// vulnFinding is a govulncheck finding. The trace starts at the vulnerable module,
+// package or function and, for called vulnerabilities, ends in the user's code.
+type vulnFinding struct {
+	OSV          string       `json:"osv"`
+	FixedVersion string       `json:"fixed_version"`
+	Trace        []*vulnFrame `json:"trace"`
+}
+
+type vulnFrame struct {
+	Module   string `json:"module"`
+	Version  string `json:"version"`
+	Package  string `json:"package"`
+	Function string `json:"function"`
+	Receiver string `json:"receiver"`
+	Position *struct {
+		Filename string `json:"filename"`
+		Line     int    `json:"line"`
+		Column   int    `json:"column"`
+	} `json:"position"`
+}
+
+// Levels at which a module is affected by a vulnerability, from most to least severe
+const (
+	vulnCalled = iota
+	vulnImported
+	vulnRequired
+)
+
+var vulnLevelNames = []string{"called", "imported", "required"}
+
+// vulnReport gathers the findings for one vulnerability
+type vulnReport struct {
+	id        string
+	level     int
+	module    string
+	version   string
+	fixed     string
+	callSites []string
+}
+
+// Vulncheck runs govulncheck through gopls on the packages matching pattern in the
+// Go module containing dir, and reports the known vulnerabilities that affect them
+func Vulncheck(ctx context.Context, client *lsp.Client, dir, pattern string, timeout time.Duration) (string, error) {
+	if !client.SupportsCommand("gopls.run_govulncheck") {
+		return "", fmt.Errorf("the language server does not support gopls.run_govulncheck, vulncheck needs gopls")
+	}
+	if pattern == "" {
+		pattern = "./..."
+	}
+
+	goMod, err := findGoMod(dir)
+	if err != nil {
+		return "", err
+	}
+	uri := fileuri.FromPath(goMod)
+	moduleDir := filepath.Dir(goMod)
+
+	runArgs, err := json.Marshal(vulncheckArgs{URI: uri, Pattern: pattern})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal govulncheck arguments: %v", err)
+	}
+	fetchArgs, err := json.Marshal(vulncheckArgs{URI: uri})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal govulncheck arguments: %v", err)
+	}
+
+	// The command starts govulncheck in the background and returns the token of
+	// the work done progress that ends when it finishes
+	result, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
+		Command:   "gopls.run_govulncheck",
+		Arguments: []json.RawMessage{runArgs},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start govulncheck: %v", err)
+	}
+	started, ok := result.(map[string]any)
+	if !ok || started["Token"] == nil {
+		return "", fmt.Errorf("unexpected gopls.run_govulncheck result: %v", result)
+	}
+
+	message, err := client.WaitForProgress(ctx, fmt.Sprint(started["Token"]), timeout)
+	if err != nil {
+		return "", fmt.Errorf("govulncheck did not finish: %v", err)
+	}
+	toolsLogger.Debug("govulncheck finished: %s", message)
+
+	fetched, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
+		Command:   "gopls.fetch_vulncheck_result",
+		Arguments: []json.RawMessage{fetchArgs},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch govulncheck results: %v", err)
+	}
+
+	// The result is decoded generically, so round trip it through JSON
+	data, err := json.Marshal(fetched)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal govulncheck results: %v", err)
+	}
+	var results map[protocol.DocumentUri]*vulncheckResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return "", fmt.Errorf("failed to parse govulncheck results: %v", err)
+	}
+	vulns := results[uri]
+	if vulns == nil {
+		if message != "" {
+			return "", fmt.Errorf("no govulncheck results for %s: %s", goMod, message)
+		}
+		return "", fmt.Errorf("no govulncheck results for %s", goMod)
+	}
+
+	return formatVulncheckResult(vulns, moduleDir, pattern), nil
+}
+
+// formatVulncheckResult lists the vulnerabilities in a result, those the code
+// calls first
+func formatVulncheckResult(result *vulncheckResult, moduleDir, pattern string) string {
+	reports := make(map[string]*vulnReport)
+	for _, finding := range result.Findings {
+		if len(finding.Trace) == 0 {
+			continue
+		}
+		vulnerable := finding.Trace[0]
+
+		level := vulnRequired
+		if vulnerable.Function != "" {
+			level = vulnCalled
+		} else if vulnerable.Package != "" {
+			level = vulnImported
+		}
+
+		report, ok := reports[finding.OSV]
+		if !ok {
+			report = &vulnReport{id: finding.OSV, level: level}
+			reports[finding.OSV] = report
+		}
+		report.level = min(report.level, level)
+		if report.module == "" {
+			report.module, report.version = vulnerable.Module, vulnerable.Version
+		}
+		if report.fixed == "" {
+			report.fixed = finding.FixedVersion
+		}
+		if level == vulnCalled && len(report.callSites) < maxVulnCallSites {
+			if site := vulnCallSite(finding.Trace, moduleDir); site != "" {
+				report.callSites = append(report.callSites, site)
+			}
+		}
+	}
+
+	var sorted []*vulnReport
+	for _, report := range reports {
+		sorted = append(sorted, report)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].level != sorted[j].level {
+			return sorted[i].level < sorted[j].level
+		}
+		return sorted[i].id < sorted[j].id
+	})
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("govulncheck of %s in %s", pattern, moduleDir))
+	if !result.AsOf.IsZero() {
+		output.WriteString(fmt.Sprintf(", vulnerability database as of %s", result.AsOf.Format("2006-01-02")))
+	}
+	if len(sorted) == 0 {
+		output.WriteString(": no known vulnerabilities affect this module.\n")
+		return output.String()
+	}
+
+	var called int
+	for _, report := range sorted {
+		if report.level == vulnCalled {
+			called++
+		}
+	}
+	output.WriteString(fmt.Sprintf(": %d vulnerabilities, %d called by the code.\n", len(sorted), called))
+
+	for _, report := range sorted {
+		output.WriteString("\n" + report.id)
+		if entry := result.Entries[report.id]; entry != nil {
+			if len(entry.Aliases) > 0 {
+				output.WriteString(" (" + strings.Join(entry.Aliases, ", ") + ")")
+			}
+			if entry.Summary != "" {
+				output.WriteString(": " + entry.Summary)
+			}
+		}
+		output.WriteString(fmt.Sprintf("\n  Level: %s\n", vulnLevelNames[report.level]))
+		output.WriteString(fmt.Sprintf("  Module: %s@%s", report.module, report.version))
+		if report.fixed != "" {
+			output.WriteString(", fixed in " + report.fixed)
+		} else {
+			output.WriteString(", no fixed version")
+		}
+		output.WriteString("\n")
+		for _, site := range report.callSites {
+			output.WriteString("  Call: " + site + "\n")
+		}
+		output.WriteString(fmt.Sprintf("  More info: https://pkg.go.dev/vuln/%s\n", report.id))
+	}
+
+	return output.String()
+}
+
+// vulnCallSite describes where the code calls into a vulnerable function: the last
+// frame of the trace is the caller in the module, the first is the vulnerable function
+func vulnCallSite(trace []*vulnFrame, moduleDir string) string {
+	caller := trace[len(trace)-1]
+	if caller.Position == nil || len(trace) < 2 {
+		return ""
+	}
+	filename := caller.Position.Filename
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(moduleDir, filename)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s calls %s", filename, caller.Position.Line, caller.Position.Column,
+		vulnFunctionName(caller), vulnFunctionName(trace[0]))
+}
+
+// vulnFunctionName returns the qualified name of a frame's function
+func vulnFunctionName(frame *vulnFrame) string {
+	name := frame.Function
+	if frame.Receiver != "" {
+		name = strings.TrimPrefix(frame.Receiver, "*") + "." + name
+	}
+	return frame.Package + "." + name
+}
+
+// findGoMod returns the go.mod file of the module containing dir
+func findGoMod(dir string) (string, error) {
+	for current := dir; ; {
+		goMod := filepath.Join(current, "go.mod")
+		if _, err := os.Stat(goMod); err == nil {
+			return goMod, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("%s is not in a Go module", dir)
+		}
+		current = parent
+	}
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatVulncheckResult(t *testing.T) {
+	data := `{
+		"Entries": {
+			"GO-2023-0001": {"id": "GO-2023-0001", "summary": "Denial of service in net/http2", "aliases": ["CVE-2023-1"]},
+			"GO-2023-0002": {"id": "GO-2023-0002", "summary": "Panic in yaml"}
+		},
+		"Findings": [
+			{"osv": "GO-2023-0002", "fixed_version": "v3.0.1", "trace": [
+				{"module": "gopkg.in/yaml.v3", "version": "v3.0.0", "package": "gopkg.in/yaml.v3"}
+			]},
+			{"osv": "GO-2023-0001", "fixed_version": "v0.17.0", "trace": [
+				{"module": "golang.org/x/net", "version": "v0.1.0"}
+			]},
+			{"osv": "GO-2023-0001", "fixed_version": "v0.17.0", "trace": [
+				{"module": "golang.org/x/net", "version": "v0.1.0", "package": "golang.org/x/net/http2", "function": "ServeConn", "receiver": "*Server"},
+				{"module": "example.com/app", "package": "example.com/app", "function": "serve",
+					"position": {"filename": "server.go", "line": 12, "column": 5}}
+			]}
+		],
+		"Mode": "govulncheck",
+		"AsOf": "2026-01-02T00:00:00Z"
+	}`
+
+	var result vulncheckResult
+	require.NoError(t, json.Unmarshal([]byte(data), &result))
+
+	expected := `govulncheck of ./... in /ws, vulnerability database as of 2026-01-02: 2 vulnerabilities, 1 called by the code.
+
+GO-2023-0001 (CVE-2023-1): Denial of service in net/http2
+  Level: called
+  Module: golang.org/x/net@v0.1.0, fixed in v0.17.0
+  Call: /ws/server.go:12:5: example.com/app.serve calls golang.org/x/net/http2.Server.ServeConn
+  More info: https://pkg.go.dev/vuln/GO-2023-0001
+
+GO-2023-0002: Panic in yaml
+  Level: imported
+  Module: gopkg.in/yaml.v3@v3.0.0, fixed in v3.0.1
+  More info: https://pkg.go.dev/vuln/GO-2023-0002
+`
+	assert.Equal(t, expected, formatVulncheckResult(&result, "/ws", "./..."))
+
+	empty := formatVulncheckResult(&vulncheckResult{}, "/ws", "./cmd/...")
+	assert.Equal(t, "govulncheck of ./cmd/... in /ws: no known vulnerabilities affect this module.\n", empty)
+}
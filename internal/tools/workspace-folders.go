@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+)
+
+// AddWorkspaceFolder attaches a project directory to the running language server and
+// starts watching it for changes. Once added, tools accept the paths inside it, so
+// unless allowedRoots is nil the directory must be inside one of them.
+func AddWorkspaceFolder(ctx context.Context, client *lsp.Client, workspaceWatcher *watcher.WorkspaceWatcher, path string, allowedRoots []string) (string, error) {
+	dir := filepath.Clean(client.ResolvePath(path))
+	if allowedRoots != nil && !lsp.InRoots(dir, allowedRoots) {
+		return "", fmt.Errorf("%s is outside the configured workspace folders; start the server with --allow-external-folders to add it", dir)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat workspace folder: %v", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+
+	if err := client.AddWorkspaceFolder(ctx, dir); err != nil {
+		return "", err
+	}
+
+	// The folder's files are opened in the background after the tool call returns
+	if err := workspaceWatcher.AddRoot(context.WithoutCancel(ctx), dir); err != nil {
+		toolsLogger.Error("Error watching workspace folder %s: %v", dir, err)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Added workspace folder %s\n", dir))
+	writeWorkspaceFolders(&output, client)
+	return output.String(), nil
+}
+
+// RemoveWorkspaceFolder detaches a project directory from the running language server
+// and stops watching it
+func RemoveWorkspaceFolder(ctx context.Context, client *lsp.Client, workspaceWatcher *watcher.WorkspaceWatcher, path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %v", err)
+	}
+
+	if err := client.RemoveWorkspaceFolder(ctx, dir); err != nil {
+		return "", err
+	}
+	workspaceWatcher.RemoveRoot(dir)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Removed workspace folder %s\n", dir))
+	writeWorkspaceFolders(&output, client)
+	return output.String(), nil
+}
+
+// writeWorkspaceFolders lists the current workspace folders, noting servers that
+// ignore folder changes
+func writeWorkspaceFolders(output *strings.Builder, client *lsp.Client) {
+	if !client.SupportsWorkspaceFolderChanges() {
+		output.WriteString("Warning: the language server did not ask for workspace folder change notifications and may not pick up this change\n")
+	}
+
+	output.WriteString("\nWorkspace folders:\n")
+	for i, root := range client.WorkspaceRoots() {
+		if i == 0 {
+			output.WriteString(fmt.Sprintf("- %s (primary)\n", root))
+		} else {
+			output.WriteString(fmt.Sprintf("- %s\n", root))
+		}
+	}
+}
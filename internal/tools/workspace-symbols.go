@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// SearchWorkspaceSymbols searches for symbols across the workspace using workspace/symbol.
+// The query is matched fuzzily by the language server unless exactMatch is set.
+func SearchWorkspaceSymbols(ctx context.Context, client *lsp.Client, query string, limit int, exactMatch bool) (string, error) {
+	matches, err := matchWorkspaceSymbols(ctx, client, query, exactMatch)
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return fmt.Sprintf("No symbols found matching: %s", query), nil
+	}
+
+	total := len(matches)
+	if limit > 0 && total > limit {
+		matches = matches[:limit]
+	}
+
+	var output strings.Builder
+	if len(matches) < total {
+		output.WriteString(fmt.Sprintf("Found %d symbols matching %q (showing first %d):\n\n", total, query, len(matches)))
+	} else {
+		output.WriteString(fmt.Sprintf("Found %d symbols matching %q:\n\n", total, query))
+	}
+
+	lines := newDocumentLines(client)
+	for _, symbol := range matches {
+		output.WriteString(formatWorkspaceSymbol(lines, symbol))
+		output.WriteString("\n")
+	}
+
+	return output.String(), nil
+}
+
+// matchWorkspaceSymbols returns the workspace symbols matching a query, only those
+// named exactly like it with exactMatch. While the server is starting, symbols
+// found in the symbol index are returned without waiting for it.
+func matchWorkspaceSymbols(ctx context.Context, client *lsp.Client, query string, exactMatch bool) ([]protocol.WorkspaceSymbolResult, error) {
+	var results []protocol.WorkspaceSymbolResult
+	if index := client.SymbolIndex(); index != nil && !client.ServerReady() {
+		results = index.Search(query)
+		toolsLogger.Debug("Found %d symbols matching %q in the symbol index", len(results), query)
+	}
+	if len(results) == 0 {
+		symbolResult, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{
+			Query: query,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch symbols: %v", err)
+		}
+
+		results, err = symbolResult.Results()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse results: %v", err)
+		}
+	}
+
+	var matches []protocol.WorkspaceSymbolResult
+	for _, symbol := range results {
+		if exactMatch && !symbolNameMatches(symbol, query) {
+			continue
+		}
+		matches = append(matches, symbol)
+	}
+	return matches, nil
+}
+
+// symbolDetails returns the kind and container name of a workspace symbol
+func symbolDetails(symbol protocol.WorkspaceSymbolResult) (protocol.SymbolKind, string) {
+	switch v := symbol.(type) {
+	case *protocol.SymbolInformation:
+		return v.Kind, v.ContainerName
+	case *protocol.WorkspaceSymbol:
+		return v.Kind, v.ContainerName
+	}
+	return 0, ""
+}
+
+// formatWorkspaceSymbol renders a symbol as a single line with its kind, container and location
+func formatWorkspaceSymbol(lines *documentLines, symbol protocol.WorkspaceSymbolResult) string {
+	kind, container := symbolDetails(symbol)
+
+	var line strings.Builder
+	line.WriteString(symbol.GetName())
+	if kindName, ok := protocol.TableKindMap[kind]; ok {
+		line.WriteString(fmt.Sprintf(" [%s]", kindName))
+	}
+	if container != "" {
+		line.WriteString(fmt.Sprintf(" in %s", container))
+	}
+
+	loc := symbol.GetLocation()
+	line.WriteString(fmt.Sprintf(" - %s L%d:C%d-L%d:C%d",
+		fileuri.ToPath(loc.URI),
+		loc.Range.Start.Line+1,
+		lines.column(loc.URI, loc.Range.Start),
+		loc.Range.End.Line+1,
+		lines.column(loc.URI, loc.Range.End),
+	))
+
+	return line.String()
+}
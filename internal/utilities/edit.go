@@ -2,12 +2,15 @@ package utilities
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
@@ -18,11 +21,14 @@ var (
 	osRemove    = os.Remove
 	osRemoveAll = os.RemoveAll
 	osRename    = os.Rename
+	osMkdirAll  = os.MkdirAll
 )
 
-// ApplyTextEdits applies a sequence of text edits to a file specified by URI
-func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
-	path := strings.TrimPrefix(string(uri), "file://")
+// ApplyTextEdits applies a sequence of text edits to a file specified by URI. The
+// edits are applied by position, as computed by a language server that counts
+// characters in the given encoding.
+func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit, encoding protocol.PositionEncodingKind) error {
+	path := fileuri.ToPath(uri)
 
 	// Read the file content
 	content, err := osReadFile(path)
@@ -30,6 +36,101 @@ func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
+	newContent, err := SpliceContent(content, edits, encoding)
+	if err != nil {
+		return err
+	}
+
+	if err := osWriteFile(path, newContent, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// SpliceContent applies text edits to the content of a file by position and
+// returns the new content. Edits may touch but not overlap, and insertions at the
+// same position are made in the order given. New text is written with the file's
+// line endings, and a file that ended with a newline still does unless it is
+// emptied.
+func SpliceContent(content []byte, edits []protocol.TextEdit, encoding protocol.PositionEncodingKind) ([]byte, error) {
+	lineEnding := "\n"
+	if bytes.Contains(content, []byte("\r\n")) {
+		lineEnding = "\r\n"
+	}
+	endsWithNewline := bytes.HasSuffix(content, []byte("\n"))
+
+	// Offsets of the start of each line
+	lineStarts := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offsetOf := func(pos protocol.Position) int {
+		if int(pos.Line) >= len(lineStarts) {
+			return len(content)
+		}
+		start := lineStarts[pos.Line]
+		end := len(content)
+		if int(pos.Line)+1 < len(lineStarts) {
+			end = lineStarts[pos.Line+1] - 1
+		}
+		line := strings.TrimSuffix(string(content[start:end]), "\r")
+		return start + ByteOffset(line, pos.Character, encoding)
+	}
+
+	type splice struct {
+		start, end int
+		text       string
+		index      int
+	}
+	splices := make([]splice, len(edits))
+	for i, edit := range edits {
+		start, end := offsetOf(edit.Range.Start), offsetOf(edit.Range.End)
+		if end < start {
+			return nil, fmt.Errorf("edit %d ends before it starts", i)
+		}
+		text := strings.ReplaceAll(edit.NewText, "\r\n", "\n")
+		if lineEnding != "\n" {
+			text = strings.ReplaceAll(text, "\n", lineEnding)
+		}
+		splices[i] = splice{start: start, end: end, text: text, index: i}
+	}
+
+	// Insertions go before a replacement starting at the same position
+	sort.SliceStable(splices, func(i, j int) bool {
+		if splices[i].start != splices[j].start {
+			return splices[i].start < splices[j].start
+		}
+		return splices[i].end < splices[j].end
+	})
+	for i := 1; i < len(splices); i++ {
+		if splices[i].start < splices[i-1].end {
+			return nil, fmt.Errorf("overlapping edits detected between edit %d and %d", splices[i-1].index, splices[i].index)
+		}
+	}
+
+	var newContent bytes.Buffer
+	last := 0
+	for _, s := range splices {
+		newContent.Write(content[last:s.start])
+		newContent.WriteString(s.text)
+		last = s.end
+	}
+	newContent.Write(content[last:])
+
+	if endsWithNewline && newContent.Len() > 0 && !bytes.HasSuffix(newContent.Bytes(), []byte("\n")) {
+		newContent.WriteString(lineEnding)
+	}
+	return newContent.Bytes(), nil
+}
+
+// EditContent applies a sequence of line-oriented text edits to the content of a
+// file and returns the new content, keeping the file's line endings. Unlike
+// SpliceContent, an edit that empties the lines it covers removes them, which is
+// how edit_file deletes lines.
+func EditContent(content []byte, edits []protocol.TextEdit, encoding protocol.PositionEncodingKind) ([]byte, error) {
 	// Detect line ending style
 	var lineEnding string
 	if bytes.Contains(content, []byte("\r\n")) {
@@ -48,7 +149,7 @@ func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 	for i, edit1 := range edits {
 		for j := i + 1; j < len(edits); j++ {
 			if RangesOverlap(edit1.Range, edits[j].Range) {
-				return fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
+				return nil, fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
 			}
 		}
 	}
@@ -65,9 +166,9 @@ func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 
 	// Apply each edit
 	for _, edit := range sortedEdits {
-		newLines, err := ApplyTextEdit(lines, edit, lineEnding)
+		newLines, err := ApplyTextEdit(lines, edit, lineEnding, encoding)
 		if err != nil {
-			return fmt.Errorf("failed to apply edit: %w", err)
+			return nil, fmt.Errorf("failed to apply edit: %w", err)
 		}
 		lines = newLines
 	}
@@ -86,19 +187,13 @@ func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 		newContent.WriteString(lineEnding)
 	}
 
-	if err := osWriteFile(path, []byte(newContent.String()), 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
+	return []byte(newContent.String()), nil
 }
 
 // ApplyTextEdit applies a single text edit to a set of lines
-func ApplyTextEdit(lines []string, edit protocol.TextEdit, lineEnding string) ([]string, error) {
+func ApplyTextEdit(lines []string, edit protocol.TextEdit, lineEnding string, encoding protocol.PositionEncodingKind) ([]string, error) {
 	startLine := int(edit.Range.Start.Line)
 	endLine := int(edit.Range.End.Line)
-	startChar := int(edit.Range.Start.Character)
-	endChar := int(edit.Range.End.Character)
 
 	// Validate positions
 	if startLine < 0 || startLine >= len(lines) {
@@ -116,17 +211,11 @@ func ApplyTextEdit(lines []string, edit protocol.TextEdit, lineEnding string) ([
 
 	// Get the prefix of the start line
 	startLineContent := lines[startLine]
-	if startChar < 0 || startChar > len(startLineContent) {
-		startChar = len(startLineContent)
-	}
-	prefix := startLineContent[:startChar]
+	prefix := startLineContent[:ByteOffset(startLineContent, edit.Range.Start.Character, encoding)]
 
 	// Get the suffix of the end line
 	endLineContent := lines[endLine]
-	if endChar < 0 || endChar > len(endLineContent) {
-		endChar = len(endLineContent)
-	}
-	suffix := endLineContent[endChar:]
+	suffix := endLineContent[ByteOffset(endLineContent, edit.Range.End.Character, encoding):]
 
 	// Handle the edit
 	if edit.NewText == "" {
@@ -171,46 +260,65 @@ func ApplyTextEdit(lines []string, edit protocol.TextEdit, lineEnding string) ([
 }
 
 // ApplyDocumentChange applies a DocumentChange (create/rename/delete operations)
-func ApplyDocumentChange(change protocol.DocumentChange) error {
+func ApplyDocumentChange(change protocol.DocumentChange, encoding protocol.PositionEncodingKind) error {
 	if change.CreateFile != nil {
-		path := strings.TrimPrefix(string(change.CreateFile.URI), "file://")
-		if change.CreateFile.Options != nil {
-			if change.CreateFile.Options.Overwrite {
+		path := fileuri.ToPath(change.CreateFile.URI)
+		if _, err := osStat(path); err == nil {
+			options := change.CreateFile.Options
+			switch {
+			case options != nil && options.Overwrite:
 				// Proceed with overwrite
-			} else if change.CreateFile.Options.IgnoreIfExists {
-				if _, err := osStat(path); err == nil {
-					return nil // File exists and we're ignoring it
-				}
+			case options != nil && options.IgnoreIfExists:
+				return nil // File exists and we're ignoring it
+			default:
+				return fmt.Errorf("file already exists and overwrite is not allowed: %s", path)
 			}
 		}
+		if err := osMkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
 		if err := osWriteFile(path, []byte(""), 0644); err != nil {
 			return fmt.Errorf("failed to create file: %w", err)
 		}
 	}
 
 	if change.DeleteFile != nil {
-		path := strings.TrimPrefix(string(change.DeleteFile.URI), "file://")
-		if change.DeleteFile.Options != nil && change.DeleteFile.Options.Recursive {
-			if err := osRemoveAll(path); err != nil {
-				return fmt.Errorf("failed to delete directory recursively: %w", err)
-			}
+		path := fileuri.ToPath(change.DeleteFile.URI)
+		options := change.DeleteFile.Options
+		var err error
+		if options != nil && options.Recursive {
+			err = osRemoveAll(path)
 		} else {
-			if err := osRemove(path); err != nil {
-				return fmt.Errorf("failed to delete file: %w", err)
-			}
+			err = osRemove(path)
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, os.ErrNotExist) && options != nil && options.IgnoreIfNotExists:
+			// Nothing to delete
+		case options != nil && options.Recursive:
+			return fmt.Errorf("failed to delete directory recursively: %w", err)
+		default:
+			return fmt.Errorf("failed to delete file: %w", err)
 		}
 	}
 
 	if change.RenameFile != nil {
-		oldPath := strings.TrimPrefix(string(change.RenameFile.OldURI), "file://")
-		newPath := strings.TrimPrefix(string(change.RenameFile.NewURI), "file://")
-		if change.RenameFile.Options != nil {
-			if !change.RenameFile.Options.Overwrite {
-				if _, err := osStat(newPath); err == nil {
-					return fmt.Errorf("target file already exists and overwrite is not allowed: %s", newPath)
-				}
+		oldPath := fileuri.ToPath(change.RenameFile.OldURI)
+		newPath := fileuri.ToPath(change.RenameFile.NewURI)
+		if _, err := osStat(newPath); err == nil {
+			options := change.RenameFile.Options
+			switch {
+			case options != nil && options.Overwrite:
+				// Proceed with overwrite
+			case options != nil && options.IgnoreIfExists:
+				return nil // Target exists and we're ignoring it
+			default:
+				return fmt.Errorf("target file already exists and overwrite is not allowed: %s", newPath)
 			}
 		}
+		if err := osMkdirAll(filepath.Dir(newPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
 		if err := osRename(oldPath, newPath); err != nil {
 			return fmt.Errorf("failed to rename file: %w", err)
 		}
@@ -220,37 +328,133 @@ func ApplyDocumentChange(change protocol.DocumentChange) error {
 		textEdits := make([]protocol.TextEdit, len(change.TextDocumentEdit.Edits))
 		for i, edit := range change.TextDocumentEdit.Edits {
 			var err error
-			textEdits[i], err = edit.AsTextEdit()
+			textEdits[i], err = TextEditOf(edit)
 			if err != nil {
 				return fmt.Errorf("invalid edit type: %w", err)
 			}
 		}
-		return ApplyTextEdits(change.TextDocumentEdit.TextDocument.URI, textEdits)
+		return ApplyTextEdits(change.TextDocumentEdit.TextDocument.URI, textEdits, encoding)
 	}
 
 	return nil
 }
 
-// ApplyWorkspaceEdit applies the given WorkspaceEdit to the filesystem
-func ApplyWorkspaceEdit(edit protocol.WorkspaceEdit) error {
-	// Handle Changes field
-	for uri, textEdits := range edit.Changes {
-		if err := ApplyTextEdits(uri, textEdits); err != nil {
+// ApplyWorkspaceEdit applies the given WorkspaceEdit, with positions counted in the
+// given encoding, to the filesystem. The edit is applied atomically: if any change
+// fails, files touched so far are restored.
+func ApplyWorkspaceEdit(edit protocol.WorkspaceEdit, encoding protocol.PositionEncodingKind) error {
+	tx := &editTransaction{encoding: encoding}
+	if err := tx.apply(edit); err != nil {
+		if rollbackErr := tx.rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// editTransaction records how to undo each change made while applying a
+// WorkspaceEdit so that a partially applied edit can be rolled back
+type editTransaction struct {
+	encoding protocol.PositionEncodingKind
+	undo     []func() error
+}
+
+// apply applies each change in the edit, recording an undo step once each one succeeds
+func (tx *editTransaction) apply(edit protocol.WorkspaceEdit) error {
+	// Handle Changes field in a stable order
+	uris := make([]string, 0, len(edit.Changes))
+	for uri := range edit.Changes {
+		uris = append(uris, string(uri))
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		undo := backupFile(fileuri.ToPath(uri))
+		if err := ApplyTextEdits(protocol.DocumentUri(uri), edit.Changes[protocol.DocumentUri(uri)], tx.encoding); err != nil {
 			return fmt.Errorf("failed to apply text edits: %w", err)
 		}
+		tx.record(undo)
 	}
 
 	// Handle DocumentChanges field
 	for _, change := range edit.DocumentChanges {
 		coreLogger.Warn("Document change: %v", spew.Sdump(change))
-		if err := ApplyDocumentChange(change); err != nil {
+		undo := backupDocumentChange(change)
+		if err := ApplyDocumentChange(change, tx.encoding); err != nil {
 			return fmt.Errorf("failed to apply document change: %w", err)
 		}
+		tx.record(undo...)
+	}
+
+	return nil
+}
+
+// record adds undo steps to the transaction, skipping nil steps
+func (tx *editTransaction) record(undo ...func() error) {
+	for _, u := range undo {
+		if u != nil {
+			tx.undo = append(tx.undo, u)
+		}
 	}
+}
 
+// backupFile captures the current state of a file and returns a function that
+// restores it. Files that do not exist yet are removed on restore.
+func backupFile(path string) func() error {
+	content, err := osReadFile(path)
+	switch {
+	case err == nil:
+		return func() error {
+			return osWriteFile(path, content, 0644)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		return func() error {
+			if err := osRemove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			return nil
+		}
+	default:
+		// Directories and unreadable files cannot be restored
+		coreLogger.Warn("Cannot back up %s for rollback: %v", path, err)
+		return nil
+	}
+}
+
+// backupDocumentChange returns the steps needed to undo a single resource or
+// text document change, in the order they should be recorded
+func backupDocumentChange(change protocol.DocumentChange) []func() error {
+	switch {
+	case change.CreateFile != nil:
+		return []func() error{backupFile(fileuri.ToPath(change.CreateFile.URI))}
+	case change.DeleteFile != nil:
+		return []func() error{backupFile(fileuri.ToPath(change.DeleteFile.URI))}
+	case change.RenameFile != nil:
+		oldPath := fileuri.ToPath(change.RenameFile.OldURI)
+		newPath := fileuri.ToPath(change.RenameFile.NewURI)
+		// Steps run in reverse: move the file back, then restore anything the rename overwrote
+		return []func() error{
+			backupFile(newPath),
+			func() error { return osRename(newPath, oldPath) },
+		}
+	case change.TextDocumentEdit != nil:
+		return []func() error{backupFile(fileuri.ToPath(change.TextDocumentEdit.TextDocument.URI))}
+	}
 	return nil
 }
 
+// rollback undoes recorded changes in reverse order
+func (tx *editTransaction) rollback() error {
+	var errs []error
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		if err := tx.undo[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // RangesOverlap checks if two ranges overlap in position
 func RangesOverlap(r1, r2 protocol.Range) bool {
 	if r1.Start.Line > r2.End.Line || r2.Start.Line > r1.End.Line {
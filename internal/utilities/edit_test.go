@@ -27,6 +27,7 @@ func setupMockFileSystem(_ *testing.T, mfs *mockFileSystem) func() {
 	originalRemove := osRemove
 	originalRemoveAll := osRemoveAll
 	originalRename := osRename
+	originalMkdirAll := osMkdirAll
 
 	// Replace with mocks
 	osReadFile = func(filename string) ([]byte, error) {
@@ -96,6 +97,13 @@ func setupMockFileSystem(_ *testing.T, mfs *mockFileSystem) func() {
 		return os.ErrNotExist
 	}
 
+	osMkdirAll = func(path string, perm os.FileMode) error {
+		if err, ok := mfs.errors[path+"_mkdirall"]; ok {
+			return err
+		}
+		return nil
+	}
+
 	// Return cleanup function
 	return func() {
 		osReadFile = originalReadFile
@@ -104,6 +112,7 @@ func setupMockFileSystem(_ *testing.T, mfs *mockFileSystem) func() {
 		osRemove = originalRemove
 		osRemoveAll = originalRemoveAll
 		osRename = originalRename
+		osMkdirAll = originalMkdirAll
 	}
 }
 
@@ -445,7 +454,7 @@ func TestApplyTextEdit(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := ApplyTextEdit(tt.lines, tt.edit, tt.lineEnding)
+			result, err := ApplyTextEdit(tt.lines, tt.edit, tt.lineEnding, protocol.UTF16)
 			if tt.expectErr {
 				if err == nil {
 					t.Errorf("Expected error but got none")
@@ -644,7 +653,7 @@ func TestApplyTextEdits(t *testing.T) {
 			cleanup := setupMockFileSystem(t, mfs)
 			defer cleanup()
 
-			err := ApplyTextEdits(tt.uri, tt.edits)
+			err := ApplyTextEdits(tt.uri, tt.edits, protocol.UTF16)
 			if tt.expectErr {
 				if err == nil {
 					t.Errorf("Expected error but got none")
@@ -743,6 +752,40 @@ func TestApplyDocumentChange(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Create file - exists without options",
+			change: protocol.DocumentChange{
+				CreateFile: &protocol.CreateFile{
+					URI: "file:///test/existing.txt",
+				},
+			},
+			expectErr: true,
+			setupMocks: func(mfs *mockFileSystem) {
+				mfs.files = map[string][]byte{
+					"/test/existing.txt": []byte("existing content"),
+				}
+				mfs.fileStats = map[string]os.FileInfo{
+					"/test/existing.txt": mockFileInfo{name: "existing.txt"},
+				}
+			},
+			checkState: func(t *testing.T, mfs *mockFileSystem) {},
+		},
+		{
+			name: "Delete file - ignore if not exists",
+			change: protocol.DocumentChange{
+				DeleteFile: &protocol.DeleteFile{
+					URI: "file:///test/missing.txt",
+					Options: &protocol.DeleteFileOptions{
+						IgnoreIfNotExists: true,
+					},
+				},
+			},
+			expectErr: false,
+			setupMocks: func(mfs *mockFileSystem) {
+				mfs.files = map[string][]byte{}
+			},
+			checkState: func(t *testing.T, mfs *mockFileSystem) {},
+		},
 		{
 			name: "Delete file",
 			change: protocol.DocumentChange{
@@ -896,7 +939,7 @@ func TestApplyDocumentChange(t *testing.T) {
 			cleanup := setupMockFileSystem(t, mfs)
 			defer cleanup()
 
-			err := ApplyDocumentChange(tt.change)
+			err := ApplyDocumentChange(tt.change, protocol.UTF16)
 			if tt.expectErr {
 				if err == nil {
 					t.Errorf("Expected error but got none")
@@ -1100,7 +1143,7 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 			cleanup := setupMockFileSystem(t, mfs)
 			defer cleanup()
 
-			err := ApplyWorkspaceEdit(tt.edit)
+			err := ApplyWorkspaceEdit(tt.edit, protocol.UTF16)
 			if tt.expectErr {
 				if err == nil {
 					t.Errorf("Expected error but got none")
@@ -1114,3 +1157,207 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyWorkspaceEditRollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		edit       protocol.WorkspaceEdit
+		setupMocks func(*mockFileSystem)
+		checkState func(*testing.T, *mockFileSystem)
+	}{
+		{
+			name: "Text edits are restored when a later file fails",
+			edit: protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+					"file:///test/a.txt": {
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 0, Character: 0},
+								End:   protocol.Position{Line: 0, Character: 4},
+							},
+							NewText: "That",
+						},
+					},
+					"file:///test/b.txt": {
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 0, Character: 0},
+								End:   protocol.Position{Line: 0, Character: 4},
+							},
+							NewText: "That",
+						},
+					},
+				},
+			},
+			setupMocks: func(mfs *mockFileSystem) {
+				mfs.files = map[string][]byte{
+					"/test/a.txt": []byte("This is a"),
+					"/test/b.txt": []byte("This is b"),
+				}
+				mfs.errors = map[string]error{
+					"/test/b.txt_write": errors.New("disk full"),
+				}
+			},
+			checkState: func(t *testing.T, mfs *mockFileSystem) {
+				if string(mfs.files["/test/a.txt"]) != "This is a" {
+					t.Errorf("a.txt was not restored, content: %s", string(mfs.files["/test/a.txt"]))
+				}
+				if string(mfs.files["/test/b.txt"]) != "This is b" {
+					t.Errorf("b.txt was modified, content: %s", string(mfs.files["/test/b.txt"]))
+				}
+			},
+		},
+		{
+			name: "Created and renamed files are reverted",
+			edit: protocol.WorkspaceEdit{
+				DocumentChanges: []protocol.DocumentChange{
+					{
+						CreateFile: &protocol.CreateFile{
+							URI: "file:///test/newfile.txt",
+						},
+					},
+					{
+						RenameFile: &protocol.RenameFile{
+							OldURI: "file:///test/oldname.txt",
+							NewURI: "file:///test/newname.txt",
+						},
+					},
+					{
+						DeleteFile: &protocol.DeleteFile{
+							URI: "file:///test/missing.txt",
+						},
+					},
+				},
+			},
+			setupMocks: func(mfs *mockFileSystem) {
+				mfs.files = map[string][]byte{
+					"/test/oldname.txt": []byte("file content"),
+				}
+			},
+			checkState: func(t *testing.T, mfs *mockFileSystem) {
+				if _, ok := mfs.files["/test/newfile.txt"]; ok {
+					t.Errorf("Created file was not removed")
+				}
+				if _, ok := mfs.files["/test/newname.txt"]; ok {
+					t.Errorf("Renamed file was not moved back")
+				}
+				if string(mfs.files["/test/oldname.txt"]) != "file content" {
+					t.Errorf("Original file was not restored")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mfs := &mockFileSystem{}
+			tt.setupMocks(mfs)
+			cleanup := setupMockFileSystem(t, mfs)
+			defer cleanup()
+
+			if err := ApplyWorkspaceEdit(tt.edit, protocol.UTF16); err == nil {
+				t.Fatalf("Expected error but got none")
+			}
+			tt.checkState(t, mfs)
+		})
+	}
+}
+
+func TestSpliceContent(t *testing.T) {
+	edit := func(startLine, startChar, endLine, endChar uint32, text string) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: startLine, Character: startChar},
+				End:   protocol.Position{Line: endLine, Character: endChar},
+			},
+			NewText: text,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		content   string
+		edits     []protocol.TextEdit
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "Emptying a line keeps it",
+			content:  "one\ntwo\nthree\n",
+			edits:    []protocol.TextEdit{edit(1, 0, 1, 3, "")},
+			expected: "one\n\nthree\n",
+		},
+		{
+			name:     "Deleting across the line break joins lines",
+			content:  "one\ntwo\nthree\n",
+			edits:    []protocol.TextEdit{edit(0, 3, 1, 3, "")},
+			expected: "one\nthree\n",
+		},
+		{
+			name:     "Touching edits",
+			content:  "abcdef",
+			edits:    []protocol.TextEdit{edit(0, 0, 0, 3, "x"), edit(0, 3, 0, 6, "y")},
+			expected: "xy",
+		},
+		{
+			name:     "Insertions at one position keep their order",
+			content:  "ab",
+			edits:    []protocol.TextEdit{edit(0, 1, 0, 2, "Z"), edit(0, 1, 0, 1, "1"), edit(0, 1, 0, 1, "2")},
+			expected: "a12Z",
+		},
+		{
+			name:     "New text takes CRLF line endings",
+			content:  "one\r\ntwo\r\n",
+			edits:    []protocol.TextEdit{edit(1, 0, 1, 3, "2\nthree")},
+			expected: "one\r\n2\r\nthree\r\n",
+		},
+		{
+			name:     "CRLF is not split by positions",
+			content:  "one\r\ntwo\r\n",
+			edits:    []protocol.TextEdit{edit(0, 10, 0, 10, "!")},
+			expected: "one!\r\ntwo\r\n",
+		},
+		{
+			name:     "Final newline is kept",
+			content:  "one\ntwo\n",
+			edits:    []protocol.TextEdit{edit(1, 0, 2, 0, "end")},
+			expected: "one\nend\n",
+		},
+		{
+			name:     "Emptied file stays empty",
+			content:  "one\n",
+			edits:    []protocol.TextEdit{edit(0, 0, 1, 0, "")},
+			expected: "",
+		},
+		{
+			name:     "Positions past the end refer to the end",
+			content:  "one",
+			edits:    []protocol.TextEdit{edit(5, 0, 5, 0, "\ntwo")},
+			expected: "one\ntwo",
+		},
+		{
+			name:      "Overlapping edits",
+			content:   "abcdef",
+			edits:     []protocol.TextEdit{edit(0, 0, 0, 4, "x"), edit(0, 3, 0, 6, "y")},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SpliceContent([]byte(tt.content), tt.edits, protocol.UTF16)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("SpliceContent() = %q, want %q", string(result), tt.expected)
+			}
+		})
+	}
+}
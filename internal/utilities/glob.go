@@ -0,0 +1,85 @@
+package utilities
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// MatchGlob reports whether a slash separated path matches a glob pattern.
+// Patterns follow the LSP glob syntax: * and ? within a path segment, [...]
+// character ranges, {a,b} alternatives and ** for any number of segments.
+func MatchGlob(pattern, path string) bool {
+	segments := strings.Split(path, "/")
+	for _, alternative := range ExpandBraces(pattern) {
+		if matchSegments(strings.Split(alternative, "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandBraces expands {a,b} alternatives into separate patterns
+func ExpandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	depth := 0
+	alternativeStart := start + 1
+	var alternatives []string
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, pattern[alternativeStart:i])
+				alternativeStart = i + 1
+			}
+		case '}':
+			depth--
+			if depth == 0 {
+				alternatives = append(alternatives, pattern[alternativeStart:i])
+				var expanded []string
+				for _, alternative := range alternatives {
+					expanded = append(expanded, ExpandBraces(pattern[:start]+alternative+pattern[i+1:])...)
+				}
+				return expanded
+			}
+		}
+	}
+
+	// Unbalanced braces are matched literally
+	return []string{pattern}
+}
+
+// matchSegments matches path segments against pattern segments, where a **
+// segment matches zero or more path segments
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(path) == 0 {
+			return false
+		}
+		// LSP globs negate ranges with [!...] where filepath.Match uses [^...]
+		matched, err := filepath.Match(strings.ReplaceAll(pattern[0], "[!", "[^"), path[0])
+		if err != nil {
+			coreLogger.Error("Error matching pattern %s: %v", pattern[0], err)
+			return false
+		}
+		if !matched {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
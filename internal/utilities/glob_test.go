@@ -0,0 +1,58 @@
+package utilities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"**/*.go", "/home/user/project/main.go", true},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "/home/user/project/go", false},
+		{"*.go", "main.go", true},
+		{"*.go", "internal/main.go", false},
+		{"**/Cargo.toml", "/project/Cargo.toml", true},
+		{"**/Cargo.toml", "/project/NotCargo.toml", false},
+		{"**/*.{ts,tsx}", "/project/src/app.tsx", true},
+		{"**/*.{ts,tsx}", "/project/src/app.js", false},
+		{"src/**/*.rs", "src/main.rs", true},
+		{"src/**/*.rs", "src/a/b/lib.rs", true},
+		{"src/**/*.rs", "tests/lib.rs", false},
+		{"docs/**", "docs", true},
+		{"docs/**", "docs/guide/index.md", true},
+		{"**/.settings/*.prefs", "/project/.settings/org.eclipse.prefs", true},
+		{"example.[0-9]", "example.1", true},
+		{"example.[!0-9]", "example.1", false},
+		{"file?.txt", "file1.txt", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"*.go"}},
+		{"*.{go,mod}", []string{"*.go", "*.mod"}},
+		{"{a,b{c,d}}/x", []string{"a/x", "bc/x", "bd/x"}},
+		{"{src,lib}/*.{js,ts}", []string{"src/*.js", "src/*.ts", "lib/*.js", "lib/*.ts"}},
+		{"unbalanced{", []string{"unbalanced{"}},
+	}
+
+	for _, tt := range tests {
+		if got := ExpandBraces(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandBraces(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package utilities
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// maxJournalEntries is how many edits the journal keeps before dropping the oldest
+const maxJournalEntries = 50
+
+// EditJournal records the edits applied to the workspace so they can be undone
+var EditJournal = &Journal{}
+
+// Journal keeps the contents files had before each edit, so that an edit can be
+// reverted without involving version control
+type Journal struct {
+	mu      sync.Mutex
+	entries []*JournalEntry
+	nextID  int
+}
+
+// JournalEntry is a single edit in the journal, such as one tool call or one
+// workspace/applyEdit request. It is recorded once Commit is called.
+type JournalEntry struct {
+	ID          int
+	Description string
+	Time        time.Time
+
+	journal *Journal
+	files   []*journalFile
+	seen    map[string]bool
+}
+
+// journalFile is the content of a file before an edit and its state after it
+type journalFile struct {
+	path    string
+	existed bool
+	content []byte
+	after   string
+}
+
+// Begin starts a journal entry. Files must be captured before they are changed.
+func (j *Journal) Begin(description string) *JournalEntry {
+	return &JournalEntry{
+		Description: description,
+		journal:     j,
+		seen:        make(map[string]bool),
+	}
+}
+
+// Capture records the current content of files before they are changed. The
+// files in a directory are captured individually. Paths captured before are
+// skipped, so the entry keeps the content from before its first change.
+func (e *JournalEntry) Capture(paths ...string) {
+	for _, path := range paths {
+		e.capture(path)
+	}
+}
+
+func (e *JournalEntry) capture(path string) {
+	if e.seen[path] {
+		return
+	}
+
+	content, err := osReadFile(path)
+	if err == nil {
+		e.seen[path] = true
+		e.files = append(e.files, &journalFile{path: path, existed: true, content: content})
+		return
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		e.seen[path] = true
+		e.files = append(e.files, &journalFile{path: path})
+		return
+	}
+
+	if info, statErr := osStat(path); statErr == nil && info.IsDir() {
+		e.seen[path] = true
+		_ = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err == nil && d.Type().IsRegular() {
+				e.capture(file)
+			}
+			return nil
+		})
+		return
+	}
+
+	coreLogger.Warn("Cannot record %s in the edit journal: %v", path, err)
+}
+
+// CaptureEdit records the files a workspace edit changes, creates, renames or deletes
+func (e *JournalEntry) CaptureEdit(edit protocol.WorkspaceEdit) {
+	e.Capture(EditPaths(edit)...)
+}
+
+// Commit adds the entry to the journal once its changes have been made. Files
+// that ended up unchanged are left out, and so is an entry that changed nothing.
+func (e *JournalEntry) Commit() {
+	var changed []*journalFile
+	for _, f := range e.files {
+		f.after = fileState(f.path)
+		before := "absent"
+		if f.existed {
+			before = fmt.Sprintf("file:%x", sha256.Sum256(f.content))
+		}
+		if f.after != before {
+			changed = append(changed, f)
+		}
+	}
+	e.files = changed
+	if len(e.files) == 0 {
+		return
+	}
+
+	j := e.journal
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.nextID++
+	e.ID = j.nextID
+	e.Time = time.Now()
+	j.entries = append(j.entries, e)
+	if len(j.entries) > maxJournalEntries {
+		j.entries = j.entries[len(j.entries)-maxJournalEntries:]
+	}
+}
+
+// Files lists the files the entry changed
+func (e *JournalEntry) Files() []string {
+	paths := make([]string, len(e.files))
+	for i, f := range e.files {
+		paths[i] = f.path
+	}
+	return paths
+}
+
+// Entries returns the edits in the journal, oldest first
+func (j *Journal) Entries() []*JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]*JournalEntry(nil), j.entries...)
+}
+
+// Undo restores the files of an edit to their content before it and removes it
+// from the journal. An id of 0 undoes the most recent edit. Unless force is set,
+// files that changed again since the edit are left alone and an error is returned.
+func (j *Journal) Undo(id int, force bool) (*JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	index := len(j.entries) - 1
+	if id != 0 {
+		for index >= 0 && j.entries[index].ID != id {
+			index--
+		}
+	}
+	if index < 0 {
+		if id == 0 {
+			return nil, fmt.Errorf("there are no edits to undo")
+		}
+		return nil, fmt.Errorf("edit %d is not in the journal", id)
+	}
+	e := j.entries[index]
+
+	if !force {
+		var changed []string
+		for _, f := range e.files {
+			if fileState(f.path) != f.after {
+				changed = append(changed, f.path)
+			}
+		}
+		if len(changed) > 0 {
+			return nil, fmt.Errorf("files changed since edit %d, undo it with force to overwrite them: %s",
+				e.ID, strings.Join(changed, ", "))
+		}
+	}
+
+	// Remove files the edit created first, since a renamed directory may now be
+	// where one of the restored files goes
+	var errs []error
+	for _, f := range e.files {
+		if !f.existed {
+			if err := osRemoveAll(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, f := range e.files {
+		if f.existed {
+			if err := osMkdirAll(filepath.Dir(f.path), 0755); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := osWriteFile(f.path, f.content, 0644); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("failed to undo edit %d: %w", e.ID, err)
+	}
+
+	j.entries = append(j.entries[:index], j.entries[index+1:]...)
+	return e, nil
+}
+
+// fileState summarizes what is at path, so changes made after an edit can be detected
+func fileState(path string) string {
+	content, err := osReadFile(path)
+	if err == nil {
+		return fmt.Sprintf("file:%x", sha256.Sum256(content))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return "absent"
+	}
+
+	// Directories are summarized by the names and contents of their files
+	hash := sha256.New()
+	_ = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			content, _ := osReadFile(file)
+			fmt.Fprintf(hash, "%s\x00%x\x00", file, sha256.Sum256(content))
+		}
+		return nil
+	})
+	return fmt.Sprintf("dir:%x", hash.Sum(nil))
+}
+
+// EditPaths lists the paths a workspace edit changes, creates, renames or deletes, sorted
+func EditPaths(edit protocol.WorkspaceEdit) []string {
+	seen := make(map[string]bool)
+	add := func(uri protocol.DocumentUri) {
+		seen[fileuri.ToPath(uri)] = true
+	}
+
+	for uri := range edit.Changes {
+		add(uri)
+	}
+	for _, change := range edit.DocumentChanges {
+		switch {
+		case change.CreateFile != nil:
+			add(change.CreateFile.URI)
+		case change.DeleteFile != nil:
+			add(change.DeleteFile.URI)
+		case change.RenameFile != nil:
+			add(change.RenameFile.OldURI)
+			add(change.RenameFile.NewURI)
+		case change.TextDocumentEdit != nil:
+			add(change.TextDocumentEdit.TextDocument.URI)
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,156 @@
+package utilities
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func TestJournal(t *testing.T) {
+	replaceFirstLine := func(uri protocol.DocumentUri, text string) protocol.WorkspaceEdit {
+		return protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+				uri: {
+					{
+						Range: protocol.Range{
+							Start: protocol.Position{Line: 0, Character: 0},
+							End:   protocol.Position{Line: 0, Character: 4},
+						},
+						NewText: text,
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("Undo restores changed and removes created files", func(t *testing.T) {
+		mfs := &mockFileSystem{
+			files: map[string][]byte{
+				"/ws/a.txt": []byte("This is a"),
+			},
+		}
+		cleanup := setupMockFileSystem(t, mfs)
+		defer cleanup()
+
+		journal := &Journal{}
+		entry := journal.Begin("edit")
+		entry.Capture("/ws/a.txt", "/ws/b.txt")
+		if err := ApplyWorkspaceEdit(replaceFirstLine("file:///ws/a.txt", "That"), protocol.UTF16); err != nil {
+			t.Fatalf("ApplyWorkspaceEdit failed: %v", err)
+		}
+		mfs.files["/ws/b.txt"] = []byte("new")
+		entry.Commit()
+
+		if entry.ID != 1 || len(journal.Entries()) != 1 {
+			t.Fatalf("entry was not recorded: id %d, %d entries", entry.ID, len(journal.Entries()))
+		}
+
+		undone, err := journal.Undo(0, false)
+		if err != nil {
+			t.Fatalf("Undo failed: %v", err)
+		}
+		if undone != entry {
+			t.Errorf("undid %v, want %v", undone, entry)
+		}
+		if string(mfs.files["/ws/a.txt"]) != "This is a" {
+			t.Errorf("a.txt was not restored, content: %s", mfs.files["/ws/a.txt"])
+		}
+		if _, ok := mfs.files["/ws/b.txt"]; ok {
+			t.Errorf("b.txt was not removed")
+		}
+		if len(journal.Entries()) != 0 {
+			t.Errorf("undone entry is still in the journal")
+		}
+	})
+
+	t.Run("Unchanged files are not recorded", func(t *testing.T) {
+		mfs := &mockFileSystem{
+			files: map[string][]byte{
+				"/ws/a.txt": []byte("This is a"),
+			},
+		}
+		cleanup := setupMockFileSystem(t, mfs)
+		defer cleanup()
+
+		journal := &Journal{}
+		entry := journal.Begin("no-op")
+		entry.Capture("/ws/a.txt", "/ws/missing.txt")
+		entry.Commit()
+
+		if len(journal.Entries()) != 0 {
+			t.Errorf("edit that changed nothing was recorded")
+		}
+		if _, err := journal.Undo(0, false); err == nil {
+			t.Errorf("Undo of an empty journal succeeded")
+		}
+	})
+
+	t.Run("Files changed since the edit need force", func(t *testing.T) {
+		mfs := &mockFileSystem{
+			files: map[string][]byte{
+				"/ws/a.txt": []byte("This is a"),
+				"/ws/b.txt": []byte("This is b"),
+			},
+		}
+		cleanup := setupMockFileSystem(t, mfs)
+		defer cleanup()
+
+		journal := &Journal{}
+		first := journal.Begin("first")
+		first.Capture("/ws/a.txt")
+		mfs.files["/ws/a.txt"] = []byte("That is a")
+		first.Commit()
+
+		second := journal.Begin("second")
+		second.Capture("/ws/b.txt")
+		mfs.files["/ws/b.txt"] = []byte("That is b")
+		second.Commit()
+
+		mfs.files["/ws/a.txt"] = []byte("Changed again")
+		_, err := journal.Undo(first.ID, false)
+		if err == nil || !strings.Contains(err.Error(), "/ws/a.txt") {
+			t.Fatalf("Undo error = %v, want a conflict on a.txt", err)
+		}
+		if string(mfs.files["/ws/a.txt"]) != "Changed again" {
+			t.Errorf("a.txt was overwritten without force")
+		}
+
+		if _, err := journal.Undo(first.ID, true); err != nil {
+			t.Fatalf("Undo with force failed: %v", err)
+		}
+		if string(mfs.files["/ws/a.txt"]) != "This is a" {
+			t.Errorf("a.txt was not restored, content: %s", mfs.files["/ws/a.txt"])
+		}
+
+		// The later edit is still there and can be undone on its own
+		entries := journal.Entries()
+		if len(entries) != 1 || entries[0] != second {
+			t.Fatalf("journal = %v, want only the second edit", entries)
+		}
+		if _, err := journal.Undo(0, false); err != nil {
+			t.Fatalf("Undo failed: %v", err)
+		}
+		if string(mfs.files["/ws/b.txt"]) != "This is b" {
+			t.Errorf("b.txt was not restored, content: %s", mfs.files["/ws/b.txt"])
+		}
+	})
+}
+
+func TestEditPaths(t *testing.T) {
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			"file:///ws/b.go": nil,
+		},
+		DocumentChanges: []protocol.DocumentChange{
+			{RenameFile: &protocol.RenameFile{OldURI: "file:///ws/old.go", NewURI: "file:///ws/new.go"}},
+			{CreateFile: &protocol.CreateFile{URI: "file:///ws/a.go"}},
+		},
+	}
+
+	paths := EditPaths(edit)
+	expected := []string{"/ws/a.go", "/ws/b.go", "/ws/new.go", "/ws/old.go"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("EditPaths = %v, want %v", paths, expected)
+	}
+}
@@ -0,0 +1,377 @@
+package utilities
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FilePatch holds the changes a unified diff makes to a single file. OldPath is
+// empty for created files and NewPath is empty for deleted files.
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+// Hunk is a single @@ section of a unified diff. Lines keep their ' ', '-' or '+' prefix.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []string
+
+	// Set by "\ No newline at end of file" markers
+	OldNoNewline bool
+	NewNoNewline bool
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParseUnifiedDiff parses a unified diff, such as the output of `diff -u` or
+// `git diff`, into per-file patches
+func ParseUnifiedDiff(diff string) ([]FilePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+
+	var patches []FilePatch
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			if i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "+++ ") {
+				return nil, fmt.Errorf("line %d: expected +++ after ---", i+1)
+			}
+			patches = append(patches, FilePatch{
+				OldPath: parsePatchPath(line[4:], "a/"),
+				NewPath: parsePatchPath(lines[i+1][4:], "b/"),
+			})
+			i++
+
+		case strings.HasPrefix(line, "@@ "):
+			if len(patches) == 0 {
+				return nil, fmt.Errorf("line %d: hunk before file header", i+1)
+			}
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			current := &patches[len(patches)-1]
+			current.Hunks = append(current.Hunks, hunk)
+			i = next - 1
+
+		default:
+			// Ignore everything else, such as "diff --git" and "index" lines
+		}
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no file changes found in diff")
+	}
+
+	return patches, nil
+}
+
+// parseHunk parses the hunk starting at lines[start] and returns the index of the
+// first line after it
+func parseHunk(lines []string, start int) (Hunk, int, error) {
+	match := hunkHeader.FindStringSubmatch(lines[start])
+	if match == nil {
+		return Hunk{}, 0, fmt.Errorf("line %d: invalid hunk header: %s", start+1, lines[start])
+	}
+
+	hunk := Hunk{
+		OldStart: atoiDefault(match[1], 0),
+		OldLines: atoiDefault(match[2], 1),
+		NewStart: atoiDefault(match[3], 0),
+		NewLines: atoiDefault(match[4], 1),
+	}
+
+	oldCount, newCount := 0, 0
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.HasPrefix(line, `\`) {
+			// Marker applies to the line before it
+			if len(hunk.Lines) > 0 {
+				switch hunk.Lines[len(hunk.Lines)-1][0] {
+				case '-':
+					hunk.OldNoNewline = true
+				case '+':
+					hunk.NewNoNewline = true
+				default:
+					hunk.OldNoNewline = true
+					hunk.NewNoNewline = true
+				}
+			}
+			continue
+		}
+
+		if oldCount >= hunk.OldLines && newCount >= hunk.NewLines {
+			break
+		}
+
+		// Some editors strip the space from empty context lines
+		if line == "" {
+			line = " "
+		}
+
+		switch line[0] {
+		case ' ':
+			oldCount++
+			newCount++
+		case '-':
+			oldCount++
+		case '+':
+			newCount++
+		default:
+			return Hunk{}, 0, fmt.Errorf("line %d: unexpected line in hunk: %s", i+1, line)
+		}
+		hunk.Lines = append(hunk.Lines, line)
+	}
+
+	if oldCount != hunk.OldLines || newCount != hunk.NewLines {
+		return Hunk{}, 0, fmt.Errorf("line %d: hunk is truncated, expected -%d +%d lines but found -%d +%d",
+			start+1, hunk.OldLines, hunk.NewLines, oldCount, newCount)
+	}
+
+	return hunk, i, nil
+}
+
+// parsePatchPath extracts the file path from a ---/+++ header, dropping any
+// timestamp and the a/ or b/ prefix added by git
+func parsePatchPath(header string, prefix string) string {
+	path, _, _ := strings.Cut(header, "\t")
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// ApplyHunks applies hunks to the content of a file. Each hunk is placed at the line
+// given in its header when the context matches there, otherwise at the nearest
+// position where it does.
+func ApplyHunks(content []byte, hunks []Hunk) ([]byte, error) {
+	lineEnding := "\n"
+	if bytes.Contains(content, []byte("\r\n")) {
+		lineEnding = "\r\n"
+	}
+
+	text := string(content)
+	endsWithNewline := text == "" || strings.HasSuffix(text, lineEnding)
+	text = strings.TrimSuffix(text, lineEnding)
+
+	var lines []string
+	if text != "" {
+		lines = strings.Split(text, lineEnding)
+	}
+
+	// Lines added or removed by earlier hunks shift the position of later ones
+	offset := 0
+	for n, hunk := range hunks {
+		var oldLines, newLines []string
+		for _, line := range hunk.Lines {
+			switch line[0] {
+			case ' ':
+				oldLines = append(oldLines, line[1:])
+				newLines = append(newLines, line[1:])
+			case '-':
+				oldLines = append(oldLines, line[1:])
+			case '+':
+				newLines = append(newLines, line[1:])
+			}
+		}
+
+		// A hunk with no old lines inserts after line OldStart
+		expected := hunk.OldStart - 1 + offset
+		if hunk.OldLines == 0 {
+			expected = hunk.OldStart + offset
+		}
+
+		pos := findHunk(lines, oldLines, expected)
+		if pos < 0 {
+			return nil, fmt.Errorf("hunk %d (@@ -%d,%d +%d,%d @@) does not match the file",
+				n+1, hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+		}
+
+		result := make([]string, 0, len(lines)-len(oldLines)+len(newLines))
+		result = append(result, lines[:pos]...)
+		result = append(result, newLines...)
+		result = append(result, lines[pos+len(oldLines):]...)
+
+		// Newline markers only matter for hunks that reach the end of the file
+		if pos+len(oldLines) == len(lines) {
+			if hunk.NewNoNewline {
+				endsWithNewline = false
+			} else if hunk.OldNoNewline {
+				endsWithNewline = true
+			}
+		}
+
+		offset += pos - expected + len(newLines) - len(oldLines)
+		lines = result
+	}
+
+	output := strings.Join(lines, lineEnding)
+	if endsWithNewline && len(lines) > 0 {
+		output += lineEnding
+	}
+	return []byte(output), nil
+}
+
+// findHunk returns the line index where want matches lines, searching outward from
+// expected, or -1 if there is no match
+func findHunk(lines []string, want []string, expected int) int {
+	matches := func(pos int) bool {
+		if pos < 0 || pos+len(want) > len(lines) {
+			return false
+		}
+		for i, line := range want {
+			if lines[pos+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+
+	for delta := 0; delta <= len(lines); delta++ {
+		if matches(expected - delta) {
+			return expected - delta
+		}
+		if delta > 0 && matches(expected+delta) {
+			return expected + delta
+		}
+	}
+	return -1
+}
+
+// fileWrite is the result of patching a single file
+type fileWrite struct {
+	oldPath string
+	newPath string
+	before  []byte
+	content []byte
+}
+
+// patchFiles computes the new content of every file in the patches without writing
+// anything, so that a bad hunk changes nothing
+func patchFiles(patches []FilePatch) ([]fileWrite, error) {
+	var writes []fileWrite
+	for _, patch := range patches {
+		oldPath, newPath := patch.OldPath, patch.NewPath
+
+		var before []byte
+		if oldPath != "" {
+			var err error
+			before, err = osReadFile(oldPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", oldPath, err)
+			}
+		} else if _, err := osStat(newPath); err == nil {
+			return nil, fmt.Errorf("cannot create %s: file already exists", newPath)
+		}
+
+		content := before
+		if newPath != "" {
+			var err error
+			content, err = ApplyHunks(before, patch.Hunks)
+			if err != nil {
+				return nil, fmt.Errorf("failed to patch %s: %w", newPath, err)
+			}
+		}
+
+		writes = append(writes, fileWrite{oldPath: oldPath, newPath: newPath, before: before, content: content})
+	}
+	return writes, nil
+}
+
+// PreviewPatch checks that parsed patches apply and renders the changes they would
+// make as unified diffs, without writing anything to disk
+func PreviewPatch(patches []FilePatch) (string, error) {
+	writes, err := patchFiles(patches)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	for _, w := range writes {
+		var after []byte
+		if w.newPath != "" {
+			after = w.content
+		}
+		diff, err := UnifiedDiff(w.oldPath, w.newPath, w.before, after)
+		if err != nil {
+			return "", err
+		}
+		output.WriteString(diff)
+	}
+	return output.String(), nil
+}
+
+// ApplyPatch applies parsed patches to the filesystem. Every hunk is checked before
+// anything is written, and files changed so far are restored if writing fails.
+// It returns a summary line for each file.
+func ApplyPatch(patches []FilePatch) ([]string, error) {
+	writes, err := patchFiles(patches)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &editTransaction{}
+	var summary []string
+	for _, w := range writes {
+		var err error
+		switch {
+		case w.newPath == "":
+			tx.record(backupFile(w.oldPath))
+			err = osRemove(w.oldPath)
+			summary = append(summary, "deleted: "+w.oldPath)
+		case w.oldPath == "":
+			tx.record(backupFile(w.newPath))
+			if err = osMkdirAll(filepath.Dir(w.newPath), 0755); err == nil {
+				err = osWriteFile(w.newPath, w.content, 0644)
+			}
+			summary = append(summary, "created: "+w.newPath)
+		case w.oldPath != w.newPath:
+			tx.record(backupFile(w.oldPath), backupFile(w.newPath))
+			if err = osMkdirAll(filepath.Dir(w.newPath), 0755); err == nil {
+				err = osWriteFile(w.newPath, w.content, 0644)
+			}
+			if err == nil {
+				err = osRemove(w.oldPath)
+			}
+			summary = append(summary, fmt.Sprintf("renamed: %s -> %s", w.oldPath, w.newPath))
+		default:
+			tx.record(backupFile(w.newPath))
+			err = osWriteFile(w.newPath, w.content, 0644)
+			summary = append(summary, "modified: "+w.newPath)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("failed to write patch: %w", err)
+			if rollbackErr := tx.rollback(); rollbackErr != nil {
+				return nil, fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+			}
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,270 @@
+package utilities
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-func old() {}
++func new() {}
+
+@@ -10 +10,2 @@
+ last
++added
+\ No newline at end of file
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1 @@
++package main
+--- old.go	2024-01-01 00:00:00
++++ /dev/null	2024-01-01 00:00:00
+@@ -1 +0,0 @@
+-package main
+`
+
+	patches, err := ParseUnifiedDiff(diff)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff failed: %v", err)
+	}
+
+	expected := []FilePatch{
+		{
+			OldPath: "main.go",
+			NewPath: "main.go",
+			Hunks: []Hunk{
+				{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3, Lines: []string{" package main", "-func old() {}", "+func new() {}", " "}},
+				{OldStart: 10, OldLines: 1, NewStart: 10, NewLines: 2, Lines: []string{" last", "+added"}, NewNoNewline: true},
+			},
+		},
+		{
+			NewPath: "new.go",
+			Hunks: []Hunk{
+				{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []string{"+package main"}},
+			},
+		},
+		{
+			OldPath: "old.go",
+			Hunks: []Hunk{
+				{OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0, Lines: []string{"-package main"}},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(patches, expected) {
+		t.Errorf("ParseUnifiedDiff() = %+v, want %+v", patches, expected)
+	}
+}
+
+func TestParseUnifiedDiffErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+	}{
+		{name: "Empty diff", diff: ""},
+		{name: "Hunk without file header", diff: "@@ -1 +1 @@\n-a\n+b\n"},
+		{name: "Missing +++ header", diff: "--- a/file.go\n@@ -1 +1 @@\n"},
+		{name: "Truncated hunk", diff: "--- a/file.go\n+++ b/file.go\n@@ -1,3 +1,3 @@\n a\n"},
+		{name: "Invalid hunk line", diff: "--- a/file.go\n+++ b/file.go\n@@ -1,2 +1,2 @@\n a\n*b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseUnifiedDiff(tt.diff); err == nil {
+				t.Errorf("ParseUnifiedDiff() expected error")
+			}
+		})
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		hunks    []Hunk
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:    "Replace line",
+			content: "a\nb\nc\n",
+			hunks: []Hunk{
+				{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3, Lines: []string{" a", "-b", "+B", " c"}},
+			},
+			expected: "a\nB\nc\n",
+		},
+		{
+			name:    "Hunk found at an offset",
+			content: "x\ny\na\nb\nc\n",
+			hunks: []Hunk{
+				{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2, Lines: []string{" a", "-b", "+B"}},
+			},
+			expected: "x\ny\na\nB\nc\n",
+		},
+		{
+			name:    "Later hunks account for earlier insertions",
+			content: "a\nb\nc\nd\n",
+			hunks: []Hunk{
+				{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 3, Lines: []string{" a", "+1", "+2"}},
+				{OldStart: 4, OldLines: 1, NewStart: 6, NewLines: 1, Lines: []string{"-d", "+D"}},
+			},
+			expected: "a\n1\n2\nb\nc\nD\n",
+		},
+		{
+			name:    "Insert into empty file",
+			content: "",
+			hunks: []Hunk{
+				{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 2, Lines: []string{"+a", "+b"}},
+			},
+			expected: "a\nb\n",
+		},
+		{
+			name:    "Remove trailing newline",
+			content: "a\nb\n",
+			hunks: []Hunk{
+				{OldStart: 2, OldLines: 1, NewStart: 2, NewLines: 1, Lines: []string{"-b", "+b"}, NewNoNewline: true},
+			},
+			expected: "a\nb",
+		},
+		{
+			name:    "Preserve CRLF line endings",
+			content: "a\r\nb\r\n",
+			hunks: []Hunk{
+				{OldStart: 2, OldLines: 1, NewStart: 2, NewLines: 1, Lines: []string{"-b", "+B"}},
+			},
+			expected: "a\r\nB\r\n",
+		},
+		{
+			name:    "Context does not match",
+			content: "a\nb\n",
+			hunks: []Hunk{
+				{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1, Lines: []string{"-z", "+Z"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ApplyHunks([]byte(tt.content), tt.hunks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyHunks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(result) != tt.expected {
+				t.Errorf("ApplyHunks() = %q, want %q", string(result), tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	t.Run("Create, modify and delete files", func(t *testing.T) {
+		mfs := &mockFileSystem{
+			files: map[string][]byte{
+				"/ws/main.go": []byte("package main\n\nfunc old() {}\n"),
+				"/ws/old.go":  []byte("package main\n"),
+			},
+		}
+		cleanup := setupMockFileSystem(t, mfs)
+		defer cleanup()
+
+		patches := []FilePatch{
+			{OldPath: "/ws/main.go", NewPath: "/ws/main.go", Hunks: []Hunk{
+				{OldStart: 3, OldLines: 1, NewStart: 3, NewLines: 1, Lines: []string{"-func old() {}", "+func new() {}"}},
+			}},
+			{NewPath: "/ws/pkg/new.go", Hunks: []Hunk{
+				{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []string{"+package pkg"}},
+			}},
+			{OldPath: "/ws/old.go", Hunks: []Hunk{
+				{OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0, Lines: []string{"-package main"}},
+			}},
+		}
+
+		summary, err := ApplyPatch(patches)
+		if err != nil {
+			t.Fatalf("ApplyPatch failed: %v", err)
+		}
+
+		expectedSummary := []string{"modified: /ws/main.go", "created: /ws/pkg/new.go", "deleted: /ws/old.go"}
+		if !reflect.DeepEqual(summary, expectedSummary) {
+			t.Errorf("summary = %v, want %v", summary, expectedSummary)
+		}
+
+		expectedFiles := map[string]string{
+			"/ws/main.go":    "package main\n\nfunc new() {}\n",
+			"/ws/pkg/new.go": "package pkg\n",
+		}
+		if len(mfs.files) != len(expectedFiles) {
+			t.Errorf("got %d files, want %d", len(mfs.files), len(expectedFiles))
+		}
+		for path, content := range expectedFiles {
+			if string(mfs.files[path]) != content {
+				t.Errorf("%s = %q, want %q", path, string(mfs.files[path]), content)
+			}
+		}
+	})
+
+	t.Run("Mismatched hunk writes nothing", func(t *testing.T) {
+		mfs := &mockFileSystem{
+			files: map[string][]byte{
+				"/ws/a.go": []byte("a\n"),
+				"/ws/b.go": []byte("b\n"),
+			},
+		}
+		cleanup := setupMockFileSystem(t, mfs)
+		defer cleanup()
+
+		patches := []FilePatch{
+			{OldPath: "/ws/a.go", NewPath: "/ws/a.go", Hunks: []Hunk{
+				{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1, Lines: []string{"-a", "+A"}},
+			}},
+			{OldPath: "/ws/b.go", NewPath: "/ws/b.go", Hunks: []Hunk{
+				{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1, Lines: []string{"-z", "+Z"}},
+			}},
+		}
+
+		if _, err := ApplyPatch(patches); err == nil {
+			t.Fatal("ApplyPatch expected error")
+		}
+		if string(mfs.files["/ws/a.go"]) != "a\n" {
+			t.Errorf("a.go was modified: %q", string(mfs.files["/ws/a.go"]))
+		}
+	})
+
+	t.Run("Write failure rolls back earlier files", func(t *testing.T) {
+		mfs := &mockFileSystem{
+			files: map[string][]byte{
+				"/ws/a.go": []byte("a\n"),
+				"/ws/b.go": []byte("b\n"),
+			},
+			errors: map[string]error{
+				"/ws/b.go_write": errors.New("disk full"),
+			},
+		}
+		cleanup := setupMockFileSystem(t, mfs)
+		defer cleanup()
+
+		patches := []FilePatch{
+			{OldPath: "/ws/a.go", NewPath: "/ws/a.go", Hunks: []Hunk{
+				{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1, Lines: []string{"-a", "+A"}},
+			}},
+			{OldPath: "/ws/b.go", NewPath: "/ws/b.go", Hunks: []Hunk{
+				{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1, Lines: []string{"-b", "+B"}},
+			}},
+		}
+
+		if _, err := ApplyPatch(patches); err == nil {
+			t.Fatal("ApplyPatch expected error")
+		}
+		if string(mfs.files["/ws/a.go"]) != "a\n" {
+			t.Errorf("a.go was not restored: %q", string(mfs.files["/ws/a.go"]))
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package utilities
+
+import (
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// The language server decides how the character of a position is counted when it
+// is initialized: in UTF-16 code units, unless it picks UTF-8 bytes or UTF-32 code
+// points from the encodings the client offers. Each client keeps the encoding its
+// server chose, and positions in protocol types are always in the encoding of the
+// server they came from and are converted where text is sliced. Columns that tools
+// take and show count Unicode characters, whatever the server uses.
+
+// NormalizePositionEncoding returns the encoding a server chose. Any encoding
+// other than UTF-8 and UTF-32 is taken to be UTF-16, the protocol's default.
+func NormalizePositionEncoding(encoding protocol.PositionEncodingKind) protocol.PositionEncodingKind {
+	if encoding != protocol.UTF8 && encoding != protocol.UTF32 {
+		return protocol.UTF16
+	}
+	return encoding
+}
+
+// runeUnits returns the number of units a rune takes in an encoding
+func runeUnits(r rune, size int, encoding protocol.PositionEncodingKind) uint32 {
+	switch encoding {
+	case protocol.UTF8:
+		return uint32(size)
+	case protocol.UTF32:
+		return 1
+	}
+	if r >= 0x10000 && r != utf8.RuneError {
+		return 2
+	}
+	return 1
+}
+
+// ByteOffset converts the character of a position on a line to a byte offset in
+// the line. A character past the end of the line refers to the end of the line.
+func ByteOffset(line string, character uint32, encoding protocol.PositionEncodingKind) int {
+	if encoding == protocol.UTF8 {
+		return min(int(character), len(line))
+	}
+
+	var units uint32
+	for offset := 0; offset < len(line); {
+		if units >= character {
+			return offset
+		}
+		r, size := utf8.DecodeRuneInString(line[offset:])
+		units += runeUnits(r, size, encoding)
+		offset += size
+	}
+	return len(line)
+}
+
+// CharacterOf converts a byte offset in a line to the character of a position.
+// An offset past the end of the line refers to the end of the line.
+func CharacterOf(line string, offset int, encoding protocol.PositionEncodingKind) uint32 {
+	offset = max(0, min(offset, len(line)))
+	if encoding == protocol.UTF8 {
+		return uint32(offset)
+	}
+
+	var units uint32
+	for i := 0; i < offset; {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		units += runeUnits(r, size, encoding)
+		i += size
+	}
+	return units
+}
+
+// CharacterLength returns the length of text in the units of a position's character
+func CharacterLength(text string, encoding protocol.PositionEncodingKind) uint32 {
+	return CharacterOf(text, len(text), encoding)
+}
+
+// ColumnToCharacter converts a one-indexed column on a line, counted in Unicode
+// characters, to the character of a position. Columns past the end of the line
+// are kept past it, so the server can reject them.
+func ColumnToCharacter(line string, column int, encoding protocol.PositionEncodingKind) uint32 {
+	if column < 1 {
+		return 0
+	}
+	offset := 0
+	for i := 1; i < column; i++ {
+		if offset >= len(line) {
+			return CharacterLength(line, encoding) + uint32(column-i)
+		}
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return CharacterOf(line, offset, encoding)
+}
+
+// CharacterToColumn converts the character of a position on a line to a one-indexed
+// column counted in Unicode characters
+func CharacterToColumn(line string, character uint32, encoding protocol.PositionEncodingKind) int {
+	offset := ByteOffset(line, character, encoding)
+	column := utf8.RuneCountInString(line[:offset]) + 1
+	if length := CharacterLength(line, encoding); character > length {
+		column += int(character - length)
+	}
+	return column
+}
+
+// ConvertCharacter converts the character of a position on a line counted in one
+// encoding to the character counted in another, such as from a precomputed index
+// to the server's encoding. Characters past the end of the line stay past it, so
+// that positions in lines that could not be read are kept.
+func ConvertCharacter(line string, character uint32, from, to protocol.PositionEncodingKind) uint32 {
+	if from == to {
+		return character
+	}
+	end := CharacterLength(line, from)
+	if character > end {
+		return CharacterLength(line, to) + character - end
+	}
+	return CharacterOf(line, ByteOffset(line, character, from), to)
+}
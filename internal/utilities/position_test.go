@@ -0,0 +1,83 @@
+package utilities
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositionConversions(t *testing.T) {
+	// "é" is 2 bytes and 1 UTF-16 unit, "😀" is 4 bytes and 2 UTF-16 units
+	line := `s := "é😀x"`
+
+	tests := []struct {
+		encoding protocol.PositionEncodingKind
+		// character of the x, and the length of the line
+		character uint32
+		length    uint32
+	}{
+		{protocol.UTF8, 12, 14},
+		{protocol.UTF16, 9, 11},
+		{protocol.UTF32, 8, 10},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.encoding), func(t *testing.T) {
+			enc := tt.encoding
+
+			assert.Equal(t, 12, ByteOffset(line, tt.character, enc))
+			assert.Equal(t, tt.character, CharacterOf(line, 12, enc))
+			assert.Equal(t, tt.length, CharacterLength(line, enc))
+			assert.Equal(t, len(line), ByteOffset(line, tt.length+5, enc))
+
+			// The x is the ninth character of the line
+			assert.Equal(t, tt.character, ColumnToCharacter(line, 9, enc))
+			assert.Equal(t, 9, CharacterToColumn(line, tt.character, enc))
+			assert.Equal(t, 11, CharacterToColumn(line, tt.length, enc))
+
+			// Columns past the end of the line stay past it
+			assert.Equal(t, tt.length+2, ColumnToCharacter(line, 13, enc))
+			assert.Equal(t, 13, CharacterToColumn(line, tt.length+2, enc))
+		})
+	}
+}
+
+func TestNormalizePositionEncoding(t *testing.T) {
+	assert.Equal(t, protocol.UTF8, NormalizePositionEncoding(protocol.UTF8))
+	assert.Equal(t, protocol.UTF32, NormalizePositionEncoding(protocol.UTF32))
+	assert.Equal(t, protocol.UTF16, NormalizePositionEncoding("utf-7"))
+	assert.Equal(t, protocol.UTF16, NormalizePositionEncoding(""))
+}
+
+func TestEditContentPositionEncoding(t *testing.T) {
+	content := []byte("a := \"😀\" + b\n")
+	edit := protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 12},
+			End:   protocol.Position{Line: 0, Character: 13},
+		},
+		NewText: "c",
+	}
+	edited, err := EditContent(content, []protocol.TextEdit{edit}, protocol.UTF16)
+	require.NoError(t, err)
+	assert.Equal(t, "a := \"😀\" + c\n", string(edited))
+
+	// Counted in bytes, the b is two characters further along
+	edit.Range.Start.Character, edit.Range.End.Character = 14, 15
+	edited, err = EditContent(content, []protocol.TextEdit{edit}, protocol.UTF8)
+	require.NoError(t, err)
+	assert.Equal(t, "a := \"😀\" + c\n", string(edited))
+}
+
+func TestConvertCharacter(t *testing.T) {
+	line := "s := \"é😀\" + b"
+	assert.Equal(t, uint32(13), ConvertCharacter(line, 10, protocol.UTF16, protocol.UTF8))
+	assert.Equal(t, uint32(10), ConvertCharacter(line, 13, protocol.UTF8, protocol.UTF16))
+	assert.Equal(t, uint32(9), ConvertCharacter(line, 10, protocol.UTF16, protocol.UTF32))
+	assert.Equal(t, uint32(4), ConvertCharacter(line, 4, protocol.UTF8, protocol.UTF8))
+
+	// Past the end of the line, and on lines that could not be read
+	assert.Equal(t, uint32(18), ConvertCharacter(line, 15, protocol.UTF16, protocol.UTF8))
+	assert.Equal(t, uint32(7), ConvertCharacter("", 7, protocol.UTF8, protocol.UTF16))
+}
@@ -0,0 +1,200 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// previewFile is the state of one file while a WorkspaceEdit is applied in memory
+type previewFile struct {
+	// oldPath is the path the file had before the edit, empty for created files
+	oldPath string
+	// newPath is the path the file has after the edit, empty for deleted files
+	newPath string
+	before  []byte
+	after   []byte
+}
+
+// editPreview applies a WorkspaceEdit to copies of the files it touches
+type editPreview struct {
+	encoding protocol.PositionEncodingKind
+	files    map[string]*previewFile
+	order    []*previewFile
+	notes    []string
+}
+
+// PreviewWorkspaceEdit renders the changes a WorkspaceEdit would make as unified
+// diffs, one per file, without writing anything to disk
+func PreviewWorkspaceEdit(edit protocol.WorkspaceEdit, encoding protocol.PositionEncodingKind) (string, error) {
+	p := &editPreview{encoding: encoding, files: make(map[string]*previewFile)}
+
+	// Handle Changes field in the same stable order as ApplyWorkspaceEdit
+	uris := make([]string, 0, len(edit.Changes))
+	for uri := range edit.Changes {
+		uris = append(uris, string(uri))
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		if err := p.editText(uri, edit.Changes[protocol.DocumentUri(uri)]); err != nil {
+			return "", err
+		}
+	}
+
+	for _, change := range edit.DocumentChanges {
+		if err := p.documentChange(change); err != nil {
+			return "", err
+		}
+	}
+
+	return p.render()
+}
+
+// file returns the in-memory state of path, reading it from disk the first time
+func (p *editPreview) file(path string) (*previewFile, error) {
+	if f, ok := p.files[path]; ok {
+		return f, nil
+	}
+	content, err := osReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	f := &previewFile{oldPath: path, newPath: path, before: content, after: content}
+	p.files[path] = f
+	p.order = append(p.order, f)
+	return f, nil
+}
+
+func (p *editPreview) editText(uri string, edits []protocol.TextEdit) error {
+	f, err := p.file(fileuri.ToPath(uri))
+	if err != nil {
+		return err
+	}
+	f.after, err = SpliceContent(f.after, edits, p.encoding)
+	if err != nil {
+		return fmt.Errorf("failed to apply text edits: %w", err)
+	}
+	return nil
+}
+
+func (p *editPreview) documentChange(change protocol.DocumentChange) error {
+	switch {
+	case change.CreateFile != nil:
+		path := fileuri.ToPath(change.CreateFile.URI)
+		existing, err := p.file(path)
+		if err == nil && existing.newPath != "" {
+			if change.CreateFile.Options != nil && change.CreateFile.Options.IgnoreIfExists && !change.CreateFile.Options.Overwrite {
+				return nil
+			}
+			existing.after = nil
+			return nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		f := &previewFile{newPath: path}
+		p.files[path] = f
+		p.order = append(p.order, f)
+
+	case change.DeleteFile != nil:
+		path := fileuri.ToPath(change.DeleteFile.URI)
+		if info, err := osStat(path); err == nil && info.IsDir() {
+			p.notes = append(p.notes, "delete directory: "+path)
+			return nil
+		}
+		f, err := p.file(path)
+		if err != nil {
+			return err
+		}
+		f.newPath = ""
+		delete(p.files, path)
+
+	case change.RenameFile != nil:
+		oldPath := fileuri.ToPath(change.RenameFile.OldURI)
+		newPath := fileuri.ToPath(change.RenameFile.NewURI)
+		if info, err := osStat(oldPath); err == nil && info.IsDir() {
+			p.notes = append(p.notes, fmt.Sprintf("rename directory: %s -> %s", oldPath, newPath))
+			return nil
+		}
+		f, err := p.file(oldPath)
+		if err != nil {
+			return err
+		}
+		f.newPath = newPath
+		delete(p.files, oldPath)
+		p.files[newPath] = f
+
+	case change.TextDocumentEdit != nil:
+		textEdits := make([]protocol.TextEdit, len(change.TextDocumentEdit.Edits))
+		for i, edit := range change.TextDocumentEdit.Edits {
+			var err error
+			textEdits[i], err = TextEditOf(edit)
+			if err != nil {
+				return fmt.Errorf("invalid edit type: %w", err)
+			}
+		}
+		return p.editText(string(change.TextDocumentEdit.TextDocument.URI), textEdits)
+	}
+	return nil
+}
+
+func (p *editPreview) render() (string, error) {
+	var output strings.Builder
+	for _, note := range p.notes {
+		output.WriteString(note + "\n")
+	}
+	for _, f := range p.order {
+		diff, err := UnifiedDiff(f.oldPath, f.newPath, f.before, f.after)
+		if err != nil {
+			return "", err
+		}
+		output.WriteString(diff)
+	}
+	return output.String(), nil
+}
+
+// UnifiedDiff renders the change from before to after as a unified diff. An empty
+// path stands for a file that does not exist, shown as /dev/null.
+func UnifiedDiff(oldPath, newPath string, before, after []byte) (string, error) {
+	fromFile, toFile := oldPath, newPath
+	if fromFile == "" {
+		fromFile = "/dev/null"
+	}
+	if toFile == "" {
+		toFile = "/dev/null"
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate diff: %v", err)
+	}
+
+	// Renames and empty files created or deleted still get a header
+	if diff == "" && fromFile != toFile {
+		diff = fmt.Sprintf("--- %s\n+++ %s\n", fromFile, toFile)
+	}
+	return diff, nil
+}
+
+// splitLines splits content into lines that keep their line endings. Unlike
+// difflib.SplitLines it does not add an empty line at the end of the content.
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
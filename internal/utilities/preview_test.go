@@ -0,0 +1,113 @@
+package utilities
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func TestPreviewWorkspaceEdit(t *testing.T) {
+	mfs := &mockFileSystem{
+		files: map[string][]byte{
+			"/ws/a.go":   []byte("package a\n\nfunc Old() {}\n"),
+			"/ws/old.go": []byte("package a\n"),
+		},
+	}
+	cleanup := setupMockFileSystem(t, mfs)
+	defer cleanup()
+
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			"file:///ws/a.go": {
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 2, Character: 5},
+						End:   protocol.Position{Line: 2, Character: 8},
+					},
+					NewText: "New",
+				},
+			},
+		},
+		DocumentChanges: []protocol.DocumentChange{
+			{
+				RenameFile: &protocol.RenameFile{
+					OldURI: "file:///ws/old.go",
+					NewURI: "file:///ws/new.go",
+				},
+			},
+			{
+				CreateFile: &protocol.CreateFile{URI: "file:///ws/b.go"},
+			},
+			{
+				TextDocumentEdit: &protocol.TextDocumentEdit{
+					TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: "file:///ws/b.go"},
+					},
+					Edits: []protocol.Or_TextDocumentEdit_edits_Elem{
+						{Value: protocol.TextEdit{NewText: "package a\n"}},
+					},
+				},
+			},
+		},
+	}
+
+	diff, err := PreviewWorkspaceEdit(edit, protocol.UTF16)
+	if err != nil {
+		t.Fatalf("PreviewWorkspaceEdit failed: %v", err)
+	}
+
+	// Unchanged lines keep a leading space, even when they are empty
+	expected := `--- /ws/a.go
++++ /ws/a.go
+@@ -1,3 +1,3 @@
+ package a
+` + " " + `
+-func Old() {}
++func New() {}
+--- /ws/old.go
++++ /ws/new.go
+--- /dev/null
++++ /ws/b.go
+@@ -0,0 +1 @@
++package a
+`
+	if diff != expected {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, expected)
+	}
+
+	// Nothing is written
+	if len(mfs.files) != 2 || string(mfs.files["/ws/a.go"]) != "package a\n\nfunc Old() {}\n" {
+		t.Errorf("files were modified: %v", mfs.files)
+	}
+}
+
+func TestPreviewPatch(t *testing.T) {
+	mfs := &mockFileSystem{
+		files: map[string][]byte{
+			"/ws/old.go": []byte("package main\n"),
+		},
+	}
+	cleanup := setupMockFileSystem(t, mfs)
+	defer cleanup()
+
+	diff, err := PreviewPatch([]FilePatch{
+		{OldPath: "/ws/old.go", Hunks: []Hunk{
+			{OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0, Lines: []string{"-package main"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("PreviewPatch failed: %v", err)
+	}
+
+	expected := `--- /ws/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package main
+`
+	if diff != expected {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, expected)
+	}
+	if _, ok := mfs.files["/ws/old.go"]; !ok {
+		t.Errorf("old.go was deleted")
+	}
+}
@@ -0,0 +1,160 @@
+package utilities
+
+import (
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// TextEditOf converts an edit of a TextDocumentEdit to a plain text edit. Snippet
+// edits are materialized, so that files never contain literal snippet markers.
+func TextEditOf(edit protocol.Or_TextDocumentEdit_edits_Elem) (protocol.TextEdit, error) {
+	if snippet, ok := edit.Value.(protocol.SnippetTextEdit); ok {
+		return protocol.TextEdit{
+			Range:   snippet.Range,
+			NewText: MaterializeSnippet(snippet.Snippet.Value),
+		}, nil
+	}
+	return edit.AsTextEdit()
+}
+
+// MaterializeSnippet turns LSP snippet syntax into the text an editor would insert
+// if the user accepted every default: tabstops such as $1 are removed, placeholders
+// such as ${2:name} become their default text, choices become their first option
+// and variables become their default, if any. Text that is not valid snippet
+// syntax is kept as it is.
+func MaterializeSnippet(snippet string) string {
+	p := snippetParser{input: snippet}
+	return p.parse(false)
+}
+
+// snippetParser materializes snippets following the grammar in the LSP
+// specification
+type snippetParser struct {
+	input string
+	pos   int
+}
+
+// parse materializes text up to the end of the input, or up to the closing brace
+// of the placeholder being parsed when nested
+func (p *snippetParser) parse(nested bool) string {
+	var output strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.input) && strings.IndexByte(`$}\`, p.input[p.pos+1]) >= 0:
+			output.WriteByte(p.input[p.pos+1])
+			p.pos += 2
+		case c == '}' && nested:
+			return output.String()
+		case c == '$':
+			output.WriteString(p.dollar())
+		default:
+			output.WriteByte(c)
+			p.pos++
+		}
+	}
+	return output.String()
+}
+
+// dollar materializes the tabstop, placeholder, choice or variable at the current
+// position, which is a dollar sign
+func (p *snippetParser) dollar() string {
+	start := p.pos
+	p.pos++
+
+	// $1 or $VAR
+	if name := p.name(); name != "" {
+		return ""
+	}
+	if p.pos >= len(p.input) || p.input[p.pos] != '{' {
+		return "$"
+	}
+	p.pos++
+
+	name := p.name()
+	if name == "" || p.pos >= len(p.input) {
+		return p.literal(start)
+	}
+	switch p.input[p.pos] {
+	case '}':
+		// ${1} or ${VAR}
+		p.pos++
+		return ""
+	case ':':
+		// ${1:default} or ${VAR:default}
+		p.pos++
+		text := p.parse(true)
+		if p.pos >= len(p.input) {
+			return p.literal(start)
+		}
+		p.pos++
+		return text
+	case '|':
+		// ${1|first,second|}
+		if choice, ok := p.choice(); ok {
+			return choice
+		}
+	case '/':
+		// ${VAR/regex/format/options} transforms a variable, which has no value here
+		if end := strings.IndexByte(p.input[p.pos:], '}'); end >= 0 {
+			p.pos += end + 1
+			return ""
+		}
+	}
+	return p.literal(start)
+}
+
+// name reads a tabstop number or variable name
+func (p *snippetParser) name() string {
+	start := p.pos
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+	isLetter := func(c byte) bool { return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') }
+	if p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+		for p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+			p.pos++
+		}
+	} else if p.pos < len(p.input) && isLetter(p.input[p.pos]) {
+		for p.pos < len(p.input) && (isLetter(p.input[p.pos]) || isDigit(p.input[p.pos])) {
+			p.pos++
+		}
+	}
+	return p.input[start:p.pos]
+}
+
+// choice reads the options of a choice, at the bar before the first, and returns
+// the first option
+func (p *snippetParser) choice() (string, bool) {
+	p.pos++
+	var first strings.Builder
+	done := false
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.input) && strings.IndexByte(`$}\,|`, p.input[p.pos+1]) >= 0:
+			if !done {
+				first.WriteByte(p.input[p.pos+1])
+			}
+			p.pos += 2
+		case c == '|' && p.pos+1 < len(p.input) && p.input[p.pos+1] == '}':
+			p.pos += 2
+			return first.String(), true
+		case c == ',':
+			done = true
+			p.pos++
+		default:
+			if !done {
+				first.WriteByte(c)
+			}
+			p.pos++
+		}
+	}
+	return "", false
+}
+
+// literal gives up on invalid syntax that started at start, keeping the dollar
+// sign as text and continuing after it
+func (p *snippetParser) literal(start int) string {
+	p.pos = start + 1
+	return "$"
+}
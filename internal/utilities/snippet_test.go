@@ -0,0 +1,53 @@
+package utilities
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func TestMaterializeSnippet(t *testing.T) {
+	tests := []struct {
+		snippet  string
+		expected string
+	}{
+		{"plain text", "plain text"},
+		{"fmt.Println($1)$0", "fmt.Println()"},
+		{"for ${1:i} := 0; ${1:i} < ${2:n}; ${1:i}++ {\n\t$0\n}", "for i := 0; i < n; i++ {\n\t\n}"},
+		{"${1:outer ${2:inner}}", "outer inner"},
+		{"${1|public,private|} int", "public int"},
+		{"${1|a\\,b,c|}", "a,b"},
+		{"${TM_FILENAME:main.go}", "main.go"},
+		{"$TM_SELECTED_TEXT!", "!"},
+		{"${TM_FILENAME/(.*)\\..*/$1/}", ""},
+		{"cost: \\$5 \\} \\\\", "cost: $5 } \\"},
+		{"${1:unterminated", "${1:unterminated"},
+		{"$", "$"},
+		{"a $ b", "a $ b"},
+		{"${}", "${}"},
+		{"${1}x", "x"},
+		{"$1abc", "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.snippet, func(t *testing.T) {
+			if result := MaterializeSnippet(tt.snippet); result != tt.expected {
+				t.Errorf("MaterializeSnippet(%q) = %q, want %q", tt.snippet, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTextEditOfSnippet(t *testing.T) {
+	rng := protocol.Range{End: protocol.Position{Character: 3}}
+	edit, err := TextEditOf(protocol.Or_TextDocumentEdit_edits_Elem{Value: protocol.SnippetTextEdit{
+		Range:   rng,
+		Snippet: protocol.StringValue{Kind: "snippet", Value: "func ${1:name}() {$0}"},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if edit.Range != rng || edit.NewText != "func name() {}" {
+		t.Errorf("TextEditOf() = %+v", edit)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package watcher
+
+import "syscall"
+
+// File system magic numbers from statfs(2) where inotify misses remote changes
+var networkFilesystems = map[int64]string{
+	0x6969:     "nfs",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x517b:     "smb",
+	0x65735546: "fuse",
+	0x01021997: "9p",
+}
+
+// isNetworkFilesystem reports whether path is on a file system that does not
+// deliver inotify events for changes made elsewhere
+func isNetworkFilesystem(path string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return false
+	}
+	_, network := networkFilesystems[int64(stat.Type)]
+	return network
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package watcher
+
+// isNetworkFilesystem is only implemented on Linux, other platforms use the
+// native watcher unless it fails
+func isNetworkFilesystem(path string) bool {
+	return false
+}
@@ -1,55 +1,148 @@
 package watcher
 
 import (
+	"bufio"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	gitignore "github.com/sabhiram/go-gitignore"
 )
 
-// GitignoreMatcher provides a simple wrapper around the go-gitignore package
+// GitignoreMatcher matches paths against the .gitignore files of a workspace. The
+// root .gitignore and .git/info/exclude apply to the whole workspace, and nested
+// .gitignore files apply to their own directory. Nested files are loaded the first
+// time a path below them is checked.
 type GitignoreMatcher struct {
-	gitignore *gitignore.GitIgnore
-	basePath  string
+	basePath string
+
+	// Compiled patterns by the directory they apply to, nil when it has none
+	ignores map[string]*gitignore.GitIgnore
+	mu      sync.RWMutex
 }
 
 // NewGitignoreMatcher creates a new gitignore matcher for a workspace
 func NewGitignoreMatcher(workspacePath string) (*GitignoreMatcher, error) {
-	gitignorePath := filepath.Join(workspacePath, ".gitignore")
-
-	// Check if .gitignore exists
-	_, err := os.Stat(gitignorePath)
-	if os.IsNotExist(err) {
-		// No .gitignore file, return a matcher with no patterns
-		emptyIgnore := gitignore.CompileIgnoreLines([]string{}...)
-		return &GitignoreMatcher{
-			gitignore: emptyIgnore,
-			basePath:  workspacePath,
-		}, nil
-	} else if err != nil {
-		return nil, err
+	g := &GitignoreMatcher{
+		basePath: workspacePath,
+		ignores:  make(map[string]*gitignore.GitIgnore),
 	}
 
-	// Parse .gitignore file using the go-gitignore library
-	ignore, err := gitignore.CompileIgnoreFile(gitignorePath)
+	// Load the workspace's own patterns up front so that errors are reported
+	ignore, err := g.load(workspacePath)
 	if err != nil {
 		return nil, err
 	}
+	g.ignores[workspacePath] = ignore
 
-	return &GitignoreMatcher{
-		gitignore: ignore,
-		basePath:  workspacePath,
-	}, nil
+	return g, nil
 }
 
 // ShouldIgnore checks if a file or directory should be ignored based on gitignore patterns
 func (g *GitignoreMatcher) ShouldIgnore(path string, isDir bool) bool {
-	// Make path relative to workspace root
-	relPath, err := filepath.Rel(g.basePath, path)
+	if !isWithin(path, g.basePath) || path == g.basePath {
+		return false
+	}
+
+	// Check the patterns of every directory from the root down to the path's parent
+	dir := g.basePath
+	rel, err := filepath.Rel(g.basePath, path)
 	if err != nil {
 		return false
 	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
 
-	// Use the go-gitignore Match function to check if the path should be ignored
-	return g.gitignore.MatchesPath(relPath)
+	for i := range parts {
+		if ignore := g.ignoresFor(dir); ignore != nil {
+			relPath := strings.Join(parts[i:], "/")
+			// Directory patterns such as "node_modules/" only match with a trailing slash
+			if isDir {
+				relPath += "/"
+			}
+			if ignore.MatchesPath(relPath) {
+				return true
+			}
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return false
+}
+
+// Reload discards the cached patterns of the directory containing a changed
+// .gitignore or .git/info/exclude file, so that they are read again
+func (g *GitignoreMatcher) Reload(path string) {
+	dir := filepath.Dir(path)
+	if filepath.Base(path) == "exclude" && filepath.Dir(dir) == filepath.Join(g.basePath, ".git") {
+		dir = g.basePath
+	}
+
+	g.mu.Lock()
+	delete(g.ignores, dir)
+	g.mu.Unlock()
+}
+
+// IsGitignoreFile reports whether a path is a file that defines ignore patterns
+func IsGitignoreFile(path string) bool {
+	return filepath.Base(path) == ".gitignore" ||
+		strings.HasSuffix(filepath.ToSlash(path), "/.git/info/exclude")
+}
+
+// ignoresFor returns the compiled patterns of a directory, loading them if needed
+func (g *GitignoreMatcher) ignoresFor(dir string) *gitignore.GitIgnore {
+	g.mu.RLock()
+	ignore, ok := g.ignores[dir]
+	g.mu.RUnlock()
+	if ok {
+		return ignore
+	}
+
+	ignore, err := g.load(dir)
+	if err != nil {
+		watcherLogger.Error("Error reading gitignore in %s: %v", dir, err)
+	}
+
+	g.mu.Lock()
+	g.ignores[dir] = ignore
+	g.mu.Unlock()
+	return ignore
+}
+
+// load compiles the patterns that apply to a directory, or returns nil when it has none
+func (g *GitignoreMatcher) load(dir string) (*gitignore.GitIgnore, error) {
+	files := []string{filepath.Join(dir, ".gitignore")}
+	if dir == g.basePath {
+		files = append(files, filepath.Join(dir, ".git", "info", "exclude"))
+	}
+
+	var lines []string
+	for _, file := range files {
+		fileLines, err := readLines(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		lines = append(lines, fileLines...)
+	}
+
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	return gitignore.CompileIgnoreLines(lines...), nil
+}
+
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
@@ -18,13 +19,21 @@ type LSPClient interface {
 	// NotifyChange notifies the server of a file change
 	NotifyChange(ctx context.Context, path string) error
 
+	// NotifySave notifies the server that a file was saved
+	NotifySave(ctx context.Context, path string) error
+
 	// DidChangeWatchedFiles sends watched file events to the server
 	DidChangeWatchedFiles(ctx context.Context, params protocol.DidChangeWatchedFilesParams) error
+
+	// SetFileWatchHandlers registers the handlers for the file watchers the
+	// server registers and unregisters
+	SetFileWatchHandlers(watch lsp.FileWatchHandler, unwatch lsp.FileUnwatchHandler)
 }
 
 // WatcherConfig holds basic configuration for the watcher
 type WatcherConfig struct {
-	// DebounceTime is the duration to wait before sending file change events
+	// DebounceTime is how long to wait for further file events before sending
+	// the pending ones to the server in a single notification
 	DebounceTime time.Duration
 
 	// ExcludedDirs are directory names that should be excluded from watching
@@ -38,12 +47,37 @@ type WatcherConfig struct {
 
 	// MaxFileSize is the maximum size of a file to open
 	MaxFileSize int64
+
+	// FilePatterns are glob patterns watched and opened in addition to the file
+	// watchers registered by the server
+	FilePatterns []string
+
+	// ExcludePatterns are glob patterns, relative to the workspace root, of files
+	// and directories that should not be watched or opened
+	ExcludePatterns []string
+
+	// IncludePatterns, when set, limit the files that are watched and opened to
+	// those matching one of these glob patterns, relative to the workspace root
+	IncludePatterns []string
+
+	// Backend selects how changes are detected: BackendNative, BackendPoll, or
+	// BackendAuto to poll only when the native watcher cannot be used
+	Backend string
+
+	// PollInterval is how often the polling backend rescans watched directories
+	PollInterval time.Duration
+
+	// MaxWatchedDirs caps how many directories are watched, 0 for no limit.
+	// Directories past the cap are not watched and a warning is logged.
+	MaxWatchedDirs int
 }
 
 // DefaultWatcherConfig returns a configuration with sensible defaults
 func DefaultWatcherConfig() *WatcherConfig {
 	return &WatcherConfig{
 		DebounceTime: 300 * time.Millisecond,
+		Backend:      BackendAuto,
+		PollInterval: 2 * time.Second,
 		ExcludedDirs: map[string]bool{
 			".git":         true,
 			"node_modules": true,
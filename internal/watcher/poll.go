@@ -0,0 +1,258 @@
+package watcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// eventSource delivers file system events for watched directories. It is
+// implemented by fsnotify and by a polling fallback for file systems where
+// fsnotify gets no events, such as NFS, SSHFS and some container mounts.
+type eventSource interface {
+	Add(path string) error
+	Remove(path string) error
+	WatchList() []string
+	Close() error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+}
+
+// Watcher backends
+const (
+	BackendAuto   = "auto"
+	BackendNative = "native"
+	BackendPoll   = "poll"
+)
+
+// newEventSource creates the event source for the configured backend. In auto
+// mode polling is used when a root is on a network file system or when the
+// native watcher cannot be created or cannot watch a root.
+func newEventSource(config *WatcherConfig, roots []string) (eventSource, error) {
+	switch config.Backend {
+	case BackendPoll:
+		return newPollingWatcher(config.PollInterval), nil
+	case BackendNative:
+		return newNativeWatcher()
+	case "", BackendAuto:
+	default:
+		return nil, errors.New("unknown watcher backend: " + config.Backend)
+	}
+
+	for _, root := range roots {
+		if isNetworkFilesystem(root) {
+			watcherLogger.Info("%s is on a network file system, polling for changes every %v", root, config.PollInterval)
+			return newPollingWatcher(config.PollInterval), nil
+		}
+	}
+
+	native, err := newNativeWatcher()
+	if err != nil {
+		watcherLogger.Warn("Native file watcher unavailable, polling for changes every %v: %v", config.PollInterval, err)
+		return newPollingWatcher(config.PollInterval), nil
+	}
+	for _, root := range roots {
+		if err := native.Add(root); err != nil {
+			watcherLogger.Warn("Native file watcher cannot watch %s, polling for changes every %v: %v", root, config.PollInterval, err)
+			_ = native.Close()
+			return newPollingWatcher(config.PollInterval), nil
+		}
+	}
+	return native, nil
+}
+
+// OnNetworkFilesystem reports whether path is on a network file system, where
+// native watching misses changes made on other machines
+func OnNetworkFilesystem(path string) bool {
+	return isNetworkFilesystem(path)
+}
+
+// nativeWatcher adapts fsnotify to eventSource
+type nativeWatcher struct {
+	*fsnotify.Watcher
+}
+
+func newNativeWatcher() (*nativeWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &nativeWatcher{watcher}, nil
+}
+
+func (n *nativeWatcher) Events() <-chan fsnotify.Event { return n.Watcher.Events }
+func (n *nativeWatcher) Errors() <-chan error          { return n.Watcher.Errors }
+
+// fileState is what the polling watcher compares between scans
+type fileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+}
+
+// pollingWatcher finds changes by listing watched directories on an interval.
+// Like fsnotify it is not recursive: each directory is added separately.
+type pollingWatcher struct {
+	interval time.Duration
+	events   chan fsnotify.Event
+	errors   chan error
+	done     chan struct{}
+
+	// Last seen contents of each watched directory
+	dirs map[string]map[string]fileState
+	mu   sync.Mutex
+
+	closeOnce sync.Once
+}
+
+func newPollingWatcher(interval time.Duration) *pollingWatcher {
+	if interval <= 0 {
+		interval = DefaultWatcherConfig().PollInterval
+	}
+	p := &pollingWatcher{
+		interval: interval,
+		events:   make(chan fsnotify.Event, 100),
+		errors:   make(chan error, 10),
+		done:     make(chan struct{}),
+		dirs:     make(map[string]map[string]fileState),
+	}
+	go p.run()
+	return p
+}
+
+func (p *pollingWatcher) Add(path string) error {
+	entries, err := scanDir(path)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.dirs[path]; !exists {
+		p.dirs[path] = entries
+	}
+	return nil
+}
+
+func (p *pollingWatcher) Remove(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.dirs[path]; !exists {
+		return fsnotify.ErrNonExistentWatch
+	}
+	delete(p.dirs, path)
+	return nil
+}
+
+func (p *pollingWatcher) WatchList() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]string, 0, len(p.dirs))
+	for dir := range p.dirs {
+		list = append(list, dir)
+	}
+	return list
+}
+
+func (p *pollingWatcher) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	return nil
+}
+
+func (p *pollingWatcher) Events() <-chan fsnotify.Event { return p.events }
+func (p *pollingWatcher) Errors() <-chan error          { return p.errors }
+
+func (p *pollingWatcher) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.poll()
+		}
+	}
+}
+
+// poll rescans every watched directory and sends events for the differences
+func (p *pollingWatcher) poll() {
+	for _, dir := range p.WatchList() {
+		current, err := scanDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			// The parent reports the removal
+			p.mu.Lock()
+			delete(p.dirs, dir)
+			p.mu.Unlock()
+			continue
+		} else if err != nil {
+			p.sendError(err)
+			continue
+		}
+
+		p.mu.Lock()
+		previous, exists := p.dirs[dir]
+		if exists {
+			p.dirs[dir] = current
+		}
+		p.mu.Unlock()
+		if !exists {
+			continue
+		}
+
+		for name, state := range current {
+			path := filepath.Join(dir, name)
+			old, existed := previous[name]
+			switch {
+			case !existed:
+				p.send(fsnotify.Event{Name: path, Op: fsnotify.Create})
+			case !state.isDir && (!state.modTime.Equal(old.modTime) || state.size != old.size):
+				p.send(fsnotify.Event{Name: path, Op: fsnotify.Write})
+			}
+		}
+		for name := range previous {
+			if _, exists := current[name]; !exists {
+				p.send(fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove})
+			}
+		}
+	}
+}
+
+func (p *pollingWatcher) send(event fsnotify.Event) {
+	select {
+	case p.events <- event:
+	case <-p.done:
+	}
+}
+
+func (p *pollingWatcher) sendError(err error) {
+	select {
+	case p.errors <- err:
+	case <-p.done:
+	}
+}
+
+// scanDir records the state of every entry in a directory
+func scanDir(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since the directory was read
+			continue
+		}
+		states[entry.Name()] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+			isDir:   entry.IsDir(),
+		}
+	}
+	return states, nil
+}
@@ -198,3 +198,67 @@ exact_file.txt
 		}
 	})
 }
+
+// TestGitignoreMatcher tests matching against the root .gitignore, .git/info/exclude
+// and nested .gitignore files without running the watcher
+func TestGitignoreMatcher(t *testing.T) {
+	testDir := t.TempDir()
+
+	files := map[string]string{
+		".gitignore":           "node_modules/\n*.log\n",
+		".git/info/exclude":    "scratch.txt\n",
+		"pkg/.gitignore":       "generated/\n",
+		"pkg/generated/a.go":   "",
+		"pkg/main.go":          "",
+		"node_modules/x/a.js":  "",
+		"app/debug.log":        "",
+		"scratch.txt":          "",
+		"other/generated/b.go": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(testDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	matcher, err := watcher.NewGitignoreMatcher(testDir)
+	if err != nil {
+		t.Fatalf("Failed to create gitignore matcher: %v", err)
+	}
+
+	testCases := []struct {
+		path   string
+		isDir  bool
+		ignore bool
+	}{
+		{"node_modules", true, true},
+		{"node_modules/x/a.js", false, true},
+		{"app/debug.log", false, true},
+		{"scratch.txt", false, true},
+		{"pkg/generated", true, true},
+		{"pkg/generated/a.go", false, true},
+		{"pkg/main.go", false, false},
+		{"other/generated", true, false},
+		{"other/generated/b.go", false, false},
+	}
+	for _, tc := range testCases {
+		got := matcher.ShouldIgnore(filepath.Join(testDir, tc.path), tc.isDir)
+		if got != tc.ignore {
+			t.Errorf("ShouldIgnore(%s) = %v, want %v", tc.path, got, tc.ignore)
+		}
+	}
+
+	// Edited patterns apply after a reload
+	nested := filepath.Join(testDir, "pkg", ".gitignore")
+	if err := os.WriteFile(nested, []byte("main.go\n"), 0644); err != nil {
+		t.Fatalf("Failed to update .gitignore: %v", err)
+	}
+	matcher.Reload(nested)
+	if !matcher.ShouldIgnore(filepath.Join(testDir, "pkg", "main.go"), false) {
+		t.Errorf("Expected pkg/main.go to be ignored after reload")
+	}
+}
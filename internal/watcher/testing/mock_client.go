@@ -4,6 +4,8 @@ import (
 	"context"
 	"sync"
 
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 	"github.com/isaacphi/mcp-language-server/internal/watcher"
 )
@@ -18,11 +20,15 @@ type FileEvent struct {
 type MockLSPClient struct {
 	mu             sync.Mutex
 	events         []FileEvent
+	notifications  int
+	saves          map[string]int
 	openedFiles    map[string]bool
 	openErrors     map[string]error
 	notifyErrors   map[string]error
 	changeErrors   map[string]error
 	eventsReceived chan struct{}
+	watch          lsp.FileWatchHandler
+	unwatch        lsp.FileUnwatchHandler
 }
 
 // NewMockLSPClient creates a new mock LSP client for testing
@@ -30,6 +36,7 @@ func NewMockLSPClient() *MockLSPClient {
 	return &MockLSPClient{
 		events:         []FileEvent{},
 		openedFiles:    make(map[string]bool),
+		saves:          make(map[string]int),
 		openErrors:     make(map[string]error),
 		notifyErrors:   make(map[string]error),
 		changeErrors:   make(map[string]error),
@@ -57,6 +64,14 @@ func (m *MockLSPClient) OpenFile(ctx context.Context, path string) error {
 	return nil
 }
 
+// SetOpenError makes opening path fail, so that its changes are reported as
+// watched file events
+func (m *MockLSPClient) SetOpenError(path string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.openErrors[path] = err
+}
+
 // NotifyChange mocks notifying the server of a file change
 func (m *MockLSPClient) NotifyChange(ctx context.Context, path string) error {
 	m.mu.Lock()
@@ -68,7 +83,7 @@ func (m *MockLSPClient) NotifyChange(ctx context.Context, path string) error {
 
 	// Record this as a change event
 	m.events = append(m.events, FileEvent{
-		URI:  "file://" + path,
+		URI:  string(fileuri.FromPath(path)),
 		Type: protocol.FileChangeType(protocol.Changed),
 	})
 
@@ -82,11 +97,27 @@ func (m *MockLSPClient) NotifyChange(ctx context.Context, path string) error {
 	return nil
 }
 
+// NotifySave mocks notifying the server that a file was saved
+func (m *MockLSPClient) NotifySave(ctx context.Context, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saves[path]++
+	return nil
+}
+
+// CountSaves returns how many times a file was reported as saved
+func (m *MockLSPClient) CountSaves(path string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.saves[path]
+}
+
 // DidChangeWatchedFiles mocks sending watched file events to the server
 func (m *MockLSPClient) DidChangeWatchedFiles(ctx context.Context, params protocol.DidChangeWatchedFilesParams) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.notifications++
 	for _, change := range params.Changes {
 		uri := string(change.URI)
 
@@ -136,11 +167,20 @@ func (m *MockLSPClient) CountEvents(uri string, eventType protocol.FileChangeTyp
 	return count
 }
 
-// ResetEvents clears the recorded events
+// CountNotifications returns how many didChangeWatchedFiles notifications were sent
+func (m *MockLSPClient) CountNotifications() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.notifications
+}
+
+// ResetEvents clears the recorded events and saves
 func (m *MockLSPClient) ResetEvents() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.events = []FileEvent{}
+	m.notifications = 0
+	m.saves = make(map[string]int)
 }
 
 // WaitForEvent waits for at least one event to be received or context to be done
@@ -155,3 +195,18 @@ func (m *MockLSPClient) WaitForEvent(ctx context.Context) bool {
 
 // Verify the MockLSPClient implements the watcher.LSPClient interface
 var _ watcher.LSPClient = (*MockLSPClient)(nil)
+
+// SetFileWatchHandlers mocks registering the handlers for the server's file watchers
+func (m *MockLSPClient) SetFileWatchHandlers(watch lsp.FileWatchHandler, unwatch lsp.FileUnwatchHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watch = watch
+	m.unwatch = unwatch
+}
+
+// FileWatchHandlers returns the handlers the watcher registered
+func (m *MockLSPClient) FileWatchHandlers() (lsp.FileWatchHandler, lsp.FileUnwatchHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.watch, m.unwatch
+}
@@ -2,6 +2,7 @@ package testing
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -140,6 +141,37 @@ func TestWatcherBasicFunctionality(t *testing.T) {
 		}
 	})
 
+	t.Run("OpenFileModification", func(t *testing.T) {
+		// Reset events
+		mockClient.ResetEvents()
+
+		// Changes to open files are sent as didChange followed by didSave
+		filePath := filepath.Join(testDir, "test.txt")
+		if err := mockClient.OpenFile(ctx, filePath); err != nil {
+			t.Fatalf("Failed to open file: %v", err)
+		}
+		err := os.WriteFile(filePath, []byte("Saved content"), 0644)
+		if err != nil {
+			t.Fatalf("Failed to modify file: %v", err)
+		}
+
+		// Wait for notification
+		waitCtx, waitCancel := context.WithTimeout(ctx, 2*time.Second)
+		defer waitCancel()
+
+		if !mockClient.WaitForEvent(waitCtx) {
+			t.Fatal("Timed out waiting for file modification event")
+		}
+
+		uri := "file://" + filePath
+		if count := mockClient.CountEvents(uri, protocol.FileChangeType(protocol.Changed)); count != 1 {
+			t.Errorf("Expected one change event for %s, got %d", filePath, count)
+		}
+		if saves := mockClient.CountSaves(filePath); saves != 1 {
+			t.Errorf("Expected one save for %s, got %d", filePath, saves)
+		}
+	})
+
 	t.Run("FileDeletion", func(t *testing.T) {
 		// Reset events
 		mockClient.ResetEvents()
@@ -432,4 +464,345 @@ func TestRapidChangesDebouncing(t *testing.T) {
 			t.Errorf("Expected at most 2 change events due to debouncing, got %d", count)
 		}
 	})
+
+	// Test that events for many files are sent together
+	t.Run("BatchedCreates", func(t *testing.T) {
+		mockClient.ResetEvents()
+
+		const fileCount = 20
+		for i := range fileCount {
+			filePath := filepath.Join(testDir, fmt.Sprintf("batch%d.txt", i))
+			if err := os.WriteFile(filePath, []byte("content"), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+		}
+
+		// Wait longer than the debounce time
+		time.Sleep(config.DebounceTime + 500*time.Millisecond)
+
+		for i := range fileCount {
+			uri := "file://" + filepath.Join(testDir, fmt.Sprintf("batch%d.txt", i))
+			if count := mockClient.CountEvents(uri, protocol.FileChangeType(protocol.Created)); count != 1 {
+				t.Errorf("Expected 1 create event for %s, got %d", uri, count)
+			}
+		}
+
+		// Writes right after creation are folded into the create events
+		if count := mockClient.CountNotifications(); count > 2 {
+			t.Errorf("Expected the create events in at most 2 notifications, got %d", count)
+		}
+	})
+}
+
+// TestPollingWatcher tests the polling backend used for network file systems
+func TestPollingWatcher(t *testing.T) {
+	testDir, err := os.MkdirTemp("", "watcher-poll-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(testDir); err != nil {
+			t.Logf("Failed to remove test directory: %v", err)
+		}
+	}()
+
+	mockClient := NewMockLSPClient()
+
+	config := watcher.DefaultWatcherConfig()
+	config.Backend = watcher.BackendPoll
+	config.PollInterval = 100 * time.Millisecond
+	config.DebounceTime = 50 * time.Millisecond
+	testWatcher := watcher.NewWorkspaceWatcherWithConfig(mockClient, config)
+
+	watchers := []protocol.FileSystemWatcher{
+		{
+			GlobPattern: protocol.GlobPattern{Value: "**/*.txt"},
+			Kind: func() *protocol.WatchKind {
+				kind := protocol.WatchKind(protocol.WatchCreate | protocol.WatchChange | protocol.WatchDelete)
+				return &kind
+			}(),
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	go testWatcher.WatchWorkspace(ctx, testDir)
+	time.Sleep(300 * time.Millisecond)
+	testWatcher.AddRegistrations(ctx, "test-id", watchers)
+
+	// Files in new directories are found once the directory is watched
+	subDir := filepath.Join(testDir, "sub")
+	filePath := filepath.Join(subDir, "polled.txt")
+	uri := "file://" + filePath
+
+	waitFor := func(changeType protocol.FileChangeType) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) {
+			if mockClient.CountEvents(uri, changeType) > 0 {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for event %d on %s, got %+v", changeType, uri, mockClient.GetEvents())
+	}
+
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	if err := os.WriteFile(filePath, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	waitFor(protocol.FileChangeType(protocol.Created))
+
+	if err := os.WriteFile(filePath, []byte("changed content"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	waitFor(protocol.FileChangeType(protocol.Changed))
+
+	if err := os.Remove(filePath); err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+	waitFor(protocol.FileChangeType(protocol.Deleted))
+}
+
+// TestServerRegistrations tests that events are filtered by the patterns and kinds
+// of the file watchers registered by the server
+func TestServerRegistrations(t *testing.T) {
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping filesystem watcher tests in GitHub Actions environment")
+	}
+
+	testDir, err := os.MkdirTemp("", "watcher-registration-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(testDir); err != nil {
+			t.Logf("Failed to remove test directory: %v", err)
+		}
+	}()
+	srcDir := filepath.Join(testDir, "src")
+	if err := os.Mkdir(srcDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	mockClient := NewMockLSPClient()
+
+	config := watcher.DefaultWatcherConfig()
+	config.DebounceTime = 50 * time.Millisecond
+	testWatcher := watcher.NewWorkspaceWatcherWithConfig(mockClient, config)
+
+	createAndDelete := protocol.WatchKind(protocol.WatchCreate | protocol.WatchDelete)
+	changeOnly := protocol.WatchKind(protocol.WatchChange)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	go testWatcher.WatchWorkspace(ctx, testDir)
+	time.Sleep(500 * time.Millisecond)
+
+	testWatcher.AddRegistrations(ctx, "manifests", []protocol.FileSystemWatcher{
+		{GlobPattern: protocol.GlobPattern{Value: "**/Cargo.{toml,lock}"}, Kind: &createAndDelete},
+	})
+	testWatcher.AddRegistrations(ctx, "sources", []protocol.FileSystemWatcher{
+		{
+			GlobPattern: protocol.GlobPattern{Value: protocol.RelativePattern{
+				BaseURI: protocol.Or_RelativePattern_baseUri{Value: protocol.WorkspaceFolder{URI: "file://" + testDir, Name: "test"}},
+				Pattern: "src/**/*.rs",
+			}},
+			Kind: &changeOnly,
+		},
+	})
+	time.Sleep(200 * time.Millisecond)
+
+	settle := func() {
+		time.Sleep(config.DebounceTime + 400*time.Millisecond)
+	}
+	write := func(path string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	t.Run("Kinds", func(t *testing.T) {
+		mockClient.ResetEvents()
+
+		cargoPath := filepath.Join(testDir, "Cargo.lock")
+		mainPath := filepath.Join(srcDir, "main.rs")
+		write(cargoPath)
+		write(mainPath)
+		write(filepath.Join(testDir, "notes.txt"))
+		settle()
+
+		if count := mockClient.CountEvents("file://"+cargoPath, protocol.FileChangeType(protocol.Created)); count != 1 {
+			t.Errorf("Expected 1 create event for Cargo.lock, got %d", count)
+		}
+		if count := mockClient.CountEvents("file://"+mainPath, protocol.FileChangeType(protocol.Created)); count != 0 {
+			t.Errorf("Expected no create event for main.rs, which is watched for changes only, got %d", count)
+		}
+		if !mockClient.IsFileOpen(mainPath) {
+			t.Errorf("Expected main.rs to match the relative pattern and be opened")
+		}
+		for _, event := range mockClient.GetEvents() {
+			if event.URI == "file://"+filepath.Join(testDir, "notes.txt") {
+				t.Errorf("Unexpected event for unwatched file: %+v", event)
+			}
+		}
+
+		mockClient.ResetEvents()
+		if err := os.Remove(cargoPath); err != nil {
+			t.Fatalf("Failed to delete file: %v", err)
+		}
+		settle()
+		if count := mockClient.CountEvents("file://"+cargoPath, protocol.FileChangeType(protocol.Deleted)); count != 1 {
+			t.Errorf("Expected 1 delete event for Cargo.lock, got %d", count)
+		}
+	})
+
+	t.Run("Unregister", func(t *testing.T) {
+		testWatcher.RemoveRegistrations("sources")
+		mockClient.ResetEvents()
+
+		libPath := filepath.Join(srcDir, "lib.rs")
+		write(libPath)
+		settle()
+
+		if mockClient.IsFileOpen(libPath) {
+			t.Errorf("Expected lib.rs not to be opened after its watcher was unregistered")
+		}
+		if events := mockClient.GetEvents(); len(events) != 0 {
+			t.Errorf("Expected no events after unregistering, got %+v", events)
+		}
+	})
+}
+
+// TestConfigPatterns tests that the configured include and exclude patterns limit
+// the files that are watched and opened
+func TestConfigPatterns(t *testing.T) {
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping filesystem watcher tests in GitHub Actions environment")
+	}
+
+	testDir, err := os.MkdirTemp("", "watcher-patterns-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(testDir); err != nil {
+			t.Logf("Failed to remove test directory: %v", err)
+		}
+	}()
+	for _, dir := range []string{"src", "docs", "src/generated"} {
+		if err := os.MkdirAll(filepath.Join(testDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	mockClient := NewMockLSPClient()
+
+	config := watcher.DefaultWatcherConfig()
+	config.DebounceTime = 50 * time.Millisecond
+	config.IncludePatterns = []string{"src/**"}
+	config.ExcludePatterns = []string{"src/generated", "**/*_gen.go"}
+	testWatcher := watcher.NewWorkspaceWatcherWithConfig(mockClient, config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	go testWatcher.WatchWorkspace(ctx, testDir)
+	time.Sleep(500 * time.Millisecond)
+	testWatcher.AddRegistrations(ctx, "go", []protocol.FileSystemWatcher{
+		{GlobPattern: protocol.GlobPattern{Value: "**/*.go"}},
+	})
+	time.Sleep(200 * time.Millisecond)
+
+	paths := map[string]bool{
+		// Matches the include pattern
+		"src/main.go": true,
+		// Matches no include pattern
+		"docs/example.go": false,
+		// Matches the include pattern, but the exclude patterns win
+		"src/api_gen.go":         false,
+		"src/generated/types.go": false,
+	}
+	for path := range paths {
+		if err := os.WriteFile(filepath.Join(testDir, path), []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	time.Sleep(config.DebounceTime + 500*time.Millisecond)
+
+	for path, included := range paths {
+		fullPath := filepath.Join(testDir, path)
+		created := mockClient.CountEvents("file://"+fullPath, protocol.FileChangeType(protocol.Created))
+		if included && (created != 1 || !mockClient.IsFileOpen(fullPath)) {
+			t.Errorf("Expected %s to be reported and opened, got %d create events", path, created)
+		}
+		if !included && (created != 0 || mockClient.IsFileOpen(fullPath)) {
+			t.Errorf("Expected %s to be left out, got %d create events", path, created)
+		}
+	}
+}
+
+// TestMaxWatchedDirs tests that directories past the configured cap are not watched
+func TestMaxWatchedDirs(t *testing.T) {
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping filesystem watcher tests in GitHub Actions environment")
+	}
+
+	testDir, err := os.MkdirTemp("", "watcher-limit-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(testDir); err != nil {
+			t.Logf("Failed to remove test directory: %v", err)
+		}
+	}()
+	for _, dir := range []string{"a", "b", "c"} {
+		if err := os.Mkdir(filepath.Join(testDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	mockClient := NewMockLSPClient()
+
+	// The workspace root and the first directory, in walk order, are watched
+	config := watcher.DefaultWatcherConfig()
+	config.DebounceTime = 50 * time.Millisecond
+	config.MaxWatchedDirs = 2
+	testWatcher := watcher.NewWorkspaceWatcherWithConfig(mockClient, config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	go testWatcher.WatchWorkspace(ctx, testDir)
+	time.Sleep(500 * time.Millisecond)
+	testWatcher.AddRegistrations(ctx, "txt", []protocol.FileSystemWatcher{
+		{GlobPattern: protocol.GlobPattern{Value: "**/*.txt"}},
+	})
+	time.Sleep(200 * time.Millisecond)
+
+	for _, dir := range []string{".", "a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(testDir, dir, "file.txt"), []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	time.Sleep(config.DebounceTime + 500*time.Millisecond)
+
+	for dir, watched := range map[string]bool{".": true, "a": true, "b": false, "c": false} {
+		uri := "file://" + filepath.Join(testDir, dir, "file.txt")
+		created := mockClient.CountEvents(uri, protocol.FileChangeType(protocol.Created))
+		if watched && created != 1 {
+			t.Errorf("Expected 1 create event in %s, got %d", dir, created)
+		}
+		if !watched && created != 0 {
+			t.Errorf("Expected no events in %s, past the directory cap, got %d", dir, created)
+		}
+	}
 }
@@ -0,0 +1,38 @@
+package watcher
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// WalkWorkspaceFiles calls fn for each file under root that is not in a hidden or
+// commonly excluded directory or excluded by .gitignore
+func WalkWorkspaceFiles(root string, fn func(path string) error) error {
+	gitignore, err := NewGitignoreMatcher(root)
+	if err != nil {
+		return err
+	}
+	excludedDirs := DefaultWatcherConfig().ExcludedDirs
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		name := d.Name()
+		if d.IsDir() {
+			if strings.HasPrefix(name, ".") || excludedDirs[name] || gitignore.ShouldIgnore(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(name, ".") || gitignore.ShouldIgnore(path, false) {
+			return nil
+		}
+		return fn(path)
+	})
+}
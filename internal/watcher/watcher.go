@@ -2,17 +2,21 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
 	"github.com/isaacphi/mcp-language-server/internal/logging"
-	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
 // Create a logger for the watcher component
@@ -20,19 +24,34 @@ var watcherLogger = logging.NewLogger(logging.Watcher)
 
 // WorkspaceWatcher manages LSP file watching
 type WorkspaceWatcher struct {
-	client        LSPClient
-	workspacePath string
-
-	config      *WatcherConfig
-	debounceMap map[string]*time.Timer
-	debounceMu  sync.Mutex
-
-	// File watchers registered by the server
-	registrations  []protocol.FileSystemWatcher
-	registrationMu sync.RWMutex
-
-	// Gitignore matcher
-	gitignore *GitignoreMatcher
+	client LSPClient
+
+	// Workspace roots being watched, each with its own gitignore matcher
+	workspacePaths []string
+	gitignores     map[string]*GitignoreMatcher
+	fsWatcher      eventSource
+	rootsMu        sync.RWMutex
+
+	// Approximate number of watched directories, checked against MaxWatchedDirs
+	watchedDirs  atomic.Int64
+	limitWarning sync.Once
+
+	config *WatcherConfig
+
+	// File events waiting to be sent in the next didChangeWatchedFiles batch
+	pending      map[string]protocol.FileChangeType
+	pendingOrder []string
+	batchTimer   *time.Timer
+	batchStart   time.Time
+	batchMu      sync.Mutex
+
+	// File events queued since the watcher was created, indexed by change type
+	eventCounts [protocol.Deleted + 1]atomic.Int64
+
+	// File watchers registered by the server, flattened from registrationsByID
+	registrations     []protocol.FileSystemWatcher
+	registrationsByID map[string][]protocol.FileSystemWatcher
+	registrationMu    sync.RWMutex
 }
 
 // NewWorkspaceWatcher creates a new workspace watcher with default configuration
@@ -43,10 +62,12 @@ func NewWorkspaceWatcher(client LSPClient) *WorkspaceWatcher {
 // NewWorkspaceWatcherWithConfig creates a new workspace watcher with custom configuration
 func NewWorkspaceWatcherWithConfig(client LSPClient, config *WatcherConfig) *WorkspaceWatcher {
 	return &WorkspaceWatcher{
-		client:        client,
-		config:        config,
-		debounceMap:   make(map[string]*time.Timer),
-		registrations: []protocol.FileSystemWatcher{},
+		client:            client,
+		config:            config,
+		gitignores:        make(map[string]*GitignoreMatcher),
+		pending:           make(map[string]protocol.FileChangeType),
+		registrations:     []protocol.FileSystemWatcher{},
+		registrationsByID: make(map[string][]protocol.FileSystemWatcher),
 	}
 }
 
@@ -55,8 +76,10 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 	w.registrationMu.Lock()
 	defer w.registrationMu.Unlock()
 
-	// Add new watchers
-	w.registrations = append(w.registrations, watchers...)
+	// Add new watchers. A restarted server registers again under the same id, which
+	// replaces its earlier watchers instead of duplicating them.
+	w.registrationsByID[id] = watchers
+	w.flattenRegistrations()
 
 	// Log registration information
 	watcherLogger.Info("Added %d file watcher registrations (id: %s), total: %d",
@@ -113,11 +136,45 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 
 	// Find and open all existing files that match the newly registered patterns
 	// TODO: not all language servers require this, but typescript does. Make this configurable
-	go func() {
-		startTime := time.Now()
-		filesOpened := 0
+	go w.openMatchingFiles(ctx, w.roots()...)
+}
+
+// RemoveRegistrations removes the file watchers the server registered under id
+func (w *WorkspaceWatcher) RemoveRegistrations(id string) {
+	w.registrationMu.Lock()
+	defer w.registrationMu.Unlock()
+
+	if _, exists := w.registrationsByID[id]; !exists {
+		return
+	}
+	delete(w.registrationsByID, id)
+	w.flattenRegistrations()
+
+	watcherLogger.Info("Removed file watcher registrations (id: %s), total: %d", id, len(w.registrations))
+}
 
-		err := filepath.WalkDir(w.workspacePath, func(path string, d os.DirEntry, err error) error {
+// flattenRegistrations rebuilds registrations from registrationsByID in a stable
+// order. The caller must hold registrationMu.
+func (w *WorkspaceWatcher) flattenRegistrations() {
+	ids := make([]string, 0, len(w.registrationsByID))
+	for registeredID := range w.registrationsByID {
+		ids = append(ids, registeredID)
+	}
+	sort.Strings(ids)
+	w.registrations = w.registrations[:0]
+	for _, registeredID := range ids {
+		w.registrations = append(w.registrations, w.registrationsByID[registeredID]...)
+	}
+}
+
+// openMatchingFiles opens every file under the given workspace roots that matches
+// a registered file watcher pattern
+func (w *WorkspaceWatcher) openMatchingFiles(ctx context.Context, workspacePaths ...string) {
+	startTime := time.Now()
+	filesOpened := 0
+
+	for _, workspacePath := range workspacePaths {
+		err := filepath.WalkDir(workspacePath, func(path string, d os.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
@@ -125,7 +182,7 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 			// Skip directories that should be excluded
 			if d.IsDir() {
 				watcherLogger.Debug("Processing directory: %s", path)
-				if path != w.workspacePath && w.shouldExcludeDir(path) {
+				if path != workspacePath && w.shouldExcludeDir(path) {
 					watcherLogger.Debug("Skipping excluded directory: %s", path)
 					return filepath.SkipDir
 				}
@@ -142,36 +199,37 @@ func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watc
 
 			return nil
 		})
-
-		elapsedTime := time.Since(startTime)
-		watcherLogger.Info("Workspace scan complete: processed %d files in %.2f seconds",
-			filesOpened, elapsedTime.Seconds())
-
 		if err != nil {
-			watcherLogger.Error("Error scanning workspace for files to open: %v", err)
+			watcherLogger.Error("Error scanning workspace %s for files to open: %v", workspacePath, err)
 		}
-	}()
-}
+	}
 
-// WatchWorkspace sets up file watching for a workspace
-func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath string) {
-	w.workspacePath = workspacePath
+	elapsedTime := time.Since(startTime)
+	watcherLogger.Info("Workspace scan complete: processed %d files in %.2f seconds",
+		filesOpened, elapsedTime.Seconds())
+}
 
-	// Initialize gitignore matcher
-	gitignore, err := NewGitignoreMatcher(workspacePath)
-	if err != nil {
-		watcherLogger.Error("Error initializing gitignore matcher: %v", err)
-	} else {
-		w.gitignore = gitignore
-		watcherLogger.Info("Initialized gitignore matcher for %s", workspacePath)
+// WatchWorkspace sets up file watching for one or more workspace roots
+func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePaths ...string) {
+	for _, workspacePath := range workspacePaths {
+		w.addRoot(workspacePath)
 	}
 
 	// Register handler for file watcher registrations from the server
-	lsp.RegisterFileWatchHandler(func(id string, watchers []protocol.FileSystemWatcher) {
+	w.client.SetFileWatchHandlers(func(id string, watchers []protocol.FileSystemWatcher) {
 		w.AddRegistrations(ctx, id, watchers)
-	})
+	}, w.RemoveRegistrations)
+
+	// Watch configured patterns for servers that do not register their own
+	if len(w.config.FilePatterns) > 0 {
+		watchers := make([]protocol.FileSystemWatcher, len(w.config.FilePatterns))
+		for i, pattern := range w.config.FilePatterns {
+			watchers[i] = protocol.FileSystemWatcher{GlobPattern: protocol.GlobPattern{Value: pattern}}
+		}
+		w.AddRegistrations(ctx, "config", watchers)
+	}
 
-	watcher, err := fsnotify.NewWatcher()
+	watcher, err := newEventSource(w.config, workspacePaths)
 	if err != nil {
 		watcherLogger.Fatal("Error creating watcher: %v", err)
 	}
@@ -181,46 +239,41 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 		}
 	}()
 
-	// Watch the workspace recursively
-	err = filepath.WalkDir(workspacePath, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip excluded directories (except workspace root)
-		if d.IsDir() && path != workspacePath {
-			if w.shouldExcludeDir(path) {
-				watcherLogger.Debug("Skipping watching excluded directory: %s", path)
-				return filepath.SkipDir
-			}
-		}
-
-		// Add directories to watcher
-		if d.IsDir() {
-			err = watcher.Add(path)
-			if err != nil {
-				watcherLogger.Error("Error watching path %s: %v", path, err)
-			}
+	for _, workspacePath := range workspacePaths {
+		if err := w.watchRoot(watcher, workspacePath); err != nil {
+			watcherLogger.Fatal("Error walking workspace: %v", err)
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		watcherLogger.Fatal("Error walking workspace: %v", err)
 	}
 
+	// Allow roots to be added and removed while the event loop runs
+	w.rootsMu.Lock()
+	w.fsWatcher = watcher
+	w.rootsMu.Unlock()
+	defer func() {
+		w.rootsMu.Lock()
+		w.fsWatcher = nil
+		w.rootsMu.Unlock()
+	}()
+
 	// Event loop
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case event, ok := <-watcher.Events:
+		case event, ok := <-watcher.Events():
 			if !ok {
 				return
 			}
 
-			uri := fmt.Sprintf("file://%s", event.Name)
+			uri := string(fileuri.FromPath(event.Name))
+
+			// Pick up edited ignore patterns
+			if IsGitignoreFile(event.Name) {
+				if gitignore := w.gitignoreFor(event.Name); gitignore != nil {
+					gitignore.Reload(event.Name)
+					watcherLogger.Info("Reloaded gitignore patterns from %s", event.Name)
+				}
+			}
 
 			// Check if this is a file (not a directory) and should be excluded
 			isFile := false
@@ -248,7 +301,7 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 					if info.IsDir() {
 						// Skip excluded directories
 						if !w.shouldExcludeDir(event.Name) {
-							if err := watcher.Add(event.Name); err != nil {
+							if err := w.addWatch(watcher, event.Name); err != nil {
 								watcherLogger.Error("Error watching new directory: %v", err)
 							}
 						}
@@ -273,39 +326,45 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 				continue
 			}
 
+			// Keep open documents in sync even if the server does not watch them
+			if event.Op&fsnotify.Write != 0 && w.client.IsFileOpen(event.Name) {
+				w.queueFileEvent(ctx, uri, protocol.FileChangeType(protocol.Changed))
+				continue
+			}
+
 			// Check if this path should be watched according to server registrations
 			if watched, watchKind := w.isPathWatched(event.Name); watched {
 				switch {
 				case event.Op&fsnotify.Write != 0:
 					if watchKind&protocol.WatchChange != 0 {
-						w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Changed))
+						w.queueFileEvent(ctx, uri, protocol.FileChangeType(protocol.Changed))
 					}
 				case event.Op&fsnotify.Create != 0:
 					// Already handled earlier in the event loop
 					// Just send the notification if needed
 					info, _ := os.Stat(event.Name)
 					if info != nil && !info.IsDir() && watchKind&protocol.WatchCreate != 0 {
-						w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
+						w.queueFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
 					}
 				case event.Op&fsnotify.Remove != 0:
 					if watchKind&protocol.WatchDelete != 0 {
-						w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
+						w.queueFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
 					}
 				case event.Op&fsnotify.Rename != 0:
 					// For renames, first delete
 					if watchKind&protocol.WatchDelete != 0 {
-						w.handleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
+						w.queueFileEvent(ctx, uri, protocol.FileChangeType(protocol.Deleted))
 					}
 
 					// Then check if the new file exists and create an event
 					if info, err := os.Stat(event.Name); err == nil && !info.IsDir() {
 						if watchKind&protocol.WatchCreate != 0 {
-							w.debounceHandleFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
+							w.queueFileEvent(ctx, uri, protocol.FileChangeType(protocol.Created))
 						}
 					}
 				}
 			}
-		case err, ok := <-watcher.Errors:
+		case err, ok := <-watcher.Errors():
 			if !ok {
 				return
 			}
@@ -314,131 +373,243 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 	}
 }
 
-// isPathWatched checks if a path should be watched based on server registrations
-func (w *WorkspaceWatcher) isPathWatched(path string) (bool, protocol.WatchKind) {
-	w.registrationMu.RLock()
-	defer w.registrationMu.RUnlock()
+// addRoot records a workspace root and initializes its gitignore matcher
+func (w *WorkspaceWatcher) addRoot(workspacePath string) {
+	gitignore, err := NewGitignoreMatcher(workspacePath)
+	if err != nil {
+		watcherLogger.Error("Error initializing gitignore matcher: %v", err)
+	}
 
-	// If no explicit registrations, watch everything
-	if len(w.registrations) == 0 {
-		return true, protocol.WatchKind(protocol.WatchChange | protocol.WatchCreate | protocol.WatchDelete)
+	w.rootsMu.Lock()
+	w.workspacePaths = append(w.workspacePaths, workspacePath)
+	if gitignore != nil {
+		w.gitignores[workspacePath] = gitignore
+		watcherLogger.Info("Initialized gitignore matcher for %s", workspacePath)
 	}
+	w.rootsMu.Unlock()
+}
 
-	// Check each registration
-	for _, reg := range w.registrations {
-		isMatch := w.matchesPattern(path, reg.GlobPattern)
-		if isMatch {
-			kind := protocol.WatchKind(protocol.WatchChange | protocol.WatchCreate | protocol.WatchDelete)
-			if reg.Kind != nil {
-				kind = *reg.Kind
+// watchRoot adds a workspace root and its subdirectories to the watcher
+func (w *WorkspaceWatcher) watchRoot(watcher eventSource, workspacePath string) error {
+	return filepath.WalkDir(workspacePath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip excluded directories (except workspace root)
+		if d.IsDir() && path != workspacePath {
+			if w.shouldExcludeDir(path) {
+				watcherLogger.Debug("Skipping watching excluded directory: %s", path)
+				return filepath.SkipDir
+			}
+		}
+
+		// Add directories to watcher
+		if d.IsDir() {
+			err = w.addWatch(watcher, path)
+			if err == errWatchLimit {
+				return filepath.SkipAll
+			} else if err != nil {
+				watcherLogger.Error("Error watching path %s: %v", path, err)
 			}
-			return true, kind
 		}
-	}
 
-	return false, 0
+		return nil
+	})
 }
 
-// matchesGlob handles advanced glob patterns including ** and alternatives
-func matchesGlob(pattern, path string) bool {
-	// Handle file extension patterns with braces like *.{go,mod,sum}
-	if strings.Contains(pattern, "{") && strings.Contains(pattern, "}") {
-		// Extract extensions from pattern like "*.{go,mod,sum}"
-		parts := strings.SplitN(pattern, "{", 2)
-		if len(parts) == 2 {
-			prefix := parts[0]
-			extPart := strings.SplitN(parts[1], "}", 2)
-			if len(extPart) == 2 {
-				extensions := strings.Split(extPart[0], ",")
-				suffix := extPart[1]
-
-				// Check if the path matches any of the extensions
-				for _, ext := range extensions {
-					extPattern := prefix + ext + suffix
-					isMatch := matchesSimpleGlob(extPattern, path)
-					if isMatch {
-						return true
-					}
-				}
-				return false
-			}
+// errWatchLimit is returned by addWatch once MaxWatchedDirs directories are watched
+var errWatchLimit = errors.New("watched directory limit reached")
+
+// addWatch watches a directory unless the configured limit has been reached
+func (w *WorkspaceWatcher) addWatch(watcher eventSource, path string) error {
+	if limit := int64(w.config.MaxWatchedDirs); limit > 0 && w.watchedDirs.Load() >= limit {
+		// Directories that were deleted are no longer watched, so recount
+		w.watchedDirs.Store(int64(len(watcher.WatchList())))
+		if w.watchedDirs.Load() >= limit {
+			w.limitWarning.Do(func() {
+				watcherLogger.Warn("Watching the maximum of %d directories, further directories will not be watched. Exclude directories with watcher exclude patterns or raise the limit.", limit)
+			})
+			return errWatchLimit
 		}
 	}
 
-	return matchesSimpleGlob(pattern, path)
+	if err := watcher.Add(path); err != nil {
+		return err
+	}
+	w.watchedDirs.Add(1)
+	return nil
 }
 
-// matchesSimpleGlob handles glob patterns with ** wildcards
-func matchesSimpleGlob(pattern, path string) bool {
-	// Handle special case for **/*.ext pattern (common in LSP)
-	if strings.HasPrefix(pattern, "**/") {
-		rest := strings.TrimPrefix(pattern, "**/")
-
-		// If the rest is a simple file extension pattern like *.go
-		if strings.HasPrefix(rest, "*.") {
-			ext := strings.TrimPrefix(rest, "*")
-			isMatch := strings.HasSuffix(path, ext)
-			return isMatch
+// AddRoot starts watching another workspace root while WatchWorkspace is running and
+// opens its files that match the registered file watchers
+func (w *WorkspaceWatcher) AddRoot(ctx context.Context, workspacePath string) error {
+	w.rootsMu.RLock()
+	watcher := w.fsWatcher
+	w.rootsMu.RUnlock()
+	if watcher == nil {
+		return fmt.Errorf("workspace watcher is not running")
+	}
+
+	for _, root := range w.roots() {
+		if root == workspacePath {
+			return nil
 		}
+	}
+
+	w.addRoot(workspacePath)
+	if err := w.watchRoot(watcher, workspacePath); err != nil {
+		w.RemoveRoot(workspacePath)
+		return fmt.Errorf("error walking workspace: %w", err)
+	}
 
-		// Otherwise, try to check if the path ends with the rest part
-		isMatch := strings.HasSuffix(path, rest)
+	go w.openMatchingFiles(ctx, workspacePath)
+	return nil
+}
 
-		// If it matches directly, great!
-		if isMatch {
-			return true
+// RemoveRoot stops watching a workspace root. Directories that also belong to
+// another root stay watched.
+func (w *WorkspaceWatcher) RemoveRoot(workspacePath string) {
+	w.rootsMu.Lock()
+	var remaining []string
+	for _, root := range w.workspacePaths {
+		if root != workspacePath {
+			remaining = append(remaining, root)
 		}
+	}
+	w.workspacePaths = remaining
+	delete(w.gitignores, workspacePath)
+	watcher := w.fsWatcher
+	w.rootsMu.Unlock()
 
-		// Otherwise, check if any path component matches
-		pathComponents := strings.Split(path, "/")
-		for i := range pathComponents {
-			subPath := strings.Join(pathComponents[i:], "/")
-			if strings.HasSuffix(subPath, rest) {
-				return true
+	if watcher == nil {
+		return
+	}
+
+	for _, path := range watcher.WatchList() {
+		if !isWithin(path, workspacePath) {
+			continue
+		}
+		stillWatched := false
+		for _, root := range remaining {
+			if isWithin(path, root) {
+				stillWatched = true
+				break
 			}
 		}
-
-		return false
+		if stillWatched {
+			continue
+		}
+		if err := watcher.Remove(path); err != nil {
+			watcherLogger.Debug("Error removing watch for %s: %v", path, err)
+			continue
+		}
+		w.watchedDirs.Add(-1)
 	}
+	watcherLogger.Info("Stopped watching workspace %s", workspacePath)
+}
+
+// roots returns a copy of the watched workspace roots
+func (w *WorkspaceWatcher) roots() []string {
+	w.rootsMu.RLock()
+	defer w.rootsMu.RUnlock()
+	return append([]string(nil), w.workspacePaths...)
+}
 
-	// Handle other ** wildcard pattern cases
-	if strings.Contains(pattern, "**") {
-		parts := strings.Split(pattern, "**")
+// gitignoreFor returns the gitignore matcher of the workspace root containing path
+func (w *WorkspaceWatcher) gitignoreFor(path string) *GitignoreMatcher {
+	w.rootsMu.RLock()
+	defer w.rootsMu.RUnlock()
 
-		// Validate the path starts with the first part
-		if !strings.HasPrefix(path, parts[0]) && parts[0] != "" {
-			return false
+	// Prefer the most specific root when roots are nested
+	var best string
+	for _, root := range w.workspacePaths {
+		if isWithin(path, root) && len(root) > len(best) {
+			best = root
 		}
+	}
+	return w.gitignores[best]
+}
+
+// matchesConfigPattern reports whether a path, relative to its workspace root,
+// matches one of the user's glob patterns. A directory also matches patterns for
+// everything inside it, such as "docs/**", since ** matches zero segments.
+func (w *WorkspaceWatcher) matchesConfigPattern(patterns []string, path string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
 
-		// For patterns like "**/*.go", just check the suffix
-		if len(parts) == 2 && parts[0] == "" {
-			isMatch := strings.HasSuffix(path, parts[1])
-			return isMatch
+	w.rootsMu.RLock()
+	var root string
+	for _, r := range w.workspacePaths {
+		if isWithin(path, r) && len(r) > len(root) {
+			root = r
 		}
+	}
+	w.rootsMu.RUnlock()
 
-		// For other patterns, handle middle part
-		remaining := strings.TrimPrefix(path, parts[0])
-		if len(parts) == 2 {
-			isMatch := strings.HasSuffix(remaining, parts[1])
-			return isMatch
+	rel := path
+	if root != "" {
+		if r, err := filepath.Rel(root, path); err == nil {
+			rel = filepath.ToSlash(r)
 		}
 	}
 
-	// Handle simple * wildcard for file extension patterns (*.go, *.sum, etc)
-	if strings.HasPrefix(pattern, "*.") {
-		ext := strings.TrimPrefix(pattern, "*")
-		isMatch := strings.HasSuffix(path, ext)
-		return isMatch
+	for _, pattern := range patterns {
+		if utilities.MatchGlob(pattern, rel) {
+			return true
+		}
 	}
+	return false
+}
 
-	// Fall back to simple matching for simpler patterns
-	matched, err := filepath.Match(pattern, path)
-	if err != nil {
-		watcherLogger.Error("Error matching pattern %s: %v", pattern, err)
-		return false
+// isWithin reports whether path is root or inside it
+func isWithin(path, root string) bool {
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// isPathWatched checks if a path is covered by the file watchers registered by
+// the server, returning the combined kinds of events of every matching watcher
+func (w *WorkspaceWatcher) isPathWatched(path string) (bool, protocol.WatchKind) {
+	w.registrationMu.RLock()
+	defer w.registrationMu.RUnlock()
+
+	var kind protocol.WatchKind
+	matched := false
+	for _, reg := range w.registrations {
+		if !w.matchesPattern(path, reg.GlobPattern) {
+			continue
+		}
+		matched = true
+		if reg.Kind != nil {
+			kind |= *reg.Kind
+		} else {
+			kind |= protocol.WatchKind(protocol.WatchChange | protocol.WatchCreate | protocol.WatchDelete)
+		}
 	}
 
-	return matched
+	return matched, kind
+}
+
+// isWatchedByName reports whether a server registration names the file exactly
+// in its last path segment, rather than matching it with a wildcard
+func (w *WorkspaceWatcher) isWatchedByName(path string) bool {
+	w.registrationMu.RLock()
+	defer w.registrationMu.RUnlock()
+
+	fileName := filepath.Base(path)
+	for _, reg := range w.registrations {
+		patternInfo, err := reg.GlobPattern.AsPattern()
+		if err != nil {
+			continue
+		}
+		for _, alternative := range utilities.ExpandBraces(patternInfo.GetPattern()) {
+			if alternative[strings.LastIndex(alternative, "/")+1:] == fileName && w.matchesPattern(path, reg.GlobPattern) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // matchesPattern checks if a path matches the glob pattern
@@ -452,112 +623,145 @@ func (w *WorkspaceWatcher) matchesPattern(path string, pattern protocol.GlobPatt
 	basePath := patternInfo.GetBasePath()
 	patternText := patternInfo.GetPattern()
 
-	// watcherLogger.Debug("Matching path %s against pattern %s (base: %s)", path, patternText, basePath)
-
 	path = filepath.ToSlash(path)
 
-	// Special handling for wildcard patterns like "**/*"
-	if patternText == "**/*" {
-		// This should match any file
-		// watcherLogger.Debug("Using special matching for **/* pattern")
-		return true
-	}
-
-	// Special handling for wildcard patterns like "**/*.ext"
-	if strings.HasPrefix(patternText, "**/") {
-		if strings.HasPrefix(strings.TrimPrefix(patternText, "**/"), "*.") {
-			// Extension pattern like **/*.go
-			ext := strings.TrimPrefix(strings.TrimPrefix(patternText, "**/"), "*")
-			// watcherLogger.Debug("Using extension matching for **/*.ext pattern: checking if %s ends with %s", path, ext)
-			return strings.HasSuffix(path, ext)
-		} else {
-			// Any other pattern starting with **/ should match any path
-			// watcherLogger.Debug("Using path substring matching for **/ pattern")
-			return true
-		}
-	}
-
 	// For simple patterns without base path
 	if basePath == "" {
-		// Check if the pattern matches the full path or just the file extension
-		fullPathMatch := matchesGlob(patternText, path)
-		baseNameMatch := matchesGlob(patternText, filepath.Base(path))
-
-		watcherLogger.Debug("No base path, fullPathMatch: %v, baseNameMatch: %v", fullPathMatch, baseNameMatch)
-		return fullPathMatch || baseNameMatch
+		// Check if the pattern matches the full path or just the file name
+		return utilities.MatchGlob(patternText, path) || utilities.MatchGlob(patternText, filepath.Base(path))
 	}
 
-	// For relative patterns
-	basePath = strings.TrimPrefix(basePath, "file://")
-	basePath = filepath.ToSlash(basePath)
-
-	// Make path relative to basePath for matching
-	relPath, err := filepath.Rel(basePath, path)
+	// For relative patterns, make path relative to basePath for matching
+	relPath, err := filepath.Rel(filepath.FromSlash(basePath), filepath.FromSlash(path))
 	if err != nil {
 		watcherLogger.Error("Error getting relative path for %s: %v", path, err)
 		return false
 	}
 	relPath = filepath.ToSlash(relPath)
+	if relPath == ".." || strings.HasPrefix(relPath, "../") {
+		// Outside the pattern's base directory
+		return false
+	}
 
-	isMatch := matchesGlob(patternText, relPath)
+	isMatch := utilities.MatchGlob(patternText, relPath)
 	watcherLogger.Debug("Relative path matching: %s against %s = %v", relPath, patternText, isMatch)
 
 	return isMatch
 }
 
-// debounceHandleFileEvent handles file events with debouncing to reduce notifications
-func (w *WorkspaceWatcher) debounceHandleFileEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) {
-	w.debounceMu.Lock()
-	defer w.debounceMu.Unlock()
+// maxBatchDelay bounds how many debounce windows a batch can be extended by
+// events that keep arriving, so a long running build still sends updates
+const maxBatchDelay = 10
+
+// queueFileEvent adds a file event to the pending batch. The batch is sent once
+// no new events have arrived for the debounce time.
+func (w *WorkspaceWatcher) queueFileEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) {
+	if int(changeType) < len(w.eventCounts) {
+		w.eventCounts[changeType].Add(1)
+	}
 
-	// Create a unique key based on URI and change type
-	key := fmt.Sprintf("%s:%d", uri, changeType)
+	w.batchMu.Lock()
+	defer w.batchMu.Unlock()
 
-	// Cancel existing timer if any
-	if timer, exists := w.debounceMap[key]; exists {
-		timer.Stop()
+	previous, exists := w.pending[uri]
+	if !exists {
+		w.pendingOrder = append(w.pendingOrder, uri)
 	}
+	w.pending[uri] = coalesceFileEvents(previous, changeType, exists)
 
-	// Create new timer
-	w.debounceMap[key] = time.AfterFunc(w.config.DebounceTime, func() {
-		w.handleFileEvent(ctx, uri, changeType)
+	if w.batchTimer == nil {
+		w.batchStart = time.Now()
+		w.batchTimer = time.AfterFunc(w.config.DebounceTime, func() {
+			w.flushFileEvents(ctx)
+		})
+	} else if time.Since(w.batchStart) < maxBatchDelay*w.config.DebounceTime {
+		w.batchTimer.Reset(w.config.DebounceTime)
+	}
+}
 
-		// Cleanup timer after execution
-		w.debounceMu.Lock()
-		delete(w.debounceMap, key)
-		w.debounceMu.Unlock()
-	})
+// EventCounts returns how many file events of each change type have been queued
+// for the server, before events for the same file are combined
+func (w *WorkspaceWatcher) EventCounts() map[protocol.FileChangeType]int64 {
+	counts := make(map[protocol.FileChangeType]int64)
+	for _, changeType := range []protocol.FileChangeType{protocol.Created, protocol.Changed, protocol.Deleted} {
+		counts[changeType] = w.eventCounts[changeType].Load()
+	}
+	return counts
 }
 
-// handleFileEvent sends file change notifications
-func (w *WorkspaceWatcher) handleFileEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) {
-	// If the file is open and it's a change event, use didChange notification
-	filePath := uri[7:] // Remove "file://" prefix
-	if changeType == protocol.FileChangeType(protocol.Changed) && w.client.IsFileOpen(filePath) {
-		err := w.client.NotifyChange(ctx, filePath)
-		if err != nil {
-			watcherLogger.Error("Error notifying change: %v", err)
+// coalesceFileEvents combines two events for the same file into the one the
+// server should see, or 0 when they cancel out
+func coalesceFileEvents(previous, next protocol.FileChangeType, exists bool) protocol.FileChangeType {
+	if !exists || previous == 0 {
+		return next
+	}
+	switch {
+	case previous == protocol.Created && next == protocol.Changed:
+		// Still a new file as far as the server knows
+		return protocol.Created
+	case previous == protocol.Created && next == protocol.Deleted:
+		// The server never saw the file
+		return 0
+	case previous == protocol.Deleted && next == protocol.Created:
+		// Replaced, for example by an editor's atomic save
+		return protocol.Changed
+	}
+	return next
+}
+
+// flushFileEvents sends the pending batch. Changes to open files are sent as
+// didChange notifications, everything else in one didChangeWatchedFiles.
+func (w *WorkspaceWatcher) flushFileEvents(ctx context.Context) {
+	w.batchMu.Lock()
+	pending, order := w.pending, w.pendingOrder
+	w.pending = make(map[string]protocol.FileChangeType)
+	w.pendingOrder = nil
+	w.batchTimer = nil
+	w.batchMu.Unlock()
+
+	var changes []protocol.FileEvent
+	for _, uri := range order {
+		changeType := pending[uri]
+		if changeType == 0 {
+			continue
+		}
+
+		// If the file is open and it's a change event, use didChange notification.
+		// The change was written to disk, so it is also a save.
+		filePath := fileuri.ToPath(uri)
+		if changeType == protocol.Changed && w.client.IsFileOpen(filePath) {
+			if err := w.client.NotifyChange(ctx, filePath); err != nil {
+				watcherLogger.Error("Error notifying change: %v", err)
+				continue
+			}
+			if err := w.client.NotifySave(ctx, filePath); err != nil {
+				watcherLogger.Error("Error notifying save: %v", err)
+			}
+			continue
 		}
+
+		changes = append(changes, protocol.FileEvent{
+			URI:  protocol.DocumentUri(uri),
+			Type: changeType,
+		})
+	}
+
+	if len(changes) == 0 {
 		return
 	}
 
-	// Notify LSP server about the file event using didChangeWatchedFiles
-	if err := w.notifyFileEvent(ctx, uri, changeType); err != nil {
-		watcherLogger.Error("Error notifying LSP server about file event: %v", err)
+	// Notify LSP server about the file events using didChangeWatchedFiles
+	if err := w.notifyFileEvents(ctx, changes); err != nil {
+		watcherLogger.Error("Error notifying LSP server about file events: %v", err)
 	}
 }
 
-// notifyFileEvent sends a didChangeWatchedFiles notification for a file event
-func (w *WorkspaceWatcher) notifyFileEvent(ctx context.Context, uri string, changeType protocol.FileChangeType) error {
-	watcherLogger.Debug("Notifying file event: %s (type: %d)", uri, changeType)
+// notifyFileEvents sends a didChangeWatchedFiles notification for a batch of file events
+func (w *WorkspaceWatcher) notifyFileEvents(ctx context.Context, changes []protocol.FileEvent) error {
+	watcherLogger.Debug("Notifying %d file events", len(changes))
 
 	params := protocol.DidChangeWatchedFilesParams{
-		Changes: []protocol.FileEvent{
-			{
-				URI:  protocol.DocumentUri(uri),
-				Type: changeType,
-			},
-		},
+		Changes: changes,
 	}
 
 	return w.client.DidChangeWatchedFiles(ctx, params)
@@ -577,8 +781,14 @@ func (w *WorkspaceWatcher) shouldExcludeDir(dirPath string) bool {
 		return true
 	}
 
+	// Check configured exclude patterns
+	if w.matchesConfigPattern(w.config.ExcludePatterns, dirPath) {
+		watcherLogger.Debug("Directory %s excluded by exclude pattern", dirPath)
+		return true
+	}
+
 	// Check gitignore patterns
-	if w.gitignore != nil && w.gitignore.ShouldIgnore(dirPath, true) {
+	if gitignore := w.gitignoreFor(dirPath); gitignore != nil && gitignore.ShouldIgnore(dirPath, true) {
 		watcherLogger.Debug("Directory %s excluded by gitignore pattern", dirPath)
 		return true
 	}
@@ -590,14 +800,18 @@ func (w *WorkspaceWatcher) shouldExcludeDir(dirPath string) bool {
 func (w *WorkspaceWatcher) shouldExcludeFile(filePath string) bool {
 	fileName := filepath.Base(filePath)
 
+	// Files the server watches by name, such as Cargo.lock or .classpath, are
+	// kept even though lock files and dot files are skipped otherwise
+	watchedByName := w.isWatchedByName(filePath)
+
 	// Skip dot files
-	if strings.HasPrefix(fileName, ".") {
+	if strings.HasPrefix(fileName, ".") && !watchedByName {
 		return true
 	}
 
 	// Check file extension
 	ext := strings.ToLower(filepath.Ext(filePath))
-	if w.config.ExcludedFileExtensions[ext] || w.config.LargeBinaryExtensions[ext] {
+	if (w.config.ExcludedFileExtensions[ext] || w.config.LargeBinaryExtensions[ext]) && !watchedByName {
 		return true
 	}
 
@@ -606,8 +820,18 @@ func (w *WorkspaceWatcher) shouldExcludeFile(filePath string) bool {
 		return true
 	}
 
+	// Check configured include and exclude patterns
+	if w.matchesConfigPattern(w.config.ExcludePatterns, filePath) {
+		watcherLogger.Debug("File %s excluded by exclude pattern", filePath)
+		return true
+	}
+	if len(w.config.IncludePatterns) > 0 && !w.matchesConfigPattern(w.config.IncludePatterns, filePath) {
+		watcherLogger.Debug("File %s does not match any include pattern", filePath)
+		return true
+	}
+
 	// Check gitignore patterns
-	if w.gitignore != nil && w.gitignore.ShouldIgnore(filePath, false) {
+	if gitignore := w.gitignoreFor(filePath); gitignore != nil && gitignore.ShouldIgnore(filePath, false) {
 		watcherLogger.Debug("File %s excluded by gitignore pattern", filePath)
 		return true
 	}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// activityMonitor tracks MCP requests and client sessions, to shut down an idle
+// server and to release language server resources when every client is gone
+type activityMonitor struct {
+	// lastRequest is the time of the last MCP request in Unix nanoseconds
+	lastRequest atomic.Int64
+	sessions    atomic.Int64
+	// idle is closed when the idle timeout passes
+	idle chan struct{}
+}
+
+func newActivityMonitor() *activityMonitor {
+	m := &activityMonitor{idle: make(chan struct{})}
+	m.touch()
+	return m
+}
+
+func (m *activityMonitor) touch() {
+	m.lastRequest.Store(time.Now().UnixNano())
+}
+
+// hooks adds the hooks that record activity to hooks. lastDisconnect is called
+// when the last client session ends.
+func (m *activityMonitor) hooks(hooks *server.Hooks, lastDisconnect func()) *server.Hooks {
+	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		m.touch()
+	})
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		m.sessions.Add(1)
+		m.touch()
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		if m.sessions.Add(-1) == 0 && lastDisconnect != nil {
+			go lastDisconnect()
+		}
+	})
+	return hooks
+}
+
+// watchIdle closes idle once there has been no request for timeout, or returns
+// when done is closed
+func (m *activityMonitor) watchIdle(timeout time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(min(timeout/10, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, m.lastRequest.Load())) >= timeout {
+				close(m.idle)
+				return
+			}
+		}
+	}
+}
+
+// releaseFiles closes the files open in the language server when the last client
+// of the sse transport disconnects, unless --keep-warm is set. Files with staged
+// edits are kept, since closing them would drop the edits.
+func (s *mcpServer) releaseFiles() {
+	if s.config.transport != "sse" || s.config.keepWarm || s.lspClient == nil {
+		return
+	}
+
+	closed := 0
+	for _, doc := range s.lspClient.OpenDocuments() {
+		if doc.Staged {
+			continue
+		}
+		if err := s.lspClient.CloseFile(s.ctx, doc.Path); err != nil {
+			coreLogger.Warn("Failed to close %s: %v", doc.Path, err)
+			continue
+		}
+		closed++
+	}
+	coreLogger.Info("Last client disconnected, closed %d files in the language server", closed)
+}
@@ -3,19 +3,29 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/codeindex"
+	conf "github.com/isaacphi/mcp-language-server/internal/config"
 	"github.com/isaacphi/mcp-language-server/internal/logging"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
 	"github.com/isaacphi/mcp-language-server/internal/watcher"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
@@ -23,11 +33,134 @@ import (
 var coreLogger = logging.NewLogger(logging.Core)
 
 type config struct {
-	workspaceDir string
-	lspCommand   string
-	lspArgs      []string
-	configFile   string
-	lspConfig    map[string]any
+	workspaceDirs []string
+	lspCommand    string
+	lspArgs       []string
+	lspAddress    string
+	serverName    string
+	configFile    string
+	lspConfig     map[string]any
+	settings      map[string]any
+	readiness     lsp.ReadinessProbe
+	preload       lsp.PreloadPolicy
+	saveActions   lsp.SaveActions
+	preset        string
+	tools         toolAccess
+	watch         watchOptions
+	filePatterns  []string
+	languageIDs   map[string]protocol.LanguageKind
+	transport     string
+	listenAddr    string
+	// maxResponseBytes is the size tool results are truncated to, 0 for the
+	// default and negative for no limit
+	maxResponseBytes int
+	// trace is where LSP messages are traced to: stderr, a file path, or empty
+	// for no tracing
+	trace             string
+	tracePayloadBytes int
+	logging           logOptions
+	// serverMessages is the least severe language server message forwarded to
+	// MCP clients, 0 to forward none
+	serverMessages protocol.MessageType
+	// metricsAddr is where Prometheus metrics are served, empty for none
+	metricsAddr string
+	// maxOpenFiles caps the files open in the language server, 0 for no limit
+	maxOpenFiles int
+	// idleTimeout shuts the server down after this many minutes without MCP
+	// requests, 0 to never
+	idleTimeout int
+	// lspKeepalive is the seconds between pings to the language server, 0 for none
+	lspKeepalive int
+	// keepWarm keeps the language server's open files when the last client of
+	// a network transport disconnects
+	keepWarm bool
+	// remote runs the language server over SSH when its host is set
+	remote lsp.RemoteWorkspace
+	// container runs the language server in a container when its image is set
+	container lsp.ContainerWorkspace
+	// symbolIndex keeps the symbols the server reports in the primary workspace,
+	// to answer symbol queries while the server starts
+	symbolIndex bool
+	// codeIndex is the path of a prebuilt LSIF or SCIP index that definition,
+	// references and hover queries are answered from
+	codeIndex string
+	// configWarnings are problems found in the config file that are not errors
+	configWarnings []string
+	// routes are the additional language servers of the config file, highest
+	// priority first
+	routes []serverRoute
+	// fallbackGlobs match the files read and edited without a language server
+	fallbackGlobs []string
+}
+
+// stringList is a flag that may be repeated, collecting every value
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// watchOptions limits what the workspace watcher watches and opens
+type watchOptions struct {
+	// Include limits watched files to those matching these globs
+	Include []string `json:"include"`
+	// Exclude skips files and directories matching these globs
+	Exclude []string `json:"exclude"`
+	// MaxDirs caps the number of watched directories, 0 for no limit
+	MaxDirs int `json:"maxDirs"`
+	// DebounceMs is how long to collect file events before notifying the server, 0 for the default
+	DebounceMs int `json:"debounceMs"`
+	// Backend is auto, native or poll
+	Backend string `json:"backend"`
+	// PollIntervalMs is how often the poll backend rescans, 0 for the default
+	PollIntervalMs int `json:"pollIntervalMs"`
+}
+
+// logOptions override the logging settings of the LOG_LEVEL, LOG_COMPONENT_LEVELS,
+// LOG_FORMAT and LOG_FILE environment variables
+type logOptions struct {
+	level      string
+	components string
+	format     string
+	file       string
+}
+
+// apply configures logging with the options that are set
+func (o logOptions) apply() error {
+	if o.level != "" {
+		level, err := logging.ParseLevel(o.level)
+		if err != nil {
+			return err
+		}
+		logging.SetGlobalLevel(level)
+	}
+	if o.components != "" {
+		levels, err := logging.ParseComponentLevels(o.components)
+		if err != nil {
+			return err
+		}
+		for component, level := range levels {
+			logging.SetLevel(component, level)
+		}
+	}
+	if o.format != "" {
+		format, err := logging.ParseFormat(o.format)
+		if err != nil {
+			return err
+		}
+		logging.SetFormat(format)
+	}
+	if o.file != "" {
+		if err := logging.SetupRotatingFileLogging(o.file, logging.DefaultFileMaxBytes, logging.DefaultFileBackups); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type mcpServer struct {
@@ -37,78 +170,326 @@ type mcpServer struct {
 	ctx              context.Context
 	cancelFunc       context.CancelFunc
 	workspaceWatcher *watcher.WorkspaceWatcher
+	// routes are the started additional language servers, highest priority first
+	routes []*route
+	// fallback reads and edits the files of the fallback globs, nil without any
+	fallback  *lsp.Client
+	activity  *activityMonitor
+	sseServer *server.SSEServer
+	progress  *progressBridge
+	responses *responseBudget
+	metrics   *serverMetrics
+	toolNames map[string]bool
 }
 
-func parseConfig() (*config, error) {
+// parseConfig parses the flags of a command, the MCP_LS_* environment variables
+// and the config file, in that order of precedence
+func parseConfig(args []string) (*config, error) {
 	cfg := &config{}
-	flag.StringVar(&cfg.workspaceDir, "workspace", "", "Path to workspace directory")
-	flag.StringVar(&cfg.lspCommand, "lsp", "", "LSP command to run (args should be passed after --)")
-	flag.StringVar(&cfg.configFile, "config", "", "Path to LSP configuration file (JSON)")
-	flag.Parse()
-
-	// Get remaining args after -- as LSP arguments
-	cfg.lspArgs = flag.Args()
+	set := conf.NewSet(flag.CommandLine)
+	set.PathListVar(&cfg.workspaceDirs, "workspaces", "workspace", "Path to workspace directory (may be repeated for multiple roots)")
+	set.StringVar(&cfg.lspCommand, "lsp", "lsp", "", "LSP command to run (args should be passed after --)")
+	set.ArgsVar(&cfg.lspArgs, "lspArgs")
+	set.StringVar(&cfg.lspAddress, "lspAddress", "lsp-address", "", "Address of an already running LSP server to connect to instead of starting one (host:port or unix:/path)")
+	set.StringVar(&cfg.serverName, "serverName", "server-name", "", "Name of the language server, used to select its built-in initializer and its config file entries (defaults to the --lsp binary name)")
+	set.StringVar(&cfg.preset, "preset", "preset", "", "Built-in configuration for a language server: python, rust, c, java, lua or ruby")
+	set.ConfigFileVar(&cfg.configFile, "config", "Path to LSP configuration file (JSON)")
+	set.BoolVar(&cfg.tools.ReadOnly, "tools.readOnly", "read-only", false, "Disable tools that edit files or run language server commands")
+	set.ListVar(&cfg.tools.Enable, "tools.enable", "enable-tools", "Comma separated list of the only tools to enable")
+	set.ListVar(&cfg.tools.Disable, "tools.disable", "disable-tools", "Comma separated list of tools to disable")
+	set.BoolVar(&cfg.tools.HideUnsupported, "tools.hideUnsupported", "hide-unsupported-tools", false, "Do not register tools the language server lacks the capability for, instead of having them explain that they are not supported")
+	set.BoolVar(&cfg.tools.AllowExternalFolders, "tools.allowExternalFolders", "allow-external-folders", false, "Let add_workspace_folder attach directories outside the configured workspace folders")
+	set.ListVar(&cfg.watch.Include, "watcher.include", "watch-include", "Comma separated globs, relative to the workspace, limiting which files are watched")
+	set.ListVar(&cfg.watch.Exclude, "watcher.exclude", "watch-exclude", "Comma separated globs, relative to the workspace, of files and directories not to watch")
+	set.IntVar(&cfg.watch.MaxDirs, "watcher.maxDirs", "max-watched-dirs", 0, "Maximum number of directories to watch, 0 for no limit").NonNegative()
+	set.IntVar(&cfg.watch.DebounceMs, "watcher.debounceMs", "watch-debounce-ms", 0, "Milliseconds to collect file events into one notification to the language server (default 300)").NonNegative()
+	set.StringVar(&cfg.watch.Backend, "watcher.backend", "watcher", "", "File watcher backend: native, poll for network file systems, or auto to poll when native watching is unavailable (default auto)").OneOf(watcher.BackendAuto, watcher.BackendNative, watcher.BackendPoll)
+	set.IntVar(&cfg.watch.PollIntervalMs, "watcher.pollIntervalMs", "poll-interval-ms", 0, "Milliseconds between scans with the poll watcher (default 2000)").NonNegative()
+	set.StringVar(&cfg.preload.Strategy, "preload.strategy", "preload", "", "Files to open at startup so first queries have results: none, globs for files matching the preset's file patterns, or gitRecent for files changed recently in git (default none)")
+	set.IntVar(&cfg.preload.MaxFiles, "preload.maxFiles", "preload-max-files", 0, "Maximum number of files to open at startup (default 50)")
+	set.IntVar(&cfg.maxOpenFiles, "maxOpenFiles", "max-open-files", 0, "Maximum number of files open in the language server, closing the least recently used past it, 0 for no limit").NonNegative()
+	set.IntVar(&cfg.idleTimeout, "idleTimeout", "idle-timeout", 0, "Minutes without MCP requests after which the server shuts down, 0 to never").NonNegative()
+	set.IntVar(&cfg.lspKeepalive, "lspKeepalive", "lsp-keepalive", 0, "Seconds between keep-alive pings to the language server, which is restarted when it does not answer, 0 for no pings").NonNegative()
+	set.BoolVar(&cfg.keepWarm, "keepWarm", "keep-warm", false, "With the sse transport, keep the files open in the language server when the last client disconnects, so reconnecting clients find it warm")
+	set.StringVar(&cfg.remote.Host, "ssh.host", "ssh", "", "Run the LSP command on this host over SSH, such as user@devbox, with paths translated between the local and remote workspace")
+	set.StringVar(&cfg.remote.Root, "ssh.root", "ssh-root", "", "Remote directory of the primary workspace when using --ssh (defaults to the same path as the local workspace)")
+	set.RepeatedVar(&cfg.remote.Options, "ssh.options", "ssh-option", "Option passed to ssh with -o, such as ConnectTimeout=10 (may be repeated)")
+	set.StringVar(&cfg.container.Image, "container.image", "container-image", "", "Run the LSP command in a container of this image, with the workspace mounted and paths translated")
+	set.StringVar(&cfg.container.Runtime, "container.runtime", "container-runtime", "", "Container CLI used with a container image: docker or podman (default docker)")
+	set.BoolVar(&cfg.symbolIndex, "symbolIndex", "symbol-index", false, "Keep the symbols the language server reports in "+lsp.SymbolIndexDir+" in the workspace, and answer symbol queries from them while the server indexes after a restart")
+	set.PathVar(&cfg.codeIndex, "codeIndex", "code-index", "", "Path of a prebuilt LSIF or SCIP index to answer definition, references and hover queries from, falling back to the language server for files changed since it was produced. Without --lsp or --lsp-address only the index is used")
+	set.StringVar(&cfg.transport, "transport", "transport", "stdio", "MCP transport to serve: stdio or sse").OneOf("stdio", "sse")
+	set.StringVar(&cfg.listenAddr, "listen", "listen", "localhost:8080", "Address to listen on when using the sse transport")
+	set.StringVar(&cfg.logging.level, "log.level", "log-level", "", "Minimum level of log messages: debug, info, warn or error (default info, or LOG_LEVEL)")
+	set.StringVar(&cfg.logging.components, "log.components", "log-components", "", "Comma separated levels for single components, such as lsp:debug,watcher:warn. The components are core, lsp, wire, lsp-process, watcher and tools (or LOG_COMPONENT_LEVELS)")
+	set.StringVar(&cfg.logging.format, "log.format", "log-format", "", "Log format: text, or json for one JSON object per line (default text, or LOG_FORMAT)")
+	set.PathVar(&cfg.logging.file, "log.file", "log-file", "", "File to write logs to in addition to stderr, rotated at 50MB keeping three old files (or LOG_FILE)")
+	set.StringVar(&cfg.trace, "trace", "trace", "", "Trace every JSON-RPC message exchanged with the language server, as JSON lines, to stderr or to a file that is rotated at 50MB")
+	set.IntVar(&cfg.tracePayloadBytes, "tracePayloadBytes", "trace-payload-bytes", lsp.DefaultTracePayloadBytes, "Bytes of the params or result of each traced message to keep, 0 for all of them").NonNegative()
+	set.IntVar(&cfg.maxResponseBytes, "maxResponseBytes", "max-response-bytes", 0, "Maximum size of a tool result in bytes, with the rest returned by continue_output, or -1 for no limit (default 60000)")
+	set.StringVar(&cfg.metricsAddr, "metricsAddr", "metrics-addr", "", "Address such as localhost:9090 to serve Prometheus metrics on at /metrics")
+	var serverMessages string
+	set.StringVar(&serverMessages, "serverMessages", "server-messages", "info", "Least severe language server message forwarded to the MCP client as a log notification: error, warning, info, log, or off")
+	set.ListVar(&cfg.fallbackGlobs, "fallback.globs", "fallback-globs", "Comma separated globs of files, such as docs and config files, that are read and edited without a language server when no other server's globs match them, or default for "+strings.Join(defaultFallbackGlobs, ","))
+	set.FileSchema(configFileSchema())
+	if err := set.Parse(args, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if slices.Equal(cfg.fallbackGlobs, []string{"default"}) {
+		cfg.fallbackGlobs = defaultFallbackGlobs
+	}
 
-	// Validate workspace directory
-	if cfg.workspaceDir == "" {
-		return nil, fmt.Errorf("workspace directory is required")
+	if err := cfg.logging.apply(); err != nil {
+		return nil, fmt.Errorf("invalid logging options: %v", err)
 	}
 
-	workspaceDir, err := filepath.Abs(cfg.workspaceDir)
+	level, err := parseMessageLevel(serverMessages)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path for workspace: %v", err)
+		return nil, err
 	}
-	cfg.workspaceDir = workspaceDir
+	cfg.serverMessages = level
 
-	if _, err := os.Stat(cfg.workspaceDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("workspace directory does not exist: %s", cfg.workspaceDir)
+	if cfg.keepWarm && cfg.transport != "sse" {
+		return nil, fmt.Errorf("keep-warm requires the sse transport")
 	}
 
-	// Validate LSP command
-	if cfg.lspCommand == "" {
-		return nil, fmt.Errorf("LSP command is required")
+	// Presets supply defaults that flags and the config file override
+	if cfg.preset != "" {
+		p, err := lookupPreset(cfg.preset)
+		if err != nil {
+			return nil, err
+		}
+		cfg.applyPreset(p)
 	}
 
-	if _, err := exec.LookPath(cfg.lspCommand); err != nil {
-		return nil, fmt.Errorf("LSP command not found: %s", cfg.lspCommand)
+	// When connecting to a running server the command is optional and only used
+	// to pick its section of the config file
+	if cfg.lspAddress != "" && cfg.remote.Host != "" {
+		return nil, fmt.Errorf("ssh cannot be combined with lsp-address")
+	}
+	if cfg.remote.Root != "" && !path.IsAbs(cfg.remote.Root) {
+		return nil, fmt.Errorf("ssh-root must be an absolute path")
 	}
 
-	// Parse config file if provided
+	// The set read the settings of the config file, and the entries that are not
+	// settings are read here
 	if cfg.configFile != "" {
 		err := parseConfigFile(cfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse config file: %v", err)
 		}
+
+		// Initialization options under another name are usually meant for this server
+		cfg.configWarnings = set.Warnings()
+		sections := slices.DeleteFunc(set.Sections(), func(section string) bool {
+			return slices.ContainsFunc(cfg.routes, func(route serverRoute) bool { return route.Name == section })
+		})
+		if len(sections) > 0 && cfg.lspName() != "" && !slices.Contains(sections, cfg.lspName()) {
+			cfg.configWarnings = append(cfg.configWarnings, fmt.Sprintf("config file has initialization options for %s but not for %s, the name of this server; pass --server-name to use them", strings.Join(sections, ", "), cfg.lspName()))
+		}
+		for _, warning := range cfg.configWarnings {
+			coreLogger.Warn("%s", warning)
+		}
+	}
+
+	// Validate LSP command. Without a command or address only a code index
+	// answers queries, which may be set in the config file.
+	if cfg.codeIndex != "" {
+		codeIndex, err := filepath.Abs(cfg.codeIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for code index: %v", err)
+		}
+		cfg.codeIndex = codeIndex
+	}
+	if cfg.indexOnly() {
+		if cfg.codeIndex == "" {
+			return nil, fmt.Errorf("LSP command or address is required")
+		}
+		if cfg.remote.Host != "" || cfg.container.Image != "" {
+			return nil, fmt.Errorf("ssh and containers require an LSP command")
+		}
+	} else if cfg.lspAddress == "" {
+		// A remote or containerized command is looked up where it runs, which
+		// may only be known from the config file
+		lookup := cfg.lspCommand
+		switch {
+		case cfg.remote.Host != "" && cfg.container.Image != "":
+			return nil, fmt.Errorf("ssh cannot be combined with a container")
+		case cfg.remote.Host != "":
+			lookup = "ssh"
+		case cfg.container.Image != "":
+			if err := cfg.container.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid container: %v", err)
+			}
+			lookup = cfg.container.RuntimeCommand()
+		}
+		if _, err := exec.LookPath(lookup); err != nil {
+			return nil, fmt.Errorf("LSP command not found: %s", lookup)
+		}
+	} else if cfg.container.Image != "" {
+		return nil, fmt.Errorf("a container cannot be combined with lsp-address")
 	}
 
+	// Globs default to the patterns the preset watches
+	if cfg.preload.Strategy == lsp.PreloadGlobs && len(cfg.preload.Globs) == 0 {
+		cfg.preload.Globs = cfg.filePatterns
+	}
+	if err := cfg.preload.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid preload policy: %v", err)
+	}
+
+	// Validate workspace directories
+	if len(cfg.workspaceDirs) == 0 {
+		return nil, fmt.Errorf("workspace directory is required")
+	}
+
+	seen := make(map[string]bool)
+	var workspaceDirs []string
+	for _, dir := range cfg.workspaceDirs {
+		workspaceDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for workspace: %v", err)
+		}
+
+		if _, err := os.Stat(workspaceDir); os.IsNotExist(err) {
+			return nil, fmt.Errorf("workspace directory does not exist: %s", workspaceDir)
+		}
+
+		if !seen[workspaceDir] {
+			seen[workspaceDir] = true
+			workspaceDirs = append(workspaceDirs, workspaceDir)
+		}
+	}
+	cfg.workspaceDirs = workspaceDirs
+
 	return cfg, nil
 }
 
+// configFileEntries are the entries of the config file that are not settings. Any
+// other key that holds a JSON object is the initialization options of the server
+// of that name.
+type configFileEntries struct {
+	// LanguageIDs add to those of the preset, keyed by extension or file name
+	LanguageIDs map[string]string      `json:"languageIds"`
+	Container   lsp.ContainerWorkspace `json:"container"`
+	Preload     lsp.PreloadPolicy      `json:"preload"`
+	// Settings answer the server's workspace/configuration requests, keyed by section
+	Settings map[string]any `json:"settings"`
+	// Readiness probes and save actions are keyed by server name
+	Readiness map[string]lsp.ReadinessProbe `json:"readiness"`
+	Save      map[string]lsp.SaveActions    `json:"save"`
+	// Servers are started alongside the primary one for the files matching their globs
+	Servers []serverRoute `json:"servers"`
+}
+
+// configFileSchema describes the config file entries that are not settings
+func configFileSchema() *conf.Schema {
+	schema := conf.SchemaOf(configFileEntries{})
+	schema.AdditionalProperties = &conf.Schema{
+		Type:                 "object",
+		AdditionalProperties: &conf.Schema{},
+		Description:          "keys other than settings hold the initialization options of the server of that name",
+	}
+	return schema
+}
+
 func parseConfigFile(cfg *config) error {
 	data, err := os.ReadFile(cfg.configFile)
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %v", err)
 	}
 
-	var allConfigs map[string]any
-	if err := json.Unmarshal(data, &allConfigs); err != nil {
-		return fmt.Errorf("failed to parse JSON config: %v", err)
+	var entries configFileEntries
+	allConfigs, err := conf.DecodeFile(data, &entries)
+	if err != nil {
+		return err
+	}
+
+	if len(entries.LanguageIDs) > 0 {
+		merged := make(map[string]protocol.LanguageKind, len(cfg.languageIDs)+len(entries.LanguageIDs))
+		for key, id := range cfg.languageIDs {
+			merged[key] = id
+		}
+		for key, id := range entries.LanguageIDs {
+			if key == "" || id == "" {
+				return fmt.Errorf("config file key languageIds: must map extensions or file names to language IDs")
+			}
+			if strings.HasPrefix(key, ".") {
+				key = strings.ToLower(key)
+			}
+			merged[key] = protocol.LanguageKind(id)
+		}
+		cfg.languageIDs = merged
+	}
+
+	// The container image and runtime are settings, resolved with their flags
+	container := entries.Container
+	container.Image = cfg.container.Image
+	container.Runtime = cfg.container.Runtime
+	cfg.container = container
+
+	// The preload strategy and file limit are settings, resolved with their flags
+	cfg.preload.Globs = entries.Preload.Globs
+	cfg.preload.Commits = entries.Preload.Commits
+
+	if entries.Settings != nil {
+		cfg.settings = entries.Settings
 	}
 
 	// Extract config for the specific LSP server
-	lspName := extractLSPName(cfg.lspCommand)
+	lspName := cfg.lspName()
+
+	// Fields the probe leaves out keep the values of the preset's probe
+	probes, _ := allConfigs["readiness"].(map[string]any)
+	if probe, exists := probes[lspName]; exists {
+		data, err := json.Marshal(probe)
+		if err != nil {
+			return fmt.Errorf("failed to read readiness probe for %s: %v", lspName, err)
+		}
+		if err := json.Unmarshal(data, &cfg.readiness); err != nil {
+			return fmt.Errorf("invalid readiness probe for %s: %v", lspName, err)
+		}
+		if err := cfg.readiness.Validate(); err != nil {
+			return fmt.Errorf("config file key readiness.%s: %v", lspName, err)
+		}
+	}
+	if actions, exists := entries.Save[lspName]; exists {
+		cfg.saveActions = actions
+	}
 	if lspConfig, exists := allConfigs[lspName]; exists {
 		if configMap, ok := lspConfig.(map[string]any); ok {
 			cfg.lspConfig = configMap
 		} else {
-			return fmt.Errorf("config for %s must be a JSON object", lspName)
+			return fmt.Errorf("config file key %s: initialization options must be a JSON object", lspName)
 		}
 	}
 
+	routes, err := parseRoutes(entries, allConfigs, lspName)
+	if err != nil {
+		return err
+	}
+	cfg.routes = routes
+
 	return nil
 }
 
+// lspName returns the name the language server is known by
+func (cfg *config) lspName() string {
+	if cfg.serverName != "" {
+		return cfg.serverName
+	}
+	return extractLSPName(cfg.lspCommand)
+}
+
+// indexOnly reports whether there is no language server, only a code index
+func (cfg *config) indexOnly() bool {
+	return cfg.lspCommand == "" && cfg.lspAddress == ""
+}
+
 func extractLSPName(command string) string {
+	if command == "" {
+		return ""
+	}
 	// Extract just the binary name from the full path
 	baseName := filepath.Base(command)
 	// Remove file extension if present
@@ -120,71 +501,340 @@ func extractLSPName(command string) string {
 
 func newServer(config *config) (*mcpServer, error) {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &mcpServer{
+	s := &mcpServer{
 		config:     *config,
 		ctx:        ctx,
 		cancelFunc: cancel,
-	}, nil
+		progress:   newProgressBridge(),
+		responses:  newResponseBudget(config.maxResponseBytes),
+		toolNames:  make(map[string]bool),
+		activity:   newActivityMonitor(),
+	}
+	if config.metricsAddr != "" {
+		s.metrics = newServerMetrics(s)
+	}
+	return s, nil
 }
 
-func (s *mcpServer) initializeLSP() error {
-	if err := os.Chdir(s.config.workspaceDir); err != nil {
-		return fmt.Errorf("failed to change to workspace directory: %v", err)
+// reloadConfiguration re-reads the config file and sends the language server's
+// settings to it
+func (s *mcpServer) reloadConfiguration(ctx context.Context) (string, error) {
+	if s.config.configFile == "" {
+		return "", fmt.Errorf("no config file was given with --config")
 	}
 
-	client, err := lsp.NewClient(s.config.lspCommand, s.config.lspArgs...)
+	// Settings removed from the file fall back to the preset's
+	reloaded := s.config
+	reloaded.lspConfig = nil
+	reloaded.settings = nil
+	if s.config.preset != "" {
+		if p, err := lookupPreset(s.config.preset); err == nil {
+			reloaded.lspConfig = p.initializationOptions
+		}
+	}
+	if err := parseConfigFile(&reloaded); err != nil {
+		return "", fmt.Errorf("failed to parse config file: %v", err)
+	}
+
+	coreLogger.Info("Reloaded config file %s", s.config.configFile)
+	return tools.UpdateConfiguration(ctx, s.lspClient, reloaded.lspConfig, reloaded.settings)
+}
+
+// startClient starts the language server, or loads the code index, and initializes
+// it, without waiting for it to be ready
+func (s *mcpServer) startClient() (*lsp.Client, error) {
+	// Relative paths default to the primary workspace
+	if err := os.Chdir(s.config.workspaceDirs[0]); err != nil {
+		return nil, fmt.Errorf("failed to change to workspace directory: %v", err)
+	}
+
+	var index *codeindex.Index
+	if s.config.codeIndex != "" {
+		loaded, err := codeindex.Load(s.config.codeIndex, s.config.workspaceDirs[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to load code index: %v", err)
+		}
+		coreLogger.Info("Loaded %s index of %d files from %s", loaded.Format, loaded.Documents(), s.config.codeIndex)
+		index = loaded
+	}
+
+	var client *lsp.Client
+	var err error
+	switch {
+	case s.config.indexOnly():
+		client = lsp.NewIndexClient(index)
+	case s.config.lspAddress != "":
+		client, err = lsp.NewClientFromAddress(s.config.lspAddress)
+	case s.config.remote.Host != "":
+		remote := s.config.remote
+		if remote.Root == "" {
+			remote.Root = filepath.ToSlash(s.config.workspaceDirs[0])
+		}
+		client, err = lsp.NewRemoteClient(remote, s.config.workspaceDirs[0], s.config.lspCommand, s.config.lspArgs...)
+	case s.config.container.Image != "":
+		client, err = lsp.NewContainerClient(s.config.container, s.config.workspaceDirs, s.config.lspCommand, s.config.lspArgs...)
+	default:
+		client, err = lsp.NewClient(s.config.lspCommand, s.config.lspArgs...)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create LSP client: %v", err)
+		return nil, fmt.Errorf("failed to create LSP client: %v", err)
 	}
 	s.lspClient = client
-	s.workspaceWatcher = watcher.NewWorkspaceWatcher(client)
+	if err := s.startTrace(); err != nil {
+		return nil, err
+	}
+	watcherConfig := s.watcherConfig()
+	watcherConfig.FilePatterns = s.config.filePatterns
+	watcherConfig.IncludePatterns = s.config.watch.Include
+	s.workspaceWatcher = watcher.NewWorkspaceWatcherWithConfig(client, watcherConfig)
+	client.SetRestartHandler(s.notifyRestart)
+	client.SetMessageHandler(s.forwardServerMessage)
+	if s.metrics != nil {
+		client.SetRequestObserver(s.metrics.observeRequest)
+	}
+	client.SetProgressHandler(s.progress.forward)
+	client.SetReadinessProbe(s.config.readiness)
+	client.SetPreloadPolicy(s.config.preload)
+	client.SetMaxOpenFiles(s.config.maxOpenFiles)
+	client.SetSaveActions(s.config.saveActions)
+	client.SetLanguageIDs(s.config.languageIDs)
+	client.SetSettings(s.config.settings)
+	if name := s.config.lspName(); name != "" {
+		client.SetServerName(name)
+	}
+	if s.config.symbolIndex {
+		client.SetSymbolIndex(lsp.LoadSymbolIndex(s.config.workspaceDirs[0]))
+	}
+	if index != nil {
+		client.SetCodeIndex(index)
+	}
 
-	initResult, err := client.InitializeLSPClient(s.ctx, s.config.workspaceDir, s.config.lspConfig)
+	initResult, err := client.InitializeLSPClient(s.ctx, s.config.workspaceDirs, s.config.lspConfig)
 	if err != nil {
-		return fmt.Errorf("initialize failed: %v", err)
+		return nil, fmt.Errorf("initialize failed: %v", err)
 	}
 
 	coreLogger.Debug("Server capabilities: %+v", initResult.Capabilities)
+	return client, nil
+}
 
-	go s.workspaceWatcher.WatchWorkspace(s.ctx, s.config.workspaceDir)
+// watcherConfig returns the configuration of a workspace watcher from the
+// watcher settings, leaving the files to watch to the caller
+func (s *mcpServer) watcherConfig() *watcher.WatcherConfig {
+	watcherConfig := watcher.DefaultWatcherConfig()
+	watcherConfig.ExcludePatterns = s.config.watch.Exclude
+	watcherConfig.MaxWatchedDirs = s.config.watch.MaxDirs
+	if s.config.watch.DebounceMs > 0 {
+		watcherConfig.DebounceTime = time.Duration(s.config.watch.DebounceMs) * time.Millisecond
+	}
+	if s.config.watch.Backend != "" {
+		watcherConfig.Backend = s.config.watch.Backend
+	}
+	if s.config.watch.PollIntervalMs > 0 {
+		watcherConfig.PollInterval = time.Duration(s.config.watch.PollIntervalMs) * time.Millisecond
+	}
+	return watcherConfig
+}
+
+// initializeLSP starts the language server and the workspace watcher, and waits
+// for the server to be ready
+func (s *mcpServer) initializeLSP() error {
+	client, err := s.startClient()
+	if err != nil {
+		return err
+	}
+
+	client.PreloadFiles(s.ctx)
+	if s.config.lspKeepalive > 0 && !s.config.indexOnly() {
+		go client.KeepAlive(s.ctx, time.Duration(s.config.lspKeepalive)*time.Second)
+	}
+
+	go s.workspaceWatcher.WatchWorkspace(s.ctx, s.config.workspaceDirs...)
+
+	if err := s.startRoutes(); err != nil {
+		return err
+	}
+	if err := s.startFallback(); err != nil {
+		return err
+	}
+
+	// With indexed symbols to answer queries from, startup does not wait for the
+	// server, and the index is brought up to date once it is ready
+	if index := client.SymbolIndex(); index != nil && index.Len() > 0 {
+		go func() {
+			if err := client.WaitForServerReady(s.ctx); err == nil {
+				client.ReconcileSymbolIndex(s.ctx)
+			}
+		}()
+		return nil
+	}
 	return client.WaitForServerReady(s.ctx)
 }
 
+// notifyRestart forwards language server crashes and automatic restarts to MCP
+// clients as log messages
+func (s *mcpServer) notifyRestart(event lsp.RestartEvent) {
+	if s.mcpServer == nil {
+		return
+	}
+
+	level, data := mcp.LoggingLevelInfo, event.Message
+	if event.Err != nil {
+		level, data = mcp.LoggingLevelWarning, fmt.Sprintf("%s: %v", event.Message, event.Err)
+	}
+
+	s.mcpServer.SendNotificationToAllClients("notifications/message", map[string]any{
+		"level":  level,
+		"logger": "mcp-language-server",
+		"data":   data,
+	})
+}
+
+// messageLevels maps the values of --server-messages to the least severe message
+// type forwarded
+var messageLevels = map[string]protocol.MessageType{
+	"off":     0,
+	"error":   protocol.Error,
+	"warning": protocol.Warning,
+	"info":    protocol.Info,
+	"log":     protocol.Log,
+}
+
+// parseMessageLevel parses the value of --server-messages
+func parseMessageLevel(value string) (protocol.MessageType, error) {
+	level, ok := messageLevels[strings.ToLower(value)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported server messages level: %s (expected error, warning, info, log or off)", value)
+	}
+	return level, nil
+}
+
+// forwardServerMessage sends messages the language server shows or logs to MCP
+// clients as log messages, so problems such as a missing module reach the user
+// without digging through stderr
+func (s *mcpServer) forwardServerMessage(msg lsp.ServerMessage) {
+	if s.mcpServer == nil || msg.Type == 0 || msg.Type > s.config.serverMessages {
+		return
+	}
+
+	level := mcp.LoggingLevelDebug
+	switch msg.Type {
+	case protocol.Error:
+		level = mcp.LoggingLevelError
+	case protocol.Warning:
+		level = mcp.LoggingLevelWarning
+	case protocol.Info:
+		level = mcp.LoggingLevelInfo
+	}
+
+	logger := "language-server"
+	if name := s.lspClient.ServerName(); name != "" {
+		logger = name
+	}
+	s.mcpServer.SendNotificationToAllClients("notifications/message", map[string]any{
+		"level":  level,
+		"logger": logger,
+		"data":   msg.Message,
+	})
+}
+
+// startTrace traces the messages exchanged with the language server when --trace
+// is set
+func (s *mcpServer) startTrace() error {
+	var w io.Writer
+	switch s.config.trace {
+	case "":
+		return nil
+	case "stderr":
+		w = os.Stderr
+	default:
+		file, err := logging.OpenRotatingFile(s.config.trace, logging.DefaultFileMaxBytes, logging.DefaultFileBackups)
+		if err != nil {
+			return fmt.Errorf("failed to open trace file: %v", err)
+		}
+		w = file
+	}
+	coreLogger.Info("Tracing LSP messages to %s", s.config.trace)
+	s.lspClient.SetTracer(lsp.NewTracer(w, s.config.tracePayloadBytes))
+	return nil
+}
+
 func (s *mcpServer) start() error {
 	if err := s.initializeLSP(); err != nil {
 		return err
 	}
+	if err := s.registerMCP(); err != nil {
+		return err
+	}
+
+	if err := s.startMetrics(); err != nil {
+		return err
+	}
+
+	if s.config.transport == "sse" {
+		// The health check is served next to the MCP endpoints
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", s.handleHealth)
+		s.sseServer = server.NewSSEServer(s.mcpServer,
+			server.WithKeepAlive(true),
+			server.WithHTTPServer(&http.Server{Handler: mux}),
+		)
+		mux.Handle("/", s.sseServer)
+		coreLogger.Info("Serving MCP over SSE on %s", s.config.listenAddr)
+		if err := s.sseServer.Start(s.config.listenAddr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+
+	return server.ServeStdio(s.mcpServer)
+}
 
+// registerMCP creates the MCP server with the tools and resources the language
+// server supports
+func (s *mcpServer) registerMCP() error {
 	s.mcpServer = server.NewMCPServer(
 		"MCP Language Server",
-		"v0.0.2",
+		version,
 		server.WithLogging(),
 		server.WithRecovery(),
+		server.WithHooks(s.activity.hooks(s.progress.hooks(), s.releaseFiles)),
 	)
+	s.progress.attach(s.mcpServer)
 
-	err := s.registerTools()
-	if err != nil {
+	if err := s.registerTools(); err != nil {
 		return fmt.Errorf("tool registration failed: %v", err)
 	}
-
-	return server.ServeStdio(s.mcpServer)
+	s.registerResources()
+	s.registerDiagnosticsResources()
+	return nil
 }
 
 func main() {
+	if err := runCommand(os.Args[1:]); err != nil {
+		coreLogger.Fatal("%v", err)
+	}
+}
+
+// runServe runs the serve command, serving MCP until a signal, the parent process
+// exiting or the idle timeout stops it
+func runServe(args []string) error {
 	coreLogger.Info("MCP Language Server starting")
 
 	done := make(chan struct{})
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
 
-	config, err := parseConfig()
+	config, err := parseConfig(args)
 	if err != nil {
-		coreLogger.Fatal("%v", err)
+		return err
 	}
 
 	server, err := newServer(config)
 	if err != nil {
-		coreLogger.Fatal("%v", err)
+		return err
 	}
 
 	// Parent process monitoring channel
@@ -192,7 +842,12 @@ func main() {
 
 	// Monitor parent process termination
 	// Claude desktop does not properly kill child processes for MCP servers
+	// SSE servers run as daemons shared by many clients, so they outlive their parent
 	go func() {
+		if config.transport == "sse" {
+			return
+		}
+
 		ppid := os.Getppid()
 		coreLogger.Debug("Monitoring parent process: %d", ppid)
 
@@ -214,6 +869,28 @@ func main() {
 		}
 	}()
 
+	// Shut down after the idle timeout
+	if config.idleTimeout > 0 {
+		go server.activity.watchIdle(time.Duration(config.idleTimeout)*time.Minute, done)
+	}
+
+	// Reload the config file on SIGHUP
+	go func() {
+		for {
+			select {
+			case <-reloadChan:
+				if server.lspClient == nil {
+					continue
+				}
+				if _, err := server.reloadConfiguration(server.ctx); err != nil {
+					coreLogger.Error("Failed to reload configuration: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
 	// Handle shutdown triggers
 	go func() {
 		select {
@@ -223,18 +900,20 @@ func main() {
 		case <-parentDeath:
 			coreLogger.Info("Parent death detected, initiating shutdown")
 			cleanup(server, done)
+		case <-server.activity.idle:
+			coreLogger.Info("No MCP requests for %d minutes, initiating shutdown", config.idleTimeout)
+			cleanup(server, done)
 		}
 	}()
 
 	if err := server.start(); err != nil {
-		coreLogger.Error("Server error: %v", err)
 		cleanup(server, done)
-		os.Exit(1)
+		return fmt.Errorf("server error: %v", err)
 	}
 
 	<-done
 	coreLogger.Info("Server shutdown complete for PID: %d", os.Getpid())
-	os.Exit(0)
+	return nil
 }
 
 func cleanup(s *mcpServer, done chan struct{}) {
@@ -244,41 +923,28 @@ func cleanup(s *mcpServer, done chan struct{}) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if s.lspClient != nil {
-		coreLogger.Info("Closing open files")
-		s.lspClient.CloseAllFiles(ctx)
-
-		// Create a shorter timeout context for the shutdown request
-		shutdownCtx, shutdownCancel := context.WithTimeout(ctx, 500*time.Millisecond)
-		defer shutdownCancel()
-
-		// Run shutdown in a goroutine with timeout to avoid blocking if LSP doesn't respond
-		shutdownDone := make(chan struct{})
-		go func() {
-			coreLogger.Info("Sending shutdown request")
-			if err := s.lspClient.Shutdown(shutdownCtx); err != nil {
-				coreLogger.Error("Shutdown request failed: %v", err)
-			}
-			close(shutdownDone)
-		}()
-
-		// Wait for shutdown with timeout
-		select {
-		case <-shutdownDone:
-			coreLogger.Info("Shutdown request completed")
-		case <-time.After(1 * time.Second):
-			coreLogger.Warn("Shutdown request timed out, proceeding with exit")
+	if s.sseServer != nil {
+		coreLogger.Info("Stopping SSE server")
+		if err := s.sseServer.Shutdown(ctx); err != nil {
+			coreLogger.Error("Failed to stop SSE server: %v", err)
 		}
+	}
 
-		coreLogger.Info("Sending exit notification")
-		if err := s.lspClient.Exit(ctx); err != nil {
-			coreLogger.Error("Exit notification failed: %v", err)
+	// Symbols recorded since the last save would be lost
+	if s.lspClient != nil && s.lspClient.SymbolIndex() != nil {
+		if err := s.lspClient.SymbolIndex().Save(); err != nil {
+			coreLogger.Error("Failed to save symbol index: %v", err)
 		}
+	}
 
-		coreLogger.Info("Closing LSP client")
-		if err := s.lspClient.Close(); err != nil {
-			coreLogger.Error("Failed to close LSP client: %v", err)
-		}
+	for _, route := range s.routes {
+		stopClient(ctx, route.client)
+	}
+	if s.fallback != nil {
+		stopClient(ctx, s.fallback)
+	}
+	if s.lspClient != nil {
+		stopClient(ctx, s.lspClient)
 	}
 
 	// Send signal to the done channel
@@ -290,3 +956,51 @@ func cleanup(s *mcpServer, done chan struct{}) {
 
 	coreLogger.Info("Cleanup completed for PID: %d", os.Getpid())
 }
+
+// stopClient shuts down a language server the client started, or disconnects
+// from one it did not
+func stopClient(ctx context.Context, client *lsp.Client) {
+	if !client.ManagesProcess() {
+		// Leave externally managed servers running for their other clients
+		coreLogger.Info("Disconnecting from LSP server")
+		if err := client.Close(); err != nil {
+			coreLogger.Error("Failed to close LSP client: %v", err)
+		}
+		return
+	}
+
+	coreLogger.Info("Closing open files")
+	client.CloseAllFiles(ctx)
+
+	// Create a shorter timeout context for the shutdown request
+	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer shutdownCancel()
+
+	// Run shutdown in a goroutine with timeout to avoid blocking if LSP doesn't respond
+	shutdownDone := make(chan struct{})
+	go func() {
+		coreLogger.Info("Sending shutdown request")
+		if err := client.Shutdown(shutdownCtx); err != nil {
+			coreLogger.Error("Shutdown request failed: %v", err)
+		}
+		close(shutdownDone)
+	}()
+
+	// Wait for shutdown with timeout
+	select {
+	case <-shutdownDone:
+		coreLogger.Info("Shutdown request completed")
+	case <-time.After(1 * time.Second):
+		coreLogger.Warn("Shutdown request timed out, proceeding with exit")
+	}
+
+	coreLogger.Info("Sending exit notification")
+	if err := client.Exit(ctx); err != nil {
+		coreLogger.Error("Exit notification failed: %v", err)
+	}
+
+	coreLogger.Info("Closing LSP client")
+	if err := client.Close(); err != nil {
+		coreLogger.Error("Failed to close LSP client: %v", err)
+	}
+}
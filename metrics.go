@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/metrics"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// metricsPrefix starts the name of every metric
+const metricsPrefix = "mcp_language_server_"
+
+// severityLabels are the values of the severity label of the diagnostics metric
+var severityLabels = map[protocol.DiagnosticSeverity]string{
+	protocol.SeverityError:       "error",
+	protocol.SeverityWarning:     "warning",
+	protocol.SeverityInformation: "information",
+	protocol.SeverityHint:        "hint",
+}
+
+// changeTypeLabels are the values of the type label of the watcher events metric
+var changeTypeLabels = map[protocol.FileChangeType]string{
+	protocol.Created: "created",
+	protocol.Changed: "changed",
+	protocol.Deleted: "deleted",
+}
+
+// serverMetrics are the metrics served on --metrics-addr
+type serverMetrics struct {
+	registry     *metrics.Registry
+	toolCalls    *metrics.Counter
+	toolDuration *metrics.Histogram
+	lspRequests  *metrics.Histogram
+	lspErrors    *metrics.Counter
+}
+
+// newServerMetrics creates the metrics of s. Those describing the language server
+// and the watcher are read from them when scraped.
+func newServerMetrics(s *mcpServer) *serverMetrics {
+	r := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: r,
+		toolCalls: r.NewCounter(metricsPrefix+"tool_calls_total",
+			"MCP tool calls by tool and status, ok or error.", "tool", "status"),
+		toolDuration: r.NewHistogram(metricsPrefix+"tool_call_duration_seconds",
+			"Time taken by MCP tool calls.", metrics.DefaultBuckets, "tool"),
+		lspRequests: r.NewHistogram(metricsPrefix+"lsp_request_duration_seconds",
+			"Time taken by requests to the language server, by method.", metrics.DefaultBuckets, "method"),
+		lspErrors: r.NewCounter(metricsPrefix+"lsp_request_errors_total",
+			"Requests to the language server that failed or timed out, by method.", "method"),
+	}
+
+	r.NewGaugeFunc(metricsPrefix+"lsp_open_files", "Files open in the language server.", "", func() map[string]float64 {
+		if s.lspClient == nil {
+			return nil
+		}
+		return map[string]float64{"": float64(s.lspClient.Stats().OpenFiles)}
+	})
+	r.NewGaugeFunc(metricsPrefix+"lsp_diagnostics", "Diagnostics published by the language server, by severity.", "severity", func() map[string]float64 {
+		if s.lspClient == nil {
+			return nil
+		}
+		values := make(map[string]float64)
+		for _, label := range severityLabels {
+			values[label] = 0
+		}
+		for severity, count := range s.lspClient.Stats().Diagnostics {
+			if label, ok := severityLabels[severity]; ok {
+				values[label] += float64(count)
+			}
+		}
+		return values
+	})
+	r.NewCounterFunc(metricsPrefix+"lsp_crashes_total", "Unexpected exits of the language server.", "", func() map[string]float64 {
+		if s.lspClient == nil {
+			return nil
+		}
+		return map[string]float64{"": float64(s.lspClient.Stats().Crashes)}
+	})
+	r.NewCounterFunc(metricsPrefix+"lsp_restarts_total", "Restarts of the language server, automatic or requested.", "", func() map[string]float64 {
+		if s.lspClient == nil {
+			return nil
+		}
+		return map[string]float64{"": float64(s.lspClient.Stats().Restarts)}
+	})
+	r.NewCounterFunc(metricsPrefix+"watcher_events_total", "File events sent to the language server by the watcher, by type.", "type", func() map[string]float64 {
+		if s.workspaceWatcher == nil {
+			return nil
+		}
+		values := make(map[string]float64)
+		for changeType, count := range s.workspaceWatcher.EventCounts() {
+			values[changeTypeLabels[changeType]] = float64(count)
+		}
+		return values
+	})
+	r.NewCounterFunc(metricsPrefix+"unchanged_notifications_skipped_total", "Change notifications skipped because the file content was unchanged, by source.", "source", func() map[string]float64 {
+		values := make(map[string]float64)
+		if s.lspClient != nil {
+			values["didChange"] = float64(s.lspClient.Stats().SkippedChanges)
+		}
+		return values
+	})
+	return m
+}
+
+// withToolMetrics counts the calls of a tool and how long they take
+func (m *serverMetrics) withToolMetrics(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := handler(ctx, request)
+		m.toolDuration.Observe(time.Since(start).Seconds(), name)
+
+		status := "ok"
+		if err != nil || (result != nil && result.IsError) {
+			status = "error"
+		}
+		m.toolCalls.Inc(name, status)
+		return result, err
+	}
+}
+
+// observeRequest records a request to the language server
+func (m *serverMetrics) observeRequest(method string, elapsed time.Duration, err error) {
+	m.lspRequests.Observe(elapsed.Seconds(), method)
+	if err != nil {
+		m.lspErrors.Inc(method)
+	}
+}
+
+// startMetrics serves the metrics on --metrics-addr at /metrics, along with the
+// health check at /healthz
+func (s *mcpServer) startMetrics() error {
+	if s.metrics == nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", s.config.metricsAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.metrics.registry)
+	mux.HandleFunc("/healthz", s.handleHealth)
+
+	coreLogger.Info("Serving metrics on http://%s/metrics", listener.Addr())
+	go func() {
+		if err := http.Serve(listener, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			coreLogger.Error("Metrics server stopped: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Lines and columns in tool arguments are one-indexed, like the L12:C5 positions
+// tools show and the ones editors display. Tools that take positions also accept
+// indexBase "zero" for positions taken from LSP messages or other zero-indexed
+// sources. The arguments are converted before the handler is called, so handlers
+// and results only ever deal with one-indexed positions.
+
+const (
+	indexBaseOne  = "one"
+	indexBaseZero = "zero"
+)
+
+// positionArguments are the names of the tool arguments that hold lines and columns
+var positionArguments = []string{"line", "column", "startLine", "startColumn", "endLine", "endColumn"}
+
+// hasPositionArguments reports whether a schema's properties include a line or column
+func hasPositionArguments(properties map[string]any) bool {
+	for name := range properties {
+		if slices.Contains(positionArguments, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// positionArrays returns the array arguments whose items hold lines and columns,
+// such as the edits of edit_file
+func positionArrays(properties map[string]any) []string {
+	var names []string
+	for name, property := range properties {
+		schema, ok := property.(map[string]any)
+		if !ok {
+			continue
+		}
+		items, ok := schema["items"].(map[string]any)
+		if !ok {
+			continue
+		}
+		if itemProperties, ok := items["properties"].(map[string]any); ok && hasPositionArguments(itemProperties) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withIndexBase adds the indexBase argument to tools that take lines or columns, and
+// wraps their handler to convert the positions it is given to one-indexed ones
+func withIndexBase(tool mcp.Tool, handler server.ToolHandlerFunc) (mcp.Tool, server.ToolHandlerFunc) {
+	arrays := positionArrays(tool.InputSchema.Properties)
+	if !hasPositionArguments(tool.InputSchema.Properties) && len(arrays) == 0 {
+		return tool, handler
+	}
+
+	mcp.WithString("indexBase",
+		mcp.Description("Whether the lines and columns given are one-indexed (\"one\"), like the positions tools show, or zero-indexed (\"zero\"), like LSP positions. Results are always one-indexed."),
+		mcp.Enum(indexBaseOne, indexBaseZero),
+		mcp.DefaultString(indexBaseOne),
+	)(&tool)
+
+	return tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		zeroBased := false
+		switch request.Params.Arguments["indexBase"] {
+		case nil, "", indexBaseOne:
+		case indexBaseZero:
+			zeroBased = true
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("indexBase must be %q or %q", indexBaseOne, indexBaseZero)), nil
+		}
+
+		arguments, err := normalizePositions(request.Params.Arguments, arrays, zeroBased)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		request.Params.Arguments = arguments
+		return handler(ctx, request)
+	}
+}
+
+// normalizePositions returns a copy of a tool's arguments with its lines and columns,
+// including those in the items of the given array arguments, made one-indexed
+func normalizePositions(arguments map[string]any, arrays []string, zeroBased bool) (map[string]any, error) {
+	normalized := make(map[string]any, len(arguments))
+	for name, value := range arguments {
+		normalized[name] = value
+	}
+
+	for _, name := range positionArguments {
+		value, ok := normalized[name]
+		if !ok {
+			continue
+		}
+		position, err := normalizePosition(name, value, zeroBased)
+		if err != nil {
+			return nil, err
+		}
+		normalized[name] = position
+	}
+
+	for _, name := range arrays {
+		items, ok := normalized[name].([]any)
+		if !ok {
+			continue
+		}
+		normalizedItems := make([]any, len(items))
+		for i, item := range items {
+			if fields, ok := item.(map[string]any); ok {
+				normalizedFields, err := normalizePositions(fields, nil, zeroBased)
+				if err != nil {
+					return nil, fmt.Errorf("%s[%d]: %w", name, i, err)
+				}
+				item = normalizedFields
+			}
+			normalizedItems[i] = item
+		}
+		normalized[name] = normalizedItems
+	}
+	return normalized, nil
+}
+
+// normalizePosition makes a line or column one-indexed, rejecting ones that are out
+// of range for the index base. Values that are not numbers are left for the handler
+// to report.
+func normalizePosition(name string, value any, zeroBased bool) (any, error) {
+	var position float64
+	switch v := value.(type) {
+	case float64:
+		position = v
+	case int:
+		position = float64(v)
+	default:
+		return value, nil
+	}
+
+	if zeroBased {
+		if position < 0 {
+			return nil, fmt.Errorf("%s is %v, but zero-indexed lines and columns start at 0", name, position)
+		}
+		return position + 1, nil
+	}
+	if position < 1 {
+		return nil, fmt.Errorf("%s is %v, but lines and columns are one-indexed; pass indexBase %q to give zero-indexed positions", name, position, indexBaseZero)
+	}
+	return position, nil
+}
+
+// symbolPosition returns the file and one-indexed position a tool acts on: that of
+// the name of the symbol given by the symbolName argument, found by its qualified
+// name, or else the filePath, line and column arguments
+func (s *mcpServer) symbolPosition(ctx context.Context, arguments map[string]any) (string, int, int, error) {
+	if symbolName, ok := arguments["symbolName"].(string); ok && symbolName != "" {
+		match, err := tools.ResolveSymbolPosition(ctx, s.lspClient, symbolName)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		return match.Path, match.Line, match.Column, nil
+	}
+
+	filePath, ok := arguments["filePath"].(string)
+	if !ok {
+		return "", 0, 0, fmt.Errorf("symbolName or filePath must be a string")
+	}
+	filePath, err := s.lspClient.ResolveWorkspacePath(filePath)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	// Handle both float64 and int for line and column due to JSON parsing
+	var position [2]int
+	for i, name := range []string{"line", "column"} {
+		switch v := arguments[name].(type) {
+		case float64:
+			position[i] = int(v)
+		case int:
+			position[i] = v
+		default:
+			return "", 0, 0, fmt.Errorf("%s must be a number", name)
+		}
+	}
+	return filePath, position[0], position[1], nil
+}
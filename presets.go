@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// preset is a curated configuration for a popular language server
+type preset struct {
+	// Server name, which also selects its initializer and config file entries
+	name    string
+	command string
+	args    []string
+
+	initializationOptions map[string]any
+	readiness             lsp.ReadinessProbe
+
+	// Files to watch and open even if the server never registers file watchers
+	filePatterns []string
+
+	// Language IDs by file extension, for extensions not detected correctly
+	languageIDs map[string]protocol.LanguageKind
+}
+
+var presets = map[string]preset{
+	"python": {
+		name:    "pyright",
+		command: "pyright-langserver",
+		args:    []string{"--stdio"},
+		readiness: lsp.ReadinessProbe{
+			Strategy: lsp.ReadinessDiagnostics,
+		},
+		filePatterns: []string{"**/*.py", "**/*.pyi", "**/pyproject.toml", "**/pyrightconfig.json"},
+	},
+	"rust": {
+		name:    "rust-analyzer",
+		command: "rust-analyzer",
+		initializationOptions: map[string]any{
+			"cargo":       map[string]any{"buildScripts": map[string]any{"enable": true}},
+			"procMacro":   map[string]any{"enable": true},
+			"checkOnSave": true,
+		},
+		readiness: lsp.ReadinessProbe{
+			Strategy:      lsp.ReadinessProgress,
+			ProgressTitle: "Indexing",
+			TimeoutMs:     120000,
+		},
+		filePatterns: []string{"**/*.rs", "**/Cargo.toml"},
+	},
+	"c": {
+		name:    "clangd",
+		command: "clangd",
+		args:    []string{"--background-index"},
+		initializationOptions: map[string]any{
+			"clangdFileStatus": true,
+		},
+		readiness: lsp.ReadinessProbe{
+			Strategy:      lsp.ReadinessProgress,
+			ProgressTitle: "indexing",
+		},
+		filePatterns: []string{"**/*.{c,h,cc,cpp,cxx,hh,hpp,hxx}", "**/compile_commands.json"},
+		languageIDs: map[string]protocol.LanguageKind{
+			".h":   protocol.LangCPP,
+			".hh":  protocol.LangCPP,
+			".hpp": protocol.LangCPP,
+			".hxx": protocol.LangCPP,
+		},
+	},
+	"java": {
+		name:    "jdtls",
+		command: "jdtls",
+		initializationOptions: map[string]any{
+			"extendedClientCapabilities": map[string]any{
+				"classFileContentsSupport": true,
+			},
+		},
+		readiness: lsp.ReadinessProbe{
+			Strategy:  lsp.ReadinessIdle,
+			TimeoutMs: 120000,
+		},
+		filePatterns: []string{"**/*.java", "**/pom.xml", "**/*.gradle", "**/*.gradle.kts"},
+	},
+	"lua": {
+		name:    "lua-language-server",
+		command: "lua-language-server",
+		readiness: lsp.ReadinessProbe{
+			Strategy: lsp.ReadinessDiagnostics,
+		},
+		filePatterns: []string{"**/*.lua", "**/.luarc.json"},
+	},
+	"ruby": {
+		name:    "solargraph",
+		command: "solargraph",
+		args:    []string{"stdio"},
+		initializationOptions: map[string]any{
+			"diagnostics": true,
+			"formatting":  true,
+		},
+		filePatterns: []string{"**/*.rb", "**/*.rake", "**/Gemfile", "**/.solargraph.yml"},
+		languageIDs: map[string]protocol.LanguageKind{
+			".rake": protocol.LangRuby,
+		},
+	},
+}
+
+// Other names presets may be selected by
+var presetAliases = map[string]string{
+	"pyright":             "python",
+	"rust-analyzer":       "rust",
+	"cpp":                 "c",
+	"clangd":              "c",
+	"jdtls":               "java",
+	"lua-language-server": "lua",
+	"solargraph":          "ruby",
+}
+
+// lookupPreset finds a preset by language or server name
+func lookupPreset(name string) (preset, error) {
+	if alias, ok := presetAliases[name]; ok {
+		name = alias
+	}
+	if p, ok := presets[name]; ok {
+		return p, nil
+	}
+
+	names := make([]string, 0, len(presets))
+	for presetName := range presets {
+		names = append(names, presetName)
+	}
+	sort.Strings(names)
+	return preset{}, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// applyPreset fills in the parts of the configuration that were not given on the
+// command line
+func (cfg *config) applyPreset(p preset) {
+	if cfg.lspCommand == "" && cfg.lspAddress == "" {
+		cfg.lspCommand = p.command
+		if len(cfg.lspArgs) == 0 {
+			cfg.lspArgs = p.args
+		}
+	}
+	if cfg.serverName == "" {
+		cfg.serverName = p.name
+	}
+	cfg.lspConfig = p.initializationOptions
+	cfg.readiness = p.readiness
+	cfg.filePatterns = p.filePatterns
+	cfg.languageIDs = p.languageIDs
+}
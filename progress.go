@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressBridge forwards work done progress from the language server, such as
+// indexing, to tool calls that asked for MCP progress notifications
+type progressBridge struct {
+	mcpServer *server.MCPServer
+
+	calls map[any]*progressCall
+	mu    sync.Mutex
+}
+
+// progressCall is a tool call waiting for progress notifications
+type progressCall struct {
+	ctx   context.Context
+	token mcp.ProgressToken
+	// MCP progress must increase, so each notification counts one more report
+	sent int
+}
+
+func newProgressBridge() *progressBridge {
+	return &progressBridge{calls: make(map[any]*progressCall)}
+}
+
+// hooks returns MCP server hooks that track tool calls with a progress token
+func (b *progressBridge) hooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddBeforeCallTool(func(ctx context.Context, id any, message *mcp.CallToolRequest) {
+		if message.Params.Meta == nil || message.Params.Meta.ProgressToken == nil {
+			return
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.calls[id] = &progressCall{ctx: ctx, token: message.Params.Meta.ProgressToken}
+	})
+	hooks.AddAfterCallTool(func(ctx context.Context, id any, message *mcp.CallToolRequest, result *mcp.CallToolResult) {
+		b.remove(id)
+	})
+	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+		if method == mcp.MethodToolsCall {
+			b.remove(id)
+		}
+	})
+	return hooks
+}
+
+func (b *progressBridge) remove(id any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.calls, id)
+}
+
+// attach sets the MCP server that notifications are sent through
+func (b *progressBridge) attach(mcpServer *server.MCPServer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mcpServer = mcpServer
+}
+
+// forward sends a language server progress report to every tool call in flight
+func (b *progressBridge) forward(event lsp.ProgressEvent) {
+	message := event.Title
+	if event.Message != "" {
+		message = fmt.Sprintf("%s: %s", message, event.Message)
+	}
+	if event.Percentage != nil {
+		message = fmt.Sprintf("%s (%.0f%%)", message, *event.Percentage)
+	}
+	if event.Kind == "end" {
+		message = fmt.Sprintf("%s: done", event.Title)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.mcpServer == nil {
+		return
+	}
+	for _, call := range b.calls {
+		call.sent++
+		err := b.mcpServer.SendNotificationToClient(call.ctx, "notifications/progress", map[string]any{
+			"progressToken": call.token,
+			"progress":      call.sent,
+			"message":       message,
+		})
+		if err != nil {
+			coreLogger.Debug("Failed to send progress notification: %v", err)
+		}
+	}
+}
+
+// reporter returns a function that sends messages to the MCP client as progress
+// notifications of a tool call, or nil when the call did not ask for progress
+func (b *progressBridge) reporter(ctx context.Context, request mcp.CallToolRequest) func(string) {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	token := request.Params.Meta.ProgressToken
+
+	sent := 0
+	return func(message string) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if b.mcpServer == nil {
+			return
+		}
+
+		// Share the count with language server progress sent to the same call
+		progress := &sent
+		for _, call := range b.calls {
+			if call.token == token {
+				progress = &call.sent
+				break
+			}
+		}
+		*progress++
+
+		err := b.mcpServer.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      *progress,
+			"message":       message,
+		})
+		if err != nil {
+			coreLogger.Debug("Failed to send progress notification: %v", err)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// runQueryCommand runs the query subcommand, which starts the language server as
+// serving would, calls one tool, prints its result to stdout and exits. The tool
+// comes first, then its arguments and the flags of the server:
+//
+//	mcp-language-server query references --file x.go --line 10 --col 5 --workspace . --lsp gopls
+func runQueryCommand(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: mcp-language-server query <tool> [--file path --line n --col n] [--symbol name] [--arg name=value] [--json] [flags]")
+	}
+	toolName, args := args[0], args[1:]
+
+	file := flag.String("file", "", "File for tools that take a filePath")
+	line := flag.Int("line", 0, "Line of the position in --file, one-indexed")
+	col := flag.Int("col", 0, "Column of the position in --file, one-indexed")
+	symbol := flag.String("symbol", "", "Symbol name for tools that take a symbolName")
+	asJSON := flag.Bool("json", false, "Print the result as JSON")
+	var toolArgs stringList
+	flag.Var(&toolArgs, "arg", "Tool argument as name=value, with JSON values such as numbers and lists decoded (may be repeated)")
+
+	config, err := parseConfig(args)
+	if err != nil {
+		return err
+	}
+	// The result goes to stdout in full rather than in continue_output pages
+	if config.maxResponseBytes == 0 {
+		config.maxResponseBytes = -1
+	}
+
+	server, err := newServer(config)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer cleanup(server, done)
+	if err := server.initializeLSP(); err != nil {
+		return err
+	}
+	client := server.lspClient
+	if !client.ServerReady() {
+		if err := client.WaitForServerReady(server.ctx); err != nil {
+			return err
+		}
+	}
+	if err := server.registerMCP(); err != nil {
+		return err
+	}
+
+	tool, err := server.lookupTool(server.ctx, toolName)
+	if err != nil {
+		return err
+	}
+	arguments, err := queryArguments(tool, *file, *line, *col, *symbol, toolArgs)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		arguments["format"] = formatJSON
+	}
+
+	result, err := server.callTool(server.ctx, toolName, arguments)
+	if err != nil {
+		return err
+	}
+	var text strings.Builder
+	for _, content := range result.Content {
+		if textContent, ok := content.(mcp.TextContent); ok {
+			text.WriteString(textContent.Text)
+		}
+	}
+	if result.IsError {
+		return fmt.Errorf("%s: %s", toolName, text.String())
+	}
+	fmt.Fprintln(os.Stdout, strings.TrimRight(text.String(), "\n"))
+	return nil
+}
+
+// queryArguments builds the arguments of a tool call from the query flags. A
+// position is passed as filePath, line and column to tools that take them, and
+// as the name of the identifier under it to tools that only take a symbolName.
+func queryArguments(tool mcp.Tool, file string, line, col int, symbol string, toolArgs []string) (map[string]any, error) {
+	properties := tool.InputSchema.Properties
+	arguments := make(map[string]any)
+
+	if file != "" {
+		switch {
+		case properties["filePath"] != nil:
+			arguments["filePath"] = file
+			if line > 0 {
+				arguments["line"] = line
+			}
+			if col > 0 {
+				arguments["column"] = col
+			}
+		case properties["symbolName"] != nil && line > 0 && col > 0:
+			name, err := identifierAt(file, line, col)
+			if err != nil {
+				return nil, err
+			}
+			arguments["symbolName"] = name
+		default:
+			return nil, fmt.Errorf("%s does not take a file", tool.Name)
+		}
+	}
+	if symbol != "" {
+		if properties["symbolName"] == nil {
+			return nil, fmt.Errorf("%s does not take a symbol name", tool.Name)
+		}
+		arguments["symbolName"] = symbol
+	}
+
+	for _, arg := range toolArgs {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("--arg must be name=value: %s", arg)
+		}
+		if properties[name] == nil {
+			return nil, fmt.Errorf("%s has no argument %s", tool.Name, name)
+		}
+		var decoded any
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			decoded = value
+		}
+		arguments[name] = decoded
+	}
+
+	for _, name := range tool.InputSchema.Required {
+		if _, ok := arguments[name]; !ok {
+			return nil, fmt.Errorf("%s requires %s", tool.Name, name)
+		}
+	}
+	return arguments, nil
+}
+
+// identifierAt returns the identifier at a one-indexed line and column of a file
+func identifierAt(path string, line, col int) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	lines := strings.Split(string(content), "\n")
+	if line > len(lines) {
+		return "", fmt.Errorf("%s has %d lines", path, len(lines))
+	}
+	text := []rune(lines[line-1])
+	isIdent := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	if col > len(text) || !isIdent(text[col-1]) {
+		return "", fmt.Errorf("no identifier at %s:%d:%d", path, line, col)
+	}
+	start, end := col-1, col
+	for start > 0 && isIdent(text[start-1]) {
+		start--
+	}
+	for end < len(text) && isIdent(text[end]) {
+		end++
+	}
+	return string(text[start:end]), nil
+}
+
+// lookupTool finds a registered tool by name, listing the tools when there is none
+func (s *mcpServer) lookupTool(ctx context.Context, name string) (mcp.Tool, error) {
+	response := s.mcpServer.HandleMessage(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	listed, ok := response.(mcp.JSONRPCResponse)
+	if !ok {
+		return mcp.Tool{}, fmt.Errorf("failed to list tools: %+v", response)
+	}
+	var names []string
+	for _, tool := range listed.Result.(mcp.ListToolsResult).Tools {
+		if tool.Name == name {
+			return tool, nil
+		}
+		names = append(names, tool.Name)
+	}
+	sort.Strings(names)
+	return mcp.Tool{}, fmt.Errorf("unknown tool %q, the tools are: %s", name, strings.Join(names, ", "))
+}
+
+// callTool calls a registered tool as an MCP client would, through the hooks and
+// wrappers of the server
+func (s *mcpServer) callTool(ctx context.Context, name string, arguments map[string]any) (*mcp.CallToolResult, error) {
+	message, err := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      1,
+		"method":  mcp.MethodToolsCall,
+		"params":  map[string]any{"name": name, "arguments": arguments},
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch response := s.mcpServer.HandleMessage(ctx, message).(type) {
+	case mcp.JSONRPCResponse:
+		result := response.Result.(mcp.CallToolResult)
+		return &result, nil
+	case mcp.JSONRPCError:
+		return nil, fmt.Errorf("%s: %s", name, response.Error.Message)
+	default:
+		return nil, fmt.Errorf("%s: unexpected response %+v", name, response)
+	}
+}
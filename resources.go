@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/fileuri"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxListedResources caps how many workspace files are listed as resources. Files
+// beyond it can still be read through the file:// resource template.
+const maxListedResources = 5000
+
+// Source file types that the mime package does not know about
+var sourceMIMETypes = map[string]string{
+	".go":   "text/x-go",
+	".mod":  "text/plain",
+	".sum":  "text/plain",
+	".py":   "text/x-python",
+	".pyi":  "text/x-python",
+	".rs":   "text/x-rust",
+	".ts":   "text/x-typescript",
+	".tsx":  "text/x-typescript",
+	".jsx":  "text/javascript",
+	".c":    "text/x-c",
+	".h":    "text/x-c",
+	".cc":   "text/x-c++",
+	".cpp":  "text/x-c++",
+	".hpp":  "text/x-c++",
+	".java": "text/x-java",
+	".lua":  "text/x-lua",
+	".rb":   "text/x-ruby",
+	".md":   "text/markdown",
+	".toml": "application/toml",
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+}
+
+// registerResources exposes the files of the workspace as MCP resources, skipping
+// those excluded by .gitignore
+func (s *mcpServer) registerResources() {
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate("file://{+path}", "Workspace file",
+			mcp.WithTemplateDescription("A file within one of the workspace folders, by absolute path"),
+		),
+		s.readFileResource,
+	)
+
+	listed := 0
+	for _, root := range s.config.workspaceDirs {
+		err := watcher.WalkWorkspaceFiles(root, func(path string) error {
+			if listed >= maxListedResources {
+				return fs.SkipAll
+			}
+			listed++
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				rel = path
+			}
+			s.mcpServer.AddResource(
+				mcp.NewResource(string(fileuri.FromPath(path)), rel, mcp.WithMIMEType(mimeTypeFor(path, nil))),
+				s.readFileResource,
+			)
+			return nil
+		})
+		if err != nil {
+			coreLogger.Error("Failed to list workspace files in %s: %v", root, err)
+		}
+	}
+
+	if listed >= maxListedResources {
+		coreLogger.Warn("Listed the first %d workspace files as resources", maxListedResources)
+	} else {
+		coreLogger.Info("Listed %d workspace files as resources", listed)
+	}
+}
+
+// readFileResource reads a workspace file resource
+func (s *mcpServer) readFileResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := request.Params.URI
+	path, err := s.lspClient.ResolveWorkspacePath(fileuri.ToPath(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	root := ""
+	for _, dir := range s.lspClient.WorkspaceRoots() {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			root = dir
+			break
+		}
+	}
+	if root != "" {
+		if ignored, err := isIgnored(root, path, false); err != nil {
+			return nil, err
+		} else if ignored {
+			return nil, fmt.Errorf("%s is excluded by .gitignore", path)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	mimeType := mimeTypeFor(path, content)
+	if !utf8.Valid(content) {
+		return []mcp.ResourceContents{mcp.BlobResourceContents{
+			URI:      uri,
+			MIMEType: mimeType,
+			Blob:     base64.StdEncoding.EncodeToString(content),
+		}}, nil
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      uri,
+		MIMEType: mimeType,
+		Text:     string(content),
+	}}, nil
+}
+
+// isIgnored reports whether a path within root is excluded by .gitignore
+func isIgnored(root, path string, isDir bool) (bool, error) {
+	gitignore, err := watcher.NewGitignoreMatcher(root)
+	if err != nil {
+		return false, err
+	}
+	return gitignore.ShouldIgnore(path, isDir), nil
+}
+
+// mimeTypeFor guesses a file's MIME type from its extension, falling back to
+// sniffing its content when given
+func mimeTypeFor(path string, content []byte) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if mimeType, ok := sourceMIMETypes[ext]; ok {
+		return mimeType
+	}
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		return mimeType
+	}
+	if content != nil {
+		return http.DetectContentType(content)
+	}
+	return "text/plain"
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/isaacphi/mcp-language-server/internal/watcher"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// serverRoute is an entry of the config file's servers list: a language server
+// started alongside the primary one, which answers for the files matching its
+// globs. Its initialization options, readiness probe and save actions are read
+// from the entries of its name, as the primary server's are.
+type serverRoute struct {
+	// Name selects the server's initializer and config file entries (defaults
+	// to the command's binary name)
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	// Globs are matched against paths relative to a workspace root, such as
+	// **/*.proto
+	Globs []string `json:"globs"`
+	// Priority orders the servers whose globs match the same file, highest
+	// first. Servers of the same priority are tried in the order of the list,
+	// and files no server matches go to the primary server.
+	Priority int `json:"priority"`
+
+	initializationOptions map[string]any
+	readiness             lsp.ReadinessProbe
+	saveActions           lsp.SaveActions
+}
+
+// route is a started additional language server
+type route struct {
+	serverRoute
+	client  *lsp.Client
+	watcher *watcher.WorkspaceWatcher
+}
+
+// parseRoutes checks the servers of the config file and reads their entries,
+// ordering them by priority
+func parseRoutes(entries configFileEntries, allConfigs map[string]any, primary string) ([]serverRoute, error) {
+	routes := make([]serverRoute, 0, len(entries.Servers))
+	for i, route := range entries.Servers {
+		key := fmt.Sprintf("config file key servers[%d]", i)
+		if route.Command == "" {
+			return nil, fmt.Errorf("%s: command is required", key)
+		}
+		if len(route.Globs) == 0 {
+			return nil, fmt.Errorf("%s: globs are required to route files to %s", key, route.Command)
+		}
+		if route.Name == "" {
+			route.Name = extractLSPName(route.Command)
+		}
+		if route.Name == primary || slices.ContainsFunc(routes, func(other serverRoute) bool { return other.Name == route.Name }) {
+			return nil, fmt.Errorf("%s: another server is named %s; set a different name", key, route.Name)
+		}
+
+		if options, exists := allConfigs[route.Name]; exists {
+			optionsMap, ok := options.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("config file key %s: initialization options must be a JSON object", route.Name)
+			}
+			route.initializationOptions = optionsMap
+		}
+		if probe, exists := entries.Readiness[route.Name]; exists {
+			if err := probe.Validate(); err != nil {
+				return nil, fmt.Errorf("config file key readiness.%s: %v", route.Name, err)
+			}
+			route.readiness = probe
+		}
+		route.saveActions = entries.Save[route.Name]
+		routes = append(routes, route)
+	}
+
+	slices.SortStableFunc(routes, func(a, b serverRoute) int {
+		return b.Priority - a.Priority
+	})
+	return routes, nil
+}
+
+// startRoutes starts the additional language servers, each with a watcher of
+// the files matching its globs
+func (s *mcpServer) startRoutes() error {
+	for _, config := range s.config.routes {
+		client, err := lsp.NewClient(config.Command, config.Args...)
+		if err != nil {
+			return fmt.Errorf("failed to create LSP client for %s: %v", config.Name, err)
+		}
+		client.SetServerName(config.Name)
+		client.SetRestartHandler(s.notifyRestart)
+		client.SetMessageHandler(s.forwardServerMessage)
+		if s.metrics != nil {
+			client.SetRequestObserver(s.metrics.observeRequest)
+		}
+		client.SetProgressHandler(s.progress.forward)
+		client.SetReadinessProbe(config.readiness)
+		client.SetMaxOpenFiles(s.config.maxOpenFiles)
+		client.SetSaveActions(config.saveActions)
+		client.SetLanguageIDs(s.config.languageIDs)
+		client.SetSettings(s.config.settings)
+
+		watcherConfig := s.watcherConfig()
+		watcherConfig.FilePatterns = config.Globs
+		watcherConfig.IncludePatterns = config.Globs
+		r := &route{
+			serverRoute: config,
+			client:      client,
+			watcher:     watcher.NewWorkspaceWatcherWithConfig(client, watcherConfig),
+		}
+		// Added before initializing so that a failure still shuts the server down
+		s.routes = append(s.routes, r)
+
+		if _, err := client.InitializeLSPClient(s.ctx, s.config.workspaceDirs, config.initializationOptions); err != nil {
+			return fmt.Errorf("initialize failed for %s: %v", config.Name, err)
+		}
+		coreLogger.Info("Started %s for %v", config.Name, config.Globs)
+
+		if s.config.lspKeepalive > 0 {
+			go client.KeepAlive(s.ctx, time.Duration(s.config.lspKeepalive)*time.Second)
+		}
+		go r.watcher.WatchWorkspace(s.ctx, s.config.workspaceDirs...)
+	}
+	return nil
+}
+
+// defaultFallbackGlobs are documentation and configuration files, which language
+// servers for code rarely handle, used for a fallback.globs of default
+var defaultFallbackGlobs = []string{
+	"**/*.{md,markdown,rst,txt,adoc}",
+	"**/*.{yaml,yml,toml,ini,cfg,conf,properties,env}",
+	"**/*.csv",
+	"**/{LICENSE,CODEOWNERS,.gitignore,.gitattributes,.dockerignore,.editorconfig}",
+}
+
+// startFallback starts the built-in backend of the files of the fallback globs
+func (s *mcpServer) startFallback() error {
+	if len(s.config.fallbackGlobs) == 0 {
+		return nil
+	}
+	client := lsp.NewPlainTextClient()
+	client.SetMaxOpenFiles(s.config.maxOpenFiles)
+	if _, err := client.InitializeLSPClient(s.ctx, s.config.workspaceDirs, nil); err != nil {
+		return fmt.Errorf("failed to start the plain text fallback: %v", err)
+	}
+	s.fallback = client
+	return nil
+}
+
+// clientForPath returns the client of the highest priority server whose globs
+// match a file. Files no server's globs match go to the primary server, unless
+// they match the fallback globs and not the file patterns of the primary server.
+func (s *mcpServer) clientForPath(path string) *lsp.Client {
+	if len(s.routes) == 0 && s.fallback == nil {
+		return s.lspClient
+	}
+
+	path = s.lspClient.ResolvePath(path)
+	for _, root := range s.config.workspaceDirs {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, r := range s.routes {
+			if matchAny(r.Globs, rel) {
+				return r.client
+			}
+		}
+		if s.fallback != nil && matchAny(s.config.fallbackGlobs, rel) && !matchAny(s.config.filePatterns, rel) {
+			return s.fallback
+		}
+	}
+	return s.lspClient
+}
+
+// matchAny reports whether a slash separated path matches one of the globs
+func matchAny(globs []string, path string) bool {
+	for _, glob := range globs {
+		if utilities.MatchGlob(glob, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// routedClientKey is the context key of the client a tool call is routed to
+type routedClientKey struct{}
+
+// client returns the client a tool call is routed to
+func (s *mcpServer) client(ctx context.Context) *lsp.Client {
+	if client, ok := routedClient(ctx); ok {
+		return client
+	}
+	return s.lspClient
+}
+
+// routedClient returns the client of the file a tool call is about, if any
+func routedClient(ctx context.Context) (*lsp.Client, bool) {
+	client, ok := ctx.Value(routedClientKey{}).(*lsp.Client)
+	return client, ok
+}
+
+// clientsFor returns the clients of the servers that support a tool, the primary
+// one first, for tools that merge the results of every server. Without any, it
+// returns the primary client to report that the tool is not supported.
+func (s *mcpServer) clientsFor(name string) []*lsp.Client {
+	var clients []*lsp.Client
+	if clientSupports(s.lspClient, name) {
+		clients = append(clients, s.lspClient)
+	}
+	for _, r := range s.routes {
+		if clientSupports(r.client, name) {
+			clients = append(clients, r.client)
+		}
+	}
+	if len(clients) == 0 {
+		return []*lsp.Client{s.lspClient}
+	}
+	return clients
+}
+
+// filesByClient groups files by the server they are routed to
+func (s *mcpServer) filesByClient(files []string) map[*lsp.Client][]string {
+	grouped := make(map[*lsp.Client][]string)
+	for _, file := range files {
+		client := s.clientForPath(file)
+		grouped[client] = append(grouped[client], file)
+	}
+	return grouped
+}
+
+// routedPathArguments are the arguments naming the file a tool call is about
+var routedPathArguments = []string{"filePath", "oldPath"}
+
+// withRouting routes the calls of a tool to the server of the file they are
+// about. Calls without a file go to the primary server.
+func (s *mcpServer) withRouting(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if len(s.config.routes) == 0 && len(s.config.fallbackGlobs) == 0 {
+		return handler
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		for _, name := range routedPathArguments {
+			if path, ok := request.Params.Arguments[name].(string); ok && path != "" {
+				ctx = context.WithValue(ctx, routedClientKey{}, s.clientForPath(path))
+				break
+			}
+		}
+		return handler(ctx, request)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []serverRoute
+		configs map[string]any
+		want    []string
+		err     string
+	}{
+		{
+			name: "ordered by priority, then by the list",
+			servers: []serverRoute{
+				{Command: "/usr/bin/buf", Args: []string{"lsp"}, Globs: []string{"**/*.proto"}},
+				{Name: "sql", Command: "sqls", Globs: []string{"**/*.sql"}, Priority: 2},
+				{Command: "taplo", Globs: []string{"**/*.toml"}},
+				{Command: "marksman", Globs: []string{"**/*.md"}, Priority: 1},
+			},
+			want: []string{"sql", "marksman", "buf", "taplo"},
+		},
+		{
+			name:    "command is required",
+			servers: []serverRoute{{Globs: []string{"**/*.proto"}}},
+			err:     "servers[0]: command is required",
+		},
+		{
+			name:    "globs are required",
+			servers: []serverRoute{{Command: "buf"}},
+			err:     "servers[0]: globs are required",
+		},
+		{
+			name:    "name of the primary server",
+			servers: []serverRoute{{Command: "/opt/gopls", Globs: []string{"**/*.tmpl"}}},
+			err:     "servers[0]: another server is named gopls",
+		},
+		{
+			name: "name of another route",
+			servers: []serverRoute{
+				{Command: "buf", Globs: []string{"**/*.proto"}},
+				{Name: "buf", Command: "protols", Globs: []string{"**/*.proto"}},
+			},
+			err: "servers[1]: another server is named buf",
+		},
+		{
+			name:    "initialization options that are not an object",
+			servers: []serverRoute{{Command: "buf", Globs: []string{"**/*.proto"}}},
+			configs: map[string]any{"buf": "lsp"},
+			err:     "config file key buf: initialization options must be a JSON object",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := parseRoutes(configFileEntries{Servers: tt.servers}, tt.configs, "gopls")
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			var names []string
+			for _, route := range routes {
+				names = append(names, route.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestParseRoutesEntries(t *testing.T) {
+	entries := configFileEntries{
+		Servers:   []serverRoute{{Command: "buf", Globs: []string{"**/*.proto"}}},
+		Readiness: map[string]lsp.ReadinessProbe{"buf": {Strategy: lsp.ReadinessIdle}},
+		Save:      map[string]lsp.SaveActions{"buf": {WillSaveWaitUntil: true}},
+	}
+	routes, err := parseRoutes(entries, map[string]any{"buf": map[string]any{"lint": true}}, "gopls")
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	assert.Equal(t, map[string]any{"lint": true}, routes[0].initializationOptions)
+	assert.Equal(t, lsp.ReadinessIdle, routes[0].readiness.Strategy)
+	assert.True(t, routes[0].saveActions.WillSaveWaitUntil)
+
+	entries.Readiness["buf"] = lsp.ReadinessProbe{Strategy: "never"}
+	_, err = parseRoutes(entries, nil, "gopls")
+	assert.ErrorContains(t, err, "config file key readiness.buf")
+}
+
+func TestClientForPath(t *testing.T) {
+	primary := lsp.NewPlainTextClient()
+	proto := lsp.NewPlainTextClient()
+	sql := lsp.NewPlainTextClient()
+	generated := lsp.NewPlainTextClient()
+	fallback := lsp.NewPlainTextClient()
+
+	s := &mcpServer{
+		lspClient: primary,
+		// Highest priority first, as parseRoutes orders them
+		routes: []*route{
+			{serverRoute: serverRoute{Name: "generated", Globs: []string{"gen/**/*.sql"}, Priority: 1}, client: generated},
+			{serverRoute: serverRoute{Name: "buf", Globs: []string{"**/*.proto"}}, client: proto},
+			{serverRoute: serverRoute{Name: "sqls", Globs: []string{"**/*.sql"}}, client: sql},
+		},
+		fallback: fallback,
+	}
+	s.config.workspaceDirs = []string{"/work", "/other"}
+	s.config.fallbackGlobs = []string{"**/*.{md,yaml}"}
+	s.config.filePatterns = []string{"**/*.go", "deploy/*.yaml"}
+
+	tests := []struct {
+		path string
+		want *lsp.Client
+	}{
+		{"/work/main.go", primary},
+		{"/work/api/service.proto", proto},
+		{"/other/api/service.proto", proto},
+		{"/work/db/schema.sql", sql},
+		// The higher priority route wins where globs overlap
+		{"/work/gen/db/queries.sql", generated},
+		// Globs are relative to a workspace root
+		{"/elsewhere/api/service.proto", primary},
+		{"/work/README.md", fallback},
+		{"/work/config/app.yaml", fallback},
+		// The primary server's file patterns keep its files
+		{"/work/deploy/app.yaml", primary},
+		{"/work/notes.txt", primary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Same(t, tt.want, s.clientForPath(tt.path))
+		})
+	}
+}
+
+func TestClientForPathWithoutRoutes(t *testing.T) {
+	primary := lsp.NewPlainTextClient()
+	s := &mcpServer{lspClient: primary}
+	s.config.workspaceDirs = []string{"/work"}
+	s.config.fallbackGlobs = []string{"**/*.md"}
+
+	// Without a started fallback, fallback globs alone route nothing
+	assert.Same(t, primary, s.clientForPath("/work/README.md"))
+}
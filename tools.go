@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
 	"github.com/isaacphi/mcp-language-server/internal/tools"
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -12,7 +14,7 @@ func (s *mcpServer) registerTools() error {
 	coreLogger.Debug("Registering MCP tools")
 
 	applyTextEditTool := mcp.NewTool("edit_file",
-		mcp.WithDescription("Apply multiple text edits to a file."),
+		mcp.WithDescription("Apply multiple text edits to a file. The language server is notified of the change and the file's diagnostics are returned, so mistakes in the edit show up immediately."),
 		mcp.WithArray("edits",
 			mcp.Required(),
 			mcp.Description("List of edits to apply"),
@@ -27,6 +29,14 @@ func (s *mcpServer) registerTools() error {
 						"type":        "number",
 						"description": "End line to replace, inclusive, one-indexed",
 					},
+					"startColumn": map[string]any{
+						"type":        "number",
+						"description": "Start column on startLine, inclusive, one-indexed. Omit both columns to replace whole lines",
+					},
+					"endColumn": map[string]any{
+						"type":        "number",
+						"description": "End column on endLine, exclusive, one-indexed. Omit both columns to replace whole lines",
+					},
 					"newText": map[string]any{
 						"type":        "string",
 						"description": "Replacement text. Replace with the new text. Leave blank to remove lines.",
@@ -39,14 +49,26 @@ func (s *mcpServer) registerTools() error {
 			mcp.Required(),
 			mcp.Description("Path to the file to edit"),
 		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("stage",
+			mcp.Description("If true, stage the edits in the language server's copy of the file instead of writing them to disk, so several edits can be checked together before commit_staged writes them. Line numbers refer to the file with earlier staged edits applied."),
+			mcp.DefaultBool(false),
+		),
 	)
 
-	s.mcpServer.AddTool(applyTextEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(applyTextEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, ok := request.Params.Arguments["filePath"].(string)
 		if !ok {
 			return mcp.NewToolResultError("filePath must be a string"), nil
 		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
 		// Extract edits array
 		editsArg, ok := request.Params.Arguments["edits"]
@@ -77,17 +99,26 @@ func (s *mcpServer) registerTools() error {
 				return mcp.NewToolResultError("endLine must be a number"), nil
 			}
 
+			// Columns are optional
+			startColumn, _ := editMap["startColumn"].(float64)
+			endColumn, _ := editMap["endColumn"].(float64)
+
 			newText, _ := editMap["newText"].(string) // newText can be empty
 
 			edits = append(edits, tools.TextEdit{
-				StartLine: int(startLine),
-				EndLine:   int(endLine),
-				NewText:   newText,
+				StartLine:   int(startLine),
+				EndLine:     int(endLine),
+				StartColumn: int(startColumn),
+				EndColumn:   int(endColumn),
+				NewText:     newText,
 			})
 		}
 
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+		stage, _ := request.Params.Arguments["stage"].(bool)
+
 		coreLogger.Debug("Executing edit_file for file: %s", filePath)
-		response, err := tools.ApplyTextEdits(s.ctx, s.lspClient, filePath, edits)
+		response, err := tools.ApplyTextEditsWithDiagnostics(ctx, s.client(ctx), filePath, edits, dryRun, stage)
 		if err != nil {
 			coreLogger.Error("Failed to apply edits: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to apply edits: %v", err)), nil
@@ -95,274 +126,2763 @@ func (s *mcpServer) registerTools() error {
 		return mcp.NewToolResultText(response), nil
 	})
 
-	readDefinitionTool := mcp.NewTool("definition",
-		mcp.WithDescription("Read the source code definition of a symbol (function, type, constant, etc.) from the codebase. Returns the complete implementation code where the symbol is defined."),
-		mcp.WithString("symbolName",
-			mcp.Required(),
-			mcp.Description("The name of the symbol whose definition you want to find (e.g. 'mypackage.MyFunction', 'MyType.MyMethod')"),
+	commitStagedTool := mcp.NewTool("commit_staged",
+		mcp.WithDescription("Write the edits staged with edit_file's stage option to disk. Refuses for a file that changed on disk after its edits were staged."),
+		mcp.WithString("filePath",
+			mcp.Description("The file whose staged edits to write. Omit to write the staged edits of every file"),
 		),
 	)
 
-	s.mcpServer.AddTool(readDefinitionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(commitStagedTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
-		symbolName, ok := request.Params.Arguments["symbolName"].(string)
-		if !ok {
-			return mcp.NewToolResultError("symbolName must be a string"), nil
+		filePath, _ := request.Params.Arguments["filePath"].(string)
+		if filePath != "" {
+			var err error
+			if filePath, err = s.client(ctx).ResolveWorkspacePath(filePath); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
 		}
 
-		coreLogger.Debug("Executing definition for symbol: %s", symbolName)
-		text, err := tools.ReadDefinition(s.ctx, s.lspClient, symbolName)
+		coreLogger.Debug("Executing commit_staged for file: %s", filePath)
+		text, err := tools.CommitStaged(ctx, s.client(ctx), filePath)
 		if err != nil {
-			coreLogger.Error("Failed to get definition: %v", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to get definition: %v", err)), nil
+			coreLogger.Error("Failed to commit staged edits: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to commit staged edits: %v", err)), nil
 		}
 		return mcp.NewToolResultText(text), nil
 	})
 
-	findReferencesTool := mcp.NewTool("references",
-		mcp.WithDescription("Find all usages and references of a symbol throughout the codebase. Returns a list of all files and locations where the symbol appears."),
-		mcp.WithString("symbolName",
-			mcp.Required(),
-			mcp.Description("The name of the symbol to search for (e.g. 'mypackage.MyFunction', 'MyType')"),
+	discardStagedTool := mcp.NewTool("discard_staged",
+		mcp.WithDescription("Drop the edits staged with edit_file's stage option, so the language server sees the file on disk again."),
+		mcp.WithString("filePath",
+			mcp.Description("The file whose staged edits to drop. Omit to drop the staged edits of every file"),
 		),
 	)
 
-	s.mcpServer.AddTool(findReferencesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(discardStagedTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
-		symbolName, ok := request.Params.Arguments["symbolName"].(string)
-		if !ok {
-			return mcp.NewToolResultError("symbolName must be a string"), nil
+		filePath, _ := request.Params.Arguments["filePath"].(string)
+		if filePath != "" {
+			var err error
+			if filePath, err = s.client(ctx).ResolveWorkspacePath(filePath); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
 		}
 
-		coreLogger.Debug("Executing references for symbol: %s", symbolName)
-		text, err := tools.FindReferences(s.ctx, s.lspClient, symbolName)
+		coreLogger.Debug("Executing discard_staged for file: %s", filePath)
+		text, err := tools.DiscardStaged(ctx, s.client(ctx), filePath)
 		if err != nil {
-			coreLogger.Error("Failed to find references: %v", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
+			coreLogger.Error("Failed to discard staged edits: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to discard staged edits: %v", err)), nil
 		}
 		return mcp.NewToolResultText(text), nil
 	})
 
-	getDiagnosticsTool := mcp.NewTool("diagnostics",
-		mcp.WithDescription("Get diagnostic information for a specific file from the language server."),
+	replaceSymbolBodyTool := mcp.NewTool("replace_symbol_body",
+		mcp.WithDescription("Replace the whole definition of a symbol, such as a function, method or type, found by name in a file. This avoids counting line numbers. Returns the file's diagnostics after the edit."),
 		mcp.WithString("filePath",
 			mcp.Required(),
-			mcp.Description("The path to the file to get diagnostics for"),
+			mcp.Description("The path to the file containing the symbol"),
 		),
-		mcp.WithBoolean("contextLines",
-			mcp.Description("Lines to include around each diagnostic."),
-			mcp.DefaultBool(false),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name or qualified path of the symbol (e.g. 'MyFunction', 'MyType.MyMethod', 'MyClass::method')"),
 		),
-		mcp.WithBoolean("showLineNumbers",
-			mcp.Description("If true, adds line numbers to the output"),
-			mcp.DefaultBool(true),
+		mcp.WithString("newSource",
+			mcp.Required(),
+			mcp.Description("The complete new definition of the symbol, including its signature"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
 		),
 	)
 
-	s.mcpServer.AddTool(getDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(replaceSymbolBodyTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, ok := request.Params.Arguments["filePath"].(string)
 		if !ok {
 			return mcp.NewToolResultError("filePath must be a string"), nil
 		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
-		contextLines := 5 // default value
-		if contextLinesArg, ok := request.Params.Arguments["contextLines"].(int); ok {
-			contextLines = contextLinesArg
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("symbolName must be a string"), nil
 		}
 
-		showLineNumbers := true // default value
-		if showLineNumbersArg, ok := request.Params.Arguments["showLineNumbers"].(bool); ok {
-			showLineNumbers = showLineNumbersArg
+		newSource, ok := request.Params.Arguments["newSource"].(string)
+		if !ok {
+			return mcp.NewToolResultError("newSource must be a string"), nil
 		}
 
-		coreLogger.Debug("Executing diagnostics for file: %s", filePath)
-		text, err := tools.GetDiagnosticsForFile(s.ctx, s.lspClient, filePath, contextLines, showLineNumbers)
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing replace_symbol_body for symbol: %s file: %s", symbolName, filePath)
+		text, err := tools.ReplaceSymbolBody(ctx, s.client(ctx), filePath, symbolName, newSource, dryRun)
 		if err != nil {
-			coreLogger.Error("Failed to get diagnostics: %v", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to get diagnostics: %v", err)), nil
+			coreLogger.Error("Failed to replace symbol body: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to replace symbol body: %v", err)), nil
 		}
 		return mcp.NewToolResultText(text), nil
 	})
 
-	// Uncomment to add codelens tools
-	//
-	// getCodeLensTool := mcp.NewTool("get_codelens",
-	// 	mcp.WithDescription("Get code lens hints for a given file from the language server."),
-	// 	mcp.WithString("filePath",
-	// 		mcp.Required(),
-	// 		mcp.Description("The path to the file to get code lens information for"),
-	// 	),
-	// )
-	//
-	// s.mcpServer.AddTool(getCodeLensTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	// 	// Extract arguments
-	// 	filePath, ok := request.Params.Arguments["filePath"].(string)
-	// 	if !ok {
-	// 		return mcp.NewToolResultError("filePath must be a string"), nil
-	// 	}
-	//
-	// 	coreLogger.Debug("Executing get_codelens for file: %s", filePath)
-	// 	text, err := tools.GetCodeLens(s.ctx, s.lspClient, filePath)
-	// 	if err != nil {
-	// 		coreLogger.Error("Failed to get code lens: %v", err)
-	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to get code lens: %v", err)), nil
-	// 	}
-	// 	return mcp.NewToolResultText(text), nil
-	// })
-	//
-	// executeCodeLensTool := mcp.NewTool("execute_codelens",
-	// 	mcp.WithDescription("Execute a code lens command for a given file and lens index."),
-	// 	mcp.WithString("filePath",
-	// 		mcp.Required(),
-	// 		mcp.Description("The path to the file containing the code lens to execute"),
-	// 	),
-	// 	mcp.WithNumber("index",
-	// 		mcp.Required(),
-	// 		mcp.Description("The index of the code lens to execute (from get_codelens output), 1 indexed"),
-	// 	),
-	// )
-	//
-	// s.mcpServer.AddTool(executeCodeLensTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	// 	// Extract arguments
-	// 	filePath, ok := request.Params.Arguments["filePath"].(string)
-	// 	if !ok {
-	// 		return mcp.NewToolResultError("filePath must be a string"), nil
-	// 	}
-	//
-	// 	// Handle both float64 and int for index due to JSON parsing
-	// 	var index int
-	// 	switch v := request.Params.Arguments["index"].(type) {
-	// 	case float64:
-	// 		index = int(v)
-	// 	case int:
-	// 		index = v
-	// 	default:
-	// 		return mcp.NewToolResultError("index must be a number"), nil
-	// 	}
-	//
-	// 	coreLogger.Debug("Executing execute_codelens for file: %s index: %d", filePath, index)
-	// 	text, err := tools.ExecuteCodeLens(s.ctx, s.lspClient, filePath, index)
-	// 	if err != nil {
-	// 		coreLogger.Error("Failed to execute code lens: %v", err)
-	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to execute code lens: %v", err)), nil
-	// 	}
-	// 	return mcp.NewToolResultText(text), nil
-	// })
-
-	hoverTool := mcp.NewTool("hover",
-		mcp.WithDescription("Get hover information (type, documentation) for a symbol at the specified position."),
+	insertNearSymbolTool := mcp.NewTool("insert_near_symbol",
+		mcp.WithDescription("Insert code on its own lines immediately before or after a symbol, such as a function, method or type, found by name in a file. This avoids counting line numbers. Code inserted before a symbol goes above its doc comments. Returns the file's diagnostics after the edit."),
 		mcp.WithString("filePath",
 			mcp.Required(),
-			mcp.Description("The path to the file to get hover information for"),
+			mcp.Description("The path to the file containing the symbol"),
 		),
-		mcp.WithNumber("line",
+		mcp.WithString("symbolName",
 			mcp.Required(),
-			mcp.Description("The line number where the hover is requested (1-indexed)"),
+			mcp.Description("The name or qualified path of the symbol (e.g. 'MyFunction', 'MyType.MyMethod', 'MyClass::method')"),
 		),
-		mcp.WithNumber("column",
+		mcp.WithString("position",
 			mcp.Required(),
-			mcp.Description("The column number where the hover is requested (1-indexed)"),
+			mcp.Description("Where to insert the code relative to the symbol"),
+			mcp.Enum("before", "after"),
+		),
+		mcp.WithString("source",
+			mcp.Required(),
+			mcp.Description("The code to insert"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
 		),
 	)
 
-	s.mcpServer.AddTool(hoverTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(insertNearSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, ok := request.Params.Arguments["filePath"].(string)
 		if !ok {
 			return mcp.NewToolResultError("filePath must be a string"), nil
 		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
-		// Handle both float64 and int for line and column due to JSON parsing
-		var line, column int
-		switch v := request.Params.Arguments["line"].(type) {
-		case float64:
-			line = int(v)
-		case int:
-			line = v
-		default:
-			return mcp.NewToolResultError("line must be a number"), nil
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("symbolName must be a string"), nil
 		}
 
-		switch v := request.Params.Arguments["column"].(type) {
-		case float64:
-			column = int(v)
-		case int:
-			column = v
-		default:
-			return mcp.NewToolResultError("column must be a number"), nil
+		position, _ := request.Params.Arguments["position"].(string)
+		if position != "before" && position != "after" {
+			return mcp.NewToolResultError("position must be before or after"), nil
 		}
 
-		coreLogger.Debug("Executing hover for file: %s line: %d column: %d", filePath, line, column)
-		text, err := tools.GetHoverInfo(s.ctx, s.lspClient, filePath, line, column)
+		source, ok := request.Params.Arguments["source"].(string)
+		if !ok {
+			return mcp.NewToolResultError("source must be a string"), nil
+		}
+
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing insert_near_symbol %s symbol: %s file: %s", position, symbolName, filePath)
+		text, err := tools.InsertNearSymbol(ctx, s.client(ctx), filePath, symbolName, source, position == "before", dryRun)
 		if err != nil {
-			coreLogger.Error("Failed to get hover information: %v", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to get hover information: %v", err)), nil
+			coreLogger.Error("Failed to insert code: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to insert code: %v", err)), nil
 		}
 		return mcp.NewToolResultText(text), nil
 	})
 
-	renameSymbolTool := mcp.NewTool("rename_symbol",
-		mcp.WithDescription("Rename a symbol (variable, function, class, etc.) at the specified position and update all references throughout the codebase."),
+	readDefinitionTool := mcp.NewTool("definition",
+		mcp.WithDescription("Read the source code definition of a symbol (function, type, constant, etc.) from the codebase. Returns the complete implementation code where the symbol is defined. Look the symbol up by name with symbolName, or go to the definition of the symbol at a position with filePath, line and column."),
+		mcp.WithString("symbolName",
+			mcp.Description("The name of the symbol whose definition you want to find (e.g. 'mypackage.MyFunction', 'MyType.MyMethod', 'internal/lsp.Client.OpenFile')"),
+		),
 		mcp.WithString("filePath",
-			mcp.Required(),
-			mcp.Description("The path to the file containing the symbol to rename"),
+			mcp.Description("The path to a file using the symbol, instead of symbolName"),
 		),
 		mcp.WithNumber("line",
-			mcp.Required(),
-			mcp.Description("The line number where the symbol is located (1-indexed)"),
+			mcp.Description("The line number where the symbol is used in filePath (1-indexed)"),
 		),
 		mcp.WithNumber("column",
-			mcp.Required(),
-			mcp.Description("The column number where the symbol is located (1-indexed)"),
+			mcp.Description("The column number where the symbol is used in filePath (1-indexed)"),
 		),
-		mcp.WithString("newName",
-			mcp.Required(),
-			mcp.Description("The new name for the symbol"),
+		mcp.WithBoolean("fullBody",
+			mcp.Description("When going to a definition from a position, return the entire enclosing function or type body rather than just the declaration line"),
+			mcp.DefaultBool(true),
 		),
 	)
 
-	s.mcpServer.AddTool(renameSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(readDefinitionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
-		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if filePath, ok := request.Params.Arguments["filePath"].(string); ok && filePath != "" {
+			filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			// Handle both float64 and int for line and column due to JSON parsing
+			var line, column int
+			switch v := request.Params.Arguments["line"].(type) {
+			case float64:
+				line = int(v)
+			case int:
+				line = v
+			default:
+				return mcp.NewToolResultError("line must be a number"), nil
+			}
+
+			switch v := request.Params.Arguments["column"].(type) {
+			case float64:
+				column = int(v)
+			case int:
+				column = v
+			default:
+				return mcp.NewToolResultError("column must be a number"), nil
+			}
+
+			fullBody := true // default value
+			if fullBodyArg, ok := request.Params.Arguments["fullBody"].(bool); ok {
+				fullBody = fullBodyArg
+			}
+
+			coreLogger.Debug("Executing definition for file: %s line: %d column: %d", filePath, line, column)
+			if wantsJSON(request) {
+				result, err := tools.CollectDefinitions(ctx, s.client(ctx), filePath, line, column)
+				if err != nil {
+					coreLogger.Error("Failed to get definition: %v", err)
+					return mcp.NewToolResultError(fmt.Sprintf("failed to get definition: %v", err)), nil
+				}
+				return jsonResult(result)
+			}
+			text, err := tools.ReadDefinitionAtPosition(ctx, s.client(ctx), filePath, line, column, fullBody)
+			if err != nil {
+				coreLogger.Error("Failed to get definition: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to get definition: %v", err)), nil
+			}
+			return mcp.NewToolResultText(text), nil
+		}
+
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
 		if !ok {
-			return mcp.NewToolResultError("filePath must be a string"), nil
+			return mcp.NewToolResultError("symbolName or filePath must be a string"), nil
 		}
 
-		newName, ok := request.Params.Arguments["newName"].(string)
+		coreLogger.Debug("Executing definition for symbol: %s", symbolName)
+		if wantsJSON(request) {
+			result, err := tools.CollectDefinitionsByName(ctx, s.client(ctx), symbolName)
+			if err != nil {
+				coreLogger.Error("Failed to get definition: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to get definition: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.ReadDefinition(ctx, s.client(ctx), symbolName)
+		if err != nil {
+			coreLogger.Error("Failed to get definition: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get definition: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	readSymbolTool := mcp.NewTool("read_symbol",
+		mcp.WithDescription("Read the source of a single symbol, such as one function, method or type, instead of the whole file. The symbol is found with the file's document symbols, or by searching the workspace when no file is given."),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name or qualified path of the symbol (e.g. 'MyFunction', 'MyType.MyMethod', 'mypackage.MyType.MyMethod', 'MyClass::method')"),
+		),
+		mcp.WithString("filePath",
+			mcp.Description("The path to the file containing the symbol. If omitted, the workspace is searched."),
+		),
+	)
+
+	s.addTool(readSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
 		if !ok {
-			return mcp.NewToolResultError("newName must be a string"), nil
+			return mcp.NewToolResultError("symbolName must be a string"), nil
 		}
 
-		// Handle both float64 and int for line and column due to JSON parsing
-		var line, column int
-		switch v := request.Params.Arguments["line"].(type) {
-		case float64:
-			line = int(v)
-		case int:
-			line = v
-		default:
-			return mcp.NewToolResultError("line must be a number"), nil
+		filePath, _ := request.Params.Arguments["filePath"].(string)
+		if filePath != "" {
+			var err error
+			if filePath, err = s.client(ctx).ResolveWorkspacePath(filePath); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
 		}
 
-		switch v := request.Params.Arguments["column"].(type) {
+		coreLogger.Debug("Executing read_symbol for symbol: %s file: %s", symbolName, filePath)
+		text, err := tools.ReadSymbol(ctx, s.client(ctx), filePath, symbolName)
+		if err != nil {
+			coreLogger.Error("Failed to read symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read symbol: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	findAndReadTool := mcp.NewTool("find_and_read",
+		mcp.WithDescription("Search the workspace for a symbol and return its definition source, hover information and the diagnostics within it in one call. When several symbols match equally well, they are listed and one can be picked with choice."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("The symbol name to search for (e.g. 'MyFunction', 'MyType.MyMethod')"),
+		),
+		mcp.WithNumber("choice",
+			mcp.Description("Which of the listed candidates to read (1-indexed), when a previous call returned several"),
+		),
+	)
+
+	s.addTool(findAndReadTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		query, ok := request.Params.Arguments["query"].(string)
+		if !ok {
+			return mcp.NewToolResultError("query must be a string"), nil
+		}
+
+		choice := 0
+		switch v := request.Params.Arguments["choice"].(type) {
 		case float64:
-			column = int(v)
+			choice = int(v)
 		case int:
-			column = v
-		default:
-			return mcp.NewToolResultError("column must be a number"), nil
+			choice = v
 		}
 
-		coreLogger.Debug("Executing rename_symbol for file: %s line: %d column: %d newName: %s", filePath, line, column, newName)
-		text, err := tools.RenameSymbol(s.ctx, s.lspClient, filePath, line, column, newName)
+		coreLogger.Debug("Executing find_and_read for query: %s", query)
+		text, err := tools.FindAndRead(ctx, s.client(ctx), query, choice)
 		if err != nil {
-			coreLogger.Error("Failed to rename symbol: %v", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to rename symbol: %v", err)), nil
+			coreLogger.Error("Failed to find and read symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to find and read symbol: %v", err)), nil
 		}
 		return mcp.NewToolResultText(text), nil
 	})
 
+	findReferencesTool := mcp.NewTool("references",
+		mcp.WithDescription("Find all usages and references of a symbol throughout the codebase. Returns a list of all files and locations where the symbol appears."),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name of the symbol to search for (e.g. 'mypackage.MyFunction', 'MyType', 'internal/lsp.Client.OpenFile')"),
+		),
+		mcp.WithNumber("contextBefore",
+			mcp.Description("Lines of context to show before each reference (defaults to LSP_CONTEXT_LINES or 5)"),
+		),
+		mcp.WithNumber("contextAfter",
+			mcp.Description("Lines of context to show after each reference (defaults to LSP_CONTEXT_LINES or 5)"),
+		),
+		mcp.WithString("groupBy",
+			mcp.Description("'file' to group references under a header per file, or 'none' for a flat list of references"),
+			mcp.Enum("file", "none"),
+			mcp.DefaultString("file"),
+		),
+		mcp.WithBoolean("includeDeclaration",
+			mcp.Description("If true, also return the declaration of the symbol"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of references to return. When there are more, the result ends with a cursor for the next page."),
+			mcp.DefaultNumber(200),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("Cursor returned by a previous call, to continue with the next page of references"),
+		),
+		mcp.WithBoolean("textFallback",
+			mcp.Description("If true and the language server finds no references, as it may not while indexing, return the lines containing the identifier instead, marked as textual matches (unverified)"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(findReferencesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("symbolName must be a string"), nil
+		}
+
+		opts := tools.DefaultReferenceOptions()
+		opts.Limit = 200 // default value
+		switch v := request.Params.Arguments["contextBefore"].(type) {
+		case float64:
+			opts.ContextBefore = int(v)
+		case int:
+			opts.ContextBefore = v
+		}
+		switch v := request.Params.Arguments["contextAfter"].(type) {
+		case float64:
+			opts.ContextAfter = int(v)
+		case int:
+			opts.ContextAfter = v
+		}
+		if groupBy, ok := request.Params.Arguments["groupBy"].(string); ok {
+			switch groupBy {
+			case "file":
+				opts.GroupByFile = true
+			case "none":
+				opts.GroupByFile = false
+			default:
+				return mcp.NewToolResultError("groupBy must be 'file' or 'none'"), nil
+			}
+		}
+		if includeDeclaration, ok := request.Params.Arguments["includeDeclaration"].(bool); ok {
+			opts.IncludeDeclaration = includeDeclaration
+		}
+		switch v := request.Params.Arguments["limit"].(type) {
+		case float64:
+			opts.Limit = int(v)
+		case int:
+			opts.Limit = v
+		}
+		if cursor, ok := request.Params.Arguments["cursor"].(string); ok {
+			opts.Cursor = cursor
+		}
+		if textFallback, ok := request.Params.Arguments["textFallback"].(bool); ok {
+			opts.TextFallback = textFallback
+		}
+
+		coreLogger.Debug("Executing references for symbol: %s", symbolName)
+		if clients := s.clientsFor("references"); len(clients) > 1 {
+			if wantsJSON(request) {
+				result, err := tools.CollectReferencesAcross(ctx, clients, symbolName, opts)
+				if err != nil {
+					coreLogger.Error("Failed to find references: %v", err)
+					return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
+				}
+				return jsonResult(result)
+			}
+			text, err := tools.FindReferencesAcross(ctx, clients, symbolName, opts)
+			if err != nil {
+				coreLogger.Error("Failed to find references: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
+			}
+			return mcp.NewToolResultText(text), nil
+		}
+		if wantsJSON(request) {
+			result, err := tools.CollectReferences(ctx, s.client(ctx), symbolName, opts)
+			if err != nil {
+				coreLogger.Error("Failed to find references: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.FindReferencesWithOptions(ctx, s.client(ctx), symbolName, opts)
+		if err != nil {
+			coreLogger.Error("Failed to find references: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	findImplementationsTool := mcp.NewTool("find_implementations",
+		mcp.WithDescription("Find all implementations of an interface, abstract type or method throughout the codebase. Returns each implementation with surrounding code context."),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name of the interface, type or method to find implementations of (e.g. 'mypackage.MyInterface', 'MyInterface.Method')"),
+		),
+	)
+
+	s.addTool(findImplementationsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		symbolName, ok := request.Params.Arguments["symbolName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("symbolName must be a string"), nil
+		}
+
+		coreLogger.Debug("Executing find_implementations for symbol: %s", symbolName)
+		if wantsJSON(request) {
+			result, err := tools.CollectImplementations(ctx, s.client(ctx), symbolName)
+			if err != nil {
+				coreLogger.Error("Failed to find implementations: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to find implementations: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.FindImplementations(ctx, s.client(ctx), symbolName)
+		if err != nil {
+			coreLogger.Error("Failed to find implementations: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to find implementations: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	projectOverviewTool := mcp.NewTool("project_overview",
+		mcp.WithDescription("Get an orientation snapshot of the workspace: file counts per language, the modules and packages it defines (go.mod, Cargo.toml, package.json and similar), the entry points of its programs, its top-level directories, and the main exported symbols of each package. Files excluded by .gitignore are skipped. Use this first when starting work on an unfamiliar project."),
+		mcp.WithNumber("symbolsPerPackage",
+			mcp.Description("How many exported symbols to list for each package, types first. 0 leaves symbols out, which is faster."),
+			mcp.DefaultNumber(10),
+		),
+	)
+
+	s.addTool(projectOverviewTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		symbolsPerPackage := 10 // default value
+		switch v := request.Params.Arguments["symbolsPerPackage"].(type) {
+		case float64:
+			symbolsPerPackage = int(v)
+		case int:
+			symbolsPerPackage = v
+		}
+
+		coreLogger.Debug("Executing project_overview with %d symbols per package", symbolsPerPackage)
+		text, err := tools.ProjectOverview(ctx, s.client(ctx), symbolsPerPackage)
+		if err != nil {
+			coreLogger.Error("Failed to get project overview: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get project overview: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	workspaceSymbolsTool := mcp.NewTool("workspace_symbols",
+		mcp.WithDescription("Search for symbols (functions, types, constants, etc.) across the whole workspace. Returns each match with its kind, container, file and range, so declarations can be found without knowing which file they are in."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("The symbol name or fuzzy query to search for (e.g. 'MyFunc', 'mpfn')"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of results to return"),
+			mcp.DefaultNumber(50),
+		),
+		mcp.WithBoolean("exactMatch",
+			mcp.Description("If true, only return symbols whose name exactly matches the query"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(workspaceSymbolsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		query, ok := request.Params.Arguments["query"].(string)
+		if !ok {
+			return mcp.NewToolResultError("query must be a string"), nil
+		}
+
+		limit := 50 // default value
+		switch v := request.Params.Arguments["limit"].(type) {
+		case float64:
+			limit = int(v)
+		case int:
+			limit = v
+		}
+
+		exactMatch := false // default value
+		if exactMatchArg, ok := request.Params.Arguments["exactMatch"].(bool); ok {
+			exactMatch = exactMatchArg
+		}
+
+		coreLogger.Debug("Executing workspace_symbols for query: %s", query)
+		if clients := s.clientsFor("workspace_symbols"); len(clients) > 1 {
+			if wantsJSON(request) {
+				result, err := tools.CollectWorkspaceSymbolsAcross(ctx, clients, query, limit, exactMatch)
+				if err != nil {
+					coreLogger.Error("Failed to search workspace symbols: %v", err)
+					return mcp.NewToolResultError(fmt.Sprintf("failed to search workspace symbols: %v", err)), nil
+				}
+				return jsonResult(result)
+			}
+			text, err := tools.SearchWorkspaceSymbolsAcross(ctx, clients, query, limit, exactMatch)
+			if err != nil {
+				coreLogger.Error("Failed to search workspace symbols: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to search workspace symbols: %v", err)), nil
+			}
+			return mcp.NewToolResultText(text), nil
+		}
+		if wantsJSON(request) {
+			result, err := tools.CollectWorkspaceSymbols(ctx, s.client(ctx), query, limit, exactMatch)
+			if err != nil {
+				coreLogger.Error("Failed to search workspace symbols: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to search workspace symbols: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.SearchWorkspaceSymbols(ctx, s.client(ctx), query, limit, exactMatch)
+		if err != nil {
+			coreLogger.Error("Failed to search workspace symbols: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to search workspace symbols: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	resolveSymbolTool := mcp.NewTool("resolve_symbol",
+		mcp.WithDescription("Find where a symbol given by its qualified name is declared, such as 'internal/lsp.Client.OpenFile', 'lsp.Client.OpenFile' or 'Namespace::Class::method'. Returns the position of each matching symbol's name, best match first, ready to pass to tools that take a position. Leading qualifiers that match no symbol and differences in case are tolerated and reported as partial matches."),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("The qualified name of the symbol. A slash separated prefix such as 'internal/lsp' narrows the search to files in a matching directory."),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of symbols to return"),
+			mcp.DefaultNumber(10),
+		),
+	)
+
+	s.addTool(resolveSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok {
+			return mcp.NewToolResultError("name must be a string"), nil
+		}
+
+		limit := 10
+		if v, ok := request.Params.Arguments["limit"].(float64); ok {
+			limit = int(v)
+		}
+
+		coreLogger.Debug("Executing resolve_symbol for name: %s", name)
+		resolution, err := tools.CollectResolveSymbol(ctx, s.client(ctx), name, limit)
+		if err != nil {
+			coreLogger.Error("Failed to resolve symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to resolve symbol: %v", err)), nil
+		}
+		if wantsJSON(request) {
+			return jsonResult(resolution)
+		}
+		return mcp.NewToolResultText(tools.ResolveSymbol(resolution)), nil
+	})
+
+	grepTool := mcp.NewTool("grep",
+		mcp.WithDescription("Search the text of the workspace files for a regular expression, like ripgrep, skipping files excluded by .gitignore, binary files and very large files. Each matching line is shown with the innermost symbol enclosing it, such as the function or type, found with the language server, so text hits can be mapped to code in one call."),
+		mcp.WithString("pattern",
+			mcp.Required(),
+			mcp.Description("The regular expression to search for, in RE2 syntax (e.g. 'func \\w+Handler', 'TODO|FIXME')"),
+		),
+		mcp.WithString("include",
+			mcp.Description("Only search files whose path relative to the workspace matches this glob (e.g. '**/*.go', 'internal/**')"),
+		),
+		mcp.WithBoolean("ignoreCase",
+			mcp.Description("If true, match without regard to case"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("symbols",
+			mcp.Description("If true, show the symbol enclosing each match. False is faster for broad searches."),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of matching lines to return"),
+			mcp.DefaultNumber(100),
+		),
+	)
+
+	s.addTool(grepTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		pattern, ok := request.Params.Arguments["pattern"].(string)
+		if !ok {
+			return mcp.NewToolResultError("pattern must be a string"), nil
+		}
+
+		opts := tools.GrepOptions{Symbols: true, Limit: 100}
+		if include, ok := request.Params.Arguments["include"].(string); ok {
+			opts.Include = include
+		}
+		if ignoreCase, ok := request.Params.Arguments["ignoreCase"].(bool); ok {
+			opts.IgnoreCase = ignoreCase
+		}
+		if symbols, ok := request.Params.Arguments["symbols"].(bool); ok {
+			opts.Symbols = symbols
+		}
+		switch v := request.Params.Arguments["limit"].(type) {
+		case float64:
+			opts.Limit = int(v)
+		case int:
+			opts.Limit = v
+		}
+
+		coreLogger.Debug("Executing grep for pattern: %s", pattern)
+		result, err := tools.CollectGrep(ctx, s.client(ctx), pattern, opts)
+		if err != nil {
+			coreLogger.Error("Failed to search files: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to search files: %v", err)), nil
+		}
+		if wantsJSON(request) {
+			return jsonResult(result)
+		}
+		return mcp.NewToolResultText(tools.Grep(result)), nil
+	})
+
+	getDiagnosticsTool := mcp.NewTool("diagnostics",
+		mcp.WithDescription("Get diagnostic information for a specific file from the language server."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to get diagnostics for"),
+		),
+		mcp.WithBoolean("contextLines",
+			mcp.Description("Lines to include around each diagnostic."),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("showLineNumbers",
+			mcp.Description("If true, adds line numbers to the output"),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithString("severity",
+			mcp.Description("Only include diagnostics at least this severe"),
+			mcp.Enum("error", "warning", "info", "hint"),
+		),
+		mcp.WithArray("sources",
+			mcp.Description("Only include diagnostics from these sources, such as compiler or go vet"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("codes",
+			mcp.Description("Only include diagnostics with these codes"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithBoolean("includeFixes",
+			mcp.Description("If true, list the quick fixes the server offers for each diagnostic, with the arguments to apply them with apply_code_action"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(getDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		contextLines := 5 // default value
+		if contextLinesArg, ok := request.Params.Arguments["contextLines"].(int); ok {
+			contextLines = contextLinesArg
+		}
+
+		showLineNumbers := true // default value
+		if showLineNumbersArg, ok := request.Params.Arguments["showLineNumbers"].(bool); ok {
+			showLineNumbers = showLineNumbersArg
+		}
+
+		filter, err := diagnosticFilter(request.Params.Arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Servers without code actions have no quick fixes to offer
+		includeFixes, _ := request.Params.Arguments["includeFixes"].(bool)
+		includeFixes = includeFixes && s.client(ctx).SupportsMethod("textDocument/codeAction")
+
+		coreLogger.Debug("Executing diagnostics for file: %s", filePath)
+		if wantsJSON(request) {
+			result, err := tools.CollectFileDiagnostics(ctx, s.client(ctx), filePath, filter, includeFixes)
+			if err != nil {
+				coreLogger.Error("Failed to get diagnostics: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to get diagnostics: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.GetDiagnosticsForFile(ctx, s.client(ctx), filePath, contextLines, showLineNumbers, filter, includeFixes)
+		if err != nil {
+			coreLogger.Error("Failed to get diagnostics: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get diagnostics: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	batchDiagnosticsTool := mcp.NewTool("batch_diagnostics",
+		mcp.WithDescription("Get the diagnostics of several files in one call, given as a list of paths, a glob or both. The files are opened together and the server is waited on once, which is much faster than calling diagnostics for each file. Use it to verify changes spanning several files."),
+		mcp.WithArray("filePaths",
+			mcp.Description("The paths of the files to check"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("glob",
+			mcp.Description("A glob matched against paths relative to each workspace root, such as internal/**/*.go"),
+		),
+		mcp.WithNumber("maxFiles",
+			mcp.Description("The most files to check"),
+			mcp.DefaultNumber(50),
+		),
+		mcp.WithString("severity",
+			mcp.Description("Only include diagnostics at least this severe"),
+			mcp.Enum("error", "warning", "info", "hint"),
+		),
+		mcp.WithArray("sources",
+			mcp.Description("Only include diagnostics from these sources, such as compiler or go vet"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("codes",
+			mcp.Description("Only include diagnostics with these codes"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+	)
+
+	s.addTool(batchDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		var filePaths []string
+		if paths, ok := request.Params.Arguments["filePaths"].([]any); ok {
+			for _, path := range paths {
+				filePath, ok := path.(string)
+				if !ok {
+					return mcp.NewToolResultError("filePaths must be a list of strings"), nil
+				}
+				filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+				if err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+				filePaths = append(filePaths, filePath)
+			}
+		}
+		glob, _ := request.Params.Arguments["glob"].(string)
+		if len(filePaths) == 0 && glob == "" {
+			return mcp.NewToolResultError("filePaths or glob is required"), nil
+		}
+
+		maxFiles := 0
+		switch v := request.Params.Arguments["maxFiles"].(type) {
+		case float64:
+			maxFiles = int(v)
+		case int:
+			maxFiles = v
+		}
+
+		filter, err := diagnosticFilter(request.Params.Arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		files, skipped, err := tools.BatchDiagnosticsFiles(s.client(ctx), filePaths, glob, maxFiles)
+		if err != nil {
+			coreLogger.Error("Failed to find files: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to find files: %v", err)), nil
+		}
+		if len(files) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no files match %s", glob)), nil
+		}
+
+		coreLogger.Debug("Executing batch_diagnostics for %d files", len(files))
+		var report tools.BatchDiagnosticsReport
+		if grouped := s.filesByClient(files); len(grouped) > 1 {
+			report = tools.CollectBatchDiagnosticsAcross(ctx, grouped, filter)
+		} else {
+			report = tools.CollectBatchDiagnostics(ctx, s.clientForPath(files[0]), files, filter)
+		}
+		report.Skipped = skipped
+		if wantsJSON(request) {
+			return jsonResult(report)
+		}
+		return mcp.NewToolResultText(tools.BatchDiagnostics(report, !filter.IsEmpty())), nil
+	})
+
+	changedDiagnosticsTool := mcp.NewTool("changed_diagnostics",
+		mcp.WithDescription("Get the diagnostics on lines changed relative to a git ref, including uncommitted and untracked files, so that new problems can be told apart from existing ones. Use it to review your own changes."),
+		mcp.WithString("ref",
+			mcp.Description("The git ref to compare against, such as HEAD, main or a commit hash"),
+			mcp.DefaultString("HEAD"),
+		),
+		mcp.WithBoolean("mergeBase",
+			mcp.Description("If true, compare against the merge base of ref and HEAD, like a pull request against ref would"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(changedDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		ref := "HEAD" // default value
+		if refArg, ok := request.Params.Arguments["ref"].(string); ok && refArg != "" {
+			ref = refArg
+		}
+
+		mergeBase, _ := request.Params.Arguments["mergeBase"].(bool)
+
+		coreLogger.Debug("Executing changed_diagnostics ref: %s mergeBase: %v", ref, mergeBase)
+		text, err := tools.ChangedDiagnostics(ctx, s.client(ctx), ref, mergeBase)
+		if err != nil {
+			coreLogger.Error("Failed to get changed diagnostics: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get changed diagnostics: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	diagnosticsDeltaTool := mcp.NewTool("diagnostics_delta",
+		mcp.WithDescription("Report which diagnostics appeared and disappeared since a checkpoint, such as your last edit of a file, so you can verify a fix removed the error without introducing new ones. Diagnostics are matched by severity, source, code and message, since their positions move as the file is edited."),
+		mcp.WithString("filePath",
+			mcp.Description("The file to compare. Omit to compare every file whose diagnostics changed"),
+		),
+		mcp.WithString("since",
+			mcp.Description("lastEdit to compare with the diagnostics just before each file was last edited, the name of a checkpoint, or an RFC 3339 time"),
+			mcp.DefaultString(tools.SinceLastEdit),
+		),
+		mcp.WithString("checkpoint",
+			mcp.Description("If set, record a checkpoint with this name after comparing, to compare against in a later call"),
+		),
+	)
+
+	s.addTool(diagnosticsDeltaTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, _ := request.Params.Arguments["filePath"].(string)
+		if filePath != "" {
+			resolved, err := s.client(ctx).ResolveWorkspacePath(filePath)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			filePath = resolved
+		}
+		since, _ := request.Params.Arguments["since"].(string)
+		checkpoint, _ := request.Params.Arguments["checkpoint"].(string)
+
+		coreLogger.Debug("Executing diagnostics_delta for file: %s since: %s", filePath, since)
+		report, err := tools.CollectDiagnosticsDelta(ctx, s.client(ctx), filePath, since)
+		if err != nil {
+			coreLogger.Error("Failed to compare diagnostics: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to compare diagnostics: %v", err)), nil
+		}
+		if checkpoint != "" {
+			s.client(ctx).SetCheckpoint(checkpoint)
+		}
+		if wantsJSON(request) {
+			return jsonResult(report)
+		}
+		text := tools.DiagnosticsDelta(report)
+		if checkpoint != "" {
+			text += fmt.Sprintf("Recorded checkpoint %s.\n", checkpoint)
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	// Uncomment to add codelens tools
+	//
+	// getCodeLensTool := mcp.NewTool("get_codelens",
+	// 	mcp.WithDescription("Get code lens hints for a given file from the language server."),
+	// 	mcp.WithString("filePath",
+	// 		mcp.Required(),
+	// 		mcp.Description("The path to the file to get code lens information for"),
+	// 	),
+	// )
+	//
+	// s.mcpServer.AddTool(getCodeLensTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 	// Extract arguments
+	// 	filePath, ok := request.Params.Arguments["filePath"].(string)
+	// 	if !ok {
+	// 		return mcp.NewToolResultError("filePath must be a string"), nil
+	// 	}
+	//
+	// 	coreLogger.Debug("Executing get_codelens for file: %s", filePath)
+	// 	text, err := tools.GetCodeLens(ctx, s.client(ctx), filePath)
+	// 	if err != nil {
+	// 		coreLogger.Error("Failed to get code lens: %v", err)
+	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to get code lens: %v", err)), nil
+	// 	}
+	// 	return mcp.NewToolResultText(text), nil
+	// })
+	//
+	// executeCodeLensTool := mcp.NewTool("execute_codelens",
+	// 	mcp.WithDescription("Execute a code lens command for a given file and lens index."),
+	// 	mcp.WithString("filePath",
+	// 		mcp.Required(),
+	// 		mcp.Description("The path to the file containing the code lens to execute"),
+	// 	),
+	// 	mcp.WithNumber("index",
+	// 		mcp.Required(),
+	// 		mcp.Description("The index of the code lens to execute (from get_codelens output), 1 indexed"),
+	// 	),
+	// )
+	//
+	// s.mcpServer.AddTool(executeCodeLensTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// 	// Extract arguments
+	// 	filePath, ok := request.Params.Arguments["filePath"].(string)
+	// 	if !ok {
+	// 		return mcp.NewToolResultError("filePath must be a string"), nil
+	// 	}
+	//
+	// 	// Handle both float64 and int for index due to JSON parsing
+	// 	var index int
+	// 	switch v := request.Params.Arguments["index"].(type) {
+	// 	case float64:
+	// 		index = int(v)
+	// 	case int:
+	// 		index = v
+	// 	default:
+	// 		return mcp.NewToolResultError("index must be a number"), nil
+	// 	}
+	//
+	// 	coreLogger.Debug("Executing execute_codelens for file: %s index: %d", filePath, index)
+	// 	text, err := tools.ExecuteCodeLens(ctx, s.client(ctx), filePath, index)
+	// 	if err != nil {
+	// 		coreLogger.Error("Failed to execute code lens: %v", err)
+	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to execute code lens: %v", err)), nil
+	// 	}
+	// 	return mcp.NewToolResultText(text), nil
+	// })
+
+	listTestsTool := mcp.NewTool("list_tests",
+		mcp.WithDescription("List the tests in a file that the language server can run, found through its code lenses (such as gopls' \"run test\" and rust-analyzer's \"Run Test\"). Give a line to list only the test at or above it. The numbers can be passed to run_test."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the test file"),
+		),
+		mcp.WithNumber("line",
+			mcp.Description("A line number (1-indexed) inside a test, to list only that test"),
+		),
+	)
+
+	s.addTool(listTestsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		var line int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		}
+
+		coreLogger.Debug("Executing list_tests for file: %s line: %d", filePath, line)
+		text, err := tools.ListTests(ctx, s.client(ctx), filePath, line)
+		if err != nil {
+			coreLogger.Error("Failed to list tests: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to list tests: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	runTestTool := mcp.NewTool("run_test",
+		mcp.WithDescription("Run a test listed by list_tests and return whether it passed along with its output. Go and Rust tests are run with the exact go test or cargo command the language server describes, and their output is streamed as progress notifications when the client asks for them."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the test file"),
+		),
+		mcp.WithNumber("line",
+			mcp.Description("The line passed to list_tests, if any"),
+		),
+		mcp.WithNumber("index",
+			mcp.Description("The number of the test in the list_tests output, 1 indexed"),
+			mcp.DefaultNumber(1),
+		),
+		mcp.WithNumber("timeoutSeconds",
+			mcp.Description("How long the test may run before it is stopped"),
+			mcp.DefaultNumber(300),
+		),
+	)
+
+	s.addTool(runTestTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		var line int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		}
+
+		index := 1 // default value
+		switch v := request.Params.Arguments["index"].(type) {
+		case float64:
+			index = int(v)
+		case int:
+			index = v
+		}
+
+		timeoutSeconds := 300 // default value
+		switch v := request.Params.Arguments["timeoutSeconds"].(type) {
+		case float64:
+			timeoutSeconds = int(v)
+		case int:
+			timeoutSeconds = v
+		}
+
+		coreLogger.Debug("Executing run_test for file: %s line: %d index: %d", filePath, line, index)
+		text, err := tools.RunTest(ctx, s.client(ctx), filePath, line, index,
+			time.Duration(timeoutSeconds)*time.Second, s.progress.reporter(ctx, request))
+		if err != nil {
+			coreLogger.Error("Failed to run test: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to run test: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	vulncheckTool := mcp.NewTool("vulncheck",
+		mcp.WithDescription("Run govulncheck through gopls on a Go module and report the known vulnerabilities affecting it: the OSV ID and aliases, the affected module version and the version that fixes it, and for vulnerable functions the code actually calls, where it calls them. Needs gopls."),
+		mcp.WithString("directory",
+			mcp.Description("A directory in the Go module to check. Defaults to the workspace root."),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("The package pattern to check, relative to the module root, such as './...' for the whole module or './cmd/server'"),
+			mcp.DefaultString("./..."),
+		),
+		mcp.WithNumber("timeoutSeconds",
+			mcp.Description("How long govulncheck may run before giving up"),
+			mcp.DefaultNumber(600),
+		),
+	)
+
+	s.addTool(vulncheckTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		var directory string
+		if directoryArg, ok := request.Params.Arguments["directory"].(string); ok && directoryArg != "" {
+			resolved, err := s.client(ctx).ResolveWorkspacePath(directoryArg)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			directory = resolved
+		} else if roots := s.client(ctx).WorkspaceRoots(); len(roots) > 0 {
+			directory = roots[0]
+		}
+
+		pattern := "./..." // default value
+		if patternArg, ok := request.Params.Arguments["pattern"].(string); ok && patternArg != "" {
+			pattern = patternArg
+		}
+
+		timeoutSeconds := 600 // default value
+		switch v := request.Params.Arguments["timeoutSeconds"].(type) {
+		case float64:
+			timeoutSeconds = int(v)
+		case int:
+			timeoutSeconds = v
+		}
+
+		coreLogger.Debug("Executing vulncheck for directory: %s pattern: %s", directory, pattern)
+		text, err := tools.Vulncheck(ctx, s.client(ctx), directory, pattern, time.Duration(timeoutSeconds)*time.Second)
+		if err != nil {
+			coreLogger.Error("Failed to run vulncheck: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to run vulncheck: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	hoverTool := mcp.NewTool("hover",
+		mcp.WithDescription("Get hover information (type, documentation) for a symbol at the specified position, or for a symbol by name."),
+		mcp.WithString("filePath",
+			mcp.Description("The path to the file to get hover information for"),
+		),
+		mcp.WithNumber("line",
+			mcp.Description("The line number where the hover is requested (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Description("The column number where the hover is requested (1-indexed)"),
+		),
+		mcp.WithString("symbolName",
+			mcp.Description("The name of a symbol to get hover information for (e.g. 'mypackage.MyFunction', 'MyType', 'internal/lsp.Client.OpenFile'). Use instead of filePath, line and column."),
+		),
+	)
+
+	s.addTool(hoverTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Look up by symbol name if one was given
+		if symbolName, ok := request.Params.Arguments["symbolName"].(string); ok && symbolName != "" {
+			coreLogger.Debug("Executing hover for symbol: %s", symbolName)
+			text, err := tools.GetHoverInfoForSymbol(ctx, s.client(ctx), symbolName)
+			if err != nil {
+				coreLogger.Error("Failed to get hover information: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to get hover information: %v", err)), nil
+			}
+			return mcp.NewToolResultText(text), nil
+		}
+
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		coreLogger.Debug("Executing hover for file: %s line: %d column: %d", filePath, line, column)
+		text, err := tools.GetHoverInfo(ctx, s.client(ctx), filePath, line, column)
+		if err != nil {
+			coreLogger.Error("Failed to get hover information: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get hover information: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	renameSymbolTool := mcp.NewTool("rename_symbol",
+		mcp.WithDescription("Rename a symbol (variable, function, class, etc.) at the specified position, or given by its qualified name, and update all references throughout the codebase."),
+		mcp.WithString("symbolName",
+			mcp.Description("The qualified name of the symbol to rename (e.g. 'MyType.MyMethod', 'internal/lsp.Client.OpenFile'). Use instead of filePath, line and column."),
+		),
+		mcp.WithString("filePath",
+			mcp.Description("The path to the file containing the symbol to rename"),
+		),
+		mcp.WithNumber("line",
+			mcp.Description("The line number where the symbol is located (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Description("The column number where the symbol is located (1-indexed)"),
+		),
+		mcp.WithString("newName",
+			mcp.Required(),
+			mcp.Description("The new name for the symbol"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(renameSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		newName, ok := request.Params.Arguments["newName"].(string)
+		if !ok {
+			return mcp.NewToolResultError("newName must be a string"), nil
+		}
+
+		filePath, line, column, err := s.symbolPosition(ctx, request.Params.Arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing rename_symbol for file: %s line: %d column: %d newName: %s", filePath, line, column, newName)
+		text, err := tools.RenameSymbol(ctx, s.client(ctx), filePath, line, column, newName, dryRun)
+		if err != nil {
+			coreLogger.Error("Failed to rename symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to rename symbol: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	renameFileTool := mcp.NewTool("rename_file",
+		mcp.WithDescription("Rename or move a file or directory. Language servers that support it update references such as import paths first."),
+		mcp.WithString("oldPath",
+			mcp.Required(),
+			mcp.Description("The path of the file or directory to rename"),
+		),
+		mcp.WithString("newPath",
+			mcp.Required(),
+			mcp.Description("The new path for the file or directory"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(renameFileTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		oldPath, ok := request.Params.Arguments["oldPath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("oldPath must be a string"), nil
+		}
+		oldPath, err := s.client(ctx).ResolveWorkspacePath(oldPath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		newPath, ok := request.Params.Arguments["newPath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("newPath must be a string"), nil
+		}
+		newPath, err = s.client(ctx).ResolveWorkspacePath(newPath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing rename_file from %s to %s", oldPath, newPath)
+		text, err := tools.RenameFile(ctx, s.client(ctx), oldPath, newPath, dryRun)
+		if err != nil {
+			coreLogger.Error("Failed to rename file: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to rename file: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	createFileTool := mcp.NewTool("create_file",
+		mcp.WithDescription("Create a new file with the given content and tell the language server about it, returning the file's diagnostics. Language servers that support it may also update other files, such as package declarations."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path of the file to create"),
+		),
+		mcp.WithString("content",
+			mcp.Description("The content of the new file"),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("Replace the file if it already exists"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(createFileTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		content, _ := request.Params.Arguments["content"].(string)
+		overwrite, _ := request.Params.Arguments["overwrite"].(bool)
+
+		coreLogger.Debug("Executing create_file for file: %s", filePath)
+		text, err := tools.CreateFile(ctx, s.client(ctx), filePath, content, overwrite)
+		if err != nil {
+			coreLogger.Error("Failed to create file: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to create file: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	deleteFileTool := mcp.NewTool("delete_file",
+		mcp.WithDescription("Delete a file or directory and tell the language server about it, so that it drops the file from its index and diagnostics."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path of the file or directory to delete"),
+		),
+		mcp.WithBoolean("recursive",
+			mcp.Description("Required to delete a directory and everything in it"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(deleteFileTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		recursive, _ := request.Params.Arguments["recursive"].(bool)
+
+		coreLogger.Debug("Executing delete_file for path: %s", filePath)
+		text, err := tools.DeleteFile(ctx, s.client(ctx), filePath, recursive)
+		if err != nil {
+			coreLogger.Error("Failed to delete file: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to delete file: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	completionTool := mcp.NewTool("completion",
+		mcp.WithDescription("Get code completion candidates at the specified position, with their kinds, detail strings and documentation."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to get completions for"),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where completion is requested (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where completion is requested (1-indexed)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of completions to return"),
+			mcp.DefaultNumber(50),
+		),
+	)
+
+	s.addTool(completionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		limit := 50 // default value
+		switch v := request.Params.Arguments["limit"].(type) {
+		case float64:
+			limit = int(v)
+		case int:
+			limit = v
+		}
+
+		coreLogger.Debug("Executing completion for file: %s line: %d column: %d", filePath, line, column)
+		text, err := tools.GetCompletions(ctx, s.client(ctx), filePath, line, column, limit)
+		if err != nil {
+			coreLogger.Error("Failed to get completions: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get completions: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	callHierarchyTool := mcp.NewTool("call_hierarchy",
+		mcp.WithDescription("Show who calls the function at the specified position, or given by its qualified name, (incoming calls) and what it calls (outgoing calls) as a nested, depth-limited tree with file and line anchors."),
+		mcp.WithString("symbolName",
+			mcp.Description("The qualified name of the function (e.g. 'MyType.MyMethod', 'internal/lsp.Client.OpenFile'). Use instead of filePath, line and column."),
+		),
+		mcp.WithString("filePath",
+			mcp.Description("The path to the file containing the function"),
+		),
+		mcp.WithNumber("line",
+			mcp.Description("The line number where the function is located (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Description("The column number where the function is located (1-indexed)"),
+		),
+		mcp.WithString("direction",
+			mcp.Description("Which calls to show: 'incoming' (callers), 'outgoing' (callees), or 'both'"),
+			mcp.Enum("incoming", "outgoing", "both"),
+			mcp.DefaultString("incoming"),
+		),
+		mcp.WithNumber("depth",
+			mcp.Description("How many levels of calls to follow"),
+			mcp.DefaultNumber(1),
+		),
+	)
+
+	s.addTool(callHierarchyTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, line, column, err := s.symbolPosition(ctx, request.Params.Arguments)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		direction := "incoming" // default value
+		if directionArg, ok := request.Params.Arguments["direction"].(string); ok {
+			direction = directionArg
+		}
+		if direction != "incoming" && direction != "outgoing" && direction != "both" {
+			return mcp.NewToolResultError("direction must be one of 'incoming', 'outgoing' or 'both'"), nil
+		}
+
+		depth := 1 // default value
+		switch v := request.Params.Arguments["depth"].(type) {
+		case float64:
+			depth = int(v)
+		case int:
+			depth = v
+		}
+
+		coreLogger.Debug("Executing call_hierarchy for file: %s line: %d column: %d direction: %s depth: %d", filePath, line, column, direction, depth)
+		text, err := tools.GetCallHierarchy(ctx, s.client(ctx), filePath, line, column, direction, depth)
+		if err != nil {
+			coreLogger.Error("Failed to get call hierarchy: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get call hierarchy: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	typeHierarchyTool := mcp.NewTool("type_hierarchy",
+		mcp.WithDescription("Show the supertypes (interfaces and base classes) and/or subtypes (implementations and subclasses) of the type at the specified position as a nested, depth-limited tree."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the type"),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where the type is located (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where the type is located (1-indexed)"),
+		),
+		mcp.WithString("direction",
+			mcp.Description("Which related types to show: 'supertypes', 'subtypes', or 'both'"),
+			mcp.Enum("supertypes", "subtypes", "both"),
+			mcp.DefaultString("both"),
+		),
+		mcp.WithNumber("depth",
+			mcp.Description("How many levels of the hierarchy to follow"),
+			mcp.DefaultNumber(1),
+		),
+	)
+
+	s.addTool(typeHierarchyTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		direction := "both" // default value
+		if directionArg, ok := request.Params.Arguments["direction"].(string); ok {
+			direction = directionArg
+		}
+		if direction != "supertypes" && direction != "subtypes" && direction != "both" {
+			return mcp.NewToolResultError("direction must be one of 'supertypes', 'subtypes' or 'both'"), nil
+		}
+
+		depth := 1 // default value
+		switch v := request.Params.Arguments["depth"].(type) {
+		case float64:
+			depth = int(v)
+		case int:
+			depth = v
+		}
+
+		coreLogger.Debug("Executing type_hierarchy for file: %s line: %d column: %d direction: %s depth: %d", filePath, line, column, direction, depth)
+		text, err := tools.GetTypeHierarchy(ctx, s.client(ctx), filePath, line, column, direction, depth)
+		if err != nil {
+			coreLogger.Error("Failed to get type hierarchy: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get type hierarchy: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	listCodeActionsTool := mcp.NewTool("list_code_actions",
+		mcp.WithDescription("List the quick fixes, refactorings and source actions (such as organize imports) available for a range in a file. Each action is numbered so it can be passed to apply_code_action."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file"),
+		),
+		mcp.WithNumber("startLine",
+			mcp.Required(),
+			mcp.Description("Start line of the range (1-indexed)"),
+		),
+		mcp.WithNumber("startColumn",
+			mcp.Required(),
+			mcp.Description("Start column of the range (1-indexed)"),
+		),
+		mcp.WithNumber("endLine",
+			mcp.Required(),
+			mcp.Description("End line of the range (1-indexed)"),
+		),
+		mcp.WithNumber("endColumn",
+			mcp.Required(),
+			mcp.Description("End column of the range (1-indexed)"),
+		),
+		mcp.WithString("kind",
+			mcp.Description("Only return code actions of this kind or its sub-kinds (e.g. 'quickfix', 'refactor.extract', 'source.organizeImports')"),
+		),
+	)
+
+	s.addTool(listCodeActionsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for range positions due to JSON parsing
+		var startLine, startColumn, endLine, endColumn int
+		switch v := request.Params.Arguments["startLine"].(type) {
+		case float64:
+			startLine = int(v)
+		case int:
+			startLine = v
+		default:
+			return mcp.NewToolResultError("startLine must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["startColumn"].(type) {
+		case float64:
+			startColumn = int(v)
+		case int:
+			startColumn = v
+		default:
+			return mcp.NewToolResultError("startColumn must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["endLine"].(type) {
+		case float64:
+			endLine = int(v)
+		case int:
+			endLine = v
+		default:
+			return mcp.NewToolResultError("endLine must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["endColumn"].(type) {
+		case float64:
+			endColumn = int(v)
+		case int:
+			endColumn = v
+		default:
+			return mcp.NewToolResultError("endColumn must be a number"), nil
+		}
+
+		kind := "" // default value
+		if kindArg, ok := request.Params.Arguments["kind"].(string); ok {
+			kind = kindArg
+		}
+
+		coreLogger.Debug("Executing list_code_actions for file: %s range: L%d:C%d-L%d:C%d", filePath, startLine, startColumn, endLine, endColumn)
+		text, err := tools.ListCodeActions(ctx, s.client(ctx), filePath, startLine, startColumn, endLine, endColumn, kind)
+		if err != nil {
+			coreLogger.Error("Failed to list code actions: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to list code actions: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	applyCodeActionTool := mcp.NewTool("apply_code_action",
+		mcp.WithDescription("Apply a code action returned by list_code_actions. The same file, range and kind must be given so the action can be found again. Applies the action's edits and runs its command, if any."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file"),
+		),
+		mcp.WithNumber("startLine",
+			mcp.Required(),
+			mcp.Description("Start line of the range (1-indexed)"),
+		),
+		mcp.WithNumber("startColumn",
+			mcp.Required(),
+			mcp.Description("Start column of the range (1-indexed)"),
+		),
+		mcp.WithNumber("endLine",
+			mcp.Required(),
+			mcp.Description("End line of the range (1-indexed)"),
+		),
+		mcp.WithNumber("endColumn",
+			mcp.Required(),
+			mcp.Description("End column of the range (1-indexed)"),
+		),
+		mcp.WithString("kind",
+			mcp.Description("Only return code actions of this kind or its sub-kinds (e.g. 'quickfix', 'refactor.extract', 'source.organizeImports')"),
+		),
+		mcp.WithNumber("index",
+			mcp.Required(),
+			mcp.Description("The index of the code action to apply (from list_code_actions output), 1 indexed"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(applyCodeActionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for range positions due to JSON parsing
+		var startLine, startColumn, endLine, endColumn int
+		switch v := request.Params.Arguments["startLine"].(type) {
+		case float64:
+			startLine = int(v)
+		case int:
+			startLine = v
+		default:
+			return mcp.NewToolResultError("startLine must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["startColumn"].(type) {
+		case float64:
+			startColumn = int(v)
+		case int:
+			startColumn = v
+		default:
+			return mcp.NewToolResultError("startColumn must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["endLine"].(type) {
+		case float64:
+			endLine = int(v)
+		case int:
+			endLine = v
+		default:
+			return mcp.NewToolResultError("endLine must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["endColumn"].(type) {
+		case float64:
+			endColumn = int(v)
+		case int:
+			endColumn = v
+		default:
+			return mcp.NewToolResultError("endColumn must be a number"), nil
+		}
+
+		kind := "" // default value
+		if kindArg, ok := request.Params.Arguments["kind"].(string); ok {
+			kind = kindArg
+		}
+
+		var index int
+		switch v := request.Params.Arguments["index"].(type) {
+		case float64:
+			index = int(v)
+		case int:
+			index = v
+		default:
+			return mcp.NewToolResultError("index must be a number"), nil
+		}
+
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing apply_code_action for file: %s range: L%d:C%d-L%d:C%d index: %d", filePath, startLine, startColumn, endLine, endColumn, index)
+		text, err := tools.ApplyCodeAction(ctx, s.client(ctx), filePath, startLine, startColumn, endLine, endColumn, kind, index, dryRun)
+		if err != nil {
+			coreLogger.Error("Failed to apply code action: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to apply code action: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	extractTool := mcp.NewTool("extract",
+		mcp.WithDescription("Extract the code in a range into a new function, method, variable or constant. Without index, lists the extract refactorings the language server offers for the range. With index, applies that refactoring and renames the symbol it introduces to newName, or reports the name the server chose."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file"),
+		),
+		mcp.WithNumber("startLine",
+			mcp.Required(),
+			mcp.Description("Start line of the code to extract (1-indexed)"),
+		),
+		mcp.WithNumber("startColumn",
+			mcp.Required(),
+			mcp.Description("Start column of the code to extract (1-indexed)"),
+		),
+		mcp.WithNumber("endLine",
+			mcp.Required(),
+			mcp.Description("End line of the code to extract (1-indexed)"),
+		),
+		mcp.WithNumber("endColumn",
+			mcp.Required(),
+			mcp.Description("End column of the code to extract, exclusive (1-indexed)"),
+		),
+		mcp.WithString("kind",
+			mcp.Description("Only offer extracting into this kind of symbol. Leave empty for every kind"),
+			mcp.Enum("function", "method", "variable", "constant"),
+		),
+		mcp.WithNumber("index",
+			mcp.Description("The index of the refactoring to apply, from the list returned without index, 1 indexed. The same range and kind must be given"),
+		),
+		mcp.WithString("newName",
+			mcp.Description("Name for the extracted symbol, instead of the one the server makes up"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(extractTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for range positions due to JSON parsing
+		var startLine, startColumn, endLine, endColumn int
+		switch v := request.Params.Arguments["startLine"].(type) {
+		case float64:
+			startLine = int(v)
+		case int:
+			startLine = v
+		default:
+			return mcp.NewToolResultError("startLine must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["startColumn"].(type) {
+		case float64:
+			startColumn = int(v)
+		case int:
+			startColumn = v
+		default:
+			return mcp.NewToolResultError("startColumn must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["endLine"].(type) {
+		case float64:
+			endLine = int(v)
+		case int:
+			endLine = v
+		default:
+			return mcp.NewToolResultError("endLine must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["endColumn"].(type) {
+		case float64:
+			endColumn = int(v)
+		case int:
+			endColumn = v
+		default:
+			return mcp.NewToolResultError("endColumn must be a number"), nil
+		}
+
+		kindArg, _ := request.Params.Arguments["kind"].(string)
+		kind, ok := tools.ExtractKinds[kindArg]
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("unsupported kind %q (expected function, method, variable or constant)", kindArg)), nil
+		}
+
+		var index int
+		switch v := request.Params.Arguments["index"].(type) {
+		case float64:
+			index = int(v)
+		case int:
+			index = v
+		}
+
+		newName, _ := request.Params.Arguments["newName"].(string)
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		if index == 0 {
+			coreLogger.Debug("Executing extract for file: %s range: L%d:C%d-L%d:C%d kind: %s", filePath, startLine, startColumn, endLine, endColumn, kind)
+			text, err := tools.ListRefactorings(ctx, s.client(ctx), filePath, startLine, startColumn, endLine, endColumn, kind)
+			if err != nil {
+				coreLogger.Error("Failed to list extract refactorings: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to list extract refactorings: %v", err)), nil
+			}
+			return mcp.NewToolResultText(text), nil
+		}
+
+		coreLogger.Debug("Executing extract for file: %s range: L%d:C%d-L%d:C%d kind: %s index: %d newName: %s", filePath, startLine, startColumn, endLine, endColumn, kind, index, newName)
+		text, err := tools.ApplyExtract(ctx, s.client(ctx), filePath, startLine, startColumn, endLine, endColumn, kind, index, newName, dryRun)
+		if err != nil {
+			coreLogger.Error("Failed to extract: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to extract: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	inlineTool := mcp.NewTool("inline",
+		mcp.WithDescription("Inline a variable, constant or function call: replace its uses with its value or body. Without index, lists the inline refactorings the language server offers at the position or range. With index, applies that refactoring, which may change many files, and returns the diagnostics of each changed file."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file"),
+		),
+		mcp.WithNumber("startLine",
+			mcp.Required(),
+			mcp.Description("Line of the symbol or call to inline (1-indexed)"),
+		),
+		mcp.WithNumber("startColumn",
+			mcp.Required(),
+			mcp.Description("Column of the symbol or call to inline (1-indexed)"),
+		),
+		mcp.WithNumber("endLine",
+			mcp.Description("End line of the range to inline, defaults to startLine (1-indexed)"),
+		),
+		mcp.WithNumber("endColumn",
+			mcp.Description("End column of the range to inline, defaults to startColumn (1-indexed)"),
+		),
+		mcp.WithNumber("index",
+			mcp.Description("The index of the refactoring to apply, from the list returned without index, 1 indexed. The same position or range must be given"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(inlineTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for positions due to JSON parsing
+		var startLine, startColumn int
+		switch v := request.Params.Arguments["startLine"].(type) {
+		case float64:
+			startLine = int(v)
+		case int:
+			startLine = v
+		default:
+			return mcp.NewToolResultError("startLine must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["startColumn"].(type) {
+		case float64:
+			startColumn = int(v)
+		case int:
+			startColumn = v
+		default:
+			return mcp.NewToolResultError("startColumn must be a number"), nil
+		}
+
+		endLine, endColumn := startLine, startColumn
+		switch v := request.Params.Arguments["endLine"].(type) {
+		case float64:
+			endLine = int(v)
+		case int:
+			endLine = v
+		}
+
+		switch v := request.Params.Arguments["endColumn"].(type) {
+		case float64:
+			endColumn = int(v)
+		case int:
+			endColumn = v
+		}
+
+		var index int
+		switch v := request.Params.Arguments["index"].(type) {
+		case float64:
+			index = int(v)
+		case int:
+			index = v
+		}
+
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		if index == 0 {
+			coreLogger.Debug("Executing inline for file: %s range: L%d:C%d-L%d:C%d", filePath, startLine, startColumn, endLine, endColumn)
+			text, err := tools.ListRefactorings(ctx, s.client(ctx), filePath, startLine, startColumn, endLine, endColumn, protocol.RefactorInline)
+			if err != nil {
+				coreLogger.Error("Failed to list inline refactorings: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to list inline refactorings: %v", err)), nil
+			}
+			return mcp.NewToolResultText(text), nil
+		}
+
+		coreLogger.Debug("Executing inline for file: %s range: L%d:C%d-L%d:C%d index: %d", filePath, startLine, startColumn, endLine, endColumn, index)
+		text, err := tools.ApplyInline(ctx, s.client(ctx), filePath, startLine, startColumn, endLine, endColumn, index, dryRun)
+		if err != nil {
+			coreLogger.Error("Failed to inline: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to inline: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	fixDiagnosticsTool := mcp.NewTool("fix_diagnostics",
+		mcp.WithDescription("Apply the language server's quick fixes for the diagnostics of a file, or of every workspace file with diagnostics when no file is given. Only fixes that edit files are applied, and by default only those the server marks as preferred. Reports which diagnostics were fixed and which remain."),
+		mcp.WithString("filePath",
+			mcp.Description("The path to the file to fix. Leave empty to fix the whole workspace"),
+		),
+		mcp.WithBoolean("preferredOnly",
+			mcp.Description("If true, only apply fixes the server marks as preferred. If false, apply the first quick fix offered for each diagnostic"),
+			mcp.DefaultBool(true),
+		),
+	)
+
+	s.addTool(fixDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, _ := request.Params.Arguments["filePath"].(string)
+		if filePath != "" {
+			var err error
+			filePath, err = s.client(ctx).ResolveWorkspacePath(filePath)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+
+		preferredOnly := true // default value
+		if preferredOnlyArg, ok := request.Params.Arguments["preferredOnly"].(bool); ok {
+			preferredOnly = preferredOnlyArg
+		}
+
+		coreLogger.Debug("Executing fix_diagnostics for file: %s preferredOnly: %v", filePath, preferredOnly)
+		text, err := tools.FixDiagnostics(ctx, s.client(ctx), filePath, preferredOnly)
+		if err != nil {
+			coreLogger.Error("Failed to fix diagnostics: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to fix diagnostics: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	undoLastEditTool := mcp.NewTool("undo_last_edit",
+		mcp.WithDescription("Revert the most recent change this server made to the workspace, whether by a tool such as edit_file or rename_symbol or by the language server applying an edit, restoring the files' previous content. Refuses if the files changed again since, unless force is set."),
+		mcp.WithBoolean("force",
+			mcp.Description("If true, undo the edit even if its files changed again since, overwriting those changes"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(undoLastEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		force, _ := request.Params.Arguments["force"].(bool)
+
+		coreLogger.Debug("Executing undo_last_edit force: %v", force)
+		text, err := tools.UndoEdit(ctx, s.client(ctx), 0, force)
+		if err != nil {
+			coreLogger.Error("Failed to undo edit: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to undo edit: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	undoTransactionTool := mcp.NewTool("undo_transaction",
+		mcp.WithDescription("Revert a specific earlier change this server made to the workspace, identified by its number in edit_history, restoring the files' previous content. Refuses if the files changed again since, unless force is set."),
+		mcp.WithNumber("id",
+			mcp.Required(),
+			mcp.Description("The number of the edit, as listed by edit_history"),
+		),
+		mcp.WithBoolean("force",
+			mcp.Description("If true, undo the edit even if its files changed again since, overwriting those changes"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(undoTransactionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		var id int
+		switch v := request.Params.Arguments["id"].(type) {
+		case float64:
+			id = int(v)
+		case int:
+			id = v
+		default:
+			return mcp.NewToolResultError("id must be a number"), nil
+		}
+		if id < 1 {
+			return mcp.NewToolResultError("id must be at least 1"), nil
+		}
+
+		force, _ := request.Params.Arguments["force"].(bool)
+
+		coreLogger.Debug("Executing undo_transaction id: %d force: %v", id, force)
+		text, err := tools.UndoEdit(ctx, s.client(ctx), id, force)
+		if err != nil {
+			coreLogger.Error("Failed to undo edit: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to undo edit: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	editHistoryTool := mcp.NewTool("edit_history",
+		mcp.WithDescription("List the recent changes this server made to the workspace, most recent first, with the files each one changed. The numbers can be passed to undo_transaction."),
+	)
+
+	s.addTool(editHistoryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing edit_history")
+		return mcp.NewToolResultText(tools.EditHistory()), nil
+	})
+
+	formatDocumentTool := mcp.NewTool("format_document",
+		mcp.WithDescription("Format a file, or a range of lines in it, with the language server's formatter. The changes are written to disk and returned as a unified diff."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to format"),
+		),
+		mcp.WithNumber("startLine",
+			mcp.Description("First line of the range to format (1-indexed). Formats the whole file if omitted"),
+		),
+		mcp.WithNumber("endLine",
+			mcp.Description("Last line of the range to format, inclusive (1-indexed). Defaults to startLine"),
+		),
+		mcp.WithNumber("tabSize",
+			mcp.Description("Size of a tab in spaces"),
+			mcp.DefaultNumber(4),
+		),
+		mcp.WithBoolean("insertSpaces",
+			mcp.Description("Prefer spaces over tabs for indentation"),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(formatDocumentTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for line numbers due to JSON parsing
+		startLine := 0 // default value, format the whole file
+		switch v := request.Params.Arguments["startLine"].(type) {
+		case float64:
+			startLine = int(v)
+		case int:
+			startLine = v
+		}
+
+		endLine := startLine // default value
+		switch v := request.Params.Arguments["endLine"].(type) {
+		case float64:
+			endLine = int(v)
+		case int:
+			endLine = v
+		}
+
+		tabSize := 4 // default value
+		switch v := request.Params.Arguments["tabSize"].(type) {
+		case float64:
+			tabSize = int(v)
+		case int:
+			tabSize = v
+		}
+
+		insertSpaces := true // default value
+		if insertSpacesArg, ok := request.Params.Arguments["insertSpaces"].(bool); ok {
+			insertSpaces = insertSpacesArg
+		}
+
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing format_document for file: %s lines: %d-%d", filePath, startLine, endLine)
+		text, err := tools.FormatDocument(ctx, s.client(ctx), filePath, startLine, endLine, tabSize, insertSpaces, dryRun)
+		if err != nil {
+			coreLogger.Error("Failed to format document: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to format document: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	semanticTokensTool := mcp.NewTool("semantic_tokens",
+		mcp.WithDescription("List the semantic tokens of a file, or a range of lines in it, as computed by the language server. Each token is shown with its position, type (function, variable, type, etc.), modifiers and text."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to get semantic tokens for"),
+		),
+		mcp.WithNumber("startLine",
+			mcp.Description("First line of the range (1-indexed). Returns tokens for the whole file if omitted"),
+		),
+		mcp.WithNumber("endLine",
+			mcp.Description("Last line of the range, inclusive (1-indexed). Defaults to startLine"),
+		),
+	)
+
+	s.addTool(semanticTokensTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for line numbers due to JSON parsing
+		startLine := 0 // default value, the whole file
+		switch v := request.Params.Arguments["startLine"].(type) {
+		case float64:
+			startLine = int(v)
+		case int:
+			startLine = v
+		}
+
+		endLine := startLine // default value
+		switch v := request.Params.Arguments["endLine"].(type) {
+		case float64:
+			endLine = int(v)
+		case int:
+			endLine = v
+		}
+
+		coreLogger.Debug("Executing semantic_tokens for file: %s lines: %d-%d", filePath, startLine, endLine)
+		text, err := tools.GetSemanticTokens(ctx, s.client(ctx), filePath, startLine, endLine)
+		if err != nil {
+			coreLogger.Error("Failed to get semantic tokens: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get semantic tokens: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	readFileTool := mcp.NewTool("read_file",
+		mcp.WithDescription("Read the lines of a file, or a slice of them, with line numbers for edit_file. Works for any file, including docs and config files no language server handles, and includes staged edits."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to read"),
+		),
+		mcp.WithNumber("startLine",
+			mcp.Description("First line to read, one-indexed (defaults to the start of the file)"),
+		),
+		mcp.WithNumber("endLine",
+			mcp.Description("Last line to read, inclusive (defaults to the end of the file)"),
+		),
+	)
+
+	s.addTool(readFileTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		var startLine, endLine int
+		switch v := request.Params.Arguments["startLine"].(type) {
+		case float64:
+			startLine = int(v)
+		case int:
+			startLine = v
+		}
+		switch v := request.Params.Arguments["endLine"].(type) {
+		case float64:
+			endLine = int(v)
+		case int:
+			endLine = v
+		}
+
+		coreLogger.Debug("Executing read_file for file: %s", filePath)
+		text, err := tools.ReadFile(s.client(ctx), filePath, startLine, endLine)
+		if err != nil {
+			coreLogger.Error("Failed to read file: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read file: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	foldingRangesTool := mcp.NewTool("folding_ranges",
+		mcp.WithDescription("Get an outline of the structural blocks of a file (imports, functions, classes, comments, regions) with their line ranges. Use it to read only the sections of a large file you need."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to get folding ranges for"),
+		),
+	)
+
+	s.addTool(foldingRangesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		coreLogger.Debug("Executing folding_ranges for file: %s", filePath)
+		text, err := tools.GetFoldingRanges(ctx, s.client(ctx), filePath)
+		if err != nil {
+			coreLogger.Error("Failed to get folding ranges: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get folding ranges: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	typeDefinitionTool := mcp.NewTool("type_definition",
+		mcp.WithDescription("Jump to the definition of the type of the symbol at the specified position, such as the struct or class of a variable. Returns the complete source code of the type."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol"),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where the symbol is located (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where the symbol is located (1-indexed)"),
+		),
+	)
+
+	s.addTool(typeDefinitionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		coreLogger.Debug("Executing type_definition for file: %s line: %d column: %d", filePath, line, column)
+		if wantsJSON(request) {
+			result, err := tools.CollectTypeDefinitions(ctx, s.client(ctx), filePath, line, column)
+			if err != nil {
+				coreLogger.Error("Failed to get type definition: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to get type definition: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.GetTypeDefinition(ctx, s.client(ctx), filePath, line, column)
+		if err != nil {
+			coreLogger.Error("Failed to get type definition: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get type definition: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	declarationTool := mcp.NewTool("declaration",
+		mcp.WithDescription("Jump to the declaration of the symbol at the specified position. In languages that separate declarations from definitions, such as C and C++ headers, this finds the declaration rather than the implementation."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol"),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where the symbol is located (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where the symbol is located (1-indexed)"),
+		),
+	)
+
+	s.addTool(declarationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		coreLogger.Debug("Executing declaration for file: %s line: %d column: %d", filePath, line, column)
+		if wantsJSON(request) {
+			result, err := tools.CollectDeclarations(ctx, s.client(ctx), filePath, line, column)
+			if err != nil {
+				coreLogger.Error("Failed to get declaration: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to get declaration: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.GetDeclaration(ctx, s.client(ctx), filePath, line, column)
+		if err != nil {
+			coreLogger.Error("Failed to get declaration: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get declaration: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	documentHighlightTool := mcp.NewTool("document_highlight",
+		mcp.WithDescription("List every occurrence within a file of the symbol at the specified position, each marked as a read, a write, or a plain text match. Cheaper than references when only the usage within one file matters, such as where a local variable is assigned."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol"),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where the symbol is located (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where the symbol is located (1-indexed)"),
+		),
+	)
+
+	s.addTool(documentHighlightTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		coreLogger.Debug("Executing document_highlight for file: %s line: %d column: %d", filePath, line, column)
+		if wantsJSON(request) {
+			result, err := tools.CollectDocumentHighlights(ctx, s.client(ctx), filePath, line, column)
+			if err != nil {
+				coreLogger.Error("Failed to get document highlights: %v", err)
+				return mcp.NewToolResultError(fmt.Sprintf("failed to get document highlights: %v", err)), nil
+			}
+			return jsonResult(result)
+		}
+		text, err := tools.GetDocumentHighlights(ctx, s.client(ctx), filePath, line, column)
+		if err != nil {
+			coreLogger.Error("Failed to get document highlights: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get document highlights: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	monikerTool := mcp.NewTool("moniker",
+		mcp.WithDescription("Get the monikers of the symbol at the specified position: stable identifiers made of a scheme (such as the package manager or indexer) and an identifier unique within it, plus whether the symbol is imported, exported, or local. Use them to link a symbol to the same symbol in other projects or in LSIF and SCIP based code search."),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol"),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where the symbol is located (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where the symbol is located (1-indexed)"),
+		),
+	)
+
+	s.addTool(monikerTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, ok := request.Params.Arguments["filePath"].(string)
+		if !ok {
+			return mcp.NewToolResultError("filePath must be a string"), nil
+		}
+		filePath, err := s.client(ctx).ResolveWorkspacePath(filePath)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle both float64 and int for line and column due to JSON parsing
+		var line, column int
+		switch v := request.Params.Arguments["line"].(type) {
+		case float64:
+			line = int(v)
+		case int:
+			line = v
+		default:
+			return mcp.NewToolResultError("line must be a number"), nil
+		}
+
+		switch v := request.Params.Arguments["column"].(type) {
+		case float64:
+			column = int(v)
+		case int:
+			column = v
+		default:
+			return mcp.NewToolResultError("column must be a number"), nil
+		}
+
+		coreLogger.Debug("Executing moniker for file: %s line: %d column: %d", filePath, line, column)
+		text, err := tools.GetMonikers(ctx, s.client(ctx), filePath, line, column)
+		if err != nil {
+			coreLogger.Error("Failed to get monikers: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get monikers: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	waitForDiagnosticsTool := mcp.NewTool("wait_for_diagnostics",
+		mcp.WithDescription("Wait until the language server has finished processing recent changes, detected by its progress reports ending and no new diagnostics arriving for a quiet period. Returns a summary of the files with diagnostics. Use it after editing files and before requesting diagnostics."),
+		mcp.WithNumber("debounceMs",
+			mcp.Description("How long the server must stay quiet, in milliseconds, before it is considered idle"),
+			mcp.DefaultNumber(1000),
+		),
+		mcp.WithNumber("timeoutMs",
+			mcp.Description("Maximum time to wait, in milliseconds"),
+			mcp.DefaultNumber(30000),
+		),
+	)
+
+	s.addTool(waitForDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		debounceMs := 1000 // default value
+		switch v := request.Params.Arguments["debounceMs"].(type) {
+		case float64:
+			debounceMs = int(v)
+		case int:
+			debounceMs = v
+		}
+
+		timeoutMs := 30000 // default value
+		switch v := request.Params.Arguments["timeoutMs"].(type) {
+		case float64:
+			timeoutMs = int(v)
+		case int:
+			timeoutMs = v
+		}
+
+		coreLogger.Debug("Executing wait_for_diagnostics with debounce: %dms timeout: %dms", debounceMs, timeoutMs)
+		text, err := tools.WaitForDiagnostics(ctx, s.client(ctx), time.Duration(debounceMs)*time.Millisecond, time.Duration(timeoutMs)*time.Millisecond)
+		if err != nil {
+			coreLogger.Error("Failed to wait for diagnostics: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to wait for diagnostics: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	applyPatchTool := mcp.NewTool("apply_patch",
+		mcp.WithDescription("Apply a unified diff (as produced by `diff -u` or `git diff`) to one or more files. Files can be modified, created or deleted. The patch is checked before any file is written, the language server is notified of each change, and the diagnostics for each changed file are returned."),
+		mcp.WithString("patch",
+			mcp.Required(),
+			mcp.Description("The unified diff to apply. Paths are relative to the workspace root and may use git's a/ and b/ prefixes"),
+		),
+		mcp.WithBoolean("dryRun",
+			mcp.Description("If true, return the changes as a unified diff without writing them to disk"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	s.addTool(applyPatchTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		patch, ok := request.Params.Arguments["patch"].(string)
+		if !ok {
+			return mcp.NewToolResultError("patch must be a string"), nil
+		}
+
+		dryRun, _ := request.Params.Arguments["dryRun"].(bool)
+
+		coreLogger.Debug("Executing apply_patch")
+		text, err := tools.ApplyPatch(ctx, s.client(ctx), patch, dryRun)
+		if err != nil {
+			coreLogger.Error("Failed to apply patch: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to apply patch: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	addWorkspaceFolderTool := mcp.NewTool("add_workspace_folder",
+		mcp.WithDescription("Attach another project directory to the language server at runtime, for example a nested module you need symbols from. The directory must be inside the workspace folders the server was started with, unless it was started with --allow-external-folders. The directory is watched for changes and relative file paths passed to other tools can resolve against it."),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Path to the directory to add"),
+		),
+	)
+
+	s.addTool(addWorkspaceFolderTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		path, ok := request.Params.Arguments["path"].(string)
+		if !ok {
+			return mcp.NewToolResultError("path must be a string"), nil
+		}
+
+		// Folders added at runtime widen the paths tools accept, so they must be
+		// inside the folders the server was started with
+		allowedRoots := append([]string{}, s.config.workspaceDirs...)
+		if s.config.tools.AllowExternalFolders {
+			allowedRoots = nil
+		}
+
+		coreLogger.Debug("Executing add_workspace_folder for path: %s", path)
+		text, err := tools.AddWorkspaceFolder(ctx, s.client(ctx), s.workspaceWatcher, path, allowedRoots)
+		if err != nil {
+			coreLogger.Error("Failed to add workspace folder: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to add workspace folder: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	removeWorkspaceFolderTool := mcp.NewTool("remove_workspace_folder",
+		mcp.WithDescription("Detach a project directory from the language server at runtime. Its open files are closed and it is no longer watched. The last remaining workspace folder cannot be removed."),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Path to the directory to remove"),
+		),
+	)
+
+	s.addTool(removeWorkspaceFolderTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		path, ok := request.Params.Arguments["path"].(string)
+		if !ok {
+			return mcp.NewToolResultError("path must be a string"), nil
+		}
+
+		coreLogger.Debug("Executing remove_workspace_folder for path: %s", path)
+		text, err := tools.RemoveWorkspaceFolder(ctx, s.client(ctx), s.workspaceWatcher, path)
+		if err != nil {
+			coreLogger.Error("Failed to remove workspace folder: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to remove workspace folder: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	restartLanguageServerTool := mcp.NewTool("restart_language_server",
+		mcp.WithDescription("Restart the language server. Use this when the server has crashed or stopped responding. The server is started again with the same workspace folders and configuration, and previously open files are reopened."),
+	)
+
+	s.addTool(restartLanguageServerTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing restart_language_server")
+		text, err := tools.RestartLanguageServer(ctx, s.client(ctx))
+		if err != nil {
+			coreLogger.Error("Failed to restart language server: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to restart language server: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	serverStatusTool := mcp.NewTool("server_status",
+		mcp.WithDescription("Report the state of the language server: its process and uptime, whether it is initialized or still indexing, the open files and when diagnostics were last published. Use this to find out why queries return nothing."),
+	)
+
+	s.addTool(serverStatusTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing server_status")
+		if wantsJSON(request) {
+			return jsonResult(tools.CollectServerStatus(s.client(ctx)))
+		}
+		return mcp.NewToolResultText(tools.ServerStatus(s.client(ctx))), nil
+	})
+
+	openFilesTool := mcp.NewTool("open_files",
+		mcp.WithDescription("List the files open in the language server, most recently used first, with their document versions and whether they have staged edits. With --max-open-files set, the least recently used files are closed once the limit is passed."),
+	)
+
+	s.addTool(openFilesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing open_files")
+		if wantsJSON(request) {
+			return jsonResult(tools.CollectOpenFiles(s.client(ctx)))
+		}
+		return mcp.NewToolResultText(tools.OpenFiles(s.client(ctx))), nil
+	})
+
+	lspCapabilitiesTool := mcp.NewTool("lsp_capabilities",
+		mcp.WithDescription("Report what the language server supports: its name and version, the position encoding, which optional LSP methods it implements and which tools are consequently active. Use this to find out which tools will work with this server."),
+	)
+
+	s.addTool(lspCapabilitiesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing lsp_capabilities")
+		report := tools.CollectCapabilities(s.client(ctx), s.toolSupport())
+		if wantsJSON(request) {
+			return jsonResult(report)
+		}
+		return mcp.NewToolResultText(tools.Capabilities(report)), nil
+	})
+
+	reloadConfigurationTool := mcp.NewTool("reload_configuration",
+		mcp.WithDescription("Re-read the --config file and send the language server's settings with workspace/didChangeConfiguration, without restarting it. Use this after editing server settings such as gopls analyses."),
+	)
+
+	s.addTool(reloadConfigurationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing reload_configuration")
+		text, err := s.reloadConfiguration(ctx)
+		if err != nil {
+			coreLogger.Error("Failed to reload configuration: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to reload configuration: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	})
+
+	continueOutput := mcp.NewTool(continueOutputTool,
+		mcp.WithDescription("Get the next chunk of a tool result that was too large to return at once. Results larger than the response budget are cut at a file or symbol boundary where possible, and end with the token to pass here. Chunks of JSON results are JSON objects with the chunk in a text field and the token in a continuation field; joining the text of every chunk gives the whole result."),
+		mcp.WithString("token",
+			mcp.Required(),
+			mcp.Description("The continuation token from the truncated result or the previous chunk"),
+		),
+	)
+
+	s.addTool(continueOutput, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		token, ok := request.Params.Arguments["token"].(string)
+		if !ok {
+			return mcp.NewToolResultError("token must be a string"), nil
+		}
+
+		coreLogger.Debug("Executing continue_output for token: %s", token)
+		return s.responses.nextChunk(token)
+	})
+
+	if err := s.config.tools.checkNames(s.toolNames); err != nil {
+		return err
+	}
+
 	coreLogger.Info("Successfully registered all MCP tools")
 	return nil
 }